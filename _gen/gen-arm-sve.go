@@ -197,7 +197,7 @@ func fromAvx2ToSve() {
 	asmOut, goOut := &bytes.Buffer{}, &bytes.Buffer{}
 
 	goOut.WriteString(`// Code generated by command: go generate ` + os.Getenv("GOFILE") + `. DO NOT EDIT.` + "\n\n")
-	goOut.WriteString("//go:build !noasm && !appengine && !gccgo && !nopshufb\n\n")
+	goOut.WriteString("//go:build !noasm && !purego && !appengine && !gccgo && !nopshufb\n\n")
 	goOut.WriteString("package reedsolomon\n\n")
 
 	const input = 10
@@ -312,7 +312,7 @@ func addEarlyExit(arch string) {
 	asmOut := &bytes.Buffer{}
 
 	asmOut.WriteString(`// Code generated by command: go generate ` + os.Getenv("GOFILE") + `. DO NOT EDIT.` + "\n\n")
-	asmOut.WriteString("//go:build !appengine && !noasm && !nogen && !nopshufb && gc\n\n")
+	asmOut.WriteString("//go:build !appengine && !noasm && !purego && !nogen && !nopshufb && gc\n\n")
 	asmOut.WriteString(`#include "textflag.h"` + "\n\n")
 
 	input := 10
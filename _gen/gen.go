@@ -47,6 +47,7 @@ const prefetchDst = 0
 func main() {
 	Constraint(buildtags.Not("appengine").ToConstraint())
 	Constraint(buildtags.Not("noasm").ToConstraint())
+	Constraint(buildtags.Not("purego").ToConstraint())
 	Constraint(buildtags.Not("nogen").ToConstraint())
 	if pshufb {
 		Constraint(buildtags.Not("nopshufb").ToConstraint())
@@ -116,6 +117,7 @@ func genSwitch() {
 
 // +build !appengine
 // +build !noasm
+// +build !purego
 // +build gc
 // +build !nogen
 ` + tag + `
@@ -0,0 +1,54 @@
+package reedsolomon
+
+import "errors"
+
+// ErrNotAligned is returned with WithRequireAligned if a shard
+// does not start on a 64 byte boundary, or does not have the capacity
+// to round its length up to a multiple of 64 bytes.
+var ErrNotAligned = errors.New("shard is not aligned")
+
+// shardAlign is the alignment of shards returned by AllocAligned.
+const shardAlign = 64
+
+// isAligned returns whether b starts on a shardAlign byte boundary,
+// and has the capacity to round its length up to a multiple of shardAlign.
+func isAligned(b []byte) bool {
+	return cap(b) >= alignedSize(len(b)) && addrAligned(b)
+}
+
+// alignedSize returns n rounded up to a multiple of shardAlign.
+func alignedSize(n int) int {
+	return (n + shardAlign - 1) &^ (shardAlign - 1)
+}
+
+// checkAligned returns ErrNotAligned if WithRequireAligned is given
+// and a shard with data is not aligned.
+func (r *reedSolomon) checkAligned(shards [][]byte) error {
+	if !r.o.requireAligned {
+		return nil
+	}
+	for _, shard := range shards {
+		if len(shard) > 0 && !isAligned(shard) {
+			return ErrNotAligned
+		}
+	}
+	return nil
+}
+
+// alignedShards returns whether all shards have length n and are aligned.
+func alignedShards(shards [][]byte, n int) bool {
+	for _, shard := range shards {
+		if len(shard) != n || !isAligned(shard) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendShardsLen appends shards to dst with their length set to n, within their capacity.
+func appendShardsLen(dst, shards [][]byte, n int) [][]byte {
+	for _, shard := range shards {
+		dst = append(dst, shard[:n])
+	}
+	return dst
+}
@@ -0,0 +1,155 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWithRequireAligned(t *testing.T) {
+	for _, size := range []int{64, 1000, 100<<10 + 1} {
+		enc, err := New(10, 4, testOptions(WithRequireAligned())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := New(10, 4, testOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(size)
+		for _, shard := range shards[:10] {
+			fillRandom(shard)
+		}
+		want := make([][]byte, len(shards))
+		for i := range shards {
+			want[i] = append([]byte(nil), shards[i]...)
+		}
+		if err := ref.Encode(want); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if len(shards[i]) != size {
+				t.Fatalf("size %d: shard %d has length %d", size, i, len(shards[i]))
+			}
+			if !bytes.Equal(shards[i], want[i]) {
+				t.Fatalf("size %d: shard %d differs", size, i)
+			}
+		}
+		ok, err := enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+
+		// Missing shards with unaligned capacity are replaced by aligned ones.
+		shards[0] = nil
+		shards[12] = make([]byte, 0, size)
+		if err := enc.Reconstruct(shards); err != nil {
+			t.Fatal(err)
+		}
+		if !isAligned(shards[0]) || !isAligned(shards[12]) {
+			t.Errorf("size %d: reconstructed shards are not aligned", size)
+		}
+		if !bytes.Equal(shards[0], want[0]) || !bytes.Equal(shards[12], want[12]) {
+			t.Fatalf("size %d: reconstructed shards differ", size)
+		}
+	}
+}
+
+func TestWithRequireAlignedError(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithRequireAligned())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+
+	// Capacity too small to round up to a multiple of 64.
+	saved := shards[3]
+	shards[3] = make([]byte, 1000)
+	if err := enc.Encode(shards); !errors.Is(err, ErrNotAligned) {
+		t.Errorf("got %v, want %v", err, ErrNotAligned)
+	}
+	if _, err := enc.Verify(shards); !errors.Is(err, ErrNotAligned) {
+		t.Errorf("got %v, want %v", err, ErrNotAligned)
+	}
+	shards[3] = saved
+
+	if !addrAligned(make([]byte, 2)[1:]) {
+		// Addresses can be checked.
+		buf := AllocAligned(1, 1064)[0]
+		shards[5] = buf[1:1001]
+		if err := enc.Reconstruct(shards); !errors.Is(err, ErrNotAligned) {
+			t.Errorf("got %v, want %v", err, ErrNotAligned)
+		}
+	}
+}
+
+func TestWithRequireAlignedConcurrentVerify(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithRequireAligned())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	for g := 0; g < 4; g++ {
+		go func() {
+			for i := 0; i < 100; i++ {
+				if ok, err := enc.Verify(shards); !ok || err != nil {
+					done <- fmt.Errorf("not ok: %v, err: %v", ok, err)
+					return
+				}
+			}
+			done <- nil
+		}()
+	}
+	for g := 0; g < 4; g++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, shard := range shards {
+		if len(shard) != 1000 {
+			t.Fatalf("shard %d has length %d", i, len(shard))
+		}
+	}
+}
+
+func TestWithRequireAlignedSplit(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithRequireAligned())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10001)
+	fillRandom(data)
+	shards, err := enc.Split(data[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, shards, len(data)-1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data[1:]) {
+		t.Fatal("joined data differs")
+	}
+
+	enc, err = New(10, 4, testOptions(WithRequireAligned(), WithZeroCopySplit())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.Split(data[1:]); err != ErrSplitCopy {
+		t.Fatalf("want ErrSplitCopy, got %v", err)
+	}
+}
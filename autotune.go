@@ -0,0 +1,118 @@
+package reedsolomon
+
+import "time"
+
+const (
+	// autoTuneShardSize is the shard size tuned for if none is given with WithAutoGoroutines.
+	autoTuneShardSize = 64 << 10
+
+	// autoTuneMaxBytes is the maximum size of all shards encoded when tuning.
+	autoTuneMaxBytes = 8 << 20
+
+	// autoTuneRounds is the number of timed encodes of each candidate.
+	// The fastest is used, to reduce the effect of interruptions.
+	autoTuneRounds = 3
+)
+
+// autoTune selects the kernels and the work split that encode shards of
+// the calibrated size fastest, by timing encodes of a test stripe.
+//
+// Kernels are disabled one at a time, starting with the ones expected to be fastest,
+// then the number of goroutines and the bytes per round are adjusted for the best kernels.
+// The stripe is capped at autoTuneMaxBytes, so tuning for very large shards
+// uses smaller ones.
+func (r *reedSolomon) autoTune() {
+	size := r.o.shardSize
+	if size <= 0 {
+		size = autoTuneShardSize
+	}
+	if limit := autoTuneMaxBytes / r.totalShards; size > limit {
+		size = limit
+	}
+	size = (size + 63) &^ 63
+	shards := AllocAligned(r.totalShards, size)
+	for i, shard := range shards[:r.dataShards] {
+		for j := range shard {
+			shard[j] = byte(i*31 + j*7)
+		}
+	}
+	measure := func() time.Duration {
+		r.codeSomeShardsCPU(r.parity, shards[:r.dataShards], shards[r.dataShards:], size)
+		var best time.Duration
+		for i := 0; i < autoTuneRounds; i++ {
+			start := time.Now()
+			r.codeSomeShardsCPU(r.parity, shards[:r.dataShards], shards[r.dataShards:], size)
+			if d := time.Since(start); i == 0 || d < best {
+				best = d
+			}
+		}
+		return best
+	}
+
+	// Kernels. Each step disables more features than the previous.
+	// On CPUs where the 256 bit kernels may be faster, those are tried first.
+	bestO, bestT := r.o, measure()
+	for _, disable := range []func(o *options) bool{
+		func(o *options) bool {
+			if !archProfiles[cpuArch()].prefer256 || !o.useAVX2 {
+				return false
+			}
+			changed := o.useAvx512GFNI || o.useAVX512
+			o.useAvx512GFNI, o.useAVX512 = false, false
+			return changed
+		},
+		func(o *options) bool {
+			changed := o.useAvx512GFNI || o.useAvxGNFI
+			o.useAvx512GFNI, o.useAvxGNFI = false, false
+			return changed
+		},
+		func(o *options) bool {
+			changed := o.useAVX512
+			o.useAVX512 = false
+			return changed
+		},
+		func(o *options) bool {
+			changed := o.useAVX10 || o.useSHA3
+			o.useAVX10, o.useSHA3 = false, false
+			return changed
+		},
+	} {
+		if !disable(&r.o) {
+			continue
+		}
+		if t := measure(); t < bestT {
+			bestO, bestT = r.o, t
+		}
+	}
+	r.o = bestO
+
+	// Work split. Goroutines are only used above the minimum split size.
+	if size <= r.o.minSplitSize {
+		return
+	}
+	best := *r.calib.Load()
+	try := func(c calibration) {
+		if c.maxGoroutines < 1 || c.perRound < 1<<10 {
+			return
+		}
+		r.calib.Store(&c)
+		if t := measure(); t < bestT {
+			best, bestT = c, t
+		}
+	}
+	base := best
+	for _, g := range []int{base.maxGoroutines / 2, 1} {
+		if g != base.maxGoroutines {
+			c := base
+			c.maxGoroutines = g
+			try(c)
+		}
+	}
+	base = best
+	for _, n := range []int{base.perRound * 2, (base.perRound / 2) &^ 63} {
+		c := base
+		c.perRound = n
+		try(c)
+	}
+	r.calib.Store(&best)
+}
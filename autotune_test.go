@@ -0,0 +1,39 @@
+package reedsolomon
+
+import "testing"
+
+func TestWithAutoTune(t *testing.T) {
+	for _, size := range []int{1000, 256 << 10} {
+		enc, err := New(10, 4, testOptions(WithAutoTune(), WithAutoGoroutines(size))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := enc.(*reedSolomon)
+		if r.o.useAVX512 && !defaultOptions.useAVX512 || r.o.useAvx512GFNI && !defaultOptions.useAvx512GFNI || r.o.useAvxGNFI && !defaultOptions.useAvxGNFI {
+			t.Errorf("size %d: tuning enabled an unsupported kernel", size)
+		}
+		if cal := r.calib.Load(); cal.maxGoroutines < 1 || cal.perRound < 1<<10 {
+			t.Errorf("size %d: invalid calibration %+v", size, *cal)
+		}
+
+		shards := enc.(Extensions).AllocAligned(size)
+		for _, shard := range shards[:10] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+		shards[0], shards[11] = nil, nil
+		if err := enc.Reconstruct(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err = enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package reedsolomon
+
+import "sync"
+
+// Backend performs the Galois field matrix multiplications of an encoder,
+// so they can be offloaded to a GPU or another accelerator
+// implemented in a separate module.
+//
+// Multiplications are in GF(2^8) with the polynomial 0x11d,
+// as done by the CPU kernels of encoders without WithFieldRepresentation.
+type Backend interface {
+	// Available returns whether the backend can be used.
+	// It is called once by New, and if it returns false the CPU is used.
+	Available() bool
+
+	// MulMatrix sets each output to the sum of the products of inputs
+	// with the coefficients of the corresponding matrix row.
+	// matrix has one row for each output with one coefficient for each input,
+	// and all inputs and outputs have the same length.
+	//
+	// Large shards are split into blocks and several blocks are passed
+	// in concurrent calls, so transfers of one block can overlap
+	// computation of another.
+	// If an error is returned, the outputs are computed by the CPU instead,
+	// so backends can decline calls, for example ones too small to be worth a transfer.
+	// inputs, outputs and matrix must not be retained after the call returns.
+	MulMatrix(matrix, inputs, outputs [][]byte) error
+}
+
+const (
+	// backendBlockSize is the number of bytes of each shard passed in each call to a Backend.
+	backendBlockSize = 1 << 20
+
+	// backendPipeline is the maximum number of concurrent calls to a Backend for an operation.
+	backendPipeline = 3
+)
+
+// codeSomeShardsBackend performs codeSomeShards with the backend.
+// Blocks the backend fails to process are processed by the CPU.
+func (r *reedSolomon) codeSomeShardsBackend(matrixRows, inputs, outputs [][]byte, byteCount int) {
+	b := r.o.backend
+	matrix := make([][]byte, len(outputs))
+	for i := range matrix {
+		matrix[i] = matrixRows[i][:len(inputs)]
+	}
+	if byteCount <= backendBlockSize {
+		if b.MulMatrix(matrix, sliceShards(inputs, 0, byteCount), sliceShards(outputs, 0, byteCount)) != nil {
+			r.codeSomeShardsCPU(matrixRows, inputs, outputs, byteCount)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, backendPipeline)
+	for start := 0; start < byteCount; start += backendBlockSize {
+		end := start + backendBlockSize
+		if end > byteCount {
+			end = byteCount
+		}
+		ins, outs := sliceShards(inputs, start, end), sliceShards(outputs, start, end)
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.MulMatrix(matrix, ins, outs) != nil {
+				r.codeSomeShardsCPU(matrixRows, ins, outs, len(ins[0]))
+			}
+			<-sem
+		}()
+	}
+	wg.Wait()
+}
+
+// sliceShards returns shards sliced to [start:end].
+func sliceShards(shards [][]byte, start, end int) [][]byte {
+	res := make([][]byte, len(shards))
+	for i, shard := range shards {
+		res[i] = shard[start:end]
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// testBackend is a Backend computing on the CPU, recording its calls.
+type testBackend struct {
+	available bool
+	fail      bool
+
+	mu      sync.Mutex
+	calls   int
+	running int
+	maxRun  int
+}
+
+func (b *testBackend) Available() bool {
+	return b.available
+}
+
+func (b *testBackend) MulMatrix(matrix, inputs, outputs [][]byte) error {
+	b.mu.Lock()
+	b.calls++
+	b.running++
+	if b.running > b.maxRun {
+		b.maxRun = b.running
+	}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.running--
+		b.mu.Unlock()
+	}()
+	if b.fail {
+		return errors.New("backend failure")
+	}
+	for i, out := range outputs {
+		for j := range out {
+			out[j] = 0
+		}
+		for c, in := range inputs {
+			for j := range in {
+				out[j] ^= galMultiply(matrix[i][c], in[j])
+			}
+		}
+	}
+	return nil
+}
+
+func TestWithBackend(t *testing.T) {
+	sizes := []int{1000, backendBlockSize*2 + 100}
+	if testing.Short() {
+		sizes = sizes[:1]
+	}
+	for _, size := range sizes {
+		for _, fail := range []bool{false, true} {
+			b := &testBackend{available: true, fail: fail}
+			enc, err := New(5, 3, testOptions(WithBackend(b))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ref, err := New(5, 3, testOptions()...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shards := enc.(Extensions).AllocAligned(size)
+			for _, shard := range shards[:5] {
+				fillRandom(shard)
+			}
+			want := AllocAligned(8, size)
+			for i := range shards[:5] {
+				copy(want[i], shards[i])
+			}
+			if err := enc.Encode(shards); err != nil {
+				t.Fatal(err)
+			}
+			if err := ref.Encode(want); err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(shards[i], want[i]) {
+					t.Fatalf("size %d, fail %v: shard %d mismatch", size, fail, i)
+				}
+			}
+			if b.calls == 0 {
+				t.Fatalf("size %d, fail %v: backend not called", size, fail)
+			}
+			if b.maxRun > backendPipeline {
+				t.Errorf("size %d: %d concurrent calls, want at most %d", size, b.maxRun, backendPipeline)
+			}
+
+			shards[1], shards[6] = nil, nil
+			if err := enc.Reconstruct(shards); err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(shards[i], want[i]) {
+					t.Fatalf("size %d, fail %v: reconstructed shard %d mismatch", size, fail, i)
+				}
+			}
+		}
+	}
+}
+
+func TestWithBackendUnavailable(t *testing.T) {
+	b := &testBackend{}
+	enc, err := New(5, 3, testOptions(WithBackend(b))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:5] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := enc.Verify(shards); !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+	if b.calls != 0 {
+		t.Errorf("unavailable backend called %d times", b.calls)
+	}
+}
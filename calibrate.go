@@ -0,0 +1,56 @@
+package reedsolomon
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+const (
+	// driftMisses is the number of consecutive operations with a shard size
+	// outside the calibrated range before recalibrating.
+	driftMisses = 16
+
+	// driftProcsInterval is the number of operations between checks of GOMAXPROCS.
+	driftProcsInterval = 256
+)
+
+// calibration contains the parameters for splitting work between goroutines.
+// It is replaced as a whole when recalibrating, and must not be modified.
+type calibration struct {
+	maxGoroutines int
+	perRound      int // Bytes processed per round by each goroutine.
+	shardSize     int // Shard size calibrated for. 0 if none.
+	procs         int // GOMAXPROCS when calibrated.
+}
+
+// driftDetector tracks the operations done by an encoder
+// to detect when it should be recalibrated.
+type driftDetector struct {
+	misses atomic.Int32
+	calls  atomic.Uint32
+}
+
+// Recalibrate adjusts the number of goroutines for the given shard size.
+func (r *reedSolomon) Recalibrate(shardSize int) {
+	r.calib.Store(r.calibrate(shardSize))
+}
+
+// checkDrift records an operation on shards of shardSize bytes,
+// and recalibrates if the workload has drifted from the calibration.
+func (r *reedSolomon) checkDrift(shardSize int) {
+	cal := r.calib.Load()
+	if r.drift.calls.Add(1)%driftProcsInterval == 0 && runtime.GOMAXPROCS(0) != cal.procs {
+		r.Recalibrate(cal.shardSize)
+		return
+	}
+	if cal.shardSize > 0 && shardSize >= cal.shardSize/2 && shardSize <= cal.shardSize*2 {
+		if r.drift.misses.Load() != 0 {
+			r.drift.misses.Store(0)
+		}
+		return
+	}
+	if r.drift.misses.Add(1) >= driftMisses {
+		r.drift.misses.Store(0)
+		r.Recalibrate(shardSize)
+	}
+}
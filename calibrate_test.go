@@ -0,0 +1,73 @@
+package reedsolomon
+
+import (
+	"testing"
+)
+
+func TestRecalibrate(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithMaxGoroutines(7))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		t.Skip("not a reedSolomon encoder")
+	}
+	initial := *r.calib.Load()
+
+	r.Recalibrate(1 << 20)
+	if got := r.calib.Load(); got.shardSize != 1<<20 {
+		t.Errorf("got shard size %d, want %d", got.shardSize, 1<<20)
+	}
+	want := *r.calibrate(1 << 20)
+	if got := *r.calib.Load(); got != want {
+		t.Errorf("got calibration %+v, want %+v", got, want)
+	}
+
+	r.Recalibrate(0)
+	if got := *r.calib.Load(); got != initial {
+		t.Errorf("got calibration %+v, want %+v", got, initial)
+	}
+
+	shards := enc.(Extensions).AllocAligned(50000)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err = enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+}
+
+func TestAutoRecalibrate(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithAutoGoroutines(1<<20), WithAutoRecalibrate(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		t.Skip("not a reedSolomon encoder")
+	}
+	shards := enc.(Extensions).AllocAligned(10000)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	for i := 0; i < driftMisses; i++ {
+		if got := r.calib.Load().shardSize; got != 1<<20 {
+			t.Fatalf("recalibrated after %d operations", i)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.calib.Load().shardSize; got != 10000 {
+		t.Errorf("got shard size %d, want %d", got, 10000)
+	}
+	ok, err = enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+}
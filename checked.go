@@ -0,0 +1,75 @@
+//go:build rscheck
+
+package reedsolomon
+
+import "fmt"
+
+// checkEnabled enables extra runtime assertions in the optimized paths.
+// Build with the 'rscheck' tag to enable.
+const checkEnabled = true
+
+// checkSlice panics if out cannot hold the result of processing in.
+func checkSlice(in, out []byte) {
+	if len(out) < len(in) {
+		panic(fmt.Sprintf("reedsolomon: output length %d less than input length %d", len(out), len(in)))
+	}
+}
+
+// checkSlices panics if the matrix has too few rows or columns,
+// or if any input or output is shorter than stop.
+func checkSlices[T any](matrixRows [][]T, in, out [][]byte, start, stop int) {
+	if start < 0 || start > stop {
+		panic(fmt.Sprintf("reedsolomon: invalid range %d-%d", start, stop))
+	}
+	if matrixRows != nil {
+		if len(matrixRows) < len(out) {
+			panic(fmt.Sprintf("reedsolomon: %d matrix rows for %d outputs", len(matrixRows), len(out)))
+		}
+		for i, row := range matrixRows[:len(out)] {
+			if len(row) < len(in) {
+				panic(fmt.Sprintf("reedsolomon: matrix row %d has %d columns for %d inputs", i, len(row), len(in)))
+			}
+		}
+	}
+	for i, v := range in {
+		if len(v) < stop {
+			panic(fmt.Sprintf("reedsolomon: input %d length %d less than %d", i, len(v), stop))
+		}
+	}
+	for i, v := range out {
+		if len(v) < stop {
+			panic(fmt.Sprintf("reedsolomon: output %d length %d less than %d", i, len(v), stop))
+		}
+	}
+}
+
+// checkSystematic panics if the top square of m is not the identity matrix.
+func checkSystematic(m matrix, dataShards int) {
+	for r, row := range m[:dataShards] {
+		for c, v := range row[:dataShards] {
+			want := byte(0)
+			if r == c {
+				want = 1
+			}
+			if v != want {
+				panic(fmt.Sprintf("reedsolomon: encoding matrix is not systematic at row %d, column %d", r, c))
+			}
+		}
+	}
+}
+
+// checkInverse panics if m multiplied by inv is not the identity matrix in f.
+func checkInverse(m, inv matrix, f *galoisField) {
+	t := f.mul
+	for r := range m {
+		for c := range inv[0] {
+			var v byte
+			for i := range m[r] {
+				v ^= t[m[r][i]][inv[i][c]]
+			}
+			if (r == c && v != 1) || (r != c && v != 0) {
+				panic(fmt.Sprintf("reedsolomon: inverted matrix is invalid at row %d, column %d", r, c))
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !rscheck
+
+package reedsolomon
+
+const checkEnabled = false
+
+func checkSlice(in, out []byte) {}
+
+func checkSlices[T any](matrixRows [][]T, in, out [][]byte, start, stop int) {}
+
+func checkSystematic(m matrix, dataShards int) {}
+
+func checkInverse(m, inv matrix, f *galoisField) {}
@@ -0,0 +1,30 @@
+//go:build rscheck
+
+package reedsolomon
+
+import "testing"
+
+func TestCheckedPanics(t *testing.T) {
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("short output", func() { checkSlice(make([]byte, 10), make([]byte, 9)) })
+	mustPanic("short input", func() {
+		checkSlices([][]byte{{1}}, [][]byte{make([]byte, 9)}, [][]byte{make([]byte, 10)}, 0, 10)
+	})
+	mustPanic("short matrix", func() {
+		checkSlices([][]byte{{1}}, [][]byte{make([]byte, 10), make([]byte, 10)}, [][]byte{make([]byte, 10)}, 0, 10)
+	})
+	mustPanic("not systematic", func() { checkSystematic(matrix{{1, 0}, {1, 1}, {1, 2}}, 2) })
+	mustPanic("bad inverse", func() { checkInverse(matrix{{1, 0}, {1, 1}}, matrix{{1, 0}, {0, 1}}, stdField) })
+
+	checkSlices([][]byte{{1}}, [][]byte{make([]byte, 10)}, [][]byte{make([]byte, 10)}, 0, 10)
+	checkSystematic(matrix{{1, 0}, {0, 1}, {1, 2}}, 2)
+	checkInverse(matrix{{1, 0}, {1, 1}}, matrix{{1, 0}, {1, 1}}, stdField)
+}
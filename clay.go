@@ -0,0 +1,488 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// clayCodec is a minimum-storage regenerating code using the Clay construction.
+// Construct using New with WithClayCode.
+//
+// Nodes are arranged in a grid of q = parityShards rows and t columns,
+// with shortened data nodes containing zeros added after the data shards
+// to fill the grid. Each shard is divided into q^t sub-chunks.
+// The coupled sub-chunks C are stored, while the uncoupled sub-chunks U
+// of each layer form a codeword of the inner Reed-Solomon code.
+//
+// Sub-chunk z of node (x, y) is paired with sub-chunk z' of node (z_y, y),
+// where z_y is digit y of z in base q, and z' is z with digit y set to x.
+// Pairs are coupled as C = U + gamma*U' and C' = U' + gamma*U.
+// Sub-chunks with z_y = x are unpaired, and C = U.
+type clayCodec struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	q, t      int   // Grid size.
+	nodes     int   // Number of nodes, including shortened nodes.
+	virtual   int   // Number of shortened nodes.
+	subChunks int   // Number of sub-chunks in each shard.
+	pow       []int // pow[y] is q^y.
+
+	inner Encoder // Encoder for each layer.
+	o     options
+}
+
+const (
+	// clayMaxSubChunks is the maximum number of sub-chunks per shard.
+	clayMaxSubChunks = 1 << 16
+
+	// clayGamma is the coupling coefficient.
+	// Any value except 0 and 1 works.
+	clayGamma = 2
+)
+
+var (
+	clayGammaInv = galOneOver(clayGamma)
+	// Coefficients for uncoupling a pair: U = a*C + b*C'.
+	clayUncoupleA = galOneOver(1 ^ galMultiply(clayGamma, clayGamma))
+	clayUncoupleB = galMultiply(clayGamma, clayUncoupleA)
+)
+
+// newClay is like New, but for the Clay construction.
+func newClay(dataShards, parityShards int, opt options) (*clayCodec, error) {
+	if dataShards <= 0 || parityShards < 0 {
+		return nil, ErrInvShardNum
+	}
+	if dataShards+parityShards > 256 {
+		return nil, ErrMaxShardNum
+	}
+	r := &clayCodec{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		q:            parityShards,
+		o:            opt,
+	}
+	if r.q == 0 {
+		r.q = 1
+	}
+	r.t = (r.totalShards + r.q - 1) / r.q
+	r.nodes = r.q * r.t
+	r.virtual = r.nodes - r.totalShards
+	if r.nodes > 256 {
+		return nil, ErrMaxShardNum
+	}
+	r.subChunks = 1
+	r.pow = make([]int, r.t)
+	for y := range r.pow {
+		r.pow[y] = r.subChunks
+		r.subChunks *= r.q
+		if r.subChunks > clayMaxSubChunks {
+			return nil, ErrMaxShardNum
+		}
+	}
+
+	var err error
+	r.inner, err = New(dataShards+r.virtual, parityShards, func(o *options) {
+		*o = opt
+		o.useClay = false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+var _ = Extensions(&clayCodec{})
+
+// shard returns the shard stored at node i, or -1 for shortened nodes.
+func (r *clayCodec) shard(i int) int {
+	switch {
+	case i < r.dataShards:
+		return i
+	case i < r.dataShards+r.virtual:
+		return -1
+	}
+	return i - r.virtual
+}
+
+// node returns the node storing the shard.
+func (r *clayCodec) node(shard int) int {
+	if shard < r.dataShards {
+		return shard
+	}
+	return shard + r.virtual
+}
+
+// digit returns digit y of layer z.
+func (r *clayCodec) digit(z, y int) int {
+	return z / r.pow[y] % r.q
+}
+
+// companion returns the node and layer paired with node i in layer z.
+// If the sub-chunk is unpaired, i and z are returned.
+func (r *clayCodec) companion(i, z int) (int, int) {
+	x, y := i%r.q, i/r.q
+	zy := r.digit(z, y)
+	return y*r.q + zy, z + (x-zy)*r.pow[y]
+}
+
+// repairLayers returns the layers needed to repair node i, in increasing order.
+func (r *clayCodec) repairLayers(i int) []int {
+	x, y := i%r.q, i/r.q
+	layers := make([]int, 0, r.subChunks/r.q)
+	for z := 0; z < r.subChunks; z++ {
+		if r.digit(z, y) == x {
+			layers = append(layers, z)
+		}
+	}
+	return layers
+}
+
+// clayChunk returns sub-chunk z of b.
+func clayChunk(b []byte, z, size int) []byte {
+	return b[z*size : (z+1)*size : (z+1)*size]
+}
+
+// uncouple writes a*c1 + b*c2 to dst.
+func (r *clayCodec) uncouple(dst, c1, c2 []byte, a, b byte) {
+	galMulSlice(a, c1, dst, &r.o)
+	galMulSliceXor(b, c2, dst, &r.o)
+}
+
+// decode recovers the coupled sub-chunks of the erased nodes.
+// c contains the coupled data of each node, and must be allocated for erased nodes.
+// No more than parityShards nodes may be erased.
+func (r *clayCodec) decode(c [][]byte, erased []bool, size int) error {
+	u := AllocAligned(r.nodes, size*r.subChunks)
+
+	// Layers must be decoded in order of the number of erased unpaired
+	// sub-chunks, so uncoupling can use erased sub-chunks decoded in
+	// earlier layers.
+	score := make([]int, r.subChunks)
+	order := make([]int, r.subChunks)
+	for z := range order {
+		order[z] = z
+		for i, e := range erased {
+			if e && r.digit(z, i/r.q) == i%r.q {
+				score[z]++
+			}
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return score[order[a]] < score[order[b]]
+	})
+
+	layer := make([][]byte, r.nodes)
+	for _, z := range order {
+		for i := range layer {
+			dst := clayChunk(u[i], z, size)
+			if erased[i] {
+				layer[i] = dst[:0]
+				continue
+			}
+			layer[i] = dst
+			j, z2 := r.companion(i, z)
+			switch {
+			case j == i:
+				copy(dst, clayChunk(c[i], z, size))
+			case erased[j]:
+				r.uncouple(dst, clayChunk(c[i], z, size), clayChunk(u[j], z2, size), 1, clayGamma)
+			default:
+				r.uncouple(dst, clayChunk(c[i], z, size), clayChunk(c[j], z2, size), clayUncoupleA, clayUncoupleB)
+			}
+		}
+		if err := r.inner.Reconstruct(layer); err != nil {
+			return err
+		}
+	}
+
+	for i, e := range erased {
+		if !e {
+			continue
+		}
+		for z := 0; z < r.subChunks; z++ {
+			j, z2 := r.companion(i, z)
+			dst := clayChunk(c[i], z, size)
+			copy(dst, clayChunk(u[i], z, size))
+			if j != i {
+				galMulSliceXor(clayGamma, clayChunk(u[j], z2, size), dst, &r.o)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *clayCodec) ShardSizeMultiple() int {
+	return r.subChunks
+}
+
+func (r *clayCodec) DataShards() int {
+	return r.dataShards
+}
+
+func (r *clayCodec) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *clayCodec) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *clayCodec) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+func (r *clayCodec) PureGo() bool {
+	return r.inner.(PureGoer).PureGo()
+}
+
+func (r *clayCodec) Recalibrate(shardSize int) {
+	r.inner.(Recalibrator).Recalibrate(shardSize / r.subChunks)
+}
+
+// CostModel returns the estimated costs for the encoder.
+// Reconstruction costs are for repairing a single shard with RepairShard.
+func (r *clayCodec) CostModel() CostModel {
+	var c CostModel
+	if r.parityShards == 0 {
+		return c
+	}
+	d, p, q := float64(r.dataShards), float64(r.parityShards), float64(r.q)
+	k := float64(r.nodes - r.parityShards)
+	paired := (q - 1) / q
+
+	// Uncouple the data, encode each layer and couple the parity.
+	c.EncodeMulsPerByte = (k*(2*paired+p) + p*paired) / d
+	c.EncodeBytesPerByte = 2 * (d + p) / d
+
+	// Each repaired layer uncouples and decodes q sub-chunks of the lost shard.
+	n := float64(r.totalShards)
+	c.ReconstructMulsPerByte = (k*2*paired + k*q + 2*(q-1)) / q
+	c.ReconstructBytesPerByte = (n-1)/q + 1
+	return c
+}
+
+func (r *clayCodec) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	size := len(shards[0])
+	if size%r.subChunks != 0 {
+		return ErrInvalidShardSize
+	}
+	if r.parityShards == 0 {
+		return nil
+	}
+	c := make([][]byte, r.nodes)
+	erased := make([]bool, r.nodes)
+	zero := make([]byte, size)
+	for i := range c {
+		s := r.shard(i)
+		switch {
+		case s < 0:
+			c[i] = zero
+		default:
+			c[i] = shards[s]
+			erased[i] = s >= r.dataShards
+		}
+	}
+	return r.decode(c, erased, size/r.subChunks)
+}
+
+// EncodeIdx is not supported, since parity depends on data shards in
+// other columns through the coupling.
+func (r *clayCodec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	return ErrNotSupported
+}
+
+func (r *clayCodec) Update(shards [][]byte, newDatashards [][]byte) error {
+	return ErrNotSupported
+}
+
+func (r *clayCodec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	shardSize := len(shards[0])
+	outputs := make([][]byte, r.totalShards)
+	copy(outputs, shards[:r.dataShards])
+	for i := r.dataShards; i < r.totalShards; i++ {
+		outputs[i] = make([]byte, shardSize)
+	}
+	if err := r.Encode(outputs); err != nil {
+		return false, err
+	}
+
+	// Compare.
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !bytes.Equal(outputs[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *clayCodec) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+func (r *clayCodec) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *clayCodec) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards.
+// If required is non-nil, only shards marked as required are recreated.
+// All missing shards are decoded, since the layers depend on each other.
+func (r *clayCodec) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	size := shardSize(shards)
+	if size%r.subChunks != 0 {
+		return ErrInvalidShardSize
+	}
+	isRequired := func(i int) bool {
+		return len(shards[i]) == 0 && (i < r.dataShards || !dataOnly) && (required == nil || required[i])
+	}
+
+	missing, want := 0, false
+	for i := range shards {
+		if len(shards[i]) == 0 {
+			missing++
+			want = want || isRequired(i)
+		}
+	}
+	if !want {
+		return nil
+	}
+	if missing > r.parityShards {
+		return ErrTooFewShards
+	}
+
+	c := make([][]byte, r.nodes)
+	erased := make([]bool, r.nodes)
+	zero := make([]byte, size)
+	for i := range c {
+		s := r.shard(i)
+		switch {
+		case s < 0:
+			c[i] = zero
+		case len(shards[s]) != 0:
+			c[i] = shards[s]
+		case isRequired(s):
+			if cap(shards[s]) >= size {
+				shards[s] = shards[s][:size]
+			} else {
+				shards[s] = AllocAligned(1, size)[0]
+			}
+			c[i] = shards[s]
+			erased[i] = true
+		default:
+			c[i] = make([]byte, size)
+			erased[i] = true
+		}
+	}
+	return r.decode(c, erased, size/r.subChunks)
+}
+
+// repair recreates the shard at node f from the repair layers of all other nodes.
+// fetched contains the repair layers of each shard, in increasing order.
+func (r *clayCodec) repair(f int, fetched [][]byte, size int) ([]byte, error) {
+	layers := r.repairLayers(f)
+	pos := make([]int, r.subChunks)
+	for n, z := range layers {
+		pos[z] = n
+	}
+	zero := make([]byte, size)
+	// coupled returns sub-chunk z of node i, which must be a repair layer.
+	coupled := func(i, z int) []byte {
+		s := r.shard(i)
+		if s < 0 {
+			return zero
+		}
+		return clayChunk(fetched[s], pos[z], size)
+	}
+
+	x0, y0 := f%r.q, f/r.q
+	out := AllocAligned(1, size*r.subChunks)[0]
+	u := AllocAligned(r.nodes, size)
+	tmp := make([]byte, size)
+	layer := make([][]byte, r.nodes)
+	for _, z := range layers {
+		// Nodes in the column of f are unknown in this layer,
+		// while the others can be uncoupled using their companion,
+		// which is also in a repair layer.
+		for i := range layer {
+			if i/r.q == y0 {
+				layer[i] = u[i][:0]
+				continue
+			}
+			layer[i] = u[i]
+			j, z2 := r.companion(i, z)
+			if j == i {
+				copy(u[i], coupled(i, z))
+			} else {
+				r.uncouple(u[i], coupled(i, z), coupled(j, z2), clayUncoupleA, clayUncoupleB)
+			}
+		}
+		if err := r.inner.Reconstruct(layer); err != nil {
+			return nil, err
+		}
+
+		// f is unpaired in this layer.
+		copy(clayChunk(out, z, size), layer[f])
+
+		// Each other node in the column is paired with f in another layer.
+		for x := 0; x < r.q; x++ {
+			if x == x0 {
+				continue
+			}
+			i := y0*r.q + x
+			z2 := z + (x-x0)*r.pow[y0]
+			dst := clayChunk(out, z2, size)
+			// U(f, z2) = (C(i, z) + U(i, z)) / gamma
+			// C(f, z2) = U(f, z2) + gamma * U(i, z)
+			copy(tmp, coupled(i, z))
+			sliceXor(layer[i], tmp, &r.o)
+			galMulSlice(clayGammaInv, tmp, dst, &r.o)
+			galMulSliceXor(clayGamma, layer[i], dst, &r.o)
+		}
+	}
+	return out, nil
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+// Shards are padded with zeros to a multiple of the number of sub-chunks.
+func (r *clayCodec) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, r.subChunks, r.o.zeroCopySplit, false)
+}
+
+func (r *clayCodec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
@@ -0,0 +1,198 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestClayCode(t *testing.T) {
+	for _, test := range []struct{ data, parity int }{
+		{1, 1}, {2, 2}, {4, 2}, {5, 3}, {6, 3}, {8, 4}, {10, 4}, {3, 0},
+	} {
+		enc, err := New(test.data, test.parity, testOptions(WithClayCode(true))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ext := enc.(Extensions)
+		shards := ext.AllocAligned(ext.ShardSizeMultiple() * 5)
+		for _, shard := range shards[:test.data] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := enc.Verify(shards)
+		if err != nil || !ok {
+			t.Fatalf("%d+%d: verification failed: %v", test.data, test.parity, err)
+		}
+
+		// Lose up to parity shards.
+		rng := rand.New(rand.NewSource(int64(test.data*100 + test.parity)))
+		for iter := 0; iter < 10; iter++ {
+			damaged := make([][]byte, len(shards))
+			copy(damaged, shards)
+			for _, i := range rng.Perm(len(shards))[:test.parity] {
+				damaged[i] = nil
+			}
+			if err := enc.Reconstruct(damaged); err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(damaged[i], shards[i]) {
+					t.Fatalf("%d+%d: shard %d mismatch", test.data, test.parity, i)
+				}
+			}
+		}
+		if test.parity == 0 {
+			continue
+		}
+
+		// Repair each shard from the planned sub-chunks only.
+		size := len(shards[0])
+		for i := range shards {
+			plan, err := PlanRepair(enc, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Helpers) != len(shards)-1 {
+				t.Fatalf("got %d helpers", len(plan.Helpers))
+			}
+			if want := (len(shards) - 1) * size / test.parity; plan.ReadSize(size) != want {
+				t.Fatalf("%d+%d: reads %d bytes, want %d", test.data, test.parity, plan.ReadSize(size), want)
+			}
+			fetched := make([][]byte, len(shards))
+			for n, h := range plan.Helpers {
+				for _, rg := range plan.Ranges(n, size) {
+					fetched[h] = append(fetched[h], shards[h][rg[0]:rg[1]]...)
+				}
+			}
+			got, err := RepairShard(enc, plan, fetched)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, shards[i]) {
+				t.Fatalf("%d+%d: repaired shard %d mismatch", test.data, test.parity, i)
+			}
+		}
+
+		if test.parity < 2 {
+			continue
+		}
+		// Only the required shards are returned.
+		damaged := make([][]byte, len(shards))
+		copy(damaged, shards)
+		damaged[0], damaged[len(shards)-1] = nil, nil
+		if err := enc.ReconstructData(damaged); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(damaged[0], shards[0]) || damaged[len(shards)-1] != nil {
+			t.Fatal("unexpected ReconstructData result")
+		}
+	}
+}
+
+func TestClayCodeErrors(t *testing.T) {
+	for _, opt := range []Option{WithLeopardGF16(true), WithConstantTime(true), WithPAR2Matrix(), WithFieldRepresentation(FieldRijndael)} {
+		if _, err := New(4, 2, WithClayCode(true), opt); err != ErrNotSupported {
+			t.Errorf("want ErrNotSupported, got %v", err)
+		}
+	}
+	if _, err := New(40, 4, WithClayCode(true)); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum, got %v", err)
+	}
+
+	enc, err := New(4, 2, testOptions(WithClayCode(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(enc.(Extensions).ShardSizeMultiple() + 1)
+	if err := enc.Encode(shards); err != ErrInvalidShardSize {
+		t.Errorf("want ErrInvalidShardSize, got %v", err)
+	}
+	shards = enc.(Extensions).AllocAligned(enc.(Extensions).ShardSizeMultiple())
+	shards[0], shards[1], shards[2] = nil, nil, nil
+	if err := enc.Reconstruct(shards); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+
+	data := make([]byte, 1000)
+	fillRandom(data)
+	shards, err = enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards[0])%enc.(Extensions).ShardSizeMultiple() != 0 {
+		t.Fatal("shard size not a multiple of sub-chunks", len(shards[0]))
+	}
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, shards, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("join mismatch")
+	}
+}
+
+func TestRepairShardRS(t *testing.T) {
+	enc, err := New(5, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:5] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := PlanRepair(enc, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Helpers) != 5 || plan.ReadSize(1000) != 5000 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	fetched := make([][]byte, len(shards))
+	for _, h := range plan.Helpers {
+		fetched[h] = shards[h]
+	}
+	got, err := RepairShard(enc, plan, fetched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, shards[2]) {
+		t.Fatal("repaired shard mismatch")
+	}
+}
+
+func BenchmarkClayRepair(b *testing.B) {
+	enc, err := New(10, 4, WithClayCode(true))
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1 << 20)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		b.Fatal(err)
+	}
+	plan, err := PlanRepair(enc, 3)
+	if err != nil {
+		b.Fatal(err)
+	}
+	fetched := make([][]byte, len(shards))
+	for n, h := range plan.Helpers {
+		for _, rg := range plan.Ranges(n, len(shards[0])) {
+			fetched[h] = append(fetched[h], shards[h][rg[0]:rg[1]]...)
+		}
+	}
+	b.SetBytes(int64(len(shards[0])))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := RepairShard(enc, plan, fetched); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package reedsolomon
+
+import (
+	"errors"
+)
+
+// correctWindow is the number of bytes of each shard checked at once
+// when locating corrupt shards.
+const correctWindow = 4 << 10
+
+// defaultCorrectSearchLimit is the number of combinations of shards tried for
+// each window when searching for corrupt shards, see WithCorrectSearchLimit.
+const defaultCorrectSearchLimit = 1 << 12
+
+// ErrUncorrectable is returned by ReconstructWithErrors when there are
+// too many corrupt or missing shards to locate and correct them.
+var ErrUncorrectable = errors.New("too many corrupt shards to correct")
+
+// ReconstructWithErrors reconstructs missing shards like Reconstruct,
+// and also locates and corrects shards that contain corrupted data.
+// The indexes of the corrected shards are returned in increasing order.
+//
+// With p parity shards and m missing shards, up to (p-m)/2 corrupt shards
+// can be located and corrected.
+// If more shards are corrupt, this is usually detected and ErrUncorrectable
+// is returned with shards left unmodified, but as with any error correcting
+// decoder, the shards may also be miscorrected.
+//
+// With the default Vandermonde matrix and with WithCauchyMatrix, the corrupt
+// shards are located by syndrome decoding, which costs about as much as
+// encoding the shards with all shards as parity.
+// With other encoders, the shards are checked in windows, and for each window
+// with inconsistent parity, the corrupt shards are located by searching for
+// the smallest set of shards that gives consistent parity when reconstructed.
+// The cost of this grows with the number of combinations of shards,
+// so it is intended for rare use, like scrubbing, and the number of
+// combinations tried is limited, see WithCorrectSearchLimit.
+// Consistent windows only cost a verification.
+func ReconstructWithErrors(enc Encoder, shards [][]byte) ([]int, error) {
+	corrected, err := LocateCorruptShards(enc, shards)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reconstruct the corrupt and missing shards into new buffers,
+	// so shards is unmodified if correction fails.
+	fixed := make([][]byte, len(shards))
+	copy(fixed, shards)
+	for _, i := range corrected {
+		fixed[i] = nil
+	}
+	if err := enc.Reconstruct(fixed); err != nil {
+		return nil, err
+	}
+	if ok, err := enc.Verify(fixed); !ok || err != nil {
+		return nil, ErrUncorrectable
+	}
+	for i := range shards {
+		if len(shards[i]) == 0 {
+			shards[i] = fixed[i]
+		}
+	}
+	for _, i := range corrected {
+		copy(shards[i], fixed[i])
+	}
+	return corrected, nil
+}
+
+// LocateCorruptShards locates shards that contain corrupted data,
+// for example after Verify returns false, without modifying shards.
+// The indexes of the corrupt shards are returned in increasing order.
+// If no shards are corrupt, nil is returned.
+//
+// Missing shards are allowed, and are not reported.
+// The search is the same as done by ReconstructWithErrors, and has the same limits.
+// If the corrupt shards cannot be located, ErrUncorrectable is returned.
+func LocateCorruptShards(enc Encoder, shards [][]byte) ([]int, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return nil, err
+	}
+	size := shardSize(shards)
+	if size%ext.ShardSizeMultiple() != 0 {
+		return nil, ErrInvalidShardSize
+	}
+	missing := 0
+	for _, shard := range shards {
+		if len(shard) == 0 {
+			missing++
+		}
+	}
+	if len(shards)-missing < ext.DataShards() {
+		return nil, ErrTooFewShards
+	}
+	maxErrors := (ext.ParityShards() - missing) / 2
+
+	window := correctWindow
+	if m := ext.ShardSizeMultiple(); window%m != 0 {
+		window = (window + m - 1) / m * m
+	}
+	corrupt := make([]bool, len(shards))
+	var d *syndromeDecoder
+	if r, ok := enc.(*reedSolomon); ok {
+		d = r.syndromeDecoder()
+	}
+	var err error
+	if d != nil {
+		err = d.locate(shards, size, window, maxErrors, corrupt)
+	} else {
+		err = locateSearch(enc, shards, size, window, maxErrors, correctSearchLimit(enc), corrupt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var found []int
+	for i, c := range corrupt {
+		if c {
+			found = append(found, i)
+		}
+	}
+	if len(found) > maxErrors {
+		return nil, ErrUncorrectable
+	}
+	return found, nil
+}
+
+// correctSearchLimit returns the limit set with WithCorrectSearchLimit for enc.
+func correctSearchLimit(enc Encoder) int {
+	var o *options
+	switch e := enc.(type) {
+	case *reedSolomon:
+		o = &e.o
+	case *leopardFF8:
+		o = &e.o
+	case *leopardFF16:
+		o = &e.o
+	case *leopardFF32:
+		o = &e.o
+	case *gf16Matrix:
+		o = &e.o
+	case *gf4Codec:
+		o = &e.o
+	case *par2Codec:
+		o = &e.o
+	case *primeCodec:
+		o = &e.o
+	case *clayCodec:
+		o = &e.o
+	case *piggybackCodec:
+		o = &e.o
+	case *priorityCodec:
+		o = &e.o
+	case *rdpCodec:
+		o = &e.o
+	case *checksumCodec:
+		return correctSearchLimit(e.inner)
+	}
+	if o == nil || o.correctSearchLimit <= 0 {
+		return defaultCorrectSearchLimit
+	}
+	return o.correctSearchLimit
+}
+
+// locateSearch marks the shards with errors in corrupt, by searching
+// each inconsistent window of shards for the smallest set of shards that
+// gives consistent parity when reconstructed.
+// ErrUncorrectable is returned if no set is found after trying limit sets in a window.
+func locateSearch(enc Encoder, shards [][]byte, size, window, maxErrors, limit int, corrupt []bool) error {
+	var present []int
+	for i, shard := range shards {
+		if len(shard) != 0 {
+			present = append(present, i)
+		}
+	}
+	missing := len(shards) - len(present)
+	scratch := enc.(Extensions).AllocAligned(window)
+	test := make([][]byte, len(shards))
+
+	// consistent returns whether the window at off is consistent,
+	// when the shards in bad are treated as missing.
+	consistent := func(off, n int, bad []int) bool {
+		for i, shard := range shards {
+			test[i] = scratch[i][:0]
+			if len(shard) != 0 {
+				test[i] = shard[off : off+n]
+			}
+		}
+		for _, i := range bad {
+			test[i] = scratch[i][:0]
+		}
+		if missing+len(bad) > 0 {
+			if err := enc.Reconstruct(test); err != nil {
+				return false
+			}
+		}
+		ok, err := enc.Verify(test)
+		return ok && err == nil
+	}
+
+	bad := make([]int, 0, maxErrors)
+	for off := 0; off < size; off += window {
+		n := window
+		if size-off < n {
+			n = size - off
+		}
+		if consistent(off, n, nil) {
+			continue
+		}
+		// Search sets of increasing size.
+		tries := 0
+		var try func(start, e int) bool
+		try = func(start, e int) bool {
+			if len(bad) == e {
+				tries++
+				return consistent(off, n, bad)
+			}
+			for i := start; i < len(present) && tries < limit; i++ {
+				bad = append(bad, present[i])
+				if try(i+1, e) {
+					return true
+				}
+				bad = bad[:len(bad)-1]
+			}
+			return false
+		}
+		found := false
+		for e := 1; e <= maxErrors && !found && tries < limit; e++ {
+			bad = bad[:0]
+			found = try(0, e)
+		}
+		if !found {
+			return ErrUncorrectable
+		}
+		for _, i := range bad {
+			corrupt[i] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReconstructWithErrors(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithCauchyMatrix()}, {WithLeopardGF(true)}, {WithLeopardGF16(true)}} {
+		enc, err := New(8, 5, testOptions(opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(3*correctWindow + 64)
+		for _, shard := range shards[:8] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		want := make([][]byte, len(shards))
+		for i := range shards {
+			want[i] = append([]byte{}, shards[i]...)
+		}
+
+		// Consistent shards.
+		got, err := ReconstructWithErrors(enc, shards)
+		if err != nil || len(got) != 0 {
+			t.Fatalf("got %v, %v", got, err)
+		}
+
+		// One missing shard, and two corrupt shards in different windows.
+		shards[4] = nil
+		shards[2][10] ^= 0x55
+		shards[11][2*correctWindow+5] ^= 0xff
+		got, err = ReconstructWithErrors(enc, shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, []int{2, 11}) {
+			t.Errorf("got corrected %v, want [2 11]", got)
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], want[i]) {
+				t.Fatal("shard", i, "mismatch")
+			}
+		}
+
+		// Three corrupt shards cannot be corrected with one missing.
+		shards[4] = nil
+		shards[0][0] ^= 1
+		shards[1][1] ^= 1
+		shards[3][2] ^= 1
+		if _, err := ReconstructWithErrors(enc, shards); err != ErrUncorrectable {
+			t.Errorf("got %v, want %v", err, ErrUncorrectable)
+		}
+		if shards[4] != nil || shards[0][0] == want[0][0] {
+			t.Error("shards modified on failure")
+		}
+	}
+}
+
+func TestLocateCorruptShards(t *testing.T) {
+	enc, err := New(6, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(2 * correctWindow)
+	for _, shard := range shards[:6] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LocateCorruptShards(enc, shards)
+	if err != nil || got != nil {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	shards[1][100] ^= 1
+	shards[8][correctWindow+100] ^= 1
+	got, err = LocateCorruptShards(enc, shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 8}) {
+		t.Errorf("got %v, want [1 8]", got)
+	}
+	// Shards are not modified.
+	if ok, _ := enc.Verify(shards); ok {
+		t.Fatal("shards were corrected")
+	}
+
+	shards[3] = nil
+	if _, err := LocateCorruptShards(enc, shards); err != ErrUncorrectable {
+		t.Errorf("got %v, want %v", err, ErrUncorrectable)
+	}
+}
+
+func TestSyndromeDecoder(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{name: "vandermonde", want: true},
+		{name: "cauchy", opts: []Option{WithCauchyMatrix()}, want: true},
+		{name: "rijndael", opts: []Option{WithFieldRepresentation(FieldRijndael)}, want: true},
+		{name: "raw-vandermonde", opts: []Option{WithRawVandermondeMatrix()}},
+		{name: "jerasure", opts: []Option{WithJerasureMatrix()}},
+		{name: "par1", opts: []Option{WithPAR1Matrix()}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := New(20, 10, testOptions(test.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := enc.(*reedSolomon).syndromeDecoder()
+			if (d != nil) != test.want {
+				t.Fatalf("got decoder %v, want %v", d != nil, test.want)
+			}
+			if d == nil {
+				return
+			}
+			rng := rand.New(rand.NewSource(0))
+			shards := enc.(Extensions).AllocAligned(1000)
+			for _, shard := range shards[:20] {
+				rng.Read(shard)
+			}
+			if err := enc.Encode(shards); err != nil {
+				t.Fatal(err)
+			}
+			// The syndromes of a consistent stripe are zero.
+			corrupt := make([]bool, len(shards))
+			if err := d.locate(shards, 1000, 512, 5, corrupt); err != nil {
+				t.Fatal(err)
+			}
+			for missing := 0; missing <= 4; missing++ {
+				for i := 0; i < 20; i++ {
+					damaged := make([][]byte, len(shards))
+					for j := range shards {
+						damaged[j] = append([]byte(nil), shards[j]...)
+					}
+					perm := rng.Perm(len(shards))
+					for _, j := range perm[:missing] {
+						damaged[j] = nil
+					}
+					errors := (10 - missing) / 2
+					want := make([]bool, len(shards))
+					for _, j := range perm[missing : missing+errors] {
+						want[j] = true
+						damaged[j][rng.Intn(1000)] ^= byte(1 + rng.Intn(255))
+					}
+					corrupt := make([]bool, len(shards))
+					if err := d.locate(damaged, 1000, 512, errors, corrupt); err != nil {
+						t.Fatal(err)
+					}
+					if !reflect.DeepEqual(corrupt, want) {
+						t.Fatalf("%d missing: got corrupt %v, want %v", missing, corrupt, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReconstructWithErrorsMany(t *testing.T) {
+	// Too many corrupt shards are detected without searching combinations.
+	enc, err := New(20, 10, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(correctWindow)
+	for _, shard := range shards[:20] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 3, 7, 12, 21, 28} {
+		fillRandom(shards[i])
+	}
+	start := time.Now()
+	if _, err := ReconstructWithErrors(enc, shards); err != ErrUncorrectable {
+		t.Errorf("got %v, want %v", err, ErrUncorrectable)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("took %v", d)
+	}
+}
+
+func TestCorrectSearchLimit(t *testing.T) {
+	for _, limit := range []int{0, 10} {
+		enc, err := New(20, 10, testOptions(WithLeopardGF(true), WithCorrectSearchLimit(limit))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(correctWindow)
+		for _, shard := range shards[:20] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		shards[5][10] ^= 1
+		shards[25][20] ^= 1
+		got, err := LocateCorruptShards(enc, shards)
+		if limit == 10 {
+			// Finding two shards needs more than 10 combinations.
+			if err != ErrUncorrectable {
+				t.Errorf("limit %d: got %v, want %v", limit, err, ErrUncorrectable)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, []int{5, 25}) {
+			t.Errorf("got %v, want [5 25]", got)
+		}
+	}
+}
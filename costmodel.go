@@ -0,0 +1,116 @@
+package reedsolomon
+
+import "math/bits"
+
+// CostModel contains estimated costs for an encoder configuration.
+//
+// Costs are given relative to shard bytes, so they are independent of the shard size.
+// A multiplication is a GF multiply-add of one shard byte,
+// and bytes moved count all shard bytes read and written,
+// including any temporary buffers used by the algorithm.
+//
+// The numbers are analytical estimates, useful for comparing configurations.
+// Actual speed will depend on the CPU features available.
+type CostModel struct {
+	// EncodeMulsPerByte is the number of multiplications per byte of input data when encoding.
+	EncodeMulsPerByte float64
+
+	// EncodeBytesPerByte is the number of bytes moved per byte of input data when encoding.
+	EncodeBytesPerByte float64
+
+	// ReconstructMulsPerByte is the average number of multiplications
+	// per byte of a reconstructed shard, when a single shard is lost.
+	ReconstructMulsPerByte float64
+
+	// ReconstructBytesPerByte is the average number of bytes moved
+	// per byte of a reconstructed shard, when a single shard is lost.
+	ReconstructBytesPerByte float64
+}
+
+// CostModel returns the estimated costs for the encoder.
+//
+// Encoding cost is based on the number of non-zero coefficients in the coding matrix.
+// For reconstruction, a lost data shard is assumed to be recovered from the
+// parity row with the fewest non-zero coefficients that covers it,
+// so locally reconstructible codes created with WithCustomMatrix are estimated correctly.
+func (r *reedSolomon) CostModel() CostModel {
+	var c CostModel
+	if r.parityShards == 0 {
+		return c
+	}
+	d, p := float64(r.dataShards), float64(r.parityShards)
+
+	var coeffs int
+	for _, row := range r.m[r.dataShards:] {
+		coeffs += nonZero(row)
+	}
+	c.EncodeMulsPerByte = float64(coeffs) / d
+	c.EncodeBytesPerByte = (d + p) / d
+
+	var muls, moved int
+	for i := 0; i < r.totalShards; i++ {
+		if i >= r.dataShards {
+			// All data is available, so recompute the parity row.
+			n := nonZero(r.m[i])
+			muls += n
+			moved += n + 1
+			continue
+		}
+		// Solve the sparsest parity row covering i for data shard i.
+		best := r.dataShards
+		for _, row := range r.m[r.dataShards:] {
+			if row[i] != 0 && nonZero(row) < best {
+				best = nonZero(row)
+			}
+		}
+		// Read the parity and the other shards of the row.
+		muls += best
+		moved += best + 1
+	}
+	c.ReconstructMulsPerByte = float64(muls) / float64(r.totalShards)
+	c.ReconstructBytesPerByte = float64(moved) / float64(r.totalShards)
+	return c
+}
+
+// nonZero returns the number of non-zero values in row.
+func nonZero(row []byte) int {
+	n := 0
+	for _, v := range row {
+		if v != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// leopardCostModel returns the estimated cost for Leopard encoders.
+// Both GF(2^8) and GF(2^16) use the same transforms,
+// where each butterfly performs one multiplication and
+// reads and writes two work buffers.
+func leopardCostModel(dataShards, parityShards int) CostModel {
+	// butterflies returns the number of butterflies in an FFT of size n.
+	butterflies := func(n int) int {
+		return n / 2 * (bits.Len(uint(n)) - 1)
+	}
+	d, p := float64(dataShards), float64(parityShards)
+
+	// Encoding does an IFFT for each group of m data shards,
+	// followed by a single FFT on the parity.
+	m := ceilPow2(parityShards)
+	groups := (dataShards + m - 1) / m
+	bf := float64((groups + 1) * butterflies(m))
+	c := CostModel{
+		EncodeMulsPerByte:  bf / d,
+		EncodeBytesPerByte: (d + p + 4*bf) / d,
+	}
+
+	// Decoding multiplies all inputs by the error locator,
+	// does an IFFT and an FFT of size n, and finally
+	// multiplies the recovered shard.
+	n := ceilPow2(m + dataShards)
+	bf = float64(2 * butterflies(n))
+	inputs := float64(m + dataShards - 1)
+	c.ReconstructMulsPerByte = inputs + bf + 1
+	c.ReconstructBytesPerByte = inputs + 4*bf + 1
+	return c
+}
@@ -0,0 +1,50 @@
+package reedsolomon
+
+import "testing"
+
+func TestCostModel(t *testing.T) {
+	enc, err := New(10, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := enc.(CostModeler).CostModel()
+	if c.EncodeMulsPerByte != 4 || c.EncodeBytesPerByte != 1.4 {
+		t.Errorf("unexpected encode cost: %+v", c)
+	}
+	if c.ReconstructMulsPerByte != 10 || c.ReconstructBytesPerByte != 11 {
+		t.Errorf("unexpected reconstruct cost: %+v", c)
+	}
+
+	// Local groups of 5 data shards, with 2 global parities.
+	lrc := [][]byte{
+		{1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 1, 1, 1, 1, 1},
+	}
+	global, err := buildMatrix(10, 12, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lrc = append(lrc, global[10:]...)
+	enc, err = New(10, 4, testOptions(WithCustomMatrix(lrc))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := enc.(CostModeler).CostModel()
+	if l.EncodeMulsPerByte != 3 {
+		t.Errorf("unexpected LRC encode cost: %+v", l)
+	}
+	if l.ReconstructMulsPerByte >= c.ReconstructMulsPerByte {
+		t.Errorf("LRC reconstruct cost %+v should be less than %+v", l, c)
+	}
+
+	for _, opt := range []Option{WithLeopardGF(true), WithLeopardGF16(true)} {
+		enc, err = New(10, 4, testOptions(opt)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := enc.(CostModeler).CostModel()
+		if c.EncodeMulsPerByte <= 0 || c.EncodeBytesPerByte <= 1 || c.ReconstructMulsPerByte <= 0 || c.ReconstructBytesPerByte <= 1 {
+			t.Errorf("unexpected leopard cost: %+v", c)
+		}
+	}
+}
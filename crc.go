@@ -0,0 +1,158 @@
+package reedsolomon
+
+import (
+	"hash/crc32"
+	"hash/crc64"
+)
+
+// ShardCRC32 returns the CRC-32 of each shard computed with tab.
+// Missing shards get a checksum of 0.
+//
+// Combined with JoinCRC32, this allows verifying the joined object
+// against checksums stored with the shards.
+func ShardCRC32(shards [][]byte, tab *crc32.Table) []uint32 {
+	res := make([]uint32, len(shards))
+	for i, shard := range shards {
+		if len(shard) > 0 {
+			res[i] = crc32.Checksum(shard, tab)
+		}
+	}
+	return res
+}
+
+// ShardCRC64 returns the CRC-64 of each shard computed with tab.
+// Missing shards get a checksum of 0.
+func ShardCRC64(shards [][]byte, tab *crc64.Table) []uint64 {
+	res := make([]uint64, len(shards))
+	for i, shard := range shards {
+		if len(shard) > 0 {
+			res[i] = crc64.Checksum(shard, tab)
+		}
+	}
+	return res
+}
+
+// CRC32Combine returns the CRC-32 of A followed by B,
+// given crc1, the CRC-32 of A, and crc2, the CRC-32 of B,
+// and the length of B.
+// The polynomial must be in reversed notation, like crc32.IEEE and crc32.Castagnoli.
+func CRC32Combine(poly uint32, crc1, crc2 uint32, len2 int64) uint32 {
+	return uint32(crcZeros(uint64(poly), 32, len2, false).times(uint64(crc1))) ^ crc2
+}
+
+// CRC64Combine returns the CRC-64 of A followed by B,
+// given crc1, the CRC-64 of A, and crc2, the CRC-64 of B,
+// and the length of B.
+// The polynomial must be in reversed notation, like crc64.ISO and crc64.ECMA.
+func CRC64Combine(poly uint64, crc1, crc2 uint64, len2 int64) uint64 {
+	return crcZeros(poly, 64, len2, false).times(crc1) ^ crc2
+}
+
+// JoinCRC32 returns the CRC-32 of the outSize bytes Join would write,
+// given the CRC-32 of each data shard of shardSize bytes.
+// Additional checksums, like those of parity shards, are ignored.
+//
+// The data shards must be zero after outSize bytes, as returned by Split.
+func JoinCRC32(poly uint32, crcs []uint32, shardSize, outSize int64) (uint32, error) {
+	c := make([]uint64, len(crcs))
+	for i, crc := range crcs {
+		c[i] = uint64(crc)
+	}
+	crc, err := joinCRC(uint64(poly), 32, c, shardSize, outSize)
+	return uint32(crc), err
+}
+
+// JoinCRC64 returns the CRC-64 of the outSize bytes Join would write,
+// given the CRC-64 of each data shard of shardSize bytes.
+// Additional checksums, like those of parity shards, are ignored.
+//
+// The data shards must be zero after outSize bytes, as returned by Split.
+func JoinCRC64(poly uint64, crcs []uint64, shardSize, outSize int64) (uint64, error) {
+	return joinCRC(poly, 64, crcs, shardSize, outSize)
+}
+
+func joinCRC(poly uint64, width int, crcs []uint64, shardSize, outSize int64) (uint64, error) {
+	if shardSize <= 0 || outSize < 0 {
+		return 0, ErrInvalidInput
+	}
+	if outSize > int64(len(crcs))*shardSize {
+		return 0, ErrShortData
+	}
+	mask := uint64(1)<<(width-1)<<1 - 1
+	var res uint64
+	for _, crc := range crcs {
+		if outSize == 0 {
+			break
+		}
+		n := shardSize
+		if n > outSize {
+			// Remove the trailing zeros from the checksum.
+			// Appending zeros gives crc' = zeros(crc) ^ crc(zeros),
+			// so the original checksum is unzeros(crc' ^ crc(zeros)).
+			z := shardSize - outSize
+			n = outSize
+			zeroCRC := crcZeros(poly, width, z, false).times(mask) ^ mask
+			crc = crcZeros(poly, width, z, true).times(crc ^ zeroCRC)
+		}
+		res = crcZeros(poly, width, n, false).times(res) ^ crc
+		outSize -= n
+	}
+	return res, nil
+}
+
+// crcMatrix is a matrix over GF(2) operating on CRC values.
+// Each element is a column.
+type crcMatrix []uint64
+
+// times returns the matrix multiplied by v.
+func (m crcMatrix) times(v uint64) uint64 {
+	var sum uint64
+	for i := 0; v != 0; i, v = i+1, v>>1 {
+		if v&1 != 0 {
+			sum ^= m[i]
+		}
+	}
+	return sum
+}
+
+// mul returns the matrix product m * n.
+func (m crcMatrix) mul(n crcMatrix) crcMatrix {
+	res := make(crcMatrix, len(n))
+	for i, col := range n {
+		res[i] = m.times(col)
+	}
+	return res
+}
+
+// crcZeros returns the matrix that updates a CRC register for n zero bytes.
+// If inverse is set, the matrix that reverses this is returned.
+func crcZeros(poly uint64, width int, n int64, inverse bool) crcMatrix {
+	// Build the matrix for a single zero bit.
+	top := uint64(1) << (width - 1)
+	op := make(crcMatrix, width)
+	for i := range op {
+		v := uint64(1) << i
+		switch {
+		case inverse && v&top != 0:
+			op[i] = (v^poly)<<1 | 1
+		case inverse:
+			op[i] = v << 1
+		case v&1 != 0:
+			op[i] = v>>1 ^ poly
+		default:
+			op[i] = v >> 1
+		}
+	}
+	// Raise it to the power of 8*n.
+	res := make(crcMatrix, width)
+	for i := range res {
+		res[i] = uint64(1) << i
+	}
+	for bits := uint64(n) * 8; bits > 0; bits >>= 1 {
+		if bits&1 != 0 {
+			res = op.mul(res)
+		}
+		op = op.mul(op)
+	}
+	return res
+}
@@ -0,0 +1,73 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"hash/crc32"
+	"hash/crc64"
+	"testing"
+)
+
+func TestCRCCombine(t *testing.T) {
+	data := make([]byte, 1000)
+	fillRandom(data)
+	for _, split := range []int{0, 1, 7, 500, 999, 1000} {
+		a, b := data[:split], data[split:]
+		for _, poly := range []uint32{crc32.IEEE, crc32.Castagnoli, crc32.Koopman} {
+			tab := crc32.MakeTable(poly)
+			got := CRC32Combine(poly, crc32.Checksum(a, tab), crc32.Checksum(b, tab), int64(len(b)))
+			if want := crc32.Checksum(data, tab); got != want {
+				t.Errorf("crc32 poly %x split %d: got %x, want %x", poly, split, got, want)
+			}
+		}
+		for _, poly := range []uint64{crc64.ISO, crc64.ECMA} {
+			tab := crc64.MakeTable(poly)
+			got := CRC64Combine(poly, crc64.Checksum(a, tab), crc64.Checksum(b, tab), int64(len(b)))
+			if want := crc64.Checksum(data, tab); got != want {
+				t.Errorf("crc64 poly %x split %d: got %x, want %x", poly, split, got, want)
+			}
+		}
+	}
+}
+
+func TestJoinCRC(t *testing.T) {
+	enc, err := New(5, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab32 := crc32.MakeTable(crc32.Castagnoli)
+	tab64 := crc64.MakeTable(crc64.ECMA)
+	for _, size := range []int{1, 63, 64, 1000, 12345} {
+		data := make([]byte, size)
+		fillRandom(data)
+		shards, err := enc.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		var joined bytes.Buffer
+		if err := enc.Join(&joined, shards, size); err != nil {
+			t.Fatal(err)
+		}
+		shardSize := int64(len(shards[0]))
+
+		got32, err := JoinCRC32(crc32.Castagnoli, ShardCRC32(shards, tab32), shardSize, int64(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := crc32.Checksum(joined.Bytes(), tab32); got32 != want {
+			t.Errorf("size %d: got crc32 %x, want %x", size, got32, want)
+		}
+		got64, err := JoinCRC64(crc64.ECMA, ShardCRC64(shards, tab64), shardSize, int64(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := crc64.Checksum(joined.Bytes(), tab64); got64 != want {
+			t.Errorf("size %d: got crc64 %x, want %x", size, got64, want)
+		}
+	}
+	if _, err := JoinCRC32(crc32.Castagnoli, make([]uint32, 2), 10, 21); err != ErrShortData {
+		t.Errorf("want ErrShortData, got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// shardSpan is a byte range [off, end) within a shard.
+type shardSpan struct {
+	shard    int
+	off, end int64
+}
+
+// DegradedRead reads length bytes at objOffset from an object that was split
+// into shards of shardSize bytes each, as done by Split.
+//
+// There must be a source for each shard, in shard order.
+// Sources of missing shards should be nil.
+// Only the byte ranges needed to serve the request are read.
+// If a requested data shard is missing, only the matching range of
+// DataShards surviving shards is read and reconstructed,
+// instead of the full shards.
+//
+// If too few shards are available, ErrTooFewShards is returned.
+// If the range is outside the object, ErrInvalidInput is returned.
+func DegradedRead(enc Encoder, shards []io.ReaderAt, shardSize, objOffset, length int64) ([]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	dataShards := ext.DataShards()
+	if len(shards) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	if shardSize <= 0 || shardSize%int64(ext.ShardSizeMultiple()) != 0 {
+		return nil, ErrInvalidShardSize
+	}
+	if objOffset < 0 || length < 0 || objOffset+length > shardSize*int64(dataShards) {
+		return nil, ErrInvalidInput
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	// Find the ranges of the data shards we want.
+	var want, missing []shardSpan
+	for off := objOffset; off < objOffset+length; {
+		s := shardSpan{shard: int(off / shardSize), off: off % shardSize}
+		s.end = shardSize
+		if rem := objOffset + length - off; rem < s.end-s.off {
+			s.end = s.off + rem
+		}
+		want = append(want, s)
+		if shards[s.shard] == nil {
+			missing = append(missing, s)
+		}
+		off += s.end - s.off
+	}
+
+	// Find the ranges to reconstruct.
+	// Reconstruction works on aligned ranges, so expand and merge them.
+	align := int64(ext.ShardSizeMultiple())
+	var recon []shardSpan
+	for _, s := range missing {
+		s.off -= s.off % align
+		s.end += (align - s.end%align) % align
+		recon = append(recon, s)
+	}
+	sort.Slice(recon, func(i, j int) bool { return recon[i].off < recon[j].off })
+	merged := recon[:0]
+	for _, s := range recon {
+		if n := len(merged); n > 0 && s.off <= merged[n-1].end {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	recon = merged
+
+	// Collect the reads needed, and perform each once.
+	var survivors []int
+	for i, src := range shards {
+		if src != nil && len(survivors) < dataShards {
+			survivors = append(survivors, i)
+		}
+	}
+	if len(recon) > 0 && len(survivors) < dataShards {
+		return nil, ErrTooFewShards
+	}
+	var reads []shardSpan
+	for _, s := range want {
+		if shards[s.shard] != nil {
+			reads = append(reads, s)
+		}
+	}
+	for _, s := range recon {
+		for _, idx := range survivors {
+			reads = append(reads, shardSpan{shard: idx, off: s.off, end: s.end})
+		}
+	}
+	buffers, err := readSpans(shards, reads)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reconstruct the missing ranges.
+	rebuilt := make(map[shardSpan][]byte)
+	for _, s := range recon {
+		sub := make([][]byte, len(shards))
+		for _, idx := range survivors {
+			sub[idx] = buffers.get(shardSpan{shard: idx, off: s.off, end: s.end})
+		}
+		required := make([]bool, len(shards))
+		for _, m := range missing {
+			if m.off < s.end && m.end > s.off {
+				required[m.shard] = true
+			}
+		}
+		if err := enc.ReconstructSome(sub, required); err != nil {
+			return nil, err
+		}
+		for i, req := range required {
+			if req {
+				rebuilt[shardSpan{shard: i, off: s.off, end: s.end}] = sub[i]
+			}
+		}
+	}
+
+	dst := make([]byte, 0, length)
+	for _, s := range want {
+		if shards[s.shard] != nil {
+			dst = append(dst, buffers.get(s)...)
+			continue
+		}
+		for r, b := range rebuilt {
+			if r.shard == s.shard && r.off <= s.off && r.end >= s.end {
+				dst = append(dst, b[s.off-r.off:s.end-r.off]...)
+				break
+			}
+		}
+	}
+	return dst, nil
+}
+
+// JoinRange writes length bytes at off of the data segment of the shards to dst,
+// like Join followed by skipping to off.
+//
+// Only the data shards holding the range are used. If a shard holding part of
+// the range is missing, only the matching range of the other shards is
+// reconstructed, as done by DegradedRead, which can be used for shards
+// that are not in memory.
+// Missing shards should be nil or zero-length. shards is not modified.
+//
+// If too few shards are available, ErrTooFewShards is returned.
+// If the range is outside the data segment, ErrInvalidInput is returned.
+func JoinRange(enc Encoder, dst io.Writer, shards [][]byte, off, length int64) error {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	size := int64(shardSize(shards))
+	if off < 0 || length < 0 || off+length > size*int64(ext.DataShards()) {
+		return ErrInvalidInput
+	}
+	if length == 0 {
+		return nil
+	}
+
+	first, last := int(off/size), int((off+length-1)/size)
+	for _, shard := range shards[first : last+1] {
+		if len(shard) == 0 {
+			// Reconstruct the missing parts of the range.
+			src := make([]io.ReaderAt, len(shards))
+			for i, shard := range shards {
+				if len(shard) != 0 {
+					src[i] = bytes.NewReader(shard)
+				}
+			}
+			data, err := DegradedRead(enc, src, size, off, length)
+			if err != nil {
+				return err
+			}
+			_, err = dst.Write(data)
+			return err
+		}
+	}
+	for i := first; i <= last && length > 0; i++ {
+		b := shards[i][off-int64(i)*size:]
+		if int64(len(b)) > length {
+			b = b[:length]
+		}
+		if _, err := dst.Write(b); err != nil {
+			return err
+		}
+		off += int64(len(b))
+		length -= int64(len(b))
+	}
+	return nil
+}
+
+// ReconstructRange returns length bytes at off of shard shardIdx.
+// If the shard is missing, only the requested range is reconstructed
+// from the same range of the other shards, instead of the whole shard.
+// If the shard is present, the range is returned without copying.
+//
+// Missing shards should be nil or zero-length. shards is not modified.
+// For the default encoder, the range is computed directly with a single
+// row of coefficients, so no other missing shards are reconstructed.
+// Other encoders reconstruct the range aligned to the shard size multiple,
+// and encoders using WithClayCode or WithPiggyback return ErrNotSupported.
+//
+// If too few shards are available, ErrTooFewShards is returned.
+// If the range is outside the shard, ErrInvalidInput is returned.
+func ReconstructRange(enc Encoder, shards [][]byte, shardIdx int, off, length int) ([]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	if shardIdx < 0 || shardIdx >= len(shards) {
+		return nil, ErrInvShardNum
+	}
+	if err := checkShards(shards, true); err != nil {
+		return nil, err
+	}
+	size := shardSize(shards)
+	if off < 0 || length < 0 || off+length > size {
+		return nil, ErrInvalidInput
+	}
+	if len(shards[shardIdx]) != 0 {
+		return shards[shardIdx][off : off+length], nil
+	}
+	present := 0
+	for _, shard := range shards {
+		if len(shard) != 0 {
+			present++
+		}
+	}
+	if present < ext.DataShards() {
+		return nil, ErrTooFewShards
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+	if r, ok := enc.(*reedSolomon); ok {
+		return r.reconstructRange(shards, shardIdx, off, length)
+	}
+	if !migratable(enc) {
+		return nil, ErrNotSupported
+	}
+
+	// Reconstruct the aligned range containing the request.
+	align := ext.ShardSizeMultiple()
+	start := off - off%align
+	end := off + length + (align-(off+length)%align)%align
+	if end > size {
+		end = size
+	}
+	sub := make([][]byte, len(shards))
+	for i, shard := range shards {
+		if len(shard) != 0 {
+			sub[i] = shard[start:end]
+		}
+	}
+	required := make([]bool, len(shards))
+	required[shardIdx] = true
+	if err := enc.ReconstructSome(sub, required); err != nil {
+		return nil, err
+	}
+	return sub[shardIdx][off-start : off-start+length], nil
+}
+
+// reconstructRange computes length bytes at off of a missing shard
+// as a single linear combination of the decoding input shards.
+func (r *reedSolomon) reconstructRange(shards [][]byte, idx, off, length int) ([]byte, error) {
+	subShards, decode, err := r.decodeMatrix(shards)
+	if err != nil {
+		return nil, err
+	}
+	var row []byte
+	if idx < r.dataShards {
+		row = decode[idx]
+	} else {
+		// The parity row applied to the decoded data shards.
+		mul := r.o.gf().mul
+		parity := r.parity[idx-r.dataShards]
+		row = make([]byte, r.dataShards)
+		for j := range row {
+			var v byte
+			for t, c := range parity {
+				v ^= mul[c][decode[t][j]]
+			}
+			row[j] = v
+		}
+	}
+	in := make([][]byte, len(subShards))
+	for i, shard := range subShards {
+		in[i] = shard[off : off+length]
+	}
+	out := AllocAligned(1, length)
+	r.codeSomeShards([][]byte{row}, in, out, length)
+	return out[0], nil
+}
+
+// spanBuffers contains the data read for a set of shard ranges.
+type spanBuffers map[int][]spanBuffer
+
+type spanBuffer struct {
+	shardSpan
+	data []byte
+}
+
+// get returns the data for s, which must be contained in a read span.
+func (b spanBuffers) get(s shardSpan) []byte {
+	for _, buf := range b[s.shard] {
+		if buf.off <= s.off && buf.end >= s.end {
+			return buf.data[s.off-buf.off : s.end-buf.off]
+		}
+	}
+	return nil
+}
+
+// readSpans reads the requested spans.
+// Overlapping or adjacent spans on the same shard are read with a single call.
+func readSpans(shards []io.ReaderAt, spans []shardSpan) (spanBuffers, error) {
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].shard != spans[j].shard {
+			return spans[i].shard < spans[j].shard
+		}
+		return spans[i].off < spans[j].off
+	})
+	res := make(spanBuffers)
+	var cur *shardSpan
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		buf := make([]byte, cur.end-cur.off)
+		n, err := shards[cur.shard].ReadAt(buf, cur.off)
+		if n == len(buf) {
+			err = nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		res[cur.shard] = append(res[cur.shard], spanBuffer{shardSpan: *cur, data: buf})
+		cur = nil
+		return nil
+	}
+	for i := range spans {
+		s := spans[i]
+		if cur != nil && cur.shard == s.shard && s.off <= cur.end {
+			if s.end > cur.end {
+				cur.end = s.end
+			}
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		cur = &s
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
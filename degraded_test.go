@@ -0,0 +1,157 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// countingReaderAt counts the bytes read through it.
+type countingReaderAt struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestDegradedRead(t *testing.T) {
+	for _, opts := range [][]Option{testOptions(), {WithLeopardGF16(true)}, {WithLeopardGF(true)}} {
+		const dataShards, parityShards = 5, 3
+		enc, err := New(dataShards, parityShards, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, 50000)
+		fillRandom(data)
+		shards, err := enc.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		shardSize := int64(len(shards[0]))
+
+		tests := []struct {
+			off, length int64
+			missing     []int
+		}{
+			{off: 0, length: 100},
+			{off: 0, length: int64(len(data))},
+			{off: 123, length: 1000, missing: []int{0}},
+			{off: shardSize - 10, length: 20, missing: []int{0, 1}},
+			{off: shardSize + 77, length: 3*shardSize - 200, missing: []int{1, 3, 6}},
+			{off: 4*shardSize + 1, length: 500, missing: []int{4, 5, 7}},
+			{off: 10, length: 0, missing: []int{0}},
+		}
+		for _, test := range tests {
+			srcs := make([]io.ReaderAt, len(shards))
+			counters := make([]*countingReaderAt, len(shards))
+			for i := range shards {
+				counters[i] = &countingReaderAt{r: bytes.NewReader(shards[i])}
+				srcs[i] = counters[i]
+			}
+			for _, m := range test.missing {
+				srcs[m] = nil
+			}
+			got, err := DegradedRead(enc, srcs, shardSize, test.off, test.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := data[test.off : test.off+test.length]; !bytes.Equal(got, want) {
+				t.Errorf("offset %d, length %d, missing %v: data mismatch", test.off, test.length, test.missing)
+			}
+
+			// The same range from shards in memory.
+			mem := append([][]byte(nil), shards...)
+			for _, m := range test.missing {
+				mem[m] = nil
+			}
+			var buf bytes.Buffer
+			if err := JoinRange(enc, &buf, mem, test.off, test.length); err != nil {
+				t.Fatal(err)
+			}
+			if want := data[test.off : test.off+test.length]; !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("JoinRange offset %d, length %d, missing %v: data mismatch", test.off, test.length, test.missing)
+			}
+			var total int64
+			for _, c := range counters {
+				if c.n > shardSize {
+					t.Errorf("offset %d, length %d: shard read %d bytes, more than shard size", test.off, test.length, c.n)
+				}
+				total += c.n
+			}
+			if len(test.missing) == 0 && total != test.length {
+				t.Errorf("offset %d, length %d: read %d bytes, want %d", test.off, test.length, total, test.length)
+			}
+		}
+
+		// Too few shards.
+		srcs := make([]io.ReaderAt, len(shards))
+		for i := range shards {
+			srcs[i] = bytes.NewReader(shards[i])
+		}
+		srcs[0], srcs[1], srcs[2], srcs[3] = nil, nil, nil, nil
+		if _, err := DegradedRead(enc, srcs, shardSize, 0, 10); err != ErrTooFewShards {
+			t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+		}
+		mem := append([][]byte(nil), shards...)
+		mem[0], mem[1], mem[2], mem[3] = nil, nil, nil, nil
+		if err := JoinRange(enc, io.Discard, mem, 0, 10); err != ErrTooFewShards {
+			t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+		}
+		if err := JoinRange(enc, io.Discard, shards, 1, 5*shardSize); err != ErrInvalidInput {
+			t.Errorf("expected %v, got %v", ErrInvalidInput, err)
+		}
+		// Unneeded missing shards are fine.
+		if _, err := DegradedRead(enc, srcs, shardSize, 4*shardSize, 10); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if _, err := DegradedRead(enc, srcs, shardSize, 4*shardSize, shardSize+1); err != ErrInvalidInput {
+			t.Errorf("expected %v, got %v", ErrInvalidInput, err)
+		}
+	}
+}
+
+func TestReconstructRange(t *testing.T) {
+	for _, opts := range [][]Option{testOptions(), testOptions(WithFieldRepresentation(FieldRijndael)), {WithLeopardGF16(true)}} {
+		enc, err := New(6, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(9984)
+		for _, shard := range shards[:6] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		damaged := append([][]byte(nil), shards...)
+		damaged[1], damaged[4], damaged[7] = nil, nil, nil
+		for _, idx := range []int{1, 4, 7, 0} {
+			for _, r := range [][2]int{{0, 9984}, {123, 456}, {9983, 1}, {64, 0}} {
+				got, err := ReconstructRange(enc, damaged, idx, r[0], r[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, shards[idx][r[0]:r[0]+r[1]]) {
+					t.Fatalf("shard %d range %v mismatch", idx, r)
+				}
+			}
+		}
+		if damaged[1] != nil || damaged[7] != nil {
+			t.Fatal("shards were modified")
+		}
+		if _, err := ReconstructRange(enc, damaged, 1, 9000, 985); err != ErrInvalidInput {
+			t.Errorf("expected %v, got %v", ErrInvalidInput, err)
+		}
+		damaged[2] = nil
+		if _, err := ReconstructRange(enc, damaged, 1, 0, 10); err != ErrTooFewShards {
+			t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package reedsolomon
+
+import "hash"
+
+// digestBlockSize is the number of bytes of each shard encoded
+// and hashed at a time by EncodeDigests.
+const digestBlockSize = 32 << 10
+
+// EncodeDigests encodes parity shards like Encode, and also returns
+// a digest of every shard, computed while encoding.
+//
+// The shards are encoded in blocks of 32KB, and each block
+// is hashed right after it has been encoded, while it is still in cache,
+// instead of reading all shards again after encoding.
+// Encoders where ranges of the shards cannot be encoded independently,
+// such as WithClayCode, encode all shards before hashing.
+//
+// A new hash is created for each shard using newHash.
+// The returned slice contains the digests of the data shards
+// followed by the digests of the parity shards.
+func EncodeDigests(enc Encoder, shards [][]byte, newHash func() hash.Hash) ([][]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return nil, err
+	}
+	size := len(shards[0])
+	block := size
+	if migratable(enc) {
+		mult := ext.ShardSizeMultiple()
+		block = (digestBlockSize + mult - 1) / mult * mult
+	}
+
+	hashes := make([]hash.Hash, len(shards))
+	for i := range hashes {
+		hashes[i] = newHash()
+	}
+	sub := make([][]byte, len(shards))
+	for off := 0; off < size; off += block {
+		end := off + block
+		if end > size {
+			end = size
+		}
+		for i, shard := range shards {
+			sub[i] = shard[off:end]
+		}
+		if err := enc.Encode(sub); err != nil {
+			return nil, err
+		}
+		for i, h := range hashes {
+			h.Write(sub[i])
+		}
+	}
+	digests := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		digests[i] = h.Sum(nil)
+	}
+	return digests, nil
+}
@@ -0,0 +1,42 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestEncodeDigests(t *testing.T) {
+	for _, opts := range [][]Option{
+		testOptions(),
+		{WithLeopardGF16(true)},
+		{WithClayCode(true)},
+	} {
+		enc, err := New(5, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mult := enc.(Extensions).ShardSizeMultiple()
+		size := (3*digestBlockSize + 1000 + mult - 1) / mult * mult
+		shards := enc.(Extensions).AllocAligned(size)
+		for _, shard := range shards[:5] {
+			fillRandom(shard)
+		}
+		digests, err := EncodeDigests(enc, shards, sha256.New)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := enc.Verify(shards); !ok || err != nil {
+			t.Fatalf("verification failed: %v", err)
+		}
+		for i, shard := range shards {
+			want := sha256.Sum256(shard)
+			if !bytes.Equal(digests[i], want[:]) {
+				t.Fatalf("shard %d: digest mismatch", i)
+			}
+		}
+		if _, err := EncodeDigests(enc, shards[:7], sha256.New); err != ErrTooFewShards {
+			t.Errorf("want ErrTooFewShards, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package reedsolomon
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// FECFrameScheme implements the Simple Reed-Solomon FEC Scheme for FECFRAME,
+// defined in RFC 6865, using the codes over GF(2^8) and GF(2^4) of RFC 5510.
+//
+// A sender collects Application Data Units (ADUs) in a FECFrameSourceBlock,
+// sends each ADU with its Explicit Source FEC Payload ID, and then sends
+// the repair symbols with their Repair FEC Payload IDs.
+// A receiver adds what arrives to a FECFrameDecoder, which returns the ADUs
+// of the block once enough symbols have been received.
+//
+// Each ADU is stored in the source block as an ADU Information (ADUI):
+// the flow ID (1 byte), the ADU length (2 bytes) and the ADU,
+// padded with zeros to a multiple of the encoding symbol length.
+type FECFrameScheme struct {
+	field      *fecField
+	symbolSize int
+	o          options
+}
+
+// FECFramePayloadID is a Source or Repair FEC Payload ID.
+type FECFramePayloadID struct {
+	// SBN is the source block number, which has 32-m bits.
+	SBN uint32
+
+	// ESI is the encoding symbol ID, which has m bits.
+	// Source symbols have ESIs below the source block length,
+	// and repair symbols the ESIs above.
+	ESI int
+
+	// SourceBlockLength is the number of source symbols in the block.
+	// It is only included in Repair FEC Payload IDs.
+	SourceBlockLength int
+}
+
+// FECFrameADU is an Application Data Unit with its flow ID.
+type FECFrameADU struct {
+	FlowID byte
+	Data   []byte
+}
+
+// ErrInvalidPayloadID is returned when a FEC Payload ID or FEC Scheme-Specific
+// Information cannot be encoded or decoded.
+var ErrInvalidPayloadID = errors.New("invalid FEC payload ID")
+
+const (
+	// FECFrameSourceIDSize and FECFrameRepairIDSize are the sizes of
+	// the Explicit Source and Repair FEC Payload IDs.
+	FECFrameSourceIDSize = 4
+	FECFrameRepairIDSize = 6
+
+	// fecframeADUIHeader is the size of the flow ID and length before each ADU.
+	fecframeADUIHeader = 3
+)
+
+// NewFECFrameScheme returns the scheme for GF(2^m), where m is 4 or 8,
+// and encoding symbols of symbolSize bytes.
+// Only the CPU feature options are used from opts.
+func NewFECFrameScheme(m, symbolSize int, opts ...Option) (*FECFrameScheme, error) {
+	field := fecFieldFor(m)
+	if field == nil {
+		return nil, ErrNotSupported
+	}
+	if symbolSize <= 0 || symbolSize > 65535 {
+		return nil, ErrInvalidShardSize
+	}
+	o := defaultOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &FECFrameScheme{field: field, symbolSize: symbolSize, o: o}, nil
+}
+
+// ParseFECFrameSchemeInfo returns the scheme described by FEC Scheme-Specific
+// Information, as returned by SchemeInfo.
+func ParseFECFrameSchemeInfo(info []byte, opts ...Option) (*FECFrameScheme, error) {
+	if len(info) != 3 {
+		return nil, ErrInvalidPayloadID
+	}
+	return NewFECFrameScheme(int(info[2]), int(binary.BigEndian.Uint16(info)), opts...)
+}
+
+// SchemeInfo returns the FEC Scheme-Specific Information, which contains the
+// encoding symbol length (16 bits) followed by m (8 bits).
+func (s *FECFrameScheme) SchemeInfo() []byte {
+	return []byte{byte(s.symbolSize >> 8), byte(s.symbolSize), byte(s.field.m)}
+}
+
+// SymbolSize returns the encoding symbol length.
+func (s *FECFrameScheme) SymbolSize() int {
+	return s.symbolSize
+}
+
+// MaxSymbols returns the maximum number of source and repair symbols in a block, 2^m-1.
+func (s *FECFrameScheme) MaxSymbols() int {
+	return s.field.order
+}
+
+// packID returns the SBN and ESI as a 32 bit value.
+func (s *FECFrameScheme) packID(id FECFramePayloadID) (uint32, error) {
+	m := uint(s.field.m)
+	if uint64(id.SBN) >= 1<<(32-m) || id.ESI < 0 || id.ESI >= 1<<m {
+		return 0, ErrInvalidPayloadID
+	}
+	return id.SBN<<m | uint32(id.ESI), nil
+}
+
+// unpackID returns the SBN and ESI of a 32 bit value.
+func (s *FECFrameScheme) unpackID(v uint32) FECFramePayloadID {
+	m := uint(s.field.m)
+	return FECFramePayloadID{SBN: v >> m, ESI: int(v & (1<<m - 1))}
+}
+
+// AppendSourceID appends the Explicit Source FEC Payload ID to dst.
+func (s *FECFrameScheme) AppendSourceID(dst []byte, id FECFramePayloadID) ([]byte, error) {
+	v, err := s.packID(id)
+	if err != nil {
+		return dst, err
+	}
+	return binary.BigEndian.AppendUint32(dst, v), nil
+}
+
+// AppendRepairID appends the Repair FEC Payload ID to dst.
+func (s *FECFrameScheme) AppendRepairID(dst []byte, id FECFramePayloadID) ([]byte, error) {
+	v, err := s.packID(id)
+	if err != nil {
+		return dst, err
+	}
+	if id.SourceBlockLength <= 0 || id.SourceBlockLength > 65535 {
+		return dst, ErrInvalidPayloadID
+	}
+	dst = binary.BigEndian.AppendUint32(dst, v)
+	return binary.BigEndian.AppendUint16(dst, uint16(id.SourceBlockLength)), nil
+}
+
+// ParseSourceID parses the Explicit Source FEC Payload ID at the start of b,
+// and returns it with the rest of b.
+func (s *FECFrameScheme) ParseSourceID(b []byte) (FECFramePayloadID, []byte, error) {
+	if len(b) < FECFrameSourceIDSize {
+		return FECFramePayloadID{}, b, ErrInvalidPayloadID
+	}
+	return s.unpackID(binary.BigEndian.Uint32(b)), b[FECFrameSourceIDSize:], nil
+}
+
+// ParseRepairID parses the Repair FEC Payload ID at the start of b,
+// and returns it with the rest of b.
+func (s *FECFrameScheme) ParseRepairID(b []byte) (FECFramePayloadID, []byte, error) {
+	if len(b) < FECFrameRepairIDSize {
+		return FECFramePayloadID{}, b, ErrInvalidPayloadID
+	}
+	id := s.unpackID(binary.BigEndian.Uint32(b))
+	id.SourceBlockLength = int(binary.BigEndian.Uint16(b[4:]))
+	if id.SourceBlockLength == 0 || id.ESI < id.SourceBlockLength {
+		return id, b, ErrInvalidPayloadID
+	}
+	return id, b[FECFrameRepairIDSize:], nil
+}
+
+// generator returns the columns of the systematic generator matrix of RFC 5510
+// for k source symbols, from column k up to column n-1.
+// The generator matrix is the k×n Vandermonde matrix with elements α^(i*j),
+// multiplied by the inverse of its first k columns.
+func (s *FECFrameScheme) generator(k, n int) ([][]byte, error) {
+	f := s.field
+	vm := make([][]byte, k)
+	for i := range vm {
+		vm[i] = make([]byte, n)
+		for j := range vm[i] {
+			vm[i][j] = f.exp[i*j%f.order]
+		}
+	}
+	left := make([][]byte, k)
+	for i := range left {
+		left[i] = vm[i][:k]
+	}
+	inv, err := f.invert(left)
+	if err != nil {
+		return nil, err
+	}
+	// Column j-k of the result is column j of inv × vm.
+	cols := make([][]byte, n-k)
+	for j := range cols {
+		cols[j] = make([]byte, k)
+		for i := 0; i < k; i++ {
+			var v byte
+			for l := 0; l < k; l++ {
+				v ^= f.mul(inv[i][l], vm[l][k+j])
+			}
+			cols[j][i] = v
+		}
+	}
+	return cols, nil
+}
+
+// FECFrameSourceBlock collects the ADUs of a source block on the sender.
+type FECFrameSourceBlock struct {
+	s       *FECFrameScheme
+	sbn     uint32
+	symbols [][]byte
+}
+
+// NewSourceBlock returns an empty source block with the given source block number.
+func (s *FECFrameScheme) NewSourceBlock(sbn uint32) *FECFrameSourceBlock {
+	return &FECFrameSourceBlock{s: s, sbn: sbn}
+}
+
+// Add adds an ADU to the block and returns its Source FEC Payload ID.
+// If the block would have more than MaxSymbols()-1 source symbols, ErrMaxShardNum is returned.
+func (b *FECFrameSourceBlock) Add(flowID byte, adu []byte) (FECFramePayloadID, error) {
+	if len(adu) > 65535 {
+		return FECFramePayloadID{}, ErrShardSize
+	}
+	e := b.s.symbolSize
+	n := (fecframeADUIHeader + len(adu) + e - 1) / e
+	if len(b.symbols)+n >= b.s.MaxSymbols() {
+		return FECFramePayloadID{}, ErrMaxShardNum
+	}
+	id := FECFramePayloadID{SBN: b.sbn, ESI: len(b.symbols)}
+	if _, err := b.s.packID(id); err != nil {
+		return id, err
+	}
+	adui := make([]byte, n*e)
+	adui[0], adui[1], adui[2] = flowID, byte(len(adu)>>8), byte(len(adu))
+	copy(adui[fecframeADUIHeader:], adu)
+	for i := 0; i < n; i++ {
+		b.symbols = append(b.symbols, adui[i*e:(i+1)*e])
+	}
+	return id, nil
+}
+
+// SourceBlockLength returns the number of source symbols in the block.
+func (b *FECFrameSourceBlock) SourceBlockLength() int {
+	return len(b.symbols)
+}
+
+// Repair returns count repair symbols for the block, with their Repair FEC Payload IDs.
+// The total number of symbols may be at most MaxSymbols().
+func (b *FECFrameSourceBlock) Repair(count int) ([]FECFramePayloadID, [][]byte, error) {
+	k := len(b.symbols)
+	if k == 0 {
+		return nil, nil, ErrShortData
+	}
+	if count < 0 || k+count > b.s.MaxSymbols() {
+		return nil, nil, ErrMaxShardNum
+	}
+	cols, err := b.s.generator(k, k+count)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]FECFramePayloadID, count)
+	repair := AllocAligned(count, b.s.symbolSize)
+	for j, col := range cols {
+		ids[j] = FECFramePayloadID{SBN: b.sbn, ESI: k + j, SourceBlockLength: k}
+		for i, c := range col {
+			b.s.field.mulSliceXor(c, b.symbols[i], repair[j], &b.s.o)
+		}
+	}
+	return ids, repair, nil
+}
+
+// FECFrameDecoder collects the symbols of a source block on the receiver.
+type FECFrameDecoder struct {
+	s       *FECFrameScheme
+	sbn     uint32
+	k       int            // Source block length, 0 until a repair symbol is received.
+	symbols map[int][]byte // Source symbols.
+	repairs map[int][]byte
+	adus    map[int]FECFrameADU
+}
+
+// NewDecoder returns a decoder for the source block with the given number.
+func (s *FECFrameScheme) NewDecoder(sbn uint32) *FECFrameDecoder {
+	return &FECFrameDecoder{
+		s:       s,
+		sbn:     sbn,
+		symbols: make(map[int][]byte),
+		repairs: make(map[int][]byte),
+		adus:    make(map[int]FECFrameADU),
+	}
+}
+
+// AddSource adds a received ADU with its Source FEC Payload ID.
+// The ADU is copied.
+func (d *FECFrameDecoder) AddSource(id FECFramePayloadID, flowID byte, adu []byte) error {
+	if id.SBN != d.sbn || len(adu) > 65535 {
+		return ErrInvalidInput
+	}
+	e := d.s.symbolSize
+	n := (fecframeADUIHeader + len(adu) + e - 1) / e
+	if id.ESI+n >= d.s.MaxSymbols() {
+		return ErrInvalidPayloadID
+	}
+	adui := make([]byte, n*e)
+	adui[0], adui[1], adui[2] = flowID, byte(len(adu)>>8), byte(len(adu))
+	copy(adui[fecframeADUIHeader:], adu)
+	for i := 0; i < n; i++ {
+		d.symbols[id.ESI+i] = adui[i*e : (i+1)*e]
+	}
+	d.adus[id.ESI] = FECFrameADU{FlowID: flowID, Data: adui[fecframeADUIHeader : fecframeADUIHeader+len(adu)]}
+	return nil
+}
+
+// AddRepair adds a received repair symbol with its Repair FEC Payload ID.
+// The decoder keeps a reference to symbol.
+func (d *FECFrameDecoder) AddRepair(id FECFramePayloadID, symbol []byte) error {
+	if id.SBN != d.sbn || id.ESI < id.SourceBlockLength || id.ESI >= d.s.MaxSymbols() {
+		return ErrInvalidPayloadID
+	}
+	if d.k != 0 && d.k != id.SourceBlockLength {
+		return ErrInvalidPayloadID
+	}
+	if len(symbol) != d.s.symbolSize {
+		return ErrShardSize
+	}
+	d.k = id.SourceBlockLength
+	d.repairs[id.ESI] = symbol
+	return nil
+}
+
+// Decode returns the ADUs of the source block in order.
+// A repair symbol is needed to know the source block length,
+// and a total of SourceBlockLength source and repair symbols are needed.
+// ErrTooFewShards is returned until they have been received.
+func (d *FECFrameDecoder) Decode() ([]FECFrameADU, error) {
+	k := d.k
+	if k == 0 {
+		return nil, ErrTooFewShards
+	}
+	// Prefer source symbols.
+	var have []int
+	for esi := range d.symbols {
+		if esi >= k {
+			return nil, ErrInvalidPayloadID
+		}
+		have = append(have, esi)
+	}
+	for esi := range d.repairs {
+		have = append(have, esi)
+	}
+	if len(have) < k {
+		return nil, ErrTooFewShards
+	}
+	sort.Ints(have)
+	have = have[:k]
+
+	// Solve for the source symbols from the received columns.
+	cols, err := d.s.generator(k, have[k-1]+1)
+	if err != nil {
+		return nil, err
+	}
+	f := d.s.field
+	sub := make([][]byte, k)
+	for r, esi := range have {
+		sub[r] = make([]byte, k)
+		if esi < k {
+			sub[r][esi] = 1
+			continue
+		}
+		copy(sub[r], cols[esi-k])
+	}
+	inv, err := f.invert(sub)
+	if err != nil {
+		return nil, err
+	}
+	// Received symbol r is the sum of sub[r][i] * source[i],
+	// so source[i] is the sum of inv[i][r] * received[r].
+	source := AllocAligned(k, d.s.symbolSize)
+	for i := range source {
+		if sym, ok := d.symbols[i]; ok {
+			copy(source[i], sym)
+			continue
+		}
+		for r, esi := range have {
+			sym := d.symbols[esi]
+			if esi >= k {
+				sym = d.repairs[esi]
+			}
+			f.mulSliceXor(inv[i][r], sym, source[i], &d.s.o)
+		}
+	}
+	return d.parse(source)
+}
+
+// parse splits the source symbols into ADUs.
+func (d *FECFrameDecoder) parse(source [][]byte) ([]FECFrameADU, error) {
+	e := d.s.symbolSize
+	var res []FECFrameADU
+	for esi := 0; esi < len(source); {
+		if adu, ok := d.adus[esi]; ok {
+			res = append(res, adu)
+			esi += (fecframeADUIHeader + len(adu.Data) + e - 1) / e
+			continue
+		}
+		// The ADUI header may span symbols if they are short.
+		var hdr [fecframeADUIHeader]byte
+		for i := range hdr {
+			if esi+(i/e) >= len(source) {
+				return nil, ErrInvalidInput
+			}
+			hdr[i] = source[esi+i/e][i%e]
+		}
+		size := int(hdr[1])<<8 | int(hdr[2])
+		n := (fecframeADUIHeader + size + e - 1) / e
+		if esi+n > len(source) {
+			return nil, ErrInvalidInput
+		}
+		adui := make([]byte, 0, n*e)
+		for _, sym := range source[esi : esi+n] {
+			adui = append(adui, sym...)
+		}
+		res = append(res, FECFrameADU{FlowID: hdr[0], Data: adui[fecframeADUIHeader : fecframeADUIHeader+size]})
+		esi += n
+	}
+	return res, nil
+}
+
+// fecField is GF(2^m) for the RFC 5510 codes.
+type fecField struct {
+	m, order int
+	exp      []byte // α^i for i < 2*order.
+	log      []int
+
+	// nibbles contains c*x for each byte x, treated as two elements, for m = 4.
+	nibbles *[16][256]byte
+}
+
+var (
+	fecField4, fecField8 *fecField
+	fecFieldOnce         sync.Once
+)
+
+// fecFieldFor returns GF(2^m), or nil if m is not supported.
+func fecFieldFor(m int) *fecField {
+	fecFieldOnce.Do(func() {
+		// The primitive polynomials of RFC 5510.
+		fecField4 = newFECField(4, 0x13)
+		fecField8 = newFECField(8, 0x11d)
+		fecField4.nibbles = new([16][256]byte)
+		for c := 0; c < 16; c++ {
+			for x := 0; x < 256; x++ {
+				fecField4.nibbles[c][x] = fecField4.mul(byte(c), byte(x>>4))<<4 | fecField4.mul(byte(c), byte(x&15))
+			}
+		}
+	})
+	switch m {
+	case 4:
+		return fecField4
+	case 8:
+		return fecField8
+	}
+	return nil
+}
+
+func newFECField(m, poly int) *fecField {
+	order := 1<<m - 1
+	f := &fecField{m: m, order: order, exp: make([]byte, 2*order), log: make([]int, order+1)}
+	x := 1
+	for i := 0; i < order; i++ {
+		f.exp[i], f.exp[i+order] = byte(x), byte(x)
+		f.log[x] = i
+		x <<= 1
+		if x > order {
+			x ^= poly
+		}
+	}
+	return f
+}
+
+func (f *fecField) mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return f.exp[f.log[a]+f.log[b]]
+}
+
+func (f *fecField) inv(a byte) byte {
+	return f.exp[(f.order-f.log[a])%f.order]
+}
+
+// mulSliceXor adds c * in to out.
+func (f *fecField) mulSliceXor(c byte, in, out []byte, o *options) {
+	if f.m == 8 {
+		galMulSliceXor(c, in, out, o)
+		return
+	}
+	t := &f.nibbles[c]
+	for i, x := range in {
+		out[i] ^= t[x]
+	}
+}
+
+// invert returns the inverse of the square matrix m, which is not modified.
+func (f *fecField) invert(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	work := make([][]byte, n)
+	inv := make([][]byte, n)
+	for i := range work {
+		work[i] = append([]byte(nil), m[i]...)
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for c := 0; c < n; c++ {
+		p := c
+		for p < n && work[p][c] == 0 {
+			p++
+		}
+		if p == n {
+			return nil, errSingular
+		}
+		work[c], work[p] = work[p], work[c]
+		inv[c], inv[p] = inv[p], inv[c]
+		if v := work[c][c]; v != 1 {
+			s := f.inv(v)
+			for j := 0; j < n; j++ {
+				work[c][j] = f.mul(work[c][j], s)
+				inv[c][j] = f.mul(inv[c][j], s)
+			}
+		}
+		for r := 0; r < n; r++ {
+			if v := work[r][c]; r != c && v != 0 {
+				for j := 0; j < n; j++ {
+					work[r][j] ^= f.mul(v, work[c][j])
+					inv[r][j] ^= f.mul(v, inv[c][j])
+				}
+			}
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,124 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestFECFrame(t *testing.T) {
+	for _, test := range []struct {
+		m, size int
+		adus    []int
+		repairs int
+		lost    []int // Indexes of lost ADUs.
+	}{
+		{m: 8, size: 20, adus: []int{50, 0, 1, 17, 100}, repairs: 9, lost: []int{0, 4}},
+		{m: 8, size: 64, adus: []int{10, 10, 10}, repairs: 3, lost: []int{0, 1, 2}},
+		{m: 4, size: 5, adus: []int{3, 8, 0}, repairs: 5, lost: []int{1}},
+		{m: 4, size: 2, adus: []int{1, 2, 3}, repairs: 4, lost: []int{2}},
+	} {
+		s, err := NewFECFrameScheme(test.m, test.size, testOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, err := ParseFECFrameSchemeInfo(s.SchemeInfo())
+		if err != nil || s2.SymbolSize() != test.size || s2.MaxSymbols() != 1<<test.m-1 {
+			t.Fatalf("scheme info round trip failed: %v", err)
+		}
+
+		rng := rand.New(rand.NewSource(int64(test.m)))
+		block := s.NewSourceBlock(7)
+		adus := make([]FECFrameADU, len(test.adus))
+		ids := make([]FECFramePayloadID, len(test.adus))
+		for i, n := range test.adus {
+			adus[i] = FECFrameADU{FlowID: byte(i), Data: make([]byte, n)}
+			rng.Read(adus[i].Data)
+			ids[i], err = block.Add(adus[i].FlowID, adus[i].Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		repairIDs, repair, err := block.Repair(test.repairs)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dec := s.NewDecoder(7)
+		lost := make(map[int]bool)
+		for _, i := range test.lost {
+			lost[i] = true
+		}
+		for i, adu := range adus {
+			if lost[i] {
+				continue
+			}
+			// Send through the wire format.
+			pkt, err := s.AppendSourceID(nil, ids[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, rest, err := s.ParseSourceID(append(pkt, adu.Data...))
+			if err != nil || id != ids[i] {
+				t.Fatalf("source ID round trip: got %+v, want %+v, %v", id, ids[i], err)
+			}
+			if err := dec.AddSource(id, adu.FlowID, rest); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := dec.Decode(); err != ErrTooFewShards {
+			t.Fatalf("want ErrTooFewShards, got %v", err)
+		}
+		for j, sym := range repair {
+			pkt, err := s.AppendRepairID(nil, repairIDs[j])
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, rest, err := s.ParseRepairID(append(pkt, sym...))
+			if err != nil || id != repairIDs[j] {
+				t.Fatalf("repair ID round trip: got %+v, want %+v, %v", id, repairIDs[j], err)
+			}
+			if err := dec.AddRepair(id, rest); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("m=%d: %v", test.m, err)
+		}
+		if len(got) != len(adus) {
+			t.Fatalf("m=%d: got %d ADUs", test.m, len(got))
+		}
+		for i := range got {
+			if got[i].FlowID != adus[i].FlowID || !bytes.Equal(got[i].Data, adus[i].Data) {
+				t.Fatalf("m=%d: ADU %d mismatch", test.m, i)
+			}
+		}
+	}
+}
+
+func TestFECFrameErrors(t *testing.T) {
+	if _, err := NewFECFrameScheme(16, 10); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+	s, err := NewFECFrameScheme(4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AppendSourceID(nil, FECFramePayloadID{SBN: 1 << 28}); err != ErrInvalidPayloadID {
+		t.Errorf("want ErrInvalidPayloadID, got %v", err)
+	}
+	block := s.NewSourceBlock(0)
+	if _, err := block.Add(0, make([]byte, 60)); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum, got %v", err)
+	}
+	if _, err := block.Add(0, make([]byte, 40)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := block.Repair(5); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum, got %v", err)
+	}
+	if _, _, err := s.ParseRepairID([]byte{0, 0, 0, 1, 0, 2}); err != ErrInvalidPayloadID {
+		t.Errorf("want ErrInvalidPayloadID, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package reedsolomon
+
+import "context"
+
+// ShardFetcher fetches the content of a single shard.
+// The fetcher should return promptly when ctx is cancelled.
+type ShardFetcher func(ctx context.Context) ([]byte, error)
+
+// FetchAndDecode starts all fetchers concurrently, and decodes the data
+// shards as soon as enough shards have been fetched to reconstruct them.
+// Fetches still running at that point are cancelled.
+//
+// There must be a fetcher for each shard, in shard order.
+// Nil fetchers are treated as missing shards.
+// A fetcher returning an error or no data is treated as a missing shard.
+//
+// The returned slice contains all data shards, as well as any parity shards
+// that were fetched. Use Join to get the original data.
+// If too few shards can be fetched, ErrTooFewShards is returned.
+// If ctx is cancelled before enough shards are fetched, ctx.Err() is returned.
+func FetchAndDecode(ctx context.Context, enc Encoder, fetchers []ShardFetcher) ([][]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(fetchers) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		idx  int
+		data []byte
+		err  error
+	}
+	// Buffered, so cancelled fetches can always deliver their result.
+	results := make(chan result, len(fetchers))
+	running := 0
+	for i, fetch := range fetchers {
+		if fetch == nil {
+			continue
+		}
+		running++
+		go func(i int, fetch ShardFetcher) {
+			b, err := fetch(ctx)
+			results <- result{idx: i, data: b, err: err}
+		}(i, fetch)
+	}
+
+	shards := make([][]byte, len(fetchers))
+	need := ext.DataShards()
+	// Stop when done, or when the remaining fetches cannot provide enough shards.
+	for need > 0 && running >= need {
+		select {
+		case res := <-results:
+			running--
+			if res.err != nil || len(res.data) == 0 {
+				continue
+			}
+			shards[res.idx] = res.data
+			need--
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if need > 0 {
+		return nil, ErrTooFewShards
+	}
+	cancel()
+
+	if err := enc.ReconstructData(shards); err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
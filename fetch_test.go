@@ -0,0 +1,88 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFetchAndDecode(t *testing.T) {
+	const dataShards, parityShards = 6, 3
+	enc, err := New(dataShards, parityShards, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10000)
+	fillRandom(data)
+	shards, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+
+	errFetch := errors.New("fetch failed")
+	cancelled := make(chan int, len(shards))
+	fetchers := make([]ShardFetcher, len(shards))
+	for i := range shards {
+		shard := shards[i]
+		idx := i
+		switch i {
+		case 0:
+			fetchers[i] = func(ctx context.Context) ([]byte, error) {
+				return nil, errFetch
+			}
+		case 2:
+			// Slow fetch, must be cancelled.
+			fetchers[i] = func(ctx context.Context) ([]byte, error) {
+				<-ctx.Done()
+				cancelled <- idx
+				return nil, ctx.Err()
+			}
+		case 4:
+			// Missing.
+		default:
+			fetchers[i] = func(ctx context.Context) ([]byte, error) {
+				return append([]byte{}, shard...), nil
+			}
+		}
+	}
+	got, err := FetchAndDecode(context.Background(), enc, fetchers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, got, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("decoded data mismatch")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("slow fetch was not cancelled")
+	}
+
+	// Too many failures.
+	fetchers[1], fetchers[3] = fetchers[0], fetchers[0]
+	if _, err := FetchAndDecode(context.Background(), enc, fetchers); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+
+	// Cancelled by caller.
+	for i := range fetchers {
+		fetchers[i] = func(ctx context.Context) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := FetchAndDecode(ctx, enc, fetchers); err != context.DeadlineExceeded {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package reedsolomon
+
+import (
+	"io"
+)
+
+// Fountain is a rateless erasure code.
+//
+// The data is split into DataShards source symbols, and an unlimited number of
+// encoding symbols can be generated from them, each identified by a 32 bit id.
+// Symbols with an id below DataShards are the source symbols themselves.
+// Other symbols are linear combinations of all source symbols in GF(2^8),
+// with coefficients generated by SplitMix64 seeded with the id.
+//
+// The data can be decoded from any set of symbols spanning the source symbols.
+// Exactly DataShards random symbols are enough with a probability above 99.6%,
+// and each extra symbol reduces the probability of failure by a factor of 256.
+//
+// Decoding is quadratic in the number of source symbols,
+// so keep DataShards in the low thousands at most.
+type Fountain struct {
+	dataShards int
+
+	// rs is used for splitting and joining data.
+	rs *reedSolomon
+	o  options
+}
+
+// FountainDecoder collects symbols from a Fountain until the data can be decoded.
+// Create using Fountain.NewDecoder.
+type FountainDecoder struct {
+	f    *Fountain
+	size int
+
+	// Symbols that added information, with their ids.
+	ids     []uint32
+	symbols [][]byte
+
+	// Coefficient rows of the added symbols, reduced in insertion order.
+	// Each row is 1 at its pivot, and 0 at the pivots of earlier rows.
+	reduced [][]byte
+	pivots  []int
+}
+
+// NewFountain creates a rateless encoder for the given number of source symbols.
+// Only the CPU feature options are used from opts.
+func NewFountain(dataShards int, opts ...Option) (*Fountain, error) {
+	if dataShards <= 0 {
+		return nil, ErrInvShardNum
+	}
+	o := defaultOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Fountain{
+		dataShards: dataShards,
+		rs:         &reedSolomon{dataShards: dataShards, totalShards: dataShards, o: o},
+		o:          o,
+	}, nil
+}
+
+// DataShards returns the number of source symbols.
+func (f *Fountain) DataShards() int {
+	return f.dataShards
+}
+
+// Split splits data into source symbols, like Encoder.Split.
+func (f *Fountain) Split(data []byte) ([][]byte, error) {
+	return f.rs.Split(data)
+}
+
+// Join writes outSize bytes of the source symbols to dst, like Encoder.Join.
+func (f *Fountain) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return f.rs.Join(dst, shards, outSize)
+}
+
+// coefficients returns the coefficients of the symbol with the given id.
+func (f *Fountain) coefficients(id uint32) []byte {
+	row := make([]byte, f.dataShards)
+	if int64(id) < int64(f.dataShards) {
+		row[id] = 1
+		return row
+	}
+	fillSplitMix(row, uint64(id))
+	return row
+}
+
+// fillSplitMix fills row with bytes generated by SplitMix64 seeded with seed.
+func fillSplitMix(row []byte, seed uint64) {
+	state := seed
+	for i := 0; i < len(row); i += 8 {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		z ^= z >> 31
+		for j := i; j < i+8 && j < len(row); j++ {
+			row[j] = byte(z)
+			z >>= 8
+		}
+	}
+}
+
+// EncodeSymbol generates the symbol with the given id from the source symbols.
+// dst is used for the output if it has sufficient capacity.
+// For source symbol ids the source symbol is copied.
+func (f *Fountain) EncodeSymbol(data [][]byte, id uint32, dst []byte) ([]byte, error) {
+	if len(data) != f.dataShards {
+		return nil, ErrTooFewShards
+	}
+	if err := checkShards(data, false); err != nil {
+		return nil, err
+	}
+	size := len(data[0])
+	if cap(dst) >= size {
+		dst = dst[:size]
+	} else {
+		dst = make([]byte, size)
+	}
+	if int64(id) < int64(f.dataShards) {
+		copy(dst, data[id])
+		return dst, nil
+	}
+	memclr(dst)
+	for i, c := range f.coefficients(id) {
+		galMulSliceXor(c, data[i], dst, &f.o)
+	}
+	return dst, nil
+}
+
+// NewDecoder returns a decoder for symbols generated by f.
+func (f *Fountain) NewDecoder() *FountainDecoder {
+	return &FountainDecoder{f: f, size: -1}
+}
+
+// Add adds the symbol with the given id to the decoder.
+// It returns true once enough symbols have been added to decode the data.
+// Symbols that add no information, such as duplicates, are ignored.
+// The decoder keeps a reference to symbol, so it must not be modified.
+func (d *FountainDecoder) Add(id uint32, symbol []byte) (bool, error) {
+	if len(symbol) == 0 {
+		return false, ErrShardNoData
+	}
+	if d.size >= 0 && len(symbol) != d.size {
+		return false, ErrShardSize
+	}
+	if d.Ready() {
+		return true, nil
+	}
+	d.size = len(symbol)
+
+	row := d.f.coefficients(id)
+	for i, r := range d.reduced {
+		if c := row[d.pivots[i]]; c != 0 {
+			galMulSliceXor(c, r, row, &d.f.o)
+		}
+	}
+	pivot := -1
+	for i, c := range row {
+		if c != 0 {
+			pivot = i
+			break
+		}
+	}
+	if pivot < 0 {
+		return false, nil
+	}
+	galMulSlice(galOneOver(row[pivot]), row, row, &d.f.o)
+
+	d.ids = append(d.ids, id)
+	d.symbols = append(d.symbols, symbol)
+	d.reduced = append(d.reduced, row)
+	d.pivots = append(d.pivots, pivot)
+	return d.Ready(), nil
+}
+
+// Ready returns true when enough symbols have been added to decode the data.
+func (d *FountainDecoder) Ready() bool {
+	return len(d.symbols) == d.f.dataShards
+}
+
+// Data returns the decoded source symbols.
+// Source symbols that were added are returned as is.
+// If not enough symbols have been added, ErrTooFewShards is returned.
+func (d *FountainDecoder) Data() ([][]byte, error) {
+	if !d.Ready() {
+		return nil, ErrTooFewShards
+	}
+	k := d.f.dataShards
+	data := make([][]byte, k)
+	var repair []int
+	for i, id := range d.ids {
+		if int64(id) < int64(k) {
+			data[id] = d.symbols[i]
+		} else {
+			repair = append(repair, i)
+		}
+	}
+	if len(repair) == 0 {
+		return data, nil
+	}
+
+	// Remove the known source symbols from the repair symbols,
+	// leaving a square system for the missing ones.
+	var missing []int
+	for i, sym := range data {
+		if sym == nil {
+			missing = append(missing, i)
+		}
+	}
+	sub, err := newMatrix(len(repair), len(missing))
+	if err != nil {
+		return nil, err
+	}
+	rhs := AllocAligned(len(repair), d.size)
+	for r, i := range repair {
+		row := d.f.coefficients(d.ids[i])
+		copy(rhs[r], d.symbols[i])
+		for j, c := range row {
+			if data[j] != nil {
+				galMulSliceXor(c, data[j], rhs[r], &d.f.o)
+			}
+		}
+		for m, j := range missing {
+			sub[r][m] = row[j]
+		}
+	}
+	inv, err := sub.Invert()
+	if err != nil {
+		return nil, err
+	}
+	out := AllocAligned(len(missing), d.size)
+	for m, j := range missing {
+		for r := range repair {
+			galMulSliceXor(inv[m][r], rhs[r], out[m], &d.f.o)
+		}
+		data[j] = out[m]
+	}
+	return data, nil
+}
@@ -0,0 +1,120 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestFountain(t *testing.T) {
+	for _, k := range []int{1, 2, 5, 17, 100} {
+		f, err := NewFountain(k, testOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, k*100+3)
+		fillRandom(data)
+		source, err := f.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rng := rand.New(rand.NewSource(int64(k)))
+		for iter := 0; iter < 10; iter++ {
+			// Receive a random mix of source and repair symbols.
+			dec := f.NewDecoder()
+			if _, err := dec.Data(); err != ErrTooFewShards {
+				t.Fatalf("want ErrTooFewShards, got %v", err)
+			}
+			added := 0
+			for done := false; !done; added++ {
+				id := rng.Uint32()
+				if iter%2 == 0 {
+					id %= uint32(2 * k)
+				}
+				sym, err := f.EncodeSymbol(source, id, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				done, err = dec.Add(id, sym)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if added > 10*k+20 {
+					t.Fatalf("k=%d: not decodable after %d symbols", k, added)
+				}
+			}
+			got, err := dec.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := f.Join(&buf, got, len(data)); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), data) {
+				t.Fatalf("k=%d: decoded data mismatch", k)
+			}
+		}
+	}
+}
+
+func TestFountainOverhead(t *testing.T) {
+	const k = 50
+	f, err := NewFountain(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := AllocAligned(k, 64)
+	for _, s := range source {
+		fillRandom(s)
+	}
+	failed := 0
+	for start := uint32(k); start < k+100*k; start += k {
+		dec := f.NewDecoder()
+		for id := start; id < start+k; id++ {
+			sym, err := f.EncodeSymbol(source, id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := dec.Add(id, sym); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !dec.Ready() {
+			failed++
+		}
+	}
+	// The expected number of failures is 0.4.
+	if failed > 3 {
+		t.Fatalf("%d of 100 sets of %d repair symbols could not be decoded", failed, k)
+	}
+}
+
+func TestFountainErrors(t *testing.T) {
+	if _, err := NewFountain(0); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+	f, err := NewFountain(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := AllocAligned(4, 10)
+	if _, err := f.EncodeSymbol(source[:3], 5, nil); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	dec := f.NewDecoder()
+	if _, err := dec.Add(0, nil); err != ErrShardNoData {
+		t.Errorf("want ErrShardNoData, got %v", err)
+	}
+	if _, err := dec.Add(0, source[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Add(1, make([]byte, 11)); err != ErrShardSize {
+		t.Errorf("want ErrShardSize, got %v", err)
+	}
+	// Duplicates are ignored.
+	if _, err := dec.Add(0, source[0]); err != nil || len(dec.symbols) != 1 {
+		t.Errorf("duplicate symbol was added: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package reedsolomon
+
+// GalMulMatrixVec multiplies the matrix with the input vectors and stores
+// the result in the output vectors, using the same SIMD code as the encoder.
+//
+// matrixRows must contain one row per output, each with one coefficient per input.
+// For every byte position, out[i] = sum(matrixRows[i][j] * in[j]) in GF(2^8).
+// All inputs and outputs must have the same length, and outputs are overwritten.
+//
+// This can be used to build custom codes, like LRC layouts or piggybacking,
+// without forking the assembly.
+// Options selecting CPU features and the field representation are respected.
+// Leopard options are not supported.
+func GalMulMatrixVec(matrixRows [][]byte, in, out [][]byte, opts ...Option) error {
+	return ProcessSlices(matrixRows, in, out, false, opts...)
+}
+
+// ProcessSlices applies the linear combinations given by matrixRows to
+// the inputs, like GalMulMatrixVec.
+//
+// If xor is false, the outputs are overwritten.
+// If xor is true, the result is added to the existing output content,
+// which allows applying partial updates, for example adding the parity
+// delta of changed data shards to existing parity.
+//
+// Work is split between goroutines as configured by opts.
+// Outputs must not overlap inputs or other outputs.
+func ProcessSlices(matrixRows [][]byte, in, out [][]byte, xor bool, opts ...Option) error {
+	if len(in) == 0 || len(matrixRows) != len(out) {
+		return ErrInvalidInput
+	}
+	for _, row := range matrixRows {
+		if len(row) != len(in) {
+			return ErrInvalidInput
+		}
+	}
+	if err := checkShards(in, false); err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	if err := checkShards(out, false); err != nil {
+		return err
+	}
+	if len(out[0]) != len(in[0]) {
+		return ErrShardSize
+	}
+	opts = append(opts[:len(opts):len(opts)], WithCustomMatrix(matrixRows), WithInversionCache(false))
+	enc, err := New(len(in), len(out), opts...)
+	if err != nil {
+		return err
+	}
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		return ErrNotSupported
+	}
+	if !xor {
+		r.codeSomeShards(r.parity, in, out, len(in[0]))
+		return nil
+	}
+	tmp := AllocAligned(len(out), len(out[0]))
+	r.codeSomeShards(r.parity, in, tmp, len(in[0]))
+	for i := range out {
+		sliceXor(tmp[i], out[i], &r.o)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGalMulMatrixVec(t *testing.T) {
+	for _, sz := range []int{1, 63, 1000, 100000} {
+		for _, shape := range [][2]int{{1, 1}, {3, 2}, {12, 5}, {20, 12}} {
+			ins, outs := shape[0], shape[1]
+			matrix := make([][]byte, outs)
+			for i := range matrix {
+				matrix[i] = make([]byte, ins)
+				fillRandom(matrix[i])
+			}
+			in := make([][]byte, ins)
+			for i := range in {
+				in[i] = make([]byte, sz)
+				fillRandom(in[i])
+			}
+			out := make([][]byte, outs)
+			for i := range out {
+				out[i] = make([]byte, sz)
+				fillRandom(out[i])
+			}
+			if err := GalMulMatrixVec(matrix, in, out, testOptions()...); err != nil {
+				t.Fatal(err)
+			}
+			want := make([]byte, sz)
+			for i, row := range matrix {
+				for j := range want {
+					want[j] = 0
+				}
+				for j, c := range row {
+					for k, v := range in[j] {
+						want[k] ^= galMultiply(c, v)
+					}
+				}
+				if !bytes.Equal(out[i], want) {
+					t.Fatalf("size %d, %dx%d: output %d mismatch", sz, ins, outs, i)
+				}
+			}
+		}
+	}
+	if err := GalMulMatrixVec([][]byte{{1}}, [][]byte{{1, 2}}, [][]byte{{1}}); err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+	if err := GalMulMatrixVec([][]byte{{1, 2}}, [][]byte{{1}}, [][]byte{{1}}); err != ErrInvalidInput {
+		t.Errorf("expected %v, got %v", ErrInvalidInput, err)
+	}
+}
+
+func TestProcessSlices(t *testing.T) {
+	const sz = 10000
+	matrix := [][]byte{{1, 2, 3}, {4, 5, 6}}
+	in := make([][]byte, 3)
+	for i := range in {
+		in[i] = make([]byte, sz)
+		fillRandom(in[i])
+	}
+	out := make([][]byte, 2)
+	for i := range out {
+		out[i] = make([]byte, sz)
+		fillRandom(out[i])
+	}
+	want := make([][]byte, 2)
+	for i := range want {
+		want[i] = make([]byte, sz)
+	}
+	if err := ProcessSlices(matrix, in, want, false, testOptions()...); err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		for j := range want[i] {
+			want[i][j] ^= out[i][j]
+		}
+	}
+	if err := ProcessSlices(matrix, in, out, true, testOptions()...); err != nil {
+		t.Fatal(err)
+	}
+	for i := range out {
+		if !bytes.Equal(out[i], want[i]) {
+			t.Fatalf("output %d mismatch", i)
+		}
+	}
+
+	// Adding the delta of an updated input must give the same result as re-encoding.
+	enc, err := New(3, 2, testOptions(WithCustomMatrix(matrix))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := append(append([][]byte{}, in...), make([]byte, sz), make([]byte, sz))
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	delta := make([]byte, sz)
+	fillRandom(delta)
+	for i := range delta {
+		shards[1][i] ^= delta[i]
+	}
+	col := [][]byte{{matrix[0][1]}, {matrix[1][1]}}
+	if err := ProcessSlices(col, [][]byte{delta}, shards[3:], true, testOptions()...); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+}
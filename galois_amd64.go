@@ -1,4 +1,4 @@
-//go:build !noasm && !appengine && !gccgo && !nopshufb
+//go:build !noasm && !purego && !appengine && !gccgo && !nopshufb
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 
@@ -24,6 +24,9 @@ func galMulAVX2Xor_64(low, high, in, out []byte)
 //go:noescape
 func galMulAVX2_64(low, high, in, out []byte)
 
+//go:noescape
+func galMulAVX10Xor_64(low, high, in, out []byte)
+
 // This is what the assembler routines do in blocks of 16 bytes:
 /*
 func galMulSSSE3(low, high, in, out []byte) {
@@ -47,6 +50,10 @@ func galMulSSSE3Xor(low, high, in, out []byte) {
 const bigSwitchover = 128
 
 func galMulSlice(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	if c == 1 {
 		copy(out, in)
 		return
@@ -58,7 +65,7 @@ func galMulSlice(c byte, in, out []byte, o *options) {
 				raceReadSlice(in[:done])
 				raceWriteSlice(out[:done])
 			}
-			galMulAVX2_64(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+			galMulAVX2_64(t.low[c][:], t.high[c][:], in, out)
 			in = in[done:]
 			out = out[done:]
 		}
@@ -68,7 +75,7 @@ func galMulSlice(c byte, in, out []byte, o *options) {
 				raceReadSlice(in[:done])
 				raceWriteSlice(out[:done])
 			}
-			galMulAVX2(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+			galMulAVX2(t.low[c][:], t.high[c][:], in, out)
 			in = in[done:]
 			out = out[done:]
 		}
@@ -78,18 +85,36 @@ func galMulSlice(c byte, in, out []byte, o *options) {
 			raceReadSlice(in[:done])
 			raceWriteSlice(out[:done])
 		}
-		galMulSSSE3(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+		galMulSSSE3(t.low[c][:], t.high[c][:], in, out)
 		in = in[done:]
 		out = out[done:]
 	}
+	if o.constantTime {
+		galMulSliceCT(c, in, out, t, false)
+		return
+	}
 	out = out[:len(in)]
-	mt := mulTable[c][:256]
+	mt := t.mul[c][:256]
 	for i := range in {
 		out[i] = mt[in[i]]
 	}
 }
 
+// galMulXor64 multiplies in by the tables and adds the result to out
+// in blocks of 64 bytes, using AVX10 to combine the XOR operations when enabled.
+func galMulXor64(low, high, in, out []byte, o *options) {
+	if o.useAVX10 {
+		galMulAVX10Xor_64(low, high, in, out)
+		return
+	}
+	galMulAVX2Xor_64(low, high, in, out)
+}
+
 func galMulSliceXor(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	if c == 1 {
 		sliceXor(in, out, o)
 		return
@@ -102,7 +127,7 @@ func galMulSliceXor(c byte, in, out []byte, o *options) {
 				raceReadSlice(in[:done])
 				raceWriteSlice(out[:done])
 			}
-			galMulAVX2Xor_64(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+			galMulXor64(t.low[c][:], t.high[c][:], in, out, o)
 			in = in[done:]
 			out = out[done:]
 		}
@@ -112,7 +137,7 @@ func galMulSliceXor(c byte, in, out []byte, o *options) {
 				raceReadSlice(in[:done])
 				raceWriteSlice(out[:done])
 			}
-			galMulAVX2Xor(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+			galMulAVX2Xor(t.low[c][:], t.high[c][:], in, out)
 			in = in[done:]
 			out = out[done:]
 		}
@@ -122,15 +147,19 @@ func galMulSliceXor(c byte, in, out []byte, o *options) {
 			raceReadSlice(in[:done])
 			raceWriteSlice(out[:done])
 		}
-		galMulSSSE3Xor(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+		galMulSSSE3Xor(t.low[c][:], t.high[c][:], in, out)
 		in = in[done:]
 		out = out[done:]
 	}
 	if len(in) == 0 {
 		return
 	}
+	if o.constantTime {
+		galMulSliceCT(c, in, out, t, true)
+		return
+	}
 	out = out[:len(in)]
-	mt := mulTable[c][:256]
+	mt := t.mul[c][:256]
 	for i := range in {
 		out[i] ^= mt[in[i]]
 	}
@@ -138,6 +167,9 @@ func galMulSliceXor(c byte, in, out []byte, o *options) {
 
 // simple slice xor
 func sliceXor(in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
 	if o.useSSE2 {
 		if len(in) >= bigSwitchover {
 			if o.useAVX2 {
@@ -571,7 +603,7 @@ func mulAdd8(x, y []byte, log_m ffe8, o *options) {
 			raceReadSlice(y[:done])
 			raceWriteSlice(x[:done])
 		}
-		galMulAVX2Xor_64(t[:16], t[16:32], y, x)
+		galMulXor64(t[:16], t[16:32], y, x, o)
 		y = y[done:]
 		x = x[done:]
 	} else if o.useSSSE3 {
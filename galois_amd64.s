@@ -1,4 +1,5 @@
 //+build !noasm
+//+build !purego
 //+build !appengine
 //+build !gccgo
 //+build !nopshufb
@@ -265,6 +266,54 @@ done_xor_avx2_64:
 	VZEROUPPER
 	RET
 
+// func galMulAVX10Xor_64(low, high, in, out []byte)
+// Requires: AVX10/256 or AVX512VL
+TEXT ·galMulAVX10Xor_64(SB), 7, $0
+	MOVQ low+0(FP), SI     // SI: &low
+	MOVQ high+24(FP), DX   // DX: &high
+	MOVQ $15, BX           // BX: low mask
+	MOVQ BX, X5
+	MOVQ in_len+56(FP), R9 // R9: len(in)
+
+	VBROADCASTI128 (SI), Y6 // low table
+	VBROADCASTI128 (DX), Y7 // high table
+	VPBROADCASTB   X5, Y8   // Y8: lomask (unpacked)
+
+	SHRQ  $6, R9           // len(in) / 64
+	MOVQ  out+72(FP), DX   // DX: &out
+	MOVQ  in+48(FP), SI    // SI: &in
+	TESTQ R9, R9
+	JZ    done_xor_avx10_64
+
+loopback_xor_avx10_64:
+	VMOVDQU    (SI), Y0
+	VMOVDQU    32(SI), Y10
+	VMOVDQU    (DX), Y4
+	VMOVDQU    32(DX), Y14
+	VPSRLQ     $4, Y0, Y1           // Y1: high input
+	VPSRLQ     $4, Y10, Y11         // Y11: high input 2
+	VPAND      Y8, Y0, Y0           // Y0: low input
+	VPAND      Y8, Y10, Y10         // Y10: low input 2
+	VPAND      Y8, Y1, Y1           // Y1: high input
+	VPAND      Y8, Y11, Y11         // Y11: high input 2
+	VPSHUFB    Y0, Y6, Y2           // Y2: mul low part
+	VPSHUFB    Y10, Y6, Y12         // Y12: mul low part 2
+	VPSHUFB    Y1, Y7, Y3           // Y3: mul high part
+	VPSHUFB    Y11, Y7, Y13         // Y13: mul high part 2
+	VPTERNLOGD $0x96, Y3, Y2, Y4    // Y4: Result
+	VPTERNLOGD $0x96, Y13, Y12, Y14 // Y14: Result 2
+	VMOVDQU    Y4, (DX)
+	VMOVDQU    Y14, 32(DX)
+
+	ADDQ $64, SI                 // in+=64
+	ADDQ $64, DX                 // out+=64
+	SUBQ $1, R9
+	JNZ  loopback_xor_avx10_64
+
+done_xor_avx10_64:
+	VZEROUPPER
+	RET
+
 // func galMulAVX2_64(low, high, in, out []byte)
 TEXT ·galMulAVX2_64(SB), 7, $0
 	MOVQ           low+0(FP), SI     // SI: &low
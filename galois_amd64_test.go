@@ -1,11 +1,14 @@
-//go:build !noasm && !appengine && !gccgo && !nopshufb
+//go:build !noasm && !purego && !appengine && !gccgo && !nopshufb
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 
 package reedsolomon
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/klauspost/cpuid/v2"
 )
 
 func TestGenGalois(t *testing.T) {
@@ -13,3 +16,63 @@ func TestGenGalois(t *testing.T) {
 		testGenGaloisUpto10x10(t, galMulSlicesAvx2, galMulSlicesAvx2Xor, 32)
 	}
 }
+
+// hasAVX10 returns whether galMulAVX10Xor_64 can run on this CPU.
+//
+// This is intentionally wider than the default of WithAVX10, which only
+// enables the kernel on AVX10 CPUs: the kernel also runs on AVX512VL CPUs,
+// where it can be enabled with WithAVX10(true), and testing it there
+// covers the kernel on the much more common AVX512 machines.
+func hasAVX10() bool {
+	return cpuid.CPU.Supports(cpuid.AVX10) || cpuid.CPU.Supports(cpuid.AVX512F, cpuid.AVX512BW, cpuid.AVX512VL)
+}
+
+func TestAVX10Default(t *testing.T) {
+	if got, want := defaultOptions.useAVX10, cpuid.CPU.Supports(cpuid.AVX10); got != want {
+		t.Errorf("AVX10 enabled by default: got %v, want %v", got, want)
+	}
+}
+
+func TestGalMulAVX10Xor(t *testing.T) {
+	if !hasAVX10() || !defaultOptions.useAVX2 {
+		t.Skip("AVX10 not supported")
+	}
+	gf := defaultOptions.gf()
+	in := make([]byte, 1024)
+	fillRandom(in)
+	for c := 0; c < 256; c++ {
+		want := make([]byte, len(in))
+		fillRandom(want)
+		got := append([]byte{}, want...)
+		galMulAVX2Xor_64(gf.low[c][:], gf.high[c][:], in, want)
+		galMulAVX10Xor_64(gf.low[c][:], gf.high[c][:], in, got)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("mismatch for c=%d", c)
+		}
+	}
+}
+
+func BenchmarkGalMulXor64(b *testing.B) {
+	if !defaultOptions.useAVX2 {
+		b.Skip("AVX2 not supported")
+	}
+	gf := defaultOptions.gf()
+	in := make([]byte, 64<<10)
+	out := make([]byte, len(in))
+	fillRandom(in)
+	b.Run("avx2", func(b *testing.B) {
+		b.SetBytes(int64(len(in)))
+		for i := 0; i < b.N; i++ {
+			galMulAVX2Xor_64(gf.low[7][:], gf.high[7][:], in, out)
+		}
+	})
+	if !hasAVX10() {
+		return
+	}
+	b.Run("avx10", func(b *testing.B) {
+		b.SetBytes(int64(len(in)))
+		for i := 0; i < b.N; i++ {
+			galMulAVX10Xor_64(gf.low[7][:], gf.high[7][:], in, out)
+		}
+	})
+}
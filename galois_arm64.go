@@ -1,4 +1,4 @@
-//go:build !noasm && !appengine && !gccgo && !nopshufb
+//go:build !noasm && !purego && !appengine && !gccgo && !nopshufb
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 // Copyright 2017, Minio, Inc.
@@ -13,8 +13,21 @@ func galMulNEON(low, high, in, out []byte)
 //go:noescape
 func galMulXorNEON(low, high, in, out []byte)
 
+//go:noescape
+func galMulXorSHA3(low, high, in, out []byte)
+
 func getVectorLength() (vl, pl uint64)
 
+// galMulXor multiplies in by the tables and adds the result to out,
+// using EOR3 to combine the XOR operations when SHA3 is enabled.
+func galMulXor(low, high, in, out []byte, o *options) {
+	if o.useSHA3 {
+		galMulXorSHA3(low, high, in, out)
+		return
+	}
+	galMulXorNEON(low, high, in, out)
+}
+
 func init() {
 	if defaultOptions.useSVE {
 		if vl, _ := getVectorLength(); vl <= 256 {
@@ -28,6 +41,10 @@ func init() {
 }
 
 func galMulSlice(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	if c == 1 {
 		copy(out, in)
 		return
@@ -38,11 +55,13 @@ func galMulSlice(c byte, in, out []byte, o *options) {
 		raceReadSlice(in[:done])
 		raceWriteSlice(out[:done])
 	}
-	galMulNEON(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+	galMulNEON(t.low[c][:], t.high[c][:], in, out)
 
 	remain := len(in) - done
-	if remain > 0 {
-		mt := mulTable[c][:256]
+	if remain > 0 && o.constantTime {
+		galMulSliceCT(c, in[done:], out[done:], t, false)
+	} else if remain > 0 {
+		mt := t.mul[c][:256]
 		for i := done; i < len(in); i++ {
 			out[i] = mt[in[i]]
 		}
@@ -50,6 +69,10 @@ func galMulSlice(c byte, in, out []byte, o *options) {
 }
 
 func galMulSliceXor(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	if c == 1 {
 		sliceXor(in, out, o)
 		return
@@ -59,11 +82,13 @@ func galMulSliceXor(c byte, in, out []byte, o *options) {
 		raceReadSlice(in[:done])
 		raceWriteSlice(out[:done])
 	}
-	galMulXorNEON(mulTableLow[c][:], mulTableHigh[c][:], in, out)
+	galMulXor(t.low[c][:], t.high[c][:], in, out, o)
 
 	remain := len(in) - done
-	if remain > 0 {
-		mt := mulTable[c][:256]
+	if remain > 0 && o.constantTime {
+		galMulSliceCT(c, in[done:], out[done:], t, true)
+	} else if remain > 0 {
+		mt := t.mul[c][:256]
 		for i := done; i < len(in); i++ {
 			out[i] ^= mt[in[i]]
 		}
@@ -126,7 +151,7 @@ func mulgf16(x, y []byte, log_m ffe, o *options) {
 
 func mulAdd8(out, in []byte, log_m ffe8, o *options) {
 	t := &multiply256LUT8[log_m]
-	galMulXorNEON(t[:16], t[16:32], in, out)
+	galMulXor(t[:16], t[16:32], in, out, o)
 	done := (len(in) >> 5) << 5
 	in = in[done:]
 	if len(in) > 0 {
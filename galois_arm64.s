@@ -1,4 +1,5 @@
 //+build !noasm
+//+build !purego
 //+build !appengine
 //+build !gccgo
 //+build !nopshufb
@@ -103,6 +104,49 @@ loopXor:
 completeXor:
 	RET
 
+// func galMulXorSHA3(low, high, in, out []byte)
+// Requires: SHA3
+TEXT ·galMulXorSHA3(SB), 7, $0
+	MOVD in_base+48(FP), R1
+	MOVD in_len+56(FP), R2   // length of message
+	MOVD out_base+72(FP), R5
+	SUBS $32, R2
+	BMI  completeXorSHA3
+
+	MOVD low+0(FP), R10   // R10: &low
+	MOVD high+24(FP), R11 // R11: &high
+	VLD1 (R10), [V6.B16]
+	VLD1 (R11), [V7.B16]
+
+	MOVD $0x0f, R3
+	VMOV R3, V8.B[0]
+	VDUP V8.B[0], V8.B16
+
+loopXorSHA3:
+	// Main loop
+	VLD1 (R5), [V20.B16, V21.B16]
+
+	LOAD(V0, V1, V10, V11)
+
+	// Mul low part and mul high part
+	VTBL V0.B16, [V6.B16], V4.B16
+	VTBL V10.B16, [V7.B16], V5.B16
+	VTBL V1.B16, [V6.B16], V14.B16
+	VTBL V11.B16, [V7.B16], V15.B16
+
+	// Combine results and output with a single EOR3 each
+	VEOR3 V20.B16, V5.B16, V4.B16, V4.B16
+	VEOR3 V21.B16, V15.B16, V14.B16, V5.B16
+
+	// Store result
+	VST1.P [V4.D2, V5.D2], 32(R5)
+
+	SUBS $32, R2
+	BPL  loopXorSHA3
+
+completeXorSHA3:
+	RET
+
 TEXT ·getVectorLength(SB), NOSPLIT, $0
     WORD $0xd2800002 // mov   x2, #0
     WORD $0x04225022 // addvl x2, x2, #1
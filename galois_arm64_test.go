@@ -1,4 +1,4 @@
-//go:build !noasm && !appengine && !gccgo && !nopshufb
+//go:build !noasm && !purego && !appengine && !gccgo && !nopshufb
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 // Copyright 2024, Minio, Inc.
@@ -6,6 +6,7 @@
 package reedsolomon
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -17,3 +18,22 @@ func TestGenGalois(t *testing.T) {
 		testGenGaloisUpto10x10(t, galMulSlicesNeon, galMulSlicesNeonXor, 32)
 	}
 }
+
+func TestGalMulXorSHA3(t *testing.T) {
+	if !defaultOptions.useSHA3 {
+		t.Skip("SHA3 not supported")
+	}
+	gf := defaultOptions.gf()
+	in := make([]byte, 1000)
+	fillRandom(in)
+	for c := 0; c < 256; c++ {
+		want := make([]byte, len(in))
+		fillRandom(want)
+		got := append([]byte{}, want...)
+		galMulXorNEON(gf.low[c][:], gf.high[c][:], in, want)
+		galMulXorSHA3(gf.low[c][:], gf.high[c][:], in, got)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("mismatch for c=%d", c)
+		}
+	}
+}
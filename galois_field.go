@@ -0,0 +1,353 @@
+package reedsolomon
+
+import (
+	"errors"
+	"sync"
+)
+
+// FieldRepresentation describes how elements of GF(2^8) are mapped to bytes.
+//
+// A byte is interpreted as a polynomial over GF(2), reduced by Polynomial.
+// Generator is the primitive element used as the base of log/exp tables,
+// and is used when aligning representations in a FieldConverter.
+type FieldRepresentation struct {
+	// Polynomial is the reducing polynomial, including the x^8 term.
+	// For example 0x11d for x^8 + x^4 + x^3 + x^2 + 1.
+	Polynomial uint16
+
+	// Generator is a primitive element of the field.
+	Generator byte
+}
+
+var (
+	// FieldStandard is the representation used by this package by default.
+	// ISA-L, Jerasure (w=8), zfec and Backblaze all use this representation.
+	FieldStandard = FieldRepresentation{Polynomial: 0x11d, Generator: 2}
+
+	// FieldRijndael is the representation used by AES,
+	// also used by a number of legacy erasure coding systems.
+	FieldRijndael = FieldRepresentation{Polynomial: 0x11b, Generator: 3}
+
+	// FieldCM256 is the representation used by the CM256 library.
+	FieldCM256 = FieldRepresentation{Polynomial: 0x14d, Generator: 2}
+)
+
+// ErrInvalidField is returned if a FieldRepresentation does not describe GF(2^8).
+// The polynomial must be of degree 8 and irreducible,
+// and the generator must be a primitive element.
+var ErrInvalidField = errors.New("invalid field representation")
+
+// galoisField contains the tables for a specific representation.
+// toStd and fromStd map elements to and from the standard representation.
+// They are nil for the standard field.
+type galoisField struct {
+	rep     FieldRepresentation
+	mul     *[256][256]uint8
+	low     *[256][16]uint8
+	high    *[256][16]uint8
+	gfni    *[256]uint64
+	toStd   *[256]byte
+	fromStd *[256]byte
+}
+
+var stdField = &galoisField{
+	rep:  FieldStandard,
+	mul:  &mulTable,
+	low:  &mulTableLow,
+	high: &mulTableHigh,
+	gfni: &gf2p811dMulMatrices,
+}
+
+// gf returns the field used for the options.
+func (o *options) gf() *galoisField {
+	if o.field == nil {
+		return stdField
+	}
+	return o.field
+}
+
+var fieldCache sync.Map // FieldRepresentation -> *galoisField
+
+// newGaloisField returns the tables for the field representation.
+// Tables are cached, so repeated calls are cheap.
+func newGaloisField(rep FieldRepresentation) (*galoisField, error) {
+	if rep == FieldStandard {
+		return stdField, nil
+	}
+	if f, ok := fieldCache.Load(rep); ok {
+		return f.(*galoisField), nil
+	}
+	if err := rep.validate(); err != nil {
+		return nil, err
+	}
+	f := galoisField{
+		rep:     rep,
+		mul:     new([256][256]uint8),
+		low:     new([256][16]uint8),
+		high:    new([256][16]uint8),
+		gfni:    new([256]uint64),
+		toStd:   new([256]byte),
+		fromStd: new([256]byte),
+	}
+	for a := 0; a < 256; a++ {
+		for b := a; b < 256; b++ {
+			v := gfMulPoly(byte(a), byte(b), rep.Polynomial)
+			f.mul[a][b], f.mul[b][a] = v, v
+		}
+	}
+	for c := range f.mul {
+		for i := 0; i < 16; i++ {
+			f.low[c][i] = f.mul[c][i]
+			f.high[c][i] = f.mul[c][i<<4]
+		}
+		f.gfni[c] = gfniMulMatrix(&f.mul[c])
+	}
+	var err error
+	*f.toStd, err = fieldIsomorphism(rep, FieldStandard)
+	if err != nil {
+		return nil, err
+	}
+	// Invert the mapping instead of searching for a new one, since
+	// generators can only be aligned in one direction, if at all.
+	for v, std := range f.toStd {
+		f.fromStd[std] = byte(v)
+	}
+	v, _ := fieldCache.LoadOrStore(rep, &f)
+	return v.(*galoisField), nil
+}
+
+// validate checks that the generator is primitive in the field given by the polynomial.
+// A ring with zero divisors cannot contain an element of order 255,
+// so this also checks that the polynomial is irreducible.
+func (f FieldRepresentation) validate() error {
+	if f.Polynomial < 0x100 || f.Polynomial > 0x1ff || f.Generator < 2 {
+		return ErrInvalidField
+	}
+	var seen [256]bool
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		if seen[x] || x == 0 {
+			return ErrInvalidField
+		}
+		seen[x] = true
+		x = gfMulPoly(x, f.Generator, f.Polynomial)
+	}
+	if x != 1 {
+		return ErrInvalidField
+	}
+	return nil
+}
+
+// primitiveElement returns the smallest primitive element of GF(2^8)
+// with the reducing polynomial poly.
+// If the polynomial does not describe GF(2^8), 1 is returned,
+// so a FieldRepresentation using it is invalid.
+func primitiveElement(poly uint16) byte {
+	for g := 2; g < 256; g++ {
+		rep := FieldRepresentation{Polynomial: poly, Generator: byte(g)}
+		if rep.validate() == nil {
+			return byte(g)
+		}
+	}
+	return 1
+}
+
+// gfMulPoly multiplies a and b, reducing by poly.
+func gfMulPoly(a, b byte, poly uint16) byte {
+	var res uint16
+	aa := uint16(a)
+	for b != 0 {
+		if b&1 != 0 {
+			res ^= aa
+		}
+		aa <<= 1
+		if aa&0x100 != 0 {
+			aa ^= poly
+		}
+		b >>= 1
+	}
+	return byte(res)
+}
+
+// gfniMulMatrix returns the GF2P8AFFINEQB matrix for multiplying with the values in mt.
+// Byte 7-i of the matrix contains the input bits that affect output bit i.
+func gfniMulMatrix(mt *[256]uint8) uint64 {
+	var res uint64
+	for i := 0; i < 8; i++ {
+		var row uint64
+		for j := 0; j < 8; j++ {
+			if mt[1<<j]&(1<<i) != 0 {
+				row |= 1 << j
+			}
+		}
+		res |= row << (8 * (7 - i))
+	}
+	return res
+}
+
+// fieldIsomorphism returns a table that maps elements of 'from' to elements of 'to',
+// preserving addition and multiplication.
+// When possible the generator of 'from' is mapped to the generator of 'to',
+// so log tables of both representations agree.
+func fieldIsomorphism(from, to FieldRepresentation) (res [256]byte, err error) {
+	if err := from.validate(); err != nil {
+		return res, err
+	}
+	if err := to.validate(); err != nil {
+		return res, err
+	}
+	mul := func(a, b byte) byte { return gfMulPoly(a, b, to.Polynomial) }
+	// eval evaluates the polynomial with coefficients given by the bits of p at x in 'to'.
+	eval := func(p uint16, x byte) byte {
+		var res, xn byte = 0, 1
+		for ; p != 0; p >>= 1 {
+			if p&1 != 0 {
+				res ^= xn
+			}
+			xn = mul(xn, x)
+		}
+		return res
+	}
+	found := false
+	var root byte
+	for x := 2; x < 256; x++ {
+		if eval(from.Polynomial, byte(x)) != 0 {
+			continue
+		}
+		if !found {
+			root, found = byte(x), true
+		}
+		if eval(uint16(from.Generator), byte(x)) == to.Generator {
+			root = byte(x)
+			break
+		}
+	}
+	if !found {
+		return res, ErrInvalidField
+	}
+	for x := range res {
+		res[x] = eval(uint16(x), root)
+	}
+	return res, nil
+}
+
+// isStd returns true if f is the standard field.
+// A nil field is treated as the standard field.
+func (f *galoisField) isStd() bool {
+	return f == nil || f.toStd == nil
+}
+
+// toStdByte returns v in the standard representation.
+func (f *galoisField) toStdByte(v byte) byte {
+	if f.isStd() {
+		return v
+	}
+	return f.toStd[v]
+}
+
+// mapMatrix applies the table to all values of m, in place.
+func mapMatrix(m matrix, table *[256]byte) matrix {
+	for _, row := range m {
+		for i, v := range row {
+			row[i] = table[v]
+		}
+	}
+	return m
+}
+
+// fromStdMatrix converts m from the standard representation, in place.
+func (f *galoisField) fromStdMatrix(m matrix) matrix {
+	if f.isStd() {
+		return m
+	}
+	return mapMatrix(m, f.fromStd)
+}
+
+// vandermonde returns a Vandermonde matrix evaluated at the points 0 to rows-1 of f.
+// The returned matrix is in the standard representation.
+func (f *galoisField) vandermonde(rows, cols int) (matrix, error) {
+	if f.isStd() {
+		return vandermonde(rows, cols)
+	}
+	result, err := newMatrix(rows, cols)
+	if err != nil {
+		return nil, err
+	}
+	for r, row := range result {
+		for c := range row {
+			result[r][c] = galExp(f.toStd[byte(r)], c)
+		}
+	}
+	return result, nil
+}
+
+// invert returns the inverse of m, where m is in the representation of f.
+func (f *galoisField) invert(m matrix) (matrix, error) {
+	if f.isStd() {
+		return m.Invert()
+	}
+	work, err := m.SubMatrix(0, 0, len(m), len(m[0]))
+	if err != nil {
+		return nil, err
+	}
+	inv, err := mapMatrix(work, f.toStd).Invert()
+	if err != nil {
+		return nil, err
+	}
+	return mapMatrix(inv, f.fromStd), nil
+}
+
+// FieldConverter converts symbols between two representations of GF(2^8).
+//
+// Converting all shards of an encoded set, as well as the coding matrix,
+// will produce a valid encoded set in the target representation.
+type FieldConverter struct {
+	fwd, rev [256]byte
+}
+
+// NewFieldConverter returns a converter from one field representation to another.
+func NewFieldConverter(from, to FieldRepresentation) (*FieldConverter, error) {
+	var c FieldConverter
+	var err error
+	c.fwd, err = fieldIsomorphism(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range c.fwd {
+		c.rev[v] = byte(i)
+	}
+	return &c, nil
+}
+
+// Reverse returns a converter that performs the inverse conversion.
+func (c *FieldConverter) Reverse() *FieldConverter {
+	return &FieldConverter{fwd: c.rev, rev: c.fwd}
+}
+
+// Convert converts the symbols in src and writes them to dst.
+// dst must be at least the size of src. dst and src may be the same slice.
+func (c *FieldConverter) Convert(dst, src []byte) {
+	dst = dst[:len(src)]
+	for i, v := range src {
+		dst[i] = c.fwd[v]
+	}
+}
+
+// ConvertShards converts all shards in place.
+// Nil shards are skipped.
+func (c *FieldConverter) ConvertShards(shards [][]byte) {
+	for _, shard := range shards {
+		c.Convert(shard, shard)
+	}
+}
+
+// ConvertMatrix returns a converted copy of a coding matrix,
+// for example one given to WithCustomMatrix.
+func (c *FieldConverter) ConvertMatrix(m [][]byte) [][]byte {
+	res := make([][]byte, len(m))
+	for i, row := range m {
+		res[i] = make([]byte, len(row))
+		c.Convert(res[i], row)
+	}
+	return res
+}
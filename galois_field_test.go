@@ -0,0 +1,230 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFieldTables(t *testing.T) {
+	for c := range mulTable {
+		if got := gfniMulMatrix(&mulTable[c]); got != gf2p811dMulMatrices[c] {
+			t.Fatalf("gfni matrix %d: got %#x, want %#x", c, got, gf2p811dMulMatrices[c])
+		}
+		for x := range mulTable[c] {
+			if got := gfMulPoly(byte(c), byte(x), FieldStandard.Polynomial); got != mulTable[c][x] {
+				t.Fatalf("%d*%d: got %d, want %d", c, x, got, mulTable[c][x])
+			}
+		}
+	}
+	f, err := newGaloisField(FieldRijndael)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// From FIPS 197, section 4.2
+	if got := f.mul[0x57][0x83]; got != 0xc1 {
+		t.Errorf("0x57*0x83: got %#x, want 0xc1", got)
+	}
+	for c := range f.mul {
+		for x := 0; x < 16; x++ {
+			if f.low[c][x] != f.mul[c][x] || f.high[c][x] != f.mul[c][x<<4] {
+				t.Fatal("split table mismatch for", c)
+			}
+		}
+	}
+	for _, rep := range []FieldRepresentation{FieldRijndael, FieldCM256} {
+		f, err := newGaloisField(rep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for v := range f.toStd {
+			if f.fromStd[f.toStd[v]] != byte(v) {
+				t.Fatalf("%v: %d does not map back to itself", rep, v)
+			}
+		}
+	}
+}
+
+func TestFieldInvalid(t *testing.T) {
+	for _, rep := range []FieldRepresentation{
+		{Polynomial: 0x11d, Generator: 1},
+		{Polynomial: 0x100, Generator: 2},
+		{Polynomial: 0x11b, Generator: 2}, // 2 has order 51
+		{Polynomial: 0x11f, Generator: 2}, // Reducible
+		{Polynomial: 0x21d, Generator: 2},
+	} {
+		if _, err := New(4, 2, WithFieldRepresentation(rep)); err != ErrInvalidField {
+			t.Errorf("%+v: want ErrInvalidField, got %v", rep, err)
+		}
+		if _, err := NewFieldConverter(FieldStandard, rep); err != ErrInvalidField {
+			t.Errorf("%+v: want ErrInvalidField, got %v", rep, err)
+		}
+	}
+	if _, err := New(4, 2, WithFieldRepresentation(FieldRijndael), WithLeopardGF(true)); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+}
+
+func TestFieldPolynomial(t *testing.T) {
+	for _, rep := range []FieldRepresentation{FieldStandard, FieldRijndael, FieldCM256} {
+		var o options
+		WithFieldPolynomial(rep.Polynomial)(&o)
+		if o.fieldRep != rep {
+			t.Errorf("%#x: got %+v, want %+v", rep.Polynomial, o.fieldRep, rep)
+		}
+	}
+	enc, err := New(10, 4, WithFieldPolynomial(0x11d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.(*reedSolomon).o.field != nil {
+		t.Error("0x11d did not use the standard field")
+	}
+
+	// Parity must match the representation with the same polynomial.
+	encPoly, err := New(10, 4, testOptions(WithFieldPolynomial(0x11b))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encRep, err := New(10, 4, testOptions(WithFieldRepresentation(FieldRijndael))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := AllocAligned(14, 1000)
+	for _, s := range shards[:10] {
+		fillRandom(s)
+	}
+	want := AllocAligned(14, 1000)
+	for i := range shards[:10] {
+		copy(want[i], shards[i])
+	}
+	if err := encPoly.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	if err := encRep.Encode(want); err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if !bytes.Equal(shards[i], want[i]) {
+			t.Fatalf("shard %d mismatch", i)
+		}
+	}
+
+	for _, poly := range []uint16{0, 0x100, 0x11f, 0x21d} {
+		if _, err := New(4, 2, WithFieldPolynomial(poly)); err != ErrInvalidField {
+			t.Errorf("%#x: want ErrInvalidField, got %v", poly, err)
+		}
+	}
+}
+
+func TestFieldEncoding(t *testing.T) {
+	const dataShards, parityShards = 10, 4
+	opts := [][]Option{
+		{}, {WithCauchyMatrix()}, {WithPAR1Matrix()}, {WithJerasureMatrix()},
+		{WithMaxGoroutines(1), WithSSSE3(false), WithAVX2(false), WithAVX512(false), WithAVXGFNI(false)},
+		{WithGFNI(false), WithAVXGFNI(false)},
+		{WithInversionCache(false)},
+	}
+	for _, opt := range opts {
+		opt = append(testOptions(opt...), WithFieldRepresentation(FieldRijndael))
+		enc, err := New(dataShards, parityShards, opt...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := enc.(*reedSolomon)
+		for _, size := range []int{1, 63, 1000, 50000} {
+			shards := r.AllocAligned(size)
+			for _, s := range shards[:dataShards] {
+				fillRandom(s, int64(size))
+			}
+			if err := enc.Encode(shards); err != nil {
+				t.Fatal(err)
+			}
+			// Compare with a slow encode in the field.
+			for p := 0; p < parityShards; p++ {
+				want := make([]byte, size)
+				for d := 0; d < dataShards; d++ {
+					for i, v := range shards[d] {
+						want[i] ^= gfMulPoly(r.m[dataShards+p][d], v, FieldRijndael.Polynomial)
+					}
+				}
+				if !bytes.Equal(want, shards[dataShards+p]) {
+					t.Fatalf("size %d, parity %d mismatch", size, p)
+				}
+			}
+			want := make([][]byte, len(shards))
+			for i := range shards {
+				want[i] = append([]byte{}, shards[i]...)
+			}
+			shards[0], shards[3], shards[dataShards], shards[dataShards+3] = nil, nil, nil, nil
+			if err := enc.Reconstruct(shards); err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(want[i], shards[i]) {
+					t.Fatalf("size %d, shard %d: reconstruct mismatch", size, i)
+				}
+			}
+			ok, err := enc.Verify(shards)
+			if !ok || err != nil {
+				t.Fatal("verify failed", err)
+			}
+		}
+	}
+}
+
+func TestFieldConverter(t *testing.T) {
+	const dataShards, parityShards = 6, 3
+	conv, err := NewFieldConverter(FieldStandard, FieldRijndael)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tmp [1]byte
+	conv.Convert(tmp[:], []byte{FieldStandard.Generator})
+	if tmp[0] != FieldRijndael.Generator {
+		t.Errorf("generator mapped to %d", tmp[0])
+	}
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			var in, out [2]byte
+			in[0], in[1] = byte(a), galMultiply(byte(a), byte(b))
+			conv.Convert(out[:], in[:])
+			var bb [1]byte
+			conv.Convert(bb[:], []byte{byte(b)})
+			if gfMulPoly(out[0], bb[0], FieldRijndael.Polynomial) != out[1] {
+				t.Fatal("conversion does not preserve multiplication", a, b)
+			}
+		}
+	}
+
+	enc, err := New(dataShards, parityShards, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, s := range shards[:dataShards] {
+		fillRandom(s)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	orig := make([][]byte, len(shards))
+	for i := range shards {
+		orig[i] = append([]byte{}, shards[i]...)
+	}
+	conv.ConvertShards(shards)
+	m := conv.ConvertMatrix(enc.(*reedSolomon).m[dataShards:])
+	enc2, err := New(dataShards, parityShards, testOptions(WithFieldRepresentation(FieldRijndael), WithCustomMatrix(m))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc2.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("converted shards did not verify", err)
+	}
+	conv.Reverse().ConvertShards(shards)
+	for i := range shards {
+		if !bytes.Equal(orig[i], shards[i]) {
+			t.Fatal("round trip mismatch on shard", i)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 // Code generated by command: go generate gen.go. DO NOT EDIT.
 
-//go:build !noasm && !appengine && !gccgo && !nopshufb
+//go:build !noasm && !purego && !appengine && !gccgo && !nopshufb
 
 package reedsolomon
 
@@ -1,6 +1,6 @@
 // Code generated by command: go generate gen.go. DO NOT EDIT.
 
-//go:build !appengine && !noasm && !nogen && !nopshufb && gc
+//go:build !appengine && !noasm && !purego && !nogen && !nopshufb && gc
 
 #include "textflag.h"
 
@@ -1,4 +1,4 @@
-//go:build !(amd64 || arm64) || noasm || appengine || gccgo || nogen
+//go:build !(amd64 || arm64) || noasm || purego || appengine || gccgo || nogen
 
 package reedsolomon
 
@@ -1,6 +1,6 @@
 // Code generated by command: go run gen.go -out ../galois_gen_nopshufb_amd64.s -stubs ../galois_gen_nopshufb_amd64.go -pkg=reedsolomon. DO NOT EDIT.
 
-//go:build !appengine && !noasm && !nogen && nopshufb && gc
+//go:build !appengine && !noasm && !purego && !nogen && nopshufb && gc
 
 #include "textflag.h"
 
@@ -1,7 +1,7 @@
 // Code generated by command: go generate gen.go. DO NOT EDIT.
 
-//go:build !appengine && !noasm && gc && !nogen && !nopshufb
-// +build !appengine,!noasm,gc,!nogen,!nopshufb
+//go:build !appengine && !noasm && !purego && gc && !nogen && !nopshufb
+// +build !appengine,!noasm,!purego,gc,!nogen,!nopshufb
 
 package reedsolomon
 
@@ -1,5 +1,5 @@
-//go:build !appengine && !noasm && gc && !nogen && !nopshufb
-// +build !appengine,!noasm,gc,!nogen,!nopshufb
+//go:build !appengine && !noasm && !purego && gc && !nogen && !nopshufb
+// +build !appengine,!noasm,!purego,gc,!nogen,!nopshufb
 
 package reedsolomon
 
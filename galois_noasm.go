@@ -1,4 +1,4 @@
-//go:build (!amd64 || noasm || appengine || gccgo) && (!arm64 || noasm || appengine || gccgo || nopshufb) && (!ppc64le || noasm || appengine || gccgo || nopshufb)
+//go:build (!amd64 || noasm || purego || appengine || gccgo) && (!arm64 || noasm || purego || appengine || gccgo || nopshufb) && (!ppc64le || noasm || purego || appengine || gccgo || nopshufb)
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 
@@ -7,24 +7,34 @@ package reedsolomon
 const pshufb = false
 
 func galMulSlice(c byte, in, out []byte, o *options) {
+	t := o.gf()
 	out = out[:len(in)]
 	if c == 1 {
 		copy(out, in)
 		return
 	}
-	mt := mulTable[c][:256]
+	if o.constantTime {
+		galMulSliceCT(c, in, out, t, false)
+		return
+	}
+	mt := t.mul[c][:256]
 	for n, input := range in {
 		out[n] = mt[input]
 	}
 }
 
 func galMulSliceXor(c byte, in, out []byte, o *options) {
+	t := o.gf()
 	out = out[:len(in)]
 	if c == 1 {
 		sliceXor(in, out, o)
 		return
 	}
-	mt := mulTable[c][:256]
+	if o.constantTime {
+		galMulSliceCT(c, in, out, t, true)
+		return
+	}
+	mt := t.mul[c][:256]
 	for n, input := range in {
 		out[n] ^= mt[input]
 	}
@@ -1,6 +1,6 @@
 // Copyright 2015, Klaus Post, see LICENSE for details
 
-//go:build nopshufb && !noasm
+//go:build nopshufb && !noasm && !purego
 
 package reedsolomon
 
@@ -11,6 +11,9 @@ const pshufb = false
 
 // simple slice xor
 func sliceXor(in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
 	if o.useSSE2 {
 		if len(in) >= bigSwitchover {
 			if o.useAVX2 {
@@ -42,12 +45,20 @@ func sliceXor(in, out []byte, o *options) {
 }
 
 func galMulSlice(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	out = out[:len(in)]
 	if c == 1 {
 		copy(out, in)
 		return
 	}
-	mt := mulTable[c][:256]
+	if o.constantTime {
+		galMulSliceCT(c, in, out, t, false)
+		return
+	}
+	mt := t.mul[c][:256]
 	for len(in) >= 4 {
 		ii := (*[4]byte)(in)
 		oo := (*[4]byte)(out)
@@ -64,12 +75,20 @@ func galMulSlice(c byte, in, out []byte, o *options) {
 }
 
 func galMulSliceXor(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	out = out[:len(in)]
 	if c == 1 {
 		sliceXor(in, out, o)
 		return
 	}
-	mt := mulTable[c][:256]
+	if o.constantTime {
+		galMulSliceCT(c, in, out, t, true)
+		return
+	}
+	mt := t.mul[c][:256]
 	for len(in) >= 4 {
 		ii := (*[4]byte)(in)
 		oo := (*[4]byte)(out)
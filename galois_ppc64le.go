@@ -1,4 +1,4 @@
-//go:build !noasm && !appengine && !gccgo && !nopshufb
+//go:build !noasm && !purego && !appengine && !gccgo && !nopshufb
 
 // Copyright 2015, Klaus Post, see LICENSE for details.
 // Copyright 2018, Minio, Inc.
@@ -32,17 +32,23 @@ func galMulPpcXor(low, high, in, out []byte) {
 */
 
 func galMulSlice(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	if c == 1 {
 		copy(out, in)
 		return
 	}
 	done := (len(in) >> 4) << 4
 	if done > 0 {
-		galMulPpc(mulTableLow[c][:], mulTableHigh[c][:], in[:done], out)
+		galMulPpc(t.low[c][:], t.high[c][:], in[:done], out)
 	}
 	remain := len(in) - done
-	if remain > 0 {
-		mt := mulTable[c][:256]
+	if remain > 0 && o.constantTime {
+		galMulSliceCT(c, in[done:], out[done:], t, false)
+	} else if remain > 0 {
+		mt := t.mul[c][:256]
 		for i := done; i < len(in); i++ {
 			out[i] = mt[in[i]]
 		}
@@ -50,17 +56,23 @@ func galMulSlice(c byte, in, out []byte, o *options) {
 }
 
 func galMulSliceXor(c byte, in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
+	t := o.gf()
 	if c == 1 {
 		sliceXor(in, out, o)
 		return
 	}
 	done := (len(in) >> 4) << 4
 	if done > 0 {
-		galMulPpcXor(mulTableLow[c][:], mulTableHigh[c][:], in[:done], out)
+		galMulPpcXor(t.low[c][:], t.high[c][:], in[:done], out)
 	}
 	remain := len(in) - done
-	if remain > 0 {
-		mt := mulTable[c][:256]
+	if remain > 0 && o.constantTime {
+		galMulSliceCT(c, in[done:], out[done:], t, true)
+	} else if remain > 0 {
+		mt := t.mul[c][:256]
 		for i := done; i < len(in); i++ {
 			out[i] ^= mt[in[i]]
 		}
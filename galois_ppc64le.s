@@ -1,4 +1,5 @@
 //+build !noasm
+//+build !purego
 //+build !appengine
 //+build !gccgo
 //+build !pshufb
@@ -186,6 +186,31 @@ func TestGalois(t *testing.T) {
 	}
 }
 
+func TestGalMulSliceCT(t *testing.T) {
+	in := make([]byte, 100)
+	fillRandom(in)
+	for c := 0; c < 256; c++ {
+		want := make([]byte, len(in))
+		got := make([]byte, len(in))
+		fillRandom(want)
+		copy(got, want)
+		galMulSliceCT(byte(c), in, got, stdField, true)
+		for i, v := range in {
+			want[i] ^= galMultiply(byte(c), v)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("xor, c=%d: got %v, want %v", c, got, want)
+		}
+		galMulSliceCT(byte(c), in, got, stdField, false)
+		for i, v := range in {
+			want[i] = galMultiply(byte(c), v)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("c=%d: got %v, want %v", c, got, want)
+		}
+	}
+}
+
 func TestSliceGalAdd(t *testing.T) {
 
 	lengthList := []int{16, 32, 34}
@@ -270,7 +295,7 @@ func testGenGalois(t *testing.T, matrixRows [][]byte, size, start, stop int, f f
 		}
 	}
 
-	m := genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), vectorLength, nil)
+	m := genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), vectorLength, nil, stdField)
 
 	end := start + f(m, inputs, outputs, start, stop)
 	if end != stop {
@@ -327,7 +352,7 @@ func testGenGaloisXor(t *testing.T, matrixRows [][]byte, size, start, stop int,
 		}
 	}
 
-	m := genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), vectorLength, nil)
+	m := genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), vectorLength, nil, stdField)
 
 	end := start + f(m, inputs, outputs, start, stop)
 	if end != stop {
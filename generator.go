@@ -0,0 +1,185 @@
+package reedsolomon
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// ErrInvalidMatrix is returned by New if the matrix given with WithCustomGenerator
+// cannot be used, and by IsMDS if the matrix is not MDS.
+// The returned error wraps ErrInvalidMatrix and describes the problem.
+var ErrInvalidMatrix = errors.New("invalid generator matrix")
+
+// generatorMaxChecks is the maximum number of submatrices checked by checkMDS.
+const generatorMaxChecks = 1 << 20
+
+// buildMatrixGenerator returns the systematic encoding matrix of the code
+// generated by g, which has a row for each shard and a column for each data shard.
+// The matrix is multiplied by the inverse of its top square, like buildMatrix does,
+// which does not change the code.
+func buildMatrixGenerator(g [][]byte, dataShards, totalShards int, f *galoisField) (matrix, error) {
+	if len(g) != totalShards {
+		return nil, fmt.Errorf("%w: %d rows, want %d", ErrInvalidMatrix, len(g), totalShards)
+	}
+	m := make(matrix, totalShards)
+	for i, row := range g {
+		if len(row) != dataShards {
+			return nil, fmt.Errorf("%w: row %d has %d columns, want %d", ErrInvalidMatrix, i, len(row), dataShards)
+		}
+		m[i] = append([]byte(nil), row...)
+	}
+	if !f.isStd() {
+		mapMatrix(m, f.toStd)
+	}
+
+	top, err := m.SubMatrix(0, 0, dataShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+	topInv, err := top.Invert()
+	if err != nil {
+		return nil, fmt.Errorf("%w: the first %d rows are not linearly independent", ErrInvalidMatrix, dataShards)
+	}
+	m, err = m.Multiply(topInv)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMDS(m[dataShards:], dataShards, 0); err != nil {
+		return nil, err
+	}
+	return f.fromStdMatrix(m), nil
+}
+
+// IsMDS reports whether the code with the given parity matrix, as given to
+// WithCustomMatrix, is MDS, meaning that the data can be recovered from any
+// dataShards shards. Only the first dataShards columns of parity are used.
+// The result does not depend on the field representation.
+//
+// Instead of inverting every combination of dataShards rows,
+// all square submatrices of the parity rows are checked for invertibility
+// in increasing size, so combinations involving few parity shards are found first.
+// If there are more than a million submatrices, only the first million
+// are checked, followed by randomChecks submatrices chosen at random.
+// A true result is then not a proof, but the check can be repeated with
+// more random checks to increase the confidence.
+//
+// If the code is not MDS, false is returned with an error wrapping
+// ErrInvalidMatrix that names shards which cannot be recovered.
+func IsMDS(parity [][]byte, dataShards, randomChecks int) (bool, error) {
+	if dataShards <= 0 || len(parity) == 0 {
+		return false, ErrInvShardNum
+	}
+	m := make(matrix, len(parity))
+	for i, row := range parity {
+		if len(row) < dataShards {
+			return false, fmt.Errorf("%w: row %d has %d columns, want %d", ErrInvalidMatrix, i, len(row), dataShards)
+		}
+		m[i] = row[:dataShards]
+	}
+	if err := checkMDS(m, dataShards, randomChecks); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkMDS checks that all square submatrices of the parity rows of a
+// systematic matrix are invertible, so any dataShards rows of the
+// matrix are invertible.
+// Submatrices are checked in increasing size, until generatorMaxChecks
+// submatrices have been checked. After that randomChecks random
+// submatrices are checked.
+func checkMDS(parity matrix, dataShards, randomChecks int) error {
+	checks := 0
+	work := make(matrix, len(parity))
+	for i := range work {
+		work[i] = make([]byte, dataShards)
+	}
+	maxSize := len(parity)
+	if dataShards < maxSize {
+		maxSize = dataShards
+	}
+	for size := 1; size <= maxSize; size++ {
+		rows, cols := make([]int, size), make([]int, size)
+		for i := range rows {
+			rows[i] = i
+		}
+		for {
+			for i := range cols {
+				cols[i] = i
+			}
+			for {
+				if checks == generatorMaxChecks {
+					return checkMDSRandom(parity, dataShards, randomChecks, work)
+				}
+				checks++
+				if err := checkSubmatrix(parity, dataShards, rows, cols, work); err != nil {
+					return err
+				}
+				if !nextCombination(cols, dataShards) {
+					break
+				}
+			}
+			if !nextCombination(rows, len(parity)) {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// checkMDSRandom checks n random square submatrices of parity.
+func checkMDSRandom(parity matrix, dataShards, n int, work matrix) error {
+	maxSize := len(parity)
+	if dataShards < maxSize {
+		maxSize = dataShards
+	}
+	for ; n > 0; n-- {
+		size := 1 + rand.Intn(maxSize)
+		rows := rand.Perm(len(parity))[:size]
+		cols := rand.Perm(dataShards)[:size]
+		sort.Ints(rows)
+		sort.Ints(cols)
+		if err := checkSubmatrix(parity, dataShards, rows, cols, work); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSubmatrix checks that the submatrix of parity with the given rows and
+// columns is invertible, using work as scratch space.
+func checkSubmatrix(parity matrix, dataShards int, rows, cols []int, work matrix) error {
+	sub := work[:len(rows)]
+	for i, r := range rows {
+		sub[i] = sub[i][:len(cols)]
+		for j, c := range cols {
+			sub[i][j] = parity[r][c]
+		}
+	}
+	if sub.gaussianElimination() == nil {
+		return nil
+	}
+	parityShards := make([]int, len(rows))
+	for i, r := range rows {
+		parityShards[i] = dataShards + r
+	}
+	return fmt.Errorf("%w: data shards %v cannot be recovered from parity shards %v",
+		ErrInvalidMatrix, cols, parityShards)
+}
+
+// nextCombination advances idx to the next increasing combination of values below n.
+// It returns false when idx was the last combination.
+func nextCombination(idx []int, n int) bool {
+	for i := len(idx) - 1; i >= 0; i-- {
+		if idx[i] < n-len(idx)+i {
+			idx[i]++
+			for j := i + 1; j < len(idx); j++ {
+				idx[j] = idx[j-1] + 1
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCustomGenerator(t *testing.T) {
+	const dataShards, parityShards = 6, 4
+	for _, rep := range []FieldRepresentation{FieldStandard, FieldRijndael} {
+		f, err := newGaloisField(rep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := f.vandermonde(dataShards+parityShards, dataShards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f.isStd() {
+			mapMatrix(g, f.fromStd)
+		}
+		want, err := New(dataShards, parityShards, append(testOptions(), WithFieldRepresentation(rep))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := New(dataShards, parityShards, append(testOptions(), WithFieldRepresentation(rep), WithCustomGenerator(g))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := want.(Extensions).AllocAligned(1000)
+		for _, s := range shards[:dataShards] {
+			fillRandom(s)
+		}
+		if err := want.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		got := make([][]byte, len(shards))
+		for i := range got {
+			got[i] = make([]byte, len(shards[i]))
+			if i < dataShards {
+				copy(got[i], shards[i])
+			}
+		}
+		if err := enc.Encode(got); err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if !bytes.Equal(got[i], shards[i]) {
+				t.Fatalf("%v: shard %d differs from the default encoder", rep, i)
+			}
+		}
+		got[0], got[3], got[7], got[9] = nil, nil, nil, nil
+		if err := enc.Reconstruct(got); err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if !bytes.Equal(got[i], shards[i]) {
+				t.Fatalf("%v: shard %d not reconstructed", rep, i)
+			}
+		}
+	}
+}
+
+func TestCustomGeneratorInvalid(t *testing.T) {
+	const dataShards, parityShards = 3, 2
+	identity := func(rows int) [][]byte {
+		m := make([][]byte, rows)
+		for i := range m {
+			m[i] = make([]byte, dataShards)
+			if i < dataShards {
+				m[i][i] = 1
+			}
+		}
+		return m
+	}
+	short := identity(dataShards + parityShards)
+	short[4] = short[4][:2]
+	singular := identity(dataShards + parityShards)
+	singular[2] = []byte{1, 0, 0}
+	for _, row := range singular[dataShards:] {
+		copy(row, []byte{1, 2, 3})
+	}
+	zero := identity(dataShards + parityShards)
+	copy(zero[3], []byte{1, 1, 1})
+	copy(zero[4], []byte{1, 0, 2})
+	dependent := identity(dataShards + parityShards)
+	copy(dependent[3], []byte{1, 1, 1})
+	copy(dependent[4], []byte{2, 2, 3})
+
+	for name, g := range map[string][][]byte{
+		"rows":      identity(dataShards + parityShards + 1),
+		"columns":   short,
+		"singular":  singular,
+		"zero":      zero,
+		"dependent": dependent,
+	} {
+		_, err := New(dataShards, parityShards, append(testOptions(), WithCustomGenerator(g))...)
+		if !errors.Is(err, ErrInvalidMatrix) {
+			t.Errorf("%s: got error %v, want ErrInvalidMatrix", name, err)
+		} else {
+			t.Logf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestIsMDS(t *testing.T) {
+	m, err := buildMatrixPAR1(4, 8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := IsMDS(m[4:], 4, 0)
+	if ok || !errors.Is(err, ErrInvalidMatrix) {
+		t.Fatalf("PAR1: got %v, %v, want false, ErrInvalidMatrix", ok, err)
+	}
+	t.Log("PAR1:", err)
+
+	m, err = buildMatrix(10, 14, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := IsMDS(m[10:], 10, 0); !ok || err != nil {
+		t.Fatalf("default matrix: got %v, %v", ok, err)
+	}
+	if _, err := IsMDS(nil, 10, 0); err != ErrInvShardNum {
+		t.Fatalf("no rows: got %v, want ErrInvShardNum", err)
+	}
+
+	// Compare against inverting all combinations of rows of small random matrices.
+	const dataShards, parityShards = 3, 3
+	for n := 0; n < 200; n++ {
+		full := make(matrix, dataShards+parityShards)
+		for i := range full {
+			full[i] = make([]byte, dataShards)
+			if i < dataShards {
+				full[i][i] = 1
+				continue
+			}
+			for j := range full[i] {
+				full[i][j] = byte(rand.Intn(4))
+			}
+		}
+		want := true
+		rows := []int{0, 1, 2}
+		for want {
+			sub := make(matrix, dataShards)
+			for i, r := range rows {
+				sub[i] = full[r]
+			}
+			if _, err := sub.Invert(); err != nil {
+				want = false
+			}
+			if !nextCombination(rows, len(full)) {
+				break
+			}
+		}
+		got, err := IsMDS(full[dataShards:], dataShards, 0)
+		if got != want || got != (err == nil) {
+			t.Fatalf("%v: got %v, %v, want %v", full, got, err, want)
+		}
+	}
+}
+
+func TestIsMDSRandom(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow")
+	}
+	m, err := buildMatrixCauchy(128, 256, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := IsMDS(m[128:], 128, 1000); !ok || err != nil {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+}
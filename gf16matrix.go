@@ -0,0 +1,581 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"hash/maphash"
+	"io"
+)
+
+// gf16Matrix is an encoder using a Cauchy matrix over the GF(2^16) field
+// of leopardFF16, so shards are multiplied with the same SIMD kernels.
+// Unlike leopardFF16, it supports EncodeIdx, Update and caching of
+// the decoding matrices, but each parity shard costs a multiplication
+// of every data shard.
+// Construct using New with WithGF16Matrix.
+type gf16Matrix struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	// parity contains the coefficients of the data shards for each parity shard.
+	parity [][]ffe
+
+	states    freeList[gf16MatrixState] // Scratch state for operations.
+	inversion *leopardCache[uint64, *gf16Decode]
+	cacheSeed maphash.Seed
+
+	o options
+}
+
+// gf16MatrixState is the state of a gf16Matrix operation.
+type gf16MatrixState struct {
+	tmp   []byte // Products, before they are added to outputs.
+	delta []byte // Changes of data shards for Update.
+	key   []byte // Inversion cache key.
+}
+
+// gf16Decode contains the rows recovering the missing data shards of an erasure pattern,
+// from the first parity shards present followed by the data shards present.
+type gf16Decode struct {
+	key  string // Key returned by cacheKey.
+	rows [][]ffe
+}
+
+// size returns the bytes used by d.
+func (d *gf16Decode) size() int64 {
+	n := int64(len(d.key))
+	for _, row := range d.rows {
+		n += int64(len(row) * 2)
+	}
+	return n
+}
+
+// gf16MatrixBlockSize is the number of bytes of each shard processed at a time.
+const gf16MatrixBlockSize = 32 << 10
+
+// newGF16Matrix is like New, but for the GF(2^16) Cauchy matrix.
+func newGF16Matrix(dataShards, parityShards int, opt options) (*gf16Matrix, error) {
+	initConstants()
+
+	if dataShards <= 0 || parityShards < 0 {
+		return nil, ErrInvShardNum
+	}
+	if dataShards+parityShards > order {
+		return nil, ErrMaxShardNum
+	}
+
+	r := &gf16Matrix{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		parity:       make([][]ffe, parityShards),
+		o:            opt,
+	}
+	// The coefficient of data shard i in parity shard j is 1/(x_j + y_i),
+	// with x_j = dataShards+j and y_i = i.
+	// All square submatrices of a Cauchy matrix are invertible,
+	// so any dataShards shards can recover the data.
+	for j := range r.parity {
+		row := make([]ffe, dataShards)
+		for i := range row {
+			row[i] = gf16Inv(ffe(dataShards+j) ^ ffe(i))
+		}
+		r.parity[j] = row
+	}
+	if opt.inversionCache {
+		r.inversion = newLeopardCache[uint64](&opt, (*gf16Decode).size)
+		r.cacheSeed = maphash.MakeSeed()
+	}
+	if n := opt.keptStates(); n > 0 {
+		r.states.keep(n)
+	}
+	return r, nil
+}
+
+var _ = Extensions(&gf16Matrix{})
+
+// gf16Mul returns a * b.
+func gf16Mul(a, b ffe) ffe {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return mulLog(a, logLUT[b])
+}
+
+// gf16Inv returns the multiplicative inverse of a, which must be non-zero.
+func gf16Inv(a ffe) ffe {
+	return expLUT[modulus-logLUT[a]]
+}
+
+// getState returns a state with a temporary buffer of gf16MatrixBlockSize bytes.
+func (r *gf16Matrix) getState() *gf16MatrixState {
+	st := r.states.get()
+	if st.tmp == nil {
+		st.tmp = AllocAligned(1, gf16MatrixBlockSize)[0]
+	}
+	return st
+}
+
+// mul sets out to in multiplied by c.
+func (r *gf16Matrix) mul(out, in []byte, c ffe) {
+	switch c {
+	case 0:
+		memclr(out)
+	case 1:
+		copy(out, in)
+	default:
+		mulgf16(out, in, logLUT[c], &r.o)
+	}
+}
+
+// mulAdd adds in multiplied by c to out.
+// tmp must be at least as long as in.
+func (r *gf16Matrix) mulAdd(out, in []byte, c ffe, tmp []byte) {
+	switch c {
+	case 0:
+	case 1:
+		sliceXor(in, out, &r.o)
+	default:
+		tmp = tmp[:len(in)]
+		mulgf16(tmp, in, logLUT[c], &r.o)
+		sliceXor(tmp, out, &r.o)
+	}
+}
+
+// mulRows sets outputs[j] to the sum of rows[j][i] * inputs[i].
+func (r *gf16Matrix) mulRows(rows [][]ffe, inputs, outputs [][]byte, byteCount int) {
+	st := r.getState()
+	defer r.states.put(st)
+	for start := 0; start < byteCount; start += gf16MatrixBlockSize {
+		end := start + gf16MatrixBlockSize
+		if end > byteCount {
+			end = byteCount
+		}
+		for j, out := range outputs {
+			out := out[start:end]
+			set := false
+			for i, in := range inputs {
+				c := rows[j][i]
+				switch {
+				case c == 0:
+				case !set:
+					r.mul(out, in[start:end], c)
+					set = true
+				default:
+					r.mulAdd(out, in[start:end], c, st.tmp)
+				}
+			}
+			if !set {
+				memclr(out)
+			}
+		}
+	}
+}
+
+func (r *gf16Matrix) ShardSizeMultiple() int {
+	return 64
+}
+
+func (r *gf16Matrix) DataShards() int {
+	return r.dataShards
+}
+
+func (r *gf16Matrix) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *gf16Matrix) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *gf16Matrix) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+func (r *gf16Matrix) PureGo() bool {
+	return r.o.pureGo()
+}
+
+// Recalibrate does nothing, since the GF(2^16) matrix encoder does not split work between goroutines.
+func (r *gf16Matrix) Recalibrate(shardSize int) {}
+
+func (r *gf16Matrix) CostModel() CostModel {
+	var c CostModel
+	if r.parityShards == 0 {
+		return c
+	}
+	d, p := float64(r.dataShards), float64(r.parityShards)
+	c.EncodeMulsPerByte = p
+	c.EncodeBytesPerByte = (d + p) / d
+	c.ReconstructMulsPerByte = d
+	c.ReconstructBytesPerByte = d + 1
+	return c
+}
+
+func (r *gf16Matrix) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	if len(shards[0])%64 != 0 {
+		return ErrInvalidShardSize
+	}
+	r.mulRows(r.parity, shards[:r.dataShards], shards[r.dataShards:], len(shards[0]))
+	return nil
+}
+
+// EncodeIdx will add parity for a single data shard.
+// Parity shards should start out zeroed. The caller must zero them before first call.
+// Data shards should only be delivered once. There is no check for this.
+func (r *gf16Matrix) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	if len(parity) != r.parityShards {
+		return ErrTooFewShards
+	}
+	if len(parity) == 0 {
+		return nil
+	}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
+	}
+	if err := checkShards(parity, false); err != nil {
+		return err
+	}
+	if len(parity[0]) != len(dataShard) {
+		return ErrShardSize
+	}
+	if len(dataShard)%64 != 0 {
+		return ErrInvalidShardSize
+	}
+	st := r.getState()
+	defer r.states.put(st)
+	for start := 0; start < len(dataShard); start += gf16MatrixBlockSize {
+		end := start + gf16MatrixBlockSize
+		if end > len(dataShard) {
+			end = len(dataShard)
+		}
+		for j, p := range parity {
+			r.mulAdd(p[start:end], dataShard[start:end], r.parity[j][idx], st.tmp)
+		}
+	}
+	return nil
+}
+
+func (r *gf16Matrix) Update(shards [][]byte, newDatashards [][]byte) error {
+	if len(shards) != r.totalShards || len(newDatashards) != r.dataShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	if err := checkShards(newDatashards, true); err != nil {
+		return err
+	}
+	for i := range newDatashards {
+		if newDatashards[i] != nil && shards[i] == nil {
+			return ErrInvalidInput
+		}
+	}
+	for _, p := range shards[r.dataShards:] {
+		if p == nil {
+			return ErrInvalidInput
+		}
+	}
+	shardSize := shardSize(shards)
+	if shardSize%64 != 0 {
+		return ErrInvalidShardSize
+	}
+	st := r.getState()
+	defer r.states.put(st)
+	if st.delta == nil {
+		st.delta = AllocAligned(1, gf16MatrixBlockSize)[0]
+	}
+	for start := 0; start < shardSize; start += gf16MatrixBlockSize {
+		end := start + gf16MatrixBlockSize
+		if end > shardSize {
+			end = shardSize
+		}
+		delta := st.delta[:end-start]
+		for i, newData := range newDatashards {
+			if newData == nil {
+				continue
+			}
+			copy(delta, shards[i][start:end])
+			sliceXor(newData[start:end], delta, &r.o)
+			for j, p := range shards[r.dataShards:] {
+				r.mulAdd(p[start:end], delta, r.parity[j][i], st.tmp)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *gf16Matrix) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	shardSize := len(shards[0])
+	outputs := make([][]byte, r.totalShards)
+	copy(outputs, shards[:r.dataShards])
+	for i := r.dataShards; i < r.totalShards; i++ {
+		outputs[i] = make([]byte, shardSize)
+	}
+	if err := r.Encode(outputs); err != nil {
+		return false, err
+	}
+
+	// Compare.
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !bytes.Equal(outputs[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *gf16Matrix) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+func (r *gf16Matrix) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *gf16Matrix) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards.
+// If required is non-nil, only shards marked as required are recreated.
+func (r *gf16Matrix) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	shardSize := shardSize(shards)
+	if shardSize%64 != 0 {
+		return ErrInvalidShardSize
+	}
+	isRequired := func(i int) bool {
+		return len(shards[i]) == 0 && (i < r.dataShards || !dataOnly) && (required == nil || required[i])
+	}
+
+	var missing, present, parity []int
+	needParity := false
+	for i := range shards {
+		switch {
+		case len(shards[i]) != 0 && i >= r.dataShards:
+			parity = append(parity, i-r.dataShards)
+		case len(shards[i]) != 0:
+			present = append(present, i)
+		case i < r.dataShards:
+			missing = append(missing, i)
+		case isRequired(i):
+			needParity = true
+		}
+	}
+	wantData := false
+	for _, i := range missing {
+		wantData = wantData || isRequired(i)
+	}
+	if !wantData && !needParity {
+		return nil
+	}
+	if len(missing) > len(parity) {
+		return ErrTooFewShards
+	}
+	parity = parity[:len(missing)]
+
+	data := make([][]byte, r.dataShards)
+	copy(data, shards[:r.dataShards])
+	if len(missing) > 0 {
+		decode, err := r.decodeRows(shards, missing, present, parity)
+		if err != nil {
+			return err
+		}
+
+		// Inputs are the parity shards and present data shards.
+		inputs := make([][]byte, 0, len(parity)+len(present))
+		for _, p := range parity {
+			inputs = append(inputs, shards[r.dataShards+p])
+		}
+		for _, i := range present {
+			inputs = append(inputs, shards[i])
+		}
+		var rows [][]ffe
+		var outputs [][]byte
+		for k, i := range missing {
+			if !isRequired(i) && !needParity {
+				continue
+			}
+			if isRequired(i) {
+				if cap(shards[i]) >= shardSize {
+					shards[i] = shards[i][:shardSize]
+				} else {
+					shards[i] = AllocAligned(1, shardSize)[0]
+				}
+				data[i] = shards[i]
+			} else {
+				data[i] = make([]byte, shardSize)
+			}
+			rows = append(rows, decode[k])
+			outputs = append(outputs, data[i])
+		}
+		r.mulRows(rows, inputs, outputs, shardSize)
+	}
+	if !needParity {
+		return nil
+	}
+
+	var rows [][]ffe
+	var outputs [][]byte
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !isRequired(i) {
+			continue
+		}
+		if cap(shards[i]) >= shardSize {
+			shards[i] = shards[i][:shardSize]
+		} else {
+			shards[i] = AllocAligned(1, shardSize)[0]
+		}
+		rows = append(rows, r.parity[i-r.dataShards])
+		outputs = append(outputs, shards[i])
+	}
+	r.mulRows(rows, data, outputs, shardSize)
+	return nil
+}
+
+// decodeRows returns the rows recovering each missing data shard
+// from the given parity shards followed by the present data shards.
+// The rows are cached by erasure pattern if the inversion cache is enabled.
+func (r *gf16Matrix) decodeRows(shards [][]byte, missing, present, parity []int) ([][]ffe, error) {
+	var (
+		st   *gf16MatrixState
+		hash uint64
+	)
+	if r.inversion != nil {
+		st = r.states.get()
+		defer r.states.put(st)
+		st.key = r.cacheKey(st.key[:0], shards)
+		hash = maphash.Bytes(r.cacheSeed, st.key)
+		if d, ok := r.inversion.get(hash); ok && d.key == string(st.key) {
+			return d.rows, nil
+		}
+	}
+
+	// Solve A * missing = parity - (contribution of present data),
+	// where A contains the coefficients of the missing shards.
+	a := make([][]ffe, len(missing))
+	for j, p := range parity {
+		a[j] = make([]ffe, len(missing))
+		for k, i := range missing {
+			a[j][k] = r.parity[p][i]
+		}
+	}
+	inv, err := gf16Invert(a)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]ffe, len(missing))
+	for k := range rows {
+		row := make([]ffe, len(parity)+len(present))
+		copy(row, inv[k])
+		// Subtract the present data from the parity before applying the inverse.
+		for n, d := range present {
+			var sum ffe
+			for j, p := range parity {
+				sum ^= gf16Mul(inv[k][j], r.parity[p][d])
+			}
+			row[len(parity)+n] = sum
+		}
+		rows[k] = row
+	}
+	if r.inversion != nil {
+		r.inversion.put(hash, &gf16Decode{key: string(st.key), rows: rows})
+	}
+	return rows, nil
+}
+
+// cacheKey appends the inversion cache key of an erasure pattern to dst.
+// The key is a bitmap of the missing shards.
+func (r *gf16Matrix) cacheKey(dst []byte, shards [][]byte) []byte {
+	for i := 0; i < len(shards); i += 8 {
+		var b byte
+		for j := i; j < i+8 && j < len(shards); j++ {
+			if len(shards[j]) == 0 {
+				b |= 1 << (j - i)
+			}
+		}
+		dst = append(dst, b)
+	}
+	return dst
+}
+
+// gf16Invert returns the inverse of the square matrix m.
+// m is modified.
+func gf16Invert(m [][]ffe) ([][]ffe, error) {
+	n := len(m)
+	inv := make([][]ffe, n)
+	for i := range inv {
+		inv[i] = make([]ffe, n)
+		inv[i][i] = 1
+	}
+	for c := 0; c < n; c++ {
+		// Find a row with a non-zero value in column c.
+		p := c
+		for p < n && m[p][c] == 0 {
+			p++
+		}
+		if p == n {
+			return nil, errSingular
+		}
+		m[c], m[p] = m[p], m[c]
+		inv[c], inv[p] = inv[p], inv[c]
+
+		// Scale the row, so the pivot is 1.
+		if s := gf16Inv(m[c][c]); s != 1 {
+			for k := 0; k < n; k++ {
+				m[c][k] = gf16Mul(m[c][k], s)
+				inv[c][k] = gf16Mul(inv[c][k], s)
+			}
+		}
+		// Eliminate column c from the other rows.
+		for row := 0; row < n; row++ {
+			f := m[row][c]
+			if row == c || f == 0 {
+				continue
+			}
+			for k := 0; k < n; k++ {
+				m[row][k] ^= gf16Mul(f, m[c][k])
+				inv[row][k] ^= gf16Mul(f, inv[c][k])
+			}
+		}
+	}
+	return inv, nil
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+// Shards are padded with zeros to a multiple of 64 bytes.
+func (r *gf16Matrix) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, 64, r.o.zeroCopySplit, false)
+}
+
+func (r *gf16Matrix) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
@@ -0,0 +1,204 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// gf16Symbol returns symbol n of a shard in the layout of the Leopard GF16 encoder.
+func gf16Symbol(shard []byte, n int) ffe {
+	off := n/32*64 + n%32
+	return ffe(shard[off]) | ffe(shard[off+32])<<8
+}
+
+func TestGF16Matrix(t *testing.T) {
+	const dataShards, parityShards, shardSize = 300, 40, 256
+	enc, err := New(dataShards, parityShards, testOptions(WithGF16Matrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(shardSize)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+
+	// Check some symbols against the Cauchy matrix.
+	for _, j := range []int{0, 17, parityShards - 1} {
+		for _, n := range []int{0, 31, 32, shardSize/2 - 1} {
+			var want ffe
+			for i := 0; i < dataShards; i++ {
+				c := gf16Inv(ffe(dataShards+j) ^ ffe(i))
+				want ^= gf16Mul(c, gf16Symbol(shards[i], n))
+			}
+			if got := gf16Symbol(shards[dataShards+j], n); got != want {
+				t.Fatalf("parity %d symbol %d: got %x, want %x", j, n, got, want)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(0))
+	for _, nLost := range []int{1, 2, 15, parityShards} {
+		lost := rng.Perm(dataShards + parityShards)[:nLost]
+		test := make([][]byte, len(shards))
+		copy(test, shards)
+		for _, i := range lost {
+			test[i] = nil
+		}
+		if err := enc.Reconstruct(test); err != nil {
+			t.Fatal(nLost, err)
+		}
+		for i := range shards {
+			if !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("%d lost: shard %d not reconstructed", nLost, i)
+			}
+		}
+		copy(test, shards)
+		for _, i := range lost {
+			test[i] = nil
+		}
+		if err := enc.ReconstructData(test); err != nil {
+			t.Fatal(nLost, err)
+		}
+		for i := range shards {
+			if i >= dataShards && shards[i] != nil && test[i] != nil && !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("%d lost: parity %d changed", nLost, i)
+			}
+			if i < dataShards && !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("%d lost: data shard %d not reconstructed", nLost, i)
+			}
+		}
+	}
+
+	// Reconstruct a single shard.
+	test := make([][]byte, len(shards))
+	copy(test, shards)
+	test[3], test[5], test[dataShards+1] = nil, nil, nil
+	required := make([]bool, len(shards))
+	required[dataShards+1] = true
+	if err := enc.ReconstructSome(test, required); err != nil {
+		t.Fatal(err)
+	}
+	if test[3] != nil || test[5] != nil || !bytes.Equal(test[dataShards+1], shards[dataShards+1]) {
+		t.Fatal("ReconstructSome did not recreate only the required shard")
+	}
+
+	// Too many lost.
+	copy(test, shards)
+	for _, i := range rng.Perm(dataShards + parityShards)[:parityShards+1] {
+		test[i] = nil
+	}
+	if err := enc.Reconstruct(test); err != ErrTooFewShards {
+		t.Fatalf("expected ErrTooFewShards, got %v", err)
+	}
+}
+
+func TestGF16MatrixEncodeIdxUpdate(t *testing.T) {
+	const dataShards, parityShards = 10, 4
+	shardSize := gf16MatrixBlockSize + 128
+	enc, err := New(dataShards, parityShards, testOptions(WithGF16Matrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(shardSize)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+
+	parity := AllocAligned(parityShards, shardSize)
+	for _, i := range rand.Perm(dataShards) {
+		if err := enc.EncodeIdx(shards[i], i, parity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for j := range parity {
+		if !bytes.Equal(parity[j], shards[dataShards+j]) {
+			t.Fatalf("EncodeIdx parity %d does not match Encode", j)
+		}
+	}
+
+	newData := make([][]byte, dataShards)
+	newData[2] = make([]byte, shardSize)
+	newData[7] = make([]byte, shardSize)
+	fillRandom(newData[2])
+	fillRandom(newData[7])
+	if err := enc.Update(shards, newData); err != nil {
+		t.Fatal(err)
+	}
+	shards[2], shards[7] = newData[2], newData[7]
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok after Update:", ok, "err:", err)
+	}
+
+	if err := enc.EncodeIdx(shards[0][:100], 0, AllocAligned(parityShards, 100)); err != ErrInvalidShardSize {
+		t.Fatalf("expected ErrInvalidShardSize, got %v", err)
+	}
+}
+
+func TestGF16MatrixInversionCache(t *testing.T) {
+	const dataShards, parityShards, shardSize = 20, 6, 64
+	enc, err := New(dataShards, parityShards, testOptions(WithGF16Matrix(), WithInversionCacheLimit(2, 0))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(shardSize)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for _, lost := range [][]int{{1, 2}, {1, 2}, {3}, {4, 21}, {1, 2}} {
+		test := make([][]byte, len(shards))
+		copy(test, shards)
+		for _, i := range lost {
+			test[i] = nil
+		}
+		if err := enc.ReconstructData(test); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < dataShards; i++ {
+			if !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("lost %v: shard %d not reconstructed", lost, i)
+			}
+		}
+	}
+	stats, err := InversionCacheStatsOf(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InversionCacheStats{Hits: 1, Misses: 4, Evictions: 2, Entries: 2}
+	stats.Bytes = 0
+	if stats != want {
+		t.Fatalf("got %+v, want %+v", stats, want)
+	}
+}
+
+func TestGF16MatrixOptions(t *testing.T) {
+	for _, opt := range []Option{WithLeopardGF16(true), WithConstantTime(true), WithFieldRepresentation(FieldRijndael)} {
+		if _, err := New(300, 20, WithGF16Matrix(), opt); err != ErrNotSupported {
+			t.Errorf("expected ErrNotSupported, got %v", err)
+		}
+	}
+	if _, err := New(65000, 600, WithGF16Matrix()); err != ErrMaxShardNum {
+		t.Errorf("expected ErrMaxShardNum, got %v", err)
+	}
+	enc, err := New(300, 20, WithPAR2Matrix(), WithGF16Matrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := enc.(*gf16Matrix); !ok {
+		t.Fatalf("got %T", enc)
+	}
+}
@@ -0,0 +1,429 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+)
+
+// gf4Codec is an encoder using a Cauchy matrix over GF(2^4).
+// Each byte holds two symbols, one in each nibble, which are coded independently.
+// The only table is the 256 byte multiplication table,
+// so it is suited for devices with little memory.
+// Construct using New with WithGF4Matrix.
+type gf4Codec struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	// m contains the encoding matrix, with the identity matrix as the first dataShards rows.
+	m [][]byte
+
+	o options
+}
+
+const (
+	// gf4Polynomial is the reducing polynomial x^4 + x + 1.
+	gf4Polynomial = 0x13
+
+	// gf4MaxShards is the number of elements of GF(2^4),
+	// which is the maximum number of shards of a Cauchy matrix.
+	gf4MaxShards = 16
+)
+
+// gf4MulTable contains the products of all pairs of elements.
+var gf4MulTable = func() (t [16][16]byte) {
+	for a := range t {
+		for b := range t[a] {
+			x, y := byte(a), byte(b)
+			var p byte
+			for ; y != 0; y >>= 1 {
+				if y&1 != 0 {
+					p ^= x
+				}
+				x <<= 1
+				if x&0x10 != 0 {
+					x ^= gf4Polynomial
+				}
+			}
+			t[a][b] = p
+		}
+	}
+	return t
+}()
+
+// gf4Inv returns the multiplicative inverse of a, which must be non-zero.
+func gf4Inv(a byte) byte {
+	for b := byte(1); b < 16; b++ {
+		if gf4MulTable[a][b] == 1 {
+			return b
+		}
+	}
+	panic("no inverse")
+}
+
+// gf4MulAdd adds c * in to out, treating each nibble as a symbol.
+func gf4MulAdd(c byte, in, out []byte) {
+	switch c {
+	case 0:
+		return
+	case 1:
+		out = out[:len(in)]
+		for i, v := range in {
+			out[i] ^= v
+		}
+		return
+	}
+	// Expand the products to whole bytes.
+	var t [256]byte
+	row := &gf4MulTable[c]
+	for x := range t {
+		t[x] = row[x&15] | row[x>>4]<<4
+	}
+	out = out[:len(in)]
+	for i, v := range in {
+		out[i] ^= t[v]
+	}
+}
+
+// gf4MulRows sets outputs[j] to the sum of rows[j][i] * inputs[i].
+func gf4MulRows(rows [][]byte, inputs, outputs [][]byte, byteCount int) {
+	for j, out := range outputs {
+		out := out[:byteCount]
+		memclr(out)
+		for i, in := range inputs {
+			gf4MulAdd(rows[j][i], in[:byteCount], out)
+		}
+	}
+}
+
+// gf4Invert returns the inverse of the square matrix m.
+// m is modified.
+func gf4Invert(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	inv := make([][]byte, n)
+	for i := range inv {
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for c := 0; c < n; c++ {
+		// Find a row with a non-zero value in column c.
+		p := c
+		for p < n && m[p][c] == 0 {
+			p++
+		}
+		if p == n {
+			return nil, errSingular
+		}
+		m[c], m[p] = m[p], m[c]
+		inv[c], inv[p] = inv[p], inv[c]
+
+		// Scale the row, so the pivot is 1.
+		if s := gf4Inv(m[c][c]); s != 1 {
+			for k := 0; k < n; k++ {
+				m[c][k] = gf4MulTable[m[c][k]][s]
+				inv[c][k] = gf4MulTable[inv[c][k]][s]
+			}
+		}
+		// Eliminate column c from the other rows.
+		for row := 0; row < n; row++ {
+			f := m[row][c]
+			if row == c || f == 0 {
+				continue
+			}
+			for k := 0; k < n; k++ {
+				m[row][k] ^= gf4MulTable[f][m[c][k]]
+				inv[row][k] ^= gf4MulTable[f][inv[c][k]]
+			}
+		}
+	}
+	return inv, nil
+}
+
+// newGF4 is like New, but for the GF(2^4) Cauchy matrix.
+func newGF4(dataShards, parityShards int, opt options) (*gf4Codec, error) {
+	if dataShards <= 0 || parityShards < 0 {
+		return nil, ErrInvShardNum
+	}
+	if dataShards+parityShards > gf4MaxShards {
+		return nil, ErrMaxShardNum
+	}
+	r := &gf4Codec{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		m:            make([][]byte, dataShards+parityShards),
+		o:            opt,
+	}
+	// The coefficient of data shard i in parity shard j is 1/(x_j + y_i),
+	// with x_j = dataShards+j and y_i = i.
+	for i := range r.m {
+		r.m[i] = make([]byte, dataShards)
+		if i < dataShards {
+			r.m[i][i] = 1
+			continue
+		}
+		for k := range r.m[i] {
+			r.m[i][k] = gf4Inv(byte(i ^ k))
+		}
+	}
+	return r, nil
+}
+
+var _ = Extensions(&gf4Codec{})
+
+func (r *gf4Codec) ShardSizeMultiple() int {
+	return 1
+}
+
+func (r *gf4Codec) DataShards() int {
+	return r.dataShards
+}
+
+func (r *gf4Codec) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *gf4Codec) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *gf4Codec) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+// PureGo always returns true, since the GF(2^4) codec has no assembly.
+func (r *gf4Codec) PureGo() bool {
+	return true
+}
+
+// Recalibrate does nothing, since the GF(2^4) codec does not split work between goroutines.
+func (r *gf4Codec) Recalibrate(shardSize int) {}
+
+func (r *gf4Codec) CostModel() CostModel {
+	var c CostModel
+	if r.parityShards == 0 {
+		return c
+	}
+	d, p := float64(r.dataShards), float64(r.parityShards)
+	c.EncodeMulsPerByte = p
+	c.EncodeBytesPerByte = (d + p) / d
+	c.ReconstructMulsPerByte = d
+	c.ReconstructBytesPerByte = d + 1
+	return c
+}
+
+func (r *gf4Codec) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	gf4MulRows(r.m[r.dataShards:], shards[:r.dataShards], shards[r.dataShards:], len(shards[0]))
+	return nil
+}
+
+// EncodeIdx will add parity for a single data shard.
+// Parity shards should start out zeroed. The caller must zero them before first call.
+// Data shards should only be delivered once. There is no check for this.
+func (r *gf4Codec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	if len(parity) != r.parityShards {
+		return ErrTooFewShards
+	}
+	if len(parity) == 0 {
+		return nil
+	}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
+	}
+	if err := checkShards(parity, false); err != nil {
+		return err
+	}
+	if len(parity[0]) != len(dataShard) {
+		return ErrShardSize
+	}
+	for j, p := range parity {
+		gf4MulAdd(r.m[r.dataShards+j][idx], dataShard, p)
+	}
+	return nil
+}
+
+func (r *gf4Codec) Update(shards [][]byte, newDatashards [][]byte) error {
+	if len(shards) != r.totalShards || len(newDatashards) != r.dataShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	if err := checkShards(newDatashards, true); err != nil {
+		return err
+	}
+	for i := range newDatashards {
+		if newDatashards[i] != nil && shards[i] == nil {
+			return ErrInvalidInput
+		}
+	}
+	for _, p := range shards[r.dataShards:] {
+		if p == nil {
+			return ErrInvalidInput
+		}
+	}
+	delta := make([]byte, shardSize(shards))
+	for i, newData := range newDatashards {
+		if newData == nil {
+			continue
+		}
+		for k := range delta {
+			delta[k] = shards[i][k] ^ newData[k]
+		}
+		for j, p := range shards[r.dataShards:] {
+			gf4MulAdd(r.m[r.dataShards+j][i], delta, p)
+		}
+	}
+	return nil
+}
+
+func (r *gf4Codec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	shardSize := len(shards[0])
+	outputs := make([][]byte, r.parityShards)
+	for i := range outputs {
+		outputs[i] = make([]byte, shardSize)
+	}
+	gf4MulRows(r.m[r.dataShards:], shards[:r.dataShards], outputs, shardSize)
+
+	// Compare.
+	for i, out := range outputs {
+		if !bytes.Equal(out, shards[r.dataShards+i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *gf4Codec) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+func (r *gf4Codec) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *gf4Codec) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards.
+// If required is non-nil, only shards marked as required are recreated.
+//
+// The rows of the encoding matrix of the first dataShards shards present
+// are inverted to recover the data, like reedSolomon does.
+func (r *gf4Codec) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	shardSize := shardSize(shards)
+	isRequired := func(i int) bool {
+		return len(shards[i]) == 0 && (i < r.dataShards || !dataOnly) && (required == nil || required[i])
+	}
+
+	var valid []int
+	wantData, wantParity := false, false
+	for i := range shards {
+		switch {
+		case len(shards[i]) != 0:
+			valid = append(valid, i)
+		case isRequired(i):
+			wantData = wantData || i < r.dataShards
+			wantParity = wantParity || i >= r.dataShards
+		}
+	}
+	if !wantData && !wantParity {
+		return nil
+	}
+	if len(valid) < r.dataShards {
+		return ErrTooFewShards
+	}
+	valid = valid[:r.dataShards]
+
+	data := make([][]byte, r.dataShards)
+	copy(data, shards[:r.dataShards])
+	if valid[r.dataShards-1] >= r.dataShards {
+		// Some data shards are missing.
+		sub := make([][]byte, r.dataShards)
+		inputs := make([][]byte, r.dataShards)
+		for k, i := range valid {
+			sub[k] = append([]byte(nil), r.m[i]...)
+			inputs[k] = shards[i]
+		}
+		inv, err := gf4Invert(sub)
+		if err != nil {
+			return err
+		}
+		var rows, outputs [][]byte
+		for i := 0; i < r.dataShards; i++ {
+			if len(shards[i]) != 0 || !isRequired(i) && !wantParity {
+				continue
+			}
+			if isRequired(i) {
+				if cap(shards[i]) >= shardSize {
+					shards[i] = shards[i][:shardSize]
+				} else {
+					shards[i] = make([]byte, shardSize)
+				}
+				data[i] = shards[i]
+			} else {
+				data[i] = make([]byte, shardSize)
+			}
+			rows = append(rows, inv[i])
+			outputs = append(outputs, data[i])
+		}
+		gf4MulRows(rows, inputs, outputs, shardSize)
+	}
+	if !wantParity {
+		return nil
+	}
+
+	var rows, outputs [][]byte
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !isRequired(i) {
+			continue
+		}
+		if cap(shards[i]) >= shardSize {
+			shards[i] = shards[i][:shardSize]
+		} else {
+			shards[i] = make([]byte, shardSize)
+		}
+		rows = append(rows, r.m[i])
+		outputs = append(outputs, shards[i])
+	}
+	gf4MulRows(rows, data, outputs, shardSize)
+	return nil
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+func (r *gf4Codec) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, 1, r.o.zeroCopySplit, false)
+}
+
+func (r *gf4Codec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
@@ -0,0 +1,181 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestGF4Tables(t *testing.T) {
+	// All non-zero elements are powers of x.
+	var seen [16]bool
+	x := byte(1)
+	for i := 0; i < 15; i++ {
+		if seen[x] {
+			t.Fatalf("x^%d repeats", i)
+		}
+		seen[x] = true
+		x = gf4MulTable[x][2]
+	}
+	for a := byte(1); a < 16; a++ {
+		if gf4MulTable[a][gf4Inv(a)] != 1 {
+			t.Fatalf("inverse of %d", a)
+		}
+	}
+}
+
+func TestGF4Matrix(t *testing.T) {
+	const dataShards, parityShards, shardSize = 10, 6, 999
+	enc, err := New(dataShards, parityShards, testOptions(WithGF4Matrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(shardSize)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+
+	// Check against symbol by symbol encoding.
+	r := enc.(*gf4Codec)
+	for j := 0; j < parityShards; j++ {
+		for n := 0; n < shardSize; n++ {
+			var lo, hi byte
+			for i := 0; i < dataShards; i++ {
+				c := r.m[dataShards+j][i]
+				lo ^= gf4MulTable[c][shards[i][n]&15]
+				hi ^= gf4MulTable[c][shards[i][n]>>4]
+			}
+			if got := shards[dataShards+j][n]; got != lo|hi<<4 {
+				t.Fatalf("parity %d byte %d: got %#x, want %#x", j, n, got, lo|hi<<4)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(0))
+	for iter := 0; iter < 100; iter++ {
+		lost := rng.Perm(dataShards + parityShards)[:1+rng.Intn(parityShards)]
+		test := make([][]byte, len(shards))
+		copy(test, shards)
+		for _, i := range lost {
+			test[i] = nil
+		}
+		if err := enc.Reconstruct(test); err != nil {
+			t.Fatal(lost, err)
+		}
+		for i := range shards {
+			if !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("lost %v: shard %d not reconstructed", lost, i)
+			}
+		}
+	}
+
+	// Reconstruct a single shard.
+	test := make([][]byte, len(shards))
+	copy(test, shards)
+	test[1], test[4], test[dataShards+2] = nil, nil, nil
+	required := make([]bool, len(shards))
+	required[4] = true
+	if err := enc.ReconstructSome(test, required); err != nil {
+		t.Fatal(err)
+	}
+	if test[1] != nil || test[dataShards+2] != nil || !bytes.Equal(test[4], shards[4]) {
+		t.Fatal("ReconstructSome did not recreate only the required shard")
+	}
+
+	copy(test, shards)
+	for _, i := range rng.Perm(dataShards + parityShards)[:parityShards+1] {
+		test[i] = nil
+	}
+	if err := enc.Reconstruct(test); err != ErrTooFewShards {
+		t.Fatalf("expected ErrTooFewShards, got %v", err)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Encode allocated %v times", allocs)
+	}
+}
+
+func TestGF4MatrixEncodeIdxUpdate(t *testing.T) {
+	const dataShards, parityShards, shardSize = 5, 3, 100
+	enc, err := New(dataShards, parityShards, testOptions(WithGF4Matrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(shardSize)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	parity := AllocAligned(parityShards, shardSize)
+	for _, i := range rand.Perm(dataShards) {
+		if err := enc.EncodeIdx(shards[i], i, parity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for j := range parity {
+		if !bytes.Equal(parity[j], shards[dataShards+j]) {
+			t.Fatalf("EncodeIdx parity %d does not match Encode", j)
+		}
+	}
+
+	newData := make([][]byte, dataShards)
+	newData[1] = make([]byte, shardSize)
+	fillRandom(newData[1])
+	if err := enc.Update(shards, newData); err != nil {
+		t.Fatal(err)
+	}
+	shards[1] = newData[1]
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok after Update:", ok, "err:", err)
+	}
+
+	var buf bytes.Buffer
+	data := make([]byte, 333)
+	fillRandom(data)
+	split, err := enc.Split(append([]byte(nil), data...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(split); err != nil {
+		t.Fatal(err)
+	}
+	split[0], split[2] = nil, nil
+	if err := enc.ReconstructData(split); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Join(&buf, split, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+}
+
+func TestGF4MatrixOptions(t *testing.T) {
+	if _, err := New(12, 5, WithGF4Matrix()); err != ErrMaxShardNum {
+		t.Errorf("expected ErrMaxShardNum, got %v", err)
+	}
+	if _, err := New(12, 4, WithGF4Matrix()); err != nil {
+		t.Error(err)
+	}
+	for _, opt := range []Option{WithLeopardGF(true), WithConstantTime(true), WithFieldRepresentation(FieldRijndael)} {
+		if _, err := New(4, 2, WithGF4Matrix(), opt); err != ErrNotSupported {
+			t.Errorf("expected ErrNotSupported, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+)
+
+// healBlockSize is the number of bytes of each shard processed at once by HealParityAt.
+const healBlockSize = 1 << 20
+
+// HealReport describes the parity rewritten by HealParity or HealParityAt.
+type HealReport struct {
+	// Healed contains the shard indexes of the parity shards
+	// that were inconsistent and have been rewritten, in increasing order.
+	Healed []int
+
+	// Bytes is the total number of parity bytes rewritten.
+	Bytes int64
+}
+
+// HealParity recomputes the parity of shards and overwrites the
+// parity shards that do not match, leaving consistent shards untouched.
+//
+// The data shards are assumed to be intact, for example because
+// they have been checked against stored checksums.
+// If a data shard is damaged, the parity is rewritten to match it,
+// and the damage can no longer be repaired.
+//
+// All shards must be present.
+// If all parity is consistent, an empty report is returned.
+func HealParity(enc Encoder, shards [][]byte) (HealReport, error) {
+	var report HealReport
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return report, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return report, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return report, err
+	}
+	dataShards := ext.DataShards()
+	size := len(shards[0])
+	parity := ext.AllocAligned(size)[dataShards:]
+	test := make([][]byte, len(shards))
+	copy(test, shards[:dataShards])
+	copy(test[dataShards:], parity)
+	if err := enc.Encode(test); err != nil {
+		return report, err
+	}
+	for i, p := range parity {
+		idx := dataShards + i
+		if !bytes.Equal(p, shards[idx]) {
+			copy(shards[idx], p)
+			report.Healed = append(report.Healed, idx)
+			report.Bytes += int64(size)
+		}
+	}
+	return report, nil
+}
+
+// HealParityAt recomputes the parity of shards of shardSize bytes
+// read from shards, and writes the parts of the parity shards that
+// do not match to the writer in fix with the same index.
+//
+// There must be a source for each shard, and the entries of fix
+// are only used for parity shards, in shard order.
+// The shards are processed in blocks, and only mismatching blocks are written.
+// As with HealParity, the data shards are assumed to be intact.
+func HealParityAt(enc Encoder, shards []io.ReaderAt, fix []io.WriterAt, shardSize int64) (HealReport, error) {
+	var report HealReport
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return report, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() || len(fix) != len(shards) {
+		return report, ErrTooFewShards
+	}
+	if shardSize <= 0 || shardSize%int64(ext.ShardSizeMultiple()) != 0 {
+		return report, ErrInvalidShardSize
+	}
+	dataShards := ext.DataShards()
+	for i := range shards {
+		if shards[i] == nil || (i >= dataShards && fix[i] == nil) {
+			return report, ErrShardNoData
+		}
+	}
+
+	blockSize := int64(healBlockSize)
+	if multiple := int64(ext.ShardSizeMultiple()); blockSize%multiple != 0 {
+		blockSize = (blockSize + multiple - 1) / multiple * multiple
+	}
+	if blockSize > shardSize {
+		blockSize = shardSize
+	}
+	stored := ext.AllocAligned(int(blockSize))
+	computed := ext.AllocAligned(int(blockSize))
+	healed := make([]bool, len(shards))
+	for off := int64(0); off < shardSize; off += blockSize {
+		n := blockSize
+		if rem := shardSize - off; rem < n {
+			n = rem
+		}
+		for i, shard := range shards {
+			if _, err := shard.ReadAt(stored[i][:n], off); err != nil {
+				return report, StreamReadError{Err: err, Stream: i}
+			}
+		}
+		test := make([][]byte, len(shards))
+		for i := range test {
+			if i < dataShards {
+				test[i] = stored[i][:n]
+			} else {
+				test[i] = computed[i][:n]
+			}
+		}
+		if err := enc.Encode(test); err != nil {
+			return report, err
+		}
+		for i := dataShards; i < len(shards); i++ {
+			if bytes.Equal(test[i], stored[i][:n]) {
+				continue
+			}
+			if _, err := fix[i].WriteAt(test[i], off); err != nil {
+				return report, StreamWriteError{Err: err, Stream: i}
+			}
+			healed[i] = true
+			report.Bytes += n
+		}
+	}
+	for i, h := range healed {
+		if h {
+			report.Healed = append(report.Healed, i)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,65 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestHealParity(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithLeopardGF(true)}, {WithLeopardGF16(true)}} {
+		enc, err := New(6, 3, testOptions(opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(3 << 20)
+		for _, shard := range shards[:6] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		report, err := HealParity(enc, shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Healed) != 0 || report.Bytes != 0 {
+			t.Fatalf("healed consistent shards: %+v", report)
+		}
+
+		// Damage a single block of parity shard 7.
+		shards[7][healBlockSize+10] ^= 1
+		var fix []io.WriterAt
+		var readers []io.ReaderAt
+		for _, shard := range shards {
+			fix = append(fix, &sparseWriter{data: shard})
+			readers = append(readers, bytes.NewReader(shard))
+		}
+		report, err = HealParityAt(enc, readers, fix, int64(len(shards[0])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (HealReport{Healed: []int{7}, Bytes: healBlockSize}); !reflect.DeepEqual(report, want) {
+			t.Fatalf("got report %+v, want %+v", report, want)
+		}
+		ok, err := enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+
+		shards[6][0] ^= 1
+		shards[8][len(shards[8])-1] ^= 1
+		report, err = HealParity(enc, shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{6, 8}; !reflect.DeepEqual(report.Healed, want) {
+			t.Fatalf("healed %v, want %v", report.Healed, want)
+		}
+		ok, err = enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package reedsolomon
+
+import "io"
+
+// Interleaver spreads the shards of several stripes over fixed size
+// transmission units, so a burst of consecutive lost units is turned into
+// erasures scattered over many codewords.
+//
+// Data is split into depth stripes, which are encoded separately.
+// Each shard is divided into symbols of unitSize bytes, and each unit carries
+// one symbol. Units are ordered by shard, then symbol position, then stripe,
+// so the symbols of a codeword are depth * shardSize / unitSize units apart.
+// A burst of lost units will then only erase one symbol from each codeword
+// unless it is longer than that.
+//
+// Missing units are repaired per codeword, so a shard with lost symbols
+// is only partially lost.
+type Interleaver struct {
+	enc      Encoder
+	depth    int
+	unitSize int
+}
+
+// NewInterleaver returns an interleaver for stripes encoded by enc.
+// unitSize must be a multiple of the shard size multiple of enc.
+// Encoders where parts of the shards cannot be coded independently,
+// such as those using WithClayCode or WithPiggyback, return ErrNotSupported.
+func NewInterleaver(enc Encoder, depth, unitSize int) (*Interleaver, error) {
+	if !migratable(enc) {
+		return nil, ErrNotSupported
+	}
+	if depth <= 0 {
+		return nil, ErrInvalidInput
+	}
+	if unitSize <= 0 || unitSize%enc.(Extensions).ShardSizeMultiple() != 0 {
+		return nil, ErrInvalidShardSize
+	}
+	return &Interleaver{enc: enc, depth: depth, unitSize: unitSize}, nil
+}
+
+// unit returns the index of the unit carrying symbol j of shard i in stripe s,
+// with d symbols per shard.
+func (iv *Interleaver) unit(s, i, j, d int) int {
+	return (i*d+j)*iv.depth + s
+}
+
+// symbols returns the number of symbols per shard for the given number of units.
+func (iv *Interleaver) symbols(units [][]byte) (int, error) {
+	n := iv.enc.(Extensions).TotalShards() * iv.depth
+	if len(units) == 0 || len(units)%n != 0 {
+		return 0, ErrTooFewShards
+	}
+	for _, u := range units {
+		if len(u) != 0 && len(u) != iv.unitSize {
+			return 0, ErrShardSize
+		}
+	}
+	return len(units) / n, nil
+}
+
+// Split splits and encodes data, and returns the transmission units.
+// The units reference the encoded shards and should not be appended to.
+func (iv *Interleaver) Split(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrShortData
+	}
+	ext := iv.enc.(Extensions)
+	k, n := ext.DataShards(), ext.TotalShards()
+	perShard := (len(data) + iv.depth*k - 1) / (iv.depth * k)
+	d := (perShard + iv.unitSize - 1) / iv.unitSize
+	size := d * iv.unitSize
+
+	units := make([][]byte, n*d*iv.depth)
+	for s := 0; s < iv.depth; s++ {
+		shards := ext.AllocAligned(size)
+		for i := 0; i < k; i++ {
+			if len(data) > 0 {
+				data = data[copy(shards[i], data):]
+			}
+		}
+		if err := iv.enc.Encode(shards); err != nil {
+			return nil, err
+		}
+		for i, shard := range shards {
+			for j := 0; j < d; j++ {
+				units[iv.unit(s, i, j, d)] = shard[j*iv.unitSize : (j+1)*iv.unitSize : (j+1)*iv.unitSize]
+			}
+		}
+	}
+	return units, nil
+}
+
+// Reconstruct recreates missing units, which must be nil or zero-length.
+// If a codeword has too few symbols, ErrTooFewShards is returned.
+func (iv *Interleaver) Reconstruct(units [][]byte) error {
+	d, err := iv.symbols(units)
+	if err != nil {
+		return err
+	}
+	shards := make([][]byte, iv.enc.(Extensions).TotalShards())
+	for s := 0; s < iv.depth; s++ {
+		for j := 0; j < d; j++ {
+			missing := false
+			for i := range shards {
+				shards[i] = units[iv.unit(s, i, j, d)]
+				missing = missing || len(shards[i]) == 0
+			}
+			if !missing {
+				continue
+			}
+			if err := iv.enc.Reconstruct(shards); err != nil {
+				return err
+			}
+			for i, shard := range shards {
+				units[iv.unit(s, i, j, d)] = shard
+			}
+		}
+	}
+	return nil
+}
+
+// Join writes the first outSize bytes of the data in units to dst.
+// If a unit with required data is missing, ErrReconstructRequired is returned.
+func (iv *Interleaver) Join(dst io.Writer, units [][]byte, outSize int) error {
+	d, err := iv.symbols(units)
+	if err != nil {
+		return err
+	}
+	k := iv.enc.(Extensions).DataShards()
+	if outSize > iv.depth*k*d*iv.unitSize {
+		return ErrShortData
+	}
+	// Check all required units before writing anything.
+	for off := 0; off < outSize; off += iv.unitSize {
+		sym := off / iv.unitSize
+		if len(units[iv.unit(sym/(k*d), sym/d%k, sym%d, d)]) == 0 {
+			return ErrReconstructRequired
+		}
+	}
+	for s := 0; s < iv.depth && outSize > 0; s++ {
+		for i := 0; i < k && outSize > 0; i++ {
+			for j := 0; j < d && outSize > 0; j++ {
+				u := units[iv.unit(s, i, j, d)]
+				if outSize < len(u) {
+					u = u[:outSize]
+				}
+				if _, err := dst.Write(u); err != nil {
+					return err
+				}
+				outSize -= len(u)
+			}
+		}
+	}
+	return nil
+}
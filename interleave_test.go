@@ -0,0 +1,89 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInterleaver(t *testing.T) {
+	enc, err := New(6, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv, err := NewInterleaver(enc, 4, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5000)
+	fillRandom(data)
+	units, err := iv.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 209 bytes per shard is 14 symbols.
+	if len(units) != 9*14*4 {
+		t.Fatalf("got %d units", len(units))
+	}
+	want := make([][]byte, len(units))
+	for i, u := range units {
+		want[i] = append([]byte(nil), u...)
+	}
+
+	// Symbols of a codeword are 14*4 units apart,
+	// so a burst of 3*56 units can be repaired.
+	for i := 100; i < 100+3*56; i++ {
+		units[i] = nil
+	}
+	var buf bytes.Buffer
+	if err := iv.Join(&buf, units, len(data)); err != ErrReconstructRequired {
+		t.Fatalf("want ErrReconstructRequired, got %v", err)
+	}
+	if err := iv.Reconstruct(units); err != nil {
+		t.Fatal(err)
+	}
+	for i := range units {
+		if !bytes.Equal(units[i], want[i]) {
+			t.Fatalf("unit %d mismatch", i)
+		}
+	}
+	if err := iv.Join(&buf, units, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+
+	// One more unit is too many.
+	for i := 100; i <= 100+3*56; i++ {
+		units[i] = nil
+	}
+	if err := iv.Reconstruct(units); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	if err := iv.Reconstruct(units[1:]); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	if err := iv.Join(&buf, want, len(data)*2); err != ErrShortData {
+		t.Errorf("want ErrShortData, got %v", err)
+	}
+}
+
+func TestInterleaverErrors(t *testing.T) {
+	enc, err := New(6, 3, WithLeopardGF(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewInterleaver(enc, 4, 16); err != ErrInvalidShardSize {
+		t.Errorf("want ErrInvalidShardSize, got %v", err)
+	}
+	if _, err := NewInterleaver(enc, 0, 64); err != ErrInvalidInput {
+		t.Errorf("want ErrInvalidInput, got %v", err)
+	}
+	clay, err := New(6, 3, WithClayCode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewInterleaver(clay, 4, 64); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+}
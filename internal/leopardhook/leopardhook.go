@@ -0,0 +1,17 @@
+// Package leopardhook connects the leopard package to the GF(2^16)
+// transforms of the reedsolomon package, without exporting them there.
+//
+// The functions are set when the reedsolomon package is initialized.
+package leopardhook
+
+// The transforms of the reedsolomon package.
+// See the leopard package for their documentation.
+var (
+	FFT  func(work [][]byte, mtrunc, m, offset int)
+	IFFT func(work [][]byte, mtrunc, m, offset int)
+	FWHT func(data *[1 << 16]uint16, mtrunc int)
+	Mul  func(dst, src []byte, logM uint16)
+	Xor  func(dst, src []byte)
+	Log  func(x uint16) uint16
+	Exp  func(logX uint16) uint16
+)
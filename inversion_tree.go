@@ -9,6 +9,7 @@ package reedsolomon
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 )
 
 // The tree uses a Reader-Writer mutex to make it thread-safe
@@ -16,11 +17,72 @@ import (
 type inversionTree struct {
 	mutex sync.RWMutex
 	root  inversionNode
+
+	// Limits of the cached matrices, or 0 for no limit.
+	maxEntries int
+	maxBytes   int64
+
+	cached []*inversionNode // Nodes with a cached matrix.
+	bytes  int64            // Bytes of the cached matrices.
+
+	clock     atomic.Uint64 // Incremented on each use of a cached matrix.
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
 }
 
 type inversionNode struct {
 	matrix   matrix
 	children []*inversionNode
+
+	// For nodes with a cached matrix.
+	invalidIndices []int         // Key of the node.
+	cachedIdx      int           // Index in inversionTree.cached.
+	lastUse        atomic.Uint64 // Clock value at the last use.
+}
+
+// InversionCacheStats contains statistics of the inversion cache of an encoder.
+type InversionCacheStats struct {
+	Hits      uint64 // Reconstructions using a cached matrix.
+	Misses    uint64 // Reconstructions inverting a matrix.
+	Evictions uint64 // Matrices removed to stay within the limits.
+	Entries   int    // Cached matrices.
+	Bytes     int64  // Bytes of the cached matrices.
+}
+
+// InversionCacheStatsOf returns statistics of the inversion cache of enc.
+// For Leopard GF8 and GF16 encoders, the cache holds error locators.
+// If the cache is disabled, all statistics are zero.
+func InversionCacheStatsOf(enc Encoder) (InversionCacheStats, error) {
+	switch r := enc.(type) {
+	case *reedSolomon:
+		return r.tree.stats(), nil
+	case *leopardFF8:
+		return r.inversion.stats(), nil
+	case *leopardFF16:
+		return r.inversion.stats(), nil
+	case *gf16Matrix:
+		return r.inversion.stats(), nil
+	case *primeCodec:
+		return r.inversion.stats(), nil
+	}
+	return InversionCacheStats{}, ErrNotSupported
+}
+
+// stats returns statistics of the tree.
+func (t *inversionTree) stats() InversionCacheStats {
+	if t == nil {
+		return InversionCacheStats{}
+	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return InversionCacheStats{
+		Hits:      t.hits.Load(),
+		Misses:    t.misses.Load(),
+		Evictions: t.evictions.Load(),
+		Entries:   len(t.cached),
+		Bytes:     t.bytes,
+	}
 }
 
 // newInversionTree initializes a tree for storing inverted matrices.
@@ -54,7 +116,14 @@ func (t *inversionTree) GetInvertedMatrix(invalidIndices []int) matrix {
 
 	// Recursively search for the inverted matrix in the tree, passing in
 	// 0 as the parent index as we start at the root of the tree.
-	return t.root.getInvertedMatrix(invalidIndices, 0)
+	node := t.root.getInvertedMatrix(invalidIndices, 0)
+	if node == nil || node.matrix == nil {
+		t.misses.Add(1)
+		return nil
+	}
+	t.hits.Add(1)
+	node.lastUse.Store(t.clock.Add(1))
+	return node.matrix
 }
 
 // errAlreadySet is returned if the root node matrix is overwritten
@@ -82,15 +151,90 @@ func (t *inversionTree) InsertInvertedMatrix(invalidIndices []int, matrix matrix
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
+	size := matrixBytes(matrix)
+	if t.maxBytes > 0 && size > t.maxBytes {
+		return nil
+	}
+
 	// Recursively create nodes for the inverted matrix in the tree until
 	// we reach the node to insert the matrix to.  We start by passing in
 	// 0 as the parent index as we start at the root of the tree.
-	t.root.insertInvertedMatrix(invalidIndices, matrix, shards, 0)
+	node := t.root.insertInvertedMatrix(invalidIndices, matrix, shards, 0)
+	node.lastUse.Store(t.clock.Add(1))
+	if node.invalidIndices != nil {
+		// Replaced a matrix inserted concurrently.
+		return nil
+	}
+	node.invalidIndices = append([]int(nil), invalidIndices...)
+	node.cachedIdx = len(t.cached)
+	t.cached = append(t.cached, node)
+	t.bytes += size
 
+	for t.maxEntries > 0 && len(t.cached) > t.maxEntries || t.maxBytes > 0 && t.bytes > t.maxBytes {
+		t.evictLRU(node)
+	}
 	return nil
 }
 
-func (n *inversionNode) getInvertedMatrix(invalidIndices []int, parent int) matrix {
+// matrixBytes returns the number of bytes of m.
+func matrixBytes(m matrix) int64 {
+	var n int64
+	for _, row := range m {
+		n += int64(len(row))
+	}
+	return n
+}
+
+// evictLRU removes the least recently used matrix other than keep from the tree.
+// The tree must be locked for writing.
+func (t *inversionTree) evictLRU(keep *inversionNode) {
+	var lru *inversionNode
+	for _, node := range t.cached {
+		if node != keep && (lru == nil || node.lastUse.Load() < lru.lastUse.Load()) {
+			lru = node
+		}
+	}
+	if lru == nil {
+		return
+	}
+
+	last := t.cached[len(t.cached)-1]
+	last.cachedIdx = lru.cachedIdx
+	t.cached[lru.cachedIdx] = last
+	t.cached = t.cached[:len(t.cached)-1]
+	t.bytes -= matrixBytes(lru.matrix)
+	t.evictions.Add(1)
+
+	indices := lru.invalidIndices
+	lru.matrix, lru.invalidIndices = nil, nil
+	t.root.prune(indices, 0)
+}
+
+// prune removes the nodes without matrices or children
+// on the path to the node keyed by invalidIndices.
+// It returns whether the child of n on the path was removed.
+func (n *inversionNode) prune(invalidIndices []int, parent int) bool {
+	firstIndex := invalidIndices[0]
+	node := n.children[firstIndex-parent]
+	if node == nil {
+		return false
+	}
+	if len(invalidIndices) > 1 && !node.prune(invalidIndices[1:], firstIndex+1) {
+		return false
+	}
+	if node.matrix != nil {
+		return false
+	}
+	for _, child := range node.children {
+		if child != nil {
+			return false
+		}
+	}
+	n.children[firstIndex-parent] = nil
+	return true
+}
+
+func (n *inversionNode) getInvertedMatrix(invalidIndices []int, parent int) *inversionNode {
 	// Get the child node to search next from the list of children.  The
 	// list of children starts relative to the parent index passed in
 	// because the indices of invalid rows is sorted (by default).  As we
@@ -118,10 +262,10 @@ func (n *inversionNode) getInvertedMatrix(invalidIndices []int, parent int) matr
 	// node.  Return it, however keep in mind that the matrix could still be
 	// nil because intermediary nodes in the tree are created sometimes with
 	// their inversion matrices uninitialized.
-	return node.matrix
+	return node
 }
 
-func (n *inversionNode) insertInvertedMatrix(invalidIndices []int, matrix matrix, shards, parent int) {
+func (n *inversionNode) insertInvertedMatrix(invalidIndices []int, matrix matrix, shards, parent int) *inversionNode {
 	// As above, get the child node to search next from the list of children.
 	// The list of children starts relative to the parent index passed in
 	// because the indices of invalid rows is sorted (by default).  As we
@@ -155,10 +299,10 @@ func (n *inversionNode) insertInvertedMatrix(invalidIndices []int, matrix matrix
 		// the invalid indices with the first index popped off the front.
 		// Also the total number of shards and parent index are passed down
 		// which is equal to the first index plus one.
-		node.insertInvertedMatrix(invalidIndices[1:], matrix, shards, firstIndex+1)
-	} else {
-		// If there aren't any more invalid indices to search, we've found our
-		// node.  Cache the inverted matrix in this node.
-		node.matrix = matrix
+		return node.insertInvertedMatrix(invalidIndices[1:], matrix, shards, firstIndex+1)
 	}
+	// If there aren't any more invalid indices to search, we've found our
+	// node.  Cache the inverted matrix in this node.
+	node.matrix = matrix
+	return node
 }
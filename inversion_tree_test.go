@@ -123,3 +123,87 @@ func TestDoubleInsertInvertedMatrix(t *testing.T) {
 		t.Fatal(matrix.String(), "!=", cachedMatrix.String())
 	}
 }
+
+func TestInversionTreeLimit(t *testing.T) {
+	tree := newInversionTree(3, 2)
+	tree.maxEntries = 2
+
+	for _, key := range [][]int{{0}, {1, 2}, {0, 3}} {
+		m, _ := newMatrix(3, 3)
+		if err := tree.InsertInvertedMatrix(key, m, 5); err != nil {
+			t.Fatal(err)
+		}
+		if key[0] == 1 {
+			// Use {0}, so {1, 2} is the least recently used.
+			if tree.GetInvertedMatrix([]int{0}) == nil {
+				t.Fatal("{0} not cached")
+			}
+		}
+	}
+	if tree.GetInvertedMatrix([]int{1, 2}) != nil {
+		t.Error("{1, 2} was not evicted")
+	}
+	if tree.root.children[1] != nil {
+		t.Error("nodes of {1, 2} were not removed")
+	}
+	if tree.GetInvertedMatrix([]int{0}) == nil || tree.GetInvertedMatrix([]int{0, 3}) == nil {
+		t.Error("recently used matrices were evicted")
+	}
+	got := tree.stats()
+	want := InversionCacheStats{Hits: 3, Misses: 1, Evictions: 1, Entries: 2, Bytes: 18}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Evicting {0} must keep the node, since it has a child.
+	tree.maxBytes = 9
+	m, _ := newMatrix(3, 3)
+	if err := tree.InsertInvertedMatrix([]int{4}, m, 5); err != nil {
+		t.Fatal(err)
+	}
+	if tree.GetInvertedMatrix([]int{0}) != nil || tree.GetInvertedMatrix([]int{0, 3}) != nil {
+		t.Error("matrices were not evicted")
+	}
+	if tree.root.children[0] != nil {
+		t.Error("nodes of {0} were not removed")
+	}
+	if s := tree.stats(); s.Entries != 1 || s.Bytes != 9 {
+		t.Errorf("got %+v, want 1 entry of 9 bytes", s)
+	}
+}
+
+func TestWithInversionCacheLimit(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithInversionCacheLimit(3, 0))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		shards[i] = nil
+		if err := enc.Reconstruct(shards); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shards[9] = nil
+	if err := enc.Reconstruct(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+	got, err := InversionCacheStatsOf(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InversionCacheStats{Hits: 1, Misses: 10, Evictions: 7, Entries: 3, Bytes: 3 * 10 * 10}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package reedsolomon
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// JoinAt writes the data segment of the shards to dst,
+// writing the data shards concurrently at their offsets.
+// Up to GOMAXPROCS shards are written at once.
+//
+// Only the data shards are considered.
+// You must supply the exact output size you want.
+// If there are too few shards given, ErrTooFewShards will be returned.
+// If the total data size is less than outSize, ErrShortData will be returned.
+// If one or more required data shards are nil, ErrReconstructRequired will be returned.
+// The first error returned by dst is returned.
+//
+// See StreamJoinerAt for joining shards read from an io.ReaderAt,
+// and JoinSparseAt for skipping blocks of zeros.
+func JoinAt(enc Encoder, dst io.WriterAt, shards [][]byte, outSize int) error {
+	return joinAt(enc, shards, 0, outSize, func(shard []byte, off int64, _ bool) error {
+		_, err := dst.WriteAt(shard, off)
+		return err
+	})
+}
+
+// joinAt checks the shards like Join, and calls write concurrently with
+// the data of each data shard and its offset in the output, starting at off.
+// last is set for the shard with the end of the output.
+func joinAt(enc Encoder, shards [][]byte, off int64, outSize int, write func(shard []byte, off int64, last bool) error) error {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return ErrNotSupported
+	}
+	if len(shards) < ext.DataShards() {
+		return ErrTooFewShards
+	}
+	shards = shards[:ext.DataShards()]
+	size := 0
+	for _, shard := range shards {
+		if shard == nil {
+			return ErrReconstructRequired
+		}
+		size += len(shard)
+		if size >= outSize {
+			break
+		}
+	}
+	if size < outSize {
+		return ErrShortData
+	}
+
+	// Cut the shards to the output, and compute their offsets.
+	var parts [][]byte
+	var offsets []int64
+	remain := outSize
+	for _, shard := range shards {
+		if remain == 0 {
+			break
+		}
+		if remain < len(shard) {
+			shard = shard[:remain]
+		}
+		remain -= len(shard)
+		if len(shard) > 0 {
+			parts = append(parts, shard)
+			offsets = append(offsets, off)
+		}
+		off += int64(len(shard))
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(parts) {
+		workers = len(parts)
+	}
+	var next atomic.Int64
+	var failed atomic.Bool
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(parts) || failed.Load() {
+					return
+				}
+				if err := write(parts[i], offsets[i], i == len(parts)-1); err != nil {
+					once.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
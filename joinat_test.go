@@ -0,0 +1,58 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// failWriterAt fails writes at or beyond off.
+type failWriterAt struct {
+	off int64
+}
+
+func (f failWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > f.off {
+		return 0, errors.New("write failed")
+	}
+	return len(p), nil
+}
+
+func TestJoinAt(t *testing.T) {
+	enc, err := New(10, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 100003)
+	fillRandom(data)
+	shards, err := enc.Split(append([]byte(nil), data...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 5000, len(data)} {
+		dst := &sparseWriter{data: make([]byte, len(data))}
+		if err := JoinAt(enc, dst, shards, size); err != nil {
+			t.Fatal(err)
+		}
+		if dst.size != int64(size) || dst.written != size {
+			t.Errorf("size %d: wrote %d bytes up to %d", size, dst.written, dst.size)
+		}
+		if !bytes.Equal(dst.data[:size], data[:size]) {
+			t.Fatalf("size %d: data mismatch", size)
+		}
+	}
+
+	if err := JoinAt(enc, failWriterAt{off: 50000}, shards, len(data)); err == nil {
+		t.Error("write error not returned")
+	}
+	if err := JoinAt(enc, &sparseWriter{}, shards, len(data)+1000); err != ErrShortData {
+		t.Errorf("got %v, want %v", err, ErrShortData)
+	}
+	if err := JoinAt(enc, &sparseWriter{}, shards[:9], len(data)); err != ErrTooFewShards {
+		t.Errorf("got %v, want %v", err, ErrTooFewShards)
+	}
+	shards[3] = nil
+	if err := JoinAt(enc, &sparseWriter{}, shards, len(data)); err != ErrReconstructRequired {
+		t.Errorf("got %v, want %v", err, ErrReconstructRequired)
+	}
+}
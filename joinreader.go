@@ -0,0 +1,95 @@
+package reedsolomon
+
+import "io"
+
+// JoinReader returns a reader of the first size bytes of the data segment
+// of the shards, like Join without the need for a writer.
+// The data is read directly from the shards, which must not be modified
+// until the reader is no longer used.
+// The reader implements io.WriterTo, so io.Copy writes the shards
+// to the destination without an intermediate buffer.
+//
+// Only the data shards are considered.
+// If there are too few shards given, ErrTooFewShards will be returned.
+// If a data shard is missing, ErrReconstructRequired will be returned.
+// If the total data size is less than size, ErrShortData will be returned.
+func JoinReader(enc Encoder, shards [][]byte, size int) (io.Reader, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(shards) < ext.DataShards() {
+		return nil, ErrTooFewShards
+	}
+	shards = shards[:ext.DataShards()]
+	total := 0
+	for i, shard := range shards {
+		if shard == nil {
+			return nil, ErrReconstructRequired
+		}
+		total += len(shard)
+		if total >= size {
+			shards = shards[:i+1]
+			break
+		}
+	}
+	if total < size {
+		return nil, ErrShortData
+	}
+	// The reader slices its own copy of the shards.
+	return &joinReader{shards: append([][]byte(nil), shards...), remain: size}, nil
+}
+
+// joinReader reads the data segment of shards.
+type joinReader struct {
+	shards [][]byte
+	remain int // Bytes left to read.
+}
+
+// next returns the unread part of the current shard, up to remain bytes.
+func (j *joinReader) next() []byte {
+	for len(j.shards) > 0 && len(j.shards[0]) == 0 {
+		j.shards = j.shards[1:]
+	}
+	if len(j.shards) == 0 || j.remain == 0 {
+		return nil
+	}
+	b := j.shards[0]
+	if len(b) > j.remain {
+		b = b[:j.remain]
+	}
+	return b
+}
+
+// advance marks n bytes of the current shard as read.
+func (j *joinReader) advance(n int) {
+	j.shards[0] = j.shards[0][n:]
+	j.remain -= n
+}
+
+func (j *joinReader) Read(p []byte) (int, error) {
+	b := j.next()
+	if len(b) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, b)
+	j.advance(n)
+	return n, nil
+}
+
+// WriteTo writes the remaining data to w.
+func (j *joinReader) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for b := j.next(); len(b) > 0; b = j.next() {
+		n, err := w.Write(b)
+		written += int64(n)
+		j.advance(n)
+		if err != nil {
+			return written, err
+		}
+		if n < len(b) {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,73 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestJoinReader(t *testing.T) {
+	enc, err := New(10, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{1, 1000, 12345} {
+		data := make([]byte, size)
+		fillRandom(data)
+		shards, err := enc.Split(append([]byte(nil), data...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		saved := append([][]byte(nil), shards...)
+
+		r, err := JoinReader(enc, shards, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := iotest.TestReader(r, data); err != nil {
+			t.Errorf("size %d: %v", size, err)
+		}
+
+		// io.Copy uses WriteTo.
+		r, err = JoinReader(enc, shards, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		n, err := io.Copy(&buf, r)
+		if err != nil || n != int64(size) || !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("size %d: copied %d bytes, err %v", size, n, err)
+		}
+		for i := range shards {
+			if len(shards[i]) != len(saved[i]) {
+				t.Fatalf("size %d: shard %d was modified", size, i)
+			}
+		}
+	}
+}
+
+func TestJoinReaderErrors(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := AllocAligned(6, 100)
+	if _, err := JoinReader(enc, shards[:3], 100); !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("got %v, want %v", err, ErrTooFewShards)
+	}
+	if _, err := JoinReader(enc, shards, 401); !errors.Is(err, ErrShortData) {
+		t.Errorf("got %v, want %v", err, ErrShortData)
+	}
+	shards[1] = nil
+	if _, err := JoinReader(enc, shards, 400); !errors.Is(err, ErrReconstructRequired) {
+		t.Errorf("got %v, want %v", err, ErrReconstructRequired)
+	}
+	if _, err := JoinReader(enc, shards, 100); err != nil {
+		t.Errorf("shards after size are not used: %v", err)
+	}
+}
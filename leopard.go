@@ -12,6 +12,8 @@ package reedsolomon
 
 import (
 	"bytes"
+	"encoding/binary"
+	"hash/maphash"
 	"io"
 	"math/bits"
 	"sync"
@@ -26,7 +28,9 @@ type leopardFF16 struct {
 	parityShards int // Number of parity shards, should not be modified.
 	totalShards  int // Total number of shards. Calculated, and should not be modified.
 
-	workPool sync.Pool
+	states    freeList[leopard16State] // Scratch state for operations.
+	inversion *leopardCache[uint64, *leopard16Decode]
+	cacheSeed maphash.Seed
 
 	o options
 }
@@ -49,6 +53,15 @@ func newFF16(dataShards, parityShards int, opt options) (*leopardFF16, error) {
 		totalShards:  dataShards + parityShards,
 		o:            opt,
 	}
+	if opt.inversionCache && (r.totalShards <= 64 || opt.forcedInversionCache) {
+		// As for leopardFF8, the cache only pays off for few shards,
+		// since each entry holds an error locator for every shard.
+		r.inversion = newLeopardCache[uint64](&opt, (*leopard16Decode).size)
+		r.cacheSeed = maphash.MakeSeed()
+	}
+	if n := opt.keptStates(); n > 0 {
+		r.states.keep(n)
+	}
 	return r, nil
 }
 
@@ -74,6 +87,17 @@ func (r *leopardFF16) AllocAligned(each int) [][]byte {
 	return AllocAligned(r.totalShards, each)
 }
 
+func (r *leopardFF16) PureGo() bool {
+	return r.o.pureGo()
+}
+
+// Recalibrate does nothing, since Leopard encoders do not split work between goroutines.
+func (r *leopardFF16) Recalibrate(shardSize int) {}
+
+func (r *leopardFF16) CostModel() CostModel {
+	return leopardCostModel(r.dataShards, r.parityShards)
+}
+
 type ffe uint16
 
 const (
@@ -126,23 +150,12 @@ func (r *leopardFF16) encode(shards [][]byte) error {
 	}
 
 	m := ceilPow2(r.parityShards)
-	var work [][]byte
-	if w, ok := r.workPool.Get().([][]byte); ok {
-		work = w
-	}
-	if cap(work) >= m*2 {
-		work = work[:m*2]
-	} else {
-		work = AllocAligned(m*2, shardSize)
-	}
-	for i := range work {
-		if cap(work[i]) < shardSize {
-			work[i] = AllocAligned(1, shardSize)[0]
-		} else {
-			work[i] = work[i][:shardSize]
-		}
+	st := r.states.get()
+	defer r.putState(st)
+	work, err := st.getWork(&r.o, m*2, shardSize)
+	if err != nil {
+		return err
 	}
-	defer r.workPool.Put(work)
 
 	mtrunc := m
 	if r.dataShards < mtrunc {
@@ -220,131 +233,92 @@ skip_body:
 	return nil
 }
 
+// EncodeIdx adds the parity of a single data shard.
+// Encoding is linear, so the parity of the data shard is found by encoding
+// the set of m data shards holding it with the other shards set to zero,
+// which only needs the IFFT of that set and the final FFT.
 func (r *leopardFF16) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
-	return ErrNotSupported
-}
-
-func (r *leopardFF16) Join(dst io.Writer, shards [][]byte, outSize int) error {
-	// Do we have enough shards?
-	if len(shards) < r.dataShards {
+	if len(parity) != r.parityShards {
 		return ErrTooFewShards
 	}
-	shards = shards[:r.dataShards]
-
-	// Do we have enough data?
-	size := 0
-	for _, shard := range shards {
-		if shard == nil {
-			return ErrReconstructRequired
-		}
-		size += len(shard)
-
-		// Do we have enough data already?
-		if size >= outSize {
-			break
-		}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
+	}
+	if err := checkShards(parity, false); err != nil {
+		return err
 	}
-	if size < outSize {
-		return ErrShortData
+	shardSize := len(parity[0])
+	if len(dataShard) != shardSize {
+		return ErrShardSize
+	}
+	if shardSize%64 != 0 {
+		return ErrInvalidShardSize
 	}
 
-	// Copy data to dst
-	write := outSize
-	for _, shard := range shards {
-		if write < len(shard) {
-			_, err := dst.Write(shard[:write])
-			return err
-		}
-		n, err := dst.Write(shard)
-		if err != nil {
-			return err
-		}
-		write -= n
+	m := ceilPow2(r.parityShards)
+	st := r.states.get()
+	defer r.putState(st)
+	work, err := st.getWork(&r.o, m+1, shardSize)
+	if err != nil {
+		return err
+	}
+
+	// The shards before idx in its set are zero.
+	// The IFFT of the set is only computed up to idx.
+	pos := idx % m
+	zero := work[m]
+	memclr(zero)
+	data := make([][]byte, pos+1)
+	for i := range data[:pos] {
+		data[i] = zero
 	}
+	data[pos] = dataShard
+
+	// work <- IFFT(data + idx - pos, m, m + idx - pos)
+	ifftDITEncoder(
+		data,
+		pos+1,
+		work,
+		nil, // No xor output
+		m,
+		fftSkew[m-1+idx-pos:],
+		&r.o,
+	)
+
+	// work <- FFT(work, m, 0)
+	fftDIT(work, r.parityShards, m, fftSkew[:], &r.o)
+	slicesXor(parity, work[:r.parityShards], &r.o)
 	return nil
 }
 
+func (r *leopardFF16) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
+
 func (r *leopardFF16) Update(shards [][]byte, newDatashards [][]byte) error {
 	return ErrNotSupported
 }
 
 func (r *leopardFF16) Split(data []byte) ([][]byte, error) {
-	if len(data) == 0 {
-		return nil, ErrShortData
-	}
-	if r.totalShards == 1 && len(data)&63 == 0 {
-		return [][]byte{data}, nil
-	}
-	dataLen := len(data)
-	// Calculate number of bytes per data shard.
-	perShard := (len(data) + r.dataShards - 1) / r.dataShards
-	perShard = ((perShard + 63) / 64) * 64
-	needTotal := r.totalShards * perShard
-
-	if cap(data) > len(data) {
-		if cap(data) > needTotal {
-			data = data[:needTotal]
-		} else {
-			data = data[:cap(data)]
-		}
-		clear := data[dataLen:]
-		for i := range clear {
-			clear[i] = 0
-		}
-	}
-
-	// Only allocate memory if necessary
-	var padding [][]byte
-	if len(data) < needTotal {
-		// calculate maximum number of full shards in `data` slice
-		fullShards := len(data) / perShard
-		padding = AllocAligned(r.totalShards-fullShards, perShard)
-		if dataLen > perShard*fullShards {
-			// Copy partial shards
-			copyFrom := data[perShard*fullShards : dataLen]
-			for i := range padding {
-				if len(copyFrom) == 0 {
-					break
-				}
-				copyFrom = copyFrom[copy(padding[i], copyFrom):]
-			}
-		}
-	} else {
-		zero := data[dataLen : r.totalShards*perShard]
-		for i := range zero {
-			zero[i] = 0
-		}
-	}
-
-	// Split into equal-length shards.
-	dst := make([][]byte, r.totalShards)
-	i := 0
-	for ; i < len(dst) && len(data) >= perShard; i++ {
-		dst[i] = data[:perShard:perShard]
-		data = data[perShard:]
-	}
-
-	for j := 0; i+j < len(dst); j++ {
-		dst[i+j] = padding[0]
-		padding = padding[1:]
-	}
-
-	return dst, nil
+	return splitShards(data, r.dataShards, r.totalShards, 64, r.o.zeroCopySplit, false)
 }
 
 func (r *leopardFF16) ReconstructSome(shards [][]byte, required []bool) error {
 	if len(required) == r.totalShards {
-		return r.reconstruct(shards, true)
+		return r.reconstruct(shards, true, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
 	}
-	return r.reconstruct(shards, false)
+	return r.reconstruct(shards, false, required)
 }
 
 func (r *leopardFF16) Reconstruct(shards [][]byte) error {
-	return r.reconstruct(shards, true)
+	return r.reconstruct(shards, true, nil)
 }
 
 func (r *leopardFF16) ReconstructData(shards [][]byte) error {
-	return r.reconstruct(shards, false)
+	return r.reconstruct(shards, false, nil)
 }
 
 func (r *leopardFF16) Verify(shards [][]byte) (bool, error) {
@@ -375,7 +349,7 @@ func (r *leopardFF16) Verify(shards [][]byte) (bool, error) {
 	return true, nil
 }
 
-func (r *leopardFF16) reconstruct(shards [][]byte, recoverAll bool) error {
+func (r *leopardFF16) reconstruct(shards [][]byte, recoverAll bool, required []bool) error {
 	if len(shards) != r.totalShards {
 		return ErrTooFewShards
 	}
@@ -388,15 +362,19 @@ func (r *leopardFF16) reconstruct(shards [][]byte, recoverAll bool) error {
 	// nothing to do.
 	numberPresent := 0
 	dataPresent := 0
+	missingRequired := 0
 	for i := 0; i < r.totalShards; i++ {
 		if len(shards[i]) != 0 {
 			numberPresent++
 			if i < r.dataShards {
 				dataPresent++
 			}
+		} else if required != nil && i < len(required) && required[i] {
+			missingRequired++
 		}
 	}
-	if numberPresent == r.totalShards || !recoverAll && dataPresent == r.dataShards {
+	if numberPresent == r.totalShards || !recoverAll && dataPresent == r.dataShards ||
+		required != nil && missingRequired == 0 {
 		// Cool. All of the shards have data. We don't
 		// need to do anything.
 		return nil
@@ -420,62 +398,78 @@ func (r *leopardFF16) reconstruct(shards [][]byte, recoverAll bool) error {
 
 	const LEO_ERROR_BITFIELD_OPT = true
 
-	// Fill in error locations.
-	var errorBits errorBitfield
-	var errLocs [order]ffe
-	for i := 0; i < r.parityShards; i++ {
-		if len(shards[i+r.dataShards]) == 0 {
-			errLocs[i] = 1
-			if LEO_ERROR_BITFIELD_OPT && recoverAll {
-				errorBits.set(i)
+	st := r.states.get()
+	defer r.putState(st)
+
+	errorBits := &st.errorBits
+	var errLocs []ffe
+	if r.inversion != nil {
+		st.key = r.cacheKey(st.key[:0], shards, recoverAll)
+		// Patterns with colliding hashes are not cached.
+		if d, ok := r.inversion.get(maphash.Bytes(r.cacheSeed, st.key)); ok && d.key == string(st.key) {
+			errLocs = d.errLocs
+			if d.bits != nil {
+				errorBits = d.bits
 			}
+			useBits = d.bits != nil
 		}
 	}
-	for i := r.parityShards; i < m; i++ {
-		errLocs[i] = 1
-		if LEO_ERROR_BITFIELD_OPT && recoverAll {
-			errorBits.set(i)
+
+	if errLocs == nil {
+		// Fill in error locations.
+		*errorBits = errorBitfield{}
+		var locs [order]ffe
+		for i := 0; i < r.parityShards; i++ {
+			if len(shards[i+r.dataShards]) == 0 {
+				locs[i] = 1
+				if LEO_ERROR_BITFIELD_OPT && recoverAll {
+					errorBits.set(i)
+				}
+			}
 		}
-	}
-	for i := 0; i < r.dataShards; i++ {
-		if len(shards[i]) == 0 {
-			errLocs[i+m] = 1
-			if LEO_ERROR_BITFIELD_OPT {
-				errorBits.set(i + m)
+		for i := r.parityShards; i < m; i++ {
+			locs[i] = 1
+			if LEO_ERROR_BITFIELD_OPT && recoverAll {
+				errorBits.set(i)
+			}
+		}
+		for i := 0; i < r.dataShards; i++ {
+			if len(shards[i]) == 0 {
+				locs[i+m] = 1
+				if LEO_ERROR_BITFIELD_OPT {
+					errorBits.set(i + m)
+				}
 			}
 		}
-	}
 
-	if LEO_ERROR_BITFIELD_OPT && useBits {
-		errorBits.prepare()
-	}
+		if LEO_ERROR_BITFIELD_OPT && useBits {
+			errorBits.prepare()
+		}
 
-	// Evaluate error locator polynomial
-	fwht(&errLocs, m+r.dataShards)
+		// Evaluate error locator polynomial
+		fwht(&locs, m+r.dataShards)
 
-	for i := 0; i < order; i++ {
-		errLocs[i] = ffe((uint(errLocs[i]) * uint(logWalsh[i])) % modulus)
-	}
+		for i := 0; i < order; i++ {
+			locs[i] = ffe((uint(locs[i]) * uint(logWalsh[i])) % modulus)
+		}
 
-	fwht(&errLocs, order)
+		fwht(&locs, order)
+		errLocs = locs[:m+r.dataShards]
 
-	var work [][]byte
-	if w, ok := r.workPool.Get().([][]byte); ok {
-		work = w
-	}
-	if cap(work) >= n {
-		work = work[:n]
-	} else {
-		work = make([][]byte, n)
-	}
-	for i := range work {
-		if cap(work[i]) < shardSize {
-			work[i] = make([]byte, shardSize)
-		} else {
-			work[i] = work[i][:shardSize]
+		if r.inversion != nil {
+			d := &leopard16Decode{key: string(st.key), errLocs: append([]ffe(nil), errLocs...)}
+			if LEO_ERROR_BITFIELD_OPT && useBits {
+				bits := *errorBits
+				d.bits = &bits
+			}
+			r.inversion.put(maphash.Bytes(r.cacheSeed, st.key), d)
 		}
 	}
-	defer r.workPool.Put(work)
+
+	work, err := st.getWork(&r.o, n, shardSize)
+	if err != nil {
+		return err
+	}
 
 	// work <- recovery data
 
@@ -541,7 +535,7 @@ func (r *leopardFF16) reconstruct(shards [][]byte, recoverAll bool) error {
 		end = r.totalShards
 	}
 	for i := 0; i < end; i++ {
-		if len(shards[i]) != 0 {
+		if len(shards[i]) != 0 || required != nil && !required[i] {
 			continue
 		}
 		if cap(shards[i]) >= shardSize {
@@ -561,6 +555,96 @@ func (r *leopardFF16) reconstruct(shards [][]byte, recoverAll bool) error {
 }
 
 // Basic no-frills version for decoder
+// leopardState contains the work buffers of a Leopard operation.
+// States are reused between operations.
+type leopardState struct {
+	work  [][]byte
+	spill func() // Releases work buffers backed by a temporary file.
+}
+
+// leopard16State is the state of a leopardFF16 operation.
+type leopard16State struct {
+	leopardState
+	errorBits errorBitfield
+	key       []byte // Inversion cache key.
+}
+
+// leopard16Decode is the decoding setup of leopardFF16 for an erasure pattern.
+type leopard16Decode struct {
+	key     string         // Key returned by cacheKey.
+	errLocs []ffe          // Error locator of the first m+dataShards positions.
+	bits    *errorBitfield // Prepared error bits, if used.
+}
+
+// size returns the bytes used by d.
+func (d *leopard16Decode) size() int64 {
+	n := int64(len(d.key) + len(d.errLocs)*2)
+	if d.bits != nil {
+		n += int64(binary.Size(d.bits))
+	}
+	return n
+}
+
+// cacheKey appends the inversion cache key of an erasure pattern to dst.
+// The key is a bitmap of the missing shards, followed by recoverAll.
+func (r *leopardFF16) cacheKey(dst []byte, shards [][]byte, recoverAll bool) []byte {
+	for i := 0; i < len(shards); i += 8 {
+		var b byte
+		for j := i; j < i+8 && j < len(shards); j++ {
+			if len(shards[j]) == 0 {
+				b |= 1 << (j - i)
+			}
+		}
+		dst = append(dst, b)
+	}
+	if recoverAll {
+		return append(dst, 1)
+	}
+	return append(dst, 0)
+}
+
+// getWork returns n work buffers of shardSize bytes, kept in st.
+// If the buffers exceed the spill budget, they are backed by a temporary file.
+// release must be called when the buffers are no longer used.
+func (st *leopardState) getWork(o *options, n, shardSize int) ([][]byte, error) {
+	if o.spillBudget > 0 && int64(n)*int64(shardSize) > o.spillBudget {
+		work, release, err := spillBuffers(o.spillDir, n, shardSize)
+		if err != ErrNotSupported {
+			st.spill = release
+			return work, err
+		}
+	}
+	work := st.work
+	if cap(work) >= n {
+		work = work[:n]
+	} else {
+		work = AllocAligned(n, shardSize)
+	}
+	for i := range work {
+		if cap(work[i]) < shardSize {
+			work[i] = AllocAligned(1, shardSize)[0]
+		} else {
+			work[i] = work[i][:shardSize]
+		}
+	}
+	st.work = work
+	return work, nil
+}
+
+// release releases the work buffers backed by a temporary file, if any.
+func (st *leopardState) release() {
+	if st.spill != nil {
+		st.spill()
+		st.spill = nil
+	}
+}
+
+// putState releases a state returned by getState.
+func (r *leopardFF16) putState(st *leopard16State) {
+	st.release()
+	r.states.put(st)
+}
+
 func ifftDITDecoder(mtrunc int, work [][]byte, m int, skewLUT []ffe, o *options) {
 	// Decimation in time: Unroll 2 layers at a time
 	dist := 1
@@ -0,0 +1,135 @@
+// Package leopard exposes the GF(2^16) transforms used by the Leopard
+// compatible codes of the reedsolomon package, so custom codes,
+// like interleaved or layered codes, can be built on the same
+// optimized implementations.
+//
+// The transforms operate on slices of field elements stored as in the
+// shards of the Leopard codes: each 64 byte block holds 32 elements,
+// with the low bytes in the first 32 bytes and the high bytes in the last 32.
+// All slices passed to one call must have the same length,
+// which must be a multiple of 64.
+//
+// Multiplicands are given as logarithms, see Log.
+package leopard
+
+import (
+	"github.com/xyz78055368/reedsolomon"
+	"github.com/xyz78055368/reedsolomon/internal/leopardhook"
+)
+
+const (
+	// Order is the number of elements of the field.
+	Order = 1 << 16
+
+	// Modulus is the order of the multiplicative group of the field.
+	Modulus = Order - 1
+
+	// BlockSize is the size of a block of 32 elements.
+	BlockSize = 64
+)
+
+// FFT evaluates the polynomials in work[:m] at the m points starting at offset,
+// in place, using the additive FFT of Lin, Han and Chung.
+// Only work[:mtrunc] is read; the rest of work is assumed to be zero.
+// m must be a power of two, mtrunc at most m,
+// and offset a multiple of m with offset+m at most Order.
+//
+// Leopard codes encode by evaluating the IFFT of the data at offset m,
+// and decode by evaluating the FFT at offset 0.
+func FFT(work [][]byte, mtrunc, m, offset int) error {
+	if err := checkTransform(work, mtrunc, m, offset); err != nil {
+		return err
+	}
+	leopardhook.FFT(work, mtrunc, m, offset)
+	return nil
+}
+
+// IFFT is the inverse of FFT.
+// Only work[:mtrunc] is read; the rest of work is assumed to be zero.
+// All of work[:m] is written.
+func IFFT(work [][]byte, mtrunc, m, offset int) error {
+	if err := checkTransform(work, mtrunc, m, offset); err != nil {
+		return err
+	}
+	for _, w := range work[mtrunc:m] {
+		for i := range w {
+			w[i] = 0
+		}
+	}
+	leopardhook.IFFT(work, mtrunc, m, offset)
+	return nil
+}
+
+// FWHT performs the fast Walsh-Hadamard transform of data,
+// with the additions modulo Modulus, in place.
+// Only data[:mtrunc] is read; the rest of data is assumed to be zero.
+// Leopard codes use it to compute the error locator polynomial
+// from the logarithms of the field elements.
+// An mtrunc outside 1 to Order transforms all of data.
+func FWHT(data *[Order]uint16, mtrunc int) {
+	if mtrunc <= 0 || mtrunc > Order {
+		mtrunc = Order
+	}
+	for i := mtrunc; i < Order; i++ {
+		data[i] = 0
+	}
+	leopardhook.FWHT(data, mtrunc)
+}
+
+// Mul sets dst to src multiplied by the element with logarithm logM.
+func Mul(dst, src []byte, logM uint16) error {
+	if err := checkPair(dst, src); err != nil {
+		return err
+	}
+	leopardhook.Mul(dst, src, logM)
+	return nil
+}
+
+// Xor adds src to dst.
+func Xor(dst, src []byte) error {
+	if err := checkPair(dst, src); err != nil {
+		return err
+	}
+	leopardhook.Xor(dst, src)
+	return nil
+}
+
+// Log returns the logarithm of x, used to give multiplicands.
+// Zero has no logarithm and is returned as Modulus,
+// which multiplies like 0, the logarithm of one.
+func Log(x uint16) uint16 {
+	return leopardhook.Log(x)
+}
+
+// Exp returns the element with logarithm logX.
+func Exp(logX uint16) uint16 {
+	return leopardhook.Exp(logX)
+}
+
+// checkTransform checks the arguments of FFT and IFFT.
+func checkTransform(work [][]byte, mtrunc, m, offset int) error {
+	if m <= 0 || m&(m-1) != 0 || m > len(work) {
+		return reedsolomon.ErrInvShardNum
+	}
+	if mtrunc < 0 || mtrunc > m || offset < 0 || offset%m != 0 || offset+m > Order {
+		return reedsolomon.ErrInvalidInput
+	}
+	size := len(work[0])
+	if size == 0 || size%BlockSize != 0 {
+		return reedsolomon.ErrShardSize
+	}
+	for _, w := range work[1:m] {
+		if len(w) != size {
+			return reedsolomon.ErrShardSize
+		}
+	}
+	return nil
+}
+
+// checkPair checks the arguments of Mul and Xor.
+func checkPair(dst, src []byte) error {
+	if len(dst) != len(src) || len(dst)%BlockSize != 0 {
+		return reedsolomon.ErrShardSize
+	}
+	return nil
+}
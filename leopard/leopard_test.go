@@ -0,0 +1,180 @@
+package leopard
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/xyz78055368/reedsolomon"
+)
+
+func randomWork(rng *rand.Rand, n, size int) [][]byte {
+	work := reedsolomon.AllocAligned(n, size)
+	for _, w := range work {
+		rng.Read(w)
+	}
+	return work
+}
+
+func cloneWork(work [][]byte) [][]byte {
+	res := make([][]byte, len(work))
+	for i, w := range work {
+		res[i] = append([]byte(nil), w...)
+	}
+	return res
+}
+
+func TestTransformRoundtrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, m := range []int{1, 2, 4, 8, 16, 64} {
+		for _, offset := range []int{0, m, 5 * m} {
+			work := randomWork(rng, m, 256)
+			want := cloneWork(work)
+			if err := IFFT(work, m, m, offset); err != nil {
+				t.Fatal(err)
+			}
+			if m > 1 && bytes.Equal(work[m-1], want[m-1]) {
+				t.Errorf("m=%d offset=%d: IFFT did not change data", m, offset)
+			}
+			if err := FFT(work, m, m, offset); err != nil {
+				t.Fatal(err)
+			}
+			for i := range work {
+				if !bytes.Equal(work[i], want[i]) {
+					t.Fatalf("m=%d offset=%d: element %d not restored", m, offset, i)
+				}
+			}
+		}
+	}
+}
+
+// TestEncodeGF16 encodes with the transforms
+// and compares with the Leopard GF16 encoder.
+func TestEncodeGF16(t *testing.T) {
+	const dataShards, parityShards, size = 10, 4, 1024
+	rng := rand.New(rand.NewSource(1))
+	enc, err := reedsolomon.New(dataShards, parityShards, reedsolomon.WithLeopardGF16(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := randomWork(rng, dataShards+parityShards, size)
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+
+	// The parity is the FFT at offset 0 of the sum of the IFFTs
+	// of each m data shards at offset m+i.
+	const m = 4
+	work := reedsolomon.AllocAligned(m, size)
+	tmp := reedsolomon.AllocAligned(m, size)
+	for i := 0; i < dataShards; i += m {
+		n := dataShards - i
+		if n > m {
+			n = m
+		}
+		for j := 0; j < n; j++ {
+			copy(tmp[j], shards[i+j])
+		}
+		if err := IFFT(tmp, n, m, m+i); err != nil {
+			t.Fatal(err)
+		}
+		for j := range work {
+			if err := Xor(work[j], tmp[j]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := FFT(work, parityShards, m, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < parityShards; i++ {
+		if !bytes.Equal(work[i], shards[dataShards+i]) {
+			t.Fatalf("parity shard %d does not match the encoder", i)
+		}
+	}
+}
+
+func TestMul(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	src := randomWork(rng, 1, 128)[0]
+	dst := make([]byte, len(src))
+	a := uint16(1 + rng.Intn(Modulus))
+	if err := Mul(dst, src, Log(a)); err != nil {
+		t.Fatal(err)
+	}
+	// Multiplying by the inverse of a restores src.
+	inv := uint16(Modulus - Log(a))
+	back := make([]byte, len(src))
+	if err := Mul(back, dst, inv); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(back, src) {
+		t.Fatal("multiplying by the inverse did not restore the input")
+	}
+	if err := Mul(dst, src, Log(1)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst, src) {
+		t.Fatal("multiplying by one changed the input")
+	}
+	for x := 0; x < Order; x += 251 {
+		if got := Exp(Log(uint16(x))); x != 0 && got != uint16(x) {
+			t.Fatalf("Exp(Log(%d)) = %d", x, got)
+		}
+	}
+}
+
+func TestFWHT(t *testing.T) {
+	var data, want [Order]uint16
+	rng := rand.New(rand.NewSource(3))
+	const n = 32
+	for i := 0; i < n; i++ {
+		data[i] = uint16(rng.Intn(Modulus))
+	}
+	// Compare with the definition for a few outputs.
+	for _, k := range []int{0, 1, 7, 31, 1000, Order - 1} {
+		var sum uint32
+		for i := 0; i < n; i++ {
+			v := uint32(data[i])
+			if popcount(i&k)&1 != 0 {
+				v = Modulus - v
+			}
+			sum = (sum + v) % Modulus
+		}
+		want[k] = uint16(sum)
+	}
+	FWHT(&data, n)
+	for _, k := range []int{0, 1, 7, 31, 1000, Order - 1} {
+		if data[k]%Modulus != want[k] {
+			t.Errorf("element %d: got %d, want %d", k, data[k], want[k])
+		}
+	}
+}
+
+func popcount(x int) int {
+	n := 0
+	for ; x != 0; x &= x - 1 {
+		n++
+	}
+	return n
+}
+
+func TestInvalidArguments(t *testing.T) {
+	work := reedsolomon.AllocAligned(4, 64)
+	if err := FFT(work, 4, 3, 0); err != reedsolomon.ErrInvShardNum {
+		t.Errorf("m=3: got %v", err)
+	}
+	if err := FFT(work, 4, 4, 2); err != reedsolomon.ErrInvalidInput {
+		t.Errorf("offset=2: got %v", err)
+	}
+	if err := IFFT(work, 5, 4, 0); err != reedsolomon.ErrInvalidInput {
+		t.Errorf("mtrunc=5: got %v", err)
+	}
+	work[2] = work[2][:32]
+	if err := FFT(work, 4, 4, 0); err != reedsolomon.ErrShardSize {
+		t.Errorf("short element: got %v", err)
+	}
+	if err := Xor(make([]byte, 64), make([]byte, 128)); err != reedsolomon.ErrShardSize {
+		t.Errorf("xor sizes: got %v", err)
+	}
+}
@@ -0,0 +1,647 @@
+package reedsolomon
+
+// This is a O(n*log n) implementation of Reed-Solomon codes over GF(2^32),
+// for more than 65536 total shards.
+// It uses the same algorithm as leopardFF16, see leopard.go.
+//
+// GF(2^32) is built as a quadratic extension of the 16 bit field,
+// so multiplication can use the 16 bit log tables.
+// The field is too large for complete tables, so FFT skews are taken
+// directly from a Cantor basis, and logarithms are computed from the norm
+// of an element and its component in the subgroup of norm 1.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// leopardFF32 is like reedSolomon but for more than 65536 total shards.
+type leopardFF32 struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	// logWalsh contains the FWHT of the logarithms of the evaluation points.
+	// It is calculated on first reconstruction.
+	logWalsh     []uint32
+	logWalshOnce sync.Once
+
+	states freeList[leopardState] // Scratch state for operations.
+
+	o options
+}
+
+// leopard32MaxShards is the maximum number of total shards for leopardFF32.
+const leopard32MaxShards = 1 << 24
+
+// newFF32 is like New, but for more than 65536 total shards.
+func newFF32(dataShards, parityShards int, opt options) (*leopardFF32, error) {
+	initConstants32()
+
+	if dataShards <= 0 || parityShards <= 0 {
+		return nil, ErrInvShardNum
+	}
+
+	if dataShards+parityShards > leopard32MaxShards {
+		return nil, ErrMaxShardNum
+	}
+
+	r := &leopardFF32{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		o:            opt,
+	}
+	if n := opt.keptStates(); n > 0 {
+		r.states.keep(n)
+	}
+	return r, nil
+}
+
+var _ = Extensions(&leopardFF32{})
+
+func (r *leopardFF32) ShardSizeMultiple() int {
+	return 64
+}
+
+func (r *leopardFF32) DataShards() int {
+	return r.dataShards
+}
+
+func (r *leopardFF32) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *leopardFF32) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *leopardFF32) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+// PureGo returns true, since there are no assembly versions of the GF(2^32) code.
+func (r *leopardFF32) PureGo() bool {
+	return true
+}
+
+// Recalibrate does nothing, since Leopard encoders do not split work between goroutines.
+func (r *leopardFF32) Recalibrate(shardSize int) {}
+
+func (r *leopardFF32) CostModel() CostModel {
+	return leopardCostModel(r.dataShards, r.parityShards)
+}
+
+// ffe32 is an element of GF(2^32), represented as hi*y + lo,
+// where hi and lo are elements of the 16 bit field and y^2 = y + ff32Poly.
+type ffe32 uint32
+
+// modulus32 is the order of the multiplicative group of GF(2^32).
+const modulus32 = 1<<32 - 1
+
+var (
+	// ff32Poly is the constant term of the polynomial defining the extension.
+	ff32Poly ffe
+
+	// ff32Cantor is a Cantor basis, with ff32Cantor[0] = 1 and
+	// ff32Cantor[i]^2 + ff32Cantor[i] = ff32Cantor[i-1].
+	// Evaluation point i is the sum of the basis elements for the bits set in i.
+	ff32Cantor [32]ffe32
+
+	// ff32ExpU contains the powers of a generator of the subgroup of norm 1,
+	// which has prime order 65537.
+	ff32ExpU *[65537]ffe32
+
+	// ff32LogU is the inverse of ff32ExpU.
+	ff32LogU map[ffe32]uint32
+)
+
+var initOnce32 sync.Once
+
+func initConstants32() {
+	initConstants()
+	initOnce32.Do(func() {
+		// y^2 + y + c is irreducible when the trace of c is 1.
+		for c := ffe(1); ; c++ {
+			t, sq := c, c
+			for i := 1; i < bitwidth; i++ {
+				sq = mul16(sq, sq)
+				t ^= sq
+			}
+			if t == 1 {
+				ff32Poly = c
+				break
+			}
+		}
+
+		ff32Cantor[0] = 1
+		for i := 1; i < len(ff32Cantor); i++ {
+			v, ok := solveQuadratic32(ff32Cantor[i-1])
+			if !ok {
+				panic("reedsolomon: no Cantor basis for GF(2^32)")
+			}
+			ff32Cantor[i] = v
+		}
+
+		// conj(y)/y has norm 1, and is not 1.
+		y := ffe32(1 << 16)
+		h := mul32(conj32(y), inv32(y))
+		ff32ExpU = &[65537]ffe32{}
+		ff32LogU = make(map[ffe32]uint32, len(ff32ExpU))
+		v := ffe32(1)
+		for i := range ff32ExpU {
+			ff32ExpU[i] = v
+			ff32LogU[v] = uint32(i)
+			v = mul32(v, h)
+		}
+	})
+}
+
+// mul16 returns a * b in the 16 bit field.
+func mul16(a, b ffe) ffe {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expLUT[addMod(logLUT[a], logLUT[b])]
+}
+
+// mul32 returns a * b.
+func mul32(a, b ffe32) ffe32 {
+	a1, a0, b1, b0 := ffe(a>>16), ffe(a), ffe(b>>16), ffe(b)
+	hh := mul16(a1, b1)
+	hi := hh ^ mul16(a1, b0) ^ mul16(a0, b1)
+	lo := mul16(a0, b0) ^ mul16(ff32Poly, hh)
+	return ffe32(hi)<<16 | ffe32(lo)
+}
+
+// conj32 returns a^(2^16), which maps y to y + 1.
+func conj32(a ffe32) ffe32 {
+	return a ^ a>>16
+}
+
+// norm32 returns a * conj32(a), which is an element of the 16 bit field.
+func norm32(a ffe32) ffe {
+	a1, a0 := ffe(a>>16), ffe(a)
+	return mul16(a0, a0^a1) ^ mul16(ff32Poly, mul16(a1, a1))
+}
+
+// inv32 returns 1/a for non-zero a.
+func inv32(a ffe32) ffe32 {
+	c := conj32(a)
+	n := expLUT[modulus-logLUT[norm32(a)]]
+	return ffe32(mul16(ffe(c>>16), n))<<16 | ffe32(mul16(ffe(c), n))
+}
+
+// log32 returns the discrete logarithm of a non-zero element.
+//
+// The multiplicative group is the product of the 16 bit field and the subgroup of norm 1,
+// since their orders 65535 and 65537 are coprime.
+// Writing a = g^alpha * h^beta, the norm of a is g^(2*alpha) and
+// conj32(a)/a is h^(-2*beta).
+// The logarithm is combined from alpha and beta by the Chinese remainder theorem.
+func log32(a ffe32) uint32 {
+	n := norm32(a)
+	alpha := uint64(logLUT[n]) * 32768 % 65535
+	c := conj32(a)
+	u := mul32(c, c)
+	ninv := expLUT[modulus-logLUT[n]]
+	u = ffe32(mul16(ffe(u>>16), ninv))<<16 | ffe32(mul16(ffe(u), ninv))
+	beta := uint64(ff32LogU[u]) * 32768 % 65537
+	t := (alpha + 65537 - beta) * 32769 % 65537
+	return uint32(alpha + 65535*t)
+}
+
+// exp32 returns the element with logarithm l.
+func exp32(l uint32) ffe32 {
+	g := expLUT[uint64(l)%65535]
+	h := ff32ExpU[uint64(l)%65537]
+	return ffe32(mul16(ffe(h>>16), g))<<16 | ffe32(mul16(ffe(h), g))
+}
+
+// solveQuadratic32 returns z so that z^2 + z = a, if one exists.
+func solveQuadratic32(a ffe32) (ffe32, bool) {
+	// z -> z^2 + z is linear, so solve the system by Gaussian elimination.
+	var img, pre [32]ffe32
+	for i := range img {
+		pre[i] = 1 << i
+		img[i] = mul32(pre[i], pre[i]) ^ pre[i]
+	}
+	var z ffe32
+	n := 0
+	for bit := 31; bit >= 0; bit-- {
+		p := n
+		for p < len(img) && img[p]>>bit&1 == 0 {
+			p++
+		}
+		if p == len(img) {
+			continue
+		}
+		img[n], img[p] = img[p], img[n]
+		pre[n], pre[p] = pre[p], pre[n]
+		for i := range img {
+			if i != n && img[i]>>bit&1 != 0 {
+				img[i] ^= img[n]
+				pre[i] ^= pre[n]
+			}
+		}
+		if a>>bit&1 != 0 {
+			a ^= img[n]
+			z ^= pre[n]
+		}
+		n++
+	}
+	return z, a == 0
+}
+
+// point32 returns evaluation point i.
+func point32(i int) ffe32 {
+	var v ffe32
+	for b := 0; i != 0; b++ {
+		if i&1 != 0 {
+			v ^= ff32Cantor[b]
+		}
+		i >>= 1
+	}
+	return v
+}
+
+// mulAdd32 sets x ^= y * c, where the slices contain little endian elements.
+func mulAdd32(x, y []byte, c ffe32) {
+	if c == 0 {
+		return
+	}
+	x = x[:len(y)]
+	if len(y) < 1024 {
+		for i := 0; i+4 <= len(y); i += 4 {
+			v := mul32(ffe32(binary.LittleEndian.Uint32(y[i:])), c)
+			binary.LittleEndian.PutUint32(x[i:], binary.LittleEndian.Uint32(x[i:])^uint32(v))
+		}
+		return
+	}
+
+	// Multiplication is linear, so look up each byte separately.
+	var lut [4][256]ffe32
+	for k := range lut {
+		for b := 0; b < 8; b++ {
+			lut[k][1<<b] = mul32(c, 1<<(8*k+b))
+		}
+		for v := 3; v < 256; v++ {
+			if low := v & -v; low != v {
+				lut[k][v] = lut[k][low] ^ lut[k][v^low]
+			}
+		}
+	}
+	for i := 0; i+4 <= len(y); i += 4 {
+		v := lut[0][y[i]] ^ lut[1][y[i+1]] ^ lut[2][y[i+2]] ^ lut[3][y[i+3]]
+		binary.LittleEndian.PutUint32(x[i:], binary.LittleEndian.Uint32(x[i:])^uint32(v))
+	}
+}
+
+// mul32Slice sets x = y * c.
+func mul32Slice(x, y []byte, c ffe32) {
+	memclr(x)
+	mulAdd32(x, y, c)
+}
+
+// fft32 evaluates the polynomial with coefficients in work, in the novel basis,
+// at the points offset to offset+len(work)-1.
+// len(work) must be a power of two, and offset must be a multiple of it.
+func fft32(work [][]byte, offset int, o *options) {
+	n := len(work)
+	for dist := n >> 1; dist > 0; dist >>= 1 {
+		shift := bits.TrailingZeros(uint(dist))
+		for r := 0; r < n; r += dist * 2 {
+			// With a Cantor basis the skew is an evaluation point.
+			skew := point32((offset + r) >> shift)
+			for i := r; i < r+dist; i++ {
+				mulAdd32(work[i], work[i+dist], skew)
+				sliceXor(work[i], work[i+dist], o)
+			}
+		}
+	}
+}
+
+// ifft32 is the inverse of fft32.
+func ifft32(work [][]byte, offset int, o *options) {
+	n := len(work)
+	for dist := 1; dist < n; dist <<= 1 {
+		shift := bits.TrailingZeros(uint(dist))
+		for r := 0; r < n; r += dist * 2 {
+			skew := point32((offset + r) >> shift)
+			for i := r; i < r+dist; i++ {
+				sliceXor(work[i], work[i+dist], o)
+				mulAdd32(work[i], work[i+dist], skew)
+			}
+		}
+	}
+}
+
+// fwht32 is the Walsh-Hadamard transform of data, modulo modulus32.
+func fwht32(data []uint32) {
+	for dist := 1; dist < len(data); dist <<= 1 {
+		for r := 0; r < len(data); r += dist * 2 {
+			for i := r; i < r+dist; i++ {
+				a, b := uint64(data[i]), uint64(data[i+dist])
+				sum := a + b
+				dif := a + modulus32 - b
+				data[i] = uint32(sum&modulus32 + sum>>32)
+				data[i+dist] = uint32(dif&modulus32 + dif>>32)
+			}
+		}
+	}
+}
+
+func (r *leopardFF32) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	return r.encode(shards)
+}
+
+func (r *leopardFF32) encode(shards [][]byte) error {
+	shardSize := shardSize(shards)
+	if shardSize%64 != 0 {
+		return ErrInvalidShardSize
+	}
+
+	m := ceilPow2(r.parityShards)
+	st := r.states.get()
+	defer r.putState(st)
+	work, err := st.getWork(&r.o, m*2, shardSize)
+	if err != nil {
+		return err
+	}
+
+	// Parity is at points 0 to m-1, and data shard i at point m+i.
+	// work <- sum of IFFT(data + i, m, m + i) for each set of m data pieces.
+	sum, temp := work[:m], work[m:]
+	for _, w := range sum {
+		memclr(w)
+	}
+	for i := 0; i < r.dataShards; i += m {
+		data := shards[i:r.dataShards]
+		for j, w := range temp {
+			if j < len(data) {
+				copy(w, data[j])
+			} else {
+				memclr(w)
+			}
+		}
+		ifft32(temp, m+i, &r.o)
+		slicesXor(sum, temp, &r.o)
+	}
+
+	// work <- FFT(work, m, 0)
+	fft32(sum, 0, &r.o)
+
+	for i, w := range sum[:r.parityShards] {
+		sh := shards[i+r.dataShards]
+		if cap(sh) >= shardSize {
+			sh = append(sh[:0], w...)
+		} else {
+			sh = append([]byte(nil), w...)
+		}
+		shards[i+r.dataShards] = sh
+	}
+	return nil
+}
+
+func (r *leopardFF32) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	return ErrNotSupported
+}
+
+func (r *leopardFF32) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
+
+func (r *leopardFF32) Update(shards [][]byte, newDatashards [][]byte) error {
+	return ErrNotSupported
+}
+
+func (r *leopardFF32) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, 64, r.o.zeroCopySplit, false)
+}
+
+func (r *leopardFF32) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, true, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, false, required)
+}
+
+func (r *leopardFF32) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+func (r *leopardFF32) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *leopardFF32) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	shardSize := len(shards[0])
+	outputs := make([][]byte, r.totalShards)
+	copy(outputs, shards[:r.dataShards])
+	for i := r.dataShards; i < r.totalShards; i++ {
+		outputs[i] = make([]byte, shardSize)
+	}
+	if err := r.Encode(outputs); err != nil {
+		return false, err
+	}
+
+	// Compare.
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !bytes.Equal(outputs[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *leopardFF32) reconstruct(shards [][]byte, recoverAll bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+
+	// Quick check: are all of the shards present?  If so, there's
+	// nothing to do.
+	numberPresent := 0
+	dataPresent := 0
+	missingRequired := 0
+	for i := 0; i < r.totalShards; i++ {
+		if len(shards[i]) != 0 {
+			numberPresent++
+			if i < r.dataShards {
+				dataPresent++
+			}
+		} else if required != nil && i < len(required) && required[i] {
+			missingRequired++
+		}
+	}
+	if numberPresent == r.totalShards || !recoverAll && dataPresent == r.dataShards ||
+		required != nil && missingRequired == 0 {
+		// Cool. All of the shards have data. We don't
+		// need to do anything.
+		return nil
+	}
+
+	// Check if we have enough to reconstruct.
+	if numberPresent < r.dataShards {
+		return ErrTooFewShards
+	}
+
+	shardSize := shardSize(shards)
+	if shardSize%64 != 0 {
+		return ErrInvalidShardSize
+	}
+
+	m := ceilPow2(r.parityShards)
+	n := ceilPow2(m + r.dataShards)
+
+	// Fill in error locations.
+	errLocs := make([]uint32, n)
+	for i := 0; i < r.parityShards; i++ {
+		if len(shards[i+r.dataShards]) == 0 {
+			errLocs[i] = 1
+		}
+	}
+	for i := r.parityShards; i < m; i++ {
+		errLocs[i] = 1
+	}
+	for i := 0; i < r.dataShards; i++ {
+		if len(shards[i]) == 0 {
+			errLocs[i+m] = 1
+		}
+	}
+
+	// Evaluate error locator polynomial.
+	// The convolution gives the sum of log(point(i) + point(j)) for each error j,
+	// skipping j == i.
+	// The transform is applied twice, so divide by n = 2^k,
+	// which is a rotation since 2^32 = 1 modulo modulus32.
+	fwht32(errLocs)
+	logWalsh := r.getLogWalsh(n)
+	for i, l := range logWalsh {
+		errLocs[i] = uint32(uint64(errLocs[i]) * uint64(l) % modulus32)
+	}
+	fwht32(errLocs)
+	shift := 32 - bits.TrailingZeros(uint(n))
+	for i, l := range errLocs {
+		errLocs[i] = bits.RotateLeft32(l, shift)
+	}
+
+	st := r.states.get()
+	defer r.putState(st)
+	work, err := st.getWork(&r.o, n, shardSize)
+	if err != nil {
+		return err
+	}
+
+	// work <- recovery data
+
+	for i := 0; i < r.parityShards; i++ {
+		if len(shards[i+r.dataShards]) != 0 {
+			mul32Slice(work[i], shards[i+r.dataShards], exp32(errLocs[i]))
+		} else {
+			memclr(work[i])
+		}
+	}
+	for i := r.parityShards; i < m; i++ {
+		memclr(work[i])
+	}
+
+	// work <- original data
+
+	for i := 0; i < r.dataShards; i++ {
+		if len(shards[i]) != 0 {
+			mul32Slice(work[m+i], shards[i], exp32(errLocs[m+i]))
+		} else {
+			memclr(work[m+i])
+		}
+	}
+	for i := m + r.dataShards; i < n; i++ {
+		memclr(work[i])
+	}
+
+	// work <- IFFT(work, n, 0)
+
+	ifft32(work, 0, &r.o)
+
+	// work <- FormalDerivative(work, n)
+
+	for i := 1; i < n; i++ {
+		width := ((i ^ (i - 1)) + 1) >> 1
+		slicesXor(work[i-width:i], work[i:i+width], &r.o)
+	}
+
+	// work <- FFT(work, n, 0)
+
+	fft32(work, 0, &r.o)
+
+	// Reveal erasures
+	//
+	//  Original = -ErrLocator * FFT( Derivative( IFFT( ErrLocator * ReceivedData ) ) )
+	//
+	// mem layout: [Recovery Data (Power of Two = M)] [Original Data (K)] [Zero Padding out to N]
+	end := r.dataShards
+	if recoverAll {
+		end = r.totalShards
+	}
+	for i := 0; i < end; i++ {
+		if len(shards[i]) != 0 || required != nil && !required[i] {
+			continue
+		}
+		if cap(shards[i]) >= shardSize {
+			shards[i] = shards[i][:shardSize]
+		} else {
+			shards[i] = make([]byte, shardSize)
+		}
+		pos := i + m
+		if i >= r.dataShards {
+			// Parity shard.
+			pos = i - r.dataShards
+		}
+		mul32Slice(shards[i], work[pos], exp32(modulus32-errLocs[pos]))
+	}
+	return nil
+}
+
+// getLogWalsh returns the FWHT of the logarithms of the first n evaluation points.
+// n is the same for every call.
+func (r *leopardFF32) getLogWalsh(n int) []uint32 {
+	r.logWalshOnce.Do(func() {
+		r.logWalsh = make([]uint32, n)
+		for i := 1; i < n; i++ {
+			r.logWalsh[i] = log32(point32(i))
+		}
+		fwht32(r.logWalsh)
+	})
+	return r.logWalsh
+}
+
+// putState releases a state used by an operation.
+func (r *leopardFF32) putState(st *leopardState) {
+	st.release()
+	r.states.put(st)
+}
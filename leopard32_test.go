@@ -0,0 +1,180 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestFF32Field(t *testing.T) {
+	initConstants32()
+	for i := 1; i < len(ff32Cantor); i++ {
+		v := ff32Cantor[i]
+		if mul32(v, v)^v != ff32Cantor[i-1] {
+			t.Fatalf("basis element %d is not a Cantor basis element", i)
+		}
+	}
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		a, b, c := ffe32(rng.Uint32()|1), ffe32(rng.Uint32()), ffe32(rng.Uint32())
+		if mul32(mul32(a, b), c) != mul32(a, mul32(b, c)) {
+			t.Fatal("multiplication is not associative")
+		}
+		if mul32(a, b^c) != mul32(a, b)^mul32(a, c) {
+			t.Fatal("multiplication is not distributive")
+		}
+		if mul32(a, inv32(a)) != 1 {
+			t.Fatalf("inverse of %x is wrong", a)
+		}
+		if exp32(log32(a)) != a {
+			t.Fatalf("exp(log(%x)) != %x", a, a)
+		}
+		if b != 0 && exp32(uint32((uint64(log32(a))+uint64(log32(b)))%modulus32)) != mul32(a, b) {
+			t.Fatal("logarithm of product mismatch")
+		}
+	}
+	if log32(1) != 0 {
+		t.Fatal("log(1) != 0")
+	}
+}
+
+func TestFF32(t *testing.T) {
+	for _, test := range []struct{ data, parity int }{
+		{1, 1}, {2, 1}, {4, 2}, {10, 5}, {3, 17}, {100, 30}, {300, 64},
+	} {
+		enc, err := New(test.data, test.parity, testOptions(WithLeopardGF32(true))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := enc.(*leopardFF32); !ok {
+			t.Fatalf("got %T", enc)
+		}
+		// Larger shards use lookup tables for multiplication.
+		shards := enc.(Extensions).AllocAligned(64 << (test.parity % 5))
+		for _, shard := range shards[:test.data] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := enc.Verify(shards)
+		if err != nil || !ok {
+			t.Fatalf("%d+%d: verification failed: %v", test.data, test.parity, err)
+		}
+
+		rng := rand.New(rand.NewSource(int64(test.data*100 + test.parity)))
+		for iter := 0; iter < 10; iter++ {
+			damaged := make([][]byte, len(shards))
+			copy(damaged, shards)
+			for _, i := range rng.Perm(len(shards))[:rng.Intn(test.parity)+1] {
+				damaged[i] = nil
+			}
+			if err := enc.Reconstruct(damaged); err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(damaged[i], shards[i]) {
+					t.Fatalf("%d+%d: shard %d mismatch", test.data, test.parity, i)
+				}
+			}
+		}
+
+		if test.parity < 2 {
+			continue
+		}
+		damaged := make([][]byte, len(shards))
+		copy(damaged, shards)
+		damaged[0], damaged[len(shards)-1] = nil, nil
+		if err := enc.ReconstructData(damaged); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(damaged[0], shards[0]) || damaged[len(shards)-1] != nil {
+			t.Fatal("unexpected ReconstructData result")
+		}
+		for i := range damaged[:test.parity+1] {
+			damaged[i] = nil
+		}
+		if err := enc.Reconstruct(damaged); err != ErrTooFewShards {
+			t.Errorf("want ErrTooFewShards, got %v", err)
+		}
+	}
+}
+
+func TestFF32Wide(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	const data, parity = 70000, 1000
+	enc, err := New(data, parity, testOptions(WithLeopardGF32(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := enc.(*leopardFF32); !ok {
+		t.Fatalf("got %T", enc)
+	}
+	shards := enc.(Extensions).AllocAligned(64)
+	for _, shard := range shards[:data] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	damaged := make([][]byte, len(shards))
+	copy(damaged, shards)
+	rng := rand.New(rand.NewSource(0))
+	for _, i := range rng.Perm(len(shards))[:parity] {
+		damaged[i] = nil
+	}
+	if err := enc.Reconstruct(damaged); err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if !bytes.Equal(damaged[i], shards[i]) {
+			t.Fatalf("shard %d mismatch", i)
+		}
+	}
+}
+
+func TestFF32Errors(t *testing.T) {
+	for _, opt := range []Option{WithConstantTime(true), WithFieldRepresentation(FieldRijndael)} {
+		if _, err := New(70000, 10, opt, WithLeopardGF32(true)); err != ErrNotSupported {
+			t.Errorf("want ErrNotSupported, got %v", err)
+		}
+	}
+	if _, err := New(leopard32MaxShards, 1, WithLeopardGF32(true)); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum, got %v", err)
+	}
+	if _, err := New(70000, 10); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum without WithLeopardGF32, got %v", err)
+	}
+
+	enc, err := New(10, 4, WithLeopardGF32(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(65)
+	if err := enc.Encode(shards); err != ErrInvalidShardSize {
+		t.Errorf("want ErrInvalidShardSize, got %v", err)
+	}
+
+	data := make([]byte, 1000)
+	fillRandom(data)
+	shards, err = enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	shards[1] = nil
+	if err := enc.ReconstructData(shards); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, shards, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("join mismatch")
+	}
+}
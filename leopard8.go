@@ -24,20 +24,43 @@ type leopardFF8 struct {
 	parityShards int // Number of parity shards, should not be modified.
 	totalShards  int // Total number of shards. Calculated, and should not be modified.
 
-	workPool    sync.Pool
-	inversion   map[[inversion8Bytes]byte]leopardGF8cache
-	inversionMu sync.Mutex
+	states    freeList[leopard8State] // Scratch state for operations.
+	inversion *leopardCache[leopard8Key, leopardGF8cache]
 
 	o options
 }
 
 const inversion8Bytes = 256 / 8
 
+// leopard8State contains the buffers of a leopardFF8 operation.
+// States are reused between operations.
+type leopard8State struct {
+	work [][]byte
+	sh   [][]byte
+	wMod [][]byte
+}
+
+// putState returns st for reuse, after dropping references to shards.
+func (r *leopardFF8) putState(st *leopard8State) {
+	for i := range st.sh {
+		st.sh[i] = nil
+	}
+	r.states.put(st)
+}
+
 type leopardGF8cache struct {
 	errorLocs [256]ffe8
 	bits      *errorBitfield8
 }
 
+// size returns the bytes used by c.
+func (c leopardGF8cache) size() int64 {
+	if c.bits != nil {
+		return int64(len(c.errorLocs) + binary.Size(c.bits))
+	}
+	return int64(len(c.errorLocs))
+}
+
 // newFF8 is like New, but for the 8-bit "leopard" implementation.
 func newFF8(dataShards, parityShards int, opt options) (*leopardFF8, error) {
 	initConstants8()
@@ -59,7 +82,10 @@ func newFF8(dataShards, parityShards int, opt options) (*leopardFF8, error) {
 	if opt.inversionCache && (r.totalShards <= 64 || opt.forcedInversionCache) {
 		// Inversion cache is relatively ineffective for big shard counts and takes up potentially lots of memory
 		// r.totalShards is not covering the space, but an estimate.
-		r.inversion = make(map[[inversion8Bytes]byte]leopardGF8cache, r.totalShards)
+		r.inversion = newLeopardCache[leopard8Key](&opt, leopardGF8cache.size)
+	}
+	if n := opt.keptStates(); n > 0 {
+		r.states.keep(n)
 	}
 	return r, nil
 }
@@ -86,6 +112,17 @@ func (r *leopardFF8) AllocAligned(each int) [][]byte {
 	return AllocAligned(r.totalShards, each)
 }
 
+func (r *leopardFF8) PureGo() bool {
+	return r.o.pureGo()
+}
+
+// Recalibrate does nothing, since Leopard encoders do not split work between goroutines.
+func (r *leopardFF8) Recalibrate(shardSize int) {}
+
+func (r *leopardFF8) CostModel() CostModel {
+	return leopardCostModel(r.dataShards, r.parityShards)
+}
+
 type ffe8 uint8
 
 const (
@@ -138,10 +175,10 @@ func (r *leopardFF8) encode(shards [][]byte) error {
 	}
 
 	m := ceilPow2(r.parityShards)
-	var work [][]byte
-	if w, ok := r.workPool.Get().([][]byte); ok {
-		work = w
-	} else {
+	st := r.states.get()
+	defer r.putState(st)
+	work := st.work
+	if work == nil {
 		work = AllocAligned(m*2, workSize8)
 	}
 	if cap(work) >= m*2 {
@@ -158,8 +195,7 @@ func (r *leopardFF8) encode(shards [][]byte) error {
 	} else {
 		work = AllocAligned(m*2, workSize8)
 	}
-
-	defer r.workPool.Put(work)
+	st.work = work
 
 	mtrunc := m
 	if r.dataShards < mtrunc {
@@ -171,10 +207,12 @@ func (r *leopardFF8) encode(shards [][]byte) error {
 	// Split large shards.
 	// More likely on lower shard count.
 	off := 0
-	sh := make([][]byte, len(shards))
+	sh := reuse(st.sh, len(shards))
+	st.sh = sh
 
 	// work slice we can modify
-	wMod := make([][]byte, len(work))
+	wMod := reuse(st.wMod, len(work))
+	st.wMod = wMod
 	copy(wMod, work)
 	for off < shardSize {
 		work := wMod
@@ -260,127 +298,113 @@ func (r *leopardFF8) encode(shards [][]byte) error {
 	return nil
 }
 
+// EncodeIdx adds the parity of a single data shard.
+// Encoding is linear, so the parity of the data shard is found by encoding
+// the set of m data shards holding it with the other shards set to zero,
+// which only needs the IFFT of that set and the final FFT.
 func (r *leopardFF8) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
-	return ErrNotSupported
-}
-
-func (r *leopardFF8) Join(dst io.Writer, shards [][]byte, outSize int) error {
-	// Do we have enough shards?
-	if len(shards) < r.dataShards {
+	if len(parity) != r.parityShards {
 		return ErrTooFewShards
 	}
-	shards = shards[:r.dataShards]
-
-	// Do we have enough data?
-	size := 0
-	for _, shard := range shards {
-		if shard == nil {
-			return ErrReconstructRequired
-		}
-		size += len(shard)
-
-		// Do we have enough data already?
-		if size >= outSize {
-			break
-		}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
 	}
-	if size < outSize {
-		return ErrShortData
+	if err := checkShards(parity, false); err != nil {
+		return err
 	}
-
-	// Copy data to dst
-	write := outSize
-	for _, shard := range shards {
-		if write < len(shard) {
-			_, err := dst.Write(shard[:write])
-			return err
-		}
-		n, err := dst.Write(shard)
-		if err != nil {
-			return err
-		}
-		write -= n
+	shardSize := len(parity[0])
+	if len(dataShard) != shardSize {
+		return ErrShardSize
 	}
-	return nil
-}
-
-func (r *leopardFF8) Update(shards [][]byte, newDatashards [][]byte) error {
-	return ErrNotSupported
-}
-
-func (r *leopardFF8) Split(data []byte) ([][]byte, error) {
-	if len(data) == 0 {
-		return nil, ErrShortData
+	if shardSize%64 != 0 {
+		return ErrInvalidShardSize
 	}
-	if r.totalShards == 1 && len(data)&63 == 0 {
-		return [][]byte{data}, nil
+
+	m := ceilPow2(r.parityShards)
+	st := r.states.get()
+	defer r.putState(st)
+	work := reuse(st.work, m+1)
+	for i := range work {
+		if cap(work[i]) < workSize8 {
+			work[i] = AllocAligned(1, workSize8)[0]
+		} else {
+			work[i] = work[i][:workSize8]
+		}
 	}
+	st.work = work
 
-	dataLen := len(data)
-	// Calculate number of bytes per data shard.
-	perShard := (len(data) + r.dataShards - 1) / r.dataShards
-	perShard = ((perShard + 63) / 64) * 64
-	needTotal := r.totalShards * perShard
+	// The shards before idx in its set are zero.
+	// The IFFT of the set is only computed up to idx.
+	pos := idx % m
+	zero := work[m]
+	memclr(zero)
+	data := reuse(st.sh, pos+1)
+	st.sh = data
+	w := reuse(st.wMod, m)
+	st.wMod = w
 
-	if cap(data) > len(data) {
-		if cap(data) > needTotal {
-			data = data[:needTotal]
-		} else {
-			data = data[:cap(data)]
+	// Split large shards.
+	for off := 0; off < shardSize; off += workSize8 {
+		end := off + workSize8
+		if end > shardSize {
+			end = shardSize
 		}
-		clear := data[dataLen:]
-		for i := range clear {
-			clear[i] = 0
+		for i := range w {
+			w[i] = work[i][:end-off]
 		}
-	}
+		for i := range data[:pos] {
+			data[i] = zero[:end-off]
+		}
+		data[pos] = dataShard[off:end]
 
-	// Only allocate memory if necessary
-	var padding [][]byte
-	if len(data) < needTotal {
-		// calculate maximum number of full shards in `data` slice
-		fullShards := len(data) / perShard
-		padding = AllocAligned(r.totalShards-fullShards, perShard)
-		if dataLen > perShard*fullShards {
-			// Copy partial shards
-			copyFrom := data[perShard*fullShards : dataLen]
-			for i := range padding {
-				if len(copyFrom) == 0 {
-					break
-				}
-				copyFrom = copyFrom[copy(padding[i], copyFrom):]
-			}
+		// work <- IFFT(data + idx - pos, m, m + idx - pos)
+		ifftDITEncoder8(
+			data,
+			pos+1,
+			w,
+			nil, // No xor output
+			m,
+			fftSkew8[m-1+idx-pos:],
+			&r.o,
+		)
+
+		// work <- FFT(work, m, 0)
+		fftDIT8(w, r.parityShards, m, fftSkew8[:], &r.o)
+		for i, p := range parity {
+			sliceXor(w[i], p[off:end], &r.o)
 		}
 	}
+	return nil
+}
 
-	// Split into equal-length shards.
-	dst := make([][]byte, r.totalShards)
-	i := 0
-	for ; i < len(dst) && len(data) >= perShard; i++ {
-		dst[i] = data[:perShard:perShard]
-		data = data[perShard:]
-	}
+func (r *leopardFF8) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
 
-	for j := 0; i+j < len(dst); j++ {
-		dst[i+j] = padding[0]
-		padding = padding[1:]
-	}
+func (r *leopardFF8) Update(shards [][]byte, newDatashards [][]byte) error {
+	return ErrNotSupported
+}
 
-	return dst, nil
+func (r *leopardFF8) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, 64, r.o.zeroCopySplit, false)
 }
 
 func (r *leopardFF8) ReconstructSome(shards [][]byte, required []bool) error {
 	if len(required) == r.totalShards {
-		return r.reconstruct(shards, true)
+		return r.reconstruct(shards, true, required)
 	}
-	return r.reconstruct(shards, false)
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, false, required)
 }
 
 func (r *leopardFF8) Reconstruct(shards [][]byte) error {
-	return r.reconstruct(shards, true)
+	return r.reconstruct(shards, true, nil)
 }
 
 func (r *leopardFF8) ReconstructData(shards [][]byte) error {
-	return r.reconstruct(shards, false)
+	return r.reconstruct(shards, false, nil)
 }
 
 func (r *leopardFF8) Verify(shards [][]byte) (bool, error) {
@@ -411,7 +435,7 @@ func (r *leopardFF8) Verify(shards [][]byte) (bool, error) {
 	return true, nil
 }
 
-func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool) error {
+func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool, required []bool) error {
 	if len(shards) != r.totalShards {
 		return ErrTooFewShards
 	}
@@ -424,15 +448,19 @@ func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool) error {
 	// nothing to do.
 	numberPresent := 0
 	dataPresent := 0
+	missingRequired := 0
 	for i := 0; i < r.totalShards; i++ {
 		if len(shards[i]) != 0 {
 			numberPresent++
 			if i < r.dataShards {
 				dataPresent++
 			}
+		} else if required != nil && i < len(required) && required[i] {
+			missingRequired++
 		}
 	}
-	if numberPresent == r.totalShards || !recoverAll && dataPresent == r.dataShards {
+	if numberPresent == r.totalShards || !recoverAll && dataPresent == r.dataShards ||
+		required != nil && missingRequired == 0 {
 		// Cool. All of the shards have data. We don't
 		// need to do anything.
 		return nil
@@ -484,11 +512,10 @@ func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool) error {
 	}
 
 	var gotInversion bool
+	var key leopard8Key
 	if LEO_ERROR_BITFIELD_OPT && r.inversion != nil {
-		cacheID := errorBits.cacheID()
-		r.inversionMu.Lock()
-		if inv, ok := r.inversion[cacheID]; ok {
-			r.inversionMu.Unlock()
+		key = cacheKey8(&errLocs, recoverAll)
+		if inv, ok := r.inversion.get(key); ok {
 			errLocs = inv.errorLocs
 			if inv.bits != nil && useBits {
 				errorBits = *inv.bits
@@ -497,8 +524,6 @@ func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool) error {
 				useBits = false
 			}
 			gotInversion = true
-		} else {
-			r.inversionMu.Unlock()
 		}
 	}
 
@@ -527,16 +552,13 @@ func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool) error {
 				x = errorBits
 				c.bits = &x
 			}
-			r.inversionMu.Lock()
-			r.inversion[errorBits.cacheID()] = c
-			r.inversionMu.Unlock()
+			r.inversion.put(key, c)
 		}
 	}
 
-	var work [][]byte
-	if w, ok := r.workPool.Get().([][]byte); ok {
-		work = w
-	}
+	st := r.states.get()
+	defer r.putState(st)
+	work := st.work
 	if cap(work) >= n {
 		work = work[:n]
 		for i := range work {
@@ -554,19 +576,20 @@ func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool) error {
 			work[i] = all[i*workSize8 : i*workSize8+workSize8]
 		}
 	}
-	defer r.workPool.Put(work)
+	st.work = work
 
 	// work <- recovery data
 
 	// Split large shards.
 	// More likely on lower shard count.
-	sh := make([][]byte, len(shards))
+	sh := reuse(st.sh, len(shards))
+	st.sh = sh
 	// Copy...
 	copy(sh, shards)
 
 	// Add output
 	for i, sh := range shards {
-		if !recoverAll && i >= r.dataShards {
+		if !recoverAll && i >= r.dataShards || required != nil && !required[i] {
 			continue
 		}
 		if len(sh) == 0 {
@@ -657,7 +680,7 @@ func (r *leopardFF8) reconstruct(shards [][]byte, recoverAll bool) error {
 		}
 		// Restore
 		for i := 0; i < end; i++ {
-			if len(sh[i]) != 0 {
+			if len(sh[i]) != 0 || required != nil && !required[i] {
 				continue
 			}
 
@@ -1161,13 +1184,22 @@ func (e *errorBitfield8) set(i int) {
 	e.Words[0][(i/64)&3] |= uint64(1) << (i & 63)
 }
 
-func (e *errorBitfield8) cacheID() [inversion8Bytes]byte {
-	var res [inversion8Bytes]byte
-	binary.LittleEndian.PutUint64(res[0:8], e.Words[0][0])
-	binary.LittleEndian.PutUint64(res[8:16], e.Words[0][1])
-	binary.LittleEndian.PutUint64(res[16:24], e.Words[0][2])
-	binary.LittleEndian.PutUint64(res[24:32], e.Words[0][3])
-	return res
+// leopard8Key identifies an erasure pattern in the inversion cache of leopardFF8.
+type leopard8Key struct {
+	erased     [inversion8Bytes]byte // Bitmap of the positions with errLocs set.
+	recoverAll bool                  // Parity positions are set in the error bits.
+}
+
+// cacheKey8 returns the inversion cache key of the error locations
+// errLocs, before the error locator is evaluated.
+func cacheKey8(errLocs *[order8]ffe8, recoverAll bool) leopard8Key {
+	k := leopard8Key{recoverAll: recoverAll}
+	for i, v := range errLocs {
+		if v != 0 {
+			k.erased[i/8] |= 1 << (i % 8)
+		}
+	}
+	return k
 }
 
 func (e *errorBitfield8) isNeeded(mipLevel, bit int) bool {
@@ -2,6 +2,7 @@ package reedsolomon
 
 import (
 	"bytes"
+	"math/rand"
 	"testing"
 )
 
@@ -163,3 +164,51 @@ func TestSplitJoinLeo(t *testing.T) {
 		t.Errorf("expected %v, got %v", ErrReconstructRequired, err)
 	}
 }
+
+func TestEncodeIdxGF(t *testing.T) {
+	for _, test := range []struct {
+		name                     string
+		opt                      Option
+		dataShards, parityShards int
+	}{
+		{name: "gf8", opt: WithLeopardGF(true), dataShards: 10, parityShards: 4},
+		{name: "gf8-wide", opt: WithLeopardGF(true), dataShards: 50, parityShards: 20},
+		{name: "gf16", opt: WithLeopardGF16(true), dataShards: 10, parityShards: 4},
+		{name: "gf16-wide", opt: WithLeopardGF16(true), dataShards: 300, parityShards: 50},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := New(test.dataShards, test.parityShards, testOptions(test.opt)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := 40000
+			if test.dataShards > 100 {
+				size = 640
+			}
+			shards := enc.(Extensions).AllocAligned(size)
+			for _, shard := range shards[:test.dataShards] {
+				fillRandom(shard)
+			}
+			if err := enc.Encode(shards); err != nil {
+				t.Fatal(err)
+			}
+			parity := AllocAligned(test.parityShards, size)
+			for _, idx := range rand.Perm(test.dataShards) {
+				if err := enc.EncodeIdx(shards[idx], idx, parity); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, p := range parity {
+				if !bytes.Equal(p, shards[test.dataShards+i]) {
+					t.Fatalf("parity shard %d differs", i)
+				}
+			}
+			if err := enc.EncodeIdx(shards[0], test.dataShards, parity); err != ErrInvShardNum {
+				t.Errorf("got %v, want %v", err, ErrInvShardNum)
+			}
+			if err := enc.EncodeIdx(shards[0][:64], 0, parity); err != ErrShardSize {
+				t.Errorf("got %v, want %v", err, ErrShardSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package reedsolomon
+
+import "sync"
+
+// leopardCache caches the decoding setup of Leopard encoders by erasure pattern,
+// like the inversion tree of Reed-Solomon encoders.
+// When a limit is exceeded, the least recently used values are removed.
+// leopardCache is safe for concurrent use.
+type leopardCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*leopardCacheEntry[V]
+	size    func(V) int64 // Returns the bytes used by a value.
+
+	// Limits of the cached values, or 0 for no limit.
+	maxEntries int
+	maxBytes   int64
+
+	bytes                   int64
+	clock                   uint64
+	hits, misses, evictions uint64
+}
+
+type leopardCacheEntry[V any] struct {
+	v       V
+	lastUse uint64
+}
+
+// newLeopardCache returns a cache with the limits given by o.
+func newLeopardCache[K comparable, V any](o *options, size func(V) int64) *leopardCache[K, V] {
+	return &leopardCache[K, V]{
+		entries:    make(map[K]*leopardCacheEntry[V]),
+		size:       size,
+		maxEntries: o.inversionMaxEntries,
+		maxBytes:   o.inversionMaxBytes,
+	}
+}
+
+// get returns the value cached for k.
+func (c *leopardCache[K, V]) get(k K) (v V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		c.misses++
+		return v, false
+	}
+	c.hits++
+	c.clock++
+	e.lastUse = c.clock
+	return e.v, true
+}
+
+// put caches v for k, and removes the least recently used values
+// while a limit is exceeded.
+func (c *leopardCache[K, V]) put(k K, v V) {
+	size := c.size(v)
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock++
+	if e, ok := c.entries[k]; ok {
+		// Inserted concurrently.
+		e.lastUse = c.clock
+		return
+	}
+	c.entries[k] = &leopardCacheEntry[V]{v: v, lastUse: c.clock}
+	c.bytes += size
+	for c.maxEntries > 0 && len(c.entries) > c.maxEntries || c.maxBytes > 0 && c.bytes > c.maxBytes {
+		c.evictLRU(k)
+	}
+}
+
+// evictLRU removes the least recently used value other than the one for keep.
+// c.mu must be held.
+func (c *leopardCache[K, V]) evictLRU(keep K) {
+	var (
+		lruKey K
+		lru    *leopardCacheEntry[V]
+	)
+	for k, e := range c.entries {
+		if k != keep && (lru == nil || e.lastUse < lru.lastUse) {
+			lruKey, lru = k, e
+		}
+	}
+	if lru == nil {
+		return
+	}
+	delete(c.entries, lruKey)
+	c.bytes -= c.size(lru.v)
+	c.evictions++
+}
+
+// stats returns statistics of the cache.
+func (c *leopardCache[K, V]) stats() InversionCacheStats {
+	if c == nil {
+		return InversionCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return InversionCacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Entries:   len(c.entries),
+		Bytes:     c.bytes,
+	}
+}
@@ -0,0 +1,112 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLeopardDecodeCache(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opt  Option
+	}{
+		{name: "gf8", opt: WithLeopardGF(true)},
+		{name: "gf16", opt: WithLeopardGF16(true)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := New(10, 4, testOptions(test.opt, WithInversionCacheLimit(2, 0))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, missing := range [][]int{{1}, {1}, {2, 11}, {1}, {3}, {2, 11}, {1}} {
+				shards := enc.(Extensions).AllocAligned(64 << 10)
+				for _, shard := range shards[:10] {
+					fillRandom(shard)
+				}
+				if err := enc.Encode(shards); err != nil {
+					t.Fatal(err)
+				}
+				want := make([][]byte, len(shards))
+				for _, i := range missing {
+					want[i] = shards[i]
+					shards[i] = nil
+				}
+				if err := enc.Reconstruct(shards); err != nil {
+					t.Fatal(err)
+				}
+				for _, i := range missing {
+					if !bytes.Equal(shards[i], want[i]) {
+						t.Fatalf("missing %v: shard %d differs", missing, i)
+					}
+				}
+			}
+			got, err := InversionCacheStatsOf(enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// {3} evicts {2, 11}, which then evicts {1}, which then evicts {3}.
+			if got.Hits != 2 || got.Misses != 5 || got.Evictions != 3 || got.Entries != 2 || got.Bytes <= 0 {
+				t.Errorf("got %+v", got)
+			}
+		})
+	}
+}
+
+func BenchmarkLeopardDecodeCache(b *testing.B) {
+	for _, cache := range []bool{false, true} {
+		name := "nocache"
+		if cache {
+			name = "cache"
+		}
+		b.Run(name, func(b *testing.B) {
+			enc, err := New(10, 4, WithLeopardGF16(true), WithInversionCache(cache))
+			if err != nil {
+				b.Fatal(err)
+			}
+			shards := enc.(Extensions).AllocAligned(1 << 10)
+			for _, shard := range shards[:10] {
+				fillRandom(shard)
+			}
+			if err := enc.Encode(shards); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(10 << 10)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				shards[2] = shards[2][:0]
+				if err := enc.Reconstruct(shards); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLeopardDecodeCacheParity(t *testing.T) {
+	// Patterns only differing in missing parity shards must not share locators.
+	for _, opt := range []Option{WithLeopardGF(true), WithLeopardGF16(true)} {
+		enc, err := New(10, 4, testOptions(opt, WithInversionCache(true))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, missing := range [][]int{{1, 11}, {1}, {1, 11}} {
+			shards := enc.(Extensions).AllocAligned(64 << 10)
+			for _, shard := range shards[:10] {
+				fillRandom(shard)
+			}
+			if err := enc.Encode(shards); err != nil {
+				t.Fatal(err)
+			}
+			want := shards[1]
+			for _, i := range missing {
+				shards[i] = nil
+			}
+			if err := enc.ReconstructData(shards); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(shards[1], want) {
+				t.Fatalf("missing %v: shard 1 differs", missing)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package reedsolomon
+
+import (
+	"unsafe"
+
+	"github.com/xyz78055368/reedsolomon/internal/leopardhook"
+)
+
+// init makes the GF(2^16) transforms available to the leopard package.
+func init() {
+	o := &defaultOptions
+	leopardhook.FFT = func(work [][]byte, mtrunc, m, offset int) {
+		initConstants()
+		fftDIT(work, mtrunc, m, fftSkew[offset:], o)
+	}
+	leopardhook.IFFT = func(work [][]byte, mtrunc, m, offset int) {
+		initConstants()
+		ifftDITDecoder(mtrunc, work, m, fftSkew[offset:], o)
+	}
+	leopardhook.FWHT = func(data *[order]uint16, mtrunc int) {
+		// ffe is uint16, so the arrays have the same layout.
+		fwht((*[order]ffe)(unsafe.Pointer(data)), mtrunc)
+	}
+	leopardhook.Mul = func(dst, src []byte, logM uint16) {
+		initConstants()
+		mulgf16(dst, src, ffe(logM), o)
+	}
+	leopardhook.Xor = func(dst, src []byte) {
+		sliceXor(src, dst, o)
+	}
+	leopardhook.Log = func(x uint16) uint16 {
+		initConstants()
+		return uint16(logLUT[x])
+	}
+	leopardhook.Exp = func(logX uint16) uint16 {
+		initConstants()
+		return uint16(expLUT[logX])
+	}
+}
@@ -0,0 +1,62 @@
+package reedsolomon
+
+import (
+	"context"
+	"sync"
+)
+
+// memoryLimiter limits the number of bytes of stream buffers in use.
+// It is shared by all copies of the options it was created with.
+type memoryLimiter struct {
+	mu      sync.Mutex
+	limit   int64
+	used    int64
+	changed chan struct{} // Closed when memory is released.
+}
+
+func newMemoryLimiter(limit int64) *memoryLimiter {
+	return &memoryLimiter{limit: limit, changed: make(chan struct{})}
+}
+
+// acquire blocks until n bytes are available, or until ctx is canceled.
+// If n is larger than the limit, it waits until no memory is in use,
+// so a single large request can always proceed.
+// Calling acquire on a nil limiter returns immediately.
+func (l *memoryLimiter) acquire(ctx context.Context, n int64) error {
+	if l == nil {
+		return nil
+	}
+	if n > l.limit {
+		n = l.limit
+	}
+	for {
+		l.mu.Lock()
+		if l.used+n <= l.limit {
+			l.used += n
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return streamCanceled(ctx)
+		}
+	}
+}
+
+// release returns n bytes passed to acquire, and wakes up waiting callers.
+func (l *memoryLimiter) release(n int64) {
+	if l == nil {
+		return
+	}
+	if n > l.limit {
+		n = l.limit
+	}
+	l.mu.Lock()
+	l.used -= n
+	close(l.changed)
+	l.changed = make(chan struct{})
+	l.mu.Unlock()
+}
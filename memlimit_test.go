@@ -0,0 +1,115 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// peakReader records the memory in use by a limiter while reading.
+type peakReader struct {
+	io.Reader
+	l    *memoryLimiter
+	peak *int64
+	mu   *sync.Mutex
+}
+
+func (p peakReader) Read(b []byte) (int, error) {
+	p.l.mu.Lock()
+	used := p.l.used
+	p.l.mu.Unlock()
+	p.mu.Lock()
+	if used > *p.peak {
+		*p.peak = used
+	}
+	p.mu.Unlock()
+	return p.Reader.Read(b)
+}
+
+func TestStreamMemoryLimit(t *testing.T) {
+	const limit = 2 * 13 * 4096
+	enc, err := NewStream(10, 3, testOptions(WithStreamBlockSize(4096), WithStreamMemoryLimit(limit))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := enc.(*rsStream)
+	shards := randomBytes(10, 50000)
+	want := emptyBuffers(3)
+	if err := enc.Encode(toReaders(toBuffers(shards)), toWriters(want)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run more operations than fit in the limit.
+	var peak int64
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := make([]io.Reader, 10)
+			for i := range data {
+				data[i] = peakReader{Reader: bytes.NewReader(shards[i]), l: r.o.memLimit, peak: &peak, mu: &mu}
+			}
+			parity := emptyBuffers(3)
+			if err := enc.Encode(data, toWriters(parity)); err != nil {
+				errs <- err
+				return
+			}
+			for i := range parity {
+				if !bytes.Equal(parity[i].Bytes(), want[i].Bytes()) {
+					errs <- errors.New("parity mismatch")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if peak == 0 || peak > limit {
+		t.Fatalf("peak memory %d, limit %d", peak, limit)
+	}
+	if r.o.memLimit.used != 0 {
+		t.Fatalf("%d bytes not released", r.o.memLimit.used)
+	}
+
+	// Waiting for memory stops when the context is canceled.
+	if err := r.o.memLimit.acquire(context.Background(), limit); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = enc.(StreamContextEncoder).EncodeContext(ctx, toReaders(toBuffers(shards)), toWriters(emptyBuffers(3)))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+	r.o.memLimit.release(limit)
+
+	// A limit smaller than two blocks reduces the block size,
+	// and Update can still get both of its buffers.
+	enc, err = NewStream(10, 3, testOptions(WithStreamMemoryLimit(1000))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs := enc.(*rsStream).o.streamBS; bs != 64 {
+		t.Fatalf("block size %d", bs)
+	}
+	old := make([]io.Reader, 13)
+	newIn := make([]io.Reader, 10)
+	old[4] = bytes.NewReader(shards[4])
+	newIn[4] = bytes.NewReader(shards[5])
+	for i := range want {
+		old[10+i] = bytes.NewReader(want[i].Bytes())
+	}
+	if err := enc.(StreamUpdater).Update(old, newIn, toWriters(emptyBuffers(3))); err != nil {
+		t.Fatal(err)
+	}
+}
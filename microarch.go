@@ -0,0 +1,119 @@
+package reedsolomon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/cpuid/v2"
+)
+
+// microArch is a family of CPU microarchitectures where the kernels
+// or work split chosen from the CPU features alone are not the fastest.
+type microArch int
+
+const (
+	archGeneric microArch = iota
+
+	// archZen4 is AMD Zen 4, which executes 512 bit instructions as two 256 bit halves,
+	// so the 512 bit kernels process no more bytes per cycle than the 256 bit ones.
+	archZen4
+
+	// archIceLake is Intel Ice Lake, Tiger Lake and Rocket Lake, which have twice
+	// the 256 bit shuffle and GFNI throughput of 512 bit, and lower clocks
+	// while running 512 bit instructions.
+	archIceLake
+
+	// archHybrid is a CPU with cores of different speeds, like Intel Alder Lake
+	// or ARM big.LITTLE, so goroutines may run on slower cores.
+	archHybrid
+)
+
+func (a microArch) String() string {
+	switch a {
+	case archZen4:
+		return "Zen4"
+	case archIceLake:
+		return "IceLake"
+	case archHybrid:
+		return "Hybrid"
+	}
+	return "Generic"
+}
+
+// archProfile contains the adjustments for a microarchitecture family.
+type archProfile struct {
+	// prefer256 makes WithAutoTune try the 256 bit kernels before disabling GFNI,
+	// since they may be faster than the 512 bit ones.
+	prefer256 bool
+
+	// overprovision is the number of goroutines started for each thread
+	// when shards are split between goroutines, so faster cores can take more work.
+	overprovision int
+}
+
+var archProfiles = map[microArch]archProfile{
+	archGeneric: {overprovision: 2},
+	archZen4:    {prefer256: true, overprovision: 2},
+	archIceLake: {prefer256: true, overprovision: 2},
+	archHybrid:  {overprovision: 4},
+}
+
+var (
+	cpuArchOnce sync.Once
+	cpuArchVal  microArch
+)
+
+// cpuArch returns the microarchitecture family of this CPU.
+// It is detected on first use, since that may read files.
+func cpuArch() microArch {
+	cpuArchOnce.Do(func() {
+		cpuArchVal = detectMicroArch()
+	})
+	return cpuArchVal
+}
+
+// detectMicroArch returns the microarchitecture family of this CPU.
+func detectMicroArch() microArch {
+	if cpuid.CPU.Supports(cpuid.HYBRID_CPU) || hybridCapacity() {
+		return archHybrid
+	}
+	return microArchOf(cpuid.CPU.VendorID, cpuid.CPU.Family, cpuid.CPU.Model, cpuid.CPU.Supports(cpuid.AVX512F))
+}
+
+// microArchOf returns the microarchitecture family of a CPU
+// from its vendor, family and model.
+func microArchOf(vendor cpuid.Vendor, family, model int, avx512 bool) microArch {
+	switch {
+	case vendor == cpuid.AMD && family == 0x19 && avx512:
+		// Zen 3 is also family 0x19, but without AVX512.
+		return archZen4
+	case vendor == cpuid.Intel && family == 6:
+		switch model {
+		case 0x6a, 0x6c, 0x7d, 0x7e, 0x8c, 0x8d, 0xa7:
+			return archIceLake
+		}
+	}
+	return archGeneric
+}
+
+// hybridCapacity returns whether the operating system reports cores
+// with different capacities, as Linux does for ARM big.LITTLE.
+func hybridCapacity() bool {
+	files, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpu_capacity")
+	var first string
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return false
+		}
+		c := strings.TrimSpace(string(b))
+		if first == "" {
+			first = c
+		} else if c != first {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package reedsolomon
+
+import (
+	"testing"
+
+	"github.com/klauspost/cpuid/v2"
+)
+
+func TestMicroArchOf(t *testing.T) {
+	for _, test := range []struct {
+		vendor        cpuid.Vendor
+		family, model int
+		avx512        bool
+		want          microArch
+	}{
+		{vendor: cpuid.AMD, family: 0x19, model: 0x61, avx512: true, want: archZen4},
+		{vendor: cpuid.AMD, family: 0x19, model: 0x21, want: archGeneric},
+		{vendor: cpuid.AMD, family: 0x1a, model: 0x02, avx512: true, want: archGeneric},
+		{vendor: cpuid.Intel, family: 6, model: 0x6a, avx512: true, want: archIceLake},
+		{vendor: cpuid.Intel, family: 6, model: 0x8c, avx512: true, want: archIceLake},
+		{vendor: cpuid.Intel, family: 6, model: 0x8f, avx512: true, want: archGeneric},
+		{vendor: cpuid.Intel, family: 6, model: 0x55, avx512: true, want: archGeneric},
+		{vendor: cpuid.ARM, family: 0, model: 0xd0c, want: archGeneric},
+	} {
+		if got := microArchOf(test.vendor, test.family, test.model, test.avx512); got != test.want {
+			t.Errorf("%v family %#x model %#x: got %v, want %v", test.vendor, test.family, test.model, got, test.want)
+		}
+	}
+	for arch, p := range archProfiles {
+		if p.overprovision < 1 {
+			t.Errorf("%v: overprovision %d", arch, p.overprovision)
+		}
+	}
+	if _, ok := archProfiles[cpuArch()]; !ok {
+		t.Errorf("no profile for %v", cpuArch())
+	}
+	t.Log("Microarchitecture:", cpuArch())
+}
@@ -0,0 +1,325 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+)
+
+// MigrateOptions controls MigrateStripe.
+type MigrateOptions struct {
+	// DataSize is the number of data bytes in the source stripe.
+	// If 0, the complete source data shards are used.
+	DataSize int64
+
+	// BlockSize is the number of bytes of each destination shard
+	// processed at a time. It is rounded up to the shard size multiple
+	// of the destination encoder. If 0, 1MB is used.
+	BlockSize int
+
+	// Resume is the offset in the destination shards to start at.
+	// Everything before it must have been written by an earlier call,
+	// as reported to Progress.
+	Resume int64
+
+	// Progress is called after each block has been written, with the number
+	// of bytes written to each destination shard and the destination shard size.
+	Progress func(done, total int64)
+}
+
+// MigratedShardSize returns the shard size of a stripe with dataSize bytes
+// encoded by enc, as written by MigrateStripe.
+func MigratedShardSize(enc Encoder, dataSize int64) int64 {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return 0
+	}
+	k, mult := int64(ext.DataShards()), int64(ext.ShardSizeMultiple())
+	size := (dataSize + k - 1) / k
+	return (size + mult - 1) / mult * mult
+}
+
+// MigrateStripe converts a stripe encoded by from to a stripe encoded by to,
+// for example from 10+4 to 16+4 shards.
+//
+// src must contain from.TotalShards() shards of srcShardSize bytes,
+// with nil for missing shards. dst must contain to.TotalShards() writers,
+// which receive shards of MigratedShardSize(to, dataSize) bytes.
+//
+// The data is moved to the new layout block by block, reading the next block
+// while the previous one is encoded and written.
+// Data in missing source shards is reconstructed from the other source shards.
+// When the number of data shards and the shard size are unchanged, the data
+// shards are copied, and source parity shards with the same encoding rows
+// as the destination are copied instead of being encoded.
+//
+// A migration that was interrupted can be resumed by setting opts.Resume
+// to the last value reported to opts.Progress.
+// Only encoders where each block of the shards can be coded independently are
+// supported, so encoders using WithClayCode or WithPiggyback return ErrNotSupported.
+func MigrateStripe(from, to Encoder, src []io.ReaderAt, srcShardSize int64, dst []io.WriterAt, opts *MigrateOptions) error {
+	if opts == nil {
+		opts = &MigrateOptions{}
+	}
+	if !migratable(from) || !migratable(to) {
+		return ErrNotSupported
+	}
+	fromExt, toExt := from.(Extensions), to.(Extensions)
+	if len(src) != fromExt.TotalShards() || len(dst) != toExt.TotalShards() {
+		return ErrTooFewShards
+	}
+	if srcShardSize <= 0 || srcShardSize%int64(fromExt.ShardSizeMultiple()) != 0 {
+		return ErrInvalidShardSize
+	}
+	dataSize := opts.DataSize
+	if dataSize == 0 {
+		dataSize = srcShardSize * int64(fromExt.DataShards())
+	}
+	if dataSize < 0 || dataSize > srcShardSize*int64(fromExt.DataShards()) {
+		return ErrInvalidInput
+	}
+
+	m := &migrator{
+		from:     from,
+		to:       to,
+		src:      src,
+		srcSize:  srcShardSize,
+		dataSize: dataSize,
+		size:     MigratedShardSize(to, dataSize),
+	}
+	mult := toExt.ShardSizeMultiple()
+	if opts.Resume < 0 || opts.Resume > m.size || opts.Resume%int64(mult) != 0 {
+		return ErrInvalidInput
+	}
+	block := opts.BlockSize
+	if block <= 0 {
+		block = 1 << 20
+	}
+	block = (block + mult - 1) / mult * mult
+	if int64(block) > m.size {
+		block = int(m.size)
+	}
+
+	// Source parity can be reused when the data layout is unchanged.
+	rsFrom, ok1 := from.(*reedSolomon)
+	rsTo, ok2 := to.(*reedSolomon)
+	if ok1 && ok2 && rsFrom.dataShards == rsTo.dataShards && m.size == srcShardSize {
+		m.raw = true
+		for i := 0; i < rsTo.parityShards && i < rsFrom.parityShards; i++ {
+			if src[rsFrom.dataShards+i] != nil && bytes.Equal(rsFrom.parity[i], rsTo.parity[i]) {
+				m.reuse = append(m.reuse, i)
+			}
+		}
+	}
+	return m.run(dst, opts.Resume, block, opts.Progress)
+}
+
+// migratable returns whether blocks of shards encoded by enc can be coded independently.
+func migratable(enc Encoder) bool {
+	switch enc.(type) {
+	case *reedSolomon, *leopardFF8, *leopardFF16, *leopardFF32, *par2Codec, *gf16Matrix, *gf4Codec:
+		return true
+	}
+	return false
+}
+
+// migrator contains the state of a MigrateStripe call.
+type migrator struct {
+	from, to Encoder
+	src      []io.ReaderAt
+	srcSize  int64
+	dataSize int64
+	size     int64 // Destination shard size.
+
+	// raw is set when destination data shards are identical to source data shards.
+	raw bool
+	// reuse contains the parity shards that are copied from the source.
+	reuse []int
+}
+
+// migrateBlock is a block of destination shards.
+type migrateBlock struct {
+	off    int64
+	shards [][]byte
+	err    error
+}
+
+func (m *migrator) run(dst []io.WriterAt, start int64, block int, progress func(done, total int64)) error {
+	toExt := m.to.(Extensions)
+
+	// Read blocks while the previous block is encoded and written.
+	free := make(chan [][]byte, 2)
+	for i := 0; i < cap(free); i++ {
+		free <- toExt.AllocAligned(block)
+	}
+	blocks := make(chan *migrateBlock)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(blocks)
+		for off := start; off < m.size; off += int64(block) {
+			var buf [][]byte
+			select {
+			case buf = <-free:
+			case <-stop:
+				return
+			}
+			n := int64(block)
+			if off+n > m.size {
+				n = m.size - off
+			}
+			b := &migrateBlock{off: off, shards: trimShards(buf, int(n))}
+			b.err = m.read(b)
+			select {
+			case blocks <- b:
+			case <-stop:
+				return
+			}
+			if b.err != nil {
+				return
+			}
+		}
+	}()
+
+	for b := range blocks {
+		if b.err != nil {
+			return b.err
+		}
+		if err := m.encode(b.shards); err != nil {
+			return err
+		}
+		for i, w := range dst {
+			if _, err := w.WriteAt(b.shards[i], b.off); err != nil {
+				return StreamWriteError{Err: err, Stream: i}
+			}
+		}
+		done := b.off + int64(len(b.shards[0]))
+		if progress != nil {
+			progress(done, m.size)
+		}
+		for i := range b.shards {
+			b.shards[i] = b.shards[i][:cap(b.shards[i])]
+		}
+		free <- b.shards
+	}
+	return nil
+}
+
+// read fills the data shards of b, and any reused parity shards.
+func (m *migrator) read(b *migrateBlock) error {
+	k := m.to.(Extensions).DataShards()
+	n := int64(len(b.shards[0]))
+	for j, sh := range b.shards[:k] {
+		pos := int64(j)*m.size + b.off
+		if m.raw {
+			if err := m.readShard(sh, j, b.off); err != nil {
+				return err
+			}
+			continue
+		}
+		// Data past the end is padded with zeros.
+		end := pos + n
+		if end > m.dataSize {
+			end = m.dataSize
+		}
+		if pos >= end {
+			memclr(sh)
+			continue
+		}
+		if err := m.readData(sh[:end-pos], pos); err != nil {
+			return err
+		}
+		memclr(sh[end-pos:])
+	}
+	fromK := m.from.(Extensions).DataShards()
+	for _, i := range m.reuse {
+		if err := readAtFull(m.src[fromK+i], b.shards[k+i], b.off, fromK+i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readData reads the source data at pos, as if all data shards were concatenated.
+func (m *migrator) readData(p []byte, pos int64) error {
+	for len(p) > 0 {
+		shard, off := int(pos/m.srcSize), pos%m.srcSize
+		n := int64(len(p))
+		if n > m.srcSize-off {
+			n = m.srcSize - off
+		}
+		if err := m.readShard(p[:n], shard, off); err != nil {
+			return err
+		}
+		p = p[n:]
+		pos += n
+	}
+	return nil
+}
+
+// readShard reads len(p) bytes at offset off of source shard i,
+// reconstructing it if it is missing.
+func (m *migrator) readShard(p []byte, i int, off int64) error {
+	if m.src[i] != nil {
+		return readAtFull(m.src[i], p, off, i)
+	}
+
+	// Reconstruct the surrounding aligned range.
+	ext := m.from.(Extensions)
+	mult := int64(ext.ShardSizeMultiple())
+	start := off / mult * mult
+	end := (off + int64(len(p)) + mult - 1) / mult * mult
+	if end > m.srcSize {
+		end = m.srcSize
+	}
+	shards := make([][]byte, ext.TotalShards())
+	present := 0
+	for j, r := range m.src {
+		if r == nil || present == ext.DataShards() {
+			continue
+		}
+		shards[j] = make([]byte, end-start)
+		if err := readAtFull(r, shards[j], start, j); err != nil {
+			return err
+		}
+		present++
+	}
+	required := make([]bool, len(shards))
+	required[i] = true
+	if err := m.from.ReconstructSome(shards, required); err != nil {
+		return err
+	}
+	copy(p, shards[i][off-start:])
+	return nil
+}
+
+// readAtFull reads len(p) bytes from r at off.
+func readAtFull(r io.ReaderAt, p []byte, off int64, stream int) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return StreamReadError{Err: err, Stream: stream}
+}
+
+// encode calculates the destination parity shards that are not reused.
+func (m *migrator) encode(shards [][]byte) error {
+	if len(m.reuse) == 0 {
+		return m.to.Encode(shards)
+	}
+	r := m.to.(*reedSolomon)
+	var rows, outputs [][]byte
+	reused := 0
+	for i := 0; i < r.parityShards; i++ {
+		if reused < len(m.reuse) && m.reuse[reused] == i {
+			reused++
+			continue
+		}
+		rows = append(rows, r.parity[i])
+		outputs = append(outputs, shards[r.dataShards+i])
+	}
+	r.codeSomeShards(rows, shards[:r.dataShards], outputs, len(shards[0]))
+	return nil
+}
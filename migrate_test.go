@@ -0,0 +1,201 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// migrateSource encodes data with from and returns the shards as readers.
+func migrateSource(t *testing.T, from Encoder, data []byte) ([][]byte, []io.ReaderAt) {
+	t.Helper()
+	shards, err := from.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := from.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	src := make([]io.ReaderAt, len(shards))
+	for i, sh := range shards {
+		src[i] = bytes.NewReader(sh)
+	}
+	return shards, src
+}
+
+// migrateCheck checks that the shards written to dst are a valid stripe for to with data.
+func migrateCheck(t *testing.T, to Encoder, dst []io.WriterAt, data []byte) [][]byte {
+	t.Helper()
+	shards := make([][]byte, len(dst))
+	for i, w := range dst {
+		shards[i] = w.(*sparseWriter).data
+	}
+	ok, err := to.Verify(shards)
+	if err != nil || !ok {
+		t.Fatalf("migrated stripe does not verify: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := to.Join(&buf, shards, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("migrated data mismatch")
+	}
+	return shards
+}
+
+func migrateDest(to Encoder, dataSize int) []io.WriterAt {
+	size := MigratedShardSize(to, int64(dataSize))
+	dst := make([]io.WriterAt, to.(Extensions).TotalShards())
+	for i := range dst {
+		dst[i] = &sparseWriter{data: make([]byte, size)}
+	}
+	return dst
+}
+
+func TestMigrateStripe(t *testing.T) {
+	from, err := New(10, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := New(16, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10007)
+	fillRandom(data)
+	shards, src := migrateSource(t, from, data)
+
+	// Lose a data shard and a parity shard.
+	src[3], src[11] = nil, nil
+	dst := migrateDest(to, len(data))
+	var progress []int64
+	opts := &MigrateOptions{
+		DataSize:  int64(len(data)),
+		BlockSize: 100,
+		Progress:  func(done, total int64) { progress = append(progress, done) },
+	}
+	if err := MigrateStripe(from, to, src, int64(len(shards[0])), dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	migrateCheck(t, to, dst, data)
+	if len(progress) != 7 || progress[6] != MigratedShardSize(to, int64(len(data))) {
+		t.Fatalf("unexpected progress: %v", progress)
+	}
+
+	// Leopard to regular.
+	from, err = New(300, 10, testOptions(WithLeopardGF16(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards, src = migrateSource(t, from, data)
+	src[0], src[1] = nil, nil
+	dst = migrateDest(to, len(data))
+	if err := MigrateStripe(from, to, src, int64(len(shards[0])), dst, &MigrateOptions{DataSize: int64(len(data))}); err != nil {
+		t.Fatal(err)
+	}
+	migrateCheck(t, to, dst, data)
+}
+
+// failingWriter fails after a number of writes.
+type failingWriter struct {
+	sparseWriter
+	left *int
+}
+
+func (f *failingWriter) WriteAt(p []byte, off int64) (int, error) {
+	if *f.left == 0 {
+		return 0, errors.New("write failed")
+	}
+	*f.left--
+	return f.sparseWriter.WriteAt(p, off)
+}
+
+func TestMigrateStripeResume(t *testing.T) {
+	from, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := New(6, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5000)
+	fillRandom(data)
+	shards, src := migrateSource(t, from, data)
+
+	// Fail during the fourth block.
+	size := MigratedShardSize(to, int64(len(data)))
+	left := 3*9 + 5
+	dst := make([]io.WriterAt, 9)
+	for i := range dst {
+		dst[i] = &failingWriter{sparseWriter: sparseWriter{data: make([]byte, size)}, left: &left}
+	}
+	var resume int64
+	opts := &MigrateOptions{BlockSize: 64, Progress: func(done, total int64) { resume = done }}
+	err = MigrateStripe(from, to, src, int64(len(shards[0])), dst, opts)
+	var werr StreamWriteError
+	if !errors.As(err, &werr) || werr.Stream != 5 {
+		t.Fatalf("want StreamWriteError on stream 5, got %v", err)
+	}
+	if resume != 3*64 {
+		t.Fatalf("resume at %d", resume)
+	}
+
+	left = -1
+	opts.Resume = resume
+	if err := MigrateStripe(from, to, src, int64(len(shards[0])), dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	for i := range dst {
+		dst[i] = &dst[i].(*failingWriter).sparseWriter
+	}
+	// The source padding is included in the data.
+	full := bytes.Join(shards[:4], nil)
+	migrateCheck(t, to, dst, full)
+}
+
+// countingReader counts read bytes.
+type countingReader struct {
+	*bytes.Reader
+	n int
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	c.n += len(p)
+	return c.Reader.ReadAt(p, off)
+}
+
+func TestMigrateStripeReuseParity(t *testing.T) {
+	from, err := New(6, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := New(6, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 6000)
+	fillRandom(data)
+	shards, src := migrateSource(t, from, data)
+	parity := &countingReader{Reader: bytes.NewReader(shards[6])}
+	src[6] = parity
+	dst := migrateDest(to, len(data))
+	if err := MigrateStripe(from, to, src, int64(len(shards[0])), dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := migrateCheck(t, to, dst, data)
+	if parity.n != len(shards[6]) || !bytes.Equal(got[6], shards[6]) || !bytes.Equal(got[7], shards[7]) {
+		t.Fatalf("parity was not reused, read %d bytes", parity.n)
+	}
+
+	// Not supported with sub-chunked codes.
+	clay, err := New(6, 2, WithClayCode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateStripe(clay, to, src, int64(len(shards[0])), dst, nil); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package reedsolomon
+
+import (
+	"sync"
+)
+
+// numaPageSize is the granularity at which memory is placed on NUMA nodes.
+const numaPageSize = 4096
+
+var (
+	numaOnce  sync.Once
+	numaCache [][]int
+)
+
+// systemNUMANodes returns the CPUs of each NUMA node with CPUs,
+// or nil if there is only one node or it cannot be determined.
+func systemNUMANodes() [][]int {
+	numaOnce.Do(func() {
+		if nodes := numaNodeCPUs(); len(nodes) > 1 {
+			numaCache = nodes
+		}
+	})
+	return numaCache
+}
+
+// numaNodes returns the NUMA nodes to spread work over,
+// or nil if WithNUMA is not enabled or there is only one node.
+func (r *reedSolomon) numaNodes() [][]int {
+	if !r.o.numa {
+		return nil
+	}
+	return systemNUMANodes()
+}
+
+// numaNodeOf returns the node of nodes processing byte pos of a shard of size bytes.
+// Shards are divided into one contiguous part for each node.
+func numaNodeOf(pos, size, nodes int) int {
+	return int(int64(pos) * int64(nodes) / int64(size))
+}
+
+// execNUMA runs exec(start, stop) on a thread pinned to the NUMA node
+// processing start of a shard of byteCount bytes, if WithNUMA is enabled.
+func (r *reedSolomon) execNUMA(exec func(start, stop int), start, stop, byteCount int) {
+	if nodes := r.numaNodes(); nodes != nil {
+		defer pinThread(nodes[numaNodeOf(start, byteCount, len(nodes))])()
+	}
+	exec(start, stop)
+}
+
+// numaTouch writes each page of shards from a thread on the node processing it,
+// so operating systems placing memory where it is first written
+// place it on that node.
+func numaTouch(shards [][]byte, nodes [][]int) {
+	if len(shards) == 0 || len(shards[0]) == 0 {
+		return
+	}
+	size := len(shards[0])
+	var wg sync.WaitGroup
+	for n, cpus := range nodes {
+		wg.Add(1)
+		go func(n int, cpus []int) {
+			defer wg.Done()
+			defer pinThread(cpus)()
+			for _, shard := range shards {
+				for pos := 0; pos < size; pos += numaPageSize {
+					if numaNodeOf(pos, size, len(nodes)) == n {
+						shard[pos] = 0
+					}
+				}
+			}
+		}(n, cpus)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,105 @@
+package reedsolomon
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// cpuMask is a Linux CPU affinity mask.
+type cpuMask [1024 / 64]uint64
+
+// numaNodeCPUs returns the CPUs of each NUMA node with CPUs, as reported by sysfs.
+func numaNodeCPUs() [][]int {
+	dirs, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	type node struct {
+		id   int
+		cpus []int
+	}
+	var nodes []node
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil
+		}
+		cpus, ok := parseCPUList(strings.TrimSpace(string(b)))
+		if !ok {
+			return nil
+		}
+		if len(cpus) > 0 {
+			nodes = append(nodes, node{id: id, cpus: cpus})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+	res := make([][]int, len(nodes))
+	for i, n := range nodes {
+		res[i] = n.cpus
+	}
+	return res
+}
+
+// parseCPUList parses a list of CPUs like "0-3,8,10-11".
+func parseCPUList(s string) ([]int, bool) {
+	var cpus []int
+	if s == "" {
+		return nil, true
+	}
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, false
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, false
+			}
+		}
+		for c := first; c <= last; c++ {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, true
+}
+
+// pinThread locks the calling goroutine to its thread,
+// and limits the thread to run on cpus.
+// The returned function restores the affinity and unlocks the thread.
+func pinThread(cpus []int) func() {
+	runtime.LockOSThread()
+	var old, mask cpuMask
+	if schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &old) != nil {
+		return runtime.UnlockOSThread
+	}
+	for _, c := range cpus {
+		if c >= 0 && c < len(mask)*64 {
+			mask[c/64] |= 1 << (c % 64)
+		}
+	}
+	if schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &mask) != nil {
+		return runtime.UnlockOSThread
+	}
+	return func() {
+		schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &old)
+		runtime.UnlockOSThread()
+	}
+}
+
+// schedAffinity gets or sets the affinity of the calling thread.
+func schedAffinity(trap uintptr, mask *cpuMask) error {
+	_, _, errno := syscall.RawSyscall(trap, 0, unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package reedsolomon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []int
+		ok   bool
+	}{
+		{in: "", ok: true},
+		{in: "0", want: []int{0}, ok: true},
+		{in: "0-3,8,10-11", want: []int{0, 1, 2, 3, 8, 10, 11}, ok: true},
+		{in: "3-1"},
+		{in: "a"},
+	} {
+		got, ok := parseCPUList(test.in)
+		if ok != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, %v, want %v, %v", test.in, got, ok, test.want, test.ok)
+		}
+	}
+}
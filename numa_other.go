@@ -0,0 +1,11 @@
+//go:build !linux
+
+package reedsolomon
+
+func numaNodeCPUs() [][]int {
+	return nil
+}
+
+func pinThread(cpus []int) func() {
+	return func() {}
+}
@@ -0,0 +1,49 @@
+package reedsolomon
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestWithNUMA(t *testing.T) {
+	// Pretend the CPUs are split over two nodes.
+	saved := systemNUMANodes()
+	numaCache = [][]int{{0}, {runtime.NumCPU() - 1}}
+	defer func() { numaCache = saved }()
+
+	const size = 1 << 20
+	enc, err := New(10, 4, testOptions(WithNUMA(true), WithMaxGoroutines(8), WithMinSplitSize(16<<10))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(size)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+	shards[2], shards[12] = nil, nil
+	if err := enc.Reconstruct(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err = enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+
+	for _, test := range []struct{ pos, size, nodes, want int }{
+		{pos: 0, size: 100, nodes: 2, want: 0},
+		{pos: 49, size: 100, nodes: 2, want: 0},
+		{pos: 50, size: 100, nodes: 2, want: 1},
+		{pos: 99, size: 100, nodes: 4, want: 3},
+	} {
+		if got := numaNodeOf(test.pos, test.size, test.nodes); got != test.want {
+			t.Errorf("numaNodeOf(%d, %d, %d): got %d, want %d", test.pos, test.size, test.nodes, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package reedsolomon
+
+import (
+	"io"
+)
+
+// objectShardSize is the maximum shard size chosen by NewObject.
+const objectShardSize = 1 << 20
+
+// Object is an object that has been split into one or more stripes,
+// each encoded separately with the same encoder.
+//
+// All stripes have the same shard size, and stripe boundaries are hidden
+// from the caller. Reads, repairs and verification operate on the object as a whole.
+type Object struct {
+	enc        Encoder
+	size       int64
+	shardSize  int
+	stripeSize int64 // Data bytes per stripe.
+	stripes    [][][]byte
+}
+
+// NewObject splits data into stripes and encodes each stripe.
+//
+// The shard size is chosen so small objects are kept in a single stripe,
+// while large objects are split into stripes with shards of up to 1MB.
+// The data will not be copied, except for the last stripe, so you
+// should not modify the data of the input slice afterwards.
+func NewObject(enc Encoder, data []byte) (*Object, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(data) == 0 {
+		return nil, ErrShortData
+	}
+	dataShards := ext.DataShards()
+	multiple := ext.ShardSizeMultiple()
+	shardSize := (len(data) + dataShards - 1) / dataShards
+	if shardSize > objectShardSize {
+		shardSize = objectShardSize
+	}
+	shardSize = (shardSize + multiple - 1) / multiple * multiple
+
+	stripeSize := shardSize * dataShards
+	o := &Object{enc: enc, size: int64(len(data)), shardSize: shardSize, stripeSize: int64(stripeSize)}
+	for len(data) > 0 {
+		var stripe []byte
+		if len(data) >= stripeSize {
+			stripe = data[:stripeSize:stripeSize]
+			data = data[stripeSize:]
+		} else {
+			// Pad the last stripe to the full size.
+			stripe = make([]byte, stripeSize)
+			copy(stripe, data)
+			data = nil
+		}
+		shards, err := enc.Split(stripe)
+		if err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(shards); err != nil {
+			return nil, err
+		}
+		o.stripes = append(o.stripes, shards)
+	}
+	return o, nil
+}
+
+// OpenObject returns an object from previously encoded stripes,
+// for instance as returned by Stripe on an object created with NewObject.
+//
+// size is the size of the object, and shardSize is the size of each shard.
+// Missing shards should be nil.
+func OpenObject(enc Encoder, size int64, shardSize int, stripes [][][]byte) (*Object, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if shardSize <= 0 || shardSize%ext.ShardSizeMultiple() != 0 {
+		return nil, ErrInvalidShardSize
+	}
+	stripeSize := int64(shardSize * ext.DataShards())
+	if size <= 0 || int64(len(stripes)) != (size+stripeSize-1)/stripeSize {
+		return nil, ErrInvalidInput
+	}
+	for _, stripe := range stripes {
+		if len(stripe) != ext.TotalShards() {
+			return nil, ErrTooFewShards
+		}
+		for _, shard := range stripe {
+			if len(shard) != 0 && len(shard) != shardSize {
+				return nil, ErrShardSize
+			}
+		}
+	}
+	return &Object{enc: enc, size: size, shardSize: shardSize, stripeSize: stripeSize, stripes: stripes}, nil
+}
+
+// Size returns the size of the object in bytes.
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ShardSize returns the size of each shard.
+func (o *Object) ShardSize() int {
+	return o.shardSize
+}
+
+// Stripes returns the number of stripes in the object.
+func (o *Object) Stripes() int {
+	return len(o.stripes)
+}
+
+// Stripe returns the shards of stripe n.
+// The returned slice is not a copy, so setting a shard to nil
+// will mark it as missing.
+func (o *Object) Stripe(n int) [][]byte {
+	return o.stripes[n]
+}
+
+// ReadAt reads len(p) bytes of the object starting at offset off.
+// It implements io.ReaderAt.
+//
+// Missing data shards are reconstructed as needed,
+// but the reconstructed shards are not stored in the object.
+// Use Repair to restore missing shards.
+func (o *Object) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidInput
+	}
+	if off >= o.size {
+		return 0, io.EOF
+	}
+	var err error
+	if rem := o.size - off; int64(len(p)) > rem {
+		p = p[:rem]
+		err = io.EOF
+	}
+	shardSize, stripeSize := int64(o.shardSize), o.stripeSize
+	n := 0
+	for len(p) > 0 {
+		stripe := o.stripes[off/stripeSize]
+		inStripe := off % stripeSize
+		var shards [][]byte
+		for len(p) > 0 && inStripe < stripeSize {
+			idx := int(inStripe / shardSize)
+			shard := stripe[idx]
+			if len(shard) == 0 {
+				if shards == nil {
+					// Reconstruct into a copy, so the object is left unchanged.
+					shards = append([][]byte{}, stripe...)
+					if rerr := o.enc.ReconstructData(shards); rerr != nil {
+						return n, rerr
+					}
+				}
+				shard = shards[idx]
+			}
+			copied := copy(p, shard[inStripe%shardSize:])
+			p = p[copied:]
+			n += copied
+			off += int64(copied)
+			inStripe += int64(copied)
+		}
+	}
+	return n, err
+}
+
+// Repair reconstructs all missing shards in all stripes.
+// If any stripe has too few shards, ErrTooFewShards is returned.
+func (o *Object) Repair() error {
+	for _, stripe := range o.stripes {
+		if err := o.enc.Reconstruct(stripe); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HealParity rewrites the inconsistent parity shards of all stripes,
+// using HealParity. A report is returned for each stripe.
+// All shards must be present.
+func (o *Object) HealParity() ([]HealReport, error) {
+	reports := make([]HealReport, len(o.stripes))
+	for i, stripe := range o.stripes {
+		var err error
+		reports[i], err = HealParity(o.enc, stripe)
+		if err != nil {
+			return reports, err
+		}
+	}
+	return reports, nil
+}
+
+// Verify returns true if the parity of all stripes is correct.
+// All shards must be present.
+func (o *Object) Verify() (bool, error) {
+	for _, stripe := range o.stripes {
+		ok, err := o.enc.Verify(stripe)
+		if !ok || err != nil {
+			return ok, err
+		}
+	}
+	return true, nil
+}
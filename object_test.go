@@ -0,0 +1,79 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestObject(t *testing.T) {
+	for _, size := range []int{1, 1000, 3<<20 + 17} {
+		enc, err := New(4, 2, testOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, size)
+		fillRandom(data)
+		want := append([]byte{}, data...)
+		obj, err := NewObject(enc, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obj.Size() != int64(size) {
+			t.Fatalf("size: got %d, want %d", obj.Size(), size)
+		}
+		if obj.ShardSize() > objectShardSize {
+			t.Fatalf("shard size %d too large", obj.ShardSize())
+		}
+		ok, err := obj.Verify()
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+
+		// Remove two shards from every stripe.
+		for i := 0; i < obj.Stripes(); i++ {
+			stripe := obj.Stripe(i)
+			stripe[i%4] = nil
+			stripe[5] = nil
+		}
+		got := make([]byte, size)
+		if _, err := obj.ReadAt(got, 0); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatal("data mismatch")
+		}
+		// Read crossing the end.
+		off := int64(size / 2)
+		n, err := obj.ReadAt(make([]byte, size+1), off)
+		if err != io.EOF {
+			t.Fatalf("expected io.EOF, got %v", err)
+		}
+		if n != size-int(off) {
+			t.Fatalf("read %d bytes, want %d", n, size-int(off))
+		}
+
+		// Reopen, repair and verify.
+		stripes := make([][][]byte, obj.Stripes())
+		for i := range stripes {
+			stripes[i] = obj.Stripe(i)
+		}
+		obj, err = OpenObject(enc, obj.Size(), obj.ShardSize(), stripes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := obj.Repair(); err != nil {
+			t.Fatal(err)
+		}
+		ok, err = obj.Verify()
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+
+		// Too many missing shards.
+		obj.Stripe(0)[0], obj.Stripe(0)[1], obj.Stripe(0)[2] = nil, nil, nil
+		if _, err := obj.ReadAt(got, 0); err != ErrTooFewShards {
+			t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+		}
+	}
+}
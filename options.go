@@ -1,6 +1,7 @@
 package reedsolomon
 
 import (
+	"hash"
 	"runtime"
 	"strings"
 
@@ -14,31 +15,75 @@ type options struct {
 	maxGoroutines int
 	minSplitSize  int
 	shardSize     int
-	perRound      int
+
+	autoRecalibrate bool
+	autoTune        bool
+	numa            bool
+
+	persistentWorkers int
+	scheduler         Scheduler
+	zeroAlloc         bool
+	scratchStates     int
+
+	correctSearchLimit int
 
 	useAvxGNFI,
 	useAvx512GFNI,
 	useAVX512,
+	useAVX10,
 	useAVX2,
 	useSSSE3,
 	useSSE2,
 	useNEON,
+	useSHA3,
 	useSVE bool
 	vectorLength int
 
 	useJerasureMatrix    bool
 	usePAR1Matrix        bool
+	usePAR2              bool
+	useGF16Matrix        bool
+	useGF4               bool
+	usePrime             bool
+	useClay              bool
+	usePiggyback         bool
+	useRDP               bool
+	classes              []PriorityClass
+	shardHash            func() hash.Hash
 	useCauchy            bool
+	useRawVandermonde    bool
+	useZfecMatrix        bool
+	useISALMatrix        bool
+	useCM256Matrix       bool
+	useCauchyGood        bool
 	fastOneParity        bool
+	constantTime         bool
+	rateLimit            *rateLimiter
+	progress             func(processed, total int64)
+	backend              Backend
+	requireAligned       bool
+	zeroCopySplit        bool
+	spillDir             string
+	spillBudget          int64
 	inversionCache       bool
+	inversionMaxEntries  int
+	inversionMaxBytes    int64
 	forcedInversionCache bool
 	customMatrix         [][]byte
+	customGenerator      [][]byte
 	withLeopard          leopardMode
+	fieldRep             FieldRepresentation
+	field                *galoisField
 
 	// stream options
-	concReads  bool
-	concWrites bool
-	streamBS   int
+	concReads      bool
+	concWrites     bool
+	concReadLimit  int
+	concWriteLimit int
+	shardConc      map[int]bool
+	streamBS       int
+	streamWorkers  int
+	memLimit       *memoryLimiter
 }
 
 var defaultOptions = options{
@@ -51,10 +96,12 @@ var defaultOptions = options{
 	useSSSE3:      cpuid.CPU.Supports(cpuid.SSSE3),
 	useSSE2:       cpuid.CPU.Supports(cpuid.SSE2),
 	useAVX2:       cpuid.CPU.Supports(cpuid.AVX2),
-	useAVX512:     cpuid.CPU.Supports(cpuid.AVX512F, cpuid.AVX512BW, cpuid.AVX512VL),
-	useAvx512GFNI: cpuid.CPU.Supports(cpuid.AVX512F, cpuid.GFNI, cpuid.AVX512DQ),
+	useAVX512:     cpuid.CPU.Supports(cpuid.AVX512F, cpuid.AVX512BW, cpuid.AVX512VL) || cpuid.CPU.Supports(cpuid.AVX10_512),
+	useAvx512GFNI: cpuid.CPU.Supports(cpuid.AVX512F, cpuid.GFNI, cpuid.AVX512DQ) || cpuid.CPU.Supports(cpuid.AVX10_512, cpuid.GFNI),
+	useAVX10:      cpuid.CPU.Supports(cpuid.AVX10),
 	useAvxGNFI:    cpuid.CPU.Supports(cpuid.AVX, cpuid.GFNI),
 	useNEON:       cpuid.CPU.Supports(cpuid.ASIMD),
+	useSHA3:       cpuid.CPU.Supports(cpuid.ASIMD, cpuid.SHA3),
 	useSVE:        cpuid.CPU.Supports(cpuid.SVE),
 	vectorLength:  32, // default vector length is 32 bytes (256 bits) for AVX2 code gen
 }
@@ -71,6 +118,8 @@ const (
 	// leopardAlways uses 8-bit leopard for shards less than or equal to 256,
 	// 16-bit leopard otherwise.
 	leopardAlways
+	// leopardGF32 uses leopard in 32-bit mode for all shard counts.
+	leopardGF32
 )
 
 func init() {
@@ -93,6 +142,61 @@ func WithMaxGoroutines(n int) Option {
 	}
 }
 
+// WithPersistentWorkers will start n goroutines when the encoder is created,
+// and use them for the work split between goroutines, instead of starting
+// goroutines for each operation.
+// This reduces scheduling overhead for services doing many small operations.
+// Operations wait for a free worker, so n should be at least the number
+// of goroutines each operation is split into, see WithMaxGoroutines.
+// The workers exit when the encoder is garbage collected.
+// If n <= 0, goroutines are started for each operation.
+// Ignored if WithScheduler is given, and by Leopard encoders.
+func WithPersistentWorkers(n int) Option {
+	return func(o *options) {
+		o.persistentWorkers = n
+	}
+}
+
+// WithScheduler will run the work split between goroutines with s,
+// for example to run it on an application worker pool with a CPU quota.
+// The calls to Submit for an operation are made by the goroutine running the operation,
+// which waits for the submitted functions to complete.
+// If Submit blocks until a worker is free, operations must not run on
+// the workers of s, since an operation could then wait for its own worker.
+// If s is nil, goroutines are started for each operation.
+// Ignored by Leopard encoders.
+func WithScheduler(s Scheduler) Option {
+	return func(o *options) {
+		o.scheduler = s
+	}
+}
+
+// WithScratchStates will keep the scratch state of n concurrent operations,
+// like the work buffers of Leopard encoders and the matrices of
+// Reed-Solomon encoders, for the lifetime of the encoder.
+// By default, states are reused through a pool, which is emptied by garbage
+// collections, so operations after a collection allocate new states.
+// Operations beyond n running concurrently use states that are not kept.
+// If n <= 0, the pool is used, unless WithZeroAlloc is given.
+func WithScratchStates(n int) Option {
+	return func(o *options) {
+		o.scratchStates = n
+	}
+}
+
+// WithZeroAlloc will make operations reuse all their scratch state,
+// so Encode and the Reconstruct functions do not allocate
+// once each shard size has been used by each concurrent operation.
+// State for GOMAXPROCS concurrent operations is kept when the encoder is created,
+// unless another number is given with WithScratchStates, and if neither WithPersistentWorkers nor WithScheduler is given,
+// persistent workers are started for the work split between goroutines.
+// Operations using WithBackend or spilling to disk may still allocate.
+func WithZeroAlloc(enabled bool) Option {
+	return func(o *options) {
+		o.zeroAlloc = enabled
+	}
+}
+
 // WithAutoGoroutines will adjust the number of goroutines for optimal speed with a
 // specific shard size.
 // Send in the shard size you expect to send. Other shard sizes will work, but may not
@@ -105,6 +209,61 @@ func WithAutoGoroutines(shardSize int) Option {
 	}
 }
 
+// WithAutoRecalibrate will recalibrate the number of goroutines when the
+// encoder is used with shard sizes that differ from the calibrated size,
+// or when GOMAXPROCS changes.
+// This allows long-lived encoders to adapt to changing workloads.
+// The shard size given with WithAutoGoroutines is used for the initial calibration.
+// See Recalibrator for recalibrating manually.
+// Ignored by Leopard encoders.
+func WithAutoRecalibrate(enabled bool) Option {
+	return func(o *options) {
+		o.autoRecalibrate = enabled
+	}
+}
+
+// WithAutoTune will time encodes of a test stripe when the encoder is created,
+// and use the kernels, number of goroutines and bytes per goroutine round
+// that are fastest on this machine, instead of choosing them from CPU features only.
+// The shard size given with WithAutoGoroutines is tuned for, or 64KB if none is given.
+// Tuning encodes at most 8MB of shards a few times for each candidate.
+// Only CPU features that are enabled are considered.
+// On AMD Zen 4 and Intel Ice Lake cores, where 512 bit instructions may be
+// slower, the 256 bit kernels are also tried with AVX512 disabled.
+// Recalibrating, for example with WithAutoRecalibrate, replaces the tuned number of goroutines.
+// Ignored by Leopard encoders.
+func WithAutoTune() Option {
+	return func(o *options) {
+		o.autoTune = true
+	}
+}
+
+// WithCorrectSearchLimit sets the maximum number of combinations of shards
+// tried for each window of shards by LocateCorruptShards and ReconstructWithErrors,
+// when the corrupt shards cannot be located by syndrome decoding.
+// If the corrupt shards are not found within the limit, ErrUncorrectable is returned.
+// The default is 4096. Values <= 0 select the default.
+func WithCorrectSearchLimit(combinations int) Option {
+	return func(o *options) {
+		o.correctSearchLimit = combinations
+	}
+}
+
+// WithNUMA will spread work over the NUMA nodes of multi-socket hosts.
+// Shards split between goroutines are divided into one contiguous part for each node,
+// and the goroutines processing a part are pinned to the CPUs of its node.
+// Shards allocated with AllocAligned of the encoder are first written
+// from the node processing each part, so they are placed in its local memory
+// on operating systems that place memory where it is first written.
+//
+// Pinning is only supported on Linux. On other platforms, or with a single node,
+// the option has no effect. Ignored by Leopard encoders.
+func WithNUMA(enabled bool) Option {
+	return func(o *options) {
+		o.numa = enabled
+	}
+}
+
 // WithMinSplitSize is the minimum encoding size in bytes per goroutine.
 // By default this parameter is determined by CPU cache characteristics.
 // See WithMaxGoroutines on how jobs are split.
@@ -123,6 +282,7 @@ func WithMinSplitSize(n int) Option {
 func WithConcurrentStreams(enabled bool) Option {
 	return func(o *options) {
 		o.concReads, o.concWrites = enabled, enabled
+		o.concReadLimit, o.concWriteLimit = 0, 0
 	}
 }
 
@@ -132,6 +292,7 @@ func WithConcurrentStreams(enabled bool) Option {
 func WithConcurrentStreamReads(enabled bool) Option {
 	return func(o *options) {
 		o.concReads = enabled
+		o.concReadLimit = 0
 	}
 }
 
@@ -141,11 +302,63 @@ func WithConcurrentStreamReads(enabled bool) Option {
 func WithConcurrentStreamWrites(enabled bool) Option {
 	return func(o *options) {
 		o.concWrites = enabled
+		o.concWriteLimit = 0
+	}
+}
+
+// WithStreamReadConcurrency will read from at most n input streams concurrently.
+// If n <= 0, all streams are read concurrently.
+// If n == 1, only one stream will be read at the time, which is the default.
+// Ignored if not used on a stream input.
+func WithStreamReadConcurrency(n int) Option {
+	return func(o *options) {
+		o.concReads = n != 1
+		o.concReadLimit = 0
+		if n > 1 {
+			o.concReadLimit = n
+		}
+	}
+}
+
+// WithStreamWriteConcurrency will write to at most n output streams concurrently.
+// If n <= 0, all streams are written concurrently.
+// If n == 1, only one stream will be written at the time, which is the default.
+// Ignored if not used on a stream input.
+//
+// The number of goroutines used for encoding each block is controlled separately
+// by WithMaxGoroutines.
+func WithStreamWriteConcurrency(n int) Option {
+	return func(o *options) {
+		o.concWrites = n != 1
+		o.concWriteLimit = 0
+		if n > 1 {
+			o.concWriteLimit = n
+		}
+	}
+}
+
+// WithStreamShardConcurrency overrides the read and write concurrency of a single shard.
+// If concurrent is true, the shard is always read and written on its own goroutine,
+// without counting towards the limits set by WithStreamReadConcurrency
+// and WithStreamWriteConcurrency.
+// This is useful for high latency shards, like remote sources.
+// If concurrent is false, the shard is read and written by the calling goroutine,
+// while other shards are processed concurrently as configured.
+// Ignored if not used on a stream input.
+func WithStreamShardConcurrency(shard int, concurrent bool) Option {
+	return func(o *options) {
+		m := make(map[int]bool, len(o.shardConc)+1)
+		for k, v := range o.shardConc {
+			m[k] = v
+		}
+		m[shard] = concurrent
+		o.shardConc = m
 	}
 }
 
 // WithInversionCache allows to control the inversion cache.
 // This will cache reconstruction matrices so they can be reused.
+// Leopard GF8 and GF16 encoders cache the error locators of erasure patterns instead.
 // Enabled by default, or <= 64 shards for Leopard encoding.
 func WithInversionCache(enabled bool) Option {
 	return func(o *options) {
@@ -154,6 +367,19 @@ func WithInversionCache(enabled bool) Option {
 	}
 }
 
+// WithInversionCacheLimit limits the inversion cache to maxEntries matrices
+// and maxBytes bytes of matrix data. When a limit is exceeded,
+// the least recently used matrices are removed.
+// A limit <= 0 means no limit, which is the default.
+// Use InversionCacheStatsOf to get the hits, misses and size of the cache.
+// Leopard GF8 and GF16 encoders apply the limits to their cache of error locators.
+func WithInversionCacheLimit(maxEntries int, maxBytes int64) Option {
+	return func(o *options) {
+		o.inversionMaxEntries = maxEntries
+		o.inversionMaxBytes = maxBytes
+	}
+}
+
 // WithStreamBlockSize allows to set a custom block size per round of reads/writes.
 // If not set, any shard size set with WithAutoGoroutines will be used.
 // If WithAutoGoroutines is also unset, 4MB will be used.
@@ -164,6 +390,36 @@ func WithStreamBlockSize(n int) Option {
 	}
 }
 
+// WithStreamWorkers sets the number of blocks that EncodeAt and ReconstructAt
+// process concurrently. Each worker uses a buffer of one block per shard.
+// If n <= 0, GOMAXPROCS workers are used, which is the default.
+// Ignored if not used on stream.
+func WithStreamWorkers(n int) Option {
+	return func(o *options) {
+		o.streamWorkers = n
+	}
+}
+
+// WithStreamMemoryLimit caps the total size of the block buffers used by
+// streams at approximately n bytes.
+// The limit applies to all operations on the stream encoder combined.
+// When the limit is reached, operations wait for buffers to be released
+// before reading more data, instead of allocating more memory.
+// Each operation uses one block per shard, and Update two,
+// so the stream block size is reduced if two blocks of all shards do not fit.
+// Buffers of operations that are canceled while concurrent reads or writes
+// are still running are counted as released when the operation returns.
+// If n <= 0, no limit is applied, which is the default.
+// Ignored if not used on stream.
+func WithStreamMemoryLimit(n int64) Option {
+	return func(o *options) {
+		o.memLimit = nil
+		if n > 0 {
+			o.memLimit = newMemoryLimiter(n)
+		}
+	}
+}
+
 // WithSSSE3 allows to enable/disable SSSE3 instructions.
 // If not set, SSSE3 will be turned on or off automatically based on CPU ID information.
 func WithSSSE3(enabled bool) Option {
@@ -192,6 +448,33 @@ func WithSSE2(enabled bool) Option {
 	}
 }
 
+// WithSHA3 allows to enable/disable the arm64 SHA3 extension.
+// When enabled, the EOR3 instruction is used to combine the XOR operations when
+// multiplying a single shard by a constant and adding it to another, as done by
+// Leopard GF8 and when no matrix kernel can be used.
+// The generated NEON and SVE kernels used for encoding and reconstruction are not affected.
+// If not set, SHA3 will be turned on or off automatically based on CPU ID information.
+func WithSHA3(enabled bool) Option {
+	return func(o *options) {
+		o.useSHA3 = enabled
+	}
+}
+
+// WithAVX10 allows to enable/disable AVX10 instructions on 256 bit vectors.
+// They are only used to combine the XOR operations when multiplying
+// a single shard by a constant and adding it to another, as done by
+// Leopard GF8 and when no matrix kernel can be used.
+// The matrix kernels used for encoding and reconstruction use AVX2.
+// The instructions are also available on CPUs with AVX512VL,
+// where they can be enabled with this option.
+// AVX10 is only used when AVX2 is enabled.
+// If not set, AVX10 will be turned on for CPUs reporting AVX10 support.
+func WithAVX10(enabled bool) Option {
+	return func(o *options) {
+		o.useAVX10 = enabled
+	}
+}
+
 // WithAVX512 allows to enable/disable AVX512 (and GFNI) instructions.
 func WithAVX512(enabled bool) Option {
 	return func(o *options) {
@@ -222,9 +505,92 @@ func WithAVXGFNI(enabled bool) Option {
 // so the first parity chunk is always equal to XOR of all data chunks.
 func WithJerasureMatrix() Option {
 	return func(o *options) {
+		o.resetMatrix()
 		o.useJerasureMatrix = true
-		o.usePAR1Matrix = false
-		o.useCauchy = false
+	}
+}
+
+// WithCauchyGoodMatrix causes the encoder to build the matrix
+// the same way as cauchy_good_general_coding_matrix in the Jerasure library
+// with w=8, which selects a Cauchy matrix with few ones in its bit matrix.
+// Parity is identical to Jerasure encoding with that matrix.
+//
+// The bit matrix weight only affects the speed of bit matrix implementations,
+// so this does not affect data throughput in this package.
+func WithCauchyGoodMatrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.useCauchyGood = true
+	}
+}
+
+// WithPAR2Matrix causes the encoder to use the GF(2^16) Vandermonde
+// construction of PARv2, so the parity shards are identical to PAR2
+// recovery slices of the data shards.
+// Parity shard n is the recovery slice with exponent n, and symbols are
+// 16 bit little endian values, so shard sizes must be a multiple of 2.
+// Up to 32768 data shards are supported.
+//
+// Like PARv1, the construction is not MDS, so recovery may fail in rare cases,
+// even if there are enough parity shards.
+// The codec is implemented in pure Go.
+// Leopard, constant time and field representation options are not supported.
+func WithPAR2Matrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.usePAR2 = true
+	}
+}
+
+// WithGF16Matrix causes the encoder to use a Cauchy matrix over GF(2^16),
+// in the field of the Leopard GF16 encoder, so it uses the same SIMD kernels.
+// Unlike the Leopard GF16 encoder, EncodeIdx, Update, ReconstructSome of
+// single shards and the inversion cache are supported.
+// Each parity shard costs a multiplication of every data shard,
+// so it is mainly useful for 257 to around 2000 total shards,
+// where Leopard GF16 is used by default.
+// Up to 65536 total shards are supported, and shard sizes must be a multiple of 64.
+// Leopard, constant time and field representation options are not supported.
+func WithGF16Matrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.useGF16Matrix = true
+	}
+}
+
+// WithGF4Matrix causes the encoder to use a Cauchy matrix over GF(2^4),
+// with the polynomial x^4 + x + 1, for up to 16 total shards.
+// Each byte holds two symbols, one in each nibble, so any shard size can be used.
+// The only table is 256 bytes and encoding does not allocate,
+// so decoders are simple to implement on constrained devices.
+// The codec is implemented in pure Go.
+// Leopard, constant time and field representation options are not supported.
+func WithGF4Matrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.useGF4 = true
+	}
+}
+
+// WithPrimeField causes the encoder to use Reed-Solomon codes over the
+// prime field of PrimeFieldModulus, computed with number theoretic transforms,
+// to interoperate with systems that commit to data in evaluation form over prime fields.
+//
+// Shard i contains evaluations at ω^i of polynomials of degree less than DataShards,
+// where ω = 3^((PrimeFieldModulus-1)/n) and n is the smallest power of two,
+// at least 2, that is not less than TotalShards.
+// Symbols are little endian 32 bit values, which must be less than PrimeFieldModulus,
+// so shard sizes must be a multiple of 4.
+// Split stores 2 bytes of data in the low bytes of each symbol, and Join reverses it.
+// If a shard contains an invalid symbol, ErrInvalidSymbol is returned.
+//
+// Up to 32768 total shards are supported. EncodeIdx and Update are not supported.
+// The codec is implemented in pure Go.
+// Leopard, constant time and field representation options are not supported.
+func WithPrimeField() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.usePrime = true
 	}
 }
 
@@ -234,24 +600,99 @@ func WithJerasureMatrix() Option {
 // shards.
 func WithPAR1Matrix() Option {
 	return func(o *options) {
-		o.useJerasureMatrix = false
+		o.resetMatrix()
 		o.usePAR1Matrix = true
-		o.useCauchy = false
 	}
 }
 
 // WithCauchyMatrix will make the encoder build a Cauchy style matrix.
 // The output of this is not compatible with the standard output.
+// The matrix is the same as gf_gen_cauchy1_matrix in Intel's ISA-L.
 // A Cauchy matrix is faster to generate. This does not affect data throughput,
 // but will result in slightly faster start-up time.
 func WithCauchyMatrix() Option {
 	return func(o *options) {
-		o.useJerasureMatrix = false
-		o.usePAR1Matrix = false
+		o.resetMatrix()
 		o.useCauchy = true
 	}
 }
 
+// WithRawVandermondeMatrix causes the encoder to use rows of the raw
+// Vandermonde matrix as parity rows, without the Gaussian elimination
+// that makes the default matrix systematic.
+// This matches implementations that skip that step.
+// As with WithPAR1Matrix, some combinations of lost shards may be
+// impossible to recover, even if there are enough parity shards.
+// Use RawVandermondeMatrix to obtain the full matrix.
+func WithRawVandermondeMatrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.useRawVandermonde = true
+	}
+}
+
+// WithZfecMatrix causes the encoder to build the matrix the same way
+// as the zfec library, so the parity shards are identical to the
+// secondary blocks produced by zfec with the same k and m.
+// Shard i corresponds to zfec block number i.
+//
+// zfec starts with a Vandermonde matrix evaluated at 0, 1, 2, 4, ...
+// (the powers of the field generator) instead of 0, 1, 2, 3, ...
+// and makes the top square the identity, so as with the default matrix
+// any square subset of rows is invertible.
+func WithZfecMatrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.useZfecMatrix = true
+	}
+}
+
+// WithISALMatrix causes the encoder to build the matrix the same way
+// as gf_gen_rs_matrix in Intel's ISA-L, so parity is identical to ISA-L
+// encoding with that matrix.
+// Parity row i contains the powers of 2^i.
+//
+// As with WithPAR1Matrix, some combinations of lost shards may be
+// impossible to recover, even if there are enough parity shards.
+// ISA-L recommends gf_gen_cauchy1_matrix for this reason,
+// which produces the same matrix as WithCauchyMatrix.
+func WithISALMatrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.useISALMatrix = true
+	}
+}
+
+// WithCM256Matrix causes the encoder to build the Cauchy matrix
+// the same way as the CM256 library, so parity shard i is identical to
+// the CM256 recovery block with index dataShards+i.
+// The first parity shard is the XOR of all data shards.
+//
+// CM256 uses another field representation than this package,
+// so unless WithFieldRepresentation is also given, FieldCM256 is used.
+func WithCM256Matrix() Option {
+	return func(o *options) {
+		o.resetMatrix()
+		o.useCM256Matrix = true
+	}
+}
+
+// resetMatrix clears all matrix construction options.
+func (o *options) resetMatrix() {
+	o.useJerasureMatrix = false
+	o.usePAR1Matrix = false
+	o.usePAR2 = false
+	o.useGF16Matrix = false
+	o.useGF4 = false
+	o.usePrime = false
+	o.useCauchy = false
+	o.useRawVandermonde = false
+	o.useZfecMatrix = false
+	o.useISALMatrix = false
+	o.useCM256Matrix = false
+	o.useCauchyGood = false
+}
+
 // WithFastOneParityMatrix will switch the matrix to a simple xor
 // if there is only one parity shard.
 // The PAR1 matrix already has this property so it has little effect there.
@@ -273,6 +714,248 @@ func WithCustomMatrix(customMatrix [][]byte) Option {
 	}
 }
 
+// WithCustomGenerator causes the encoder to use the code generated by the
+// manually specified matrix, which must have a row for each shard and
+// a column for each data shard.
+// Unlike WithCustomMatrix the rows of the data shards are included and need
+// not be the identity; the matrix is converted to the equivalent systematic
+// matrix, so data shards are stored unchanged.
+// New returns an error wrapping ErrInvalidMatrix if the dimensions are wrong,
+// if the data shard rows are not invertible, or if some combination of
+// data shards cannot be recovered from the same number of parity shards.
+// For large codes only the first million combinations are checked.
+// Use WithCustomMatrix for codes that are deliberately not MDS, like LRC.
+func WithCustomGenerator(generator [][]byte) Option {
+	return func(o *options) {
+		o.customGenerator = generator
+	}
+}
+
+// WithFieldRepresentation will make the encoder operate on GF(2^8) elements
+// in the given representation, for compatibility with systems that use
+// another polynomial or log/exp table convention than FieldStandard.
+// All matrix constructions are performed in the given field,
+// and custom matrices are interpreted in the given representation.
+// Use a FieldConverter to convert existing shards between representations.
+// If the representation is invalid, New will return ErrInvalidField.
+// Not supported by Leopard encoders.
+func WithFieldRepresentation(rep FieldRepresentation) Option {
+	return func(o *options) {
+		o.fieldRep = rep
+	}
+}
+
+// WithFieldPolynomial will make the encoder operate on GF(2^8) with the
+// given reducing polynomial, including the x^8 term,
+// for example 0x11b as used by AES and a number of legacy systems.
+// The smallest primitive element is used as the generator,
+// which matters only for constructions using powers of the generator.
+// Use WithFieldRepresentation to choose another generator.
+// If the polynomial is not irreducible, New will return ErrInvalidField.
+// Not supported by Leopard encoders.
+func WithFieldPolynomial(poly uint16) Option {
+	return func(o *options) {
+		o.fieldRep = FieldRepresentation{Polynomial: poly, Generator: primitiveElement(poly)}
+	}
+}
+
+// WithConstantTime will avoid table lookups that depend on the shard data.
+// The SSSE3, AVX2, NEON and GFNI kernels only use in-register tables,
+// and the remaining bytes are processed with a slower bit-sliced multiplication
+// instead of full multiplication tables.
+// This is intended for encoding secret material where cache timing
+// must not reveal information about the data.
+// Lookups that only depend on the coefficients and which shards are missing remain.
+// Not supported by Leopard encoders.
+func WithConstantTime(enabled bool) Option {
+	return func(o *options) {
+		o.constantTime = enabled
+	}
+}
+
+// WithRateLimit will limit processing to approximately bytesPerSec bytes
+// of input shard data per second.
+// The limit applies to all operations on the encoder combined, including
+// streams created with NewStream, and is enforced for each block processed,
+// so it is intended for throttling background jobs like re-encoding or scrubbing.
+// If bytesPerSec <= 0, no limit is applied.
+// Not supported by Leopard encoders.
+func WithRateLimit(bytesPerSec int64) Option {
+	return func(o *options) {
+		o.rateLimit = nil
+		if bytesPerSec > 0 {
+			o.rateLimit = &rateLimiter{rate: float64(bytesPerSec)}
+		}
+	}
+}
+
+// WithProgress will call fn with the progress of long running operations,
+// so they can drive progress bars and watchdogs.
+//
+// It is honored by Encode, Reconstruct, ReconstructData and ReconstructSome,
+// and by all operations of encoders created with NewStream.
+// processed and total are in bytes per shard. For Reconstruct, total includes
+// a pass for data shards and a pass for parity shards when both are missing.
+// For streams read from io.Reader, the total is unknown and -1 is reported
+// until the final call.
+//
+// Calls are rate limited, so fn is called at most every 100ms while an
+// operation is running, and once with processed == total when it completes.
+// Calls for an operation are never concurrent, but fn may be called
+// concurrently for different operations. fn must return quickly.
+// Not supported by Leopard encoders.
+func WithProgress(fn func(processed, total int64)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithRequireAligned will return ErrNotAligned from operations given shards that
+// do not start on a 64 byte boundary, or do not have the capacity to round
+// their length up to a multiple of 64 bytes, like the shards returned by AllocAligned.
+// Aligned shards are processed entirely by the vector kernels, which also
+// overwrite the bytes between the length and the capacity of output shards
+// when the length is not a multiple of 64 bytes.
+// Missing shards are reconstructed into new aligned shards if they are not aligned.
+// Split copies the data to new aligned shards when the shards would
+// not be aligned otherwise, or returns ErrSplitCopy with WithZeroCopySplit.
+// Ignored by Leopard encoders.
+func WithRequireAligned() Option {
+	return func(o *options) {
+		o.requireAligned = true
+	}
+}
+
+// WithZeroCopySplit will make Split return ErrSplitCopy instead of
+// copying the last data shard to a new buffer, so all data shards
+// returned by Split are guaranteed to alias the input slice.
+// The last data shard is copied when the data size is not a multiple of
+// the shard size, and the capacity of the data does not extend to one.
+// Parity shards are still allocated if the capacity of the data is too small.
+func WithZeroCopySplit() Option {
+	return func(o *options) {
+		o.zeroCopySplit = true
+	}
+}
+
+// WithBackend will offload the matrix multiplications used for encoding,
+// verifying and reconstructing to b, for example a GPU.
+// If b is not available, or if WithConstantTime or WithFieldRepresentation
+// with a non-standard field is given, the CPU is used.
+// Only Reed-Solomon encoders use the backend, including the Reed-Solomon
+// codes of PAR2, Clay and Piggyback encoders, but not Leopard encoders.
+func WithBackend(b Backend) Option {
+	return func(o *options) {
+		o.backend = b
+	}
+}
+
+// WithSpill will back intermediate buffers with a temporary file in dir
+// when an operation needs more than memoryBudget bytes of them.
+// This allows very large operations to complete, slowly, instead of
+// exhausting memory, since the operating system can write the buffers
+// to disk under memory pressure.
+// If dir is empty, the default directory for temporary files is used.
+// If memoryBudget <= 0, buffers are always kept in memory.
+//
+// Only Leopard GF16 encoders, used for more than 256 shards, have
+// intermediate buffers proportional to the shard size.
+// On platforms without memory mapped file support, buffers are kept in memory.
+func WithSpill(dir string, memoryBudget int64) Option {
+	return func(o *options) {
+		o.spillDir = dir
+		o.spillBudget = memoryBudget
+	}
+}
+
+// WithClayCode will use a Clay code, a minimum storage regenerating code.
+// Like Reed-Solomon, any DataShards shards can recover the data,
+// but a single lost shard can be repaired by reading only 1/ParityShards
+// of each remaining shard. Use PlanRepair and RepairShard for this.
+//
+// Each shard is divided into ParityShards^t sub-chunks, where t is
+// TotalShards/ParityShards rounded up, so shard sizes must be a multiple
+// of that. If there are more than 65536 sub-chunks, New will return ErrMaxShardNum.
+// The matrix and CPU options apply to the Reed-Solomon code used for each sub-chunk.
+// EncodeIdx and Update are not supported.
+// Leopard, PAR2, constant time and field representation options are not supported.
+func WithClayCode(enabled bool) Option {
+	return func(o *options) {
+		o.useClay = enabled
+	}
+}
+
+// WithPiggyback will use a piggybacked Reed-Solomon code.
+// Each shard is split into two halves, and halves of data shards are
+// added to the second half of parity shards, so a single lost data shard
+// can be repaired by reading DataShards+g halves instead of DataShards
+// full shards, where g is about DataShards/(ParityShards-1).
+// Use PlanRepair and RepairShard for this.
+// Any DataShards shards can still recover the data.
+//
+// At least 3 parity shards are needed for reduced repair reads.
+// Shard sizes must be a multiple of twice the size otherwise required.
+// Other options apply to the encoder used for each half.
+// Not compatible with WithClayCode.
+func WithPiggyback(enabled bool) Option {
+	return func(o *options) {
+		o.usePiggyback = enabled
+	}
+}
+
+// WithShardChecksums will append a checksum computed by newHash to each shard.
+//
+// Encode appends the checksum of every shard after computing the parity.
+// Verify returns false if any checksum does not match.
+// Reconstruct, ReconstructData and ReconstructSome treat shards with a
+// checksum mismatch as missing, so silent corruption is repaired like a lost shard,
+// and return all shards with the checksums stripped, ready for Join.
+//
+// Checksums are written in place when the capacity of a shard allows,
+// as for shards returned by Split and AllocAligned,
+// so shards must not share backing arrays beyond their length.
+// EncodeIdx and Update are not supported.
+// A nil function disables checksums.
+func WithShardChecksums(newHash func() hash.Hash) Option {
+	return func(o *options) {
+		o.shardHash = newHash
+	}
+}
+
+// WithRDP will use a Row-Diagonal Parity array code when there are 2 parity shards.
+// Encoding and reconstruction only use XOR, which is considerably faster than
+// Reed-Solomon on CPUs without GFNI. Any 2 lost shards can be recovered.
+// The output is not compatible with Reed-Solomon.
+//
+// Each shard is divided into p-1 rows, where p is the smallest prime
+// larger than DataShards, so shard sizes must be a multiple of p-1.
+// With other numbers of parity shards, the option is ignored.
+// Leopard, Clay, piggyback, PAR2, constant time and field representation
+// options are not supported.
+func WithRDP(enabled bool) Option {
+	return func(o *options) {
+		o.useRDP = enabled
+	}
+}
+
+// WithPriorityClasses will protect groups of data shards with different
+// numbers of parity shards, for example 4 parity shards for headers and
+// 2 for the payload.
+// The data shards of the classes come first, in class order, followed by
+// the parity shards in class order. The sums of the data and parity shards
+// of the classes must equal the numbers given to New.
+//
+// Each class is a separate stripe, so a class can only be reconstructed
+// from its own shards, and up to its own number of parity shards can be lost.
+// Reconstruction recreates all classes that can be recovered, and returns
+// ErrTooFewShards if any could not be.
+// Other options are used for the encoder of each class.
+func WithPriorityClasses(classes ...PriorityClass) Option {
+	return func(o *options) {
+		o.classes = append([]PriorityClass(nil), classes...)
+	}
+}
+
 // WithLeopardGF16 will always use leopard GF16 for encoding,
 // even when there is less than 256 shards.
 // This will likely improve reconstruction time for some setups.
@@ -302,6 +985,31 @@ func WithLeopardGF(enabled bool) Option {
 	}
 }
 
+// WithLeopardGF32 will always use the GF(2^32) Leopard style codec for encoding.
+// It is never selected automatically, but must be used for more than
+// 65536 total shards, and allows up to 1<<24 total shards.
+// The output is not compatible with any other codec.
+// The same restrictions as for WithLeopardGF16 apply, and it is not
+// compatible with WithConstantTime or WithFieldRepresentation.
+func WithLeopardGF32(enabled bool) Option {
+	return func(o *options) {
+		if enabled {
+			o.withLeopard = leopardGF32
+		} else {
+			o.withLeopard = leopardAsNeeded
+		}
+	}
+}
+
+// pureGo returns true if only pure Go code will be used with the options.
+// Assembly on arm64 and ppc64le cannot be disabled by options.
+func (o *options) pureGo() bool {
+	if pureGoBuild {
+		return true
+	}
+	return runtime.GOARCH == "amd64" && !(o.useSSE2 || o.useSSSE3 || o.useAVX2 || o.useAVX512 || o.useAvx512GFNI || o.useAvxGNFI)
+}
+
 func (o *options) cpuOptions() string {
 	var res []string
 	if o.useSSE2 {
@@ -316,6 +1024,9 @@ func (o *options) cpuOptions() string {
 	if o.useAVX512 {
 		res = append(res, "AVX512")
 	}
+	if o.useAVX10 && o.useAVX2 {
+		res = append(res, "AVX10/256")
+	}
 	if o.useAvx512GFNI {
 		res = append(res, "AVX512+GFNI")
 	}
@@ -327,6 +1038,9 @@ func (o *options) cpuOptions() string {
 	} else if o.useNEON {
 		res = append(res, "ARM+NEON")
 	}
+	if o.useSHA3 && (o.useSVE || o.useNEON) {
+		res = append(res, "SHA3")
+	}
 	if len(res) == 0 {
 		return "pure Go"
 	}
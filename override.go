@@ -0,0 +1,100 @@
+package reedsolomon
+
+// cpuOverrider is implemented by encoders that can create a copy
+// using different CPU options.
+type cpuOverrider interface {
+	withCPUOptions(opts []Option) Encoder
+}
+
+// Override returns an encoder that shares the configuration of enc,
+// but uses the processing kernels selected by opts.
+//
+// This allows forcing a specific code path for some operations,
+// for example to avoid AVX512 on latency critical calls while bulk jobs use it,
+// or to compare code paths on live data.
+// Creating an override is cheap, and enc is not modified.
+// Scratch states kept with WithZeroAlloc or WithScratchStates are shared with enc.
+//
+// Only the CPU feature options (WithSSE2, WithSSSE3, WithAVX2, WithAVX10, WithAVX512,
+// WithGFNI, WithAVXGFNI and WithSHA3), WithBackend and WithMaxGoroutines are used.
+// Other options are ignored.
+// Features not supported by the CPU must not be enabled, and backends must be available.
+func Override(enc Encoder, opts ...Option) (Encoder, error) {
+	c, ok := enc.(cpuOverrider)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return c.withCPUOptions(opts), nil
+}
+
+// withCPU returns o with the CPU options from opts applied.
+func (o options) withCPU(opts []Option) options {
+	n := o
+	for _, opt := range opts {
+		opt(&n)
+	}
+	o.maxGoroutines = n.maxGoroutines
+	o.useSSE2, o.useSSSE3, o.useAVX2, o.useAVX512 = n.useSSE2, n.useSSSE3, n.useAVX2, n.useAVX512
+	o.useAvx512GFNI, o.useAvxGNFI = n.useAvx512GFNI, n.useAvxGNFI
+	o.useAVX10, o.useSHA3 = n.useAVX10, n.useSHA3
+	o.backend = n.backend
+	return o
+}
+
+func (r *reedSolomon) withCPUOptions(opts []Option) Encoder {
+	c := &reedSolomon{
+		dataShards:   r.dataShards,
+		parityShards: r.parityShards,
+		totalShards:  r.totalShards,
+		m:            r.m,
+		tree:         r.tree,
+		parity:       r.parity,
+		o:            r.o.withCPU(opts),
+		tmpSize:      r.tmpSize,
+	}
+	cal := *r.calib.Load()
+	if c.o.maxGoroutines != r.o.maxGoroutines {
+		cal.maxGoroutines = c.o.maxGoroutines
+	}
+	c.calib.Store(&cal)
+	// Share the scratch states kept with WithZeroAlloc.
+	c.jobs.share(&r.jobs)
+	c.decodes.share(&r.decodes)
+	return c
+}
+
+func (r *leopardFF16) withCPUOptions(opts []Option) Encoder {
+	c := &leopardFF16{
+		dataShards:   r.dataShards,
+		parityShards: r.parityShards,
+		totalShards:  r.totalShards,
+		inversion:    r.inversion,
+		cacheSeed:    r.cacheSeed,
+		o:            r.o.withCPU(opts),
+	}
+	c.states.share(&r.states)
+	return c
+}
+
+func (r *leopardFF32) withCPUOptions(opts []Option) Encoder {
+	c := &leopardFF32{
+		dataShards:   r.dataShards,
+		parityShards: r.parityShards,
+		totalShards:  r.totalShards,
+		o:            r.o.withCPU(opts),
+	}
+	c.states.share(&r.states)
+	return c
+}
+
+func (r *leopardFF8) withCPUOptions(opts []Option) Encoder {
+	c := &leopardFF8{
+		dataShards:   r.dataShards,
+		parityShards: r.parityShards,
+		totalShards:  r.totalShards,
+		inversion:    r.inversion,
+		o:            r.o.withCPU(opts),
+	}
+	c.states.share(&r.states)
+	return c
+}
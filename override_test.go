@@ -0,0 +1,46 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOverride(t *testing.T) {
+	for _, opts := range [][]Option{testOptions(), {WithLeopardGF16(true)}, {WithLeopardGF(true)}} {
+		enc, err := New(10, 4, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, 100000)
+		fillRandom(data)
+		shards, err := enc.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range [][]Option{
+			{WithSSE2(false), WithSSSE3(false), WithAVX2(false), WithAVX512(false), WithGFNI(false), WithAVXGFNI(false)},
+			{WithAVX512(false), WithMaxGoroutines(1)},
+		} {
+			ov, err := Override(enc, o...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, err := ov.Verify(shards)
+			if !ok || err != nil {
+				t.Fatal("not ok:", ok, "err:", err)
+			}
+			test := make([][]byte, len(shards))
+			copy(test, shards)
+			test[0], test[11] = nil, nil
+			if err := ov.Reconstruct(test); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(test[0], shards[0]) || !bytes.Equal(test[11], shards[11]) {
+				t.Fatal("reconstructed shard mismatch")
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package reedsolomon
+
+import "encoding/binary"
+
+// packetHeader is the size of the length stored before each packet.
+const packetHeader = 2
+
+// MaxPacketSize is the largest packet supported by EncodePackets.
+const MaxPacketSize = 1<<16 - 1
+
+// EncodePackets encodes packets of different lengths, such as UDP datagrams,
+// and returns the parity packets.
+// There must be enc.DataShards() packets.
+//
+// Each packet is encoded as its length (2 bytes, big endian), followed by
+// the packet, zero padded to the size of the longest packet, rounded up to
+// the shard size multiple of enc. The parity packets have this size.
+// The data packets should be sent as is, since the lengths are only needed
+// for decoding lost packets, where they are recovered from the parity.
+func EncodePackets(enc Encoder, packets [][]byte) ([][]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(packets) != ext.DataShards() {
+		return nil, ErrTooFewShards
+	}
+	size := 0
+	for _, p := range packets {
+		if len(p) > MaxPacketSize {
+			return nil, ErrShardSize
+		}
+		if len(p) > size {
+			size = len(p)
+		}
+	}
+	size = packetShardSize(ext, size+packetHeader)
+
+	shards := ext.AllocAligned(size)
+	for i, p := range packets {
+		putPacket(shards[i], p)
+	}
+	if err := enc.Encode(shards); err != nil {
+		return nil, err
+	}
+	return shards[len(packets):], nil
+}
+
+// DecodePackets recovers lost packets encoded by EncodePackets.
+// packets must contain the enc.DataShards() packets, with nil for lost packets,
+// and parity the enc.ParityShards() parity packets, with nil for lost packets.
+// The returned slice contains all packets with their original lengths.
+// Received packets are returned as is.
+// If too few packets have been received, ErrTooFewShards is returned.
+func DecodePackets(enc Encoder, packets, parity [][]byte) ([][]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(packets) != ext.DataShards() || len(parity) != ext.ParityShards() {
+		return nil, ErrTooFewShards
+	}
+	res := append([][]byte(nil), packets...)
+	lost := false
+	for _, p := range packets {
+		lost = lost || p == nil
+	}
+	if !lost {
+		return res, nil
+	}
+
+	// All parity packets have the shard size.
+	size := 0
+	for _, p := range parity {
+		if len(p) != 0 {
+			if size != 0 && len(p) != size {
+				return nil, ErrShardSize
+			}
+			size = len(p)
+		}
+	}
+	if size == 0 {
+		return nil, ErrTooFewShards
+	}
+	shards := make([][]byte, ext.TotalShards())
+	for i, p := range packets {
+		if p == nil {
+			continue
+		}
+		if len(p)+packetHeader > size {
+			return nil, ErrShardSize
+		}
+		shards[i] = make([]byte, size)
+		putPacket(shards[i], p)
+	}
+	copy(shards[len(packets):], parity)
+	if err := enc.ReconstructData(shards); err != nil {
+		return nil, err
+	}
+	for i, p := range packets {
+		if p != nil {
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(shards[i]))
+		if n+packetHeader > size {
+			return nil, ErrInvalidInput
+		}
+		res[i] = shards[i][packetHeader : packetHeader+n]
+	}
+	return res, nil
+}
+
+// packetShardSize returns the shard size for encoded packets of n bytes.
+func packetShardSize(ext Extensions, n int) int {
+	mult := ext.ShardSizeMultiple()
+	return (n + mult - 1) / mult * mult
+}
+
+// putPacket stores the length and content of p in shard, which must be zeroed.
+func putPacket(shard, p []byte) {
+	binary.BigEndian.PutUint16(shard, uint16(len(p)))
+	copy(shard[packetHeader:], p)
+}
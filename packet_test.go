@@ -0,0 +1,67 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPackets(t *testing.T) {
+	enc, err := New(5, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets := make([][]byte, 5)
+	for i, n := range []int{1200, 0, 37, 1400, 512} {
+		packets[i] = make([]byte, n)
+		fillRandom(packets[i])
+	}
+	parity, err := EncodePackets(enc, packets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parity) != 3 || len(parity[0]) != 1402 {
+		t.Fatalf("got %d parity packets of %d bytes", len(parity), len(parity[0]))
+	}
+
+	received := append([][]byte(nil), packets...)
+	received[1], received[3] = nil, nil
+	got, err := DecodePackets(enc, received, [][]byte{parity[0], nil, parity[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range packets {
+		if len(got[i]) != len(packets[i]) || !bytes.Equal(got[i], packets[i]) {
+			t.Fatalf("packet %d: got %d bytes, want %d", i, len(got[i]), len(packets[i]))
+		}
+	}
+
+	// Nothing lost.
+	got, err = DecodePackets(enc, packets, make([][]byte, 3))
+	if err != nil || len(got) != 5 {
+		t.Fatalf("got %d packets, %v", len(got), err)
+	}
+
+	received[0] = nil
+	if _, err := DecodePackets(enc, received, [][]byte{parity[0], nil, parity[2]}); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	if _, err := EncodePackets(enc, packets[:4]); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+
+	// Shard size multiples are respected.
+	leo, err := New(5, 3, WithLeopardGF(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity, err = EncodePackets(leo, packets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received = append([][]byte(nil), packets...)
+	received[4] = nil
+	got, err = DecodePackets(leo, received, parity)
+	if err != nil || !bytes.Equal(got[4], packets[4]) {
+		t.Fatalf("leopard decode failed: %v", err)
+	}
+}
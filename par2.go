@@ -0,0 +1,508 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// par2Codec is an encoder using the GF(2^16) Vandermonde construction of PAR2.
+// Construct using New with WithPAR2Matrix.
+type par2Codec struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	// logBases contains the logarithm of the PAR2 input slice constant of each data shard.
+	logBases []int
+
+	o options
+}
+
+const (
+	// par2Polynomial is the generator polynomial of the PAR2 field.
+	par2Polynomial = 0x1100B
+	par2Modulus    = 1<<16 - 1
+
+	// par2MaxData is the maximum number of input slices in a PAR2 set.
+	par2MaxData = 32768
+
+	// Encode in blocks of this size.
+	par2BlockSize = 32 << 10
+)
+
+var (
+	par2Once sync.Once
+	par2Log  *[1 << 16]uint16
+	par2Exp  *[1 << 16]uint16
+)
+
+func initPAR2Tables() {
+	par2Once.Do(func() {
+		var lg, ex [1 << 16]uint16
+		x := 1
+		for i := 0; i < par2Modulus; i++ {
+			ex[i] = uint16(x)
+			lg[x] = uint16(i)
+			x <<= 1
+			if x&(1<<16) != 0 {
+				x ^= par2Polynomial
+			}
+		}
+		ex[par2Modulus] = ex[0]
+		par2Log, par2Exp = &lg, &ex
+	})
+}
+
+// par2Mul returns a * b in the PAR2 field.
+func par2Mul(a, b uint16) uint16 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return par2Exp[(int(par2Log[a])+int(par2Log[b]))%par2Modulus]
+}
+
+// par2Inv returns the multiplicative inverse of a, which must be non-zero.
+func par2Inv(a uint16) uint16 {
+	return par2Exp[(par2Modulus-int(par2Log[a]))%par2Modulus]
+}
+
+// newPAR2 is like New, but for the PAR2 construction.
+func newPAR2(dataShards, parityShards int, opt options) (*par2Codec, error) {
+	initPAR2Tables()
+
+	if dataShards <= 0 || parityShards < 0 {
+		return nil, ErrInvShardNum
+	}
+	if dataShards > par2MaxData || parityShards > par2Modulus {
+		return nil, ErrMaxShardNum
+	}
+
+	r := &par2Codec{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		logBases:     make([]int, dataShards),
+		o:            opt,
+	}
+	// The input slice constants are 2^n, for the values of n that
+	// are coprime to the field order, in increasing order.
+	n := 0
+	for i := range r.logBases {
+		for n%3 == 0 || n%5 == 0 || n%17 == 0 || n%257 == 0 {
+			n++
+		}
+		r.logBases[i] = n
+		n++
+	}
+	return r, nil
+}
+
+var _ = Extensions(&par2Codec{})
+
+// coefficient returns the coefficient of data shard i in parity shard j.
+// Parity shard j is the PAR2 recovery slice with exponent j.
+func (r *par2Codec) coefficient(j, i int) uint16 {
+	return par2Exp[r.logBases[i]*j%par2Modulus]
+}
+
+// par2MulAdd adds c * in to out, treating both as little endian 16 bit symbols.
+func par2MulAdd(c uint16, in, out []byte) {
+	if c == 0 {
+		return
+	}
+	var lo, hi [256]uint16
+	for x := 1; x < 256; x++ {
+		lo[x] = par2Mul(c, uint16(x))
+		hi[x] = par2Mul(c, uint16(x)<<8)
+	}
+	out = out[:len(in)]
+	for i := 0; i+1 < len(in); i += 2 {
+		p := lo[in[i]] ^ hi[in[i+1]]
+		out[i] ^= byte(p)
+		out[i+1] ^= byte(p >> 8)
+	}
+}
+
+// par2MulRows sets outputs[j] to the sum of rows[j][i] * inputs[i].
+func par2MulRows(rows [][]uint16, inputs, outputs [][]byte, byteCount int) {
+	for _, out := range outputs {
+		memclr(out[:byteCount])
+	}
+	for start := 0; start < byteCount; start += par2BlockSize {
+		end := start + par2BlockSize
+		if end > byteCount {
+			end = byteCount
+		}
+		for j, out := range outputs {
+			for i, in := range inputs {
+				par2MulAdd(rows[j][i], in[start:end], out[start:end])
+			}
+		}
+	}
+}
+
+// parityRows returns the coefficient rows for the given parity shards.
+func (r *par2Codec) parityRows(parity []int) [][]uint16 {
+	rows := make([][]uint16, len(parity))
+	for j, p := range parity {
+		rows[j] = make([]uint16, r.dataShards)
+		for i := range rows[j] {
+			rows[j][i] = r.coefficient(p, i)
+		}
+	}
+	return rows
+}
+
+func (r *par2Codec) ShardSizeMultiple() int {
+	return 2
+}
+
+func (r *par2Codec) DataShards() int {
+	return r.dataShards
+}
+
+func (r *par2Codec) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *par2Codec) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *par2Codec) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+// PureGo always returns true, since the PAR2 codec has no assembly.
+func (r *par2Codec) PureGo() bool {
+	return true
+}
+
+// Recalibrate does nothing, since the PAR2 codec does not split work between goroutines.
+func (r *par2Codec) Recalibrate(shardSize int) {}
+
+func (r *par2Codec) CostModel() CostModel {
+	var c CostModel
+	if r.parityShards == 0 {
+		return c
+	}
+	d, p := float64(r.dataShards), float64(r.parityShards)
+	c.EncodeMulsPerByte = p
+	c.EncodeBytesPerByte = (d + p) / d
+	c.ReconstructMulsPerByte = d
+	c.ReconstructBytesPerByte = d + 1
+	return c
+}
+
+func (r *par2Codec) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	if len(shards[0])%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	parity := make([]int, r.parityShards)
+	for i := range parity {
+		parity[i] = i
+	}
+	par2MulRows(r.parityRows(parity), shards[:r.dataShards], shards[r.dataShards:], len(shards[0]))
+	return nil
+}
+
+// EncodeIdx will add parity for a single data shard.
+// Parity shards should start out zeroed. The caller must zero them before first call.
+// Data shards should only be delivered once. There is no check for this.
+func (r *par2Codec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	if len(parity) != r.parityShards {
+		return ErrTooFewShards
+	}
+	if len(parity) == 0 {
+		return nil
+	}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
+	}
+	if err := checkShards(parity, false); err != nil {
+		return err
+	}
+	if len(parity[0]) != len(dataShard) {
+		return ErrShardSize
+	}
+	if len(dataShard)%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	for j, p := range parity {
+		par2MulAdd(r.coefficient(j, idx), dataShard, p)
+	}
+	return nil
+}
+
+func (r *par2Codec) Update(shards [][]byte, newDatashards [][]byte) error {
+	if len(shards) != r.totalShards || len(newDatashards) != r.dataShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	if err := checkShards(newDatashards, true); err != nil {
+		return err
+	}
+	for i := range newDatashards {
+		if newDatashards[i] != nil && shards[i] == nil {
+			return ErrInvalidInput
+		}
+	}
+	for _, p := range shards[r.dataShards:] {
+		if p == nil {
+			return ErrInvalidInput
+		}
+	}
+	shardSize := shardSize(shards)
+	if shardSize%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	delta := make([]byte, shardSize)
+	for i, newData := range newDatashards {
+		if newData == nil {
+			continue
+		}
+		for k := range delta {
+			delta[k] = shards[i][k] ^ newData[k]
+		}
+		for j, p := range shards[r.dataShards:] {
+			par2MulAdd(r.coefficient(j, i), delta, p)
+		}
+	}
+	return nil
+}
+
+func (r *par2Codec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	shardSize := len(shards[0])
+	outputs := make([][]byte, r.totalShards)
+	copy(outputs, shards[:r.dataShards])
+	for i := r.dataShards; i < r.totalShards; i++ {
+		outputs[i] = make([]byte, shardSize)
+	}
+	if err := r.Encode(outputs); err != nil {
+		return false, err
+	}
+
+	// Compare.
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !bytes.Equal(outputs[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *par2Codec) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+func (r *par2Codec) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *par2Codec) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards.
+// If required is non-nil, only shards marked as required are recreated.
+//
+// Missing data is recovered the same way as PAR2 does, by solving
+// the equations given by the available parity shards.
+// Since the PAR2 construction is not MDS, recovery may fail with
+// enough shards present.
+func (r *par2Codec) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	shardSize := shardSize(shards)
+	if shardSize%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	isRequired := func(i int) bool {
+		return len(shards[i]) == 0 && (i < r.dataShards || !dataOnly) && (required == nil || required[i])
+	}
+
+	var missing, present, parity []int
+	needParity := false
+	for i := range shards {
+		switch {
+		case len(shards[i]) != 0 && i >= r.dataShards:
+			parity = append(parity, i-r.dataShards)
+		case len(shards[i]) != 0:
+			present = append(present, i)
+		case i < r.dataShards:
+			missing = append(missing, i)
+		case isRequired(i):
+			needParity = true
+		}
+	}
+	wantData := false
+	for _, i := range missing {
+		wantData = wantData || isRequired(i)
+	}
+	if !wantData && !needParity {
+		return nil
+	}
+	if len(missing) > len(parity) {
+		return ErrTooFewShards
+	}
+	parity = parity[:len(missing)]
+
+	data := make([][]byte, r.dataShards)
+	copy(data, shards[:r.dataShards])
+	if len(missing) > 0 {
+		// Solve A * missing = parity - (contribution of present data),
+		// where A contains the coefficients of the missing shards.
+		a := make([][]uint16, len(missing))
+		for j, p := range parity {
+			a[j] = make([]uint16, len(missing))
+			for k, i := range missing {
+				a[j][k] = r.coefficient(p, i)
+			}
+		}
+		inv, err := par2Invert(a)
+		if err != nil {
+			return err
+		}
+
+		// Inputs are the parity shards and present data shards.
+		inputs := make([][]byte, 0, len(parity)+len(present))
+		for _, p := range parity {
+			inputs = append(inputs, shards[r.dataShards+p])
+		}
+		for _, i := range present {
+			inputs = append(inputs, shards[i])
+		}
+		var rows [][]uint16
+		var outputs [][]byte
+		for k, i := range missing {
+			if !isRequired(i) && !needParity {
+				continue
+			}
+			row := make([]uint16, len(inputs))
+			copy(row, inv[k])
+			// Subtract the present data from the parity before applying the inverse.
+			for n, d := range present {
+				var sum uint16
+				for j, p := range parity {
+					sum ^= par2Mul(inv[k][j], r.coefficient(p, d))
+				}
+				row[len(parity)+n] = sum
+			}
+			if isRequired(i) {
+				if cap(shards[i]) >= shardSize {
+					shards[i] = shards[i][:shardSize]
+				} else {
+					shards[i] = AllocAligned(1, shardSize)[0]
+				}
+				data[i] = shards[i]
+			} else {
+				data[i] = make([]byte, shardSize)
+			}
+			rows = append(rows, row)
+			outputs = append(outputs, data[i])
+		}
+		par2MulRows(rows, inputs, outputs, shardSize)
+	}
+	if !needParity {
+		return nil
+	}
+
+	var want []int
+	var outputs [][]byte
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !isRequired(i) {
+			continue
+		}
+		if cap(shards[i]) >= shardSize {
+			shards[i] = shards[i][:shardSize]
+		} else {
+			shards[i] = AllocAligned(1, shardSize)[0]
+		}
+		want = append(want, i-r.dataShards)
+		outputs = append(outputs, shards[i])
+	}
+	par2MulRows(r.parityRows(want), data, outputs, shardSize)
+	return nil
+}
+
+// par2Invert returns the inverse of the square matrix m.
+// m is modified.
+func par2Invert(m [][]uint16) ([][]uint16, error) {
+	n := len(m)
+	inv := make([][]uint16, n)
+	for i := range inv {
+		inv[i] = make([]uint16, n)
+		inv[i][i] = 1
+	}
+	for c := 0; c < n; c++ {
+		// Find a row with a non-zero value in column c.
+		p := c
+		for p < n && m[p][c] == 0 {
+			p++
+		}
+		if p == n {
+			return nil, errSingular
+		}
+		m[c], m[p] = m[p], m[c]
+		inv[c], inv[p] = inv[p], inv[c]
+
+		// Scale the row, so the pivot is 1.
+		if s := par2Inv(m[c][c]); s != 1 {
+			for k := 0; k < n; k++ {
+				m[c][k] = par2Mul(m[c][k], s)
+				inv[c][k] = par2Mul(inv[c][k], s)
+			}
+		}
+		// Eliminate column c from the other rows.
+		for row := 0; row < n; row++ {
+			f := m[row][c]
+			if row == c || f == 0 {
+				continue
+			}
+			for k := 0; k < n; k++ {
+				m[row][k] ^= par2Mul(f, m[c][k])
+				inv[row][k] ^= par2Mul(f, inv[c][k])
+			}
+		}
+	}
+	return inv, nil
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+// Shards are padded with zeros to an even size.
+func (r *par2Codec) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, 2, r.o.zeroCopySplit, false)
+}
+
+func (r *par2Codec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
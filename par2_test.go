@@ -0,0 +1,151 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// par2RefMul multiplies a and b in the PAR2 field, bit by bit.
+func par2RefMul(a, b uint16) uint16 {
+	var res uint32
+	x := uint32(a)
+	for ; b != 0; b >>= 1 {
+		if b&1 != 0 {
+			res ^= x
+		}
+		x <<= 1
+		if x&(1<<16) != 0 {
+			x ^= par2Polynomial
+		}
+	}
+	return uint16(res)
+}
+
+func par2RefPow(a uint16, e int) uint16 {
+	res := uint16(1)
+	for i := 0; i < e; i++ {
+		res = par2RefMul(res, a)
+	}
+	return res
+}
+
+// par2RefRecovery computes a PAR2 recovery slice the way the PAR2
+// specification describes it.
+func par2RefRecovery(inputs [][]byte, exponent int) []byte {
+	out := make([]byte, len(inputs[0]))
+	logbase := 0
+	for _, in := range inputs {
+		for gcd(par2Modulus, logbase) != 1 {
+			logbase++
+		}
+		c := par2RefPow(par2RefPow(2, logbase), exponent)
+		logbase++
+		for k := 0; k < len(in); k += 2 {
+			v := par2RefMul(c, binary.LittleEndian.Uint16(in[k:]))
+			binary.LittleEndian.PutUint16(out[k:], binary.LittleEndian.Uint16(out[k:])^v)
+		}
+	}
+	return out
+}
+
+func TestPAR2Matrix(t *testing.T) {
+	const dataShards, parityShards, shardSize = 10, 5, 1000
+	enc, err := New(dataShards, parityShards, testOptions(WithPAR2Matrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(shardSize)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < parityShards; j++ {
+		if !bytes.Equal(shards[dataShards+j], par2RefRecovery(shards[:dataShards], j)) {
+			t.Fatalf("parity shard %d does not match PAR2 recovery slice", j)
+		}
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+
+	// Reconstruct with mixed losses.
+	for _, lost := range [][]int{{0}, {3, 11}, {0, 1, 2, 3, 4}, {9, 10, 12, 14}, {10, 11, 12, 13, 14}} {
+		test := make([][]byte, len(shards))
+		copy(test, shards)
+		for _, i := range lost {
+			test[i] = nil
+		}
+		if err := enc.Reconstruct(test); err != nil {
+			t.Fatal(lost, err)
+		}
+		for i := range test {
+			if !bytes.Equal(test[i], shards[i]) {
+				t.Fatal(lost, "shard", i, "mismatch")
+			}
+		}
+	}
+
+	// Only reconstruct a parity shard.
+	test := make([][]byte, len(shards))
+	copy(test, shards)
+	test[2], test[12], test[13] = nil, nil, nil
+	required := make([]bool, len(shards))
+	required[13] = true
+	if err := enc.ReconstructSome(test, required); err != nil {
+		t.Fatal(err)
+	}
+	if test[2] != nil || test[12] != nil || !bytes.Equal(test[13], shards[13]) {
+		t.Fatal("ReconstructSome did not reconstruct only the required shard")
+	}
+
+	// Too many lost.
+	copy(test, shards)
+	for i := 0; i <= parityShards; i++ {
+		test[i] = nil
+	}
+	if err := enc.Reconstruct(test); err != ErrTooFewShards {
+		t.Errorf("got %v, want %v", err, ErrTooFewShards)
+	}
+
+	// Update a data shard.
+	newData := make([]byte, shardSize)
+	fillRandom(newData)
+	newShards := make([][]byte, dataShards)
+	newShards[4] = newData
+	if err := enc.Update(shards, newShards); err != nil {
+		t.Fatal(err)
+	}
+	shards[4] = newData
+	ok, err = enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok after update:", ok, "err:", err)
+	}
+
+	// Split and join.
+	data := make([]byte, 12345)
+	fillRandom(data)
+	split, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(split[0])%2 != 0 {
+		t.Fatal("odd shard size", len(split[0]))
+	}
+	if err := enc.Encode(split); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, split, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+	if _, err := New(dataShards, parityShards, WithPAR2Matrix(), WithLeopardGF(true)); err != ErrNotSupported {
+		t.Errorf("got %v, want %v", err, ErrNotSupported)
+	}
+}
@@ -0,0 +1,162 @@
+package reedsolomon
+
+import "bytes"
+
+// EncodeParityIdx computes parity shard idx from the data shards,
+// without computing any other parity shard.
+//
+// data must contain at least DataShards shards of equal size.
+// Only the data shards are used, so the complete set of shards can be given.
+// The parity is written to dst if it has sufficient capacity, and returned.
+//
+// idx may be ParityShards or above. The parity row is then generated by
+// extending the encoding matrix, so the result is identical to parity shard idx
+// of an encoder created with the same options and more parity shards.
+// With WithCauchyMatrix, element c of row idx is 1/((DataShards+idx) ^ c).
+// If extending the matrix would change the existing parity rows,
+// for example with WithFastOneParityMatrix, ErrNotSupported is returned.
+// The total number of shards cannot exceed 256, so idx must be below 256-DataShards.
+//
+// Only encoders for at most 256 shards are supported.
+func EncodeParityIdx(enc Encoder, data [][]byte, idx int, dst []byte) ([]byte, error) {
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if idx < 0 {
+		return nil, ErrInvShardNum
+	}
+	if len(data) < r.dataShards {
+		return nil, ErrTooFewShards
+	}
+	data = data[:r.dataShards]
+	if err := checkShards(data, false); err != nil {
+		return nil, err
+	}
+	ext, err := r.extend(idx + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	size := len(data[0])
+	if cap(dst) >= size {
+		dst = dst[:size]
+	} else {
+		dst = make([]byte, size)
+	}
+	ext.codeSomeShards(ext.parity[idx:idx+1], data, [][]byte{dst}, size)
+	return dst, nil
+}
+
+// AddParity computes extra parity shards for a stripe encoded by enc,
+// and returns the shards with the new parity shards appended.
+//
+// shards must contain TotalShards shards, and the data shards must be present.
+// The existing parity shards are not read or modified.
+// The returned stripe can be handled by an encoder created with the same options
+// and ParityShards+extra parity shards.
+// The same restrictions as for EncodeParityIdx apply.
+func AddParity(enc Encoder, shards [][]byte, extra int) ([][]byte, error) {
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if extra < 0 {
+		return nil, ErrInvShardNum
+	}
+	if len(shards) != r.totalShards {
+		return nil, ErrTooFewShards
+	}
+	data := shards[:r.dataShards]
+	if err := checkShards(data, false); err != nil {
+		return nil, err
+	}
+	ext, err := r.extend(r.parityShards + extra)
+	if err != nil {
+		return nil, err
+	}
+
+	size := len(data[0])
+	parity := AllocAligned(extra, size)
+	ext.codeSomeShards(ext.parity[r.parityShards:r.parityShards+extra], data, parity, size)
+	out := make([][]byte, 0, len(shards)+extra)
+	out = append(out, shards...)
+	return append(out, parity...), nil
+}
+
+// VerifyIdx verifies parity shard idx against the data shards,
+// without computing any other parity shard.
+//
+// shards must contain TotalShards shards, and the data shards and
+// parity shard idx must be present. Other parity shards are ignored.
+// This is cheaper than Verify when parity shards are checked one at a time.
+//
+// Only encoders for at most 256 shards are supported.
+func VerifyIdx(enc Encoder, shards [][]byte, idx int) (bool, error) {
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		return false, ErrNotSupported
+	}
+	if idx < 0 || idx >= r.parityShards {
+		return false, ErrInvShardNum
+	}
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	data := shards[:r.dataShards]
+	if err := checkShards(data, false); err != nil {
+		return false, err
+	}
+	parity := shards[r.dataShards+idx]
+	if len(parity) != len(data[0]) {
+		return false, ErrShardSize
+	}
+	return r.checkSomeShards(r.parity[idx:idx+1], data, [][]byte{parity}, len(parity)), nil
+}
+
+// extension is an encoder returned by extend.
+type extension struct {
+	r   *reedSolomon
+	err error
+}
+
+// extend returns an encoder with at least parityShards parity shards,
+// where the first parity rows are the same as in r.
+// Encoders are cached in r, so they are only created once for each parityShards.
+func (r *reedSolomon) extend(parityShards int) (*reedSolomon, error) {
+	if parityShards <= r.parityShards {
+		return r, nil
+	}
+	if r.dataShards+parityShards > 256 {
+		return nil, ErrMaxShardNum
+	}
+	if e, ok := r.extended.Load(parityShards); ok {
+		return e.(*extension).r, e.(*extension).err
+	}
+	e, _ := r.extended.LoadOrStore(parityShards, r.newExtension(parityShards))
+	return e.(*extension).r, e.(*extension).err
+}
+
+// newExtension creates the encoder returned by extend.
+// The encoder is only used for encoding, and shares the workers of r,
+// so it is not tuned and does not cache inverted matrices.
+func (r *reedSolomon) newExtension(parityShards int) *extension {
+	opt := r.o
+	opt.autoTune = false
+	opt.autoRecalibrate = false
+	opt.inversionCache = false
+	enc, err := New(r.dataShards, parityShards, func(o *options) { *o = opt })
+	if err != nil {
+		return &extension{err: ErrNotSupported}
+	}
+	ext, ok := enc.(*reedSolomon)
+	if !ok {
+		return &extension{err: ErrNotSupported}
+	}
+	for i, row := range r.parity {
+		if !bytes.Equal(row, ext.parity[i]) {
+			return &extension{err: ErrNotSupported}
+		}
+	}
+	return &extension{r: ext}
+}
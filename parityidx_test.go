@@ -0,0 +1,201 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeParityIdx(t *testing.T) {
+	for _, opt := range []Option{WithCauchyMatrix(), WithPAR1Matrix(), WithISALMatrix()} {
+		enc, err := New(5, 3, testOptions(opt)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// An encoder with more parity shards gives the reference.
+		wide, err := New(5, 8, testOptions(opt)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := wide.(Extensions).AllocAligned(1000)
+		for _, shard := range shards[:5] {
+			fillRandom(shard)
+		}
+		if err := wide.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		for idx := 0; idx < 8; idx++ {
+			got, err := EncodeParityIdx(enc, shards, idx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, shards[5+idx]) {
+				t.Fatalf("parity %d mismatch", idx)
+			}
+		}
+
+		// Any 5 shards of the extended stripe can reconstruct the data.
+		damaged := make([][]byte, len(shards))
+		copy(damaged, shards)
+		for i := 0; i < 5; i++ {
+			damaged[i*2] = nil
+		}
+		if err := wide.ReconstructData(damaged); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(damaged[0], shards[0]) {
+			t.Fatal("reconstructed data mismatch")
+		}
+	}
+
+	// Cauchy rows are generated directly.
+	enc, err := New(3, 1, WithCauchyMatrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := [][]byte{{1}, {0}, {0}}
+	got, err := EncodeParityIdx(enc, data, 10, make([]byte, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := galOneOver(3 + 10); got[0] != want {
+		t.Fatalf("got %d, want %d", got[0], want)
+	}
+}
+
+func TestEncodeParityIdxErrors(t *testing.T) {
+	enc, err := New(5, 1, WithFastOneParityMatrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := AllocAligned(5, 10)
+	if _, err := EncodeParityIdx(enc, data, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncodeParityIdx(enc, data, 1, nil); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+	if _, err := EncodeParityIdx(enc, data, -1, nil); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+	if _, err := EncodeParityIdx(enc, data[:4], 0, nil); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+
+	enc, err = New(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncodeParityIdx(enc, data, 251, nil); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum, got %v", err)
+	}
+	enc, err = New(5, 2, WithLeopardGF16(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncodeParityIdx(enc, data, 0, nil); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+}
+
+func TestAddParity(t *testing.T) {
+	enc, err := New(6, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(500)
+	for _, shard := range shards[:6] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	// Existing parity is not read.
+	parity := shards[6]
+	shards[6] = nil
+	grown, err := AddParity(enc, shards, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grown) != 11 || grown[6] != nil {
+		t.Fatalf("unexpected result with %d shards", len(grown))
+	}
+	grown[6] = parity
+
+	wide, err := New(6, 5, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := wide.Verify(grown)
+	if err != nil || !ok {
+		t.Fatalf("grown stripe does not verify: %v", err)
+	}
+
+	if _, err := AddParity(enc, shards[:7], 1); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	if _, err := AddParity(enc, grown[:8], -1); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+}
+
+func TestVerifyIdx(t *testing.T) {
+	enc, err := New(6, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:6] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	shards[7][500] ^= 1
+	// Other parity shards are ignored.
+	parity := shards[8]
+	shards[8] = nil
+	for idx, want := range []bool{true, false} {
+		ok, err := VerifyIdx(enc, shards, idx)
+		if err != nil || ok != want {
+			t.Fatalf("parity %d: got %v %v, want %v", idx, ok, err, want)
+		}
+	}
+	if _, err := VerifyIdx(enc, shards, 2); err != ErrShardSize {
+		t.Errorf("want ErrShardSize, got %v", err)
+	}
+	shards[8] = parity
+	if ok, err := VerifyIdx(enc, shards, 2); !ok || err != nil {
+		t.Errorf("parity 2: got %v %v", ok, err)
+	}
+	if _, err := VerifyIdx(enc, shards, 3); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+	if _, err := VerifyIdx(enc, shards[:8], 0); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+}
+
+func TestParityIdxExtendCached(t *testing.T) {
+	enc, err := New(5, 3, testOptions(WithAutoTune(), WithPersistentWorkers(2))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := enc.(*reedSolomon)
+	a, err := r.extend(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.extend(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("extended encoder was not cached")
+	}
+	if a.o.scheduler != r.o.scheduler {
+		t.Error("extended encoder does not share the workers")
+	}
+	if a.o.autoTune {
+		t.Error("extended encoder was tuned")
+	}
+}
@@ -0,0 +1,129 @@
+package reedsolomon
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// parityLogHeaderSize is the size of a parity delta record header.
+// The header contains the shard offset (8 bytes) and the delta length (4 bytes).
+const parityLogHeaderSize = 12
+
+// ParityDeltaLog records parity deltas caused by data shard updates
+// into an append-only log.
+//
+// Each update is written as a single sequential record, instead of
+// overwriting the parity shards in place.
+// The logged deltas can later be applied to the parity shards with
+// CompactParityDeltas.
+//
+// Records are written with a single Write call each.
+// A ParityDeltaLog is not safe for concurrent use.
+type ParityDeltaLog struct {
+	enc    Encoder
+	w      io.Writer
+	parity [][]byte
+	buf    []byte
+}
+
+// NewParityDeltaLog returns a log that writes parity delta records for enc to w.
+// The encoder must support EncodeIdx.
+func NewParityDeltaLog(enc Encoder, w io.Writer) (*ParityDeltaLog, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return &ParityDeltaLog{enc: enc, w: w, parity: make([][]byte, ext.ParityShards())}, nil
+}
+
+// Append logs the parity delta caused by replacing oldData with newData
+// in data shard idx, starting at byte offset in the shard.
+//
+// oldData and newData must have the same length.
+// Neither slice is modified.
+func (l *ParityDeltaLog) Append(idx, offset int, oldData, newData []byte) error {
+	if len(oldData) != len(newData) {
+		return ErrShardSize
+	}
+	if offset < 0 || len(newData) == 0 {
+		return ErrInvalidInput
+	}
+	n := len(newData)
+	size := parityLogHeaderSize + n*len(l.parity)
+	if cap(l.buf) < size {
+		l.buf = make([]byte, size)
+	}
+	buf := l.buf[:size]
+	binary.LittleEndian.PutUint64(buf, uint64(offset))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(n))
+
+	// EncodeIdx adds to the parity, so it must start out as zero.
+	body := buf[parityLogHeaderSize:]
+	for i := range body {
+		body[i] = 0
+	}
+	delta := make([]byte, n)
+	for i := range delta {
+		delta[i] = oldData[i] ^ newData[i]
+	}
+	for i := range l.parity {
+		l.parity[i] = body[i*n : (i+1)*n]
+	}
+	if err := l.enc.EncodeIdx(delta, idx, l.parity); err != nil {
+		return err
+	}
+	_, err := l.w.Write(buf)
+	return err
+}
+
+// CompactParityDeltas reads parity delta records written by a ParityDeltaLog
+// from r, and applies them to the parity shards.
+//
+// parity must contain the parity shards the log was recorded against.
+// The number of records applied is returned.
+// If the log ends with a partially written record, the records before it
+// are applied and io.ErrUnexpectedEOF is returned.
+func CompactParityDeltas(enc Encoder, r io.Reader, parity [][]byte) (int, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	if len(parity) != ext.ParityShards() {
+		return 0, ErrTooFewShards
+	}
+	if err := checkShards(parity, false); err != nil {
+		return 0, err
+	}
+	shardSize := len(parity[0])
+
+	var header [parityLogHeaderSize]byte
+	var body []byte
+	records := 0
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			return records, err
+		}
+		offset := binary.LittleEndian.Uint64(header[:])
+		n := int(binary.LittleEndian.Uint32(header[8:]))
+		if offset+uint64(n) > uint64(shardSize) {
+			return records, ErrInvalidInput
+		}
+		if cap(body) < n*len(parity) {
+			body = make([]byte, n*len(parity))
+		}
+		body = body[:n*len(parity)]
+		if _, err := io.ReadFull(r, body); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return records, err
+		}
+		for i, p := range parity {
+			sliceXor(body[i*n:(i+1)*n], p[offset:offset+uint64(n)], &defaultOptions)
+		}
+		records++
+	}
+}
@@ -0,0 +1,73 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestParityDeltaLog(t *testing.T) {
+	const dataShards, parityShards = 6, 3
+	enc, err := New(dataShards, parityShards, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+
+	var log bytes.Buffer
+	l, err := NewParityDeltaLog(enc, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := []struct{ idx, off, n int }{
+		{0, 0, 10},
+		{3, 500, 200},
+		{3, 550, 1},
+		{5, 999, 1},
+	}
+	for _, u := range updates {
+		newData := make([]byte, u.n)
+		fillRandom(newData)
+		old := shards[u.idx][u.off : u.off+u.n]
+		if err := l.Append(u.idx, u.off, old, newData); err != nil {
+			t.Fatal(err)
+		}
+		copy(old, newData)
+	}
+	ok, _ := enc.Verify(shards)
+	if ok {
+		t.Fatal("parity should not be updated yet")
+	}
+
+	logged := log.Bytes()
+	n, err := CompactParityDeltas(enc, bytes.NewReader(logged), shards[dataShards:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(updates) {
+		t.Fatalf("applied %d records, want %d", n, len(updates))
+	}
+	ok, err = enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+
+	// A torn record must not be applied.
+	parity := make([][]byte, parityShards)
+	for i := range parity {
+		parity[i] = make([]byte, 1000)
+	}
+	n, err = CompactParityDeltas(enc, bytes.NewReader(logged[:len(logged)-1]), parity)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+	if n != len(updates)-1 {
+		t.Fatalf("applied %d records, want %d", n, len(updates)-1)
+	}
+}
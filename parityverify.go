@@ -0,0 +1,90 @@
+package reedsolomon
+
+import "bytes"
+
+// ParityVerifier verifies parity shards from data shards fed one at a time,
+// in any order, so a stripe can be verified while it is received
+// without keeping all data shards in memory.
+//
+// The parity of each data shard is accumulated with EncodeIdx,
+// so the encoder must support EncodeIdx.
+// A ParityVerifier is not safe for concurrent use.
+type ParityVerifier struct {
+	enc    Encoder
+	parity [][]byte
+	added  []bool
+	n      int
+}
+
+// NewParityVerifier returns a verifier for shards of shardSize bytes encoded by enc.
+func NewParityVerifier(enc Encoder, shardSize int) (*ParityVerifier, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if shardSize <= 0 {
+		return nil, ErrShardNoData
+	}
+	return &ParityVerifier{
+		enc:    enc,
+		parity: AllocAligned(ext.ParityShards(), shardSize),
+		added:  make([]bool, ext.DataShards()),
+	}, nil
+}
+
+// Add adds data shard idx to the accumulated parity.
+// Each data shard must be added exactly once.
+// The data shard is not modified or retained.
+func (v *ParityVerifier) Add(dataShard []byte, idx int) error {
+	if idx < 0 || idx >= len(v.added) {
+		return ErrInvShardNum
+	}
+	if v.added[idx] {
+		return ErrInvalidInput
+	}
+	if len(v.parity) > 0 && len(dataShard) != len(v.parity[0]) {
+		return ErrShardSize
+	}
+	if err := v.enc.EncodeIdx(dataShard, idx, v.parity); err != nil {
+		return err
+	}
+	v.added[idx] = true
+	v.n++
+	return nil
+}
+
+// Verify returns whether parity matches the accumulated parity.
+// parity must contain ParityShards shards.
+// All data shards must have been added, otherwise ErrTooFewShards is returned.
+func (v *ParityVerifier) Verify(parity [][]byte) (bool, error) {
+	if len(parity) != len(v.parity) {
+		return false, ErrTooFewShards
+	}
+	if v.n != len(v.added) {
+		return false, ErrTooFewShards
+	}
+	for i, p := range parity {
+		if len(p) != len(v.parity[i]) {
+			return false, ErrShardSize
+		}
+	}
+	for i, p := range parity {
+		if !bytes.Equal(p, v.parity[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Reset clears the accumulated parity, so the verifier can be used for another stripe.
+func (v *ParityVerifier) Reset() {
+	for _, p := range v.parity {
+		for i := range p {
+			p[i] = 0
+		}
+	}
+	for i := range v.added {
+		v.added[i] = false
+	}
+	v.n = 0
+}
@@ -0,0 +1,57 @@
+package reedsolomon
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParityVerifier(t *testing.T) {
+	enc, err := New(10, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewParityVerifier(enc, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for round := 0; round < 2; round++ {
+		for _, idx := range rand.Perm(10) {
+			if ok, err := v.Verify(shards[10:]); ok || err != ErrTooFewShards {
+				t.Fatalf("want ErrTooFewShards, got %v %v", ok, err)
+			}
+			if err := v.Add(shards[idx], idx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := v.Add(shards[3], 3); err != ErrInvalidInput {
+			t.Errorf("want ErrInvalidInput, got %v", err)
+		}
+		ok, err := v.Verify(shards[10:])
+		if err != nil || !ok {
+			t.Fatalf("verification failed: %v", err)
+		}
+		shards[12][999] ^= 1
+		if ok, err := v.Verify(shards[10:]); ok || err != nil {
+			t.Fatalf("damage not found: %v", err)
+		}
+		shards[12][999] ^= 1
+		v.Reset()
+	}
+
+	if err := v.Add(shards[0][:999], 0); err != ErrShardSize {
+		t.Errorf("want ErrShardSize, got %v", err)
+	}
+	if err := v.Add(shards[0], 10); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+	if _, err := v.Verify(shards[11:]); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+}
@@ -0,0 +1,428 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+)
+
+// piggybackCodec is a piggybacked Reed-Solomon code.
+// Construct using New with WithPiggyback.
+//
+// Each shard is split into two halves, a and b, which are encoded as
+// two separate Reed-Solomon stripes.
+// The data shards are divided into parityShards-1 groups, and the a halves
+// of group j-1 are added to the b half of parity shard j.
+// Since the a stripe is unmodified, it can always be decoded first,
+// after which the piggybacks can be removed to decode the b stripe,
+// so the code is still MDS.
+//
+// A lost data shard can be repaired by decoding its b half from the other
+// b halves and the first parity shard, and then recovering its a half from
+// the piggyback of its group, which only requires the a halves of the
+// other shards in the group.
+type piggybackCodec struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	inner Encoder // Encoder for each half.
+	o     options
+}
+
+// newPiggyback is like New, but for piggybacked Reed-Solomon.
+func newPiggyback(dataShards, parityShards int, opt options) (*piggybackCodec, error) {
+	inner, err := New(dataShards, parityShards, func(o *options) {
+		*o = opt
+		o.usePiggyback = false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &piggybackCodec{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		inner:        inner,
+		o:            opt,
+	}, nil
+}
+
+var _ = Extensions(&piggybackCodec{})
+
+// group returns the group of data shard i.
+// Group g is piggybacked on parity shard g+1.
+// There are no groups with fewer than 2 parity shards.
+func (r *piggybackCodec) group(i int) int {
+	return i * (r.parityShards - 1) / r.dataShards
+}
+
+// piggyback adds the a halves of the data shards piggybacked on parity shard j to dst.
+func (r *piggybackCodec) piggyback(a [][]byte, j int, dst []byte) {
+	for i := range a[:r.dataShards] {
+		if r.group(i)+1 == j {
+			sliceXor(a[i], dst, &r.o)
+		}
+	}
+}
+
+// splitHalves returns the a and b halves of the shards.
+// Missing shards are returned as zero length slices with the capacity of the half.
+func splitHalves(shards [][]byte, size int) (a, b [][]byte) {
+	h := size / 2
+	a, b = make([][]byte, len(shards)), make([][]byte, len(shards))
+	for i, s := range shards {
+		if len(s) == 0 {
+			if cap(s) >= size {
+				a[i], b[i] = s[:0:h], s[h:h:size]
+			}
+			continue
+		}
+		a[i], b[i] = s[:h:h], s[h:size:size]
+	}
+	return a, b
+}
+
+func (r *piggybackCodec) ShardSizeMultiple() int {
+	return 2 * r.inner.(Extensions).ShardSizeMultiple()
+}
+
+func (r *piggybackCodec) DataShards() int {
+	return r.dataShards
+}
+
+func (r *piggybackCodec) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *piggybackCodec) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *piggybackCodec) AllocAligned(each int) [][]byte {
+	return r.inner.(Extensions).AllocAligned(each)
+}
+
+func (r *piggybackCodec) PureGo() bool {
+	return r.inner.(PureGoer).PureGo()
+}
+
+func (r *piggybackCodec) Recalibrate(shardSize int) {
+	r.inner.(Recalibrator).Recalibrate(shardSize / 2)
+}
+
+// CostModel returns the estimated costs for the encoder.
+// Reconstruction costs are for repairing a single shard with RepairShard.
+func (r *piggybackCodec) CostModel() CostModel {
+	c := r.inner.(CostModeler).CostModel()
+	if r.parityShards < 2 {
+		return c
+	}
+	d, p := float64(r.dataShards), float64(r.parityShards)
+	// Each data byte is added to one parity b half.
+	c.EncodeBytesPerByte += 0.5
+	c.EncodeMulsPerByte += 0.5
+
+	// Data shards read k b halves, one parity b half and the rest of their group.
+	group := d / (p - 1)
+	dataMoved := (d+group)/2 + 1
+	dataMuls := (d+p)/2 + group/2
+	c.ReconstructBytesPerByte = (dataMoved*d + c.ReconstructBytesPerByte*p) / (d + p)
+	c.ReconstructMulsPerByte = (dataMuls*d + c.ReconstructMulsPerByte*p) / (d + p)
+	return c
+}
+
+func (r *piggybackCodec) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	size := len(shards[0])
+	if size%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	a, b := splitHalves(shards, size)
+	if err := r.inner.Encode(a); err != nil {
+		return err
+	}
+	if err := r.inner.Encode(b); err != nil {
+		return err
+	}
+	for j := 1; j < r.parityShards; j++ {
+		r.piggyback(a, j, b[r.dataShards+j])
+	}
+	return nil
+}
+
+// EncodeIdx will add parity for a single data shard.
+// Parity shards should start out zeroed. The caller must zero them before first call.
+// Data shards should only be delivered once. There is no check for this.
+func (r *piggybackCodec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	if len(parity) != r.parityShards {
+		return ErrTooFewShards
+	}
+	if len(parity) == 0 {
+		return nil
+	}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
+	}
+	if err := checkShards(parity, false); err != nil {
+		return err
+	}
+	size := len(dataShard)
+	if len(parity[0]) != size {
+		return ErrShardSize
+	}
+	if size%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	a, b := splitHalves(parity, size)
+	if err := r.inner.EncodeIdx(dataShard[:size/2], idx, a); err != nil {
+		return err
+	}
+	if err := r.inner.EncodeIdx(dataShard[size/2:], idx, b); err != nil {
+		return err
+	}
+	if r.parityShards > 1 {
+		sliceXor(dataShard[:size/2], b[r.group(idx)+1], &r.o)
+	}
+	return nil
+}
+
+func (r *piggybackCodec) Update(shards [][]byte, newDatashards [][]byte) error {
+	if len(shards) != r.totalShards || len(newDatashards) != r.dataShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	if err := checkShards(newDatashards, true); err != nil {
+		return err
+	}
+	size := shardSize(shards)
+	if size%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	for i := range newDatashards {
+		if newDatashards[i] != nil && shards[i] == nil {
+			return ErrInvalidInput
+		}
+	}
+	for _, p := range shards[r.dataShards:] {
+		if p == nil {
+			return ErrInvalidInput
+		}
+	}
+	a, b := splitHalves(shards, size)
+	newA, newB := make([][]byte, r.dataShards), make([][]byte, r.dataShards)
+	for i, s := range newDatashards {
+		if s == nil {
+			continue
+		}
+		newA[i], newB[i] = s[:size/2], s[size/2:]
+		// Update the piggyback before the old data is modified.
+		if r.parityShards > 1 {
+			dst := b[r.dataShards+r.group(i)+1]
+			sliceXor(a[i], dst, &r.o)
+			sliceXor(newA[i], dst, &r.o)
+		}
+	}
+	if err := r.inner.Update(a, newA); err != nil {
+		return err
+	}
+	return r.inner.Update(b, newB)
+}
+
+func (r *piggybackCodec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	shardSize := len(shards[0])
+	outputs := make([][]byte, r.totalShards)
+	copy(outputs, shards[:r.dataShards])
+	for i := r.dataShards; i < r.totalShards; i++ {
+		outputs[i] = make([]byte, shardSize)
+	}
+	if err := r.Encode(outputs); err != nil {
+		return false, err
+	}
+
+	// Compare.
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !bytes.Equal(outputs[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *piggybackCodec) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+func (r *piggybackCodec) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *piggybackCodec) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards.
+// If required is non-nil, only shards marked as required are recreated.
+func (r *piggybackCodec) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	size := shardSize(shards)
+	if size%2 != 0 {
+		return ErrInvalidShardSize
+	}
+	isRequired := func(i int) bool {
+		return len(shards[i]) == 0 && (i < r.dataShards || !dataOnly) && (required == nil || required[i])
+	}
+
+	// Missing parity shards are only decoded when required,
+	// while all data shards are needed to compute the piggybacks.
+	full := make([][]byte, r.totalShards)
+	rebuilt := make([]bool, r.totalShards)
+	present, want := 0, false
+	for i := range shards {
+		switch {
+		case len(shards[i]) != 0:
+			full[i] = shards[i]
+			present++
+		case isRequired(i):
+			if cap(shards[i]) >= size {
+				shards[i] = shards[i][:size]
+			} else {
+				shards[i] = AllocAligned(1, size)[0]
+			}
+			full[i] = shards[i][:0]
+			rebuilt[i], want = true, true
+		case i < r.dataShards:
+			full[i] = make([]byte, 0, size)
+			rebuilt[i] = true
+		}
+	}
+	if !want {
+		return nil
+	}
+	if present < r.dataShards {
+		return ErrTooFewShards
+	}
+
+	a, b := splitHalves(full, size)
+	if err := r.inner.ReconstructSome(a, rebuilt); err != nil {
+		return err
+	}
+	for j := 1; j < r.parityShards; j++ {
+		i := r.dataShards + j
+		if len(b[i]) == 0 {
+			continue
+		}
+		// Remove the piggyback from a copy of the parity.
+		b[i] = append(make([]byte, 0, size/2), b[i]...)
+		r.piggyback(a, j, b[i])
+	}
+	if err := r.inner.ReconstructSome(b, rebuilt); err != nil {
+		return err
+	}
+	for j := 1; j < r.parityShards; j++ {
+		if i := r.dataShards + j; rebuilt[i] {
+			r.piggyback(a, j, b[i])
+		}
+	}
+	return nil
+}
+
+// repairPlan returns the plan for repairing data shard i using the piggyback.
+// nil is returned if the piggyback cannot be used.
+func (r *piggybackCodec) repairPlan(i int) *RepairPlan {
+	if i >= r.dataShards || r.parityShards < 3 {
+		return nil
+	}
+	p := &RepairPlan{Shard: i, SubChunkCount: 2}
+	j := r.dataShards + r.group(i) + 1
+	for h := 0; h < r.totalShards; h++ {
+		switch {
+		case h == i:
+		case h < r.dataShards && r.group(h) == r.group(i):
+			p.Helpers = append(p.Helpers, h)
+			p.HelperSubChunks = append(p.HelperSubChunks, []int{0, 1})
+		case h <= r.dataShards || h == j:
+			p.Helpers = append(p.Helpers, h)
+			p.HelperSubChunks = append(p.HelperSubChunks, []int{1})
+		}
+	}
+	return p
+}
+
+// repair recreates data shard i from the sub-chunks given by repairPlan.
+func (r *piggybackCodec) repair(i int, fetched [][]byte, h int) ([]byte, error) {
+	out := AllocAligned(1, 2*h)[0]
+	j := r.dataShards + r.group(i) + 1
+
+	// Decode the b half from the other data shards and the first parity shard.
+	b := make([][]byte, r.totalShards)
+	for n := 0; n <= r.dataShards; n++ {
+		switch {
+		case n == i:
+			b[n] = out[h : h : 2*h]
+		case n < r.dataShards && r.group(n) == r.group(i):
+			b[n] = fetched[n][h:]
+		default:
+			b[n] = fetched[n]
+		}
+	}
+	required := make([]bool, r.totalShards)
+	required[i] = true
+	if err := r.inner.ReconstructSome(b, required); err != nil {
+		return nil, err
+	}
+
+	// Compute the unpiggybacked b half of parity j and
+	// subtract it to get the piggyback.
+	b[r.dataShards] = nil
+	required[i], required[j] = false, true
+	if err := r.inner.ReconstructSome(b, required); err != nil {
+		return nil, err
+	}
+	a := out[:h]
+	copy(a, fetched[j])
+	sliceXor(b[j], a, &r.o)
+	for n := 0; n < r.dataShards; n++ {
+		if n != i && r.group(n) == r.group(i) {
+			sliceXor(fetched[n][:h], a, &r.o)
+		}
+	}
+	return out, nil
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+// Shards are padded with zeros to a multiple of ShardSizeMultiple.
+func (r *piggybackCodec) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, r.ShardSizeMultiple(), r.o.zeroCopySplit, false)
+}
+
+func (r *piggybackCodec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
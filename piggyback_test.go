@@ -0,0 +1,132 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestPiggyback(t *testing.T) {
+	for _, test := range []struct{ data, parity int }{
+		{1, 1}, {4, 2}, {6, 3}, {10, 4}, {3, 0},
+	} {
+		enc, err := New(test.data, test.parity, testOptions(WithPiggyback(true))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(1000)
+		for _, shard := range shards[:test.data] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := enc.Verify(shards)
+		if err != nil || !ok {
+			t.Fatalf("%d+%d: verification failed: %v", test.data, test.parity, err)
+		}
+
+		// Lose up to parity shards.
+		rng := rand.New(rand.NewSource(int64(test.data*100 + test.parity)))
+		for iter := 0; iter < 20; iter++ {
+			damaged := make([][]byte, len(shards))
+			copy(damaged, shards)
+			for _, i := range rng.Perm(len(shards))[:rng.Intn(test.parity+1)] {
+				damaged[i] = nil
+			}
+			if err := enc.Reconstruct(damaged); err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(damaged[i], shards[i]) {
+					t.Fatalf("%d+%d: shard %d mismatch", test.data, test.parity, i)
+				}
+			}
+		}
+
+		size := len(shards[0])
+		if test.parity == 0 {
+			if _, err := PlanRepair(enc, 0); err != ErrTooFewShards {
+				t.Errorf("want ErrTooFewShards, got %v", err)
+			}
+			continue
+		}
+
+		// Repair each shard using the plan.
+		for i := range shards {
+			plan, err := PlanRepair(enc, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i < test.data && test.parity >= 3 && plan.ReadSize(size) >= test.data*size {
+				t.Fatalf("%d+%d: shard %d reads %d bytes", test.data, test.parity, i, plan.ReadSize(size))
+			}
+			fetched := make([][]byte, len(shards))
+			for n, h := range plan.Helpers {
+				for _, rg := range plan.Ranges(n, size) {
+					fetched[h] = append(fetched[h], shards[h][rg[0]:rg[1]]...)
+				}
+			}
+			got, err := RepairShard(enc, plan, fetched)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, shards[i]) {
+				t.Fatalf("%d+%d: repaired shard %d mismatch", test.data, test.parity, i)
+			}
+		}
+
+		// EncodeIdx and Update must give the same parity as Encode.
+		parity := AllocAligned(test.parity, size)
+		for i, shard := range shards[:test.data] {
+			if err := enc.EncodeIdx(shard, i, parity); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := range parity {
+			if !bytes.Equal(parity[i], shards[test.data+i]) {
+				t.Fatalf("%d+%d: EncodeIdx parity %d mismatch", test.data, test.parity, i)
+			}
+		}
+		newData := make([][]byte, test.data)
+		newData[0] = make([]byte, size)
+		fillRandom(newData[0])
+		old := make([][]byte, len(shards))
+		for i := range shards {
+			old[i] = append([]byte{}, shards[i]...)
+		}
+		if err := enc.Update(old, newData); err != nil {
+			t.Fatal(err)
+		}
+		copy(shards[0], newData[0])
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		for i := test.data; i < len(shards); i++ {
+			if !bytes.Equal(old[i], shards[i]) {
+				t.Fatalf("%d+%d: Update parity %d mismatch", test.data, test.parity, i)
+			}
+		}
+	}
+}
+
+func TestPiggybackRepairSavings(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithPiggyback(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 1000
+	for i := 0; i < 10; i++ {
+		plan, err := PlanRepair(enc, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 10 data shards in groups of 3 or 4, so at most 14 of 20 halves are read.
+		if got := plan.ReadSize(size); got > 14*size/2 {
+			t.Errorf("shard %d: reading %d bytes", i, got)
+		}
+	}
+	if _, err := New(4, 2, WithPiggyback(true), WithClayCode(true)); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package reedsolomon
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPlacementUnsafe is returned when a placement does not
+// achieve the requested fault tolerance.
+var ErrPlacementUnsafe = errors.New("placement does not achieve requested fault tolerance")
+
+// FailureDomain describes where a shard is stored.
+// Racks are identified within their zone, and hosts within their rack.
+type FailureDomain struct {
+	Zone string
+	Rack string
+	Host string
+}
+
+// DomainLevel is a level of failure domains.
+type DomainLevel int
+
+const (
+	// DomainHost treats each host as a failure domain.
+	DomainHost DomainLevel = iota
+	// DomainRack treats each rack as a failure domain.
+	DomainRack
+	// DomainZone treats each zone as a failure domain.
+	DomainZone
+)
+
+// String returns the name of the level.
+func (l DomainLevel) String() string {
+	switch l {
+	case DomainHost:
+		return "host"
+	case DomainRack:
+		return "rack"
+	case DomainZone:
+		return "zone"
+	}
+	return fmt.Sprintf("DomainLevel(%d)", int(l))
+}
+
+// at returns the domain d belongs to at level l.
+func (d FailureDomain) at(l DomainLevel) FailureDomain {
+	switch l {
+	case DomainZone:
+		return FailureDomain{Zone: d.Zone}
+	case DomainRack:
+		return FailureDomain{Zone: d.Zone, Rack: d.Rack}
+	}
+	return d
+}
+
+// Placement assigns a failure domain to each shard, in shard order.
+type Placement []FailureDomain
+
+// Validate checks that the data can be reconstructed after any
+// combination of the given number of domains fail at the given level.
+//
+// Recovery is checked by actually reconstructing test shards with enc,
+// so layouts with uneven protection, such as locally reconstructible codes
+// created with WithCustomMatrix, are validated correctly.
+//
+// If the placement is unsafe, an error wrapping ErrPlacementUnsafe
+// describing the failing domains is returned.
+func (p Placement) Validate(enc Encoder, level DomainLevel, failures int) error {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return ErrNotSupported
+	}
+	if len(p) != ext.TotalShards() {
+		return ErrTooFewShards
+	}
+	if failures < 0 {
+		return ErrInvalidInput
+	}
+
+	// Group shards by domain.
+	var domains []FailureDomain
+	byDomain := make(map[FailureDomain][]int)
+	for i, d := range p {
+		d = d.at(level)
+		if _, ok := byDomain[d]; !ok {
+			domains = append(domains, d)
+		}
+		byDomain[d] = append(byDomain[d], i)
+	}
+	if failures > len(domains) {
+		failures = len(domains)
+	}
+
+	// Create a test shard set to reconstruct from.
+	shards := ext.AllocAligned(ext.ShardSizeMultiple())
+	for i, shard := range shards[:ext.DataShards()] {
+		fillRandomValues(shard, uint32(i))
+	}
+	if err := enc.Encode(shards); err != nil {
+		return err
+	}
+
+	// Try every combination of failed domains.
+	failed := make([]int, failures)
+	var try func(start, n int) error
+	try = func(start, n int) error {
+		if n == failures {
+			test := make([][]byte, len(shards))
+			copy(test, shards)
+			for _, f := range failed {
+				for _, idx := range byDomain[domains[f]] {
+					test[idx] = nil
+				}
+			}
+			err := enc.ReconstructData(test)
+			if err == nil {
+				for i := range shards[:ext.DataShards()] {
+					if string(test[i]) != string(shards[i]) {
+						err = ErrTooFewShards
+						break
+					}
+				}
+			}
+			if err != nil {
+				lost := make([]FailureDomain, len(failed))
+				for i, f := range failed {
+					lost[i] = domains[f]
+				}
+				return fmt.Errorf("%w: losing %s %v: %v", ErrPlacementUnsafe, level, lost, err)
+			}
+			return nil
+		}
+		for i := start; i < len(domains); i++ {
+			failed[n] = i
+			if err := try(i+1, n+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return try(0, 0)
+}
+
+// fillRandomValues fills b with pseudo-random values derived from seed.
+func fillRandomValues(b []byte, seed uint32) {
+	x := seed*2654435761 | 1
+	for i := range b {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		b[i] = byte(x)
+	}
+}
@@ -0,0 +1,59 @@
+package reedsolomon
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlacementValidate(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two shards per rack, three racks in two zones.
+	p := Placement{
+		{Zone: "a", Rack: "r1", Host: "h1"},
+		{Zone: "a", Rack: "r1", Host: "h2"},
+		{Zone: "a", Rack: "r2", Host: "h1"},
+		{Zone: "a", Rack: "r2", Host: "h2"},
+		{Zone: "b", Rack: "r1", Host: "h1"},
+		{Zone: "b", Rack: "r1", Host: "h2"},
+	}
+	if err := p.Validate(enc, DomainHost, 2); err != nil {
+		t.Error(err)
+	}
+	if err := p.Validate(enc, DomainRack, 1); err != nil {
+		t.Error(err)
+	}
+	if err := p.Validate(enc, DomainRack, 2); !errors.Is(err, ErrPlacementUnsafe) {
+		t.Errorf("expected %v, got %v", ErrPlacementUnsafe, err)
+	}
+	if err := p.Validate(enc, DomainZone, 1); !errors.Is(err, ErrPlacementUnsafe) {
+		t.Errorf("expected %v, got %v", ErrPlacementUnsafe, err)
+	}
+	if err := p[:5].Validate(enc, DomainHost, 1); err != ErrTooFewShards {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+
+	// LRC: two local groups, each with a local XOR parity.
+	lrc, err := New(4, 2, WithCustomMatrix([][]byte{
+		{1, 1, 0, 0},
+		{0, 0, 1, 1},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = Placement{
+		{Zone: "a"}, {Zone: "b"}, {Zone: "a"}, {Zone: "b"}, {Zone: "c"}, {Zone: "c"},
+	}
+	if err := p.Validate(lrc, DomainZone, 1); err != nil {
+		t.Error(err)
+	}
+	// Both shards of a local group in the same zone.
+	p = Placement{
+		{Zone: "a"}, {Zone: "a"}, {Zone: "b"}, {Zone: "b"}, {Zone: "c"}, {Zone: "c"},
+	}
+	if err := p.Validate(lrc, DomainZone, 1); !errors.Is(err, ErrPlacementUnsafe) {
+		t.Errorf("expected %v, got %v", ErrPlacementUnsafe, err)
+	}
+}
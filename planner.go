@@ -0,0 +1,221 @@
+package reedsolomon
+
+// ReconstructPlan describes the shards that must be read to reconstruct missing shards.
+// It can be used to schedule fetches before calling Reconstruct or ReconstructSome.
+type ReconstructPlan struct {
+	// Missing contains the shards that will be reconstructed, in increasing order.
+	Missing []int
+
+	// Sources contains the shards to read, in increasing order.
+	Sources []int
+
+	// Off and End give the byte range [Off, End) to read from each source.
+	// Reconstructing that range of the sources gives the same range of the missing shards.
+	Off, End int64
+
+	// ReadBytes is the total number of bytes to read from all sources.
+	ReadBytes int64
+
+	// Muls is the estimated number of multiplications needed, as defined by CostModel.
+	Muls float64
+}
+
+// PlanReconstruct returns the shards to read to reconstruct the required shards
+// of shardSize bytes, when the shards marked in present are available.
+//
+// If required is nil, all missing shards are planned, as done by Reconstruct.
+// Otherwise only required shards that are not present are reconstructed,
+// as done by ReconstructSome.
+// If no shards are missing, a plan without sources is returned.
+//
+// Passing the sources, with other shards nil, to ReconstructSome
+// reconstructs the missing shards.
+// To repair a single shard of encoders created with WithClayCode or WithPiggyback,
+// PlanRepair will usually read less.
+//
+// If too few shards are present, ErrTooFewShards is returned.
+func PlanReconstruct(enc Encoder, present, required []bool, shardSize int64) (*ReconstructPlan, error) {
+	ext, err := planEncoder(enc, present)
+	if err != nil {
+		return nil, err
+	}
+	if required != nil && len(required) != ext.TotalShards() {
+		return nil, ErrInvalidInput
+	}
+	if shardSize <= 0 || shardSize%int64(ext.ShardSizeMultiple()) != 0 {
+		return nil, ErrInvalidShardSize
+	}
+	var missing []int
+	for i, ok := range present {
+		if !ok && (required == nil || required[i]) {
+			missing = append(missing, i)
+		}
+	}
+	return planSources(enc, ext, present, missing, 0, shardSize)
+}
+
+// PlanReconstructRange returns the shards and byte ranges to read to reconstruct
+// length bytes at off of a missing shard of shardSize bytes,
+// when the shards marked in present are available.
+//
+// The range is expanded to a multiple of ShardSizeMultiple.
+// Encoders where ranges cannot be reconstructed independently,
+// such as WithClayCode and WithPiggyback, read entire shards.
+// Reconstructing the returned range of the sources with ReconstructSome
+// gives the same range of the missing shard.
+// If the shard is present, a plan without sources is returned.
+//
+// If too few shards are present, ErrTooFewShards is returned.
+// If the range is outside the shard, ErrInvalidInput is returned.
+func PlanReconstructRange(enc Encoder, present []bool, shard int, off, length, shardSize int64) (*ReconstructPlan, error) {
+	ext, err := planEncoder(enc, present)
+	if err != nil {
+		return nil, err
+	}
+	if shard < 0 || shard >= ext.TotalShards() {
+		return nil, ErrInvShardNum
+	}
+	if shardSize <= 0 || shardSize%int64(ext.ShardSizeMultiple()) != 0 {
+		return nil, ErrInvalidShardSize
+	}
+	if off < 0 || length < 0 || off+length > shardSize {
+		return nil, ErrInvalidInput
+	}
+	if present[shard] || length == 0 {
+		return &ReconstructPlan{Off: off, End: off}, nil
+	}
+	end := off + length
+	if migratable(enc) {
+		align := int64(ext.ShardSizeMultiple())
+		off -= off % align
+		end += (align - end%align) % align
+	} else {
+		off, end = 0, shardSize
+	}
+	return planSources(enc, ext, present, []int{shard}, off, end)
+}
+
+// planEncoder checks that enc can be planned for the present shards.
+func planEncoder(enc Encoder, present []bool) (Extensions, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	switch enc.(type) {
+	case *clayCodec, *piggybackCodec:
+	default:
+		if !migratable(enc) {
+			return nil, ErrNotSupported
+		}
+	}
+	if len(present) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	return ext, nil
+}
+
+// planSources returns a plan reading [off, end) of the first DataShards present shards
+// to reconstruct missing.
+func planSources(enc Encoder, ext Extensions, present []bool, missing []int, off, end int64) (*ReconstructPlan, error) {
+	p := &ReconstructPlan{Missing: missing, Off: off, End: end}
+	if len(missing) == 0 {
+		p.End = off
+		return p, nil
+	}
+	for i, ok := range present {
+		if ok && len(p.Sources) < ext.DataShards() {
+			p.Sources = append(p.Sources, i)
+		}
+	}
+	if len(p.Sources) < ext.DataShards() {
+		return nil, ErrTooFewShards
+	}
+	size := end - off
+	p.ReadBytes = int64(len(p.Sources)) * size
+
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		if cm, ok := enc.(CostModeler); ok {
+			p.Muls = cm.CostModel().ReconstructMulsPerByte * float64(int64(len(missing))*size)
+		}
+		return p, nil
+	}
+
+	// Custom matrices may not be invertible for the chosen sources.
+	shards := make([][]byte, r.totalShards)
+	for _, i := range p.Sources {
+		shards[i] = []byte{0}
+	}
+	if _, _, err := r.decodeMatrix(shards); err != nil {
+		return nil, err
+	}
+
+	// Each output is coded from DataShards inputs.
+	// Missing data shards are also recreated when parity is needed.
+	outputs := len(missing)
+	if missing[len(missing)-1] >= r.dataShards {
+		outputs = 0
+		for _, i := range missing {
+			if i >= r.dataShards {
+				outputs++
+			}
+		}
+		for _, ok := range present[:r.dataShards] {
+			if !ok {
+				outputs++
+			}
+		}
+	}
+	p.Muls = float64(int64(outputs*r.dataShards) * size)
+	return p, nil
+}
+
+// ReconstructSources reconstructs missing shards like Reconstruct,
+// or like ReconstructData if dataOnly is set, and returns the indexes
+// of the shards that were read, in increasing order.
+//
+// Only the sources given by PlanReconstruct are passed to the encoder,
+// so other present shards are not read, and their buffers can be released
+// once this returns. If no shards are missing, nil is returned.
+func ReconstructSources(enc Encoder, shards [][]byte, dataOnly bool) ([]int, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return nil, err
+	}
+	present := make([]bool, len(shards))
+	required := make([]bool, len(shards))
+	for i, shard := range shards {
+		present[i] = len(shard) != 0
+		required[i] = !dataOnly || i < ext.DataShards()
+	}
+	plan, err := PlanReconstruct(enc, present, required, int64(shardSize(shards)))
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Missing) == 0 {
+		return nil, nil
+	}
+
+	work := make([][]byte, len(shards))
+	for _, i := range plan.Sources {
+		work[i] = shards[i]
+	}
+	required = make([]bool, len(shards))
+	for _, i := range plan.Missing {
+		work[i] = shards[i]
+		required[i] = true
+	}
+	if err := enc.ReconstructSome(work, required); err != nil {
+		return nil, err
+	}
+	for _, i := range plan.Missing {
+		shards[i] = work[i]
+	}
+	return plan.Sources, nil
+}
@@ -0,0 +1,168 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPlanReconstruct(t *testing.T) {
+	for _, opts := range [][]Option{
+		testOptions(),
+		{WithLeopardGF16(true)},
+		{WithClayCode(true)},
+	} {
+		enc, err := New(6, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mult := int64(enc.(Extensions).ShardSizeMultiple())
+		size := (6000 + mult - 1) / mult * mult
+		shards := enc.(Extensions).AllocAligned(int(size))
+		for _, shard := range shards[:6] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		present := []bool{true, false, true, true, false, true, true, false, true}
+		plan, err := PlanReconstruct(enc, present, nil, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.Missing) != 3 || len(plan.Sources) != 6 || plan.ReadBytes != 6*size || plan.Muls <= 0 {
+			t.Fatalf("unexpected plan: %+v", plan)
+		}
+
+		// Only the sources are needed.
+		got := make([][]byte, len(shards))
+		for _, i := range plan.Sources {
+			got[i] = shards[i]
+		}
+		if err := enc.Reconstruct(got); err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range plan.Missing {
+			if !bytes.Equal(got[i], shards[i]) {
+				t.Fatalf("shard %d mismatch", i)
+			}
+		}
+
+		// A range reads only the range of the sources, when possible.
+		plan, err = PlanReconstructRange(enc, present, 4, 100, 200, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan.End-plan.Off != size && (plan.Off > 100 || plan.End < 300 || plan.ReadBytes != 6*(plan.End-plan.Off)) {
+			t.Fatalf("unexpected range plan: %+v", plan)
+		}
+		sub := make([][]byte, len(shards))
+		for _, i := range plan.Sources {
+			sub[i] = shards[i][plan.Off:plan.End]
+		}
+		required := make([]bool, len(shards))
+		required[4] = true
+		if err := enc.ReconstructSome(sub, required); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(sub[4], shards[4][plan.Off:plan.End]) {
+			t.Fatal("range mismatch")
+		}
+
+		plan, err = PlanReconstruct(enc, present, required, size)
+		if err != nil || len(plan.Missing) != 1 {
+			t.Fatalf("unexpected plan: %+v %v", plan, err)
+		}
+		plan, err = PlanReconstructRange(enc, present, 0, 0, 10, size)
+		if err != nil || len(plan.Sources) != 0 || plan.ReadBytes != 0 {
+			t.Fatalf("unexpected plan: %+v %v", plan, err)
+		}
+		present[0] = false
+		if _, err := PlanReconstruct(enc, present, nil, size); err != ErrTooFewShards {
+			t.Errorf("want ErrTooFewShards, got %v", err)
+		}
+		if _, err := PlanReconstructRange(enc, present, 1, size-10, 11, size); err != ErrInvalidInput {
+			t.Errorf("want ErrInvalidInput, got %v", err)
+		}
+	}
+}
+
+func TestPlanReconstructCost(t *testing.T) {
+	enc, err := New(10, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	present := make([]bool, 14)
+	for i := range present {
+		present[i] = true
+	}
+	present[3], present[12] = false, false
+
+	// Data only.
+	required := make([]bool, 14)
+	required[3] = true
+	plan, err := PlanReconstruct(enc, present, required, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Muls != 10*1000 {
+		t.Fatalf("muls %v", plan.Muls)
+	}
+	// Parity requires the missing data shard as well.
+	plan, err = PlanReconstruct(enc, present, nil, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Muls != 2*10*1000 {
+		t.Fatalf("muls %v", plan.Muls)
+	}
+	want := []int{0, 1, 2, 4, 5, 6, 7, 8, 9, 10}
+	for i, s := range plan.Sources {
+		if s != want[i] {
+			t.Fatalf("sources %v, want %v", plan.Sources, want)
+		}
+	}
+}
+
+func TestReconstructSources(t *testing.T) {
+	for _, opts := range [][]Option{
+		testOptions(),
+		{WithLeopardGF16(true)},
+		{WithClayCode(true)},
+	} {
+		enc, err := New(6, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(27 * 64 * 4)
+		for _, shard := range shards[:6] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		got := make([][]byte, len(shards))
+		copy(got, shards)
+		got[0], got[4], got[7] = nil, nil, nil
+		used, err := ReconstructSources(enc, got, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(used) != 6 || used[0] != 1 || used[5] != 8 {
+			t.Fatalf("used %v", used)
+		}
+		if !bytes.Equal(got[0], shards[0]) || !bytes.Equal(got[4], shards[4]) || got[7] != nil {
+			t.Fatal("data shards not reconstructed")
+		}
+
+		used, err = ReconstructSources(enc, got, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(used) != 6 || used[5] != 5 || !bytes.Equal(got[7], shards[7]) {
+			t.Fatalf("used %v", used)
+		}
+		if used, err := ReconstructSources(enc, got, false); used != nil || err != nil {
+			t.Fatalf("nothing to do, got %v %v", used, err)
+		}
+	}
+}
@@ -0,0 +1,665 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/maphash"
+	"io"
+	"math/bits"
+)
+
+// ErrInvalidSymbol is returned by encoders using WithPrimeField,
+// if a shard contains a symbol that is not an element of the field.
+var ErrInvalidSymbol = errors.New("symbol is not an element of the field")
+
+const (
+	// PrimeFieldModulus is the modulus of the field used by WithPrimeField.
+	PrimeFieldModulus = 65537
+
+	// primeGenerator generates the multiplicative group of the field.
+	primeGenerator = 3
+
+	// primeMaxShards is the largest evaluation domain with a disjoint coset.
+	primeMaxShards = 1 << 15
+
+	// primeWorkSize is the size in bytes of the work buffers of an operation.
+	primeWorkSize = 1 << 20
+)
+
+// primeCodec is an encoder over the prime field of PrimeFieldModulus.
+// Shard i contains evaluations of polynomials of degree less than dataShards
+// at ω^i, where ω generates the subgroup of size n.
+// Missing evaluations are computed with number theoretic transforms,
+// by dividing by the polynomial vanishing at the missing positions on a coset.
+// Construct using New with WithPrimeField.
+type primeCodec struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	n         int      // Size of the evaluation domain.
+	roots     []uint32 // ω^i, for i < n/2.
+	invRoots  []uint32 // ω^-i, for i < n/2.
+	toCoset   []uint32 // g^i/n, scaling coefficients before evaluating on the coset.
+	fromCoset []uint32 // g^-i/n, scaling coefficients after interpolating on the coset.
+
+	encoding  *primeErasures       // All positions but the data shards erased.
+	states    freeList[primeState] // Scratch state for operations.
+	inversion *leopardCache[uint64, *primeErasures]
+	cacheSeed maphash.Seed
+
+	o options
+}
+
+// primeState is the state of a primeCodec operation.
+type primeState struct {
+	work [][]uint32
+	key  []byte // Inversion cache key.
+}
+
+// primeErasures is the setup for recovering an erasure pattern.
+type primeErasures struct {
+	key       string   // Key returned by cacheKey.
+	zEval     []uint32 // Z(ω^i), where Z vanishes at the erased positions.
+	zCosetInv []uint32 // 1/Z(gω^i).
+}
+
+// size returns the bytes used by e.
+func (e *primeErasures) size() int64 {
+	return int64(len(e.key) + 4*len(e.zEval) + 4*len(e.zCosetInv))
+}
+
+func primeAdd(a, b uint32) uint32 {
+	s := a + b
+	if s >= PrimeFieldModulus {
+		s -= PrimeFieldModulus
+	}
+	return s
+}
+
+func primeSub(a, b uint32) uint32 {
+	if a < b {
+		a += PrimeFieldModulus
+	}
+	return a - b
+}
+
+func primeMul(a, b uint32) uint32 {
+	return uint32(uint64(a) * uint64(b) % PrimeFieldModulus)
+}
+
+func primePow(a uint32, e int) uint32 {
+	res := uint32(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 != 0 {
+			res = primeMul(res, a)
+		}
+		a = primeMul(a, a)
+	}
+	return res
+}
+
+// primeInv returns the multiplicative inverse of a, which must be non-zero.
+func primeInv(a uint32) uint32 {
+	return primePow(a, PrimeFieldModulus-2)
+}
+
+// newPrime is like New, but for the prime field.
+func newPrime(dataShards, parityShards int, opt options) (*primeCodec, error) {
+	if dataShards <= 0 || parityShards < 0 {
+		return nil, ErrInvShardNum
+	}
+	if dataShards+parityShards > primeMaxShards {
+		return nil, ErrMaxShardNum
+	}
+	r := &primeCodec{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		n:            2,
+		o:            opt,
+	}
+	for r.n < r.totalShards {
+		r.n <<= 1
+	}
+	n := r.n
+
+	w := primePow(primeGenerator, (PrimeFieldModulus-1)/n)
+	wInv := primeInv(w)
+	r.roots, r.invRoots = make([]uint32, n/2), make([]uint32, n/2)
+	x, y := uint32(1), uint32(1)
+	for i := range r.roots {
+		r.roots[i], r.invRoots[i] = x, y
+		x, y = primeMul(x, w), primeMul(y, wInv)
+	}
+	gInv := primeInv(primeGenerator)
+	r.toCoset, r.fromCoset = make([]uint32, n), make([]uint32, n)
+	x = primeInv(uint32(n))
+	y = x
+	for i := 0; i < n; i++ {
+		r.toCoset[i], r.fromCoset[i] = x, y
+		x, y = primeMul(x, primeGenerator), primeMul(y, gInv)
+	}
+
+	erased := make([]bool, n)
+	for i := dataShards; i < n; i++ {
+		erased[i] = true
+	}
+	r.encoding = r.erasures(erased)
+
+	if opt.inversionCache {
+		r.inversion = newLeopardCache[uint64](&opt, (*primeErasures).size)
+		r.cacheSeed = maphash.MakeSeed()
+	}
+	if n := opt.keptStates(); n > 0 {
+		r.states.keep(n)
+	}
+	return r, nil
+}
+
+var _ = Extensions(&primeCodec{})
+
+// root returns ω^i.
+func (r *primeCodec) root(i int) uint32 {
+	if i < r.n/2 {
+		return r.roots[i]
+	}
+	// ω^(n/2) = -1
+	return primeSub(0, r.roots[i-r.n/2])
+}
+
+// primeNTT evaluates the polynomial with coefficients a at the powers of the root
+// of unity of order len(a), in place.
+// roots contains the powers of a root of unity of order 2*len(roots),
+// which must be a multiple of len(a).
+// Passing the inverse roots interpolates, with the coefficients scaled by len(a).
+func primeNTT(a []uint32, roots []uint32) {
+	n := len(a)
+	shift := bits.LeadingZeros(uint(n)) + 1
+	for i := range a {
+		if j := int(bits.Reverse(uint(i)) >> shift); i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half, step := size/2, 2*len(roots)/size
+		for start := 0; start < n; start += size {
+			for j := 0; j < half; j++ {
+				u, t := a[start+j], primeMul(a[start+j+half], roots[j*step])
+				a[start+j], a[start+j+half] = primeAdd(u, t), primeSub(u, t)
+			}
+		}
+	}
+}
+
+// primeNTTRows is like primeNTT, but transforms each column of work.
+func primeNTTRows(work [][]uint32, roots []uint32) {
+	n := len(work)
+	shift := bits.LeadingZeros(uint(n)) + 1
+	for i := range work {
+		if j := int(bits.Reverse(uint(i)) >> shift); i < j {
+			work[i], work[j] = work[j], work[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half, step := size/2, 2*len(roots)/size
+		for start := 0; start < n; start += size {
+			for j := 0; j < half; j++ {
+				w := roots[j*step]
+				a, b := work[start+j], work[start+j+half]
+				b = b[:len(a)]
+				for x, u := range a {
+					t := primeMul(b[x], w)
+					a[x], b[x] = primeAdd(u, t), primeSub(u, t)
+				}
+			}
+		}
+	}
+}
+
+// primeScale multiplies the values of row by c.
+func primeScale(row []uint32, c uint32) {
+	for x, v := range row {
+		row[x] = primeMul(v, c)
+	}
+}
+
+// polyMul returns the product of the polynomials a and b,
+// whose degrees must add up to less than n.
+func (r *primeCodec) polyMul(a, b []uint32) []uint32 {
+	res := make([]uint32, len(a)+len(b)-1)
+	if len(a) < 64 || len(b) < 64 {
+		for i, x := range a {
+			for j, y := range b {
+				res[i+j] = primeAdd(res[i+j], primeMul(x, y))
+			}
+		}
+		return res
+	}
+	size := ceilPow2(len(res))
+	fa, fb := make([]uint32, size), make([]uint32, size)
+	copy(fa, a)
+	copy(fb, b)
+	primeNTT(fa, r.roots)
+	primeNTT(fb, r.roots)
+	for i := range fa {
+		fa[i] = primeMul(fa[i], fb[i])
+	}
+	primeNTT(fa, r.invRoots)
+	sizeInv := primeInv(uint32(size))
+	for i := range res {
+		res[i] = primeMul(fa[i], sizeInv)
+	}
+	return res
+}
+
+// erasures returns the setup for recovering the positions of the domain marked in erased.
+func (r *primeCodec) erasures(erased []bool) *primeErasures {
+	// Z is the product of (x - ω^e) for the erased positions,
+	// multiplied in pairs so large products use transforms.
+	var polys [][]uint32
+	for e, ok := range erased {
+		if ok {
+			polys = append(polys, []uint32{primeSub(0, r.root(e)), 1})
+		}
+	}
+	if len(polys) == 0 {
+		polys = append(polys, []uint32{1})
+	}
+	for len(polys) > 1 {
+		next := make([][]uint32, 0, (len(polys)+1)/2)
+		for i := 0; i+1 < len(polys); i += 2 {
+			next = append(next, r.polyMul(polys[i], polys[i+1]))
+		}
+		if len(polys)%2 != 0 {
+			next = append(next, polys[len(polys)-1])
+		}
+		polys = next
+	}
+	z := polys[0]
+
+	e := &primeErasures{zEval: make([]uint32, r.n), zCosetInv: make([]uint32, r.n)}
+	copy(e.zEval, z)
+	primeNTT(e.zEval, r.roots)
+	g := uint32(1)
+	for i, c := range z {
+		e.zCosetInv[i] = primeMul(c, g)
+		g = primeMul(g, primeGenerator)
+	}
+	primeNTT(e.zCosetInv, r.roots)
+	for i, v := range e.zCosetInv {
+		e.zCosetInv[i] = primeInv(v)
+	}
+	return e
+}
+
+// recover sets the erased rows of work to the evaluations of the polynomial
+// of degree less than dataShards through the other rows.
+func (r *primeCodec) recover(work [][]uint32, e *primeErasures) {
+	// Interpolate P*Z, which is zero at the erased positions.
+	for i, row := range work {
+		primeScale(row, e.zEval[i])
+	}
+	primeNTTRows(work, r.invRoots)
+
+	// Divide by Z on the coset, where it has no roots.
+	for i, row := range work {
+		primeScale(row, r.toCoset[i])
+	}
+	primeNTTRows(work, r.roots)
+	for i, row := range work {
+		primeScale(row, e.zCosetInv[i])
+	}
+	primeNTTRows(work, r.invRoots)
+	for i, row := range work {
+		primeScale(row, r.fromCoset[i])
+	}
+
+	// Evaluate P on the domain.
+	primeNTTRows(work, r.roots)
+}
+
+// code computes the shards given in outputs from the shards marked in known,
+// which must be the positions not erased in e.
+// Outputs must have byteCount bytes.
+func (r *primeCodec) code(shards [][]byte, known []bool, e *primeErasures, outputs []int, byteCount int) error {
+	st := r.states.get()
+	defer r.states.put(st)
+
+	symbols := byteCount / 4
+	block := primeWorkSize / 4 / r.n
+	if block < 1 {
+		block = 1
+	}
+	if block > symbols {
+		block = symbols
+	}
+	if len(st.work) != r.n || cap(st.work[0]) < block {
+		st.work = make([][]uint32, r.n)
+		for i := range st.work {
+			st.work[i] = make([]uint32, block)
+		}
+	}
+	work := st.work
+	for start := 0; start < symbols; start += block {
+		end := start + block
+		if end > symbols {
+			end = symbols
+		}
+		for i := range work {
+			row := work[i][:end-start]
+			work[i] = row
+			if i >= len(known) || !known[i] {
+				continue
+			}
+			src := shards[i][4*start : 4*end]
+			for x := range row {
+				v := binary.LittleEndian.Uint32(src[4*x:])
+				if v >= PrimeFieldModulus {
+					return ErrInvalidSymbol
+				}
+				row[x] = v
+			}
+		}
+		r.recover(work, e)
+		for _, o := range outputs {
+			dst := shards[o][4*start : 4*end]
+			for x, v := range work[o] {
+				binary.LittleEndian.PutUint32(dst[4*x:], v)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *primeCodec) ShardSizeMultiple() int {
+	return 4
+}
+
+func (r *primeCodec) DataShards() int {
+	return r.dataShards
+}
+
+func (r *primeCodec) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *primeCodec) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *primeCodec) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+// PureGo always returns true, since the prime field codec has no assembly.
+func (r *primeCodec) PureGo() bool {
+	return true
+}
+
+// Recalibrate does nothing, since the prime field codec does not split work between goroutines.
+func (r *primeCodec) Recalibrate(shardSize int) {}
+
+// CostModel counts a multiplication of a 4 byte symbol as 4 multiplications.
+// Each operation does 4 transforms and 4 scalings of n symbols,
+// for each symbol of the shards.
+func (r *primeCodec) CostModel() CostModel {
+	var c CostModel
+	if r.parityShards == 0 {
+		return c
+	}
+	d, n := float64(r.dataShards), float64(r.n)
+	muls := 4 * (n/2*float64(bits.Len(uint(r.n))-1) + n)
+	moved := 4 * (float64(bits.Len(uint(r.n))) + 1) * 2 * n
+	c.EncodeMulsPerByte = muls / d
+	c.EncodeBytesPerByte = (float64(r.totalShards) + moved) / d
+	c.ReconstructMulsPerByte = muls
+	c.ReconstructBytesPerByte = d + 1 + moved
+	return c
+}
+
+func (r *primeCodec) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	if len(shards[0])%4 != 0 {
+		return ErrInvalidShardSize
+	}
+	if r.parityShards == 0 {
+		return nil
+	}
+	known := make([]bool, r.dataShards)
+	outputs := make([]int, r.parityShards)
+	for i := range known {
+		known[i] = true
+	}
+	for i := range outputs {
+		outputs[i] = r.dataShards + i
+	}
+	return r.code(shards, known, r.encoding, outputs, len(shards[0]))
+}
+
+// EncodeIdx is not supported.
+func (r *primeCodec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	return ErrNotSupported
+}
+
+// Update is not supported.
+func (r *primeCodec) Update(shards [][]byte, newDatashards [][]byte) error {
+	return ErrNotSupported
+}
+
+func (r *primeCodec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	shardSize := len(shards[0])
+	outputs := make([][]byte, r.totalShards)
+	copy(outputs, shards[:r.dataShards])
+	for i := r.dataShards; i < r.totalShards; i++ {
+		outputs[i] = make([]byte, shardSize)
+	}
+	if err := r.Encode(outputs); err != nil {
+		return false, err
+	}
+
+	// Compare.
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !bytes.Equal(outputs[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *primeCodec) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+func (r *primeCodec) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *primeCodec) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards.
+// If required is non-nil, only shards marked as required are recreated.
+func (r *primeCodec) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	shardSize := shardSize(shards)
+	if shardSize%4 != 0 {
+		return ErrInvalidShardSize
+	}
+
+	known := make([]bool, r.totalShards)
+	var outputs []int
+	present := 0
+	for i, shard := range shards {
+		switch {
+		case len(shard) != 0:
+			known[i] = true
+			present++
+		case (i < r.dataShards || !dataOnly) && (required == nil || required[i]):
+			outputs = append(outputs, i)
+		}
+	}
+	if len(outputs) == 0 {
+		return nil
+	}
+	if present < r.dataShards {
+		return ErrTooFewShards
+	}
+
+	e := r.erasuresOf(known)
+	for _, i := range outputs {
+		if cap(shards[i]) >= shardSize {
+			shards[i] = shards[i][:shardSize]
+		} else {
+			shards[i] = AllocAligned(1, shardSize)[0]
+		}
+	}
+	err := r.code(shards, known, e, outputs, shardSize)
+	if err != nil {
+		for _, i := range outputs {
+			shards[i] = shards[i][:0]
+		}
+	}
+	return err
+}
+
+// erasuresOf returns the setup for recovering the shards not marked in known.
+// The setups are cached by erasure pattern if the inversion cache is enabled.
+func (r *primeCodec) erasuresOf(known []bool) *primeErasures {
+	var (
+		st   *primeState
+		hash uint64
+	)
+	if r.inversion != nil {
+		st = r.states.get()
+		defer r.states.put(st)
+		st.key = r.cacheKey(st.key[:0], known)
+		hash = maphash.Bytes(r.cacheSeed, st.key)
+		if e, ok := r.inversion.get(hash); ok && e.key == string(st.key) {
+			return e
+		}
+	}
+	erased := make([]bool, r.n)
+	for i := range erased {
+		erased[i] = i >= len(known) || !known[i]
+	}
+	e := r.erasures(erased)
+	if r.inversion != nil {
+		e.key = string(st.key)
+		r.inversion.put(hash, e)
+	}
+	return e
+}
+
+// cacheKey appends the inversion cache key of an erasure pattern to dst.
+// The key is a bitmap of the missing shards.
+func (r *primeCodec) cacheKey(dst []byte, known []bool) []byte {
+	for i := 0; i < len(known); i += 8 {
+		var b byte
+		for j := i; j < i+8 && j < len(known); j++ {
+			if !known[j] {
+				b |= 1 << (j - i)
+			}
+		}
+		dst = append(dst, b)
+	}
+	return dst
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+// Each 4 byte symbol holds 2 bytes of data, in its low bytes.
+// Split always copies the data.
+func (r *primeCodec) Split(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrShortData
+	}
+	if r.o.zeroCopySplit {
+		return nil, ErrSplitCopy
+	}
+	// Calculate number of symbols per data shard.
+	perShard := (len(data) + 2*r.dataShards - 1) / (2 * r.dataShards)
+	dst := AllocAligned(r.totalShards, perShard*4)
+	for _, shard := range dst[:r.dataShards] {
+		for x := 0; x < perShard && len(data) > 0; x++ {
+			data = data[copy(shard[4*x:4*x+2], data):]
+		}
+	}
+	return dst, nil
+}
+
+// Join the data shards and write the data segment to dst.
+// The data is the 2 low bytes of each symbol, as stored by Split.
+func (r *primeCodec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	// Do we have enough shards?
+	if len(shards) < r.dataShards {
+		return ErrTooFewShards
+	}
+	shards = shards[:r.dataShards]
+
+	// Do we have enough data?
+	size := 0
+	for _, shard := range shards {
+		if shard == nil {
+			return ErrReconstructRequired
+		}
+		size += len(shard) / 2
+
+		// Do we have enough data already?
+		if size >= outSize {
+			break
+		}
+	}
+	if size < outSize {
+		return ErrShortData
+	}
+
+	// Copy data to dst
+	write := outSize
+	var buf []byte
+	for _, shard := range shards {
+		if write == 0 {
+			break
+		}
+		buf = buf[:0]
+		for x := 0; x+4 <= len(shard) && len(buf) < write; x += 4 {
+			buf = append(buf, shard[x], shard[x+1])
+		}
+		if len(buf) > write {
+			buf = buf[:write]
+		}
+		n, err := dst.Write(buf)
+		if err != nil {
+			return err
+		}
+		write -= n
+	}
+	return nil
+}
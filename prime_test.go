@@ -0,0 +1,162 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+)
+
+// primeEval returns the polynomial with coefficients c evaluated at x.
+func primeEval(c []uint32, x uint32) uint32 {
+	var v uint32
+	for i := len(c) - 1; i >= 0; i-- {
+		v = primeAdd(primeMul(v, x), c[i])
+	}
+	return v
+}
+
+func TestPrimeFieldEvaluations(t *testing.T) {
+	for _, sz := range [][2]int{{1, 1}, {3, 2}, {4, 4}, {10, 6}, {100, 28}, {200, 57}, {300, 100}} {
+		dataShards, parityShards := sz[0], sz[1]
+		const symbols = 50
+		enc, err := New(dataShards, parityShards, testOptions(WithPrimeField())...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := enc.(*primeCodec)
+		rng := rand.New(rand.NewSource(int64(dataShards)))
+
+		// Data shards are evaluations of polynomials of degree < dataShards,
+		// so parity shards must be evaluations of the same polynomials.
+		coeffs := make([][]uint32, symbols)
+		for x := range coeffs {
+			coeffs[x] = make([]uint32, dataShards)
+			for i := range coeffs[x] {
+				coeffs[x][i] = uint32(rng.Intn(PrimeFieldModulus))
+			}
+		}
+		shards := enc.(Extensions).AllocAligned(symbols * 4)
+		for i, shard := range shards[:dataShards] {
+			for x := range coeffs {
+				binary.LittleEndian.PutUint32(shard[4*x:], primeEval(coeffs[x], r.root(i)))
+			}
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		w := primePow(primeGenerator, (PrimeFieldModulus-1)/r.n)
+		for i := dataShards; i < len(shards); i++ {
+			for x := range coeffs {
+				want := primeEval(coeffs[x], primePow(w, i))
+				if got := binary.LittleEndian.Uint32(shards[i][4*x:]); got != want {
+					t.Fatalf("%v: shard %d symbol %d: got %d, want %d", sz, i, x, got, want)
+				}
+			}
+		}
+		ok, err := enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+	}
+}
+
+func TestPrimeField(t *testing.T) {
+	const dataShards, parityShards, shardSize = 20, 12, 4 * 1000
+	enc, err := New(dataShards, parityShards, testOptions(WithPrimeField())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 33333)
+	fillRandom(data)
+	shards, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(0))
+	for iter := 0; iter < 20; iter++ {
+		lost := rng.Perm(dataShards + parityShards)[:1+rng.Intn(parityShards)]
+		test := make([][]byte, len(shards))
+		copy(test, shards)
+		for _, i := range lost {
+			test[i] = nil
+		}
+		if err := enc.Reconstruct(test); err != nil {
+			t.Fatal(lost, err)
+		}
+		for i := range shards {
+			if !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("lost %v: shard %d not reconstructed", lost, i)
+			}
+		}
+	}
+
+	test := make([][]byte, len(shards))
+	copy(test, shards)
+	test[0], test[5], test[dataShards+3] = nil, nil, nil
+	if err := enc.ReconstructData(test); err != nil {
+		t.Fatal(err)
+	}
+	if test[dataShards+3] != nil {
+		t.Fatal("ReconstructData recreated parity")
+	}
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, test, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+
+	// Repeated patterns use the cache.
+	for i := 0; i < 2; i++ {
+		copy(test, shards)
+		test[1] = nil
+		if err := enc.ReconstructData(test); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err := InversionCacheStatsOf(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Hits == 0 {
+		t.Errorf("no cache hits: %+v", stats)
+	}
+
+	copy(test, shards)
+	for _, i := range rng.Perm(dataShards + parityShards)[:parityShards+1] {
+		test[i] = nil
+	}
+	if err := enc.Reconstruct(test); err != ErrTooFewShards {
+		t.Fatalf("expected ErrTooFewShards, got %v", err)
+	}
+}
+
+func TestPrimeFieldInvalid(t *testing.T) {
+	enc, err := New(4, 2, WithPrimeField())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(8)
+	binary.LittleEndian.PutUint32(shards[2][4:], PrimeFieldModulus)
+	if err := enc.Encode(shards); err != ErrInvalidSymbol {
+		t.Errorf("expected ErrInvalidSymbol, got %v", err)
+	}
+	if err := enc.Encode(enc.(Extensions).AllocAligned(6)); err != ErrInvalidShardSize {
+		t.Errorf("expected ErrInvalidShardSize, got %v", err)
+	}
+	if err := enc.Update(shards, make([][]byte, 4)); err != ErrNotSupported {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+	if _, err := New(30000, 2769, WithPrimeField()); err != ErrMaxShardNum {
+		t.Errorf("expected ErrMaxShardNum, got %v", err)
+	}
+	if _, err := New(4, 2, WithPrimeField(), WithLeopardGF(true)); err != ErrNotSupported {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
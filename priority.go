@@ -0,0 +1,348 @@
+package reedsolomon
+
+import "io"
+
+// PriorityClass is a group of data shards protected by its own parity shards.
+// See WithPriorityClasses.
+type PriorityClass struct {
+	DataShards   int
+	ParityShards int
+}
+
+// priorityCodec protects priority classes of a stripe with different numbers of parity shards.
+// Construct using New with WithPriorityClasses.
+//
+// The data shards of all classes come first, in class order,
+// followed by the parity shards, in class order.
+// Each class is encoded as a separate stripe by its own encoder.
+type priorityCodec struct {
+	dataShards   int // Number of data shards, should not be modified.
+	parityShards int // Number of parity shards, should not be modified.
+	totalShards  int // Total number of shards. Calculated, and should not be modified.
+
+	classes []PriorityClass
+	inner   []Encoder // Encoder for each class. nil for classes without parity.
+
+	// Offsets of the data and parity shards of each class.
+	dataOff, parityOff []int
+
+	o options
+}
+
+// newPriority is like New, but for priority classes.
+func newPriority(dataShards, parityShards int, opt options) (*priorityCodec, error) {
+	r := &priorityCodec{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		totalShards:  dataShards + parityShards,
+		classes:      opt.classes,
+		o:            opt,
+	}
+	var k, m int
+	for _, c := range opt.classes {
+		if c.DataShards <= 0 || c.ParityShards < 0 {
+			return nil, ErrInvShardNum
+		}
+		r.dataOff = append(r.dataOff, k)
+		r.parityOff = append(r.parityOff, m)
+		k += c.DataShards
+		m += c.ParityShards
+		var inner Encoder
+		if c.ParityShards > 0 {
+			var err error
+			inner, err = New(c.DataShards, c.ParityShards, func(o *options) {
+				*o = opt
+				o.classes = nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		r.inner = append(r.inner, inner)
+	}
+	if k != dataShards || m != parityShards {
+		return nil, ErrInvShardNum
+	}
+	return r, nil
+}
+
+var _ = Extensions(&priorityCodec{})
+
+// indexes returns the shard indexes of class c, data shards first.
+func (r *priorityCodec) indexes(c int) []int {
+	cl := r.classes[c]
+	idx := make([]int, 0, cl.DataShards+cl.ParityShards)
+	for i := 0; i < cl.DataShards; i++ {
+		idx = append(idx, r.dataOff[c]+i)
+	}
+	for i := 0; i < cl.ParityShards; i++ {
+		idx = append(idx, r.dataShards+r.parityOff[c]+i)
+	}
+	return idx
+}
+
+// class returns the shards of class c.
+func (r *priorityCodec) class(shards [][]byte, c int) [][]byte {
+	idx := r.indexes(c)
+	sub := make([][]byte, len(idx))
+	for i, j := range idx {
+		sub[i] = shards[j]
+	}
+	return sub
+}
+
+// classOf returns the class of data shard idx.
+func (r *priorityCodec) classOf(idx int) int {
+	c := 0
+	for c+1 < len(r.classes) && r.dataOff[c+1] <= idx {
+		c++
+	}
+	return c
+}
+
+func (r *priorityCodec) ShardSizeMultiple() int {
+	mult := 1
+	for _, enc := range r.inner {
+		if enc == nil {
+			continue
+		}
+		m := enc.(Extensions).ShardSizeMultiple()
+		mult = mult / gcd(mult, m) * m
+	}
+	return mult
+}
+
+// gcd returns the greatest common divisor of a and b.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func (r *priorityCodec) DataShards() int {
+	return r.dataShards
+}
+
+func (r *priorityCodec) ParityShards() int {
+	return r.parityShards
+}
+
+func (r *priorityCodec) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *priorityCodec) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+func (r *priorityCodec) PureGo() bool {
+	for _, enc := range r.inner {
+		if enc != nil && !enc.(PureGoer).PureGo() {
+			return false
+		}
+	}
+	return r.o.pureGo()
+}
+
+func (r *priorityCodec) Recalibrate(shardSize int) {
+	for _, enc := range r.inner {
+		if enc != nil {
+			enc.(Recalibrator).Recalibrate(shardSize)
+		}
+	}
+}
+
+// CostModel returns the costs of the classes, weighted by their share of
+// the data shards for encoding, and of all shards for reconstruction.
+func (r *priorityCodec) CostModel() CostModel {
+	var c CostModel
+	for i, enc := range r.inner {
+		if enc == nil {
+			continue
+		}
+		cl := r.classes[i]
+		ic := enc.(CostModeler).CostModel()
+		d := float64(cl.DataShards) / float64(r.dataShards)
+		t := float64(cl.DataShards+cl.ParityShards) / float64(r.totalShards)
+		c.EncodeMulsPerByte += ic.EncodeMulsPerByte * d
+		c.EncodeBytesPerByte += ic.EncodeBytesPerByte * d
+		c.ReconstructMulsPerByte += ic.ReconstructMulsPerByte * t
+		c.ReconstructBytesPerByte += ic.ReconstructBytesPerByte * t
+	}
+	return c
+}
+
+func (r *priorityCodec) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	for c, enc := range r.inner {
+		if enc == nil {
+			continue
+		}
+		if err := enc.Encode(r.class(shards, c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeIdx will add parity for a single data shard.
+// Parity shards should start out zeroed. The caller must zero them before first call.
+// Data shards should only be delivered once. There is no check for this.
+func (r *priorityCodec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	if len(parity) != r.parityShards {
+		return ErrTooFewShards
+	}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
+	}
+	c := r.classOf(idx)
+	if r.inner[c] == nil {
+		return nil
+	}
+	p := parity[r.parityOff[c] : r.parityOff[c]+r.classes[c].ParityShards]
+	return r.inner[c].EncodeIdx(dataShard, idx-r.dataOff[c], p)
+}
+
+func (r *priorityCodec) Update(shards [][]byte, newDatashards [][]byte) error {
+	if len(shards) != r.totalShards || len(newDatashards) != r.dataShards {
+		return ErrTooFewShards
+	}
+	for c, enc := range r.inner {
+		newData := newDatashards[r.dataOff[c] : r.dataOff[c]+r.classes[c].DataShards]
+		changed := false
+		for _, s := range newData {
+			changed = changed || s != nil
+		}
+		if enc == nil || !changed {
+			continue
+		}
+		if err := enc.Update(r.class(shards, c), newData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *priorityCodec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+	for c, enc := range r.inner {
+		if enc == nil {
+			continue
+		}
+		if ok, err := enc.Verify(r.class(shards, c)); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (r *priorityCodec) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+// Reconstruct recreates missing shards class by class.
+// If a class cannot be reconstructed, ErrTooFewShards is returned,
+// but the other classes are still reconstructed.
+func (r *priorityCodec) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+// ReconstructData recreates missing data shards class by class.
+// If a class cannot be reconstructed, ErrTooFewShards is returned,
+// but the other classes are still reconstructed.
+func (r *priorityCodec) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards of each class.
+// If required is non-nil, only shards marked as required are recreated.
+func (r *priorityCodec) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	var res error
+	for c := range r.classes {
+		idx := r.indexes(c)
+		k := r.classes[c].DataShards
+		sub := make([][]byte, len(idx))
+		var req []bool
+		if required != nil {
+			req = make([]bool, len(idx))
+			if dataOnly {
+				req = req[:k]
+			}
+		}
+		missing := false
+		for i, j := range idx {
+			sub[i] = shards[j]
+			if i < len(req) {
+				req[i] = required[j]
+			}
+			if len(sub[i]) == 0 && (i < k || !dataOnly) && (req == nil || req[i]) {
+				missing = true
+			}
+		}
+		if !missing {
+			continue
+		}
+		if r.inner[c] == nil {
+			res = ErrTooFewShards
+			continue
+		}
+		var err error
+		switch {
+		case req != nil:
+			err = r.inner[c].ReconstructSome(sub, req)
+		case dataOnly:
+			err = r.inner[c].ReconstructData(sub)
+		default:
+			err = r.inner[c].Reconstruct(sub)
+		}
+		if err == ErrShardNoData {
+			err = ErrTooFewShards
+		}
+		if err == ErrTooFewShards {
+			res = err
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for i, j := range idx {
+			shards[j] = sub[i]
+		}
+	}
+	return res
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+// Shards are padded with zeros to a multiple of ShardSizeMultiple.
+func (r *priorityCodec) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, r.ShardSizeMultiple(), r.o.zeroCopySplit, false)
+}
+
+func (r *priorityCodec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
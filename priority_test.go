@@ -0,0 +1,103 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPriorityClasses(t *testing.T) {
+	classes := []PriorityClass{{DataShards: 2, ParityShards: 4}, {DataShards: 6, ParityShards: 2}, {DataShards: 2}}
+	enc, err := New(10, 6, testOptions(WithPriorityClasses(classes...))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10000)
+	fillRandom(data)
+	shards, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := enc.Verify(shards)
+	if err != nil || !ok {
+		t.Fatalf("verification failed: %v", err)
+	}
+	want := make([][]byte, len(shards))
+	for i := range shards {
+		want[i] = append([]byte(nil), shards[i]...)
+	}
+
+	// The first class survives the loss of 4 shards,
+	// while the second class cannot be recovered.
+	damaged := append([][]byte(nil), shards...)
+	for _, i := range []int{0, 1, 10, 11, 2, 3, 4} {
+		damaged[i] = nil
+	}
+	if err := enc.Reconstruct(damaged); err != ErrTooFewShards {
+		t.Fatalf("want ErrTooFewShards, got %v", err)
+	}
+	for _, i := range []int{0, 1, 10, 11} {
+		if !bytes.Equal(damaged[i], want[i]) {
+			t.Fatalf("shard %d not reconstructed", i)
+		}
+	}
+	if damaged[2] != nil {
+		t.Fatal("unrecoverable shard was filled in")
+	}
+
+	// Within the limits of each class.
+	damaged = append([][]byte(nil), shards...)
+	for _, i := range []int{0, 12, 2, 14} {
+		damaged[i] = nil
+	}
+	if err := enc.ReconstructData(damaged); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(damaged[0], want[0]) || !bytes.Equal(damaged[2], want[2]) || damaged[12] != nil {
+		t.Fatal("data reconstruction mismatch")
+	}
+	required := make([]bool, 16)
+	required[14] = true
+	if err := enc.ReconstructSome(damaged, required); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(damaged[14], want[14]) || damaged[12] != nil {
+		t.Fatal("required shard not reconstructed")
+	}
+
+	// Without parity a class cannot be recovered.
+	damaged = append([][]byte(nil), shards...)
+	damaged[8] = nil
+	if err := enc.Reconstruct(damaged); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+
+	// EncodeIdx and Update.
+	parity := AllocAligned(6, len(shards[0]))
+	for i := 0; i < 10; i++ {
+		if err := enc.EncodeIdx(shards[i], i, parity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range parity {
+		if !bytes.Equal(parity[i], shards[10+i]) {
+			t.Fatalf("EncodeIdx parity %d mismatch", i)
+		}
+	}
+	newData := make([][]byte, 10)
+	newData[5] = make([]byte, len(shards[0]))
+	fillRandom(newData[5])
+	if err := enc.Update(shards, newData); err != nil {
+		t.Fatal(err)
+	}
+	shards[5] = newData[5]
+	if ok, err := enc.Verify(shards); !ok || err != nil {
+		t.Fatalf("verification after update failed: %v", err)
+	}
+
+	if _, err := New(10, 5, WithPriorityClasses(classes...)); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+}
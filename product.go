@@ -0,0 +1,200 @@
+package reedsolomon
+
+// ProductCode is a two-dimensional product code.
+//
+// Shards are arranged in a grid, indexed as grid[row][column].
+// Each row is a stripe with rowData data shards followed by rowParity parity shards,
+// and each column is a stripe with colData data shards followed by colParity parity shards.
+// The data is stored in the first colData rows and rowData columns.
+//
+// Since the code is linear, the parity of the parity shards is the same
+// whether it is computed along rows or columns.
+// Up to rowParity shards can be lost in each row, and up to colParity shards
+// in each column, and many patterns with more losses, such as complete rows,
+// can be repaired by alternating between rows and columns.
+type ProductCode struct {
+	row, col Encoder
+}
+
+// NewProductCode creates a product code with the given dimensions.
+// The options are used for both the row and column encoders.
+func NewProductCode(rowData, rowParity, colData, colParity int, opts ...Option) (*ProductCode, error) {
+	row, err := New(rowData, rowParity, opts...)
+	if err != nil {
+		return nil, err
+	}
+	col, err := New(colData, colParity, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ProductCode{row: row, col: col}, nil
+}
+
+// Rows returns the number of rows in the grid.
+func (p *ProductCode) Rows() int {
+	return p.col.(Extensions).TotalShards()
+}
+
+// Columns returns the number of columns in the grid.
+func (p *ProductCode) Columns() int {
+	return p.row.(Extensions).TotalShards()
+}
+
+// AllocAligned allocates a grid of shards of the given size.
+func (p *ProductCode) AllocAligned(each int) [][][]byte {
+	grid := make([][][]byte, p.Rows())
+	for r := range grid {
+		grid[r] = AllocAligned(p.Columns(), each)
+	}
+	return grid
+}
+
+// checkGrid returns an error if grid does not have the dimensions of the code.
+func (p *ProductCode) checkGrid(grid [][][]byte) error {
+	if len(grid) != p.Rows() {
+		return ErrTooFewShards
+	}
+	for _, row := range grid {
+		if len(row) != p.Columns() {
+			return ErrTooFewShards
+		}
+	}
+	return nil
+}
+
+// Encode calculates all parity shards of the grid from the data shards.
+func (p *ProductCode) Encode(grid [][][]byte) error {
+	if err := p.checkGrid(grid); err != nil {
+		return err
+	}
+	for _, row := range grid[:p.col.(Extensions).DataShards()] {
+		if err := p.row.Encode(row); err != nil {
+			return err
+		}
+	}
+	for c := 0; c < p.Columns(); c++ {
+		column := p.column(grid, c)
+		if err := p.col.Encode(column); err != nil {
+			return err
+		}
+		p.setColumn(grid, c, column)
+	}
+	return nil
+}
+
+// Verify returns true if all rows and columns contain correct parity.
+func (p *ProductCode) Verify(grid [][][]byte) (bool, error) {
+	if err := p.checkGrid(grid); err != nil {
+		return false, err
+	}
+	for _, row := range grid {
+		if ok, err := p.row.Verify(row); !ok || err != nil {
+			return false, err
+		}
+	}
+	for c := 0; c < p.Columns(); c++ {
+		if ok, err := p.col.Verify(p.column(grid, c)); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// ReconstructRow reconstructs the missing shards of row r.
+// Missing shards are nil or zero-length.
+// If more shards are missing than there are parity shards in a row,
+// ErrTooFewShards is returned and the row is unchanged.
+func (p *ProductCode) ReconstructRow(grid [][][]byte, r int) error {
+	if err := p.checkGrid(grid); err != nil {
+		return err
+	}
+	if r < 0 || r >= p.Rows() {
+		return ErrInvShardNum
+	}
+	return p.row.Reconstruct(grid[r])
+}
+
+// ReconstructColumn reconstructs the missing shards of column c.
+// If more shards are missing than there are parity shards in a column,
+// ErrTooFewShards is returned and the column is unchanged.
+func (p *ProductCode) ReconstructColumn(grid [][][]byte, c int) error {
+	if err := p.checkGrid(grid); err != nil {
+		return err
+	}
+	if c < 0 || c >= p.Columns() {
+		return ErrInvShardNum
+	}
+	column := p.column(grid, c)
+	if err := p.col.Reconstruct(column); err != nil {
+		return err
+	}
+	p.setColumn(grid, c, column)
+	return nil
+}
+
+// Reconstruct reconstructs all missing shards, by repeatedly reconstructing
+// the rows and columns that have few enough missing shards.
+// If the grid cannot be completely reconstructed, ErrTooFewShards is returned,
+// and the shards that could be reconstructed are filled in.
+func (p *ProductCode) Reconstruct(grid [][][]byte) error {
+	if err := p.checkGrid(grid); err != nil {
+		return err
+	}
+	rowParity := p.row.(Extensions).ParityShards()
+	colParity := p.col.(Extensions).ParityShards()
+	for {
+		missing, progress := 0, false
+		for r, row := range grid {
+			n := 0
+			for _, shard := range row {
+				if len(shard) == 0 {
+					n++
+				}
+			}
+			if n > 0 && n <= rowParity {
+				if err := p.row.Reconstruct(grid[r]); err != nil {
+					return err
+				}
+				progress = true
+			}
+		}
+		for c := 0; c < p.Columns(); c++ {
+			n := 0
+			for _, row := range grid {
+				if len(row[c]) == 0 {
+					n++
+				}
+			}
+			missing += n
+			if n > 0 && n <= colParity {
+				if err := p.ReconstructColumn(grid, c); err != nil {
+					return err
+				}
+				missing -= n
+				progress = true
+			}
+		}
+		if missing == 0 {
+			return nil
+		}
+		if !progress {
+			return ErrTooFewShards
+		}
+	}
+}
+
+// column returns the shards of column c.
+func (p *ProductCode) column(grid [][][]byte, c int) [][]byte {
+	column := make([][]byte, len(grid))
+	for r, row := range grid {
+		column[r] = row[c]
+	}
+	return column
+}
+
+// setColumn stores the shards of column c.
+func (p *ProductCode) setColumn(grid [][][]byte, c int, column [][]byte) {
+	for r, row := range grid {
+		row[c] = column[r]
+	}
+}
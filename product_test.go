@@ -0,0 +1,91 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProductCode(t *testing.T) {
+	p, err := NewProductCode(4, 2, 3, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rows() != 5 || p.Columns() != 6 {
+		t.Fatalf("got %dx%d grid", p.Rows(), p.Columns())
+	}
+	grid := p.AllocAligned(100)
+	for _, row := range grid[:3] {
+		for _, shard := range row[:4] {
+			fillRandom(shard)
+		}
+	}
+	if err := p.Encode(grid); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := p.Verify(grid)
+	if err != nil || !ok {
+		t.Fatalf("verification failed: %v", err)
+	}
+
+	clone := func() [][][]byte {
+		c := make([][][]byte, len(grid))
+		for r := range grid {
+			c[r] = append([][]byte(nil), grid[r]...)
+		}
+		return c
+	}
+	check := func(got [][][]byte) {
+		t.Helper()
+		for r := range grid {
+			for c := range grid[r] {
+				if !bytes.Equal(got[r][c], grid[r][c]) {
+					t.Fatalf("shard %d,%d mismatch", r, c)
+				}
+			}
+		}
+	}
+
+	// Lose two complete rows, and a shard in another row,
+	// so the first column has more missing shards than column parity.
+	damaged := clone()
+	for c := range damaged[0] {
+		damaged[0][c], damaged[3][c] = nil, nil
+	}
+	damaged[1][0] = nil
+	if err := p.Reconstruct(damaged); err != nil {
+		t.Fatal(err)
+	}
+	check(damaged)
+
+	// Single row and column steps.
+	damaged = clone()
+	damaged[2][0], damaged[2][1], damaged[2][2] = nil, nil, nil
+	if err := p.ReconstructRow(damaged, 2); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	damaged = clone()
+	damaged[2][5], damaged[4][5] = nil, nil
+	if err := p.ReconstructColumn(damaged, 5); err != nil {
+		t.Fatal(err)
+	}
+	check(damaged)
+
+	// A 3x3 square cannot be repaired in either direction.
+	damaged = clone()
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			damaged[r][c] = nil
+		}
+	}
+	damaged[4][5] = nil
+	if err := p.Reconstruct(damaged); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	if !bytes.Equal(damaged[4][5], grid[4][5]) {
+		t.Error("repairable shard was not reconstructed")
+	}
+
+	if err := p.Encode(grid[:4]); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package reedsolomon
+
+import (
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between progress callbacks,
+// except for the final callback of an operation.
+const progressInterval = 100 * time.Millisecond
+
+// progressSteps is the number of blocks in-memory operations are split into
+// when reporting progress without a rate limit.
+const progressSteps = 16
+
+// progressTracker reports the progress of a single operation.
+// It is safe for concurrent use. All methods can be called on a nil tracker.
+type progressTracker struct {
+	fn    func(processed, total int64)
+	total int64 // -1 if unknown.
+
+	mu       sync.Mutex
+	done     int64
+	reported int64
+	last     time.Time
+}
+
+// newProgress returns a tracker for an operation processing total bytes,
+// or nil if fn is nil. total is -1 if unknown.
+func newProgress(fn func(processed, total int64), total int64) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn, total: total, reported: -1, last: time.Now()}
+}
+
+// add records n processed bytes, and calls the callback if enough time has passed
+// since the last call, or if the operation is complete.
+func (p *progressTracker) add(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += int64(n)
+	if p.total >= 0 && p.done > p.total {
+		p.done = p.total
+	}
+	now := time.Now()
+	if p.done == p.total || now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.report()
+	}
+}
+
+// finish reports that the operation has completed successfully.
+// If the total is unknown, the processed bytes are reported as the total.
+func (p *progressTracker) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.total < 0 {
+		p.total = p.done
+	}
+	p.done = p.total
+	if p.reported != p.done {
+		p.report()
+	}
+}
+
+// report calls the callback with the current progress.
+// The callback is called with the lock held, so calls are never reordered.
+func (p *progressTracker) report() {
+	p.reported = p.done
+	p.fn(p.done, p.total)
+}
+
+// codeSomeShardsProgress performs codeSomeShards, reporting the processed
+// bytes of each shard to p. If p is nil, it is the same as codeSomeShards.
+func (r *reedSolomon) codeSomeShardsProgress(matrixRows, inputs, outputs [][]byte, byteCount int, p *progressTracker) {
+	if p == nil || len(outputs) == 0 {
+		r.codeSomeShards(matrixRows, inputs, outputs, byteCount)
+		return
+	}
+	if checkEnabled {
+		checkSlices(matrixRows, inputs, outputs, 0, byteCount)
+	}
+	if r.o.autoRecalibrate {
+		r.checkDrift(byteCount)
+	}
+	r.codeSomeShardsLimited(matrixRows, inputs, outputs, byteCount, p)
+}
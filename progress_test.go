@@ -0,0 +1,115 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+// progressLog records progress callbacks.
+type progressLog struct {
+	mu    sync.Mutex
+	calls [][2]int64
+}
+
+func (p *progressLog) report(processed, total int64) {
+	p.mu.Lock()
+	p.calls = append(p.calls, [2]int64{processed, total})
+	p.mu.Unlock()
+}
+
+// check verifies that progress increased and ended at total,
+// and returns the number of calls.
+func (p *progressLog) check(t *testing.T, total int64) int {
+	t.Helper()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.calls) == 0 {
+		t.Fatal("no progress reported")
+	}
+	for i, c := range p.calls {
+		if i > 0 && c[0] < p.calls[i-1][0] {
+			t.Fatalf("progress decreased: %v", p.calls)
+		}
+	}
+	if last := p.calls[len(p.calls)-1]; last[0] != total || last[1] != total {
+		t.Fatalf("final progress %v, want %d", last, total)
+	}
+	n := len(p.calls)
+	p.calls = nil
+	return n
+}
+
+func TestProgress(t *testing.T) {
+	var log progressLog
+	enc, err := New(10, 4, testOptions(WithProgress(log.report))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1 << 20)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	log.check(t, 1<<20)
+
+	shards[2], shards[11] = nil, nil
+	if err := enc.Reconstruct(shards); err != nil {
+		t.Fatal(err)
+	}
+	log.check(t, 2<<20)
+
+	shards[2], shards[11] = nil, nil
+	if err := enc.ReconstructData(shards); err != nil {
+		t.Fatal(err)
+	}
+	log.check(t, 1<<20)
+}
+
+func TestStreamProgress(t *testing.T) {
+	var log progressLog
+	perShard := 1 << 20
+	r, err := NewStream(10, 3, testOptions(WithProgress(log.report), WithStreamBlockSize(1024))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := randomBytes(10, perShard)
+	parb := emptyBuffers(3)
+	if err := r.Encode(toReaders(toBuffers(shards)), toWriters(parb)); err != nil {
+		t.Fatal(err)
+	}
+	// Calls are rate limited, so there are much fewer than one per block.
+	if n := log.check(t, int64(perShard)); n > perShard/1024/4 {
+		t.Errorf("progress reported %d times", n)
+	}
+	parity := toBytes(parb)
+
+	all := append(toReaders(toBuffers(shards)), toReaders(toBuffers(parity))...)
+	if ok, err := r.Verify(all); !ok || err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+	log.check(t, int64(perShard))
+
+	src := make([]io.ReaderAt, 10)
+	for i := range src {
+		src[i] = bytes.NewReader(shards[i])
+	}
+	dst := make([]io.WriterAt, 3)
+	for i := range dst {
+		dst[i] = &atBuffer{data: make([]byte, perShard)}
+	}
+	if err := r.(StreamEncoderAt).EncodeAt(src, dst, int64(perShard)); err != nil {
+		t.Fatal(err)
+	}
+	log.mu.Lock()
+	for _, c := range log.calls {
+		if c[1] != int64(perShard) {
+			t.Fatalf("total not reported: %v", c)
+		}
+	}
+	log.mu.Unlock()
+	log.check(t, int64(perShard))
+}
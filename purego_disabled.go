@@ -0,0 +1,6 @@
+//go:build !noasm && !purego && !appengine && !gccgo && (amd64 || arm64 || ppc64le)
+
+package reedsolomon
+
+// pureGoBuild is true when no assembly is included in the build.
+const pureGoBuild = false
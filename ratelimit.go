@@ -0,0 +1,64 @@
+package reedsolomon
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the number of bytes processed per second.
+// It is shared by all copies of the options it was created with.
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate float64 // Bytes per second.
+	next time.Time
+}
+
+// wait blocks until n bytes can be processed.
+// Calling wait on a nil limiter returns immediately.
+func (l *rateLimiter) wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	sleep := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	l.mu.Unlock()
+	if sleep > 0 {
+		time.Sleep(sleep)
+	}
+}
+
+// codeSomeShardsLimited performs codeSomeShards in blocks, waiting for
+// the rate limiter before each block and reporting each block to p.
+// Blocks are at most perRound bytes when rate limited,
+// and otherwise a fraction of byteCount, so large blocks can still be split
+// between goroutines.
+func (r *reedSolomon) codeSomeShardsLimited(matrixRows, inputs, outputs [][]byte, byteCount int, p *progressTracker) {
+	ins := make([][]byte, len(inputs))
+	outs := make([][]byte, len(outputs))
+	perRound := r.calib.Load().perRound
+	if r.o.rateLimit == nil {
+		if n := (byteCount/progressSteps + 63) &^ 63; n > perRound {
+			perRound = n
+		}
+	}
+	for start := 0; start < byteCount; start += perRound {
+		end := start + perRound
+		if end > byteCount {
+			end = byteCount
+		}
+		for i, in := range inputs {
+			ins[i] = in[start:end]
+		}
+		for i, out := range outputs {
+			outs[i] = out[start:end]
+		}
+		r.o.rateLimit.wait((end - start) * len(inputs))
+		r.codeSomeShardsBlock(matrixRows, ins, outs, end-start)
+		p.add(end - start)
+	}
+}
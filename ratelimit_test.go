@@ -0,0 +1,33 @@
+package reedsolomon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	const rate = 1 << 20
+	enc, err := New(10, 4, testOptions(WithRateLimit(rate))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(20 << 10)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	start := time.Now()
+	// The first call is not delayed, but the second must wait for it.
+	for i := 0; i < 2; i++ {
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+	// 10 shards of 20KB per call.
+	if want := 2 * 200 * time.Millisecond * 9 / 10; time.Since(start) < want {
+		t.Errorf("took %v, want at least %v", time.Since(start), want)
+	}
+}
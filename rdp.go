@@ -0,0 +1,460 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"io"
+)
+
+// rdpCodec is a Row-Diagonal Parity array code with two parity shards,
+// which only uses XOR.
+// Construct using New with WithRDP.
+//
+// The code is defined for p-1 data disks, where p is the smallest prime
+// larger than the number of data shards. Data disks past the data shards
+// are treated as zero. Each shard is split into p-1 rows.
+//
+// The first parity shard P contains the XOR of each row of data.
+// Treating P as disk p-1, element r of disk j is on diagonal (r+j) mod p.
+// The second parity shard Q contains the XOR of each of the diagonals 0 to p-2.
+// Any two lost disks can be recovered by alternating between row and diagonal
+// equations that only have one unknown element.
+type rdpCodec struct {
+	dataShards  int // Number of data shards, should not be modified.
+	totalShards int // Total number of shards. Calculated, and should not be modified.
+
+	// p is the prime defining the array.
+	p int
+
+	o options
+}
+
+// newRDP is like New, but for RDP with 2 parity shards.
+func newRDP(dataShards int, opt options) (*rdpCodec, error) {
+	if dataShards <= 0 {
+		return nil, ErrInvShardNum
+	}
+	if dataShards+2 > 65536 {
+		return nil, ErrMaxShardNum
+	}
+	p := dataShards + 1
+	for !isPrime(p) {
+		p++
+	}
+	return &rdpCodec{
+		dataShards:  dataShards,
+		totalShards: dataShards + 2,
+		p:           p,
+		o:           opt,
+	}, nil
+}
+
+// isPrime returns whether n is a prime.
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var _ = Extensions(&rdpCodec{})
+
+// elem returns element r of disk j, which is nil for zero disks.
+// h is the size of each element.
+func (r *rdpCodec) elem(shards [][]byte, j, row, h int) []byte {
+	switch {
+	case j < r.dataShards:
+		return shards[j][row*h : (row+1)*h]
+	case j == r.p-1:
+		return shards[r.dataShards][row*h : (row+1)*h]
+	}
+	return nil
+}
+
+// diagonal returns the diagonal of element row of disk j.
+func (r *rdpCodec) diagonal(j, row int) int {
+	return (row + j) % r.p
+}
+
+func (r *rdpCodec) ShardSizeMultiple() int {
+	return r.p - 1
+}
+
+func (r *rdpCodec) DataShards() int {
+	return r.dataShards
+}
+
+func (r *rdpCodec) ParityShards() int {
+	return 2
+}
+
+func (r *rdpCodec) TotalShards() int {
+	return r.totalShards
+}
+
+func (r *rdpCodec) AllocAligned(each int) [][]byte {
+	return AllocAligned(r.totalShards, each)
+}
+
+func (r *rdpCodec) PureGo() bool {
+	return r.o.pureGo()
+}
+
+// Recalibrate does nothing, since the RDP codec does not split work between goroutines.
+func (r *rdpCodec) Recalibrate(shardSize int) {}
+
+// CostModel returns the estimated costs for the encoder.
+// There are no multiplications.
+func (r *rdpCodec) CostModel() CostModel {
+	d := float64(r.dataShards)
+	// Data is added to both parity shards, and P is added to Q.
+	// A single lost shard is recovered from a row, except Q,
+	// which is recomputed from the data and P.
+	return CostModel{
+		EncodeBytesPerByte:      (2*d + 3) / d,
+		ReconstructBytesPerByte: (d + 1) * (d + 1) / (d + 2),
+	}
+}
+
+// checkSize returns the shard size of shards,
+// or an error if it is not a multiple of the number of rows.
+func (r *rdpCodec) checkSize(shards [][]byte) (int, error) {
+	size := shardSize(shards)
+	if size%(r.p-1) != 0 {
+		return 0, ErrInvalidShardSize
+	}
+	return size, nil
+}
+
+func (r *rdpCodec) Encode(shards [][]byte) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return err
+	}
+	size, err := r.checkSize(shards)
+	if err != nil {
+		return err
+	}
+	r.encodeP(shards)
+	r.encodeQ(shards, size/(r.p-1))
+	return nil
+}
+
+// encodeP calculates the row parity.
+func (r *rdpCodec) encodeP(shards [][]byte) {
+	p := shards[r.dataShards]
+	copy(p, shards[0])
+	for _, in := range shards[1:r.dataShards] {
+		sliceXor(in, p, &r.o)
+	}
+}
+
+// encodeQ calculates the diagonal parity from the data and row parity.
+func (r *rdpCodec) encodeQ(shards [][]byte, h int) {
+	q := shards[r.dataShards+1]
+	memclr(q)
+	for j := 0; j < r.p; j++ {
+		if j >= r.dataShards && j != r.p-1 {
+			continue
+		}
+		for row := 0; row < r.p-1; row++ {
+			if d := r.diagonal(j, row); d != r.p-1 {
+				sliceXor(r.elem(shards, j, row, h), q[d*h:(d+1)*h], &r.o)
+			}
+		}
+	}
+}
+
+// addData adds the contribution of data in shard idx to the parity.
+func (r *rdpCodec) addData(data []byte, idx int, parity [][]byte) {
+	h := len(data) / (r.p - 1)
+	p, q := parity[0], parity[1]
+	for row := 0; row < r.p-1; row++ {
+		e := data[row*h : (row+1)*h]
+		sliceXor(e, p[row*h:(row+1)*h], &r.o)
+		// Directly, and through the row parity on disk p-1.
+		for _, d := range []int{r.diagonal(idx, row), r.diagonal(r.p-1, row)} {
+			if d != r.p-1 {
+				sliceXor(e, q[d*h:(d+1)*h], &r.o)
+			}
+		}
+	}
+}
+
+// EncodeIdx will add parity for a single data shard.
+// Parity shards should start out zeroed. The caller must zero them before first call.
+// Data shards should only be delivered once. There is no check for this.
+func (r *rdpCodec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	if len(parity) != 2 {
+		return ErrTooFewShards
+	}
+	if idx < 0 || idx >= r.dataShards {
+		return ErrInvShardNum
+	}
+	if err := checkShards(parity, false); err != nil {
+		return err
+	}
+	if len(parity[0]) != len(dataShard) {
+		return ErrShardSize
+	}
+	if len(dataShard)%(r.p-1) != 0 {
+		return ErrInvalidShardSize
+	}
+	r.addData(dataShard, idx, parity)
+	return nil
+}
+
+func (r *rdpCodec) Update(shards [][]byte, newDatashards [][]byte) error {
+	if len(shards) != r.totalShards || len(newDatashards) != r.dataShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	if err := checkShards(newDatashards, true); err != nil {
+		return err
+	}
+	size, err := r.checkSize(shards)
+	if err != nil {
+		return err
+	}
+	for i := range newDatashards {
+		if newDatashards[i] != nil && shards[i] == nil {
+			return ErrInvalidInput
+		}
+	}
+	for _, p := range shards[r.dataShards:] {
+		if p == nil {
+			return ErrInvalidInput
+		}
+	}
+	delta := make([]byte, size)
+	for i, s := range newDatashards {
+		if s == nil {
+			continue
+		}
+		copy(delta, shards[i])
+		sliceXor(s, delta, &r.o)
+		r.addData(delta, i, shards[r.dataShards:])
+	}
+	return nil
+}
+
+func (r *rdpCodec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.totalShards {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+	size, err := r.checkSize(shards)
+	if err != nil {
+		return false, err
+	}
+
+	// Re-encode parity shards to temporary storage.
+	outputs := make([][]byte, r.totalShards)
+	copy(outputs, shards[:r.dataShards])
+	for i := r.dataShards; i < r.totalShards; i++ {
+		outputs[i] = make([]byte, size)
+	}
+	r.encodeP(outputs)
+	r.encodeQ(outputs, size/(r.p-1))
+
+	// Compare.
+	for i := r.dataShards; i < r.totalShards; i++ {
+		if !bytes.Equal(outputs[i], shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *rdpCodec) ReconstructSome(shards [][]byte, required []bool) error {
+	if len(required) == r.totalShards {
+		return r.reconstruct(shards, false, required)
+	}
+	if required != nil && len(required) < r.dataShards {
+		return ErrTooFewShards
+	}
+	return r.reconstruct(shards, true, required)
+}
+
+func (r *rdpCodec) Reconstruct(shards [][]byte) error {
+	return r.reconstruct(shards, false, nil)
+}
+
+func (r *rdpCodec) ReconstructData(shards [][]byte) error {
+	return r.reconstruct(shards, true, nil)
+}
+
+// reconstruct recreates the missing data shards, and unless dataOnly is set,
+// the missing parity shards.
+// If required is non-nil, only shards marked as required are recreated.
+func (r *rdpCodec) reconstruct(shards [][]byte, dataOnly bool, required []bool) error {
+	if len(shards) != r.totalShards {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	size, err := r.checkSize(shards)
+	if err != nil {
+		return err
+	}
+	isRequired := func(i int) bool {
+		return len(shards[i]) == 0 && (i < r.dataShards || !dataOnly) && (required == nil || required[i])
+	}
+
+	// Solve all missing data and row parity, since both are needed
+	// to compute the diagonal parity.
+	full := make([][]byte, r.totalShards)
+	var lost []int
+	want := false
+	for i := range shards {
+		if len(shards[i]) != 0 {
+			full[i] = shards[i]
+			continue
+		}
+		lost = append(lost, i)
+		if isRequired(i) {
+			if cap(shards[i]) >= size {
+				shards[i] = shards[i][:size]
+			} else {
+				shards[i] = AllocAligned(1, size)[0]
+			}
+			full[i] = shards[i]
+			want = true
+		} else {
+			full[i] = make([]byte, size)
+		}
+	}
+	if !want {
+		return nil
+	}
+	if len(lost) > 2 {
+		return ErrTooFewShards
+	}
+
+	h := size / (r.p - 1)
+	qLost := lost[len(lost)-1] == r.dataShards+1
+	if err := r.solve(full, lost, !qLost, h); err != nil {
+		return err
+	}
+	if qLost {
+		r.encodeQ(full, h)
+	}
+	return nil
+}
+
+// solve recovers the lost data and row parity shards in place.
+// Diagonal equations are only used if useQ is set.
+func (r *rdpCodec) solve(shards [][]byte, lost []int, useQ bool, h int) error {
+	p := r.p
+	rows := p - 1
+	disk := func(i int) int {
+		if i == r.dataShards {
+			return p - 1
+		}
+		return i
+	}
+
+	// Unknown elements, and the number of unknowns in each equation.
+	unknown := make([][]bool, p)
+	rowUnknown := make([]int, rows)
+	diagUnknown := make([]int, p)
+	remaining := 0
+	for _, i := range lost {
+		if i > r.dataShards {
+			continue
+		}
+		j := disk(i)
+		unknown[j] = make([]bool, rows)
+		for row := range unknown[j] {
+			unknown[j][row] = true
+			rowUnknown[row]++
+			diagUnknown[r.diagonal(j, row)]++
+			remaining++
+		}
+	}
+
+	// Solve equations with a single unknown until all are known.
+	q := shards[r.dataShards+1]
+	for remaining > 0 {
+		progress := false
+		for row := 0; row < rows; row++ {
+			if rowUnknown[row] != 1 {
+				continue
+			}
+			var dst []byte
+			var dj int
+			for j := 0; j < p; j++ {
+				if unknown[j] != nil && unknown[j][row] {
+					dst, dj = r.elem(shards, j, row, h), j
+				}
+			}
+			memclr(dst)
+			for j := 0; j < p; j++ {
+				if j != dj {
+					if e := r.elem(shards, j, row, h); e != nil {
+						sliceXor(e, dst, &r.o)
+					}
+				}
+			}
+			unknown[dj][row] = false
+			rowUnknown[row]--
+			diagUnknown[r.diagonal(dj, row)]--
+			remaining--
+			progress = true
+		}
+		for d := 0; useQ && d < p-1; d++ {
+			if diagUnknown[d] != 1 {
+				continue
+			}
+			// Element on disk j of diagonal d is in row (d-j) mod p.
+			var dst []byte
+			var dj, drow int
+			for j := 0; j < p; j++ {
+				row := (d - j + p) % p
+				if row < rows && unknown[j] != nil && unknown[j][row] {
+					dst, dj, drow = r.elem(shards, j, row, h), j, row
+				}
+			}
+			copy(dst, q[d*h:(d+1)*h])
+			for j := 0; j < p; j++ {
+				row := (d - j + p) % p
+				if row < rows && j != dj {
+					if e := r.elem(shards, j, row, h); e != nil {
+						sliceXor(e, dst, &r.o)
+					}
+				}
+			}
+			unknown[dj][drow] = false
+			rowUnknown[drow]--
+			diagUnknown[d]--
+			remaining--
+			progress = true
+		}
+		if !progress {
+			return ErrTooFewShards
+		}
+	}
+	return nil
+}
+
+// Split a data slice into the number of shards given to the encoder,
+// and create empty parity shards.
+// Shards are padded with zeros to a multiple of ShardSizeMultiple.
+func (r *rdpCodec) Split(data []byte) ([][]byte, error) {
+	return splitShards(data, r.dataShards, r.totalShards, r.ShardSizeMultiple(), r.o.zeroCopySplit, false)
+}
+
+func (r *rdpCodec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return joinShards(dst, shards, r.dataShards, outSize)
+}
@@ -0,0 +1,150 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRDP(t *testing.T) {
+	for _, k := range []int{1, 2, 4, 6, 10, 17} {
+		enc, err := New(k, 2, testOptions(WithRDP(true))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := enc.(*rdpCodec); !ok {
+			t.Fatalf("got %T", enc)
+		}
+		mult := enc.(Extensions).ShardSizeMultiple()
+		shards := enc.(Extensions).AllocAligned(mult * 5)
+		for _, s := range shards[:k] {
+			fillRandom(s)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := enc.Verify(shards)
+		if err != nil || !ok {
+			t.Fatalf("k=%d: verification failed: %v", k, err)
+		}
+
+		// All pairs of lost shards.
+		n := k + 2
+		for a := 0; a < n; a++ {
+			for b := a; b < n; b++ {
+				damaged := append([][]byte(nil), shards...)
+				damaged[a], damaged[b] = nil, nil
+				if err := enc.Reconstruct(damaged); err != nil {
+					t.Fatalf("k=%d, lost %d,%d: %v", k, a, b, err)
+				}
+				for i := range shards {
+					if !bytes.Equal(damaged[i], shards[i]) {
+						t.Fatalf("k=%d, lost %d,%d: shard %d mismatch", k, a, b, i)
+					}
+				}
+				damaged[a], damaged[b] = nil, nil
+				if err := enc.ReconstructData(damaged); err != nil {
+					t.Fatal(err)
+				}
+				if b >= k && damaged[b] != nil {
+					t.Fatalf("parity shard %d reconstructed", b)
+				}
+			}
+		}
+		damaged := append([][]byte(nil), shards...)
+		damaged[0], damaged[1], damaged[n-1] = nil, nil, nil
+		if k > 1 {
+			if err := enc.Reconstruct(damaged); err != ErrTooFewShards {
+				t.Errorf("want ErrTooFewShards, got %v", err)
+			}
+		}
+
+		// EncodeIdx and Update give the same parity.
+		parity := AllocAligned(2, len(shards[0]))
+		for i := k - 1; i >= 0; i-- {
+			if err := enc.EncodeIdx(shards[i], i, parity); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(parity[0], shards[k]) || !bytes.Equal(parity[1], shards[k+1]) {
+			t.Fatalf("k=%d: EncodeIdx mismatch", k)
+		}
+		newData := make([][]byte, k)
+		newData[k-1] = make([]byte, len(shards[0]))
+		fillRandom(newData[k-1])
+		if err := enc.Update(shards, newData); err != nil {
+			t.Fatal(err)
+		}
+		shards[k-1] = newData[k-1]
+		ok, err = enc.Verify(shards)
+		if err != nil || !ok {
+			t.Fatalf("k=%d: verification after update failed: %v", k, err)
+		}
+	}
+}
+
+func TestRDPOptions(t *testing.T) {
+	// Other geometries use Reed-Solomon.
+	enc, err := New(10, 3, WithRDP(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := enc.(*reedSolomon); !ok {
+		t.Fatalf("got %T", enc)
+	}
+	if _, err := New(10, 2, WithRDP(true), WithClayCode(true)); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+	enc, err = New(10, 2, WithRDP(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(AllocAligned(12, 15)); err != ErrInvalidShardSize {
+		t.Errorf("want ErrInvalidShardSize, got %v", err)
+	}
+	data := make([]byte, 1000)
+	fillRandom(data)
+	shards, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	shards[3], shards[10] = nil, nil
+	if err := enc.Reconstruct(shards); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := enc.Join(&buf, shards, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatal("joined data mismatch")
+	}
+}
+
+func BenchmarkRDPEncode(b *testing.B) {
+	for _, rdp := range []bool{false, true} {
+		name := "rs"
+		if rdp {
+			name = "rdp"
+		}
+		b.Run(name, func(b *testing.B) {
+			enc, err := New(10, 2, WithRDP(rdp))
+			if err != nil {
+				b.Fatal(err)
+			}
+			shards := enc.(Extensions).AllocAligned(10 << 16)
+			for _, shard := range shards[:10] {
+				fillRandom(shard)
+			}
+			b.SetBytes(int64(10 * len(shards[0])))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := enc.Encode(shards); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
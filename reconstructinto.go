@@ -0,0 +1,53 @@
+package reedsolomon
+
+// ReconstructInto reconstructs missing shards into caller supplied buffers.
+//
+// dst must have an entry for each shard. For each missing shard to reconstruct,
+// dst must contain a buffer of the shard size, and all other entries must be nil.
+// Missing shards without a destination are not reconstructed, as done by ReconstructSome.
+//
+// Unlike Reconstruct, neither shards nor the shard buffers are modified,
+// so shards can be shared with other readers while reconstructing.
+//
+// If a destination is given for a shard that is present, ErrInvalidInput is returned.
+// If a destination does not match the shard size, ErrShardSize is returned.
+func ReconstructInto(enc Encoder, shards, dst [][]byte) error {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() || len(dst) != len(shards) {
+		return ErrTooFewShards
+	}
+	if err := checkShards(shards, true); err != nil {
+		return err
+	}
+	size := shardSize(shards)
+	work := make([][]byte, len(shards))
+	required := make([]bool, len(shards))
+	for i, shard := range shards {
+		switch {
+		case len(shard) != 0:
+			if dst[i] != nil {
+				return ErrInvalidInput
+			}
+			work[i] = shard
+		case dst[i] != nil:
+			if len(dst[i]) != size {
+				return ErrShardSize
+			}
+			// Encoders reuse the capacity of missing shards.
+			work[i] = dst[i][:0]
+			required[i] = true
+		}
+	}
+	if err := enc.ReconstructSome(work, required); err != nil {
+		return err
+	}
+	for i, req := range required {
+		if req && &work[i][0] != &dst[i][0] {
+			copy(dst[i], work[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReconstructInto(t *testing.T) {
+	for _, opts := range [][]Option{
+		testOptions(),
+		{WithLeopardGF16(true)},
+		{WithClayCode(true)},
+		{WithPiggyback(true)},
+	} {
+		enc, err := New(6, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mult := enc.(Extensions).ShardSizeMultiple()
+		size := (6000 + mult - 1) / mult * mult
+		shards := enc.(Extensions).AllocAligned(size)
+		for _, shard := range shards[:6] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		in := make([][]byte, len(shards))
+		copy(in, shards)
+		in[1], in[4], in[7] = nil, nil, nil
+		orig := make([][]byte, len(in))
+		copy(orig, in)
+
+		dst := make([][]byte, len(shards))
+		dst[4] = make([]byte, size)
+		dst[7] = make([]byte, size)
+		if err := ReconstructInto(enc, in, dst); err != nil {
+			t.Fatal(err)
+		}
+		for i := range in {
+			if len(in[i]) != len(orig[i]) {
+				t.Fatalf("input shard %d modified", i)
+			}
+		}
+		if !bytes.Equal(dst[4], shards[4]) || !bytes.Equal(dst[7], shards[7]) {
+			t.Fatal("reconstructed shard mismatch")
+		}
+
+		dst[0] = make([]byte, size)
+		if err := ReconstructInto(enc, in, dst); err != ErrInvalidInput {
+			t.Errorf("want ErrInvalidInput, got %v", err)
+		}
+		dst[0], dst[1] = nil, make([]byte, size-1)
+		if err := ReconstructInto(enc, in, dst); err != ErrShardSize {
+			t.Errorf("want ErrShardSize, got %v", err)
+		}
+		in[2] = nil
+		dst[1] = make([]byte, size)
+		if err := ReconstructInto(enc, in, dst); err != ErrTooFewShards {
+			t.Errorf("want ErrTooFewShards, got %v", err)
+		}
+	}
+}
@@ -13,10 +13,12 @@ package reedsolomon
 import (
 	"bytes"
 	"errors"
-	"fmt"
 	"io"
+	"math/bits"
 	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/klauspost/cpuid/v2"
 )
@@ -83,6 +85,8 @@ type Encoder interface {
 	// shards indicated by true values in the "required" parameter.
 	// The length of the "required" array must be equal to either Shards or DataShards.
 	// If the length is equal to DataShards, the reconstruction of parity shards will be ignored.
+	// If the length is equal to Shards, required parity shards are reconstructed as well,
+	// and missing shards that are not required are left empty.
 	//
 	// The length of "shards" array must be equal to Shards.
 	// You indicate that a shard is missing by setting it to nil or zero-length.
@@ -120,6 +124,9 @@ type Encoder interface {
 	//
 	// The data will not be copied, except for the last shard, so you
 	// should not modify the data of the input slice afterwards.
+	// The last shard is not copied either if the data size is a multiple
+	// of the shard size, or the capacity of data extends to one.
+	// See WithZeroCopySplit to get an error instead of copying.
 	Split(data []byte) ([][]byte, error)
 
 	// Join the shards and write the data segment to dst.
@@ -152,6 +159,36 @@ type Extensions interface {
 	AllocAligned(each int) [][]byte
 }
 
+// CostModeler is implemented by all encoders returned by New.
+// Use a type assertion to access it.
+type CostModeler interface {
+	// CostModel returns estimated multiplications and bytes moved
+	// per encoded byte and per reconstructed shard.
+	// See CostModel for details.
+	CostModel() CostModel
+}
+
+// PureGoer is implemented by all encoders returned by New.
+// Use a type assertion to access it.
+type PureGoer interface {
+	// PureGo returns true if the encoder only uses pure Go code,
+	// either because the package was built with the 'purego' or 'noasm' tag,
+	// or because no assembly is available for the CPU and options used.
+	PureGo() bool
+}
+
+// Recalibrator is implemented by all encoders returned by New.
+// Use a type assertion to access it.
+type Recalibrator interface {
+	// Recalibrate adjusts the number of goroutines used for optimal speed
+	// with the given shard size, like WithAutoGoroutines.
+	// The current GOMAXPROCS value is also taken into account.
+	// If shardSize <= 0, the value given by WithMaxGoroutines is used.
+	// Recalibrate is safe to call while other operations are running.
+	// See WithAutoRecalibrate for doing this automatically.
+	Recalibrate(shardSize int)
+}
+
 const (
 	codeGenMinSize           = 64
 	codeGenMinShards         = 3
@@ -172,8 +209,15 @@ type reedSolomon struct {
 	tree         *inversionTree
 	parity       [][]byte
 	o            options
-	mPoolSz      int
-	mPool        sync.Pool // Pool for temp matrices, etc
+	tmpSize      int                   // Size of temp matrices for codegen kernels.
+	jobs         freeList[codeJob]     // Scratch state for matrix multiplications.
+	decodes      freeList[decodeState] // Scratch state for reconstructions.
+
+	calib atomic.Pointer[calibration]
+	drift driftDetector
+
+	// extended contains encoders with more parity shards, see extend.
+	extended sync.Map // map[int]*extension
 }
 
 var _ = Extensions(&reedSolomon{})
@@ -194,8 +238,16 @@ func (r *reedSolomon) TotalShards() int {
 	return r.totalShards
 }
 
+func (r *reedSolomon) PureGo() bool {
+	return r.o.pureGo()
+}
+
 func (r *reedSolomon) AllocAligned(each int) [][]byte {
-	return AllocAligned(r.totalShards, each)
+	shards := AllocAligned(r.totalShards, each)
+	if nodes := r.numaNodes(); nodes != nil {
+		numaTouch(shards, nodes)
+	}
+	return shards
 }
 
 // ErrInvShardNum will be returned by New, if you attempt to create
@@ -217,11 +269,13 @@ var ErrNotSupported = errors.New("operation not supported")
 // The top square of the matrix is guaranteed to be an identity
 // matrix, which means that the data shards are unchanged after
 // encoding.
-func buildMatrix(dataShards, totalShards int) (matrix, error) {
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrix(dataShards, totalShards int, f *galoisField) (matrix, error) {
 	// Start with a Vandermonde matrix.  This matrix would work,
 	// in theory, but doesn't have the property that the data
 	// shards are unchanged after encoding.
-	vm, err := vandermonde(totalShards, dataShards)
+	vm, err := f.vandermonde(totalShards, dataShards)
 	if err != nil {
 		return nil, err
 	}
@@ -240,7 +294,96 @@ func buildMatrix(dataShards, totalShards int) (matrix, error) {
 		return nil, err
 	}
 
-	return vm.Multiply(topInv)
+	m, err := vm.Multiply(topInv)
+	if err != nil {
+		return nil, err
+	}
+	return f.fromStdMatrix(m), nil
+}
+
+// RawVandermondeMatrix returns the Vandermonde matrix used as the base
+// of the default encoding matrix, before it is made systematic.
+// Row r contains the powers of r, so the matrix has totalShards rows
+// and dataShards columns.
+// Since the top square is not the identity matrix, encoding with the full
+// matrix will also transform the data shards.
+func RawVandermondeMatrix(dataShards, totalShards int) ([][]byte, error) {
+	if err := checkMatrixShards(dataShards, totalShards); err != nil {
+		return nil, err
+	}
+	return vandermonde(totalShards, dataShards)
+}
+
+// VandermondeMatrix returns the default encoding matrix, which has
+// totalShards rows and dataShards columns, with the identity matrix
+// as the top square.
+// The matrix can be given to other implementations to produce
+// the same parity as an encoder with default options.
+func VandermondeMatrix(dataShards, totalShards int) ([][]byte, error) {
+	if err := checkMatrixShards(dataShards, totalShards); err != nil {
+		return nil, err
+	}
+	return buildMatrix(dataShards, totalShards, nil)
+}
+
+// JerasureMatrix returns the encoding matrix used with WithJerasureMatrix,
+// which is the same as the matrix of the Jerasure library.
+func JerasureMatrix(dataShards, totalShards int) ([][]byte, error) {
+	if err := checkMatrixShards(dataShards, totalShards); err != nil {
+		return nil, err
+	}
+	return buildMatrixJerasure(dataShards, totalShards, nil)
+}
+
+// PAR1Matrix returns the encoding matrix used with WithPAR1Matrix.
+// Note that not all combinations of dataShards rows are invertible.
+func PAR1Matrix(dataShards, totalShards int) ([][]byte, error) {
+	if err := checkMatrixShards(dataShards, totalShards); err != nil {
+		return nil, err
+	}
+	return buildMatrixPAR1(dataShards, totalShards, nil)
+}
+
+// CauchyMatrix returns the encoding matrix used with WithCauchyMatrix.
+func CauchyMatrix(dataShards, totalShards int) ([][]byte, error) {
+	if err := checkMatrixShards(dataShards, totalShards); err != nil {
+		return nil, err
+	}
+	return buildMatrixCauchy(dataShards, totalShards, nil)
+}
+
+// checkMatrixShards checks the shard counts given to the exported matrix constructors.
+func checkMatrixShards(dataShards, totalShards int) error {
+	if dataShards <= 0 || totalShards < dataShards {
+		return ErrInvShardNum
+	}
+	if totalShards > 256 {
+		return ErrMaxShardNum
+	}
+	return nil
+}
+
+// buildMatrixRawVandermonde creates an encoding matrix where the parity rows
+// are taken directly from the raw Vandermonde matrix, skipping the
+// systematizing step.
+//
+// The top square of the matrix is the identity matrix.
+// Unlike buildMatrix, not every square subset of rows is guaranteed to be
+// invertible.
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrixRawVandermonde(dataShards, totalShards int, f *galoisField) (matrix, error) {
+	vm, err := f.vandermonde(totalShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+	for r := 0; r < dataShards; r++ {
+		for c := range vm[r] {
+			vm[r][c] = 0
+		}
+		vm[r][r] = 1
+	}
+	return f.fromStdMatrix(vm), nil
 }
 
 // buildMatrixJerasure creates the same encoding matrix as Jerasure library
@@ -248,11 +391,13 @@ func buildMatrix(dataShards, totalShards int) (matrix, error) {
 // The top square of the matrix is guaranteed to be an identity
 // matrix, which means that the data shards are unchanged after
 // encoding.
-func buildMatrixJerasure(dataShards, totalShards int) (matrix, error) {
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrixJerasure(dataShards, totalShards int, f *galoisField) (matrix, error) {
 	// Start with a Vandermonde matrix.  This matrix would work,
 	// in theory, but doesn't have the property that the data
 	// shards are unchanged after encoding.
-	vm, err := vandermonde(totalShards, dataShards)
+	vm, err := f.vandermonde(totalShards, dataShards)
 	if err != nil {
 		return nil, err
 	}
@@ -321,7 +466,105 @@ func buildMatrixJerasure(dataShards, totalShards int) (matrix, error) {
 		}
 	}
 
-	return vm, nil
+	return f.fromStdMatrix(vm), nil
+}
+
+// buildMatrixCauchyGood creates the same encoding matrix as
+// cauchy_good_general_coding_matrix in the Jerasure library.
+//
+// With two parity shards, the first parity row is all 1's and the second
+// contains the elements with the lowest bit matrix weight.
+// Otherwise the Cauchy matrix with X = 0..parity-1 and Y = parity.. is
+// scaled so the first parity row is all 1's, and each other parity row
+// is divided by the element that gives it the lowest weight.
+//
+// The top square of the matrix is the identity matrix.
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrixCauchyGood(dataShards, totalShards int, f *galoisField) (matrix, error) {
+	if totalShards > 256 {
+		return nil, ErrMaxShardNum
+	}
+	result, err := newMatrix(totalShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+	for r := 0; r < dataShards; r++ {
+		result[r][r] = 1
+	}
+	parity := result[dataShards:]
+	if len(parity) == 0 {
+		return f.fromStdMatrix(result), nil
+	}
+
+	if len(parity) == 2 {
+		// Jerasure uses a precomputed table of the elements sorted by weight.
+		best := make([]byte, 255)
+		for i := range best {
+			best[i] = byte(i + 1)
+		}
+		sort.SliceStable(best, func(i, j int) bool {
+			return cauchyOnes(best[i]) < cauchyOnes(best[j])
+		})
+		for c := range parity[0] {
+			parity[0][c] = 1
+		}
+		copy(parity[1], best)
+		return f.fromStdMatrix(result), nil
+	}
+
+	m := len(parity)
+	for r, row := range parity {
+		for c := range row {
+			row[c] = galOneOver(byte(r ^ (m + c)))
+		}
+	}
+	// Make the first row all 1's by scaling the columns.
+	for c, v := range parity[0] {
+		if v != 1 {
+			tmp := galOneOver(v)
+			for r := range parity {
+				parity[r][c] = galMultiply(parity[r][c], tmp)
+			}
+		}
+	}
+	// Divide each remaining row by the element giving the fewest ones.
+	for _, row := range parity[1:] {
+		best, bestIdx := rowOnes(row, 1), -1
+		for c, v := range row {
+			if v == 1 {
+				continue
+			}
+			if n := rowOnes(row, galOneOver(v)); n < best {
+				best, bestIdx = n, c
+			}
+		}
+		if bestIdx >= 0 {
+			tmp := galOneOver(row[bestIdx])
+			for c := range row {
+				row[c] = galMultiply(row[c], tmp)
+			}
+		}
+	}
+	return f.fromStdMatrix(result), nil
+}
+
+// cauchyOnes returns the number of ones in the 8x8 bit matrix of multiplying by v.
+func cauchyOnes(v byte) int {
+	n := 0
+	for i := 0; i < 8; i++ {
+		n += bits.OnesCount8(galMultiply(v, 1<<i))
+	}
+	return n
+}
+
+// rowOnes returns the number of ones in the bit matrices of row multiplied by scale.
+func rowOnes(row []byte, scale byte) int {
+	n := 0
+	for _, v := range row {
+		n += cauchyOnes(galMultiply(v, scale))
+	}
+	return n
 }
 
 // buildMatrixPAR1 creates the matrix to use for encoding according to
@@ -333,7 +576,9 @@ func buildMatrixJerasure(dataShards, totalShards int) (matrix, error) {
 // The top square of the matrix is guaranteed to be an identity
 // matrix, which means that the data shards are unchanged after
 // encoding.
-func buildMatrixPAR1(dataShards, totalShards int) (matrix, error) {
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrixPAR1(dataShards, totalShards int, f *galoisField) (matrix, error) {
 	result, err := newMatrix(totalShards, dataShards)
 	if err != nil {
 		return nil, err
@@ -347,14 +592,86 @@ func buildMatrixPAR1(dataShards, totalShards int) (matrix, error) {
 			result[r][r] = 1
 		} else {
 			for c := range row {
-				result[r][c] = galExp(byte(c+1), r-dataShards)
+				result[r][c] = galExp(f.toStdByte(byte(c+1)), r-dataShards)
 			}
 		}
 	}
-	return result, nil
+	return f.fromStdMatrix(result), nil
+}
+
+// buildMatrixZfec creates the same encoding matrix as the zfec library.
+//
+// zfec starts with a Vandermonde matrix where the first row is
+// evaluated at 0 and row r > 0 is evaluated at 2^(r-1), and multiplies
+// it by the inverse of the top square.
+// The top square of the matrix is therefore the identity matrix,
+// and any square subset of rows is invertible.
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrixZfec(dataShards, totalShards int, f *galoisField) (matrix, error) {
+	if totalShards > 256 {
+		return nil, ErrMaxShardNum
+	}
+	vm, err := newMatrix(totalShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+	vm[0][0] = 1
+	for r := 1; r < totalShards; r++ {
+		for c := range vm[r] {
+			vm[r][c] = galExp(2, ((r-1)*c)%255)
+		}
+	}
+
+	top, err := vm.SubMatrix(0, 0, dataShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+	topInv, err := top.Invert()
+	if err != nil {
+		return nil, err
+	}
+	m, err := vm.Multiply(topInv)
+	if err != nil {
+		return nil, err
+	}
+	return f.fromStdMatrix(m), nil
+}
+
+// buildMatrixISAL creates the same encoding matrix as gf_gen_rs_matrix
+// in Intel's ISA-L.
+//
+// The top square of the matrix is the identity matrix, and the parity
+// rows are a Vandermonde matrix evaluated at the powers of 2.
+// Not every square subset of rows is guaranteed to be invertible.
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrixISAL(dataShards, totalShards int, f *galoisField) (matrix, error) {
+	result, err := newMatrix(totalShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := byte(1)
+	for r, row := range result {
+		if r < dataShards {
+			result[r][r] = 1
+			continue
+		}
+		p := byte(1)
+		for c := range row {
+			result[r][c] = p
+			p = galMultiply(p, gen)
+		}
+		gen = galMultiply(gen, 2)
+	}
+	return f.fromStdMatrix(result), nil
 }
 
-func buildMatrixCauchy(dataShards, totalShards int) (matrix, error) {
+// buildMatrixCauchy creates a systematic Cauchy matrix.
+//
+// If f is non-nil the matrix is built in that field.
+func buildMatrixCauchy(dataShards, totalShards int, f *galoisField) (matrix, error) {
 	result, err := newMatrix(totalShards, dataShards)
 	if err != nil {
 		return nil, err
@@ -367,11 +684,40 @@ func buildMatrixCauchy(dataShards, totalShards int) (matrix, error) {
 			result[r][r] = 1
 		} else {
 			for c := range row {
-				result[r][c] = invTable[(byte(r ^ c))]
+				result[r][c] = invTable[f.toStdByte(byte(r^c))]
 			}
 		}
 	}
-	return result, nil
+	return f.fromStdMatrix(result), nil
+}
+
+// buildMatrixCM256 creates the same systematic Cauchy matrix as the CM256 library.
+//
+// The parity row with index x has the elements (y ^ k) / (x ^ y),
+// where y is the column and k the number of data shards,
+// so the first parity row contains only 1's.
+//
+// If f is non-nil the matrix is built in that field.
+// The indexes are interpreted as elements of f.
+func buildMatrixCM256(dataShards, totalShards int, f *galoisField) (matrix, error) {
+	if totalShards > 256 {
+		return nil, ErrMaxShardNum
+	}
+	result, err := newMatrix(totalShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+
+	for r, row := range result {
+		if r < dataShards {
+			result[r][r] = 1
+			continue
+		}
+		for c := range row {
+			result[r][c] = galDivide(f.toStdByte(byte(c^dataShards)), f.toStdByte(byte(r^c)))
+		}
+	}
+	return f.fromStdMatrix(result), nil
 }
 
 // buildXorMatrix can be used to build a matrix with pure XOR
@@ -407,7 +753,9 @@ func buildXorMatrix(dataShards, totalShards int) (matrix, error) {
 // restrictions for a total larger than 256:
 //
 //   - Shard sizes must be multiple of 64
-//   - The methods Join/Split/Update/EncodeIdx are not supported
+//   - The method Update is not supported
+//
+// Up to 1<<24 total shards are supported with WithLeopardGF32.
 //
 // If no options are supplied, default options are used.
 func New(dataShards, parityShards int, opts ...Option) (Encoder, error) {
@@ -416,8 +764,92 @@ func New(dataShards, parityShards int, opts ...Option) (Encoder, error) {
 		opt(&o)
 	}
 
+	if o.useCM256Matrix && o.fieldRep == (FieldRepresentation{}) {
+		o.fieldRep = FieldCM256
+	}
+	if o.fieldRep != (FieldRepresentation{}) && o.fieldRep != FieldStandard {
+		if o.withLeopard != leopardAsNeeded {
+			return nil, ErrNotSupported
+		}
+		var err error
+		o.field, err = newGaloisField(o.fieldRep)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.constantTime && o.withLeopard != leopardAsNeeded {
+		return nil, ErrNotSupported
+	}
+
+	if o.backend != nil && (o.field != nil || o.constantTime || !o.backend.Available()) {
+		o.backend = nil
+	}
+
+	if o.shardHash != nil {
+		return newChecksum(dataShards, parityShards, o)
+	}
+
+	if len(o.classes) > 0 {
+		return newPriority(dataShards, parityShards, o)
+	}
+
+	if o.useRDP && parityShards == 2 {
+		if o.withLeopard != leopardAsNeeded || o.constantTime || o.field != nil || o.usePAR2 || o.useClay || o.usePiggyback {
+			return nil, ErrNotSupported
+		}
+		return newRDP(dataShards, o)
+	}
+
+	if o.usePiggyback {
+		if o.useClay {
+			return nil, ErrNotSupported
+		}
+		return newPiggyback(dataShards, parityShards, o)
+	}
+
+	if o.useClay {
+		if o.withLeopard != leopardAsNeeded || o.constantTime || o.field != nil || o.usePAR2 {
+			return nil, ErrNotSupported
+		}
+		return newClay(dataShards, parityShards, o)
+	}
+
+	if o.usePAR2 {
+		if o.withLeopard != leopardAsNeeded || o.constantTime || o.field != nil {
+			return nil, ErrNotSupported
+		}
+		return newPAR2(dataShards, parityShards, o)
+	}
+
+	if o.useGF16Matrix {
+		if o.withLeopard != leopardAsNeeded || o.constantTime || o.field != nil {
+			return nil, ErrNotSupported
+		}
+		return newGF16Matrix(dataShards, parityShards, o)
+	}
+
+	if o.useGF4 {
+		if o.withLeopard != leopardAsNeeded || o.constantTime || o.field != nil {
+			return nil, ErrNotSupported
+		}
+		return newGF4(dataShards, parityShards, o)
+	}
+
+	if o.usePrime {
+		if o.withLeopard != leopardAsNeeded || o.constantTime || o.field != nil {
+			return nil, ErrNotSupported
+		}
+		return newPrime(dataShards, parityShards, o)
+	}
+
 	//totShards := dataShards + parityShards
 	switch {
+	case o.withLeopard == leopardGF32 && parityShards > 0:
+		if o.constantTime || o.field != nil {
+			return nil, ErrNotSupported
+		}
+		return newFF32(dataShards, parityShards, o)
 	//case o.withLeopard == leopardGF16 && parityShards > 0 || totShards > 256:
 	case o.withLeopard == leopardGF16 && parityShards > 0:
 		return newFF16(dataShards, parityShards, o)
@@ -427,6 +859,9 @@ func New(dataShards, parityShards int, opts ...Option) (Encoder, error) {
 	//if totShards > 256 {
 	//	return nil, ErrMaxShardNum
 	//}
+	if dataShards+parityShards > 65536 {
+		return nil, ErrMaxShardNum
+	}
 
 	r := reedSolomon{
 		dataShards:   dataShards,
@@ -445,6 +880,8 @@ func New(dataShards, parityShards int, opts ...Option) (Encoder, error) {
 
 	var err error
 	switch {
+	case r.o.customGenerator != nil:
+		r.m, err = buildMatrixGenerator(r.o.customGenerator, dataShards, r.totalShards, r.o.field)
 	case r.o.customMatrix != nil:
 		if len(r.o.customMatrix) < parityShards {
 			return nil, errors.New("coding matrix must contain at least parityShards rows")
@@ -464,150 +901,175 @@ func New(dataShards, parityShards int, opts ...Option) (Encoder, error) {
 	case r.o.fastOneParity && parityShards == 1:
 		r.m, err = buildXorMatrix(dataShards, r.totalShards)
 	case r.o.useCauchy:
-		r.m, err = buildMatrixCauchy(dataShards, r.totalShards)
+		r.m, err = buildMatrixCauchy(dataShards, r.totalShards, r.o.field)
 	case r.o.usePAR1Matrix:
-		r.m, err = buildMatrixPAR1(dataShards, r.totalShards)
+		r.m, err = buildMatrixPAR1(dataShards, r.totalShards, r.o.field)
 	case r.o.useJerasureMatrix:
-		r.m, err = buildMatrixJerasure(dataShards, r.totalShards)
+		r.m, err = buildMatrixJerasure(dataShards, r.totalShards, r.o.field)
+	case r.o.useCauchyGood:
+		r.m, err = buildMatrixCauchyGood(dataShards, r.totalShards, r.o.field)
+	case r.o.useRawVandermonde:
+		r.m, err = buildMatrixRawVandermonde(dataShards, r.totalShards, r.o.field)
+	case r.o.useZfecMatrix:
+		r.m, err = buildMatrixZfec(dataShards, r.totalShards, r.o.field)
+	case r.o.useISALMatrix:
+		r.m, err = buildMatrixISAL(dataShards, r.totalShards, r.o.field)
+	case r.o.useCM256Matrix:
+		r.m, err = buildMatrixCM256(dataShards, r.totalShards, r.o.field)
 	default:
-		r.m, err = buildMatrix(dataShards, r.totalShards)
+		r.m, err = buildMatrix(dataShards, r.totalShards, r.o.field)
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	if r.o.minSplitSize <= 0 {
+		// Set minsplit as high as we can, but still have parity in L1.
+		cacheSize := cpuid.CPU.Cache.L1D
+		if cacheSize <= 0 {
+			cacheSize = 32 << 10
+		}
+
+		r.o.minSplitSize = cacheSize / (parityShards + 1)
+		// Min 1K
+		if r.o.minSplitSize < 1024 {
+			r.o.minSplitSize = 1024
+		}
+	}
+
+	r.calib.Store(r.calibrate(r.o.shardSize))
+
+	if n := r.o.keptStates(); n > 0 {
+		r.jobs.keep(n)
+		r.decodes.keep(n)
+	}
+	if r.o.zeroAlloc {
+		if r.o.persistentWorkers <= 0 {
+			r.o.persistentWorkers = r.o.maxGoroutines
+		}
+	}
+	if r.o.persistentWorkers > 0 && r.o.scheduler == nil {
+		r.o.scheduler = newWorkerPool(r.o.persistentWorkers)
+	}
+
+	// Inverted matrices are cached in a tree keyed by the indices
+	// of the invalid rows of the data to reconstruct.
+	// The inversion root node will have the identity matrix as
+	// its inversion matrix because it implies there are no errors
+	// with the original data.
+	if r.o.inversionCache {
+		r.tree = newInversionTree(dataShards, parityShards)
+		r.tree.maxEntries = r.o.inversionMaxEntries
+		r.tree.maxBytes = r.o.inversionMaxBytes
+	}
+
+	if checkEnabled {
+		checkSystematic(r.m, dataShards)
+	}
+
+	r.parity = make([][]byte, parityShards)
+	for i := range r.parity {
+		r.parity[i] = r.m[dataShards+i]
+	}
+
+	if codeGen /* && r.o.useAVX2 */ {
+		r.tmpSize = r.dataShards * r.parityShards * 2 * 32
+	}
+
+	if r.o.autoTune {
+		r.autoTune()
+	}
+	return &r, err
+}
+
+// calibrate returns the parameters for splitting work
+// between goroutines, optimized for the given shard size.
+// If shardSize <= 0, the number of goroutines given with WithMaxGoroutines is used.
+func (r *reedSolomon) calibrate(shardSize int) *calibration {
+	c := &calibration{maxGoroutines: r.o.maxGoroutines, shardSize: shardSize, procs: runtime.GOMAXPROCS(0)}
+
 	// Calculate what we want per round
-	r.o.perRound = cpuid.CPU.Cache.L2
-	if r.o.perRound < 128<<10 {
-		r.o.perRound = 128 << 10
+	c.perRound = cpuid.CPU.Cache.L2
+	if c.perRound < 128<<10 {
+		c.perRound = 128 << 10
 	}
 
 	_, _, useCodeGen := r.hasCodeGen(codeGenMinSize, codeGenMaxInputs, codeGenMaxOutputs)
 
-	divide := parityShards + 1
-	if codeGen && useCodeGen && (dataShards > codeGenMaxInputs || parityShards > codeGenMaxOutputs) {
+	divide := r.parityShards + 1
+	if codeGen && useCodeGen && (r.dataShards > codeGenMaxInputs || r.parityShards > codeGenMaxOutputs) {
 		// Base on L1 cache if we have many inputs.
-		r.o.perRound = cpuid.CPU.Cache.L1D
-		if r.o.perRound < 32<<10 {
-			r.o.perRound = 32 << 10
+		c.perRound = cpuid.CPU.Cache.L1D
+		if c.perRound < 32<<10 {
+			c.perRound = 32 << 10
 		}
 		divide = 0
-		if dataShards > codeGenMaxInputs {
+		if r.dataShards > codeGenMaxInputs {
 			divide += codeGenMaxInputs
 		} else {
-			divide += dataShards
+			divide += r.dataShards
 		}
-		if parityShards > codeGenMaxInputs {
+		if r.parityShards > codeGenMaxInputs {
 			divide += codeGenMaxOutputs
 		} else {
-			divide += parityShards
+			divide += r.parityShards
 		}
 	}
 
-	if cpuid.CPU.ThreadsPerCore > 1 && r.o.maxGoroutines > cpuid.CPU.PhysicalCores {
+	if cpuid.CPU.ThreadsPerCore > 1 && c.maxGoroutines > cpuid.CPU.PhysicalCores {
 		// If multiple threads per core, make sure they don't contend for cache.
-		r.o.perRound /= cpuid.CPU.ThreadsPerCore
+		c.perRound /= cpuid.CPU.ThreadsPerCore
 	}
 
 	// 1 input + parity must fit in cache, and we add one more to be safer.
-	r.o.perRound = r.o.perRound / divide
+	c.perRound = c.perRound / divide
 	// Align to 64 bytes.
-	r.o.perRound = ((r.o.perRound + 63) / 64) * 64
+	c.perRound = ((c.perRound + 63) / 64) * 64
 
 	// Final sanity check...
-	if r.o.perRound < 1<<10 {
-		r.o.perRound = 1 << 10
-	}
-
-	if r.o.minSplitSize <= 0 {
-		// Set minsplit as high as we can, but still have parity in L1.
-		cacheSize := cpuid.CPU.Cache.L1D
-		if cacheSize <= 0 {
-			cacheSize = 32 << 10
-		}
-
-		r.o.minSplitSize = cacheSize / (parityShards + 1)
-		// Min 1K
-		if r.o.minSplitSize < 1024 {
-			r.o.minSplitSize = 1024
-		}
+	if c.perRound < 1<<10 {
+		c.perRound = 1 << 10
 	}
 
-	if r.o.shardSize > 0 {
-		p := runtime.GOMAXPROCS(0)
-		if p == 1 || r.o.shardSize <= r.o.minSplitSize*2 {
+	if shardSize > 0 {
+		p := c.procs
+		if p == 1 || shardSize <= r.o.minSplitSize*2 {
 			// Not worth it.
-			r.o.maxGoroutines = 1
+			c.maxGoroutines = 1
 		} else {
-			g := r.o.shardSize / r.o.perRound
+			g := shardSize / c.perRound
 
-			// Overprovision by a factor of 2.
-			if g < p*2 && r.o.perRound > r.o.minSplitSize*2 {
-				g = p * 2
-				r.o.perRound /= 2
+			// Overprovision, by a factor of 2 unless the CPU has slower cores.
+			over := archProfiles[cpuArch()].overprovision
+			if g < p*over && c.perRound > r.o.minSplitSize*over {
+				g = p * over
+				c.perRound /= over
 			}
 
 			// Have g be multiple of p
 			g += p - 1
 			g -= g % p
 
-			r.o.maxGoroutines = g
+			c.maxGoroutines = g
 		}
 	}
 
 	// Generated AVX2 does not need data to stay in L1 cache between runs.
 	// We will be purely limited by RAM speed.
-	if useCodeGen && r.o.maxGoroutines > codeGenMaxGoroutines {
-		r.o.maxGoroutines = codeGenMaxGoroutines
+	if useCodeGen && c.maxGoroutines > codeGenMaxGoroutines {
+		c.maxGoroutines = codeGenMaxGoroutines
 	}
 
-	if _, _, useGFNI := r.canGFNI(codeGenMinSize, codeGenMaxInputs, codeGenMaxOutputs); useGFNI && r.o.maxGoroutines > gfniCodeGenMaxGoroutines {
-		r.o.maxGoroutines = gfniCodeGenMaxGoroutines
+	if _, _, useGFNI := r.canGFNI(codeGenMinSize, codeGenMaxInputs, codeGenMaxOutputs); useGFNI && c.maxGoroutines > gfniCodeGenMaxGoroutines {
+		c.maxGoroutines = gfniCodeGenMaxGoroutines
 	}
+	return c
+}
 
-	// Inverted matrices are cached in a tree keyed by the indices
-	// of the invalid rows of the data to reconstruct.
-	// The inversion root node will have the identity matrix as
-	// its inversion matrix because it implies there are no errors
-	// with the original data.
-	if r.o.inversionCache {
-		r.tree = newInversionTree(dataShards, parityShards)
-	}
-
-	r.parity = make([][]byte, parityShards)
-	for i := range r.parity {
-		r.parity[i] = r.m[dataShards+i]
-	}
-
-	if codeGen /* && r.o.useAVX2 */ {
-		sz := r.dataShards * r.parityShards * 2 * 32
-		r.mPool.New = func() interface{} {
-			return AllocAligned(1, sz)[0]
-		}
-		r.mPoolSz = sz
-	}
-	return &r, err
-}
-
-func (r *reedSolomon) getTmpSlice() []byte {
-	return r.mPool.Get().([]byte)
-}
-
-func (r *reedSolomon) putTmpSlice(b []byte) {
-	if b != nil && cap(b) >= r.mPoolSz {
-		r.mPool.Put(b[:r.mPoolSz])
-		return
-	}
-	if false {
-		// Sanity check
-		panic(fmt.Sprintf("got short tmp returned, want %d, got %d", r.mPoolSz, cap(b)))
-	}
-}
-
-// ErrTooFewShards is returned if too few shards where given to
-// Encode/Verify/Reconstruct/Update. It will also be returned from Reconstruct
-// if there were too few shards to reconstruct the missing data.
-var ErrTooFewShards = errors.New("too few shards given")
+// ErrTooFewShards is returned if too few shards where given to
+// Encode/Verify/Reconstruct/Update. It will also be returned from Reconstruct
+// if there were too few shards to reconstruct the missing data.
+var ErrTooFewShards = errors.New("too few shards given")
 
 // Encode parity for a set of data shards.
 // An array 'shards' containing data shards followed by parity shards.
@@ -624,12 +1086,17 @@ func (r *reedSolomon) Encode(shards [][]byte) error {
 	if err != nil {
 		return err
 	}
+	if err := r.checkAligned(shards); err != nil {
+		return err
+	}
 
 	// Get the slice of output buffers.
 	output := shards[r.dataShards:]
 
 	// Do the coding.
-	r.codeSomeShards(r.parity, shards[0:r.dataShards], output[:r.parityShards], len(shards[0]))
+	p := newProgress(r.o.progress, int64(len(shards[0])))
+	r.codeSomeShardsProgress(r.parity, shards[0:r.dataShards], output[:r.parityShards], len(shards[0]), p)
+	p.finish()
 	return nil
 }
 
@@ -638,6 +1105,7 @@ func (r *reedSolomon) Encode(shards [][]byte) error {
 // Data shards should only be delivered once. There is no check for this.
 // The parity shards will always be updated and the data shards will remain the unchanged.
 func (r *reedSolomon) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	cal := r.calib.Load()
 	if len(parity) != r.parityShards {
 		return ErrTooFewShards
 	}
@@ -655,21 +1123,26 @@ func (r *reedSolomon) EncodeIdx(dataShard []byte, idx int, parity [][]byte) erro
 		return ErrShardSize
 	}
 
-	if codeGen && len(dataShard) >= r.o.perRound && len(parity) >= codeGenMinShards && (pshufb || r.o.useAvx512GFNI || r.o.useAvxGNFI) {
-		m := make([][]byte, r.parityShards)
+	if codeGen && len(dataShard) >= cal.perRound && len(parity) >= codeGenMinShards && (pshufb || r.o.useAvx512GFNI || r.o.useAvxGNFI) {
+		st := r.decodes.get()
+		defer r.putDecodeState(st)
+		m := reuse(st.matrixRows, r.parityShards)
 		for iRow := range m {
 			m[iRow] = r.parity[iRow][idx : idx+1]
 		}
+		in := reuse(st.subShards, 1)
+		in[0] = dataShard
+		st.matrixRows, st.subShards = m, in
 		if r.o.useAvx512GFNI || r.o.useAvxGNFI {
-			r.codeSomeShardsGFNI(m, [][]byte{dataShard}, parity, len(dataShard), false, nil, nil)
+			r.codeSomeShardsGFNI(m, in, parity, len(dataShard), false, nil, nil)
 		} else {
-			r.codeSomeShardsAVXP(m, [][]byte{dataShard}, parity, len(dataShard), false, nil, nil)
+			r.codeSomeShardsAVXP(m, in, parity, len(dataShard), false, nil, nil)
 		}
 		return nil
 	}
 
 	// Process using no goroutines for now.
-	start, end := 0, r.o.perRound
+	start, end := 0, cal.perRound
 	if end > len(dataShard) {
 		end = len(dataShard)
 	}
@@ -680,7 +1153,7 @@ func (r *reedSolomon) EncodeIdx(dataShard []byte, idx int, parity [][]byte) erro
 			galMulSliceXor(r.parity[iRow][idx], in, parity[iRow][start:end], &r.o)
 		}
 		start = end
-		end += r.o.perRound
+		end += cal.perRound
 		if end > len(dataShard) {
 			end = len(dataShard)
 		}
@@ -709,6 +1182,12 @@ func (r *reedSolomon) Update(shards [][]byte, newDatashards [][]byte) error {
 	if err != nil {
 		return err
 	}
+	if err := r.checkAligned(shards); err != nil {
+		return err
+	}
+	if err := r.checkAligned(newDatashards); err != nil {
+		return err
+	}
 
 	for i := range newDatashards {
 		if newDatashards[i] != nil && shards[i] == nil {
@@ -732,11 +1211,12 @@ func (r *reedSolomon) Update(shards [][]byte, newDatashards [][]byte) error {
 }
 
 func (r *reedSolomon) updateParityShards(matrixRows, oldinputs, newinputs, outputs [][]byte, outputCount, byteCount int) {
+	cal := r.calib.Load()
 	if len(outputs) == 0 {
 		return
 	}
 
-	if r.o.maxGoroutines > 1 && byteCount > r.o.minSplitSize {
+	if cal.maxGoroutines > 1 && byteCount > r.o.minSplitSize {
 		r.updateParityShardsP(matrixRows, oldinputs, newinputs, outputs, outputCount, byteCount)
 		return
 	}
@@ -756,8 +1236,9 @@ func (r *reedSolomon) updateParityShards(matrixRows, oldinputs, newinputs, outpu
 }
 
 func (r *reedSolomon) updateParityShardsP(matrixRows, oldinputs, newinputs, outputs [][]byte, outputCount, byteCount int) {
+	cal := r.calib.Load()
 	var wg sync.WaitGroup
-	do := byteCount / r.o.maxGoroutines
+	do := byteCount / cal.maxGoroutines
 	if do < r.o.minSplitSize {
 		do = r.o.minSplitSize
 	}
@@ -797,6 +1278,9 @@ func (r *reedSolomon) Verify(shards [][]byte) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if err := r.checkAligned(shards); err != nil {
+		return false, err
+	}
 
 	// Slice of buffers being checked.
 	toCheck := shards[r.dataShards:]
@@ -818,32 +1302,67 @@ func (r *reedSolomon) codeSomeShards(matrixRows, inputs, outputs [][]byte, byteC
 	if len(outputs) == 0 {
 		return
 	}
+	if checkEnabled {
+		checkSlices(matrixRows, inputs, outputs, 0, byteCount)
+	}
+	if r.o.requireAligned && byteCount%shardAlign != 0 && alignedShards(inputs, byteCount) && alignedShards(outputs, byteCount) {
+		// Process the padding of the shards, so the kernels process all bytes.
+		// The lengths are extended on a copy of the slices,
+		// since the shards of the caller must not be modified.
+		j := r.jobs.get()
+		defer r.putPadded(j)
+		byteCount = alignedSize(byteCount)
+		j.padded = appendShardsLen(j.padded[:0], inputs, byteCount)
+		n := len(j.padded)
+		j.padded = appendShardsLen(j.padded, outputs, byteCount)
+		inputs, outputs = j.padded[:n:n], j.padded[n:]
+	}
+	if r.o.autoRecalibrate {
+		r.checkDrift(byteCount)
+	}
+	if r.o.rateLimit != nil {
+		r.codeSomeShardsLimited(matrixRows, inputs, outputs, byteCount, nil)
+		return
+	}
+	r.codeSomeShardsBlock(matrixRows, inputs, outputs, byteCount)
+}
+
+// codeSomeShardsBlock performs codeSomeShards without rate limiting.
+func (r *reedSolomon) codeSomeShardsBlock(matrixRows, inputs, outputs [][]byte, byteCount int) {
+	if r.o.backend != nil {
+		r.codeSomeShardsBackend(matrixRows, inputs, outputs, byteCount)
+		return
+	}
+	r.codeSomeShardsCPU(matrixRows, inputs, outputs, byteCount)
+}
+
+// codeSomeShardsCPU performs codeSomeShardsBlock on the CPU.
+func (r *reedSolomon) codeSomeShardsCPU(matrixRows, inputs, outputs [][]byte, byteCount int) {
+	cal := r.calib.Load()
 	if byteCount > r.o.minSplitSize {
 		r.codeSomeShardsP(matrixRows, inputs, outputs, byteCount)
 		return
 	}
 
 	// Process using no goroutines
-	start, end := 0, r.o.perRound
+	j := r.getCodeJob(matrixRows, inputs, outputs, cal.perRound, true)
+	defer r.putCodeJob(j)
+	start, end := 0, cal.perRound
 	if end > len(inputs[0]) {
 		end = len(inputs[0])
 	}
 	if galMulGFNI, galMulGFNIXor, useGFNI := r.canGFNI(byteCount, len(inputs), len(outputs)); useGFNI {
-		var gfni [codeGenMaxInputs * codeGenMaxOutputs]uint64
-		m := genGFNIMatrix(matrixRows, len(inputs), 0, len(outputs), gfni[:])
+		m := genGFNIMatrix(matrixRows, len(inputs), 0, len(outputs), j.gfni[:], r.o.gf())
 		start += (*galMulGFNI)(m, inputs, outputs, 0, byteCount)
 		end = len(inputs[0])
 	} else if galMulGen, _, ok := r.hasCodeGen(byteCount, len(inputs), len(outputs)); ok {
-		m := genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), r.o.vectorLength, r.getTmpSlice())
+		m := genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), r.o.vectorLength, j.tmpSlice(), r.o.gf())
 		start += (*galMulGen)(m, inputs, outputs, 0, byteCount)
-		r.putTmpSlice(m)
 		end = len(inputs[0])
 	} else if galMulGen, galMulGenXor, ok := r.hasCodeGen(byteCount, codeGenMaxInputs, codeGenMaxOutputs); len(inputs)+len(outputs) > codeGenMinShards && ok {
-		var gfni [codeGenMaxInputs * codeGenMaxOutputs]uint64
 		end = len(inputs[0])
 		inIdx := 0
-		m := r.getTmpSlice()
-		defer r.putTmpSlice(m)
+		m := j.tmpSlice()
 		ins := inputs
 		for len(ins) > 0 {
 			inPer := ins
@@ -858,14 +1377,14 @@ func (r *reedSolomon) codeSomeShards(matrixRows, inputs, outputs [][]byte, byteC
 					outPer = outPer[:codeGenMaxOutputs]
 				}
 				if useGFNI {
-					m := genGFNIMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), gfni[:])
+					m := genGFNIMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), j.gfni[:], r.o.gf())
 					if inIdx == 0 {
 						start = (*galMulGFNI)(m, inPer, outPer, 0, byteCount)
 					} else {
 						start = (*galMulGFNIXor)(m, inPer, outPer, 0, byteCount)
 					}
 				} else {
-					m = genCodeGenMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), r.o.vectorLength, m)
+					m = genCodeGenMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), r.o.vectorLength, m, r.o.gf())
 					if inIdx == 0 {
 						start = (*galMulGen)(m, inPer, outPer, 0, byteCount)
 					} else {
@@ -894,7 +1413,7 @@ func (r *reedSolomon) codeSomeShards(matrixRows, inputs, outputs [][]byte, byteC
 			}
 		}
 		start = end
-		end += r.o.perRound
+		end += cal.perRound
 		if end > len(inputs[0]) {
 			end = len(inputs[0])
 		}
@@ -904,228 +1423,222 @@ func (r *reedSolomon) codeSomeShards(matrixRows, inputs, outputs [][]byte, byteC
 // Perform the same as codeSomeShards, but split the workload into
 // several goroutines.
 func (r *reedSolomon) codeSomeShardsP(matrixRows, inputs, outputs [][]byte, byteCount int) {
-	var wg sync.WaitGroup
-	gor := r.o.maxGoroutines
+	cal := r.calib.Load()
+	if checkEnabled {
+		checkSlices(matrixRows, inputs, outputs, 0, byteCount)
+	}
 
-	var genMatrix []byte
-	var gfniMatrix []uint64
 	galMulGen, _, useCodeGen := r.hasCodeGen(byteCount, len(inputs), len(outputs))
 	galMulGFNI, _, useGFNI := r.canGFNI(byteCount, len(inputs), len(outputs))
-	if useGFNI {
-		var tmp [codeGenMaxInputs * codeGenMaxOutputs]uint64
-		gfniMatrix = genGFNIMatrix(matrixRows, len(inputs), 0, len(outputs), tmp[:])
-	} else if useCodeGen {
-		genMatrix = genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), r.o.vectorLength, r.getTmpSlice())
-		defer r.putTmpSlice(genMatrix)
-	} else if galMulGFNI, galMulGFNIXor, useGFNI := r.canGFNI(byteCount/4, codeGenMaxInputs, codeGenMaxOutputs); useGFNI &&
-		byteCount < 10<<20 && len(inputs)+len(outputs) > codeGenMinShards {
+	if !useGFNI && !useCodeGen && byteCount < 10<<20 && len(inputs)+len(outputs) > codeGenMinShards {
 		// It appears there is a switchover point at around 10MB where
 		// Regular processing is faster...
-		r.codeSomeShardsGFNI(matrixRows, inputs, outputs, byteCount, true, galMulGFNI, galMulGFNIXor)
-		return
-	} else if galMulGen, galMulGenXor, ok := r.hasCodeGen(byteCount/4, codeGenMaxInputs, codeGenMaxOutputs); ok &&
-		byteCount < 10<<20 && len(inputs)+len(outputs) > codeGenMinShards {
-		// It appears there is a switchover point at around 10MB where
-		// Regular processing is faster...
-		r.codeSomeShardsAVXP(matrixRows, inputs, outputs, byteCount, true, galMulGen, galMulGenXor)
-		return
+		if galMulGFNI, galMulGFNIXor, ok := r.canGFNI(byteCount/4, codeGenMaxInputs, codeGenMaxOutputs); ok {
+			r.codeSomeShardsGFNI(matrixRows, inputs, outputs, byteCount, true, galMulGFNI, galMulGFNIXor)
+			return
+		}
+		if galMulGen, galMulGenXor, ok := r.hasCodeGen(byteCount/4, codeGenMaxInputs, codeGenMaxOutputs); ok {
+			r.codeSomeShardsAVXP(matrixRows, inputs, outputs, byteCount, true, galMulGen, galMulGenXor)
+			return
+		}
 	}
 
-	do := byteCount / gor
-	if do < r.o.minSplitSize {
-		do = r.o.minSplitSize
+	j := r.getCodeJob(matrixRows, inputs, outputs, cal.perRound, true)
+	defer r.putCodeJob(j)
+	if useGFNI {
+		j.galMulGFNI = galMulGFNI
+		j.gfniMatrix = genGFNIMatrix(matrixRows, len(inputs), 0, len(outputs), j.gfni[:], r.o.gf())
+	} else if useCodeGen {
+		j.galMulGen = galMulGen
+		j.genMatrix = genCodeGenMatrix(matrixRows, len(inputs), 0, len(outputs), r.o.vectorLength, j.tmpSlice(), r.o.gf())
 	}
+	r.runCodeJob(j, byteCount, cal.maxGoroutines)
+}
 
-	exec := func(start, stop int) {
-		if stop-start >= 64 {
-			if useGFNI {
-				start += (*galMulGFNI)(gfniMatrix, inputs, outputs, start, stop)
-			} else if useCodeGen {
-				start += (*galMulGen)(genMatrix, inputs, outputs, start, stop)
-			}
-		}
+// Perform the same as codeSomeShards, but split the workload into
+// several goroutines.
+// If clear is set, the first write will overwrite the output.
+func (r *reedSolomon) codeSomeShardsAVXP(matrixRows, inputs, outputs [][]byte, byteCount int, clear bool, galMulGen, galMulGenXor *func(matrix []byte, in [][]byte, out [][]byte, start int, stop int) int) {
+	cal := r.calib.Load()
+	if checkEnabled {
+		checkSlices(matrixRows, inputs, outputs, 0, byteCount)
+	}
+	j := r.getCodeJob(matrixRows, inputs, outputs, cal.perRound, clear)
+	defer r.putCodeJob(j)
+	if galMulGen != nil && galMulGenXor != nil {
+		j.galMulGen, j.galMulGenXor = galMulGen, galMulGenXor
+		j.makePlan(false)
+	}
+	r.runCodeJob(j, byteCount, cal.maxGoroutines)
+}
 
-		lstart, lstop := start, start+r.o.perRound
-		if lstop > stop {
-			lstop = stop
-		}
-		for lstart < stop {
-			for c := 0; c < len(inputs); c++ {
-				in := inputs[c][lstart:lstop]
-				for iRow := 0; iRow < len(outputs); iRow++ {
-					if c == 0 {
-						galMulSlice(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &r.o)
-					} else {
-						galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &r.o)
-					}
-				}
-			}
-			lstart = lstop
-			lstop += r.o.perRound
-			if lstop > stop {
-				lstop = stop
-			}
-		}
-		wg.Done()
+// Perform the same as codeSomeShards, but split the workload into
+// several goroutines.
+// If clear is set, the first write will overwrite the output.
+func (r *reedSolomon) codeSomeShardsGFNI(matrixRows, inputs, outputs [][]byte, byteCount int, clear bool, galMulGFNI, galMulGFNIXor *func(matrix []uint64, in, out [][]byte, start, stop int) int) {
+	cal := r.calib.Load()
+	if checkEnabled {
+		checkSlices(matrixRows, inputs, outputs, 0, byteCount)
 	}
-	if gor <= 1 {
-		wg.Add(1)
-		exec(0, byteCount)
-		return
+	j := r.getCodeJob(matrixRows, inputs, outputs, cal.perRound, clear)
+	defer r.putCodeJob(j)
+	if galMulGFNI != nil && galMulGFNIXor != nil {
+		j.galMulGFNI, j.galMulGFNIXor = galMulGFNI, galMulGFNIXor
+		j.makePlan(true)
 	}
+	r.runCodeJob(j, byteCount, cal.maxGoroutines)
+}
 
-	// Make sizes divisible by 64
-	do = (do + 63) & (^63)
-	start := 0
-	for start < byteCount {
-		if start+do > byteCount {
-			do = byteCount - start
-		}
+// codeJob is the state of a matrix multiplication split between goroutines.
+// Jobs are reused between operations, see getCodeJob.
+type codeJob struct {
+	r        *reedSolomon
+	wg       sync.WaitGroup
+	run      func(start, stop int) // exec, bound once to avoid allocating for each operation.
+	perRound int
 
-		wg.Add(1)
-		go exec(start, start+do)
-		start += do
+	matrixRows, inputs, outputs [][]byte
+	clear                       bool // Overwrite the outputs with the first input.
+
+	galMulGen, galMulGenXor   *func(matrix []byte, in, out [][]byte, start, stop int) int
+	galMulGFNI, galMulGFNIXor *func(matrix []uint64, in, out [][]byte, start, stop int) int
+
+	// Matrices for all inputs and outputs, when they can be processed at once.
+	genMatrix  []byte
+	gfniMatrix []uint64
+
+	// plan contains the parts of the matrix when there are too many inputs
+	// or outputs to process at once.
+	plan []codePlan
+
+	// padded contains the inputs and outputs extended to a multiple
+	// of shardAlign, see codeSomeShards.
+	padded [][]byte
+
+	// Storage for matrices.
+	gfni    [codeGenMaxInputs * codeGenMaxOutputs]uint64
+	gfniBuf []uint64
+	tmp     []byte
+}
+
+// codePlan is a part of the matrix of a codeJob.
+type codePlan struct {
+	input  [][]byte
+	output [][]byte
+	m      []byte   // For codegen kernels.
+	mGFNI  []uint64 // For GFNI kernels.
+	first  bool
+}
+
+// getCodeJob returns a job multiplying inputs by matrixRows into outputs.
+func (r *reedSolomon) getCodeJob(matrixRows, inputs, outputs [][]byte, perRound int, clear bool) *codeJob {
+	j := r.jobs.get()
+	if j.run == nil {
+		j.run = j.exec
+	}
+	// Jobs are shared with encoders created by Override, see withCPUOptions.
+	j.r = r
+	j.matrixRows, j.inputs, j.outputs = matrixRows, inputs, outputs
+	j.perRound, j.clear = perRound, clear
+	return j
+}
+
+// putCodeJob releases a job returned by getCodeJob.
+func (r *reedSolomon) putCodeJob(j *codeJob) {
+	j.matrixRows, j.inputs, j.outputs = nil, nil, nil
+	j.galMulGen, j.galMulGenXor, j.galMulGFNI, j.galMulGFNIXor = nil, nil, nil, nil
+	j.genMatrix, j.gfniMatrix = nil, nil
+	for i := range j.plan {
+		j.plan[i] = codePlan{}
 	}
-	wg.Wait()
+	j.plan = j.plan[:0]
+	r.jobs.put(j)
 }
 
-// Perform the same as codeSomeShards, but split the workload into
-// several goroutines.
-// If clear is set, the first write will overwrite the output.
-func (r *reedSolomon) codeSomeShardsAVXP(matrixRows, inputs, outputs [][]byte, byteCount int, clear bool, galMulGen, galMulGenXor *func(matrix []byte, in [][]byte, out [][]byte, start int, stop int) int) {
-	var wg sync.WaitGroup
-	gor := r.o.maxGoroutines
+// putPadded releases a job used for the padded shards of codeSomeShards.
+func (r *reedSolomon) putPadded(j *codeJob) {
+	for i := range j.padded {
+		j.padded[i] = nil
+	}
+	r.jobs.put(j)
+}
 
-	type state struct {
-		input  [][]byte
-		output [][]byte
-		m      []byte
-		first  bool
+// tmpSlice returns a buffer for codegen matrices of all inputs and outputs.
+func (j *codeJob) tmpSlice() []byte {
+	if cap(j.tmp) < j.r.tmpSize {
+		j.tmp = AllocAligned(1, j.r.tmpSize)[0]
 	}
-	// Make a plan...
-	plan := make([]state, 0, ((len(inputs)+codeGenMaxInputs-1)/codeGenMaxInputs)*((len(outputs)+codeGenMaxOutputs-1)/codeGenMaxOutputs))
+	return j.tmp[:j.r.tmpSize]
+}
 
-	tmp := r.getTmpSlice()
-	defer r.putTmpSlice(tmp)
+// makePlan splits the matrix into parts that the kernels can process at once.
+func (j *codeJob) makePlan(gfni bool) {
+	inputs, outputs, matrixRows := j.inputs, j.outputs, j.matrixRows
+	var tmp []byte
+	var tmpGFNI []uint64
+	if gfni {
+		if n := len(inputs) * len(outputs); cap(j.gfniBuf) < n {
+			j.gfniBuf = make([]uint64, n)
+		}
+		tmpGFNI = j.gfniBuf[:cap(j.gfniBuf)]
+	} else {
+		tmp = j.tmpSlice()
+	}
+	add := func(inPer, outPer [][]byte, inIdx, outIdx int) {
+		p := codePlan{input: inPer, output: outPer, first: inIdx == 0 && j.clear}
+		// Generate local matrix
+		if gfni {
+			p.mGFNI = genGFNIMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), tmpGFNI, j.r.o.gf())
+			tmpGFNI = tmpGFNI[len(p.mGFNI):]
+		} else {
+			p.m = genCodeGenMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), j.r.o.vectorLength, tmp, j.r.o.gf())
+			tmp = tmp[len(p.m):]
+		}
+		j.plan = append(j.plan, p)
+	}
 
 	// Flips between input first to output first.
 	// We put the smallest data load in the inner loop.
 	if len(inputs) > len(outputs) {
-		inIdx := 0
-		ins := inputs
-		for len(ins) > 0 {
-			inPer := ins
+		for inIdx := 0; inIdx < len(inputs); inIdx += codeGenMaxInputs {
+			inPer := inputs[inIdx:]
 			if len(inPer) > codeGenMaxInputs {
 				inPer = inPer[:codeGenMaxInputs]
 			}
-			outs := outputs
-			outIdx := 0
-			for len(outs) > 0 {
-				outPer := outs
+			for outIdx := 0; outIdx < len(outputs); outIdx += codeGenMaxOutputs {
+				outPer := outputs[outIdx:]
 				if len(outPer) > codeGenMaxOutputs {
 					outPer = outPer[:codeGenMaxOutputs]
 				}
-				// Generate local matrix
-				m := genCodeGenMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), r.o.vectorLength, tmp)
-				tmp = tmp[len(m):]
-				plan = append(plan, state{
-					input:  inPer,
-					output: outPer,
-					m:      m,
-					first:  inIdx == 0 && clear,
-				})
-				outIdx += len(outPer)
-				outs = outs[len(outPer):]
+				add(inPer, outPer, inIdx, outIdx)
 			}
-			inIdx += len(inPer)
-			ins = ins[len(inPer):]
 		}
 	} else {
-		outs := outputs
-		outIdx := 0
-		for len(outs) > 0 {
-			outPer := outs
+		for outIdx := 0; outIdx < len(outputs); outIdx += codeGenMaxOutputs {
+			outPer := outputs[outIdx:]
 			if len(outPer) > codeGenMaxOutputs {
 				outPer = outPer[:codeGenMaxOutputs]
 			}
-
-			inIdx := 0
-			ins := inputs
-			for len(ins) > 0 {
-				inPer := ins
+			for inIdx := 0; inIdx < len(inputs); inIdx += codeGenMaxInputs {
+				inPer := inputs[inIdx:]
 				if len(inPer) > codeGenMaxInputs {
 					inPer = inPer[:codeGenMaxInputs]
 				}
-				// Generate local matrix
-				m := genCodeGenMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), r.o.vectorLength, tmp)
-				tmp = tmp[len(m):]
-				//fmt.Println("bytes:", len(inPer)*r.o.perRound, "out:", len(outPer)*r.o.perRound)
-				plan = append(plan, state{
-					input:  inPer,
-					output: outPer,
-					m:      m,
-					first:  inIdx == 0 && clear,
-				})
-				inIdx += len(inPer)
-				ins = ins[len(inPer):]
+				add(inPer, outPer, inIdx, outIdx)
 			}
-			outIdx += len(outPer)
-			outs = outs[len(outPer):]
 		}
 	}
+}
 
+// runCodeJob runs j on byteCount bytes, split between up to gor goroutines.
+func (r *reedSolomon) runCodeJob(j *codeJob, byteCount, gor int) {
 	do := byteCount / gor
 	if do < r.o.minSplitSize {
 		do = r.o.minSplitSize
 	}
-
-	exec := func(start, stop int) {
-		defer wg.Done()
-		lstart, lstop := start, start+r.o.perRound
-		if lstop > stop {
-			lstop = stop
-		}
-		for lstart < stop {
-			if galMulGen != nil && galMulGenXor != nil && lstop-lstart >= minCodeGenSize {
-				// Execute plan...
-				var n int
-				for _, p := range plan {
-					if p.first {
-						n = (*galMulGen)(p.m, p.input, p.output, lstart, lstop)
-					} else {
-						n = (*galMulGenXor)(p.m, p.input, p.output, lstart, lstop)
-					}
-				}
-				lstart += n
-				if lstart == lstop {
-					lstop += r.o.perRound
-					if lstop > stop {
-						lstop = stop
-					}
-					continue
-				}
-			}
-
-			for c := range inputs {
-				in := inputs[c][lstart:lstop]
-				for iRow := 0; iRow < len(outputs); iRow++ {
-					if c == 0 && clear {
-						galMulSlice(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &r.o)
-					} else {
-						galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &r.o)
-					}
-				}
-			}
-			lstart = lstop
-			lstop += r.o.perRound
-			if lstop > stop {
-				lstop = stop
-			}
-		}
-	}
-	if gor == 1 {
-		wg.Add(1)
-		exec(0, byteCount)
+	if gor <= 1 {
+		j.wg.Add(1)
+		j.exec(0, byteCount)
 		return
 	}
 
@@ -1137,162 +1650,71 @@ func (r *reedSolomon) codeSomeShardsAVXP(matrixRows, inputs, outputs [][]byte, b
 			do = byteCount - start
 		}
 
-		wg.Add(1)
-		go exec(start, start+do)
+		j.wg.Add(1)
+		r.goExec(j.run, start, start+do, byteCount)
 		start += do
 	}
-	wg.Wait()
+	j.wg.Wait()
 }
 
-// Perform the same as codeSomeShards, but split the workload into
-// several goroutines.
-// If clear is set, the first write will overwrite the output.
-func (r *reedSolomon) codeSomeShardsGFNI(matrixRows, inputs, outputs [][]byte, byteCount int, clear bool, galMulGFNI, galMulGFNIXor *func(matrix []uint64, in, out [][]byte, start, stop int) int) {
-	var wg sync.WaitGroup
-	gor := r.o.maxGoroutines
-
-	type state struct {
-		input  [][]byte
-		output [][]byte
-		m      []uint64
-		first  bool
+// exec processes bytes start to stop of the job.
+func (j *codeJob) exec(start, stop int) {
+	defer j.wg.Done()
+	inputs, outputs, matrixRows := j.inputs, j.outputs, j.matrixRows
+	if stop-start >= 64 {
+		if j.gfniMatrix != nil {
+			start += (*j.galMulGFNI)(j.gfniMatrix, inputs, outputs, start, stop)
+		} else if j.genMatrix != nil {
+			start += (*j.galMulGen)(j.genMatrix, inputs, outputs, start, stop)
+		}
 	}
-	// Make a plan...
-	plan := make([]state, 0, ((len(inputs)+codeGenMaxInputs-1)/codeGenMaxInputs)*((len(outputs)+codeGenMaxOutputs-1)/codeGenMaxOutputs))
 
-	// Flips between input first to output first.
-	// We put the smallest data load in the inner loop.
-	if len(inputs) > len(outputs) {
-		inIdx := 0
-		ins := inputs
-		for len(ins) > 0 {
-			inPer := ins
-			if len(inPer) > codeGenMaxInputs {
-				inPer = inPer[:codeGenMaxInputs]
+	lstart, lstop := start, start+j.perRound
+	if lstop > stop {
+		lstop = stop
+	}
+	for lstart < stop {
+		if len(j.plan) > 0 && lstop-lstart >= minCodeGenSize {
+			// Execute plan...
+			var n int
+			for _, p := range j.plan {
+				switch {
+				case p.mGFNI != nil && p.first:
+					n = (*j.galMulGFNI)(p.mGFNI, p.input, p.output, lstart, lstop)
+				case p.mGFNI != nil:
+					n = (*j.galMulGFNIXor)(p.mGFNI, p.input, p.output, lstart, lstop)
+				case p.first:
+					n = (*j.galMulGen)(p.m, p.input, p.output, lstart, lstop)
+				default:
+					n = (*j.galMulGenXor)(p.m, p.input, p.output, lstart, lstop)
+				}
 			}
-			outs := outputs
-			outIdx := 0
-			for len(outs) > 0 {
-				outPer := outs
-				if len(outPer) > codeGenMaxOutputs {
-					outPer = outPer[:codeGenMaxOutputs]
+			lstart += n
+			if lstart == lstop {
+				lstop += j.perRound
+				if lstop > stop {
+					lstop = stop
 				}
-				// Generate local matrix
-				m := genGFNIMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), make([]uint64, len(inPer)*len(outPer)))
-				plan = append(plan, state{
-					input:  inPer,
-					output: outPer,
-					m:      m,
-					first:  inIdx == 0 && clear,
-				})
-				outIdx += len(outPer)
-				outs = outs[len(outPer):]
+				continue
 			}
-			inIdx += len(inPer)
-			ins = ins[len(inPer):]
 		}
-	} else {
-		outs := outputs
-		outIdx := 0
-		for len(outs) > 0 {
-			outPer := outs
-			if len(outPer) > codeGenMaxOutputs {
-				outPer = outPer[:codeGenMaxOutputs]
-			}
 
-			inIdx := 0
-			ins := inputs
-			for len(ins) > 0 {
-				inPer := ins
-				if len(inPer) > codeGenMaxInputs {
-					inPer = inPer[:codeGenMaxInputs]
+		for c := range inputs {
+			in := inputs[c][lstart:lstop]
+			for iRow := 0; iRow < len(outputs); iRow++ {
+				if c == 0 && j.clear {
+					galMulSlice(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &j.r.o)
+				} else {
+					galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &j.r.o)
 				}
-				// Generate local matrix
-				m := genGFNIMatrix(matrixRows[outIdx:], len(inPer), inIdx, len(outPer), make([]uint64, len(inPer)*len(outPer)))
-				//fmt.Println("bytes:", len(inPer)*r.o.perRound, "out:", len(outPer)*r.o.perRound)
-				plan = append(plan, state{
-					input:  inPer,
-					output: outPer,
-					m:      m,
-					first:  inIdx == 0 && clear,
-				})
-				inIdx += len(inPer)
-				ins = ins[len(inPer):]
 			}
-			outIdx += len(outPer)
-			outs = outs[len(outPer):]
 		}
-	}
-
-	do := byteCount / gor
-	if do < r.o.minSplitSize {
-		do = r.o.minSplitSize
-	}
-
-	exec := func(start, stop int) {
-		defer wg.Done()
-		lstart, lstop := start, start+r.o.perRound
+		lstart = lstop
+		lstop += j.perRound
 		if lstop > stop {
 			lstop = stop
 		}
-		for lstart < stop {
-			if galMulGFNI != nil && galMulGFNIXor != nil && lstop-lstart >= minCodeGenSize {
-				// Execute plan...
-				var n int
-				for _, p := range plan {
-					if p.first {
-						n = (*galMulGFNI)(p.m, p.input, p.output, lstart, lstop)
-					} else {
-						n = (*galMulGFNIXor)(p.m, p.input, p.output, lstart, lstop)
-					}
-				}
-				lstart += n
-				if lstart == lstop {
-					lstop += r.o.perRound
-					if lstop > stop {
-						lstop = stop
-					}
-					continue
-				}
-			}
-
-			for c := range inputs {
-				in := inputs[c][lstart:lstop]
-				for iRow := 0; iRow < len(outputs); iRow++ {
-					if c == 0 && clear {
-						galMulSlice(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &r.o)
-					} else {
-						galMulSliceXor(matrixRows[iRow][c], in, outputs[iRow][lstart:lstop], &r.o)
-					}
-				}
-			}
-			lstart = lstop
-			lstop += r.o.perRound
-			if lstop > stop {
-				lstop = stop
-			}
-		}
-	}
-
-	if gor == 1 {
-		wg.Add(1)
-		exec(0, byteCount)
-		return
 	}
-
-	// Make sizes divisible by 64
-	do = (do + 63) & (^63)
-	start := 0
-	for start < byteCount {
-		if start+do > byteCount {
-			do = byteCount - start
-		}
-
-		wg.Add(1)
-		go exec(start, start+do)
-		start += do
-	}
-	wg.Wait()
 }
 
 // checkSomeShards is mostly the same as codeSomeShards,
@@ -1302,6 +1724,9 @@ func (r *reedSolomon) checkSomeShards(matrixRows, inputs, toCheck [][]byte, byte
 	if len(toCheck) == 0 {
 		return true
 	}
+	if checkEnabled {
+		checkSlices(matrixRows, inputs, toCheck, 0, byteCount)
+	}
 
 	outputs := AllocAligned(len(toCheck), byteCount)
 	r.codeSomeShards(matrixRows, inputs, outputs, byteCount)
@@ -1400,6 +1825,8 @@ func (r *reedSolomon) ReconstructData(shards [][]byte) error {
 // shards indicated by true values in the "required" parameter.
 // The length of the "required" array must be equal to either Shards or DataShards.
 // If the length is equal to DataShards, the reconstruction of parity shards will be ignored.
+// If the length is equal to Shards, required parity shards are reconstructed as well,
+// and missing shards that are not required are left empty.
 //
 // The length of "shards" array must be equal to Shards.
 // You indicate that a shard is missing by setting it to nil or zero-length.
@@ -1435,6 +1862,9 @@ func (r *reedSolomon) reconstruct(shards [][]byte, dataOnly bool, required []boo
 	if err != nil {
 		return err
 	}
+	if err := r.checkAligned(shards); err != nil {
+		return err
+	}
 
 	shardSize := shardSize(shards)
 
@@ -1465,6 +1895,134 @@ func (r *reedSolomon) reconstruct(shards [][]byte, dataOnly bool, required []boo
 		return ErrTooFewShards
 	}
 
+	// Get the shards used as input for decoding, and the matrix
+	// that re-creates the data shards from them.
+	st := r.decodes.get()
+	defer r.putDecodeState(st)
+	subShards, dataDecodeMatrix, err := r.decodeMatrixState(shards, st)
+	if err != nil {
+		return err
+	}
+
+	// Re-create any data shards that were missing.
+	//
+	// The input to the coding is all of the shards we actually
+	// have, and the output is the missing data shards.  The computation
+	// is done using the special decode matrix we just built.
+	st.outputs = reuse(st.outputs, r.parityShards)
+	st.matrixRows = reuse(st.matrixRows, r.parityShards)
+	outputs, matrixRows := st.outputs, st.matrixRows
+	outputCount := 0
+
+	// Parity is computed from all data shards, so if any parity shard is required,
+	// missing data shards that are not required are recreated in temporary buffers.
+	needParity := !dataOnly
+	if needParity && required != nil {
+		needParity = false
+		for iShard := r.dataShards; iShard < r.totalShards; iShard++ {
+			if len(shards[iShard]) == 0 && required[iShard] {
+				needParity = true
+			}
+		}
+	}
+	passes := int64(1)
+	if needParity {
+		passes = 2
+	}
+	p := newProgress(r.o.progress, passes*int64(shardSize))
+	defer p.finish()
+
+	data := shards[:r.dataShards]
+	if needParity && required != nil {
+		data = make([][]byte, r.dataShards)
+		copy(data, shards[:r.dataShards])
+	}
+
+	for iShard := 0; iShard < r.dataShards; iShard++ {
+		if len(shards[iShard]) != 0 {
+			continue
+		}
+		switch {
+		case required == nil || required[iShard]:
+			if cap(shards[iShard]) >= shardSize && (!r.o.requireAligned || isAligned(shards[iShard][:shardSize])) {
+				shards[iShard] = shards[iShard][0:shardSize]
+			} else {
+				shards[iShard] = AllocAligned(1, shardSize)[0]
+			}
+			data[iShard] = shards[iShard]
+		case needParity:
+			data[iShard] = AllocAligned(1, shardSize)[0]
+		default:
+			continue
+		}
+		outputs[outputCount] = data[iShard]
+		matrixRows[outputCount] = dataDecodeMatrix[iShard]
+		outputCount++
+	}
+	r.codeSomeShardsProgress(matrixRows, subShards, outputs[:outputCount], shardSize, p)
+
+	if !needParity {
+		// Exit out early if we are only interested in the data shards
+		return nil
+	}
+
+	// Now that we have all of the data shards intact, we can
+	// compute any of the parity that is missing.
+	//
+	// The input to the coding is ALL of the data shards, including
+	// any that we just calculated.  The output is whichever of the
+	// data shards were missing.
+	outputCount = 0
+	for iShard := r.dataShards; iShard < r.totalShards; iShard++ {
+		if len(shards[iShard]) == 0 && (required == nil || required[iShard]) {
+			if cap(shards[iShard]) >= shardSize && (!r.o.requireAligned || isAligned(shards[iShard][:shardSize])) {
+				shards[iShard] = shards[iShard][0:shardSize]
+			} else {
+				shards[iShard] = AllocAligned(1, shardSize)[0]
+			}
+			outputs[outputCount] = shards[iShard]
+			matrixRows[outputCount] = r.parity[iShard-r.dataShards]
+			outputCount++
+		}
+	}
+	r.codeSomeShardsProgress(matrixRows, data, outputs[:outputCount], shardSize, p)
+	return nil
+}
+
+// putDecodeState releases a state used by reconstruct.
+func (r *reedSolomon) putDecodeState(st *decodeState) {
+	for _, s := range [][][]byte{st.subShards, st.outputs, st.matrixRows} {
+		for i := range s {
+			s[i] = nil
+		}
+	}
+	r.decodes.put(st)
+}
+
+// decodeMatrix returns the first DataShards present shards, and the inverted
+// matrix that re-creates the data shards from them.
+// The matrix is cached in the inversion tree.
+func (r *reedSolomon) decodeMatrix(shards [][]byte) ([][]byte, matrix, error) {
+	return r.decodeMatrixState(shards, &decodeState{})
+}
+
+// decodeState contains the scratch slices of a reconstruction.
+// States are reused between operations, see reconstruct.
+type decodeState struct {
+	subShards, outputs, matrixRows [][]byte
+	validIndices, invalidIndices   []int
+}
+
+// reuse returns s with length n, reusing its storage if possible.
+func reuse[T any](s []T, n int) []T {
+	if cap(s) < n {
+		return make([]T, n)
+	}
+	return s[:n]
+}
+
+// decodeMatrixState is decodeMatrix, using the slices of st.
+func (r *reedSolomon) decodeMatrixState(shards [][]byte, st *decodeState) ([][]byte, matrix, error) {
 	// Pull out an array holding just the shards that
 	// correspond to the rows of the submatrix.  These shards
 	// will be the input to the decoding process that re-creates
@@ -1472,9 +2030,10 @@ func (r *reedSolomon) reconstruct(shards [][]byte, dataOnly bool, required []boo
 	//
 	// Also, create an array of indices of the valid rows we do have
 	// and the invalid rows we don't have up until we have enough valid rows.
-	subShards := make([][]byte, r.dataShards)
-	validIndices := make([]int, r.dataShards)
-	invalidIndices := make([]int, 0)
+	st.subShards = reuse(st.subShards, r.dataShards)
+	st.validIndices = reuse(st.validIndices, r.dataShards)
+	st.invalidIndices = st.invalidIndices[:0]
+	subShards, validIndices, invalidIndices := st.subShards, st.validIndices, st.invalidIndices
 	subMatrixRow := 0
 	for matrixRow := 0; matrixRow < r.totalShards && subMatrixRow < r.dataShards; matrixRow++ {
 		if len(shards[matrixRow]) != 0 {
@@ -1485,6 +2044,7 @@ func (r *reedSolomon) reconstruct(shards [][]byte, dataOnly bool, required []boo
 			invalidIndices = append(invalidIndices, matrixRow)
 		}
 	}
+	st.invalidIndices = invalidIndices
 
 	// Attempt to get the cached inverted matrix out of the tree
 	// based on the indices of the invalid rows.
@@ -1509,68 +2069,29 @@ func (r *reedSolomon) reconstruct(shards [][]byte, dataOnly bool, required []boo
 		// generates the shard that we want to decode.  Note that
 		// since this matrix maps back to the original data, it can
 		// be used to create a data shard, but not a parity shard.
-		dataDecodeMatrix, err = subMatrix.Invert()
+		var err error
+		dataDecodeMatrix, err = r.o.field.invert(subMatrix)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		// Cache the inverted matrix in the tree for future use keyed on the
 		// indices of the invalid rows.
 		err = r.tree.InsertInvertedMatrix(invalidIndices, dataDecodeMatrix, r.totalShards)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 	}
 
-	// Re-create any data shards that were missing.
-	//
-	// The input to the coding is all of the shards we actually
-	// have, and the output is the missing data shards.  The computation
-	// is done using the special decode matrix we just built.
-	outputs := make([][]byte, r.parityShards)
-	matrixRows := make([][]byte, r.parityShards)
-	outputCount := 0
-
-	for iShard := 0; iShard < r.dataShards; iShard++ {
-		if len(shards[iShard]) == 0 && (required == nil || required[iShard]) {
-			if cap(shards[iShard]) >= shardSize {
-				shards[iShard] = shards[iShard][0:shardSize]
-			} else {
-				shards[iShard] = AllocAligned(1, shardSize)[0]
-			}
-			outputs[outputCount] = shards[iShard]
-			matrixRows[outputCount] = dataDecodeMatrix[iShard]
-			outputCount++
+	if checkEnabled {
+		subMatrix, _ := newMatrix(r.dataShards, r.dataShards)
+		for subMatrixRow, validIndex := range validIndices {
+			copy(subMatrix[subMatrixRow], r.m[validIndex][:r.dataShards])
 		}
+		checkInverse(subMatrix, dataDecodeMatrix, r.o.gf())
 	}
-	r.codeSomeShards(matrixRows, subShards, outputs[:outputCount], shardSize)
 
-	if dataOnly {
-		// Exit out early if we are only interested in the data shards
-		return nil
-	}
-
-	// Now that we have all of the data shards intact, we can
-	// compute any of the parity that is missing.
-	//
-	// The input to the coding is ALL of the data shards, including
-	// any that we just calculated.  The output is whichever of the
-	// data shards were missing.
-	outputCount = 0
-	for iShard := r.dataShards; iShard < r.totalShards; iShard++ {
-		if len(shards[iShard]) == 0 && (required == nil || required[iShard]) {
-			if cap(shards[iShard]) >= shardSize {
-				shards[iShard] = shards[iShard][0:shardSize]
-			} else {
-				shards[iShard] = AllocAligned(1, shardSize)[0]
-			}
-			outputs[outputCount] = shards[iShard]
-			matrixRows[outputCount] = r.parity[iShard-r.dataShards]
-			outputCount++
-		}
-	}
-	r.codeSomeShards(matrixRows, shards[:r.dataShards], outputs[:outputCount], shardSize)
-	return nil
+	return subShards, dataDecodeMatrix, nil
 }
 
 // ErrShortData will be returned by Split(), if there isn't enough data
@@ -1594,63 +2115,7 @@ var ErrShortData = errors.New("not enough data to fill the number of requested s
 // The data will not be copied, except for the last shard, so you
 // should not modify the data of the input slice afterwards.
 func (r *reedSolomon) Split(data []byte) ([][]byte, error) {
-	if len(data) == 0 {
-		return nil, ErrShortData
-	}
-	if r.totalShards == 1 {
-		return [][]byte{data}, nil
-	}
-
-	dataLen := len(data)
-	// Calculate number of bytes per data shard.
-	perShard := (len(data) + r.dataShards - 1) / r.dataShards
-	needTotal := r.totalShards * perShard
-
-	if cap(data) > len(data) {
-		if cap(data) > needTotal {
-			data = data[:needTotal]
-		} else {
-			data = data[:cap(data)]
-		}
-		clear := data[dataLen:]
-		for i := range clear {
-			clear[i] = 0
-		}
-	}
-
-	// Only allocate memory if necessary
-	var padding [][]byte
-	if len(data) < needTotal {
-		// calculate maximum number of full shards in `data` slice
-		fullShards := len(data) / perShard
-		padding = AllocAligned(r.totalShards-fullShards, perShard)
-
-		if dataLen > perShard*fullShards {
-			// Copy partial shards
-			copyFrom := data[perShard*fullShards : dataLen]
-			for i := range padding {
-				if len(copyFrom) == 0 {
-					break
-				}
-				copyFrom = copyFrom[copy(padding[i], copyFrom):]
-			}
-		}
-	}
-
-	// Split into equal-length shards.
-	dst := make([][]byte, r.totalShards)
-	i := 0
-	for ; i < len(dst) && len(data) >= perShard; i++ {
-		dst[i] = data[:perShard:perShard]
-		data = data[perShard:]
-	}
-
-	for j := 0; i+j < len(dst); j++ {
-		dst[i+j] = padding[0]
-		padding = padding[1:]
-	}
-
-	return dst, nil
+	return splitShards(data, r.dataShards, r.totalShards, 1, r.o.zeroCopySplit, r.o.requireAligned)
 }
 
 // ErrReconstructRequired is returned if too few data shards are intact and a
@@ -1666,41 +2131,5 @@ var ErrReconstructRequired = errors.New("reconstruction required as one or more
 // If the total data size is less than outSize, ErrShortData will be returned.
 // If one or more required data shards are nil, ErrReconstructRequired will be returned.
 func (r *reedSolomon) Join(dst io.Writer, shards [][]byte, outSize int) error {
-	// Do we have enough shards?
-	if len(shards) < r.dataShards {
-		return ErrTooFewShards
-	}
-	shards = shards[:r.dataShards]
-
-	// Do we have enough data?
-	size := 0
-	for _, shard := range shards {
-		if shard == nil {
-			return ErrReconstructRequired
-		}
-		size += len(shard)
-
-		// Do we have enough data already?
-		if size >= outSize {
-			break
-		}
-	}
-	if size < outSize {
-		return ErrShortData
-	}
-
-	// Copy data to dst
-	write := outSize
-	for _, shard := range shards {
-		if write < len(shard) {
-			_, err := dst.Write(shard[:write])
-			return err
-		}
-		n, err := dst.Write(shard)
-		if err != nil {
-			return err
-		}
-		write -= n
-	}
-	return nil
+	return joinShards(dst, shards, r.dataShards, outSize)
 }
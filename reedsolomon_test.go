@@ -12,6 +12,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"math/bits"
 	"math/rand"
 	"os"
 	"runtime"
@@ -24,8 +25,10 @@ var noSSE2 = flag.Bool("no-sse2", !defaultOptions.useSSE2, "Disable SSE2")
 var noSSSE3 = flag.Bool("no-ssse3", !defaultOptions.useSSSE3, "Disable SSSE3")
 var noAVX2 = flag.Bool("no-avx2", !defaultOptions.useAVX2, "Disable AVX2")
 var noAVX512 = flag.Bool("no-avx512", !defaultOptions.useAVX512, "Disable AVX512")
+var noAVX10 = flag.Bool("no-avx10", !defaultOptions.useAVX10, "Disable AVX10/256")
 var noGNFI = flag.Bool("no-gfni", !defaultOptions.useAvx512GFNI, "Disable AVX512+GFNI")
 var noAVX2GNFI = flag.Bool("no-avx-gfni", !defaultOptions.useAvxGNFI, "Disable AVX+GFNI")
+var noSHA3 = flag.Bool("no-sha3", !defaultOptions.useSHA3, "Disable arm64 SHA3")
 
 func TestMain(m *testing.M) {
 	flag.Parse()
@@ -52,12 +55,18 @@ func testOptions(o ...Option) []Option {
 	if *noAVX512 {
 		o = append(o, WithAVX512(false))
 	}
+	if *noAVX10 {
+		o = append(o, WithAVX10(false))
+	}
 	if *noGNFI {
 		o = append(o, WithGFNI(false))
 	}
 	if *noAVX2GNFI {
 		o = append(o, WithAVXGFNI(false))
 	}
+	if *noSHA3 {
+		o = append(o, WithSHA3(false))
+	}
 	return o
 }
 
@@ -129,7 +138,7 @@ func findSingularSubMatrix(m matrix) (matrix, error) {
 func TestBuildMatrixJerasure(t *testing.T) {
 	totalShards := 12
 	dataShards := 8
-	m, err := buildMatrixJerasure(dataShards, totalShards)
+	m, err := buildMatrixJerasure(dataShards, totalShards, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -155,10 +164,67 @@ func TestBuildMatrixJerasure(t *testing.T) {
 	}
 }
 
+func TestBuildMatrixCauchyGood(t *testing.T) {
+	// Parity rows generated by Jerasure cauchy_good_general_coding_matrix(k, m, 8).
+	for _, test := range []struct {
+		dataShards, parityShards int
+		want                     matrix
+	}{
+		{3, 2, matrix{{1, 1, 1}, {1, 2, 142}}},
+		{4, 3, matrix{{1, 1, 1, 1}, {200, 151, 172, 1}, {140, 1, 92, 70}}},
+		{6, 4, matrix{
+			{1, 1, 1, 1, 1, 1},
+			{151, 172, 1, 225, 166, 158},
+			{143, 114, 101, 200, 1, 39},
+			{187, 70, 1, 172, 238, 200},
+		}},
+	} {
+		m, err := buildMatrixCauchyGood(test.dataShards, test.dataShards+test.parityShards, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range test.want {
+			if !bytes.Equal(m[test.dataShards+i], row) {
+				t.Fatalf("%d+%d: parity row %d is %v, want %v", test.dataShards, test.parityShards, i, m[test.dataShards+i], row)
+			}
+		}
+	}
+
+	// First entries of cbest_8 in Jerasure's cauchy_best_r6.c.
+	cbest := []byte{1, 2, 142, 4, 71, 8, 70, 173, 3, 35, 143, 16, 17, 67, 134, 140, 172, 6, 34, 69, 201}
+	m, err := buildMatrixCauchyGood(len(cbest), len(cbest)+2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m[len(cbest)+1], cbest) {
+		t.Fatalf("got %v, want %v", m[len(cbest)+1], cbest)
+	}
+
+	enc, err := New(10, 4, testOptions(WithCauchyGoodMatrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, s := range shards[:10] {
+		fillRandom(s)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{}, shards[3]...)
+	shards[0], shards[3], shards[7], shards[11] = nil, nil, nil, nil
+	if err := enc.Reconstruct(shards); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[3], want) {
+		t.Fatal("reconstructed data mismatch")
+	}
+}
+
 func TestBuildMatrixPAR1Singular(t *testing.T) {
 	totalShards := 8
 	dataShards := 4
-	m, err := buildMatrixPAR1(dataShards, totalShards)
+	m, err := buildMatrixPAR1(dataShards, totalShards, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -175,6 +241,302 @@ func TestBuildMatrixPAR1Singular(t *testing.T) {
 	t.Logf("matrix %s has singular sub-matrix %s", m, singularSubMatrix)
 }
 
+func TestBuildMatrixRawVandermonde(t *testing.T) {
+	const dataShards, totalShards = 5, 9
+	raw, err := RawVandermondeMatrix(dataShards, totalShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[2][3] != galExp(2, 3) {
+		t.Fatal("unexpected raw matrix", raw)
+	}
+	m, err := buildMatrixRawVandermonde(dataShards, totalShards, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r := range m {
+		for c := range m[r] {
+			want := raw[r][c]
+			if r < dataShards {
+				want = 0
+				if r == c {
+					want = 1
+				}
+			}
+			if m[r][c] != want {
+				t.Fatalf("row %d, col %d: got %d, want %d", r, c, m[r][c], want)
+			}
+		}
+	}
+
+	enc, err := New(dataShards, totalShards-dataShards, testOptions(WithRawVandermondeMatrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(100)
+	for _, s := range shards[:dataShards] {
+		fillRandom(s)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for p := dataShards; p < totalShards; p++ {
+		want := make([]byte, len(shards[p]))
+		for d := 0; d < dataShards; d++ {
+			galMulSliceXor(raw[p][d], shards[d], want, &defaultOptions)
+		}
+		if !bytes.Equal(want, shards[p]) {
+			t.Fatal("parity mismatch on shard", p)
+		}
+	}
+
+	if _, err := RawVandermondeMatrix(0, 1); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+	if _, err := RawVandermondeMatrix(10, 257); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum, got %v", err)
+	}
+}
+
+func TestExportedMatrices(t *testing.T) {
+	const dataShards, parityShards = 5, 3
+	for name, test := range map[string]struct {
+		build func(dataShards, totalShards int) ([][]byte, error)
+		opts  []Option
+	}{
+		"vandermonde": {VandermondeMatrix, nil},
+		"jerasure":    {JerasureMatrix, []Option{WithJerasureMatrix()}},
+		"par1":        {PAR1Matrix, []Option{WithPAR1Matrix()}},
+		"cauchy":      {CauchyMatrix, []Option{WithCauchyMatrix()}},
+	} {
+		m, err := test.build(dataShards, dataShards+parityShards)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		want, err := New(dataShards, parityShards, testOptions(test.opts...)...)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		got, err := New(dataShards, parityShards, testOptions(WithCustomMatrix(m[dataShards:]))...)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		shards := make([][]byte, dataShards+parityShards)
+		for i := range shards {
+			shards[i] = make([]byte, 100)
+			if i < dataShards {
+				fillRandom(shards[i])
+			}
+		}
+		if err := want.Encode(shards); err != nil {
+			t.Fatal(name, err)
+		}
+		if ok, err := got.Verify(shards); !ok || err != nil {
+			t.Errorf("%s: parity mismatch: %v", name, err)
+		}
+		if _, err := test.build(0, 1); err != ErrInvShardNum {
+			t.Errorf("%s: want ErrInvShardNum, got %v", name, err)
+		}
+		if _, err := test.build(10, 257); err != ErrMaxShardNum {
+			t.Errorf("%s: want ErrMaxShardNum, got %v", name, err)
+		}
+	}
+}
+
+func TestBuildMatrixZfec(t *testing.T) {
+	// Parity rows generated by the fec_new construction in zfec's fec.c.
+	for _, test := range []struct {
+		dataShards, totalShards int
+		want                    matrix
+	}{
+		{1, 3, matrix{{1}, {1}}},
+		{2, 4, matrix{{3, 2}, {5, 4}}},
+		{3, 5, matrix{{15, 8, 6}, {45, 48, 28}}},
+		{4, 7, matrix{{119, 64, 56, 14}, {199, 167, 13, 108}, {83, 2, 111, 63}}},
+		{6, 10, matrix{
+			{6, 38, 197, 229, 63, 62},
+			{130, 23, 173, 221, 230, 2},
+			{142, 76, 128, 122, 163, 154},
+			{245, 209, 95, 57, 192, 131},
+		}},
+	} {
+		m, err := buildMatrixZfec(test.dataShards, test.totalShards, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < test.dataShards; i++ {
+			for j := 0; j < test.dataShards; j++ {
+				if i != j && m[i][j] != 0 || i == j && m[i][j] != 1 {
+					t.Fatal("Top part of the matrix is not identity")
+				}
+			}
+		}
+		for i, row := range test.want {
+			if !bytes.Equal(m[test.dataShards+i], row) {
+				t.Fatalf("%d+%d: parity row %d is %v, want %v", test.dataShards, test.totalShards-test.dataShards, i, m[test.dataShards+i], row)
+			}
+		}
+	}
+
+	// zfec.Encoder(3, 5).encode([b"zfec", b"and ", b"Go! "])
+	enc, err := New(3, 2, testOptions(WithZfecMatrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := [][]byte{[]byte("zfec"), []byte("and "), []byte("Go! "), make([]byte, 4), make([]byte, 4)}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[3], []byte{44, 16, 232, 214}) || !bytes.Equal(shards[4], []byte{102, 7, 118, 216}) {
+		t.Fatalf("parity differs from zfec: %v, %v", shards[3], shards[4])
+	}
+
+	// Every combination of dataShards blocks must be able to recover the data.
+	enc, err = New(6, 4, testOptions(WithZfecMatrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards = enc.(Extensions).AllocAligned(1000)
+	for _, s := range shards[:6] {
+		fillRandom(s)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for lost := 0; lost < 1<<10; lost++ {
+		if bits.OnesCount(uint(lost)) != 4 {
+			continue
+		}
+		test := make([][]byte, len(shards))
+		for i := range shards {
+			if lost&(1<<i) == 0 {
+				test[i] = shards[i]
+			}
+		}
+		if err := enc.Reconstruct(test); err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if !bytes.Equal(test[i], shards[i]) {
+				t.Fatalf("lost %b: shard %d mismatch", lost, i)
+			}
+		}
+	}
+}
+
+func TestBuildMatrixISAL(t *testing.T) {
+	const dataShards, totalShards = 6, 10
+	// Parity rows generated by ISA-L gf_gen_rs_matrix(a, 10, 6).
+	want := matrix{
+		{1, 1, 1, 1, 1, 1},
+		{1, 2, 4, 8, 16, 32},
+		{1, 4, 16, 64, 29, 116},
+		{1, 8, 64, 58, 205, 38},
+	}
+	isal, err := buildMatrixISAL(dataShards, totalShards, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < dataShards; i++ {
+		for j := 0; j < dataShards; j++ {
+			if i != j && isal[i][j] != 0 || i == j && isal[i][j] != 1 {
+				t.Fatal("Top part of the matrix is not identity")
+			}
+		}
+	}
+	for i, row := range want {
+		if !bytes.Equal(isal[dataShards+i], row) {
+			t.Fatalf("parity row %d is %v, want %v", i, isal[dataShards+i], row)
+		}
+	}
+
+	// Parity rows generated by ISA-L gf_gen_cauchy1_matrix(a, 10, 6).
+	want = matrix{
+		{122, 186, 71, 167, 142, 244},
+		{186, 122, 167, 71, 244, 142},
+		{173, 157, 221, 152, 61, 170},
+		{157, 173, 152, 221, 170, 61},
+	}
+	m, err := buildMatrixCauchy(dataShards, totalShards, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range want {
+		if !bytes.Equal(m[dataShards+i], row) {
+			t.Fatalf("cauchy parity row %d is %v, want %v", i, m[dataShards+i], row)
+		}
+	}
+
+	enc, err := New(dataShards, totalShards-dataShards, testOptions(WithISALMatrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(100)
+	for _, s := range shards[:dataShards] {
+		fillRandom(s)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for p := dataShards; p < totalShards; p++ {
+		exp := make([]byte, len(shards[p]))
+		for d := 0; d < dataShards; d++ {
+			galMulSliceXor(isal[p][d], shards[d], exp, &defaultOptions)
+		}
+		if !bytes.Equal(exp, shards[p]) {
+			t.Fatal("parity mismatch on shard", p)
+		}
+	}
+}
+
+func TestBuildMatrixCM256(t *testing.T) {
+	const dataShards, totalShards = 4, 7
+	// Recovery block coefficients generated by CM256 for 4 originals.
+	want := matrix{
+		{1, 1, 1, 1},
+		{134, 82, 117, 99},
+		{197, 233, 167, 66},
+	}
+	f, err := newGaloisField(FieldCM256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := buildMatrixCM256(dataShards, totalShards, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range want {
+		if !bytes.Equal(m[dataShards+i], row) {
+			t.Fatalf("parity row %d is %v, want %v", i, m[dataShards+i], row)
+		}
+	}
+
+	// cm256_encode with 4 originals and recovery block indexes 4, 5 and 6.
+	enc, err := New(dataShards, totalShards-dataShards, testOptions(WithCM256Matrix())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := [][]byte{[]byte("CM25"), []byte("6 in"), []byte(" Go!"), {0, 1, 2, 255}, make([]byte, 4), make([]byte, 4), make([]byte, 4)}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range [][]byte{{85, 43, 54, 133}, {57, 253, 187, 155}, {171, 185, 67, 243}} {
+		if !bytes.Equal(shards[dataShards+i], p) {
+			t.Fatalf("recovery block %d is %v, want %v", dataShards+i, shards[dataShards+i], p)
+		}
+	}
+
+	want0 := append([]byte{}, shards[0]...)
+	want2 := append([]byte{}, shards[2]...)
+	shards[0], shards[2], shards[4] = nil, nil, nil
+	if err := enc.ReconstructData(shards); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shards[0], want0) || !bytes.Equal(shards[2], want2) {
+		t.Fatal("reconstructed data mismatch")
+	}
+}
+
 func testOpts() [][]Option {
 	if testing.Short() {
 		return [][]Option{
@@ -878,6 +1240,25 @@ func testReconstructData(t *testing.T, o ...Option) {
 		t.Log("ReconstructSome reconstructed extra shards")
 	}
 
+	// Reconstruct a parity shard while data shards are missing.
+	shardsCopy = make([][]byte, 13)
+	copy(shardsCopy, shards)
+	shardsCopy[1] = nil
+	shardsCopy[9] = nil
+	shardsCopy[11] = nil
+	shardsRequired = make([]bool, 13)
+	shardsRequired[11] = true
+	err = r.ReconstructSome(shardsCopy, shardsRequired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(shardsCopy[11], shards[11]) {
+		t.Fatal("ReconstructSome did not reconstruct required parity shard correctly")
+	}
+	if shardsCopy[1] != nil || shardsCopy[9] != nil {
+		t.Fatal("ReconstructSome reconstructed shards that were not required")
+	}
+
 	// Reconstruct with 10 shards present. Use pre-allocated memory for one of them.
 	shards[0] = nil
 	shards[2] = nil
@@ -2024,6 +2405,63 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestPureGo(t *testing.T) {
+	noAsm := []Option{WithSSE2(false), WithSSSE3(false), WithAVX2(false), WithAVX512(false), WithGFNI(false), WithAVXGFNI(false)}
+	for _, opts := range [][]Option{nil, {WithLeopardGF16(true)}, {WithLeopardGF(true)}} {
+		enc, err := New(10, 4, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pureGoBuild && !enc.(PureGoer).PureGo() {
+			t.Error("expected pure Go in pure Go build")
+		}
+		enc, err = New(10, 4, append(noAsm, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runtime.GOARCH == "amd64" && !enc.(PureGoer).PureGo() {
+			t.Error("expected pure Go with all CPU features disabled")
+		}
+	}
+}
+
+func TestConstantTime(t *testing.T) {
+	noAsm := []Option{WithSSE2(false), WithSSSE3(false), WithAVX2(false), WithAVX512(false), WithGFNI(false), WithAVXGFNI(false)}
+	for _, opts := range [][]Option{nil, noAsm} {
+		enc, err := New(10, 4, append(opts, WithConstantTime(true))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := New(10, 4, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Odd size to exercise remainders.
+		shards := enc.(Extensions).AllocAligned(10007)
+		for _, shard := range shards[:10] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := ref.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+		want := shards[3]
+		shards[3] = nil
+		if err := enc.Reconstruct(shards); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(shards[3], want) {
+			t.Fatal("reconstructed shard mismatch")
+		}
+	}
+	if _, err := New(10, 4, WithConstantTime(true), WithLeopardGF16(true)); err != ErrNotSupported {
+		t.Errorf("expected %v, got %v", ErrNotSupported, err)
+	}
+}
+
 func TestSplitZero(t *testing.T) {
 	data := make([]byte, 512)
 	for _, opts := range testOpts() {
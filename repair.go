@@ -0,0 +1,159 @@
+package reedsolomon
+
+// RepairPlan describes the data needed to repair a single lost shard,
+// when all other shards are available.
+//
+// Each shard is divided into SubChunkCount equally sized sub-chunks,
+// and the sub-chunks returned by SubChunksFor must be read from each helper.
+type RepairPlan struct {
+	// Shard is the shard to repair.
+	Shard int
+
+	// Helpers contains the shards to read from, in increasing order.
+	Helpers []int
+
+	// SubChunks contains the sub-chunks to read from each helper, in increasing order.
+	// Not used if HelperSubChunks is set.
+	SubChunks []int
+
+	// HelperSubChunks contains the sub-chunks to read from each helper,
+	// in the same order as Helpers, for plans that read different sub-chunks
+	// from different helpers.
+	HelperSubChunks [][]int
+
+	// SubChunkCount is the number of sub-chunks each shard is divided into.
+	SubChunkCount int
+}
+
+// SubChunksFor returns the sub-chunks to read from the n'th helper, in increasing order.
+func (p *RepairPlan) SubChunksFor(n int) []int {
+	if p.HelperSubChunks != nil {
+		return p.HelperSubChunks[n]
+	}
+	return p.SubChunks
+}
+
+// ReadSize returns the total number of bytes to read from all helpers.
+func (p *RepairPlan) ReadSize(shardSize int) int {
+	n := 0
+	for i := range p.Helpers {
+		n += len(p.SubChunksFor(i))
+	}
+	return shardSize / p.SubChunkCount * n
+}
+
+// Ranges returns the byte ranges to read from the n'th helper, as [start, end) pairs.
+// Adjacent sub-chunks are merged into a single range.
+func (p *RepairPlan) Ranges(n, shardSize int) [][2]int {
+	size := shardSize / p.SubChunkCount
+	var ranges [][2]int
+	for _, z := range p.SubChunksFor(n) {
+		if n := len(ranges); n > 0 && ranges[n-1][1] == z*size {
+			ranges[n-1][1] += size
+			continue
+		}
+		ranges = append(ranges, [2]int{z * size, (z + 1) * size})
+	}
+	return ranges
+}
+
+// PlanRepair returns the data needed to repair the given shard.
+//
+// For encoders created with WithClayCode, a fraction of each other shard is read.
+// For encoders created with WithPiggyback, data shards are repaired by reading
+// half of most other shards.
+// For other encoders, entire shards are read from DataShards other shards.
+func PlanRepair(enc Encoder, shard int) (*RepairPlan, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if shard < 0 || shard >= ext.TotalShards() {
+		return nil, ErrInvShardNum
+	}
+	if ext.ParityShards() == 0 {
+		return nil, ErrTooFewShards
+	}
+	if pb, ok := enc.(*piggybackCodec); ok {
+		if p := pb.repairPlan(shard); p != nil {
+			return p, nil
+		}
+	}
+	p := &RepairPlan{Shard: shard, SubChunks: []int{0}, SubChunkCount: 1}
+	helpers := ext.TotalShards() - 1
+	if c, ok := enc.(*clayCodec); ok {
+		p.SubChunks = c.repairLayers(c.node(shard))
+		p.SubChunkCount = c.subChunks
+	} else if helpers > ext.DataShards() {
+		helpers = ext.DataShards()
+	}
+	for i := 0; len(p.Helpers) < helpers; i++ {
+		if i != shard {
+			p.Helpers = append(p.Helpers, i)
+		}
+	}
+	return p, nil
+}
+
+// RepairShard recreates the shard described by the plan.
+//
+// fetched is indexed by shard, and must contain the sub-chunks given by
+// the plan for each helper, concatenated in order.
+// Entries for other shards are ignored.
+// The repaired shard is returned.
+func RepairShard(enc Encoder, plan *RepairPlan, fetched [][]byte) ([]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(fetched) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	if plan.Shard < 0 || plan.Shard >= ext.TotalShards() || plan.SubChunkCount <= 0 {
+		return nil, ErrInvalidInput
+	}
+	if plan.HelperSubChunks != nil && len(plan.HelperSubChunks) != len(plan.Helpers) {
+		return nil, ErrInvalidInput
+	}
+	size, full := -1, true
+	for n, h := range plan.Helpers {
+		chunks := len(plan.SubChunksFor(n))
+		if chunks == 0 || len(fetched[h]) == 0 {
+			return nil, ErrTooFewShards
+		}
+		if len(fetched[h])%chunks != 0 || size >= 0 && len(fetched[h])/chunks != size {
+			return nil, ErrShardSize
+		}
+		size = len(fetched[h]) / chunks
+		full = full && chunks == plan.SubChunkCount
+	}
+	if size < 0 {
+		return nil, ErrTooFewShards
+	}
+
+	switch c := enc.(type) {
+	case *clayCodec:
+		if plan.SubChunkCount == c.subChunks && !full {
+			return c.repair(c.node(plan.Shard), fetched, size)
+		}
+	case *piggybackCodec:
+		if plan.SubChunkCount == 2 && !full && c.repairPlan(plan.Shard) != nil {
+			return c.repair(plan.Shard, fetched, size)
+		}
+	}
+
+	// Entire shards have been read.
+	if !full {
+		return nil, ErrInvalidInput
+	}
+	shards := make([][]byte, len(fetched))
+	for _, h := range plan.Helpers {
+		shards[h] = fetched[h]
+	}
+	required := make([]bool, len(shards))
+	required[plan.Shard] = true
+	if err := enc.ReconstructSome(shards, required); err != nil {
+		return nil, err
+	}
+	return shards[plan.Shard], nil
+}
@@ -0,0 +1,185 @@
+package reedsolomon
+
+import (
+	"context"
+	"io"
+)
+
+// ScrubOptions contains the settings of a Scrubber.
+type ScrubOptions struct {
+	// BytesPerSec limits the number of shard bytes read per second.
+	// If 0, reads are not limited.
+	BytesPerSec int64
+
+	// Fraction is the fraction of each stripe to verify, as done by VerifySampleAt.
+	// If 0 or 1, all of each stripe is verified.
+	Fraction float64
+}
+
+// ScrubReport describes the result of scrubbing a stripe.
+type ScrubReport struct {
+	// Corrupt contains the indexes of the shards found to be corrupt, in increasing order.
+	Corrupt []int
+
+	// Repaired contains the indexes of the corrupt or missing shards
+	// that have been rewritten, in increasing order.
+	Repaired []int
+
+	// BytesRead is the number of shard bytes read.
+	BytesRead int64
+
+	// Confidence is the fraction of the stripe that was checked.
+	Confidence float64
+}
+
+// Scrubber verifies stripes in the background, and repairs damaged shards,
+// while limiting the rate of reads.
+// A Scrubber is safe for concurrent use, and the rate limit is shared by all calls.
+type Scrubber struct {
+	enc      Encoder
+	ext      Extensions
+	limit    *rateLimiter
+	fraction float64
+}
+
+// NewScrubber returns a scrubber for stripes encoded by enc.
+//
+// Only encoders where ranges of the shards can be verified independently
+// are supported, so encoders using WithClayCode or WithPiggyback return ErrNotSupported.
+func NewScrubber(enc Encoder, opts ScrubOptions) (*Scrubber, error) {
+	ext, ok := enc.(Extensions)
+	if !ok || !migratable(enc) {
+		return nil, ErrNotSupported
+	}
+	if opts.BytesPerSec < 0 || !(opts.Fraction >= 0 && opts.Fraction <= 1) {
+		return nil, ErrInvalidInput
+	}
+	s := &Scrubber{enc: enc, ext: ext, fraction: opts.Fraction}
+	if s.fraction == 0 {
+		s.fraction = 1
+	}
+	if opts.BytesPerSec > 0 {
+		s.limit = &rateLimiter{rate: float64(opts.BytesPerSec)}
+	}
+	return s, nil
+}
+
+// Scrub verifies a stripe with shards of shardSize bytes read from shards,
+// and writes repaired ranges of corrupt and missing shards to the writer
+// in repair with the same index.
+//
+// Sources of missing shards should be nil.
+// Corrupt shards are located as done by LocateCorruptShards.
+// Shards without a writer in repair are not repaired, and repair may be nil
+// to only verify the stripe.
+//
+// If the corrupt shards cannot be located, ErrUncorrectable is returned.
+// If a read fails, a StreamReadError is returned, and if a write fails,
+// a StreamWriteError is returned.
+// If ctx is canceled, scrubbing stops and the cause is returned.
+func (s *Scrubber) Scrub(ctx context.Context, shards []io.ReaderAt, repair []io.WriterAt, shardSize int64) (ScrubReport, error) {
+	var report ScrubReport
+	if len(shards) != s.ext.TotalShards() || repair != nil && len(repair) != len(shards) {
+		return report, ErrTooFewShards
+	}
+	if shardSize <= 0 || shardSize%int64(s.ext.ShardSizeMultiple()) != 0 {
+		return report, ErrInvalidShardSize
+	}
+
+	// Full scrubs use large blocks, and sampled scrubs the sampled ranges.
+	var blocks []int64
+	size := int64(healBlockSize)
+	if s.fraction < 1 {
+		var err error
+		blocks, size, err = sampleBlocks(s.ext, shardSize, s.fraction)
+		if err != nil {
+			return report, err
+		}
+	} else {
+		if mult := int64(s.ext.ShardSizeMultiple()); size%mult != 0 {
+			size = (size + mult - 1) / mult * mult
+		}
+		for b := int64(0); b*size < shardSize; b++ {
+			blocks = append(blocks, b)
+		}
+	}
+	if size > shardSize {
+		size = shardSize
+	}
+
+	buf := s.ext.AllocAligned(int(size))
+	sub := make([][]byte, len(shards))
+	corrupt := make([]bool, len(shards))
+	repaired := make([]bool, len(shards))
+	rebuild := make([]bool, len(shards))
+	for _, b := range blocks {
+		if err := ctx.Err(); err != nil {
+			return report, context.Cause(ctx)
+		}
+		off := b * size
+		n := size
+		if rem := shardSize - off; rem < n {
+			n = rem
+		}
+		present := 0
+		for i, r := range shards {
+			sub[i] = buf[i][:0]
+			if r != nil {
+				present++
+			}
+		}
+		s.limit.wait(int(n) * present)
+		for i, r := range shards {
+			if r == nil {
+				continue
+			}
+			sub[i] = buf[i][:n]
+			if err := readAtFull(r, sub[i], off, i); err != nil {
+				return report, err
+			}
+			report.BytesRead += n
+		}
+
+		bad, err := LocateCorruptShards(s.enc, sub)
+		if err != nil {
+			return report, err
+		}
+		fix := false
+		for _, i := range bad {
+			corrupt[i] = true
+			sub[i] = sub[i][:0]
+		}
+		for i := range sub {
+			rebuild[i] = len(sub[i]) == 0 && repair != nil && repair[i] != nil
+			fix = fix || rebuild[i]
+		}
+		if !fix {
+			continue
+		}
+		if err := s.enc.Reconstruct(sub); err != nil {
+			return report, err
+		}
+		for i, ok := range rebuild {
+			if !ok {
+				continue
+			}
+			if _, err := repair[i].WriteAt(sub[i], off); err != nil {
+				return report, StreamWriteError{Err: err, Stream: i}
+			}
+			repaired[i] = true
+		}
+	}
+	for i := range shards {
+		if corrupt[i] {
+			report.Corrupt = append(report.Corrupt, i)
+		}
+		if repaired[i] {
+			report.Repaired = append(report.Repaired, i)
+		}
+	}
+	report.Confidence = sampleConfidence(len(blocks), shardSize, size)
+	if len(report.Corrupt) > 0 {
+		report.Confidence = 1
+	}
+	return report, nil
+}
@@ -0,0 +1,95 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScrubber(t *testing.T) {
+	enc, err := New(6, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 3*healBlockSize/2 + 64
+	shards := enc.(Extensions).AllocAligned(size)
+	for _, shard := range shards[:6] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	want := make([][]byte, len(shards))
+	for i := range shards {
+		want[i] = append([]byte{}, shards[i]...)
+	}
+	readers := make([]io.ReaderAt, len(shards))
+	repair := make([]io.WriterAt, len(shards))
+	for i := range shards {
+		readers[i] = bytes.NewReader(shards[i])
+		repair[i] = &atBuffer{data: shards[i]}
+	}
+
+	s, err := NewScrubber(enc, ScrubOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := s.Scrub(context.Background(), readers, repair, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Corrupt != nil || report.Repaired != nil || report.BytesRead != 9*size || report.Confidence != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	// Corrupt shards are repaired in place, as are missing shards with a writer.
+	shards[2][10] ^= 1
+	shards[7][healBlockSize+10] ^= 1
+	readers[5] = nil
+	for i := range shards[5] {
+		shards[5][i] = 0
+	}
+	report, err = s.Scrub(context.Background(), readers, repair, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Corrupt, []int{2, 7}) || !reflect.DeepEqual(report.Repaired, []int{2, 5, 7}) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for i := range shards {
+		if !bytes.Equal(shards[i], want[i]) {
+			t.Fatalf("shard %d not repaired", i)
+		}
+	}
+
+	// Sampled and rate limited.
+	s, err = NewScrubber(enc, ScrubOptions{BytesPerSec: 64 << 20, Fraction: 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	report, err = s.Scrub(context.Background(), readers, nil, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Confidence < 0.09 || report.Confidence > 0.1 || report.BytesRead >= 8*size/5 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if d := time.Since(start); d < time.Duration(float64(report.BytesRead-8*sampleBlockSize)/(64<<20)*float64(time.Second)) {
+		t.Errorf("rate limit not applied: %v", d)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cause := errors.New("stop")
+	cancel(cause)
+	if _, err := s.Scrub(ctx, readers, nil, size); err != cause {
+		t.Errorf("want cause, got %v", err)
+	}
+	if _, err := NewScrubber(enc, ScrubOptions{Fraction: 2}); err != ErrInvalidInput {
+		t.Errorf("want ErrInvalidInput, got %v", err)
+	}
+}
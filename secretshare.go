@@ -0,0 +1,122 @@
+package reedsolomon
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// SecretShare is a share of a secret created by SplitSecret.
+type SecretShare struct {
+	// Index is the index of the share, from 0 to the number of shares - 1.
+	Index int
+
+	// Threshold is the number of shares needed to recover the secret.
+	Threshold int
+
+	// Data is the share, which has the same size as the secret.
+	Data []byte
+}
+
+// secretRand is the source of the random coefficient shards.
+var secretRand io.Reader = rand.Reader
+
+// secretEncoder returns the encoder for shares up to index shares-1.
+// The shares are parity shards of a Cauchy matrix, where every square
+// sub-matrix of the parity rows is invertible, so any threshold-1 shares
+// are independent of the secret, like in Shamir's scheme.
+func secretEncoder(threshold, shares int) (Encoder, error) {
+	if threshold+shares > 256 {
+		return nil, ErrMaxShardNum
+	}
+	return New(threshold, shares, WithCauchyMatrix(), WithConstantTime(true), WithInversionCache(false))
+}
+
+// SplitSecret splits secret into the given number of shares, so that any threshold
+// shares can recover it with CombineSecret, while fewer shares reveal nothing
+// about it except its length.
+//
+// The secret is encoded as the first data shard, followed by threshold-1 random
+// data shards, and the shares are the parity shards.
+// Coding uses WithConstantTime.
+// threshold + shares may be at most 256.
+func SplitSecret(secret []byte, threshold, shares int) ([]SecretShare, error) {
+	if len(secret) == 0 {
+		return nil, ErrShortData
+	}
+	if threshold <= 0 || shares < threshold {
+		return nil, ErrInvShardNum
+	}
+	enc, err := secretEncoder(threshold, shares)
+	if err != nil {
+		return nil, err
+	}
+	shards := AllocAligned(threshold+shares, len(secret))
+	copy(shards[0], secret)
+	for _, s := range shards[1:threshold] {
+		if _, err := io.ReadFull(secretRand, s); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		for _, s := range shards[:threshold] {
+			memclr(s)
+		}
+	}()
+	if err := enc.Encode(shards); err != nil {
+		return nil, err
+	}
+	res := make([]SecretShare, shares)
+	for i := range res {
+		res[i] = SecretShare{Index: i, Threshold: threshold, Data: shards[threshold+i]}
+	}
+	return res, nil
+}
+
+// CombineSecret recovers a secret from shares created by SplitSecret.
+// At least Threshold shares with distinct indexes must be given,
+// otherwise ErrTooFewShards is returned.
+// Shares that have been modified cannot be detected, and give a wrong secret.
+func CombineSecret(shares []SecretShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrTooFewShards
+	}
+	threshold, size, maxIndex := shares[0].Threshold, len(shares[0].Data), 0
+	if threshold <= 0 || size == 0 {
+		return nil, ErrInvalidInput
+	}
+	for _, s := range shares {
+		if s.Threshold != threshold || s.Index < 0 {
+			return nil, ErrInvalidInput
+		}
+		if len(s.Data) != size {
+			return nil, ErrShardSize
+		}
+		if s.Index > maxIndex {
+			maxIndex = s.Index
+		}
+	}
+	enc, err := secretEncoder(threshold, maxIndex+1)
+	if err != nil {
+		return nil, err
+	}
+	shards := make([][]byte, threshold+maxIndex+1)
+	n := 0
+	for _, s := range shares {
+		i := threshold + s.Index
+		if shards[i] != nil {
+			return nil, ErrInvalidInput
+		}
+		shards[i] = s.Data
+		n++
+	}
+	if n < threshold {
+		return nil, ErrTooFewShards
+	}
+	// Only the secret is reconstructed.
+	required := make([]bool, len(shards))
+	required[0] = true
+	if err := enc.ReconstructSome(shards, required); err != nil {
+		return nil, err
+	}
+	return shards[0], nil
+}
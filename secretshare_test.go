@@ -0,0 +1,68 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSecretShare(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	shares, err := SplitSecret(secret, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("got %d shares", len(shares))
+	}
+	for _, s := range shares {
+		if bytes.Equal(s.Data, secret) {
+			t.Fatal("share contains the secret")
+		}
+	}
+
+	// All combinations of 3 shares.
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				got, err := CombineSecret([]SecretShare{shares[c], shares[a], shares[b]})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Fatalf("shares %d,%d,%d: got %q", a, b, c, got)
+				}
+			}
+		}
+	}
+	got, err := CombineSecret(shares)
+	if err != nil || !bytes.Equal(got, secret) {
+		t.Fatalf("all shares: got %q, %v", got, err)
+	}
+
+	// Splitting again uses new random shards.
+	again, err := SplitSecret(secret, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(again[0].Data, shares[0].Data) {
+		t.Fatal("shares are not randomized")
+	}
+
+	if _, err := CombineSecret(shares[:2]); err != ErrTooFewShards {
+		t.Errorf("want ErrTooFewShards, got %v", err)
+	}
+	if _, err := CombineSecret([]SecretShare{shares[0], shares[1], shares[1]}); err != ErrInvalidInput {
+		t.Errorf("want ErrInvalidInput, got %v", err)
+	}
+	mixed := []SecretShare{shares[0], shares[1], again[2]}
+	mixed[2].Threshold = 2
+	if _, err := CombineSecret(mixed); err != ErrInvalidInput {
+		t.Errorf("want ErrInvalidInput, got %v", err)
+	}
+	if _, err := SplitSecret(secret, 4, 3); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+	if _, err := SplitSecret(secret, 100, 200); err != ErrMaxShardNum {
+		t.Errorf("want ErrMaxShardNum, got %v", err)
+	}
+}
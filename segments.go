@@ -0,0 +1,169 @@
+package reedsolomon
+
+const (
+	// segmentMinRun is the smallest number of bytes encoded directly from segments.
+	// When segment boundaries of shards are closer than this,
+	// the bytes are copied to contiguous buffers first.
+	segmentMinRun = 4 << 10
+
+	// segmentCopySize is the number of bytes copied to contiguous buffers at a time.
+	segmentCopySize = 64 << 10
+)
+
+// EncodeSegments encodes parity for shards made of segments, like the
+// buffers of a vectored read, without copying them to contiguous shards.
+//
+// shards must contain TotalShards shards, each a list of segments,
+// and the segments of each shard must have the same total length.
+// The segments of the parity shards are overwritten.
+// Segment boundaries may differ between shards, but bytes close to
+// boundaries at different offsets are copied, so encoding is fastest
+// when all shards are split at the same offsets, for example into
+// fixed size pooled buffers.
+//
+// Encoders other than Reed-Solomon copy the shards to contiguous buffers.
+func EncodeSegments(enc Encoder, shards [][][]byte) error {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return ErrTooFewShards
+	}
+	size := segmentsLen(shards[0])
+	if size == 0 {
+		return ErrShardNoData
+	}
+	for _, shard := range shards[1:] {
+		if segmentsLen(shard) != size {
+			return ErrShardSize
+		}
+	}
+	r, ok := enc.(*reedSolomon)
+	if !ok {
+		return encodeSegmentsCopy(enc, shards, size)
+	}
+
+	cursors := make([]segmentCursor, len(shards))
+	for i := range shards {
+		cursors[i].segs = shards[i]
+	}
+	views := make([][]byte, len(shards))
+	var buf [][]byte
+	for off := 0; off < size; {
+		n := size - off
+		for i := range cursors {
+			if rem := len(cursors[i].peek()); rem < n {
+				n = rem
+			}
+		}
+		if n >= segmentMinRun || n == size-off {
+			for i := range cursors {
+				views[i] = cursors[i].peek()[:n]
+			}
+			r.codeSomeShards(r.parity, views[:r.dataShards], views[r.dataShards:], n)
+			for i := range cursors {
+				cursors[i].skip(n)
+			}
+			off += n
+			continue
+		}
+
+		// Boundaries are close together, so copy the bytes.
+		n = size - off
+		if n > segmentCopySize {
+			n = segmentCopySize
+		}
+		if buf == nil {
+			buf = AllocAligned(len(shards), n)
+		}
+		for i := range views {
+			views[i] = buf[i][:n]
+		}
+		for i := range cursors[:r.dataShards] {
+			cursors[i].read(views[i])
+		}
+		r.codeSomeShards(r.parity, views[:r.dataShards], views[r.dataShards:], n)
+		for i := range cursors[r.dataShards:] {
+			cursors[r.dataShards+i].write(views[r.dataShards+i])
+		}
+		off += n
+	}
+	return nil
+}
+
+// encodeSegmentsCopy performs EncodeSegments by copying the shards
+// to contiguous buffers, and the parity back to the segments.
+func encodeSegmentsCopy(enc Encoder, shards [][][]byte, size int) error {
+	ext := enc.(Extensions)
+	flat := AllocAligned(len(shards), size)
+	for i := 0; i < ext.DataShards(); i++ {
+		c := segmentCursor{segs: shards[i]}
+		c.read(flat[i])
+	}
+	if err := enc.Encode(flat); err != nil {
+		return err
+	}
+	for i := ext.DataShards(); i < len(shards); i++ {
+		c := segmentCursor{segs: shards[i]}
+		c.write(flat[i])
+	}
+	return nil
+}
+
+// segmentsLen returns the total length of segs.
+func segmentsLen(segs [][]byte) int {
+	n := 0
+	for _, seg := range segs {
+		n += len(seg)
+	}
+	return n
+}
+
+// segmentCursor is a position in a list of segments.
+type segmentCursor struct {
+	segs [][]byte
+	off  int // Offset in segs[0].
+}
+
+// peek returns the rest of the current segment.
+func (c *segmentCursor) peek() []byte {
+	for len(c.segs) > 0 && c.off == len(c.segs[0]) {
+		c.segs, c.off = c.segs[1:], 0
+	}
+	if len(c.segs) == 0 {
+		return nil
+	}
+	return c.segs[0][c.off:]
+}
+
+// skip moves the cursor n bytes forward.
+func (c *segmentCursor) skip(n int) {
+	for n > 0 {
+		rem := c.peek()
+		if n < len(rem) {
+			c.off += n
+			return
+		}
+		n -= len(rem)
+		c.off += len(rem)
+	}
+}
+
+// read copies len(dst) bytes from the segments to dst, and moves the cursor past them.
+func (c *segmentCursor) read(dst []byte) {
+	for len(dst) > 0 {
+		n := copy(dst, c.peek())
+		dst = dst[n:]
+		c.off += n
+	}
+}
+
+// write copies src to the segments, and moves the cursor past it.
+func (c *segmentCursor) write(src []byte) {
+	for len(src) > 0 {
+		n := copy(c.peek(), src)
+		src = src[n:]
+		c.off += n
+	}
+}
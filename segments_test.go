@@ -0,0 +1,110 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// splitSegments splits b into segments with lengths returned by next.
+func splitSegments(b []byte, next func() int) [][]byte {
+	var segs [][]byte
+	for len(b) > 0 {
+		n := next()
+		if n > len(b) {
+			n = len(b)
+		}
+		segs = append(segs, b[:n])
+		b = b[n:]
+	}
+	return segs
+}
+
+func TestEncodeSegments(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for _, test := range []struct {
+		name string
+		opts []Option
+		size int
+		next func() int
+	}{
+		{name: "fixed", size: 300 << 10, next: func() int { return 64 << 10 }},
+		{name: "random", size: 300 << 10, next: func() int { return 1 + rng.Intn(20<<10) }},
+		{name: "small", size: 5000, next: func() int { return 1 + rng.Intn(100) }},
+		{name: "leopard", opts: []Option{WithLeopardGF(true)}, size: 64 << 10, next: func() int { return 1 + rng.Intn(20<<10) }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := New(10, 4, testOptions(test.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := enc.(Extensions).AllocAligned(test.size)
+			for _, shard := range want[:10] {
+				fillRandom(shard)
+			}
+			shards := make([][][]byte, len(want))
+			for i := range want {
+				shards[i] = splitSegments(append([]byte(nil), want[i]...), test.next)
+			}
+			if err := enc.Encode(want); err != nil {
+				t.Fatal(err)
+			}
+			if err := EncodeSegments(enc, shards); err != nil {
+				t.Fatal(err)
+			}
+			for i := range shards {
+				if !bytes.Equal(bytes.Join(shards[i], nil), want[i]) {
+					t.Errorf("shard %d differs", i)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeSegmentsErrors(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make([][][]byte, 6)
+	for i := range shards {
+		shards[i] = [][]byte{make([]byte, 100), make([]byte, 50)}
+	}
+	if err := EncodeSegments(enc, shards[:5]); !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("got %v, want %v", err, ErrTooFewShards)
+	}
+	shards[3] = [][]byte{make([]byte, 149)}
+	if err := EncodeSegments(enc, shards); !errors.Is(err, ErrShardSize) {
+		t.Errorf("got %v, want %v", err, ErrShardSize)
+	}
+	for i := range shards {
+		shards[i] = nil
+	}
+	if err := EncodeSegments(enc, shards); !errors.Is(err, ErrShardNoData) {
+		t.Errorf("got %v, want %v", err, ErrShardNoData)
+	}
+}
+
+func BenchmarkEncodeSegments(b *testing.B) {
+	enc, err := New(10, 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := make([][][]byte, 14)
+	for i := range shards {
+		shards[i] = AllocAligned(16, 64<<10)
+		if i < 10 {
+			for _, seg := range shards[i] {
+				fillRandom(seg)
+			}
+		}
+	}
+	b.SetBytes(10 * 16 * 64 << 10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := EncodeSegments(enc, shards); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
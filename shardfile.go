@@ -0,0 +1,426 @@
+package reedsolomon
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// A shard file stores one shard of a stream in blocks, so a block can be
+// read without reading the blocks before it.
+//
+// All integers are little endian. The file starts with a header:
+//
+//	magic "RSSF" (4), version (4), data shards (4), parity shards (4),
+//	shard index (4), block size (4), CRC32-C of the previous fields (4)
+//
+// Each block is written with a block header:
+//
+//	magic "RSBK" (4), block number (8), length (4),
+//	CRC32-C of the block data (4), CRC32-C of the previous fields (4)
+//
+// When the writer is closed, an index with an entry for each block is written:
+//
+//	magic "RSIX" (4), block count (4),
+//	per block: offset of the block header (8), length (4), data CRC (4),
+//	CRC32-C of the index (4)
+//
+// followed by a footer with the offset of the index (8) and the magic "RSFT" (4).
+const (
+	shardFileVersion      = 1
+	shardFileHeaderSize   = 28
+	shardFileBlockHeader  = 24
+	shardFileIndexEntry   = 16
+	shardFileFooterSize   = 12
+	shardFileIndexMinSize = 12
+)
+
+var (
+	shardFileMagic      = [4]byte{'R', 'S', 'S', 'F'}
+	shardFileBlockMagic = [4]byte{'R', 'S', 'B', 'K'}
+	shardFileIndexMagic = [4]byte{'R', 'S', 'I', 'X'}
+	shardFileFootMagic  = [4]byte{'R', 'S', 'F', 'T'}
+	shardFileCRC        = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// ErrShardFileCorrupt is returned when a shard file header or block is damaged or missing.
+var ErrShardFileCorrupt = errors.New("shard file is corrupt")
+
+// errShardFileClosed is returned when writing to a closed ShardFileWriter.
+var errShardFileClosed = errors.New("shard file writer closed")
+
+// ShardFileHeader describes the shard stored in a shard file.
+type ShardFileHeader struct {
+	DataShards   int
+	ParityShards int
+
+	// Shard is the index of the shard stored in the file.
+	Shard int
+
+	// BlockSize is the maximum size of a block.
+	BlockSize int
+}
+
+// ShardFileWriter writes one shard of a stream to a shard file.
+// Data is split into blocks of BlockSize bytes.
+// A ShardFileWriter is not safe for concurrent use.
+type ShardFileWriter struct {
+	w     io.Writer
+	off   int64
+	block []byte
+	index []shardFileEntry
+	err   error
+}
+
+// shardFileEntry is the index entry of a block.
+// offset is -1 for missing blocks.
+type shardFileEntry struct {
+	offset int64
+	length int
+	crc    uint32
+}
+
+// NewShardFileWriter returns a writer that writes shard number shard of enc to w,
+// in blocks of blockSize bytes. The header is written immediately.
+// Offsets in the file are relative to the start of w.
+//
+// blockSize must be a multiple of the shard size multiple of enc, so blocks
+// with the same number can be decoded independently when all shard files are
+// written with the same block size.
+func NewShardFileWriter(w io.Writer, enc Encoder, shard, blockSize int) (*ShardFileWriter, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if shard < 0 || shard >= ext.TotalShards() {
+		return nil, ErrInvShardNum
+	}
+	if blockSize <= 0 || blockSize%ext.ShardSizeMultiple() != 0 || int64(blockSize) > 1<<32-1 {
+		return nil, ErrInvalidShardSize
+	}
+	h := ShardFileHeader{
+		DataShards:   ext.DataShards(),
+		ParityShards: ext.ParityShards(),
+		Shard:        shard,
+		BlockSize:    blockSize,
+	}
+	sw := &ShardFileWriter{w: w, block: make([]byte, 0, blockSize)}
+	sw.write(h.marshal())
+	if sw.err != nil {
+		return nil, sw.err
+	}
+	return sw, nil
+}
+
+// Write adds p to the shard. Full blocks are written to the underlying writer.
+func (w *ShardFileWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && w.err == nil {
+		c := copy(w.block[len(w.block):cap(w.block)], p)
+		w.block = w.block[:len(w.block)+c]
+		p = p[c:]
+		n += c
+		if len(w.block) == cap(w.block) {
+			w.writeBlock()
+		}
+	}
+	return n, w.err
+}
+
+// Flush writes any buffered data as a block, which will be shorter than the block size.
+// Writers of the other shards of the stream must be flushed at the same position.
+func (w *ShardFileWriter) Flush() error {
+	if len(w.block) > 0 && w.err == nil {
+		w.writeBlock()
+	}
+	return w.err
+}
+
+// Close flushes any buffered data and writes the index.
+// The underlying writer is not closed.
+func (w *ShardFileWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	index := make([]byte, 0, shardFileIndexMinSize+len(w.index)*shardFileIndexEntry+shardFileFooterSize)
+	index = append(index, shardFileIndexMagic[:]...)
+	index = binary.LittleEndian.AppendUint32(index, uint32(len(w.index)))
+	for _, e := range w.index {
+		index = binary.LittleEndian.AppendUint64(index, uint64(e.offset))
+		index = binary.LittleEndian.AppendUint32(index, uint32(e.length))
+		index = binary.LittleEndian.AppendUint32(index, e.crc)
+	}
+	index = binary.LittleEndian.AppendUint32(index, crc32.Checksum(index, shardFileCRC))
+	index = binary.LittleEndian.AppendUint64(index, uint64(w.off))
+	index = append(index, shardFileFootMagic[:]...)
+	w.write(index)
+	if w.err == nil {
+		w.err = errShardFileClosed
+		return nil
+	}
+	return w.err
+}
+
+// writeBlock writes the buffered block.
+func (w *ShardFileWriter) writeBlock() {
+	e := shardFileEntry{offset: w.off, length: len(w.block), crc: crc32.Checksum(w.block, shardFileCRC)}
+	var hdr [shardFileBlockHeader]byte
+	copy(hdr[:], shardFileBlockMagic[:])
+	binary.LittleEndian.PutUint64(hdr[4:], uint64(len(w.index)))
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(e.length))
+	binary.LittleEndian.PutUint32(hdr[16:], e.crc)
+	binary.LittleEndian.PutUint32(hdr[20:], crc32.Checksum(hdr[:20], shardFileCRC))
+	w.write(hdr[:])
+	w.write(w.block)
+	w.index = append(w.index, e)
+	w.block = w.block[:0]
+}
+
+// write writes p, and records the first error.
+func (w *ShardFileWriter) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(p)
+	w.off += int64(n)
+	if err == nil && n != len(p) {
+		err = io.ErrShortWrite
+	}
+	w.err = err
+}
+
+// marshal returns the file header.
+func (h ShardFileHeader) marshal() []byte {
+	b := make([]byte, 0, shardFileHeaderSize)
+	b = append(b, shardFileMagic[:]...)
+	b = binary.LittleEndian.AppendUint32(b, shardFileVersion)
+	b = binary.LittleEndian.AppendUint32(b, uint32(h.DataShards))
+	b = binary.LittleEndian.AppendUint32(b, uint32(h.ParityShards))
+	b = binary.LittleEndian.AppendUint32(b, uint32(h.Shard))
+	b = binary.LittleEndian.AppendUint32(b, uint32(h.BlockSize))
+	return binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, shardFileCRC))
+}
+
+// ShardFileReader reads blocks from a shard file.
+// It is safe for concurrent use if the underlying reader is.
+type ShardFileReader struct {
+	r         io.ReaderAt
+	header    ShardFileHeader
+	index     []shardFileEntry
+	recovered bool
+}
+
+// OpenShardFile reads the header and index of a shard file of size bytes.
+//
+// If the index is missing or damaged, for instance because the writer
+// was not closed, the index is recovered by scanning the file for blocks.
+// Blocks with a damaged header are skipped during the scan, and
+// the scan continues with the next intact block header.
+// Recovered reports whether this happened.
+//
+// If the file header is damaged, ErrShardFileCorrupt is returned.
+// Files written by an unknown version return ErrNotSupported.
+func OpenShardFile(r io.ReaderAt, size int64) (*ShardFileReader, error) {
+	var hdr [shardFileHeaderSize]byte
+	if size < shardFileHeaderSize {
+		return nil, ErrShardFileCorrupt
+	}
+	if err := readAtFull(r, hdr[:], 0, 0); err != nil {
+		return nil, err
+	}
+	if [4]byte(hdr[:4]) != shardFileMagic || crc32.Checksum(hdr[:24], shardFileCRC) != binary.LittleEndian.Uint32(hdr[24:]) {
+		return nil, ErrShardFileCorrupt
+	}
+	if binary.LittleEndian.Uint32(hdr[4:]) != shardFileVersion {
+		return nil, ErrNotSupported
+	}
+	f := &ShardFileReader{
+		r: r,
+		header: ShardFileHeader{
+			DataShards:   int(binary.LittleEndian.Uint32(hdr[8:])),
+			ParityShards: int(binary.LittleEndian.Uint32(hdr[12:])),
+			Shard:        int(binary.LittleEndian.Uint32(hdr[16:])),
+			BlockSize:    int(binary.LittleEndian.Uint32(hdr[20:])),
+		},
+	}
+	if f.header.BlockSize <= 0 {
+		return nil, ErrShardFileCorrupt
+	}
+	if !f.readIndex(size) {
+		if err := f.scan(size); err != nil {
+			return nil, err
+		}
+		f.recovered = true
+	}
+	return f, nil
+}
+
+// readIndex reads the index written by Close and returns whether it is intact.
+func (f *ShardFileReader) readIndex(size int64) bool {
+	if size < shardFileHeaderSize+shardFileIndexMinSize+shardFileFooterSize {
+		return false
+	}
+	var foot [shardFileFooterSize]byte
+	if readAtFull(f.r, foot[:], size-shardFileFooterSize, 0) != nil || [4]byte(foot[8:]) != shardFileFootMagic {
+		return false
+	}
+	off := int64(binary.LittleEndian.Uint64(foot[:]))
+	n := size - shardFileFooterSize - off
+	if off < shardFileHeaderSize || n < shardFileIndexMinSize || (n-shardFileIndexMinSize)%shardFileIndexEntry != 0 {
+		return false
+	}
+	index := make([]byte, n)
+	if readAtFull(f.r, index, off, 0) != nil {
+		return false
+	}
+	count := int64(binary.LittleEndian.Uint32(index[4:]))
+	if [4]byte(index[:4]) != shardFileIndexMagic || count*shardFileIndexEntry != n-shardFileIndexMinSize ||
+		crc32.Checksum(index[:n-4], shardFileCRC) != binary.LittleEndian.Uint32(index[n-4:]) {
+		return false
+	}
+	f.index = make([]shardFileEntry, count)
+	for i := range f.index {
+		e := index[8+i*shardFileIndexEntry:]
+		f.index[i] = shardFileEntry{
+			offset: int64(binary.LittleEndian.Uint64(e)),
+			length: int(binary.LittleEndian.Uint32(e[8:])),
+			crc:    binary.LittleEndian.Uint32(e[12:]),
+		}
+		if f.index[i].offset < shardFileHeaderSize || f.index[i].length > f.header.BlockSize {
+			f.index = nil
+			return false
+		}
+	}
+	return true
+}
+
+// scan rebuilds the index from the block headers in the file.
+func (f *ShardFileReader) scan(size int64) error {
+	pos := int64(shardFileHeaderSize)
+	br := bufio.NewReaderSize(io.NewSectionReader(f.r, pos, size-pos), 64<<10)
+	f.index = nil
+	for {
+		hdr, err := br.Peek(shardFileBlockHeader)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF || err == bufio.ErrBufferFull {
+				return nil
+			}
+			return StreamReadError{Err: err, Stream: 0}
+		}
+		e, num, ok := f.parseBlockHeader(hdr)
+		if !ok || pos+shardFileBlockHeader+int64(e.length) > size {
+			// Resynchronize on the next byte.
+			br.Discard(1)
+			pos++
+			continue
+		}
+		e.offset = pos
+		if num >= uint64(len(f.index)) {
+			if num >= uint64(size) {
+				br.Discard(1)
+				pos++
+				continue
+			}
+			for uint64(len(f.index)) <= num {
+				f.index = append(f.index, shardFileEntry{offset: -1})
+			}
+		}
+		if f.index[num].offset < 0 {
+			f.index[num] = e
+		}
+		n := shardFileBlockHeader + e.length
+		br.Discard(n)
+		pos += int64(n)
+	}
+}
+
+// parseBlockHeader returns the index entry and number of the block header in hdr,
+// and whether the header is intact.
+func (f *ShardFileReader) parseBlockHeader(hdr []byte) (e shardFileEntry, num uint64, ok bool) {
+	if [4]byte(hdr[:4]) != shardFileBlockMagic || crc32.Checksum(hdr[:20], shardFileCRC) != binary.LittleEndian.Uint32(hdr[20:]) {
+		return e, 0, false
+	}
+	e.length = int(binary.LittleEndian.Uint32(hdr[12:]))
+	e.crc = binary.LittleEndian.Uint32(hdr[16:])
+	return e, binary.LittleEndian.Uint64(hdr[4:]), e.length <= f.header.BlockSize
+}
+
+// Header returns the file header.
+func (f *ShardFileReader) Header() ShardFileHeader {
+	return f.header
+}
+
+// Blocks returns the number of blocks in the file.
+// When the index was recovered, blocks after the last intact block are not counted.
+func (f *ShardFileReader) Blocks() int {
+	return len(f.index)
+}
+
+// Recovered returns whether the index was recovered by scanning the file.
+func (f *ShardFileReader) Recovered() bool {
+	return f.recovered
+}
+
+// ReadBlock reads block n and checks its checksum.
+// If the block is missing or damaged, ErrShardFileCorrupt is returned.
+func (f *ShardFileReader) ReadBlock(n int) ([]byte, error) {
+	if n < 0 || n >= len(f.index) {
+		return nil, ErrInvalidInput
+	}
+	e := f.index[n]
+	if e.offset < 0 {
+		return nil, ErrShardFileCorrupt
+	}
+	buf := make([]byte, shardFileBlockHeader+e.length)
+	if err := readAtFull(f.r, buf, e.offset, f.header.Shard); err != nil {
+		return nil, err
+	}
+	got, num, ok := f.parseBlockHeader(buf)
+	data := buf[shardFileBlockHeader:]
+	if !ok || num != uint64(n) || got.length != e.length || got.crc != e.crc ||
+		crc32.Checksum(data, shardFileCRC) != e.crc {
+		return nil, ErrShardFileCorrupt
+	}
+	return data, nil
+}
+
+// ReconstructShardFileBlock reads block n of each shard file and reconstructs
+// the blocks that are missing or damaged.
+//
+// There must be a file for each shard of enc, in shard order, written
+// with the same block size and the same number of data and parity shards.
+// Lost files should be nil.
+// The blocks of all shards are returned.
+func ReconstructShardFileBlock(enc Encoder, files []*ShardFileReader, n int) ([][]byte, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(files) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	shards := make([][]byte, len(files))
+	for i, f := range files {
+		if f == nil || n >= f.Blocks() {
+			continue
+		}
+		h := f.Header()
+		if h.Shard != i || h.DataShards != ext.DataShards() || h.ParityShards != ext.ParityShards() {
+			return nil, ErrInvalidInput
+		}
+		block, err := f.ReadBlock(n)
+		if err == ErrShardFileCorrupt {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		shards[i] = block
+	}
+	if err := enc.Reconstruct(shards); err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
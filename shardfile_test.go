@@ -0,0 +1,141 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShardFile(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:4] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write each shard in blocks of 256 bytes, with a short block after 300 bytes.
+	files := make([][]byte, len(shards))
+	for i, shard := range shards {
+		var buf bytes.Buffer
+		w, err := NewShardFileWriter(&buf, enc, i, 256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(shard[:300]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(shard[300:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(shard); err == nil {
+			t.Fatal("write after close succeeded")
+		}
+		files[i] = buf.Bytes()
+	}
+	blocks := [][2]int{{0, 256}, {256, 300}, {300, 556}, {556, 812}, {812, 1000}}
+
+	open := func(file []byte) *ShardFileReader {
+		t.Helper()
+		f, err := OpenShardFile(bytes.NewReader(file), int64(len(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	f := open(files[1])
+	if f.Recovered() || f.Blocks() != len(blocks) {
+		t.Fatalf("recovered: %v, blocks: %d", f.Recovered(), f.Blocks())
+	}
+	want := ShardFileHeader{DataShards: 4, ParityShards: 2, Shard: 1, BlockSize: 256}
+	if f.Header() != want {
+		t.Fatalf("got header %+v", f.Header())
+	}
+	for n := len(blocks) - 1; n >= 0; n-- {
+		got, err := f.ReadBlock(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, shards[1][blocks[n][0]:blocks[n][1]]) {
+			t.Fatalf("block %d mismatch", n)
+		}
+	}
+
+	// Damage the index, and a block header in another file.
+	damaged := append([]byte(nil), files[1]...)
+	damaged[len(damaged)-shardFileFooterSize-10] ^= 1
+	f = open(damaged)
+	if !f.Recovered() || f.Blocks() != len(blocks) {
+		t.Fatalf("recovered: %v, blocks: %d", f.Recovered(), f.Blocks())
+	}
+	got, err := f.ReadBlock(3)
+	if err != nil || !bytes.Equal(got, shards[1][556:812]) {
+		t.Fatalf("recovered block mismatch: %v", err)
+	}
+	readers := make([]*ShardFileReader, len(files))
+	for i := range files {
+		readers[i] = open(files[i])
+	}
+	readers[1] = f
+
+	// Cut the file in the last block.
+	trunc := append([]byte(nil), files[3][:1000]...)
+	trunc[shardFileHeaderSize+2*shardFileBlockHeader+256+44+5] ^= 1
+	readers[3] = open(trunc)
+	if !readers[3].Recovered() || readers[3].Blocks() != 4 {
+		t.Fatalf("recovered: %v, blocks: %d", readers[3].Recovered(), readers[3].Blocks())
+	}
+	if _, err := readers[3].ReadBlock(2); err != ErrShardFileCorrupt {
+		t.Fatalf("want ErrShardFileCorrupt, got %v", err)
+	}
+	got, err = readers[3].ReadBlock(3)
+	if err != nil || !bytes.Equal(got, shards[3][556:812]) {
+		t.Fatalf("block after damaged header mismatch: %v", err)
+	}
+
+	// Damage block data in a third file, and lose one more file.
+	damaged = append([]byte(nil), files[5]...)
+	damaged[shardFileHeaderSize+3*shardFileBlockHeader+256+44+3] ^= 1
+	readers[5] = open(damaged)
+	if _, err := readers[5].ReadBlock(2); err != ErrShardFileCorrupt {
+		t.Fatalf("want ErrShardFileCorrupt, got %v", err)
+	}
+	readers[0] = nil
+	for n, b := range blocks {
+		got, err := ReconstructShardFileBlock(enc, readers, n)
+		if n == 2 {
+			if err != ErrTooFewShards {
+				t.Fatalf("want ErrTooFewShards, got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], shards[i][b[0]:b[1]]) {
+				t.Fatalf("block %d shard %d mismatch", n, i)
+			}
+		}
+	}
+
+	// A damaged file header cannot be recovered.
+	damaged = append([]byte(nil), files[2]...)
+	damaged[10] ^= 1
+	if _, err := OpenShardFile(bytes.NewReader(damaged), int64(len(damaged))); err != ErrShardFileCorrupt {
+		t.Fatalf("want ErrShardFileCorrupt, got %v", err)
+	}
+	if _, err := NewShardFileWriter(&bytes.Buffer{}, enc, 6, 256); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+}
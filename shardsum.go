@@ -0,0 +1,172 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"hash"
+	"io"
+)
+
+// checksumCodec appends a checksum to each shard.
+// Construct using New with WithShardChecksums.
+type checksumCodec struct {
+	inner   Encoder
+	ext     Extensions
+	newHash func() hash.Hash
+	size    int // Size of each checksum.
+}
+
+// newChecksum is like New, but for shards with checksums.
+func newChecksum(dataShards, parityShards int, opt options) (*checksumCodec, error) {
+	inner, err := New(dataShards, parityShards, func(o *options) {
+		*o = opt
+		o.shardHash = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ext, ok := inner.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return &checksumCodec{inner: inner, ext: ext, newHash: opt.shardHash, size: opt.shardHash().Size()}, nil
+}
+
+var _ = Extensions(&checksumCodec{})
+
+// sum returns the checksum of b.
+func (r *checksumCodec) sum(b []byte) []byte {
+	h := r.newHash()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// valid returns whether shard has a matching checksum.
+func (r *checksumCodec) valid(shard []byte) bool {
+	n := len(shard) - r.size
+	return n > 0 && bytes.Equal(r.sum(shard[:n]), shard[n:])
+}
+
+func (r *checksumCodec) ShardSizeMultiple() int {
+	return r.ext.ShardSizeMultiple()
+}
+
+func (r *checksumCodec) DataShards() int {
+	return r.ext.DataShards()
+}
+
+func (r *checksumCodec) ParityShards() int {
+	return r.ext.ParityShards()
+}
+
+func (r *checksumCodec) TotalShards() int {
+	return r.ext.TotalShards()
+}
+
+// AllocAligned allocates shards with room for the checksum.
+func (r *checksumCodec) AllocAligned(each int) [][]byte {
+	shards := r.ext.AllocAligned(each + r.size)
+	for i := range shards {
+		shards[i] = shards[i][:each]
+	}
+	return shards
+}
+
+func (r *checksumCodec) PureGo() bool {
+	return r.ext.(PureGoer).PureGo()
+}
+
+func (r *checksumCodec) Recalibrate(shardSize int) {
+	r.ext.(Recalibrator).Recalibrate(shardSize)
+}
+
+func (r *checksumCodec) CostModel() CostModel {
+	return r.ext.(CostModeler).CostModel()
+}
+
+// Encode computes the parity, and appends the checksum to all shards.
+// The shards must not have checksums.
+func (r *checksumCodec) Encode(shards [][]byte) error {
+	if err := r.inner.Encode(shards); err != nil {
+		return err
+	}
+	for i, shard := range shards {
+		shards[i] = append(shard, r.sum(shard)...)
+	}
+	return nil
+}
+
+func (r *checksumCodec) EncodeIdx(dataShard []byte, idx int, parity [][]byte) error {
+	return ErrNotSupported
+}
+
+func (r *checksumCodec) Update(shards [][]byte, newDatashards [][]byte) error {
+	return ErrNotSupported
+}
+
+// Verify returns false if any checksum or the parity does not match.
+func (r *checksumCodec) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != r.ext.TotalShards() {
+		return false, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, err
+	}
+	stripped := make([][]byte, len(shards))
+	for i, shard := range shards {
+		if !r.valid(shard) {
+			return false, nil
+		}
+		stripped[i] = shard[:len(shard)-r.size]
+	}
+	return r.inner.Verify(stripped)
+}
+
+// strip removes the checksums from shards.
+// Shards with invalid checksums are truncated to zero length,
+// so they are reconstructed as missing shards.
+func (r *checksumCodec) strip(shards [][]byte) error {
+	if len(shards) != r.ext.TotalShards() {
+		return ErrTooFewShards
+	}
+	for i, shard := range shards {
+		switch {
+		case len(shard) == 0:
+		case r.valid(shard):
+			shards[i] = shard[:len(shard)-r.size]
+		default:
+			shards[i] = shard[:0]
+		}
+	}
+	return nil
+}
+
+func (r *checksumCodec) Reconstruct(shards [][]byte) error {
+	if err := r.strip(shards); err != nil {
+		return err
+	}
+	return r.inner.Reconstruct(shards)
+}
+
+func (r *checksumCodec) ReconstructData(shards [][]byte) error {
+	if err := r.strip(shards); err != nil {
+		return err
+	}
+	return r.inner.ReconstructData(shards)
+}
+
+func (r *checksumCodec) ReconstructSome(shards [][]byte, required []bool) error {
+	if err := r.strip(shards); err != nil {
+		return err
+	}
+	return r.inner.ReconstructSome(shards, required)
+}
+
+// Split splits data into shards without checksums.
+func (r *checksumCodec) Split(data []byte) ([][]byte, error) {
+	return r.inner.Split(data)
+}
+
+// Join joins shards without checksums, as returned by Reconstruct.
+func (r *checksumCodec) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	return r.inner.Join(dst, shards, outSize)
+}
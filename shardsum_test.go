@@ -0,0 +1,91 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+func TestShardChecksums(t *testing.T) {
+	newHash := func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) }
+	for _, opts := range [][]Option{
+		testOptions(WithShardChecksums(newHash)),
+		{WithShardChecksums(newHash), WithLeopardGF16(true)},
+	} {
+		enc, err := New(5, 3, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, 20000)
+		fillRandom(data)
+		shards, err := enc.Split(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := len(shards[0])
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		for i, shard := range shards {
+			if len(shard) != size+4 {
+				t.Fatalf("shard %d has size %d, want %d", i, len(shard), size+4)
+			}
+		}
+		want := make([][]byte, len(shards))
+		for i := range shards {
+			want[i] = append([]byte{}, shards[i][:size]...)
+		}
+		ok, err := enc.Verify(shards)
+		if err != nil || !ok {
+			t.Fatalf("verification failed: %v", err)
+		}
+
+		// Corruption is detected by Verify, and repaired by Reconstruct.
+		shards[1][100] ^= 1
+		shards[6][size] ^= 1
+		if ok, err := enc.Verify(shards); ok || err != nil {
+			t.Fatalf("corruption not detected: %v", err)
+		}
+		shards[3] = nil
+		if err := enc.Reconstruct(shards); err != nil {
+			t.Fatal(err)
+		}
+		for i := range shards {
+			if !bytes.Equal(shards[i], want[i]) {
+				t.Fatalf("shard %d mismatch", i)
+			}
+		}
+		var buf bytes.Buffer
+		if err := enc.Join(&buf, shards, len(data)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatal("join mismatch")
+		}
+
+		// Too many corrupt shards.
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range []int{0, 2, 4, 7} {
+			shards[i][0] ^= 1
+		}
+		if err := enc.ReconstructData(shards); err != ErrTooFewShards {
+			t.Errorf("want ErrTooFewShards, got %v", err)
+		}
+
+		// AllocAligned leaves room for the checksums.
+		shards = enc.(Extensions).AllocAligned(size)
+		p := &shards[0][0]
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		if &shards[0][0] != p {
+			t.Error("shard reallocated")
+		}
+		if err := enc.Update(shards, make([][]byte, 5)); err != ErrNotSupported {
+			t.Errorf("want ErrNotSupported, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package reedsolomon
+
+// ShardTree is a Merkle tree over the shards of a stripe,
+// with one leaf per shard in shard order.
+//
+// Trees use SHA-256 with the leaf and node prefixes of RFC 6962,
+// like DataSquare, so proofs can be checked by any RFC 6962 verifier.
+type ShardTree struct {
+	leaves [][]byte // Leaf hashes.
+	root   []byte
+}
+
+// NewShardTree hashes each shard and returns the Merkle tree of the shards.
+// All shards must be present.
+func NewShardTree(shards [][]byte) (*ShardTree, error) {
+	if len(shards) == 0 {
+		return nil, ErrTooFewShards
+	}
+	for _, shard := range shards {
+		if len(shard) == 0 {
+			return nil, ErrShardNoData
+		}
+	}
+	leaves := merkleLeaves(shards)
+	return &ShardTree{leaves: leaves, root: merkleHashRoot(leaves)}, nil
+}
+
+// EncodeShardTree encodes the parity of shards with enc,
+// and returns the Merkle tree of all shards.
+func EncodeShardTree(enc Encoder, shards [][]byte) (*ShardTree, error) {
+	if err := enc.Encode(shards); err != nil {
+		return nil, err
+	}
+	return NewShardTree(shards)
+}
+
+// Root returns the Merkle root of the shards.
+func (t *ShardTree) Root() []byte {
+	return t.root
+}
+
+// Shards returns the number of shards in the tree.
+func (t *ShardTree) Shards() int {
+	return len(t.leaves)
+}
+
+// Proof returns the audit path of shard i, from the leaf up.
+func (t *ShardTree) Proof(i int) ([][]byte, error) {
+	if i < 0 || i >= len(t.leaves) {
+		return nil, ErrInvShardNum
+	}
+	return merkleHashProof(t.leaves, i), nil
+}
+
+// Proofs returns the audit paths of all shards.
+func (t *ShardTree) Proofs() [][][]byte {
+	proofs := make([][][]byte, len(t.leaves))
+	for i := range proofs {
+		proofs[i] = merkleHashProof(t.leaves, i)
+	}
+	return proofs
+}
+
+// VerifyShardProof returns whether shard i of a stripe with n shards
+// is included in the tree with the given root, using the proof returned by Proof.
+func VerifyShardProof(root []byte, n, i int, shard []byte, proof [][]byte) bool {
+	if i < 0 || i >= n {
+		return false
+	}
+	return merkleVerify(root, n, i, shard, proof)
+}
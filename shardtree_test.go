@@ -0,0 +1,54 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShardTree(t *testing.T) {
+	enc, err := New(5, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(1000)
+	for _, shard := range shards[:5] {
+		fillRandom(shard)
+	}
+	tree, err := EncodeShardTree(enc, shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := enc.Verify(shards); !ok || err != nil {
+		t.Fatalf("parity not encoded: %v", err)
+	}
+	if !bytes.Equal(tree.Root(), merkleRoot(shards)) || tree.Shards() != 7 {
+		t.Fatal("root mismatch")
+	}
+	proofs := tree.Proofs()
+	for i, shard := range shards {
+		proof, err := tree.Proof(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(proof) != len(proofs[i]) {
+			t.Fatalf("shard %d: proofs differ", i)
+		}
+		if !VerifyShardProof(tree.Root(), 7, i, shard, proof) {
+			t.Fatalf("shard %d: proof not verified", i)
+		}
+		if VerifyShardProof(tree.Root(), 7, (i+1)%7, shard, proof) {
+			t.Fatalf("shard %d: proof verified at wrong index", i)
+		}
+	}
+	shards[3][10] ^= 1
+	if VerifyShardProof(tree.Root(), 7, 3, shards[3], proofs[3]) {
+		t.Fatal("corrupt shard verified")
+	}
+	if _, err := tree.Proof(7); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+	shards[6] = nil
+	if _, err := NewShardTree(shards); err != ErrShardNoData {
+		t.Errorf("want ErrShardNoData, got %v", err)
+	}
+}
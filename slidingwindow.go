@@ -0,0 +1,351 @@
+package reedsolomon
+
+import "sort"
+
+// SlidingWindow is a sliding window erasure code for real-time streams,
+// such as audio and video, where waiting for a complete block of packets
+// before sending repair packets would add too much latency.
+//
+// Each source packet gets a sequence number, starting at 0.
+// After every Sources source packets, Repairs repair symbols are generated,
+// each a random linear combination in GF(2^8) of the last Window source packets.
+// A lost source packet can be recovered as soon as enough repair symbols
+// covering it have arrived, so the code rate is Sources/(Sources+Repairs),
+// and the latency is bounded by the window.
+//
+// Source packets may be up to SymbolSize bytes. Repair symbols contain the
+// packet lengths, and are SymbolSize+2 bytes.
+// Coefficients are generated by SplitMix64 seeded with the repair id.
+// Like for Fountain, a set of repair symbols fails to recover the packets
+// they should be able to with a probability of about 1/256.
+type SlidingWindow struct {
+	window, sources, repairs int
+	symbolSize               int
+	o                        options
+}
+
+// RepairSymbol is a repair symbol generated by a SlidingWindowEncoder.
+type RepairSymbol struct {
+	// ID identifies the coefficients of the symbol.
+	ID uint64
+
+	// First is the sequence number of the first source packet covered,
+	// and Count is the number of packets covered.
+	First uint64
+	Count int
+
+	Data []byte
+}
+
+// SlidingWindowPacket is a source packet recovered by a SlidingWindowDecoder.
+type SlidingWindowPacket struct {
+	Seq  uint64
+	Data []byte
+}
+
+// NewSlidingWindow creates a sliding window code covering the last window source packets,
+// with repairs repair symbols after every sources source packets.
+// Only the CPU feature options are used from opts.
+func NewSlidingWindow(window, sources, repairs, symbolSize int, opts ...Option) (*SlidingWindow, error) {
+	if window <= 0 || sources <= 0 || repairs < 0 {
+		return nil, ErrInvShardNum
+	}
+	if symbolSize <= 0 || symbolSize > 65535 {
+		return nil, ErrInvalidShardSize
+	}
+	o := defaultOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &SlidingWindow{window: window, sources: sources, repairs: repairs, symbolSize: symbolSize, o: o}, nil
+}
+
+// coefficients returns the non-zero coefficients of the repair symbol with the given id.
+func (s *SlidingWindow) coefficients(id uint64, count int) []byte {
+	row := make([]byte, count)
+	fillSplitMix(row, id)
+	for i, c := range row {
+		if c == 0 {
+			row[i] = 1
+		}
+	}
+	return row
+}
+
+// SlidingWindowEncoder generates repair symbols for a stream.
+// Create using SlidingWindow.NewEncoder.
+type SlidingWindowEncoder struct {
+	s *SlidingWindow
+
+	// The last window source symbols, indexed by sequence number modulo window.
+	// Each symbol is the packet length followed by the zero padded packet.
+	symbols  [][]byte
+	next     uint64
+	repairID uint64
+}
+
+// NewEncoder returns an encoder for a new stream.
+func (s *SlidingWindow) NewEncoder() *SlidingWindowEncoder {
+	return &SlidingWindowEncoder{s: s, symbols: AllocAligned(s.window, s.symbolSize+2)}
+}
+
+// Add adds the next source packet, and returns its sequence number
+// and any repair symbols that should be sent after it.
+// The packet is copied, so it can be reused after the call.
+func (e *SlidingWindowEncoder) Add(packet []byte) (uint64, []RepairSymbol, error) {
+	if len(packet) > e.s.symbolSize {
+		return 0, nil, ErrShardSize
+	}
+	seq := e.next
+	sym := e.symbols[seq%uint64(e.s.window)]
+	sym[0], sym[1] = byte(len(packet)>>8), byte(len(packet))
+	memclr(sym[2+copy(sym[2:], packet):])
+	e.next++
+	if e.next%uint64(e.s.sources) != 0 || e.s.repairs == 0 {
+		return seq, nil, nil
+	}
+
+	count := uint64(e.s.window)
+	if e.next < count {
+		count = e.next
+	}
+	first := e.next - count
+	repairs := make([]RepairSymbol, e.s.repairs)
+	for i := range repairs {
+		r := RepairSymbol{ID: e.repairID, First: first, Count: int(count), Data: make([]byte, e.s.symbolSize+2)}
+		e.repairID++
+		for j, c := range e.s.coefficients(r.ID, r.Count) {
+			galMulSliceXor(c, e.symbols[(first+uint64(j))%uint64(e.s.window)], r.Data, &e.s.o)
+		}
+		repairs[i] = r
+	}
+	return seq, repairs, nil
+}
+
+// SlidingWindowDecoder recovers lost source packets of a stream.
+// Create using SlidingWindow.NewDecoder.
+//
+// Source packets and repair symbols can be added in any order.
+// Packets more than twice the window older than the newest
+// packet seen are forgotten.
+type SlidingWindowDecoder struct {
+	s       *SlidingWindow
+	known   map[uint64][]byte
+	eqs     []*swEquation
+	highest uint64
+}
+
+// swEquation is a repair symbol with the known source symbols removed.
+type swEquation struct {
+	first  uint64
+	coeffs []byte // Zero for known source symbols.
+	data   []byte
+	owned  bool // data is not shared with the caller.
+}
+
+// NewDecoder returns a decoder for a new stream.
+func (s *SlidingWindow) NewDecoder() *SlidingWindowDecoder {
+	return &SlidingWindowDecoder{s: s, known: make(map[uint64][]byte)}
+}
+
+// low returns the lowest sequence number that is kept.
+func (d *SlidingWindowDecoder) low() uint64 {
+	keep := 2 * uint64(d.s.window)
+	if d.highest < keep {
+		return 0
+	}
+	return d.highest - keep + 1
+}
+
+// advance records seq as seen and forgets old state.
+func (d *SlidingWindowDecoder) advance(seq uint64) {
+	if seq <= d.highest {
+		return
+	}
+	d.highest = seq
+	low := d.low()
+	for s := range d.known {
+		if s < low {
+			delete(d.known, s)
+		}
+	}
+	eqs := d.eqs[:0]
+	for _, eq := range d.eqs {
+		if eq.first >= low || !eq.unknownBelow(low) {
+			eqs = append(eqs, eq)
+		}
+	}
+	d.eqs = eqs
+}
+
+// unknownBelow returns whether eq has an unknown source symbol before low.
+func (eq *swEquation) unknownBelow(low uint64) bool {
+	for i, c := range eq.coeffs {
+		if c != 0 && eq.first+uint64(i) < low {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSource adds a received source packet, and returns any
+// packets that could be recovered as a result.
+func (d *SlidingWindowDecoder) AddSource(seq uint64, packet []byte) ([]SlidingWindowPacket, error) {
+	if len(packet) > d.s.symbolSize {
+		return nil, ErrShardSize
+	}
+	d.advance(seq)
+	if _, ok := d.known[seq]; ok || seq < d.low() {
+		return nil, nil
+	}
+	sym := make([]byte, d.s.symbolSize+2)
+	sym[0], sym[1] = byte(len(packet)>>8), byte(len(packet))
+	copy(sym[2:], packet)
+	d.learn(seq, sym)
+	return d.solve(), nil
+}
+
+// AddRepair adds a received repair symbol, and returns any
+// packets that could be recovered as a result.
+// The decoder keeps a reference to the data of r.
+func (d *SlidingWindowDecoder) AddRepair(r RepairSymbol) ([]SlidingWindowPacket, error) {
+	if len(r.Data) != d.s.symbolSize+2 {
+		return nil, ErrShardSize
+	}
+	if r.Count <= 0 || r.Count > d.s.window {
+		return nil, ErrInvalidInput
+	}
+	d.advance(r.First + uint64(r.Count) - 1)
+	eq := &swEquation{first: r.First, coeffs: d.s.coefficients(r.ID, r.Count), data: r.Data}
+	if eq.first < d.low() {
+		return nil, nil
+	}
+	d.eqs = append(d.eqs, eq)
+	for i, c := range eq.coeffs {
+		if sym, ok := d.known[eq.first+uint64(i)]; ok {
+			eq.remove(i, c, sym, &d.s.o)
+		}
+	}
+	return d.solve(), nil
+}
+
+// remove removes known source symbol i with coefficient c from eq.
+func (eq *swEquation) remove(i int, c byte, sym []byte, o *options) {
+	if !eq.owned {
+		eq.data = append([]byte(nil), eq.data...)
+		eq.owned = true
+	}
+	galMulSliceXor(c, sym, eq.data, o)
+	eq.coeffs[i] = 0
+}
+
+// learn stores a known source symbol and removes it from the equations.
+func (d *SlidingWindowDecoder) learn(seq uint64, sym []byte) {
+	d.known[seq] = sym
+	for _, eq := range d.eqs {
+		if seq < eq.first || seq-eq.first >= uint64(len(eq.coeffs)) {
+			continue
+		}
+		if c := eq.coeffs[seq-eq.first]; c != 0 {
+			eq.remove(int(seq-eq.first), c, sym, &d.s.o)
+		}
+	}
+}
+
+// solve recovers the source symbols that are determined by the equations.
+func (d *SlidingWindowDecoder) solve() []SlidingWindowPacket {
+	var res []SlidingWindowPacket
+	for {
+		// Remove equations without unknowns, and collect the unknowns.
+		cols := make(map[uint64]int)
+		var unknown []uint64
+		eqs := d.eqs[:0]
+		for _, eq := range d.eqs {
+			n := 0
+			for i, c := range eq.coeffs {
+				if c == 0 {
+					continue
+				}
+				n++
+				seq := eq.first + uint64(i)
+				if _, ok := cols[seq]; !ok {
+					cols[seq] = 0
+					unknown = append(unknown, seq)
+				}
+			}
+			if n > 0 {
+				eqs = append(eqs, eq)
+			}
+		}
+		d.eqs = eqs
+		if len(unknown) == 0 {
+			return res
+		}
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+		for i, seq := range unknown {
+			cols[seq] = i
+		}
+
+		// Gauss-Jordan elimination on copies of the equations.
+		rows := make([][]byte, len(eqs))
+		rhs := make([][]byte, len(eqs))
+		for r, eq := range eqs {
+			rows[r] = make([]byte, len(unknown))
+			for i, c := range eq.coeffs {
+				if c != 0 {
+					rows[r][cols[eq.first+uint64(i)]] = c
+				}
+			}
+			rhs[r] = append([]byte(nil), eq.data...)
+		}
+		var pivots []int
+		for col := 0; col < len(unknown) && len(pivots) < len(rows); col++ {
+			r := len(pivots)
+			p := r
+			for p < len(rows) && rows[p][col] == 0 {
+				p++
+			}
+			if p == len(rows) {
+				continue
+			}
+			rows[r], rows[p] = rows[p], rows[r]
+			rhs[r], rhs[p] = rhs[p], rhs[r]
+			inv := galOneOver(rows[r][col])
+			galMulSlice(inv, rows[r], rows[r], &d.s.o)
+			galMulSlice(inv, rhs[r], rhs[r], &d.s.o)
+			for o := range rows {
+				if c := rows[o][col]; o != r && c != 0 {
+					galMulSliceXor(c, rows[r], rows[o], &d.s.o)
+					galMulSliceXor(c, rhs[r], rhs[o], &d.s.o)
+				}
+			}
+			pivots = append(pivots, col)
+		}
+
+		// Rows with only the pivot left determine a source symbol.
+		found := false
+		for r, col := range pivots {
+			single := true
+			for c, v := range rows[r] {
+				if c != col && v != 0 {
+					single = false
+					break
+				}
+			}
+			if !single {
+				continue
+			}
+			seq, sym := unknown[col], rhs[r]
+			d.learn(seq, sym)
+			n := int(sym[0])<<8 | int(sym[1])
+			if n > d.s.symbolSize {
+				n = d.s.symbolSize
+			}
+			res = append(res, SlidingWindowPacket{Seq: seq, Data: sym[2 : 2+n]})
+			found = true
+		}
+		if !found {
+			return res
+		}
+	}
+}
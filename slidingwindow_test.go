@@ -0,0 +1,103 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	s, err := NewSlidingWindow(8, 4, 2, 100, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, dec := s.NewEncoder(), s.NewDecoder()
+	rng := rand.New(rand.NewSource(0))
+	var sent [][]byte
+	got := make(map[uint64][]byte)
+	deliver := func(p []SlidingWindowPacket, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pkt := range p {
+			if _, ok := got[pkt.Seq]; ok {
+				t.Fatalf("packet %d delivered twice", pkt.Seq)
+			}
+			got[pkt.Seq] = pkt.Data
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+		packet := make([]byte, rng.Intn(101))
+		rng.Read(packet)
+		seq, repairs, err := enc.Add(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != uint64(i) {
+			t.Fatalf("got seq %d, want %d", seq, i)
+		}
+		want := 0
+		if i%4 == 3 {
+			want = 2
+		}
+		if len(repairs) != want {
+			t.Fatalf("packet %d: got %d repair symbols", i, len(repairs))
+		}
+		sent = append(sent, packet)
+
+		// Lose one packet in each group, and a burst of three.
+		lost := i%4 == 1 || (i >= 80 && i < 83)
+		if !lost {
+			got[seq] = packet
+			deliver(dec.AddSource(seq, packet))
+		}
+		for _, r := range repairs {
+			deliver(dec.AddRepair(r))
+		}
+	}
+	for i, packet := range sent {
+		if !bytes.Equal(got[uint64(i)], packet) {
+			t.Fatalf("packet %d mismatch", i)
+		}
+	}
+}
+
+func TestSlidingWindowLoss(t *testing.T) {
+	s, err := NewSlidingWindow(4, 4, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, dec := s.NewEncoder(), s.NewDecoder()
+	var repair []RepairSymbol
+	for i := 0; i < 4; i++ {
+		seq, r, err := enc.Add([]byte{byte(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		repair = append(repair, r...)
+		if i >= 2 {
+			if _, err := dec.AddSource(seq, []byte{byte(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Two lost packets cannot be recovered by one repair symbol.
+	p, err := dec.AddRepair(repair[0])
+	if err != nil || len(p) != 0 {
+		t.Fatalf("got %v, %v", p, err)
+	}
+	// Receiving one of them recovers the other.
+	p, err = dec.AddSource(0, []byte{0})
+	if err != nil || len(p) != 1 || p[0].Seq != 1 || !bytes.Equal(p[0].Data, []byte{1}) {
+		t.Fatalf("got %v, %v", p, err)
+	}
+
+	if _, _, err := enc.Add(make([]byte, 11)); err != ErrShardSize {
+		t.Errorf("want ErrShardSize, got %v", err)
+	}
+	if _, err := NewSlidingWindow(0, 4, 1, 10); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+}
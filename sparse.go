@@ -0,0 +1,91 @@
+package reedsolomon
+
+import "io"
+
+// sparseBlockSize is the block size JoinSparseAt checks for zeros.
+const sparseBlockSize = 4096
+
+// JoinSparseAt writes the data segment of the shards to dst like JoinAt,
+// but starting at offset off and skipping blocks of zeros.
+//
+// Blocks of 4KB, aligned to offsets in dst, that only contain zeros are not written.
+// This avoids allocating space for padding and other zero runs in sparse files.
+// If zeroRange is non-nil, it is called for each skipped run, so the caller
+// can make sure the range reads as zeros, for instance by punching a hole.
+// Since the shards are written concurrently, zeroRange may be called concurrently.
+// If zeroRange is nil, dst is assumed to already read as zeros,
+// as is the case for a new or truncated file.
+// The last block is always written, so dst is extended to off+outSize bytes.
+//
+// Only the data shards are considered.
+// You must supply the exact output size you want.
+// If there are too few shards given, ErrTooFewShards will be returned.
+// If the total data size is less than outSize, ErrShortData will be returned.
+func JoinSparseAt(enc Encoder, dst io.WriterAt, shards [][]byte, off int64, outSize int, zeroRange func(off, n int64) error) error {
+	return joinAt(enc, shards, off, outSize, func(shard []byte, off int64, last bool) error {
+		return writeSparse(dst, shard, off, last, zeroRange)
+	})
+}
+
+// writeSparse writes shard to dst at off, skipping blocks of zeros as done by JoinSparseAt.
+// If last is set, the last block is always written.
+func writeSparse(dst io.WriterAt, shard []byte, off int64, last bool, zeroRange func(off, n int64) error) error {
+	// Pending zero run.
+	var zeroStart, zeroLen int64
+	flushZero := func() error {
+		if zeroLen == 0 || zeroRange == nil {
+			zeroLen = 0
+			return nil
+		}
+		err := zeroRange(zeroStart, zeroLen)
+		zeroLen = 0
+		return err
+	}
+
+	// Start of data not yet written in shard.
+	pending := 0
+	for pos := 0; pos < len(shard); {
+		end := pos + sparseBlockSize - int((off+int64(pos))%sparseBlockSize)
+		if end > len(shard) {
+			end = len(shard)
+		}
+		if end-pos != sparseBlockSize || (last && end == len(shard)) || !isZero(shard[pos:end]) {
+			pos = end
+			continue
+		}
+		// Full zero block. Write pending data first.
+		if pending < pos {
+			if err := flushZero(); err != nil {
+				return err
+			}
+			if _, err := dst.WriteAt(shard[pending:pos], off+int64(pending)); err != nil {
+				return err
+			}
+		}
+		if zeroLen == 0 {
+			zeroStart = off + int64(pos)
+		}
+		zeroLen += sparseBlockSize
+		pos = end
+		pending = end
+	}
+	if pending < len(shard) {
+		if err := flushZero(); err != nil {
+			return err
+		}
+		if _, err := dst.WriteAt(shard[pending:], off+int64(pending)); err != nil {
+			return err
+		}
+	}
+	return flushZero()
+}
+
+// isZero returns true if b only contains zeros.
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
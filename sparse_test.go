@@ -0,0 +1,92 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// sparseWriter records written and zeroed ranges.
+type sparseWriter struct {
+	mu      sync.Mutex
+	data    []byte
+	written int
+	zeroed  int64
+	size    int64 // End of the last write.
+}
+
+func (s *sparseWriter) WriteAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copy(s.data[off:], p)
+	s.written += len(p)
+	if end := off + int64(len(p)); end > s.size {
+		s.size = end
+	}
+	return len(p), nil
+}
+
+func TestJoinSparseAt(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 100000)
+	fillRandom(data)
+	// Add zero runs, one crossing a shard boundary.
+	for i := 10000; i < 40000; i++ {
+		data[i] = 0
+	}
+	for i := 70000; i < len(data); i++ {
+		data[i] = 0
+	}
+	shards, err := enc.Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, off := range []int64{0, 100, 4095} {
+		dst := &sparseWriter{data: make([]byte, int(off)+len(data))}
+		err := JoinSparseAt(enc, dst, shards, off, len(data)-5, func(o, n int64) error {
+			if o%sparseBlockSize != 0 || n%sparseBlockSize != 0 {
+				t.Errorf("unaligned zero range %d+%d", o, n)
+			}
+			if !isZero(data[o-off : o-off+n]) {
+				t.Errorf("zero range %d+%d contains data", o, n)
+			}
+			dst.mu.Lock()
+			dst.zeroed += n
+			dst.mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(dst.data[off:off+int64(len(data))-5], data[:len(data)-5]) {
+			t.Fatalf("offset %d: data mismatch", off)
+		}
+		if dst.written+int(dst.zeroed) != len(data)-5 {
+			t.Errorf("offset %d: wrote %d, zeroed %d, want total %d", off, dst.written, dst.zeroed, len(data)-5)
+		}
+		if dst.zeroed < 40000 {
+			t.Errorf("offset %d: only %d bytes zeroed", off, dst.zeroed)
+		}
+	}
+
+	// Trailing zeros are written without zeroRange, so the output has its full size.
+	for _, off := range []int64{0, 100} {
+		dst := &sparseWriter{data: make([]byte, int(off)+len(data))}
+		if err := JoinSparseAt(enc, dst, shards, off, len(data), nil); err != nil {
+			t.Fatal(err)
+		}
+		if dst.size != off+int64(len(data)) {
+			t.Errorf("offset %d: wrote up to %d, want %d", off, dst.size, off+int64(len(data)))
+		}
+		if !bytes.Equal(dst.data[off:], data) {
+			t.Fatalf("offset %d: data mismatch", off)
+		}
+	}
+
+	if err := JoinSparseAt(enc, &sparseWriter{}, shards, 0, len(data)+1000, nil); err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+}
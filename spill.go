@@ -0,0 +1,35 @@
+package reedsolomon
+
+import (
+	"os"
+)
+
+// spillBuffers returns n buffers of each bytes backed by a temporary file in dir.
+// The file is removed before returning, so it is deleted when the
+// buffers are released, or if the process exits.
+// ErrNotSupported is returned if memory mapped files are not supported.
+func spillBuffers(dir string, n, each int) ([][]byte, func(), error) {
+	if !spillSupported {
+		return nil, nil, ErrNotSupported
+	}
+	eachAligned := ((each + 63) / 64) * 64
+	size := n * eachAligned
+	f, err := os.CreateTemp(dir, "reedsolomon-spill-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	os.Remove(f.Name())
+	defer f.Close()
+	if err := f.Truncate(int64(size)); err != nil {
+		return nil, nil, err
+	}
+	total, err := mapFile(f, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([][]byte, n)
+	for i := range res {
+		res[i] = total[i*eachAligned : i*eachAligned+each : (i+1)*eachAligned]
+	}
+	return res, func() { unmapFile(total) }, nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package reedsolomon
+
+import (
+	"os"
+)
+
+const spillSupported = false
+
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func unmapFile(b []byte) {}
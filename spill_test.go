@@ -0,0 +1,52 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestWithSpill(t *testing.T) {
+	const dataShards, parityShards, shardSize = 20, 10, 64 << 10
+	dir := t.TempDir()
+	enc, err := New(dataShards, parityShards, testOptions(WithLeopardGF16(true), WithSpill(dir, 1))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(shardSize)
+	for _, shard := range shards[:dataShards] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+
+	// Compare with an encoder keeping buffers in memory.
+	ref, err := New(dataShards, parityShards, testOptions(WithLeopardGF16(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := ref.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+
+	want := make([][]byte, len(shards))
+	copy(want, shards)
+	for i := 0; i < parityShards; i++ {
+		shards[i*3%len(shards)] = nil
+	}
+	if err := enc.Reconstruct(shards); err != nil {
+		t.Fatal(err)
+	}
+	for i := range shards {
+		if !bytes.Equal(shards[i], want[i]) {
+			t.Fatal("shard", i, "mismatch")
+		}
+	}
+
+	// No files should be left behind.
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Fatal("spill dir not empty:", entries, err)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package reedsolomon
+
+import (
+	"os"
+	"syscall"
+)
+
+const spillSupported = true
+
+// mapFile maps the first size bytes of f into memory for reading and writing.
+// Changes are written to the file.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// unmapFile unmaps memory returned by mapFile.
+func unmapFile(b []byte) {
+	syscall.Munmap(b)
+}
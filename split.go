@@ -0,0 +1,201 @@
+package reedsolomon
+
+import (
+	"errors"
+	"io"
+)
+
+// ShardSize returns the size of the shards that the Split method of enc
+// creates for dataSize bytes of data.
+// It returns 0 if enc does not implement Extensions.
+func ShardSize(enc Encoder, dataSize int) int {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return 0
+	}
+	return splitShardSize(dataSize, ext.DataShards(), ext.ShardSizeMultiple())
+}
+
+// splitShardSize returns the size of the shards for dataSize bytes of data
+// split into dataShards shards, rounded up to a multiple of multiple.
+func splitShardSize(dataSize, dataShards, multiple int) int {
+	size := (dataSize + dataShards - 1) / dataShards
+	return (size + multiple - 1) / multiple * multiple
+}
+
+// SplitTo splits data into the shards given by the caller, like the Split
+// method of enc, for example to use shards from a pool or AllocAligned.
+//
+// shards must contain TotalShards shards, each with a capacity of at least
+// ShardSize(enc, len(data)) bytes, or ErrShardSize is returned.
+// The shards are resliced to that size, and the data shards are filled
+// with data followed by zeros. The parity shards are not written,
+// since they are overwritten by Encode.
+func SplitTo(enc Encoder, data []byte, shards [][]byte) error {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return ErrNotSupported
+	}
+	if len(data) == 0 {
+		return ErrShortData
+	}
+	if len(shards) != ext.TotalShards() {
+		return ErrTooFewShards
+	}
+	perShard := ShardSize(enc, len(data))
+	for _, shard := range shards {
+		if cap(shard) < perShard {
+			return ErrShardSize
+		}
+	}
+	for i := range shards {
+		shards[i] = shards[i][:perShard]
+		if i >= ext.DataShards() {
+			continue
+		}
+		n := copy(shards[i], data)
+		data = data[n:]
+		clear := shards[i][n:]
+		for j := range clear {
+			clear[j] = 0
+		}
+	}
+	return nil
+}
+
+// ErrSplitCopy is returned by Split with WithZeroCopySplit
+// if part of the data would be copied.
+var ErrSplitCopy = errors.New("split would copy data")
+
+// splitCopies returns whether Split copies the last data shard of data to a new
+// buffer, because the capacity of data extends neither to a multiple of perShard
+// bytes nor to needTotal bytes.
+func splitCopies(data []byte, perShard, needTotal int) bool {
+	if cap(data) >= needTotal {
+		return false
+	}
+	return len(data) > cap(data)/perShard*perShard
+}
+
+// splitShards splits data into totalShards shards, as done by the Split
+// methods of the encoders with shard sizes that are a multiple of multiple.
+//
+// If the data cannot be split without copying and zeroCopy is set,
+// ErrSplitCopy is returned.
+// If aligned is set, the data is copied to aligned shards if the shards
+// would not be aligned, see WithRequireAligned.
+func splitShards(data []byte, dataShards, totalShards, multiple int, zeroCopy, aligned bool) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrShortData
+	}
+	if totalShards == 1 && len(data)%multiple == 0 {
+		return [][]byte{data}, nil
+	}
+
+	dataLen := len(data)
+	// Calculate number of bytes per data shard.
+	perShard := splitShardSize(len(data), dataShards, multiple)
+	needTotal := totalShards * perShard
+	if aligned && (perShard%shardAlign != 0 || !addrAligned(data)) {
+		// Shards of data would not be aligned, so copy all of them.
+		if zeroCopy {
+			return nil, ErrSplitCopy
+		}
+		dst := AllocAligned(totalShards, perShard)
+		for _, shard := range dst {
+			data = data[copy(shard, data):]
+		}
+		return dst, nil
+	}
+	if zeroCopy && splitCopies(data, perShard, needTotal) {
+		return nil, ErrSplitCopy
+	}
+
+	if cap(data) > len(data) {
+		if cap(data) > needTotal {
+			data = data[:needTotal]
+		} else {
+			data = data[:cap(data)]
+		}
+		clear := data[dataLen:]
+		for i := range clear {
+			clear[i] = 0
+		}
+	}
+
+	// Only allocate memory if necessary
+	var padding [][]byte
+	if len(data) < needTotal {
+		// calculate maximum number of full shards in `data` slice
+		fullShards := len(data) / perShard
+		padding = AllocAligned(totalShards-fullShards, perShard)
+
+		if dataLen > perShard*fullShards {
+			// Copy partial shards
+			copyFrom := data[perShard*fullShards : dataLen]
+			for i := range padding {
+				if len(copyFrom) == 0 {
+					break
+				}
+				copyFrom = copyFrom[copy(padding[i], copyFrom):]
+			}
+		}
+	}
+
+	// Split into equal-length shards.
+	dst := make([][]byte, totalShards)
+	i := 0
+	for ; i < len(dst) && len(data) >= perShard; i++ {
+		dst[i] = data[:perShard:perShard]
+		data = data[perShard:]
+	}
+
+	for j := 0; i+j < len(dst); j++ {
+		dst[i+j] = padding[0]
+		padding = padding[1:]
+	}
+
+	return dst, nil
+}
+
+// joinShards writes the first outSize bytes of the data shards to dst,
+// as done by the Join methods of the encoders.
+func joinShards(dst io.Writer, shards [][]byte, dataShards, outSize int) error {
+	// Do we have enough shards?
+	if len(shards) < dataShards {
+		return ErrTooFewShards
+	}
+	shards = shards[:dataShards]
+
+	// Do we have enough data?
+	size := 0
+	for _, shard := range shards {
+		if shard == nil {
+			return ErrReconstructRequired
+		}
+		size += len(shard)
+
+		// Do we have enough data already?
+		if size >= outSize {
+			break
+		}
+	}
+	if size < outSize {
+		return ErrShortData
+	}
+
+	// Copy data to dst
+	write := outSize
+	for _, shard := range shards {
+		if write < len(shard) {
+			_, err := dst.Write(shard[:write])
+			return err
+		}
+		n, err := dst.Write(shard)
+		if err != nil {
+			return err
+		}
+		write -= n
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSplitTo(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "rs"},
+		{name: "leopard", opts: []Option{WithLeopardGF(true)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := New(10, 4, testOptions(test.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, size := range []int{1, 1000, 10 << 10, 100<<10 + 3} {
+				data := make([]byte, size)
+				fillRandom(data)
+				want, err := enc.Split(append([]byte(nil), data...))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := ShardSize(enc, size); n != len(want[0]) {
+					t.Fatalf("size %d: ShardSize returned %d, want %d", size, n, len(want[0]))
+				}
+				shards := enc.(Extensions).AllocAligned(len(want[0]) + 100)
+				for _, shard := range shards {
+					for i := range shard {
+						shard[i] = 0xff
+					}
+				}
+				if err := SplitTo(enc, data, shards); err != nil {
+					t.Fatal(err)
+				}
+				for i := range want[:10] {
+					if !bytes.Equal(shards[i], want[i]) {
+						t.Fatalf("size %d: shard %d differs", size, i)
+					}
+				}
+				for i := range shards {
+					if len(shards[i]) != len(want[i]) {
+						t.Fatalf("size %d: shard %d has length %d, want %d", size, i, len(shards[i]), len(want[i]))
+					}
+				}
+				if err := enc.Encode(shards); err != nil {
+					t.Fatal(err)
+				}
+				var buf bytes.Buffer
+				if err := enc.Join(&buf, shards, size); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf.Bytes(), data) {
+					t.Fatalf("size %d: joined data differs", size)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitToErrors(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1000)
+	shards := AllocAligned(6, 250)
+	if err := SplitTo(enc, data, shards[:5]); !errors.Is(err, ErrTooFewShards) {
+		t.Errorf("got %v, want %v", err, ErrTooFewShards)
+	}
+	if err := SplitTo(enc, nil, shards); !errors.Is(err, ErrShortData) {
+		t.Errorf("got %v, want %v", err, ErrShortData)
+	}
+	shards[5] = make([]byte, 249)
+	if err := SplitTo(enc, data, shards); !errors.Is(err, ErrShardSize) {
+		t.Errorf("got %v, want %v", err, ErrShardSize)
+	}
+}
+
+func TestWithZeroCopySplit(t *testing.T) {
+	enc, err := New(10, 4, testOptions(WithZeroCopySplit())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		size, capacity int
+		copies         bool
+	}{
+		{size: 1000, capacity: 1000},
+		{size: 1001, capacity: 1001, copies: true},
+		{size: 1001, capacity: 1100},
+		{size: 1001, capacity: 1414},
+		{size: 1001, capacity: 1009, copies: true},
+	} {
+		data := make([]byte, test.size, test.capacity)
+		fillRandom(data)
+		shards, err := enc.Split(data)
+		if test.copies {
+			if !errors.Is(err, ErrSplitCopy) {
+				t.Errorf("size %d, capacity %d: got %v, want %v", test.size, test.capacity, err, ErrSplitCopy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("size %d, capacity %d: %v", test.size, test.capacity, err)
+		}
+		perShard := len(shards[0])
+		for i, shard := range shards[:10] {
+			if &shard[0] != &data[:cap(data)][i*perShard] {
+				t.Errorf("size %d, capacity %d: shard %d does not alias data", test.size, test.capacity, i)
+			}
+		}
+	}
+
+	enc, err = New(10, 4, testOptions(WithLeopardGF(true), WithZeroCopySplit())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.Split(make([]byte, 640)); err != nil {
+		t.Error(err)
+	}
+	if _, err := enc.Split(make([]byte, 641)); !errors.Is(err, ErrSplitCopy) {
+		t.Errorf("got %v, want %v", err, ErrSplitCopy)
+	}
+}
@@ -0,0 +1,270 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// DataSquare is a k×k square of data shares extended to 2k×2k with
+// a ProductCode, as used for data availability sampling.
+//
+// The top left quadrant contains the data. Each row and each column
+// is a stripe of k data shares and k parity shares.
+// A Merkle root is computed for each row and each column,
+// and the data root commits to all of them.
+//
+// Merkle trees use SHA-256 with the leaf and node prefixes of RFC 6962,
+// and support any number of leaves.
+type DataSquare struct {
+	k        int
+	shares   [][][]byte
+	rowRoots [][]byte
+	colRoots [][]byte
+}
+
+// SquareSample is a share with a proof of inclusion in its row.
+type SquareSample struct {
+	Row, Col int
+	Share    []byte
+
+	// Proof is the Merkle audit path of the share in the row tree.
+	Proof [][]byte
+}
+
+// ErrRootMismatch is returned when repaired shares do not match the expected Merkle roots.
+var ErrRootMismatch = errors.New("merkle root mismatch")
+
+// ExtendSquare extends k*k data shares, given in row order, to a 2k×2k square.
+// All shares must have the same size.
+// The options are used for the row and column encoders.
+func ExtendSquare(data [][]byte, opts ...Option) (*DataSquare, error) {
+	k := 0
+	for (k+1)*(k+1) <= len(data) {
+		k++
+	}
+	if k == 0 || k*k != len(data) {
+		return nil, ErrInvShardNum
+	}
+	if err := checkShards(data, false); err != nil {
+		return nil, err
+	}
+	code, err := NewProductCode(k, k, k, k, opts...)
+	if err != nil {
+		return nil, err
+	}
+	grid := code.AllocAligned(len(data[0]))
+	for i, share := range data {
+		copy(grid[i/k][i%k], share)
+	}
+	if err := code.Encode(grid); err != nil {
+		return nil, err
+	}
+	s := &DataSquare{k: k, shares: grid}
+	s.computeRoots()
+	return s, nil
+}
+
+// RepairSquare reconstructs a 2k×2k square from the available shares,
+// with nil for missing shares, and checks the result against the given
+// row and column roots.
+// If the shares cannot be reconstructed, ErrTooFewShards is returned.
+// If the reconstructed square does not match the roots, ErrRootMismatch is returned.
+// shares is not modified.
+func RepairSquare(shares [][][]byte, rowRoots, colRoots [][]byte, opts ...Option) (*DataSquare, error) {
+	width := len(shares)
+	if width == 0 || width%2 != 0 || len(rowRoots) != width || len(colRoots) != width {
+		return nil, ErrInvShardNum
+	}
+	k := width / 2
+	code, err := NewProductCode(k, k, k, k, opts...)
+	if err != nil {
+		return nil, err
+	}
+	grid := make([][][]byte, width)
+	for r, row := range shares {
+		if len(row) != width {
+			return nil, ErrInvShardNum
+		}
+		grid[r] = append([][]byte(nil), row...)
+	}
+	if err := code.Reconstruct(grid); err != nil {
+		return nil, err
+	}
+	s := &DataSquare{k: k, shares: grid}
+	s.computeRoots()
+	for i := range rowRoots {
+		if !bytes.Equal(rowRoots[i], s.rowRoots[i]) || !bytes.Equal(colRoots[i], s.colRoots[i]) {
+			return nil, ErrRootMismatch
+		}
+	}
+	return s, nil
+}
+
+// computeRoots calculates the row and column roots.
+func (s *DataSquare) computeRoots() {
+	width := len(s.shares)
+	s.rowRoots = make([][]byte, width)
+	s.colRoots = make([][]byte, width)
+	column := make([][]byte, width)
+	for i := range s.shares {
+		s.rowRoots[i] = merkleRoot(s.shares[i])
+		for r, row := range s.shares {
+			column[r] = row[i]
+		}
+		s.colRoots[i] = merkleRoot(column)
+	}
+}
+
+// Width returns the number of rows and columns of the extended square.
+func (s *DataSquare) Width() int {
+	return 2 * s.k
+}
+
+// Share returns the share at the given row and column.
+func (s *DataSquare) Share(row, col int) []byte {
+	return s.shares[row][col]
+}
+
+// Data returns the k*k data shares in row order.
+func (s *DataSquare) Data() [][]byte {
+	data := make([][]byte, 0, s.k*s.k)
+	for _, row := range s.shares[:s.k] {
+		data = append(data, row[:s.k]...)
+	}
+	return data
+}
+
+// RowRoots returns the Merkle roots of the rows.
+func (s *DataSquare) RowRoots() [][]byte {
+	return s.rowRoots
+}
+
+// ColumnRoots returns the Merkle roots of the columns.
+func (s *DataSquare) ColumnRoots() [][]byte {
+	return s.colRoots
+}
+
+// DataRoot returns the Merkle root of the row roots followed by the column roots.
+func (s *DataSquare) DataRoot() []byte {
+	return SquareDataRoot(s.rowRoots, s.colRoots)
+}
+
+// SquareDataRoot returns the data root for the given row and column roots.
+// It can be used to check roots received separately from the data root.
+func SquareDataRoot(rowRoots, colRoots [][]byte) []byte {
+	leaves := make([][]byte, 0, len(rowRoots)+len(colRoots))
+	leaves = append(leaves, rowRoots...)
+	return merkleRoot(append(leaves, colRoots...))
+}
+
+// Sample returns the share at the given row and column with its inclusion proof.
+func (s *DataSquare) Sample(row, col int) (*SquareSample, error) {
+	if row < 0 || row >= s.Width() || col < 0 || col >= s.Width() {
+		return nil, ErrInvShardNum
+	}
+	return &SquareSample{
+		Row:   row,
+		Col:   col,
+		Share: s.shares[row][col],
+		Proof: merkleProof(s.shares[row], col),
+	}, nil
+}
+
+// Verify returns whether the sample is included in a row with the given root,
+// in a square with the given width.
+func (p *SquareSample) Verify(rowRoot []byte, width int) bool {
+	if p.Col < 0 || p.Col >= width {
+		return false
+	}
+	return merkleVerify(rowRoot, width, p.Col, p.Share, p.Proof)
+}
+
+// merkleLeaf returns the hash of a leaf.
+func merkleLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// merkleNode returns the hash of an inner node.
+func merkleNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleSplit returns the largest power of two less than n.
+func merkleSplit(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
+
+// merkleRoot returns the RFC 6962 Merkle tree hash of leaves.
+func merkleRoot(leaves [][]byte) []byte {
+	return merkleHashRoot(merkleLeaves(leaves))
+}
+
+// merkleProof returns the audit path of leaf i, from the leaf up.
+func merkleProof(leaves [][]byte, i int) [][]byte {
+	return merkleHashProof(merkleLeaves(leaves), i)
+}
+
+// merkleLeaves returns the leaf hashes of leaves.
+func merkleLeaves(leaves [][]byte) [][]byte {
+	hashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = merkleLeaf(leaf)
+	}
+	return hashes
+}
+
+// merkleHashRoot returns the Merkle tree hash of the given leaf hashes.
+func merkleHashRoot(hashes [][]byte) []byte {
+	if len(hashes) == 1 {
+		return hashes[0]
+	}
+	k := merkleSplit(len(hashes))
+	return merkleNode(merkleHashRoot(hashes[:k]), merkleHashRoot(hashes[k:]))
+}
+
+// merkleHashProof returns the audit path of leaf i of the given leaf hashes.
+func merkleHashProof(hashes [][]byte, i int) [][]byte {
+	if len(hashes) == 1 {
+		return nil
+	}
+	k := merkleSplit(len(hashes))
+	if i < k {
+		return append(merkleHashProof(hashes[:k], i), merkleHashRoot(hashes[k:]))
+	}
+	return append(merkleHashProof(hashes[k:], i-k), merkleHashRoot(hashes[:k]))
+}
+
+// merkleVerify checks an audit path, as described in RFC 9162 section 2.1.3.2.
+func merkleVerify(root []byte, n, i int, leaf []byte, proof [][]byte) bool {
+	fn, sn := i, n-1
+	r := merkleLeaf(leaf)
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNode(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNode(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
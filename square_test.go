@@ -0,0 +1,90 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDataSquare(t *testing.T) {
+	for _, k := range []int{1, 3, 4} {
+		data := make([][]byte, k*k)
+		for i := range data {
+			data[i] = make([]byte, 64)
+			fillRandom(data[i])
+		}
+		s, err := ExtendSquare(data, testOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		width := s.Width()
+		if width != 2*k {
+			t.Fatalf("width %d", width)
+		}
+		for i, share := range s.Data() {
+			if !bytes.Equal(share, data[i]) {
+				t.Fatalf("data share %d mismatch", i)
+			}
+		}
+
+		for r := 0; r < width; r++ {
+			for c := 0; c < width; c++ {
+				p, err := s.Sample(r, c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !p.Verify(s.RowRoots()[r], width) {
+					t.Fatalf("k=%d: sample %d,%d does not verify", k, r, c)
+				}
+				if k > 1 && p.Verify(s.RowRoots()[(r+1)%width], width) {
+					t.Fatalf("k=%d: sample %d,%d verifies against wrong row", k, r, c)
+				}
+			}
+		}
+		p, _ := s.Sample(0, 0)
+		p.Share = append([]byte{}, p.Share...)
+		p.Share[0] ^= 1
+		if p.Verify(s.RowRoots()[0], width) {
+			t.Fatal("modified sample verifies")
+		}
+		if _, err := s.Sample(width, 0); err != ErrInvShardNum {
+			t.Errorf("want ErrInvShardNum, got %v", err)
+		}
+		if !bytes.Equal(SquareDataRoot(s.RowRoots(), s.ColumnRoots()), s.DataRoot()) {
+			t.Fatal("data root mismatch")
+		}
+
+		// Keep only the parity quadrant.
+		shares := make([][][]byte, width)
+		for r := range shares {
+			shares[r] = make([][]byte, width)
+			for c := range shares[r] {
+				if r >= k && c >= k {
+					shares[r][c] = s.Share(r, c)
+				}
+			}
+		}
+		repaired, err := RepairSquare(shares, s.RowRoots(), s.ColumnRoots(), testOptions()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(repaired.DataRoot(), s.DataRoot()) {
+			t.Fatal("repaired data root mismatch")
+		}
+		if shares[0][0] != nil {
+			t.Fatal("input was modified")
+		}
+
+		// A share that was not committed to.
+		shares[width-1][width-1] = make([]byte, 64)
+		if _, err := RepairSquare(shares, s.RowRoots(), s.ColumnRoots(), testOptions()...); err != ErrRootMismatch {
+			t.Errorf("want ErrRootMismatch, got %v", err)
+		}
+		shares[width-1][width-1] = nil
+		if _, err := RepairSquare(shares, s.RowRoots(), s.ColumnRoots(), testOptions()...); err != ErrTooFewShards {
+			t.Errorf("want ErrTooFewShards, got %v", err)
+		}
+	}
+	if _, err := ExtendSquare(make([][]byte, 5)); err != ErrInvShardNum {
+		t.Errorf("want ErrInvShardNum, got %v", err)
+	}
+}
@@ -8,10 +8,14 @@
 package reedsolomon
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"runtime"
 	"sync"
+	"sync/atomic"
 )
 
 // StreamEncoder is an interface to encode Reed-Salomon parity sets for your data.
@@ -25,6 +29,11 @@ import (
 //
 // For usage examples, see "stream-encoder.go" and "streamdecoder.go" in the examples
 // folder.
+//
+// The encoders returned by NewStream also implement StreamContextEncoder,
+// StreamDigestEncoder, StreamDataReconstructor, StreamReconstructSomer,
+// StreamUpdater, StreamEncoderAt and StreamJoinerAt, which can be
+// accessed with type assertions.
 type StreamEncoder interface {
 	// Encode parity shards for a set of data shards.
 	//
@@ -90,6 +99,145 @@ type StreamEncoder interface {
 	Join(dst io.Writer, shards []io.Reader, outSize int64) error
 }
 
+// StreamContextEncoder contains variants of the StreamEncoder methods that
+// stop when a context is canceled.
+type StreamContextEncoder interface {
+	// EncodeContext encodes parity shards like Encode, but stops when ctx
+	// is canceled and returns an error wrapping the cause.
+	//
+	// Reads and writes that are in progress cannot be interrupted.
+	// With concurrent reads or writes the function returns without waiting
+	// for them, and the streams must not be used after that.
+	// Otherwise the function returns once the current read or write completes.
+	EncodeContext(ctx context.Context, data []io.Reader, parity []io.Writer) error
+
+	// VerifyContext verifies the shards like Verify,
+	// but stops when ctx is canceled, like EncodeContext.
+	VerifyContext(ctx context.Context, shards []io.Reader) (bool, error)
+
+	// ReconstructContext reconstructs missing shards like Reconstruct,
+	// but stops when ctx is canceled, like EncodeContext.
+	ReconstructContext(ctx context.Context, valid []io.Reader, fill []io.Writer) error
+}
+
+// StreamDigestEncoder encodes streams while computing digests of all shards.
+type StreamDigestEncoder interface {
+	// EncodeDigests encodes parity shards like Encode, and also returns
+	// a digest of every shard, computed while encoding.
+	//
+	// A new hash is created for each shard using newHash.
+	// The returned slice contains the digests of the data shards
+	// followed by the digests of the parity shards.
+	EncodeDigests(data []io.Reader, parity []io.Writer, newHash func() hash.Hash) ([][]byte, error)
+}
+
+// StreamDataReconstructor reconstructs only the data shards of streams.
+type StreamDataReconstructor interface {
+	// ReconstructData will recreate the missing data shards if possible.
+	//
+	// This functions as Reconstruct, but only data shards are written,
+	// so parity shards are never computed.
+	// The 'fill' slice must have one entry per data shard.
+	//
+	// If there are too few shards to reconstruct the missing
+	// ones, ErrTooFewShards will be returned.
+	ReconstructData(valid []io.Reader, fill []io.Writer) error
+
+	// ReconstructDataContext reconstructs missing data shards like ReconstructData,
+	// but stops when ctx is canceled, like EncodeContext.
+	ReconstructDataContext(ctx context.Context, valid []io.Reader, fill []io.Writer) error
+}
+
+// StreamReconstructSomer reconstructs only the requested shards of streams.
+type StreamReconstructSomer interface {
+	// ReconstructSome will recreate only the requested missing shards.
+	//
+	// This functions as Reconstruct, but a non-nil writer in 'fill' marks
+	// the shard as required, and missing shards with a nil writer are skipped.
+	// Only the required shards are computed, so repairing a single shard
+	// does not require writers for all missing shards.
+	//
+	// If there are too few shards to reconstruct the required
+	// ones, ErrTooFewShards will be returned.
+	ReconstructSome(valid []io.Reader, fill []io.Writer) error
+
+	// ReconstructSomeContext reconstructs the requested shards like ReconstructSome,
+	// but stops when ctx is canceled, like EncodeContext.
+	ReconstructSomeContext(ctx context.Context, valid []io.Reader, fill []io.Writer) error
+}
+
+// StreamUpdater updates the parity of streams for changed data shards.
+type StreamUpdater interface {
+	// Update computes new parity shards for a few changed data shards,
+	// without reading the unchanged data shards.
+	//
+	// 'shards' must contain readers for the old data shards followed by readers for
+	// the old parity shards. Old data shards that have not changed can be nil.
+	// 'newDatashards' contains readers for the changed data shards,
+	// with nil for unchanged shards.
+	// The updated parity shards are written to 'parity'.
+	//
+	// Each reader must supply the same number of bytes.
+	// If an old data shard or parity shard needed for the update is nil,
+	// ErrInvalidInput is returned.
+	// If no data shard has changed, ErrInvShardNum is returned.
+	Update(shards []io.Reader, newDatashards []io.Reader, parity []io.Writer) error
+
+	// UpdateContext updates parity shards like Update,
+	// but stops when ctx is canceled, like EncodeContext.
+	UpdateContext(ctx context.Context, shards []io.Reader, newDatashards []io.Reader, parity []io.Writer) error
+}
+
+// StreamEncoderAt encodes and reconstructs shards read and written at offsets.
+type StreamEncoderAt interface {
+	// EncodeAt encodes parity shards like Encode, but reads and writes the shards
+	// at offsets, so several blocks are processed concurrently.
+	// The number of concurrent blocks is set with WithStreamWorkers.
+	//
+	// Each shard is shardSize bytes.
+	// Blocks may be read and written in any order.
+	EncodeAt(data []io.ReaderAt, parity []io.WriterAt, shardSize int64) error
+
+	// EncodeAtContext encodes parity shards like EncodeAt,
+	// but stops when ctx is canceled, like EncodeContext.
+	EncodeAtContext(ctx context.Context, data []io.ReaderAt, parity []io.WriterAt, shardSize int64) error
+
+	// ReconstructAt recreates missing shards like Reconstruct, but reads and writes
+	// the shards at offsets, so several blocks are processed concurrently.
+	// The number of concurrent blocks is set with WithStreamWorkers.
+	//
+	// Each shard is shardSize bytes.
+	// Blocks may be read and written in any order.
+	ReconstructAt(valid []io.ReaderAt, fill []io.WriterAt, shardSize int64) error
+
+	// ReconstructAtContext recreates missing shards like ReconstructAt,
+	// but stops when ctx is canceled, like EncodeContext.
+	ReconstructAtContext(ctx context.Context, valid []io.ReaderAt, fill []io.WriterAt, shardSize int64) error
+}
+
+// StreamJoinerAt joins shards read at offsets.
+type StreamJoinerAt interface {
+	// JoinAt joins the shards like Join, but reads the data shards concurrently
+	// and writes each block at its offset in dst.
+	// The number of concurrent blocks is set with WithStreamWorkers.
+	//
+	// The shards must be laid out as written by Split, so each data shard
+	// contains (outSize+DataShards-1)/DataShards bytes of data.
+	// Blocks may be read and written in any order.
+	// See the JoinAt function for shards in memory.
+	JoinAt(dst io.WriterAt, shards []io.ReaderAt, outSize int64) error
+}
+
+var (
+	_ StreamContextEncoder    = &rsStream{}
+	_ StreamDigestEncoder     = &rsStream{}
+	_ StreamDataReconstructor = &rsStream{}
+	_ StreamReconstructSomer  = &rsStream{}
+	_ StreamUpdater           = &rsStream{}
+	_ StreamEncoderAt         = &rsStream{}
+	_ StreamJoinerAt          = &rsStream{}
+)
+
 // StreamReadError is returned when a read error is encountered
 // that relates to a supplied stream.
 // This will allow you to find out which reader has failed.
@@ -108,6 +256,11 @@ func (s StreamReadError) String() string {
 	return s.Error()
 }
 
+// Unwrap returns the underlying error.
+func (s StreamReadError) Unwrap() error {
+	return s.Err
+}
+
 // StreamWriteError is returned when a write error is encountered
 // that relates to a supplied stream. This will allow you to
 // find out which reader has failed.
@@ -126,6 +279,16 @@ func (s StreamWriteError) String() string {
 	return s.Error()
 }
 
+// Unwrap returns the underlying error.
+func (s StreamWriteError) Unwrap() error {
+	return s.Err
+}
+
+// streamCanceled returns the error for a stream operation canceled by ctx.
+func streamCanceled(ctx context.Context) error {
+	return fmt.Errorf("stream operation canceled: %w", context.Cause(ctx))
+}
+
 // rsStream contains a matrix for a specific
 // distribution of datashards and parity shards.
 // Construct if using NewStream()
@@ -133,11 +296,6 @@ type rsStream struct {
 	r *reedSolomon
 	o options
 
-	// Shard reader
-	readShards func(dst [][]byte, in []io.Reader) error
-	// Shard writer
-	writeShards func(out []io.Writer, in [][]byte) error
-
 	blockPool sync.Pool
 }
 
@@ -161,6 +319,13 @@ func NewStream(dataShards, parityShards int, o ...Option) (StreamEncoder, error)
 	if r.o.streamBS <= 0 {
 		r.o.streamBS = 4 << 20
 	}
+	// Reduce the block size so two block buffers fit in the memory limit.
+	if l := r.o.memLimit; l != nil && 2*int64(dataShards+parityShards)*int64(r.o.streamBS) > l.limit {
+		r.o.streamBS = int(l.limit/int64(2*(dataShards+parityShards))) &^ 63
+		if r.o.streamBS < 64 {
+			r.o.streamBS = 64
+		}
+	}
 	if r.o.shardSize == 0 && r.o.maxGoroutines == defaultOptions.maxGoroutines {
 		o = append(o, WithAutoGoroutines(r.o.streamBS))
 	}
@@ -169,20 +334,17 @@ func NewStream(dataShards, parityShards int, o ...Option) (StreamEncoder, error)
 	if err != nil {
 		return nil, err
 	}
-	r.r = enc.(*reedSolomon)
+	var ok bool
+	r.r, ok = enc.(*reedSolomon)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	// Progress is reported for the stream, not for each block.
+	r.r.o.progress = nil
 
 	r.blockPool.New = func() interface{} {
 		return AllocAligned(dataShards+parityShards, r.o.streamBS)
 	}
-	r.readShards = readShards
-	r.writeShards = writeShards
-	if r.o.concReads {
-		r.readShards = cReadShards
-	}
-	if r.o.concWrites {
-		r.writeShards = cWriteShards
-	}
-
 	return &r, err
 }
 
@@ -194,12 +356,55 @@ func NewStreamC(dataShards, parityShards int, conReads, conWrites bool, o ...Opt
 	return NewStream(dataShards, parityShards, append(o, WithConcurrentStreamReads(conReads), WithConcurrentStreamWrites(conWrites))...)
 }
 
-func (r *rsStream) createSlice() [][]byte {
-	out := r.blockPool.Get().([][]byte)
-	for i := range out {
-		out[i] = out[i][:r.o.streamBS]
+// createSlice returns a block buffer for each shard.
+// If WithStreamMemoryLimit is set, it waits until the memory is available,
+// or returns an error if ctx is canceled first.
+func (r *rsStream) createSlice(ctx context.Context) ([][]byte, error) {
+	s, err := r.createSlices(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return s[0], nil
+}
+
+// createSlices returns n slices like createSlice.
+// The memory for all slices is acquired at once, so callers needing
+// more than one slice cannot deadlock waiting for each other.
+func (r *rsStream) createSlices(ctx context.Context, n int) ([][][]byte, error) {
+	if err := r.o.memLimit.acquire(ctx, int64(n)*r.sliceBytes()); err != nil {
+		return nil, err
 	}
-	return out
+	s := make([][][]byte, n)
+	for j := range s {
+		out := r.blockPool.Get().([][]byte)
+		for i := range out {
+			out[i] = out[i][:r.o.streamBS]
+		}
+		s[j] = out
+	}
+	return s, nil
+}
+
+// releaseSlice returns a slice from createSlice to the pool.
+// If ctx has been canceled, concurrent reads or writes may
+// still be using it, so it is left to the garbage collector.
+func (r *rsStream) releaseSlice(ctx context.Context, all [][]byte) {
+	r.releaseSlices(ctx, [][][]byte{all})
+}
+
+// releaseSlices returns slices from createSlices to the pool.
+func (r *rsStream) releaseSlices(ctx context.Context, s [][][]byte) {
+	r.o.memLimit.release(int64(len(s)) * r.sliceBytes())
+	if ctx.Err() == nil {
+		for _, all := range s {
+			r.blockPool.Put(all)
+		}
+	}
+}
+
+// sliceBytes returns the size of a slice from createSlice.
+func (r *rsStream) sliceBytes() int64 {
+	return int64(r.r.totalShards) * int64(r.o.streamBS)
 }
 
 // Encodes parity shards for a set of data shards.
@@ -218,6 +423,38 @@ func (r *rsStream) createSlice() [][]byte {
 // will be returned. If a parity writer returns an error, a
 // StreamWriteError will be returned.
 func (r *rsStream) Encode(data []io.Reader, parity []io.Writer) error {
+	return r.encode(context.Background(), data, parity, nil)
+}
+
+// EncodeContext encodes parity shards like Encode, but stops when ctx is canceled.
+func (r *rsStream) EncodeContext(ctx context.Context, data []io.Reader, parity []io.Writer) error {
+	return r.encode(ctx, data, parity, nil)
+}
+
+// EncodeDigests encodes parity shards like Encode, and also returns
+// a digest of every shard, computed while encoding.
+//
+// A new hash is created for each shard using newHash.
+// The returned slice contains the digests of the data shards
+// followed by the digests of the parity shards.
+func (r *rsStream) EncodeDigests(data []io.Reader, parity []io.Writer, newHash func() hash.Hash) ([][]byte, error) {
+	hashes := make([]hash.Hash, r.r.totalShards)
+	for i := range hashes {
+		hashes[i] = newHash()
+	}
+	if err := r.encode(context.Background(), data, parity, hashes); err != nil {
+		return nil, err
+	}
+	digests := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		digests[i] = h.Sum(nil)
+	}
+	return digests, nil
+}
+
+// encode parity shards for the data.
+// If hashes is non-nil, all shards are written to the hash with the same index.
+func (r *rsStream) encode(ctx context.Context, data []io.Reader, parity []io.Writer, hashes []hash.Hash) error {
 	if len(data) != r.r.dataShards {
 		return ErrTooFewShards
 	}
@@ -226,20 +463,25 @@ func (r *rsStream) Encode(data []io.Reader, parity []io.Writer) error {
 		return ErrTooFewShards
 	}
 
-	all := r.createSlice()
-	defer r.blockPool.Put(all)
+	all, err := r.createSlice(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.releaseSlice(ctx, all)
 	in := all[:r.r.dataShards]
 	out := all[r.r.dataShards:]
 	read := 0
+	p := newProgress(r.o.progress, -1)
 
 	for {
-		err := r.readShards(in, data)
+		err := r.readShards(ctx, in, data, 0)
 		switch err {
 		case nil:
 		case io.EOF:
 			if read == 0 {
 				return ErrShardNoData
 			}
+			p.finish()
 			return nil
 		default:
 			return err
@@ -250,10 +492,14 @@ func (r *rsStream) Encode(data []io.Reader, parity []io.Writer) error {
 		if err != nil {
 			return err
 		}
-		err = r.writeShards(parity, out)
+		for i, h := range hashes {
+			h.Write(all[i])
+		}
+		err = r.writeShards(ctx, parity, out, r.r.dataShards)
 		if err != nil {
 			return err
 		}
+		p.add(shardSize(in))
 	}
 }
 
@@ -270,12 +516,93 @@ func trimShards(in [][]byte, size int) [][]byte {
 	return in
 }
 
-func readShards(dst [][]byte, in []io.Reader) error {
+// readShards reads a block from each shard in into dst.
+// The shard index of in[0] is first.
+func (r *rsStream) readShards(ctx context.Context, dst [][]byte, in []io.Reader, first int) error {
+	if !r.o.concReads && r.o.shardConc == nil {
+		return readShards(ctx, dst, in)
+	}
+	return cReadShards(dst, in, r.shardRunner(ctx, first, r.o.concReads, r.o.concReadLimit))
+}
+
+// writeShards writes each block in in to the shard in out.
+// The shard index of out[0] is first.
+func (r *rsStream) writeShards(ctx context.Context, out []io.Writer, in [][]byte, first int) error {
+	if !r.o.concWrites && r.o.shardConc == nil {
+		return writeShards(ctx, out, in)
+	}
+	return cWriteShards(out, in, r.shardRunner(ctx, first, r.o.concWrites, r.o.concWriteLimit))
+}
+
+// shardRunner returns a function that calls fn for each of n shards,
+// starting at shard index first.
+// Shards are processed concurrently if concurrent is set, with at most limit
+// running at once if limit > 0, unless overridden by WithStreamShardConcurrency.
+// The returned function returns when all calls have completed,
+// or with an error when ctx is canceled, without waiting for concurrent calls.
+func (r *rsStream) shardRunner(ctx context.Context, first int, concurrent bool, limit int) func(n int, fn func(i int)) error {
+	return func(n int, fn func(i int)) error {
+		var sem chan struct{}
+		if limit > 0 {
+			sem = make(chan struct{}, limit)
+		}
+		var wg sync.WaitGroup
+		var serial []int
+		for i := 0; i < n; i++ {
+			conc, override := r.o.shardConc[first+i]
+			if !override {
+				conc = concurrent
+			}
+			if !conc {
+				serial = append(serial, i)
+				continue
+			}
+			wg.Add(1)
+			go func(i int, limited bool) {
+				defer wg.Done()
+				if limited {
+					sem <- struct{}{}
+					defer func() { <-sem }()
+				}
+				fn(i)
+			}(i, sem != nil && !override)
+		}
+		for _, i := range serial {
+			if ctx.Err() != nil {
+				break
+			}
+			fn(i)
+		}
+		if ctx.Done() == nil {
+			wg.Wait()
+			return nil
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			if ctx.Err() != nil {
+				return streamCanceled(ctx)
+			}
+			return nil
+		case <-ctx.Done():
+			return streamCanceled(ctx)
+		}
+	}
+}
+
+func readShards(ctx context.Context, dst [][]byte, in []io.Reader) error {
 	if len(in) != len(dst) {
 		panic("internal error: in and dst size do not match")
 	}
 	size := -1
 	for i := range in {
+		if ctx.Err() != nil {
+			return streamCanceled(ctx)
+		}
 		if in[i] == nil {
 			dst[i] = dst[i][:0]
 			continue
@@ -305,11 +632,14 @@ func readShards(dst [][]byte, in []io.Reader) error {
 	return nil
 }
 
-func writeShards(out []io.Writer, in [][]byte) error {
+func writeShards(ctx context.Context, out []io.Writer, in [][]byte) error {
 	if len(out) != len(in) {
 		panic("internal error: in and out size do not match")
 	}
 	for i := range in {
+		if ctx.Err() != nil {
+			return streamCanceled(ctx)
+		}
 		if out[i] == nil {
 			continue
 		}
@@ -331,34 +661,31 @@ type readResult struct {
 	err  error
 }
 
-// cReadShards reads shards concurrently
-func cReadShards(dst [][]byte, in []io.Reader) error {
+// cReadShards reads shards concurrently using run.
+func cReadShards(dst [][]byte, in []io.Reader, run func(n int, fn func(i int)) error) error {
 	if len(in) != len(dst) {
 		panic("internal error: in and dst size do not match")
 	}
-	var wg sync.WaitGroup
-	wg.Add(len(in))
-	res := make(chan readResult, len(in))
-	for i := range in {
+	res := make([]readResult, len(in))
+	err := run(len(in), func(i int) {
 		if in[i] == nil {
 			dst[i] = dst[i][:0]
-			wg.Done()
-			continue
+			return
 		}
-		go func(i int) {
-			defer wg.Done()
-			n, err := io.ReadFull(in[i], dst[i])
-			// The error is EOF only if no bytes were read.
-			// If an EOF happens after reading some but not all the bytes,
-			// ReadFull returns ErrUnexpectedEOF.
-			res <- readResult{size: n, err: err, n: i}
-
-		}(i)
+		n, err := io.ReadFull(in[i], dst[i])
+		// The error is EOF only if no bytes were read.
+		// If an EOF happens after reading some but not all the bytes,
+		// ReadFull returns ErrUnexpectedEOF.
+		res[i] = readResult{size: n, err: err, n: i}
+	})
+	if err != nil {
+		return err
 	}
-	wg.Wait()
-	close(res)
 	size := -1
-	for r := range res {
+	for i, r := range res {
+		if in[i] == nil {
+			continue
+		}
 		switch r.err {
 		case io.ErrUnexpectedEOF, io.EOF:
 			if size < 0 {
@@ -379,34 +706,29 @@ func cReadShards(dst [][]byte, in []io.Reader) error {
 	return nil
 }
 
-// cWriteShards writes shards concurrently
-func cWriteShards(out []io.Writer, in [][]byte) error {
+// cWriteShards writes shards concurrently using run.
+func cWriteShards(out []io.Writer, in [][]byte, run func(n int, fn func(i int)) error) error {
 	if len(out) != len(in) {
 		panic("internal error: in and out size do not match")
 	}
-	var errs = make(chan error, len(out))
-	var wg sync.WaitGroup
-	wg.Add(len(out))
-	for i := range in {
-		go func(i int) {
-			defer wg.Done()
-			if out[i] == nil {
-				errs <- nil
-				return
-			}
-			n, err := out[i].Write(in[i])
-			if err != nil {
-				errs <- StreamWriteError{Err: err, Stream: i}
-				return
-			}
-			if n != len(in[i]) {
-				errs <- StreamWriteError{Err: io.ErrShortWrite, Stream: i}
-			}
-		}(i)
+	errs := make([]error, len(out))
+	err := run(len(out), func(i int) {
+		if out[i] == nil {
+			return
+		}
+		n, err := out[i].Write(in[i])
+		if err != nil {
+			errs[i] = StreamWriteError{Err: err, Stream: i}
+			return
+		}
+		if n != len(in[i]) {
+			errs[i] = StreamWriteError{Err: io.ErrShortWrite, Stream: i}
+		}
+	})
+	if err != nil {
+		return err
 	}
-	wg.Wait()
-	close(errs)
-	for err := range errs {
+	for _, err := range errs {
 		if err != nil {
 			return err
 		}
@@ -424,19 +746,29 @@ func cWriteShards(out []io.Writer, in [][]byte) error {
 // If a shard stream returns an error, a StreamReadError type error
 // will be returned.
 func (r *rsStream) Verify(shards []io.Reader) (bool, error) {
+	return r.VerifyContext(context.Background(), shards)
+}
+
+// VerifyContext verifies the shards like Verify, but stops when ctx is canceled.
+func (r *rsStream) VerifyContext(ctx context.Context, shards []io.Reader) (bool, error) {
 	if len(shards) != r.r.totalShards {
 		return false, ErrTooFewShards
 	}
 
 	read := 0
-	all := r.createSlice()
-	defer r.blockPool.Put(all)
+	p := newProgress(r.o.progress, -1)
+	all, err := r.createSlice(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer r.releaseSlice(ctx, all)
 	for {
-		err := r.readShards(all, shards)
+		err := r.readShards(ctx, all, shards, 0)
 		if err == io.EOF {
 			if read == 0 {
 				return false, ErrShardNoData
 			}
+			p.finish()
 			return true, nil
 		}
 		if err != nil {
@@ -447,6 +779,7 @@ func (r *rsStream) Verify(shards []io.Reader) (bool, error) {
 		if !ok || err != nil {
 			return ok, err
 		}
+		p.add(shardSize(all))
 	}
 }
 
@@ -471,6 +804,11 @@ var ErrReconstructMismatch = errors.New("valid shards and fill shards are mutual
 // However its integrity is not automatically verified.
 // Use the Verify function to check in case the data set is complete.
 func (r *rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
+	return r.ReconstructContext(context.Background(), valid, fill)
+}
+
+// ReconstructContext reconstructs missing shards like Reconstruct, but stops when ctx is canceled.
+func (r *rsStream) ReconstructContext(ctx context.Context, valid []io.Reader, fill []io.Writer) error {
 	if len(valid) != r.r.totalShards {
 		return ErrTooFewShards
 	}
@@ -478,25 +816,105 @@ func (r *rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
 		return ErrTooFewShards
 	}
 
-	all := r.createSlice()
-	defer r.blockPool.Put(all)
 	reconDataOnly := true
+	for i := range fill[r.r.dataShards:] {
+		if fill[r.r.dataShards+i] != nil {
+			reconDataOnly = false
+		}
+	}
+	return r.reconstruct(ctx, valid, fill, reconDataOnly, nil)
+}
+
+// ReconstructData will recreate the missing data shards if possible.
+//
+// Given a list of valid shards (to read) and invalid data shards (to write)
+//
+// You indicate that a shard is missing by setting it to nil in the 'valid'
+// slice and at the same time setting a non-nil writer in "fill".
+// The 'fill' slice must have one entry per data shard.
+// An index cannot contain both non-nil 'valid' and 'fill' entry.
+//
+// If there are too few shards to reconstruct the missing
+// ones, ErrTooFewShards will be returned.
+//
+// Parity shards are never reconstructed, so the shard set is not complete.
+func (r *rsStream) ReconstructData(valid []io.Reader, fill []io.Writer) error {
+	return r.ReconstructDataContext(context.Background(), valid, fill)
+}
+
+// ReconstructDataContext reconstructs missing data shards like ReconstructData,
+// but stops when ctx is canceled.
+func (r *rsStream) ReconstructDataContext(ctx context.Context, valid []io.Reader, fill []io.Writer) error {
+	if len(valid) != r.r.totalShards {
+		return ErrTooFewShards
+	}
+	if len(fill) != r.r.dataShards {
+		return ErrTooFewShards
+	}
+	all := make([]io.Writer, r.r.totalShards)
+	copy(all, fill)
+	return r.reconstruct(ctx, valid, all, true, nil)
+}
+
+// ReconstructSome will recreate only the requested missing shards.
+//
+// Given a list of valid shards (to read) and missing shards (to write)
+//
+// You indicate that a shard is missing by setting it to nil in the 'valid'
+// slice. Missing shards with a non-nil writer in "fill" are reconstructed,
+// and missing shards with a nil writer are skipped.
+// An index cannot contain both non-nil 'valid' and 'fill' entry.
+//
+// If there are too few shards to reconstruct the requested
+// ones, ErrTooFewShards will be returned.
+//
+// As the shard set is not complete unless all missing shards are requested,
+// calling the Verify function is likely to fail.
+func (r *rsStream) ReconstructSome(valid []io.Reader, fill []io.Writer) error {
+	return r.ReconstructSomeContext(context.Background(), valid, fill)
+}
+
+// ReconstructSomeContext reconstructs the requested shards like ReconstructSome,
+// but stops when ctx is canceled.
+func (r *rsStream) ReconstructSomeContext(ctx context.Context, valid []io.Reader, fill []io.Writer) error {
+	if len(valid) != r.r.totalShards {
+		return ErrTooFewShards
+	}
+	if len(fill) != r.r.totalShards {
+		return ErrTooFewShards
+	}
+	required := make([]bool, r.r.totalShards)
+	for i := range fill {
+		required[i] = fill[i] != nil
+	}
+	return r.reconstruct(ctx, valid, fill, false, required)
+}
+
+// reconstruct will write the missing shards in fill.
+// If dataOnly is set, only data shards are reconstructed.
+// If required is non-nil, only the required shards are reconstructed.
+func (r *rsStream) reconstruct(ctx context.Context, valid []io.Reader, fill []io.Writer, dataOnly bool, required []bool) error {
 	for i := range valid {
 		if valid[i] != nil && fill[i] != nil {
 			return ErrReconstructMismatch
 		}
-		if i >= r.r.dataShards && fill[i] != nil {
-			reconDataOnly = false
-		}
 	}
 
+	all, err := r.createSlice(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.releaseSlice(ctx, all)
+
 	read := 0
+	p := newProgress(r.o.progress, -1)
 	for {
-		err := r.readShards(all, valid)
+		err := r.readShards(ctx, all, valid, 0)
 		if err == io.EOF {
 			if read == 0 {
 				return ErrShardNoData
 			}
+			p.finish()
 			return nil
 		}
 		if err != nil {
@@ -505,7 +923,9 @@ func (r *rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
 		read += shardSize(all)
 		all = trimShards(all, shardSize(all))
 
-		if reconDataOnly {
+		if required != nil {
+			err = r.r.ReconstructSome(all, required) // just reconstruct requested shards
+		} else if dataOnly {
 			err = r.r.ReconstructData(all) // just reconstruct missing data shards
 		} else {
 			err = r.r.Reconstruct(all) //  reconstruct all missing shards
@@ -513,11 +933,274 @@ func (r *rsStream) Reconstruct(valid []io.Reader, fill []io.Writer) error {
 		if err != nil {
 			return err
 		}
-		err = r.writeShards(fill, all)
+		err = r.writeShards(ctx, fill, all, 0)
 		if err != nil {
 			return err
 		}
+		p.add(shardSize(all))
+	}
+}
+
+// Update computes new parity shards for a few changed data shards.
+//
+// Input is 'shards' containing readers for the old data shards followed by
+// the old parity shards. Old data shards that have not changed can be nil.
+// 'newDatashards' contains readers for the changed data shards,
+// with nil for unchanged shards.
+//
+// The updated parity shards will be written to 'parity'.
+// Only the changed data shards and the parity shards are read,
+// so this is faster than Encode when few data shards change.
+//
+// If an old data shard or parity shard needed for the update is nil,
+// ErrInvalidInput is returned. If the shard sizes do not match,
+// ErrShardSize is returned. If no data shard has changed,
+// ErrInvShardNum is returned.
+func (r *rsStream) Update(shards []io.Reader, newDatashards []io.Reader, parity []io.Writer) error {
+	return r.UpdateContext(context.Background(), shards, newDatashards, parity)
+}
+
+// UpdateContext updates parity shards like Update, but stops when ctx is canceled.
+func (r *rsStream) UpdateContext(ctx context.Context, shards []io.Reader, newDatashards []io.Reader, parity []io.Writer) error {
+	if len(shards) != r.r.totalShards || len(newDatashards) != r.r.dataShards || len(parity) != r.r.parityShards {
+		return ErrTooFewShards
+	}
+	changed := false
+	for i, in := range newDatashards {
+		if in != nil && shards[i] == nil {
+			return ErrInvalidInput
+		}
+		changed = changed || in != nil
+	}
+	if !changed {
+		return ErrInvShardNum
+	}
+	for i := range parity {
+		if shards[r.r.dataShards+i] == nil || parity[i] == nil {
+			return ErrInvalidInput
+		}
+	}
+
+	slices, err := r.createSlices(ctx, 2)
+	if err != nil {
+		return err
+	}
+	defer r.releaseSlices(ctx, slices)
+	all, newAll := slices[0], slices[1]
+	newData := make([][]byte, r.r.dataShards)
+	block := make([][]byte, r.r.totalShards)
+	read := 0
+	p := newProgress(r.o.progress, -1)
+	for {
+		err := r.readShards(ctx, all, shards, 0)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		newErr := r.readShards(ctx, newAll[:r.r.dataShards], newDatashards, 0)
+		if newErr != nil && newErr != io.EOF {
+			return newErr
+		}
+		if err == io.EOF || newErr == io.EOF {
+			if err != newErr {
+				return ErrShardSize
+			}
+			if read == 0 {
+				return ErrShardNoData
+			}
+			p.finish()
+			return nil
+		}
+		size := len(all[r.r.dataShards])
+		for i, in := range newDatashards {
+			newData[i] = nil
+			if in != nil {
+				if len(newAll[i]) != size || len(all[i]) != size {
+					return ErrShardSize
+				}
+				newData[i] = newAll[i]
+			}
+		}
+		for i := range block {
+			if shards[i] != nil {
+				block[i] = all[i]
+			}
+		}
+		if err := r.r.Update(block, newData); err != nil {
+			return err
+		}
+		read += size
+		if err := r.writeShards(ctx, parity, all[r.r.dataShards:], r.r.dataShards); err != nil {
+			return err
+		}
+		p.add(size)
+	}
+}
+
+// EncodeAt encodes parity shards for data shards of shardSize bytes.
+//
+// Blocks of the shards are read, encoded and written by several workers
+// concurrently, as set by WithStreamWorkers.
+// Blocks may be read and written in any order.
+//
+// If a data shard returns an error, a StreamReadError type error
+// will be returned. If a parity writer returns an error, a
+// StreamWriteError will be returned.
+func (r *rsStream) EncodeAt(data []io.ReaderAt, parity []io.WriterAt, shardSize int64) error {
+	return r.EncodeAtContext(context.Background(), data, parity, shardSize)
+}
+
+// EncodeAtContext encodes parity shards like EncodeAt, but stops when ctx is canceled.
+func (r *rsStream) EncodeAtContext(ctx context.Context, data []io.ReaderAt, parity []io.WriterAt, shardSize int64) error {
+	if len(data) != r.r.dataShards || len(parity) != r.r.parityShards {
+		return ErrTooFewShards
+	}
+	src := make([]io.ReaderAt, r.r.totalShards)
+	copy(src, data)
+	dst := make([]io.WriterAt, r.r.totalShards)
+	copy(dst[r.r.dataShards:], parity)
+	return r.runAt(ctx, src, dst, shardSize, func(shards [][]byte) error {
+		// Parity shards are not read, so extend them to the block size.
+		for i := r.r.dataShards; i < len(shards); i++ {
+			shards[i] = shards[i][:len(shards[0])]
+		}
+		return r.r.Encode(shards)
+	})
+}
+
+// ReconstructAt recreates the missing shards of shardSize bytes, if possible.
+//
+// You indicate that a shard is missing by setting it to nil in the 'valid'
+// slice and at the same time setting a non-nil writer in "fill".
+// An index cannot contain both non-nil 'valid' and 'fill' entry.
+// If no parity shards are requested, only data shards are reconstructed.
+//
+// Blocks of the shards are read, reconstructed and written by several workers
+// concurrently, as set by WithStreamWorkers.
+// Blocks may be read and written in any order.
+//
+// If there are too few shards to reconstruct the missing
+// ones, ErrTooFewShards will be returned.
+func (r *rsStream) ReconstructAt(valid []io.ReaderAt, fill []io.WriterAt, shardSize int64) error {
+	return r.ReconstructAtContext(context.Background(), valid, fill, shardSize)
+}
+
+// ReconstructAtContext recreates missing shards like ReconstructAt, but stops when ctx is canceled.
+func (r *rsStream) ReconstructAtContext(ctx context.Context, valid []io.ReaderAt, fill []io.WriterAt, shardSize int64) error {
+	if len(valid) != r.r.totalShards || len(fill) != r.r.totalShards {
+		return ErrTooFewShards
+	}
+	dataOnly := true
+	for i := range valid {
+		if valid[i] != nil && fill[i] != nil {
+			return ErrReconstructMismatch
+		}
+		if i >= r.r.dataShards && fill[i] != nil {
+			dataOnly = false
+		}
+	}
+	if dataOnly {
+		return r.runAt(ctx, valid, fill, shardSize, r.r.ReconstructData)
+	}
+	return r.runAt(ctx, valid, fill, shardSize, r.r.Reconstruct)
+}
+
+// runAt calls fn for each block of the shards, using the configured number of workers.
+// The shards in src are read into each block before calling fn,
+// and the shards in dst are written after.
+// The first error stops all workers.
+func (r *rsStream) runAt(ctx context.Context, src []io.ReaderAt, dst []io.WriterAt, shardSize int64, fn func(shards [][]byte) error) error {
+	if shardSize <= 0 {
+		return ErrShardNoData
+	}
+	workers := r.o.streamWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	bs := int64(r.o.streamBS)
+	blocks := (shardSize + bs - 1) / bs
+	if int64(workers) > blocks {
+		workers = int(blocks)
+	}
+
+	inner, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	p := newProgress(r.o.progress, shardSize)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			all, err := r.createSlice(inner)
+			if err != nil {
+				return
+			}
+			// All reads and writes are done when the worker returns.
+			defer r.releaseSlice(context.Background(), all)
+			block := make([][]byte, len(all))
+			for {
+				b := next.Add(1) - 1
+				if b >= blocks || inner.Err() != nil {
+					return
+				}
+				if err := r.blockAt(all, block, src, dst, b*bs, shardSize, fn); err != nil {
+					once.Do(func() { firstErr = err })
+					cancel()
+					return
+				}
+				if n := shardSize - b*bs; n < bs {
+					p.add(int(n))
+				} else {
+					p.add(int(bs))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if ctx.Err() != nil {
+		return streamCanceled(ctx)
+	}
+	p.finish()
+	return nil
+}
+
+// blockAt processes the block at offset off of each shard, using the buffers in all.
+func (r *rsStream) blockAt(all, block [][]byte, src []io.ReaderAt, dst []io.WriterAt, off, shardSize int64, fn func(shards [][]byte) error) error {
+	n := shardSize - off
+	if n > int64(r.o.streamBS) {
+		n = int64(r.o.streamBS)
+	}
+	for i := range block {
+		block[i] = all[i][:n]
+		if src[i] == nil {
+			block[i] = block[i][:0]
+			continue
+		}
+		if err := readAtFull(src[i], block[i], off, i); err != nil {
+			return err
+		}
 	}
+	if err := fn(block); err != nil {
+		return err
+	}
+	for i, w := range dst {
+		if w == nil {
+			continue
+		}
+		written, err := w.WriteAt(block[i], off)
+		if err == nil && written != len(block[i]) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return StreamWriteError{Err: err, Stream: i}
+		}
+	}
+	return nil
 }
 
 // Join the shards and write the data segment to dst.
@@ -557,6 +1240,99 @@ func (r *rsStream) Join(dst io.Writer, shards []io.Reader, outSize int64) error
 	return nil
 }
 
+// JoinAt joins the shards and writes the data segment to dst,
+// reading the data shards concurrently and writing each block at its offset.
+//
+// Only the data shards are considered.
+// The shards must be laid out as written by Split, so each data shard
+// contains (outSize+DataShards-1)/DataShards bytes of data.
+// Blocks of the shards are read and written by several workers
+// concurrently, as set by WithStreamWorkers.
+//
+// If there are to few shards given, ErrTooFewShards will be returned.
+// If a shard contains less data than needed, ErrShortData will be returned.
+// If dst returns an error, a StreamWriteError with the index of the shard
+// being written will be returned.
+func (r *rsStream) JoinAt(dst io.WriterAt, shards []io.ReaderAt, outSize int64) error {
+	if len(shards) < r.r.dataShards {
+		return ErrTooFewShards
+	}
+	shards = shards[:r.r.dataShards]
+	for i := range shards {
+		if shards[i] == nil {
+			return StreamReadError{Err: ErrShardNoData, Stream: i}
+		}
+	}
+	if outSize <= 0 {
+		return nil
+	}
+	perShard := (outSize + int64(r.r.dataShards) - 1) / int64(r.r.dataShards)
+	bs := int64(r.o.streamBS)
+	perBlocks := (perShard + bs - 1) / bs
+	blocks := perBlocks * int64(r.r.dataShards)
+	workers := r.o.streamWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if int64(workers) > blocks {
+		workers = int(blocks)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.o.memLimit.acquire(ctx, bs); err != nil {
+				return
+			}
+			defer r.o.memLimit.release(bs)
+			buf := make([]byte, bs)
+			for {
+				b := next.Add(1) - 1
+				if b >= blocks || ctx.Err() != nil {
+					return
+				}
+				i := int(b / perBlocks)
+				off := (b % perBlocks) * bs
+				pos := int64(i)*perShard + off
+				n := bs
+				if off+n > perShard {
+					n = perShard - off
+				}
+				if pos+n > outSize {
+					n = outSize - pos
+				}
+				if n <= 0 {
+					continue
+				}
+				err := readAtFull(shards[i], buf[:n], off, i)
+				var rerr StreamReadError
+				if errors.As(err, &rerr) && rerr.Err == io.ErrUnexpectedEOF {
+					err = ErrShortData
+				}
+				if err == nil {
+					if _, werr := dst.WriteAt(buf[:n], pos); werr != nil {
+						err = StreamWriteError{Err: werr, Stream: i}
+					}
+				}
+				if err != nil {
+					once.Do(func() { firstErr = err })
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
 // Split a an input stream into the number of shards given to the encoder.
 //
 // The data will be split into equally sized shards.
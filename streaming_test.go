@@ -8,10 +8,15 @@ package reedsolomon
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestStreamEncoding(t *testing.T) {
@@ -258,6 +263,202 @@ func toBytes(in []*bytes.Buffer) [][]byte {
 	return b
 }
 
+func TestStreamEncodeDigests(t *testing.T) {
+	perShard := 10 << 20
+	if testing.Short() {
+		perShard = 50000
+	}
+	r, err := NewStream(10, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(0)
+	shards := randomBytes(10, perShard)
+	parb := emptyBuffers(3)
+
+	digests, err := r.(StreamDigestEncoder).EncodeDigests(toReaders(toBuffers(shards)), toWriters(parb), sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := append(shards, toBytes(parb)...)
+	if len(digests) != len(all) {
+		t.Fatalf("got %d digests, want %d", len(digests), len(all))
+	}
+	for i, shard := range all {
+		want := sha256.Sum256(shard)
+		if !bytes.Equal(digests[i], want[:]) {
+			t.Errorf("shard %d: digest mismatch", i)
+		}
+	}
+}
+
+// concTracker records the maximum number of concurrent calls.
+type concTracker struct {
+	mu          sync.Mutex
+	active, max int
+}
+
+func (c *concTracker) enter() {
+	c.mu.Lock()
+	c.active++
+	if c.active > c.max {
+		c.max = c.active
+	}
+	c.mu.Unlock()
+	time.Sleep(time.Millisecond)
+}
+
+func (c *concTracker) leave() {
+	c.mu.Lock()
+	c.active--
+	c.mu.Unlock()
+}
+
+type trackedReader struct {
+	io.Reader
+	c *concTracker
+}
+
+func (t trackedReader) Read(p []byte) (int, error) {
+	t.c.enter()
+	defer t.c.leave()
+	return t.Reader.Read(p)
+}
+
+type trackedWriter struct {
+	io.Writer
+	c *concTracker
+}
+
+func (t trackedWriter) Write(p []byte) (int, error) {
+	t.c.enter()
+	defer t.c.leave()
+	return t.Writer.Write(p)
+}
+
+func TestStreamConcurrencyLimits(t *testing.T) {
+	const perShard = 50000
+	r, err := NewStream(10, 3, testOptions(
+		WithStreamBlockSize(10000),
+		WithStreamReadConcurrency(3),
+		WithStreamWriteConcurrency(0),
+		WithStreamShardConcurrency(0, true),
+		WithStreamShardConcurrency(12, false))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(0)
+	shards := randomBytes(10, perShard)
+	var reads, writes concTracker
+	in := toReaders(toBuffers(shards))
+	for i := range in {
+		in[i] = trackedReader{Reader: in[i], c: &reads}
+	}
+	parb := emptyBuffers(3)
+	out := toWriters(parb)
+	for i := range out {
+		out[i] = trackedWriter{Writer: out[i], c: &writes}
+	}
+	if err := r.Encode(in, out); err != nil {
+		t.Fatal(err)
+	}
+	// Three limited readers and the override.
+	if reads.max > 4 || reads.max < 2 {
+		t.Errorf("got %d concurrent reads, want 2-4", reads.max)
+	}
+	if writes.max > 3 || writes.max < 2 {
+		t.Errorf("got %d concurrent writes, want 2-3", writes.max)
+	}
+
+	all := append(shards, toBytes(parb)...)
+	ok, err := r.Verify(toReaders(toBuffers(all)))
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+}
+
+// blockingReader blocks until release is closed.
+type blockingReader struct {
+	release chan struct{}
+}
+
+func (b blockingReader) Read(p []byte) (int, error) {
+	<-b.release
+	return 0, io.EOF
+}
+
+// cancelingReader cancels a context after reading n bytes.
+type cancelingReader struct {
+	io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	if c.n -= n; c.n <= 0 {
+		c.cancel()
+	}
+	return n, err
+}
+
+func TestStreamContext(t *testing.T) {
+	const perShard = 50000
+	r, err := NewStream(10, 3, testOptions(WithStreamBlockSize(10000))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := randomBytes(10, perShard)
+
+	// Canceled before starting.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = r.(StreamContextEncoder).EncodeContext(ctx, toReaders(toBuffers(shards)), toWriters(emptyBuffers(3)))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+
+	// Canceled during the second block.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	in := toReaders(toBuffers(shards))
+	in[3] = &cancelingReader{Reader: in[3], n: 15000, cancel: cancel}
+	parb := emptyBuffers(3)
+	err = r.(StreamContextEncoder).EncodeContext(ctx, in, toWriters(parb))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+	if n := parb[0].Len(); n != 10000 {
+		t.Fatalf("wrote %d bytes before stopping", n)
+	}
+
+	// Concurrent reads return without waiting for blocked readers.
+	r, err = NewStream(10, 3, testOptions(WithStreamBlockSize(10000), WithConcurrentStreamReads(true))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	all := toReaders(toBuffers(append(shards, randomBytes(3, perShard)...)))
+	all[5] = blockingReader{release: release}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = r.(StreamContextEncoder).VerifyContext(ctx, all)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+	fill := make([]io.Writer, 13)
+	fill[5] = &bytes.Buffer{}
+	all = toReaders(toBuffers(append(shards, randomBytes(3, perShard)...)))
+	all[5] = nil
+	all[0] = blockingReader{release: release}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.(StreamContextEncoder).ReconstructContext(ctx, all, fill); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
 func TestStreamReconstruct(t *testing.T) {
 	perShard := 10 << 20
 	if testing.Short() {
@@ -370,6 +571,305 @@ func TestStreamReconstruct(t *testing.T) {
 	}
 }
 
+func TestStreamReconstructData(t *testing.T) {
+	perShard := 10 << 20
+	if testing.Short() {
+		perShard = 50000
+	}
+	r, err := NewStream(10, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(0)
+	shards := randomBytes(10, perShard)
+	parb := emptyBuffers(3)
+
+	err = r.Encode(toReaders(toBuffers(shards)), toWriters(parb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity := toBytes(parb)
+
+	// Reconstruct two data shards, with a parity shard missing.
+	all := append(toReaders(toBuffers(shards)), toReaders(toBuffers(parity))...)
+	fill := make([]io.Writer, 10)
+	filled := emptyBuffers(2)
+	all[0] = nil
+	fill[0] = filled[0]
+	all[7] = nil
+	fill[7] = filled[1]
+	all[11] = nil
+
+	err = r.(StreamDataReconstructor).ReconstructData(all, fill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(filled[0].Bytes(), shards[0]) || !bytes.Equal(filled[1].Bytes(), shards[7]) {
+		t.Fatal("reconstructed data mismatch")
+	}
+
+	err = r.(StreamDataReconstructor).ReconstructData(toReaders(emptyBuffers(13)), toWriters(emptyBuffers(13)))
+	if err != ErrTooFewShards {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	err = r.(StreamDataReconstructor).ReconstructData(toReaders(emptyBuffers(13)), toWriters(emptyBuffers(10)))
+	if err != ErrReconstructMismatch {
+		t.Errorf("expected %v, got %v", ErrReconstructMismatch, err)
+	}
+}
+
+func TestStreamReconstructSome(t *testing.T) {
+	perShard := 10 << 20
+	if testing.Short() {
+		perShard = 50000
+	}
+	r, err := NewStream(10, 3, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rand.Seed(0)
+	shards := randomBytes(10, perShard)
+	parb := emptyBuffers(3)
+
+	err = r.Encode(toReaders(toBuffers(shards)), toWriters(parb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity := toBytes(parb)
+
+	// Three shards are missing, but only one parity shard is requested.
+	all := append(toReaders(toBuffers(shards)), toReaders(toBuffers(parity))...)
+	fill := make([]io.Writer, 13)
+	filled := emptyBuffers(1)
+	all[2] = nil
+	all[11] = nil
+	all[12] = nil
+	fill[11] = filled[0]
+
+	err = r.(StreamReconstructSomer).ReconstructSome(all, fill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(filled[0].Bytes(), parity[1]) {
+		t.Fatal("reconstructed parity mismatch")
+	}
+
+	// Too many missing shards.
+	all = append(toReaders(toBuffers(shards)), toReaders(toBuffers(parity))...)
+	all[0], all[1], all[2], all[3] = nil, nil, nil, nil
+	fill = make([]io.Writer, 13)
+	fill[0] = emptyBuffers(1)[0]
+	err = r.(StreamReconstructSomer).ReconstructSome(all, fill)
+	if err != ErrTooFewShards {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	err = r.(StreamReconstructSomer).ReconstructSome(toReaders(emptyBuffers(13)), toWriters(emptyBuffers(13)))
+	if err != ErrReconstructMismatch {
+		t.Errorf("expected %v, got %v", ErrReconstructMismatch, err)
+	}
+}
+
+func TestStreamUpdate(t *testing.T) {
+	perShard := 10 << 20
+	if testing.Short() {
+		perShard = 50000
+	}
+	r, err := NewStream(10, 3, testOptions(WithStreamBlockSize(16384))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := randomBytes(10, perShard)
+	parb := emptyBuffers(3)
+	err = r.Encode(toReaders(toBuffers(shards)), toWriters(parb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity := toBytes(parb)
+
+	// Change two data shards, and only supply those.
+	newData := make([][]byte, 10)
+	newData[1] = randomBytes(1, perShard)[0]
+	newData[6] = randomBytes(1, perShard)[0]
+	old := make([]io.Reader, 13)
+	newIn := make([]io.Reader, 10)
+	for _, i := range []int{1, 6} {
+		old[i] = bytes.NewReader(shards[i])
+		newIn[i] = bytes.NewReader(newData[i])
+	}
+	for i := range parity {
+		old[10+i] = bytes.NewReader(parity[i])
+	}
+	updated := emptyBuffers(3)
+	err = r.(StreamUpdater).Update(old, newIn, toWriters(updated))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shards[1], shards[6] = newData[1], newData[6]
+	want := emptyBuffers(3)
+	err = r.Encode(toReaders(toBuffers(shards)), toWriters(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if !bytes.Equal(updated[i].Bytes(), want[i].Bytes()) {
+			t.Fatalf("parity shard %d mismatch", i)
+		}
+	}
+
+	// The old data shard is required for changed shards.
+	old[1] = nil
+	err = r.(StreamUpdater).Update(old, newIn, toWriters(emptyBuffers(3)))
+	if err != ErrInvalidInput {
+		t.Errorf("expected %v, got %v", ErrInvalidInput, err)
+	}
+
+	// Shard sizes must match.
+	old = make([]io.Reader, 13)
+	newIn = make([]io.Reader, 10)
+	old[0] = bytes.NewReader(shards[0])
+	newIn[0] = bytes.NewReader(newData[1][:perShard-1])
+	for i := range parity {
+		old[10+i] = bytes.NewReader(parity[i])
+	}
+	err = r.(StreamUpdater).Update(old, newIn, toWriters(emptyBuffers(3)))
+	if err != ErrShardSize {
+		t.Errorf("expected %v, got %v", ErrShardSize, err)
+	}
+
+	// At least one data shard must change.
+	for i := range parity {
+		old[10+i] = bytes.NewReader(parity[i])
+	}
+	err = r.(StreamUpdater).Update(old, make([]io.Reader, 10), toWriters(emptyBuffers(3)))
+	if err != ErrInvShardNum {
+		t.Errorf("expected %v, got %v", ErrInvShardNum, err)
+	}
+}
+
+// atBuffer is an io.WriterAt that can be written concurrently at distinct offsets.
+type atBuffer struct {
+	data []byte
+}
+
+func (a *atBuffer) WriteAt(p []byte, off int64) (int, error) {
+	return copy(a.data[off:], p), nil
+}
+
+func TestStreamEncodeAt(t *testing.T) {
+	perShard := 10 << 20
+	if testing.Short() {
+		perShard = 50000
+	}
+	r, err := NewStream(10, 3, testOptions(WithStreamBlockSize(4096), WithStreamWorkers(4))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := randomBytes(10, perShard)
+	parb := emptyBuffers(3)
+	err = r.Encode(toReaders(toBuffers(shards)), toWriters(parb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parity := toBytes(parb)
+
+	src := make([]io.ReaderAt, 10)
+	for i := range src {
+		src[i] = bytes.NewReader(shards[i])
+	}
+	out := make([]*atBuffer, 3)
+	dst := make([]io.WriterAt, 3)
+	for i := range dst {
+		out[i] = &atBuffer{data: make([]byte, perShard)}
+		dst[i] = out[i]
+	}
+	err = r.(StreamEncoderAt).EncodeAt(src, dst, int64(perShard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range parity {
+		if !bytes.Equal(out[i].data, parity[i]) {
+			t.Fatalf("parity shard %d mismatch", i)
+		}
+	}
+
+	// Reconstruct a data shard and a parity shard.
+	all := make([]io.ReaderAt, 13)
+	for i := range all {
+		if i < 10 {
+			all[i] = bytes.NewReader(shards[i])
+		} else {
+			all[i] = bytes.NewReader(parity[i-10])
+		}
+	}
+	all[3], all[12] = nil, nil
+	fill := make([]io.WriterAt, 13)
+	filled := []*atBuffer{{data: make([]byte, perShard)}, {data: make([]byte, perShard)}}
+	fill[3], fill[12] = filled[0], filled[1]
+	err = r.(StreamEncoderAt).ReconstructAt(all, fill, int64(perShard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(filled[0].data, shards[3]) || !bytes.Equal(filled[1].data, parity[2]) {
+		t.Fatal("reconstructed shard mismatch")
+	}
+
+	// A read error is returned.
+	all[0] = bytes.NewReader(shards[0][:perShard/2])
+	err = r.(StreamEncoderAt).ReconstructAt(all, fill, int64(perShard))
+	var rerr StreamReadError
+	if !errors.As(err, &rerr) || rerr.Stream != 0 {
+		t.Errorf("expected StreamReadError on stream 0, got %v", err)
+	}
+	all[0], all[1], all[2] = bytes.NewReader(shards[0]), nil, nil
+	err = r.(StreamEncoderAt).ReconstructAt(all, fill, int64(perShard))
+	if err != ErrTooFewShards {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+	fill[0] = filled[0]
+	err = r.(StreamEncoderAt).ReconstructAt(all, fill, int64(perShard))
+	if err != ErrReconstructMismatch {
+		t.Errorf("expected %v, got %v", ErrReconstructMismatch, err)
+	}
+}
+
+func TestStreamJoinAt(t *testing.T) {
+	r, err := NewStream(10, 3, testOptions(WithStreamBlockSize(1000), WithStreamWorkers(3))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 123457)
+	fillRandom(data)
+	split := emptyBuffers(10)
+	if err := r.Split(bytes.NewReader(data), toWriters(split), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	shards := make([]io.ReaderAt, 13)
+	for i := range split {
+		shards[i] = bytes.NewReader(split[i].Bytes())
+	}
+	dst := &atBuffer{data: make([]byte, len(data))}
+	if err := r.(StreamJoinerAt).JoinAt(dst, shards, int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.data, data) {
+		t.Fatal("joined data mismatch")
+	}
+
+	shards[9] = bytes.NewReader(split[9].Bytes()[:100])
+	if err := r.(StreamJoinerAt).JoinAt(dst, shards, int64(len(data))); err != ErrShortData {
+		t.Errorf("expected %v, got %v", ErrShortData, err)
+	}
+	shards[4] = nil
+	var rerr StreamReadError
+	if err := r.(StreamJoinerAt).JoinAt(dst, shards, int64(len(data))); !errors.As(err, &rerr) || rerr.Stream != 4 {
+		t.Errorf("expected StreamReadError on stream 4, got %v", err)
+	}
+	if err := r.(StreamJoinerAt).JoinAt(dst, shards[:9], int64(len(data))); err != ErrTooFewShards {
+		t.Errorf("expected %v, got %v", ErrTooFewShards, err)
+	}
+}
+
 func TestStreamVerify(t *testing.T) {
 	perShard := 10 << 20
 	if testing.Short() {
@@ -0,0 +1,239 @@
+package reedsolomon
+
+// syndromeDecoder locates corrupt shards of a generalized Reed-Solomon code
+// from the syndromes of the shards, using the Berlekamp-Massey algorithm.
+//
+// The parity check matrix has the rows h[j][i] = v[i] * x[i]^j,
+// so the syndromes of errors e[i] are s[j] = sum(v[i] * e[i] * x[i]^j)
+// and the shards with errors are given by the roots of the error locator.
+type syndromeDecoder struct {
+	r    *reedSolomon
+	h    matrix // Parity check matrix, in the representation of the field of r.
+	xInv []byte // Inverse of the error locator of each shard, in the standard representation.
+}
+
+// syndromeDecoder returns a decoder for the code of r,
+// or nil if the matrix of r is not a generalized Reed-Solomon code
+// with the evaluation points 0 to totalShards-1.
+//
+// This is the case for the systematic matrices built from a Vandermonde
+// or Cauchy matrix. Other matrices are detected while deriving the parity
+// check matrix.
+func (r *reedSolomon) syndromeDecoder() *syndromeDecoder {
+	k, p, n := r.dataShards, r.parityShards, r.totalShards
+	if p == 0 {
+		return nil
+	}
+	f := r.o.gf()
+	a := make([]byte, n)
+	var used [256]bool
+	for i := range a {
+		a[i] = f.toStdByte(byte(i))
+		used[a[i]] = true
+	}
+	// The locators are the points offset by an unused element,
+	// so no shard has a zero locator.
+	offset := -1
+	for i, u := range used {
+		if !u {
+			offset = i
+			break
+		}
+	}
+	if offset < 0 {
+		return nil
+	}
+
+	for i, row := range r.m[:k] {
+		for c, v := range row {
+			if v != 0 && (v != 1 || c != i) {
+				return nil
+			}
+		}
+	}
+	parity := make(matrix, p)
+	for j := range parity {
+		parity[j] = make([]byte, k)
+		for i, v := range r.m[k+j] {
+			parity[j][i] = f.toStdByte(v)
+		}
+	}
+
+	// poly returns the value at x of the polynomial with roots
+	// at the points of all parity shards except k+j.
+	poly := func(j int, x byte) byte {
+		y := byte(1)
+		for l := k; l < n; l++ {
+			if l != k+j {
+				y = galMultiply(y, x^a[l])
+			}
+		}
+		return y
+	}
+
+	// Row j of the systematic parity check matrix [parity | I] must be
+	// scale[j] * v[i] * poly(j, a[i]), with v the column multipliers of the dual code.
+	// Derive scale from the first column and v from the first row,
+	// then check the remaining rows.
+	scale := make([]byte, p)
+	for j := range scale {
+		if parity[j][0] == 0 {
+			return nil
+		}
+		scale[j] = galDivide(parity[j][0], poly(j, a[0]))
+	}
+	v := make([]byte, n)
+	for i := 0; i < k; i++ {
+		v[i] = galDivide(parity[0][i], galMultiply(scale[0], poly(0, a[i])))
+		if v[i] == 0 {
+			return nil
+		}
+	}
+	for j := 0; j < p; j++ {
+		v[k+j] = galOneOver(galMultiply(scale[j], poly(j, a[k+j])))
+	}
+	for j := 1; j < p; j++ {
+		for i := 1; i < k; i++ {
+			if parity[j][i] != galMultiply(galMultiply(scale[j], v[i]), poly(j, a[i])) {
+				return nil
+			}
+		}
+	}
+
+	d := &syndromeDecoder{r: r, h: make(matrix, p), xInv: make([]byte, n)}
+	for j := range d.h {
+		d.h[j] = make([]byte, n)
+		for i := range d.h[j] {
+			d.h[j][i] = galMultiply(v[i], galExp(a[i]^byte(offset), j))
+		}
+	}
+	for i := range d.xInv {
+		d.xInv[i] = galOneOver(a[i] ^ byte(offset))
+	}
+	f.fromStdMatrix(d.h)
+	return d
+}
+
+// locate marks the shards with errors in corrupt, checking window bytes at a time.
+// Missing shards are treated as erasures.
+// ErrUncorrectable is returned if more than maxErrors shards have errors,
+// or if the errors of a byte cannot be located.
+func (d *syndromeDecoder) locate(shards [][]byte, size, window, maxErrors int, corrupt []bool) error {
+	p := len(d.h)
+	f := d.r.o.gf()
+
+	// The erasure locator is the product of (1 - x[i]*z) for the missing shards.
+	gamma := make([]byte, p+1)
+	gamma[0] = 1
+	erasures := 0
+	for i, shard := range shards {
+		if len(shard) != 0 {
+			continue
+		}
+		x := galOneOver(d.xInv[i])
+		for l := erasures + 1; l > 0; l-- {
+			gamma[l] ^= galMultiply(x, gamma[l-1])
+		}
+		erasures++
+	}
+
+	syn := make([][]byte, p)
+	for j := range syn {
+		syn[j] = make([]byte, window)
+	}
+	s := make([]byte, p)
+	c, b, t := make([]byte, p+1), make([]byte, p+1), make([]byte, p+1)
+	found := 0
+	for off := 0; off < size; off += window {
+		n := window
+		if size-off < n {
+			n = size - off
+		}
+		for j, row := range d.h {
+			out := syn[j][:n]
+			for i := range out {
+				out[i] = 0
+			}
+			for i, shard := range shards {
+				if len(shard) != 0 {
+					galMulSliceXor(row[i], shard[off:off+n], out, &d.r.o)
+				}
+			}
+		}
+		for pos := 0; pos < n; pos++ {
+			zero := true
+			for j := range s {
+				s[j] = f.toStdByte(syn[j][pos])
+				zero = zero && s[j] == 0
+			}
+			if zero {
+				continue
+			}
+			l := berlekampMassey(s, gamma, erasures, c, b, t)
+			if 2*(l-erasures) > p-erasures {
+				return ErrUncorrectable
+			}
+			roots := 0
+			for i, x := range d.xInv {
+				if evalPoly(c[:l+1], x) != 0 {
+					continue
+				}
+				roots++
+				if len(shards[i]) != 0 && !corrupt[i] {
+					corrupt[i] = true
+					found++
+					if found > maxErrors {
+						return ErrUncorrectable
+					}
+				}
+			}
+			if roots != l {
+				return ErrUncorrectable
+			}
+		}
+	}
+	return nil
+}
+
+// berlekampMassey stores in c the locator of the errors and erasures
+// with the syndromes s, where gamma is the locator of the erasures,
+// and returns the degree of the locator.
+// b and t are scratch space with the same length as c.
+func berlekampMassey(s, gamma []byte, erasures int, c, b, t []byte) int {
+	copy(c, gamma)
+	copy(b, gamma)
+	l, m, prev := erasures, 1, byte(1)
+	for n := erasures; n < len(s); n++ {
+		var d byte
+		for i := 0; i <= n && i < len(c); i++ {
+			d ^= galMultiply(c[i], s[n-i])
+		}
+		if d == 0 {
+			m++
+			continue
+		}
+		coef := galDivide(d, prev)
+		copy(t, c)
+		for i := 0; i+m < len(c); i++ {
+			c[i+m] ^= galMultiply(coef, b[i])
+		}
+		if 2*l <= n+erasures {
+			l = n + 1 - l + erasures
+			copy(b, t)
+			prev = d
+			m = 1
+		} else {
+			m++
+		}
+	}
+	return l
+}
+
+// evalPoly returns the value of the polynomial with the coefficients c at x.
+func evalPoly(c []byte, x byte) byte {
+	var y byte
+	for i := len(c) - 1; i >= 0; i-- {
+		y = galMultiply(y, x) ^ c[i]
+	}
+	return y
+}
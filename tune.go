@@ -0,0 +1,108 @@
+package reedsolomon
+
+import "time"
+
+// Benchmark measures encoding on this machine with dataShards data shards
+// and parityShards parity shards of shardSize bytes, and returns the options
+// for New that were fastest.
+//
+// The options select the codec, the CPU kernels, the number of goroutines
+// and the minimum split size, so they can be determined once, for example
+// when a service starts, and used for all encoders of that shape.
+// Reed-Solomon kernels and goroutines are selected as done by WithAutoTune,
+// and the codecs are compared by encoding for duration in total.
+//
+// The codec may be a Leopard codec, which produces parity that is not compatible
+// with the other codecs, so the options must only be used for new data,
+// and must be stored with it.
+// All shards are allocated, so memory for TotalShards shards of shardSize is needed.
+func Benchmark(dataShards, parityShards, shardSize int, duration time.Duration) ([]Option, error) {
+	if shardSize <= 0 {
+		return nil, ErrInvalidShardSize
+	}
+	type candidate struct {
+		opts []Option
+		enc  Encoder
+	}
+	var candidates []candidate
+	add := func(opts ...Option) error {
+		enc, err := New(dataShards, parityShards, opts...)
+		if err == nil {
+			candidates = append(candidates, candidate{opts: opts, enc: enc})
+		}
+		return err
+	}
+
+	if dataShards+parityShards <= 256 {
+		enc, err := New(dataShards, parityShards, WithAutoTune(), WithAutoGoroutines(shardSize))
+		if err != nil {
+			return nil, err
+		}
+		r, ok := enc.(*reedSolomon)
+		if !ok {
+			return nil, ErrNotSupported
+		}
+		candidates = append(candidates, candidate{opts: r.tunedOptions(), enc: enc})
+		if err := add(WithLeopardGF(true)); err != nil {
+			return nil, err
+		}
+	}
+	if dataShards+parityShards <= 65536 {
+		if err := add(WithLeopardGF16(true)); err != nil {
+			return nil, err
+		}
+	} else if err := add(WithLeopardGF32(true)); err != nil {
+		return nil, err
+	}
+
+	per := duration / time.Duration(len(candidates))
+	best, bestSpeed := 0, 0.0
+	for i, c := range candidates {
+		ext := c.enc.(Extensions)
+		size := shardSize
+		if mult := ext.ShardSizeMultiple(); size%mult != 0 {
+			size += mult - size%mult
+		}
+		shards := ext.AllocAligned(size)
+		for j, shard := range shards[:dataShards] {
+			for k := range shard {
+				shard[k] = byte(j*31 + k*7)
+			}
+		}
+		var n int
+		start := time.Now()
+		for n == 0 || time.Since(start) < per {
+			if err := c.enc.Encode(shards); err != nil {
+				return nil, err
+			}
+			n++
+		}
+		speed := float64(n*size) / float64(time.Since(start))
+		if speed > bestSpeed {
+			best, bestSpeed = i, speed
+		}
+	}
+	return candidates[best].opts, nil
+}
+
+// tunedOptions returns the options that create an encoder with the
+// kernels and goroutines of r.
+func (r *reedSolomon) tunedOptions() []Option {
+	cal := r.calib.Load()
+	opts := []Option{WithMaxGoroutines(cal.maxGoroutines), WithMinSplitSize(r.o.minSplitSize)}
+	for _, f := range []struct {
+		def, use bool
+		opt      func(bool) Option
+	}{
+		{def: defaultOptions.useAvx512GFNI, use: r.o.useAvx512GFNI, opt: WithGFNI},
+		{def: defaultOptions.useAvxGNFI, use: r.o.useAvxGNFI, opt: WithAVXGFNI},
+		{def: defaultOptions.useAVX512, use: r.o.useAVX512, opt: WithAVX512},
+		{def: defaultOptions.useAVX10, use: r.o.useAVX10, opt: WithAVX10},
+		{def: defaultOptions.useSHA3, use: r.o.useSHA3, opt: WithSHA3},
+	} {
+		if f.def && !f.use {
+			opts = append(opts, f.opt(false))
+		}
+	}
+	return opts
+}
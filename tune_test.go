@@ -0,0 +1,36 @@
+package reedsolomon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBenchmark(t *testing.T) {
+	for _, shape := range [][2]int{{10, 4}, {250, 50}} {
+		opts, err := Benchmark(shape[0], shape[1], 16<<10, 20*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := New(shape[0], shape[1], opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(16 << 10)
+		for _, shard := range shards[:shape[0]] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("not ok:", ok, "err:", err)
+		}
+	}
+	if _, err := Benchmark(10, 4, 0, time.Millisecond); err != ErrInvalidShardSize {
+		t.Errorf("got %v, want %v", err, ErrInvalidShardSize)
+	}
+	if _, err := Benchmark(0, 4, 1000, time.Millisecond); err != ErrInvShardNum {
+		t.Errorf("got %v, want %v", err, ErrInvShardNum)
+	}
+}
@@ -1,4 +1,4 @@
-//go:build !noasm && !nounsafe && !gccgo && !appengine
+//go:build !noasm && !purego && !nounsafe && !gccgo && !appengine
 
 /**
  * Reed-Solomon Coding over 8-bit values.
@@ -39,3 +39,8 @@ func AllocAligned(shards, each int) [][]byte {
 	}
 	return res
 }
+
+// addrAligned returns whether b starts on a shardAlign byte boundary.
+func addrAligned(b []byte) bool {
+	return uintptr(unsafe.Pointer(unsafe.SliceData(b)))&(shardAlign-1) == 0
+}
@@ -1,4 +1,4 @@
-//go:build noasm || nounsafe || gccgo || appengine
+//go:build noasm || purego || nounsafe || gccgo || appengine
 
 /**
  * Reed-Solomon Coding over 8-bit values.
@@ -21,3 +21,9 @@ func AllocAligned(shards, each int) [][]byte {
 	}
 	return res
 }
+
+// addrAligned returns whether b starts on a shardAlign byte boundary.
+// Addresses cannot be checked without unsafe, so it always returns true.
+func addrAligned(b []byte) bool {
+	return true
+}
@@ -0,0 +1,151 @@
+package reedsolomon
+
+import (
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// sampleBlockSize is the size of the column ranges checked by VerifySample and VerifySampleAt.
+const sampleBlockSize = 4096
+
+// VerifySample verifies a random fraction of the column ranges of shards,
+// instead of every byte, for cheap continuous scrubbing.
+//
+// The shards are divided into ranges of 4KB, and about fraction of the ranges,
+// at least one, are chosen at random and verified like Verify.
+// fraction must be in (0, 1].
+//
+// If damage is found, false is returned with a confidence of 1.
+// Otherwise the confidence is the fraction of ranges that were checked,
+// which is the probability that damage confined to a single range would
+// have been found. Damage to n independent ranges is found with probability
+// 1-(1-confidence)^n.
+//
+// All shards must be present. Only encoders where ranges of the shards can be
+// verified independently are supported, so encoders using WithClayCode
+// or WithPiggyback return ErrNotSupported.
+func VerifySample(enc Encoder, shards [][]byte, fraction float64) (bool, float64, error) {
+	ext, ok := enc.(Extensions)
+	if !ok || !migratable(enc) {
+		return false, 0, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return false, 0, ErrTooFewShards
+	}
+	if err := checkShards(shards, false); err != nil {
+		return false, 0, err
+	}
+	blocks, size, err := sampleBlocks(ext, int64(len(shards[0])), fraction)
+	if err != nil {
+		return false, 0, err
+	}
+	sub := make([][]byte, len(shards))
+	for _, b := range blocks {
+		start := b * size
+		end := start + size
+		if end > int64(len(shards[0])) {
+			end = int64(len(shards[0]))
+		}
+		for i, shard := range shards {
+			sub[i] = shard[start:end]
+		}
+		ok, err := enc.Verify(sub)
+		if err != nil {
+			return false, 0, err
+		}
+		if !ok {
+			return false, 1, nil
+		}
+	}
+	return true, sampleConfidence(len(blocks), int64(len(shards[0])), size), nil
+}
+
+// VerifySampleAt verifies a random fraction of the column ranges of shards
+// of shardSize bytes read from shards, like VerifySample.
+// Only the chosen ranges are read, in increasing order.
+//
+// If a read fails, a StreamReadError is returned.
+func VerifySampleAt(enc Encoder, shards []io.ReaderAt, shardSize int64, fraction float64) (bool, float64, error) {
+	ext, ok := enc.(Extensions)
+	if !ok || !migratable(enc) {
+		return false, 0, ErrNotSupported
+	}
+	if len(shards) != ext.TotalShards() {
+		return false, 0, ErrTooFewShards
+	}
+	for _, r := range shards {
+		if r == nil {
+			return false, 0, ErrTooFewShards
+		}
+	}
+	if shardSize <= 0 {
+		return false, 0, ErrShardNoData
+	}
+	blocks, size, err := sampleBlocks(ext, shardSize, fraction)
+	if err != nil {
+		return false, 0, err
+	}
+	buf := AllocAligned(len(shards), int(size))
+	sub := make([][]byte, len(shards))
+	for _, b := range blocks {
+		start := b * size
+		n := size
+		if start+n > shardSize {
+			n = shardSize - start
+		}
+		for i, r := range shards {
+			sub[i] = buf[i][:n]
+			if err := readAtFull(r, sub[i], start, i); err != nil {
+				return false, 0, err
+			}
+		}
+		ok, err := enc.Verify(sub)
+		if err != nil {
+			return false, 0, err
+		}
+		if !ok {
+			return false, 1, nil
+		}
+	}
+	return true, sampleConfidence(len(blocks), shardSize, size), nil
+}
+
+// sampleBlocks returns the indexes of the sampled ranges in increasing order,
+// and the size of each range.
+func sampleBlocks(ext Extensions, shardSize int64, fraction float64) ([]int64, int64, error) {
+	if !(fraction > 0 && fraction <= 1) {
+		return nil, 0, ErrInvalidInput
+	}
+	mult := int64(ext.ShardSizeMultiple())
+	size := (sampleBlockSize + mult - 1) / mult * mult
+	total := (shardSize + size - 1) / size
+	n := int64(fraction * float64(total))
+	if n < 1 {
+		n = 1
+	}
+	if n > total {
+		n = total
+	}
+
+	// Choose n distinct ranges with Floyd's algorithm.
+	chosen := make(map[int64]struct{}, n)
+	for j := total - n; j < total; j++ {
+		b := rand.Int63n(j + 1)
+		if _, ok := chosen[b]; ok {
+			b = j
+		}
+		chosen[b] = struct{}{}
+	}
+	blocks := make([]int64, 0, n)
+	for b := range chosen {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	return blocks, size, nil
+}
+
+// sampleConfidence returns the fraction of the ranges that were checked.
+func sampleConfidence(checked int, shardSize, size int64) float64 {
+	return float64(checked) / float64((shardSize+size-1)/size)
+}
@@ -0,0 +1,82 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestVerifySample(t *testing.T) {
+	enc, err := New(10, 4, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(100 * 4096)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	if err := enc.Encode(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, conf, err := VerifySample(enc, shards, 0.1)
+	if err != nil || !ok {
+		t.Fatalf("verification failed: %v", err)
+	}
+	if conf != 0.1 {
+		t.Fatalf("confidence %v, want 0.1", conf)
+	}
+
+	// A full sample always finds the damage.
+	shards[12][123456] ^= 1
+	ok, conf, err = VerifySample(enc, shards, 1)
+	if err != nil || ok || conf != 1 {
+		t.Fatalf("damage not found: %v %v %v", ok, conf, err)
+	}
+
+	// With a small sample, damage is found about as often as the confidence.
+	found := 0
+	for i := 0; i < 200; i++ {
+		ok, _, err := VerifySample(enc, shards, 0.25)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			found++
+		}
+	}
+	if found < 20 || found > 80 {
+		t.Errorf("damage found %d of 200 times", found)
+	}
+
+	readers := make([]io.ReaderAt, len(shards))
+	for i, shard := range shards {
+		readers[i] = bytes.NewReader(shard)
+	}
+	ok, _, err = VerifySampleAt(enc, readers, int64(len(shards[0])), 1)
+	if err != nil || ok {
+		t.Fatalf("damage not found: %v %v", ok, err)
+	}
+	shards[12][123456] ^= 1
+	ok, conf, err = VerifySampleAt(enc, readers, int64(len(shards[0])), 0.001)
+	if err != nil || !ok || conf != 0.01 {
+		t.Fatalf("verification failed: %v %v %v", ok, conf, err)
+	}
+
+	// A short shard is a read error.
+	readers[3] = bytes.NewReader(shards[3][:1000])
+	var rerr StreamReadError
+	if _, _, err := VerifySampleAt(enc, readers, int64(len(shards[0])), 1); !errors.As(err, &rerr) || rerr.Stream != 3 {
+		t.Errorf("want StreamReadError on stream 3, got %v", err)
+	}
+	if _, _, err := VerifySample(enc, shards, 0); err != ErrInvalidInput {
+		t.Errorf("want ErrInvalidInput, got %v", err)
+	}
+	clay, err := New(4, 2, WithClayCode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := VerifySample(clay, shards[:6], 1); err != ErrNotSupported {
+		t.Errorf("want ErrNotSupported, got %v", err)
+	}
+}
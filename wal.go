@@ -0,0 +1,226 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// walHeaderSize is the size of the header written before each shard of a WAL record.
+// The header contains the sequence number (8 bytes), record length (4 bytes),
+// shard length (4 bytes) and a CRC32-C of the header fields and shard (4 bytes).
+const walHeaderSize = 20
+
+// walReadChunk is the largest shard ReadWAL allocates at once.
+// Larger shards are read in parts, so a corrupted header cannot make
+// ReadWAL allocate much more memory than the device contains.
+const walReadChunk = 1 << 20
+
+var walCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrWALCorrupt is returned by ReadWAL when devices disagree on the log contents.
+var ErrWALCorrupt = errors.New("wal devices are inconsistent")
+
+// WALDevice is a device a WAL writes shards to.
+// Sync is called after each record has been written to all devices,
+// and should make the written data durable, like (*os.File).Sync.
+type WALDevice interface {
+	io.Writer
+	Sync() error
+}
+
+// WAL is a write-ahead log that erasure codes each record across
+// one device per shard.
+//
+// Records can be recovered with ReadWAL as long as no more than
+// ParityShards devices are lost or damaged.
+// A WAL is not safe for concurrent use.
+type WAL struct {
+	enc     Encoder
+	devices []WALDevice
+	seq     uint64
+	buf     []byte
+	err     error
+}
+
+// NewWAL returns a WAL writing to the given devices.
+// There must be a device for each shard, in shard order.
+// The first record written will have sequence number seq.
+func NewWAL(enc Encoder, devices []WALDevice, seq uint64) (*WAL, error) {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if len(devices) != ext.TotalShards() {
+		return nil, ErrTooFewShards
+	}
+	return &WAL{enc: enc, devices: devices, seq: seq}, nil
+}
+
+// Append encodes rec and writes a shard to each device.
+// When all shards have been written, all devices are synced.
+// The sequence number of the record is returned.
+//
+// If writing or syncing fails, the error is returned and the record
+// should be considered not written.
+// Since some devices may contain the record, the WAL cannot be used after
+// that, and all later calls return the same error.
+func (w *WAL) Append(rec []byte) (uint64, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(rec) == 0 {
+		return 0, ErrShortData
+	}
+	ext := w.enc.(Extensions)
+	shardSize := walShardSize(ext, len(rec))
+
+	// Allocate each shard with room for its header.
+	size := (walHeaderSize + shardSize) * len(w.devices)
+	if cap(w.buf) < size {
+		w.buf = make([]byte, size)
+	}
+	buf := w.buf[:size]
+	for i := range buf {
+		buf[i] = 0
+	}
+	shards := make([][]byte, len(w.devices))
+	for i := range shards {
+		shards[i] = buf[i*(walHeaderSize+shardSize)+walHeaderSize : (i+1)*(walHeaderSize+shardSize)]
+	}
+	recLen := len(rec)
+	for i := 0; i < ext.DataShards() && len(rec) > 0; i++ {
+		rec = rec[copy(shards[i], rec):]
+	}
+	if err := w.enc.Encode(shards); err != nil {
+		return 0, err
+	}
+
+	seq := w.seq
+	for i, dev := range w.devices {
+		b := buf[i*(walHeaderSize+shardSize) : (i+1)*(walHeaderSize+shardSize)]
+		binary.LittleEndian.PutUint64(b, seq)
+		binary.LittleEndian.PutUint32(b[8:], uint32(recLen))
+		binary.LittleEndian.PutUint32(b[12:], uint32(shardSize))
+		binary.LittleEndian.PutUint32(b[16:], walChecksum(b[:16], shards[i]))
+		if _, err := dev.Write(b); err != nil {
+			w.err = err
+			return 0, err
+		}
+	}
+	for _, dev := range w.devices {
+		if err := dev.Sync(); err != nil {
+			w.err = err
+			return 0, err
+		}
+	}
+	w.seq++
+	return seq, nil
+}
+
+// ReadWAL reads the records written by a WAL and calls fn for each
+// record in order.
+//
+// There must be a reader for each device, in shard order.
+// Lost devices should be nil.
+// Shards with a bad checksum are treated as missing, and a device
+// that ends before the others is treated as lost from that point.
+//
+// Reading stops without error when fewer than DataShards devices
+// contain the next record, since that record was never fully written.
+// If fn returns an error, reading stops and the error is returned.
+func ReadWAL(enc Encoder, devices []io.Reader, fn func(seq uint64, rec []byte) error) error {
+	ext, ok := enc.(Extensions)
+	if !ok {
+		return ErrNotSupported
+	}
+	if len(devices) != ext.TotalShards() {
+		return ErrTooFewShards
+	}
+	devices = append([]io.Reader{}, devices...)
+	for {
+		shards := make([][]byte, len(devices))
+		var seq uint64
+		recLen, shardSize, found := -1, -1, 0
+		for i, dev := range devices {
+			if dev == nil {
+				continue
+			}
+			var header [walHeaderSize]byte
+			if _, err := io.ReadFull(dev, header[:]); err != nil {
+				devices[i] = nil
+				continue
+			}
+			// The length cannot be checked by the checksum before the shard
+			// is read, so check that it matches the record length.
+			n := int(binary.LittleEndian.Uint32(header[12:]))
+			if rl := int(binary.LittleEndian.Uint32(header[8:])); rl == 0 || n != walShardSize(ext, rl) {
+				devices[i] = nil
+				continue
+			}
+			shard, err := readWALShard(dev, n)
+			if err != nil {
+				devices[i] = nil
+				continue
+			}
+			if walChecksum(header[:16], shard) != binary.LittleEndian.Uint32(header[16:]) {
+				continue
+			}
+			s := binary.LittleEndian.Uint64(header[:])
+			rl := int(binary.LittleEndian.Uint32(header[8:]))
+			if recLen < 0 {
+				seq, recLen, shardSize = s, rl, len(shard)
+			} else if s != seq || rl != recLen || len(shard) != shardSize {
+				return ErrWALCorrupt
+			}
+			shards[i] = shard
+			found++
+		}
+		if found < ext.DataShards() {
+			return nil
+		}
+		if err := enc.ReconstructData(shards); err != nil {
+			return err
+		}
+		rec := make([]byte, 0, recLen)
+		for _, shard := range shards[:ext.DataShards()] {
+			if len(rec)+len(shard) > recLen {
+				shard = shard[:recLen-len(rec)]
+			}
+			rec = append(rec, shard...)
+		}
+		if err := fn(seq, rec); err != nil {
+			return err
+		}
+	}
+}
+
+// walShardSize returns the size of the shards of a record of recLen bytes.
+func walShardSize(ext Extensions, recLen int) int {
+	shardSize := (recLen + ext.DataShards() - 1) / ext.DataShards()
+	multiple := ext.ShardSizeMultiple()
+	return (shardSize + multiple - 1) / multiple * multiple
+}
+
+// readWALShard reads a shard of n bytes from r.
+// Shards larger than walReadChunk are read in parts.
+func readWALShard(r io.Reader, n int) ([]byte, error) {
+	if n <= walReadChunk {
+		shard := make([]byte, n)
+		_, err := io.ReadFull(r, shard)
+		return shard, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(walReadChunk)
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// walChecksum returns the checksum of a shard and its header fields.
+func walChecksum(header, shard []byte) uint32 {
+	return crc32.Update(crc32.Checksum(header, walCRC), walCRC, shard)
+}
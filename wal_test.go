@@ -0,0 +1,186 @@
+package reedsolomon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"testing"
+)
+
+type walBuffer struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (w *walBuffer) Sync() error {
+	w.syncs++
+	return nil
+}
+
+func TestWAL(t *testing.T) {
+	const dataShards, parityShards = 4, 2
+	enc, err := New(dataShards, parityShards, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bufs := make([]*walBuffer, dataShards+parityShards)
+	devices := make([]WALDevice, len(bufs))
+	for i := range bufs {
+		bufs[i] = &walBuffer{}
+		devices[i] = bufs[i]
+	}
+	w, err := NewWAL(enc, devices, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records [][]byte
+	for i := 0; i < 10; i++ {
+		rec := make([]byte, 1+i*37)
+		fillRandom(rec)
+		seq, err := w.Append(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seq != uint64(100+i) {
+			t.Fatalf("got sequence %d, want %d", seq, 100+i)
+		}
+		records = append(records, rec)
+	}
+	for i, b := range bufs {
+		if b.syncs != len(records) {
+			t.Errorf("device %d: got %d syncs, want %d", i, b.syncs, len(records))
+		}
+	}
+
+	read := func(readers []io.Reader) ([][]byte, error) {
+		var got [][]byte
+		err := ReadWAL(enc, readers, func(seq uint64, rec []byte) error {
+			if seq != uint64(100+len(got)) {
+				return fmt.Errorf("got sequence %d, want %d", seq, 100+len(got))
+			}
+			got = append(got, rec)
+			return nil
+		})
+		return got, err
+	}
+	check := func(name string, readers []io.Reader, want int) {
+		t.Helper()
+		got, err := read(readers)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(got) != want {
+			t.Fatalf("%s: got %d records, want %d", name, len(got), want)
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], records[i]) {
+				t.Fatalf("%s: record %d mismatch", name, i)
+			}
+		}
+	}
+	readers := func() []io.Reader {
+		r := make([]io.Reader, len(bufs))
+		for i, b := range bufs {
+			r[i] = bytes.NewReader(b.Bytes())
+		}
+		return r
+	}
+
+	check("all", readers(), len(records))
+
+	// Lose two devices.
+	r := readers()
+	r[0], r[5] = nil, nil
+	check("lost", r, len(records))
+
+	// Corrupt a shard and truncate another device.
+	r = readers()
+	corrupt := append([]byte{}, bufs[1].Bytes()...)
+	corrupt[walHeaderSize+1] ^= 1
+	r[1] = bytes.NewReader(corrupt)
+	r[2] = bytes.NewReader(bufs[2].Bytes()[:bufs[2].Len()/2])
+	check("damaged", r, len(records))
+
+	// A record only written to some devices is not returned.
+	r = readers()
+	for i := dataShards - 1; i < len(r); i++ {
+		b := bufs[i].Bytes()
+		r[i] = bytes.NewReader(b[:len(b)-1])
+	}
+	check("torn", r, len(records)-1)
+}
+
+type walFailDevice struct {
+	walBuffer
+	fail bool
+}
+
+func (w *walFailDevice) Write(b []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("write failed")
+	}
+	return w.walBuffer.Write(b)
+}
+
+func TestWALFailed(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	devs := make([]*walFailDevice, 6)
+	devices := make([]WALDevice, len(devs))
+	for i := range devs {
+		devs[i] = &walFailDevice{}
+		devices[i] = devs[i]
+	}
+	w, err := NewWAL(enc, devices, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	devs[3].fail = true
+	_, werr := w.Append([]byte("first"))
+	if werr == nil {
+		t.Fatal("expected error")
+	}
+	devs[3].fail = false
+	if _, err := w.Append([]byte("second")); err != werr {
+		t.Fatalf("want %v, got %v", werr, err)
+	}
+	for i, d := range devs {
+		if i > 3 && d.Len() != 0 {
+			t.Errorf("device %d was written after the failure", i)
+		}
+	}
+}
+
+func TestWALCorruptLength(t *testing.T) {
+	enc, err := New(4, 2, testOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext := enc.(Extensions)
+	readers := make([]io.Reader, 6)
+	for i := range readers {
+		// A header claiming a huge record, with little data behind it.
+		var header [walHeaderSize]byte
+		binary.LittleEndian.PutUint32(header[8:], math.MaxUint32)
+		binary.LittleEndian.PutUint32(header[12:], uint32(walShardSize(ext, math.MaxUint32)))
+		readers[i] = bytes.NewReader(append(header[:], make([]byte, 100)...))
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if err := ReadWAL(enc, readers, func(uint64, []byte) error {
+		t.Fatal("unexpected record")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 64<<20 {
+		t.Fatalf("allocated %d bytes", n)
+	}
+}
@@ -0,0 +1,76 @@
+package reedsolomon
+
+import "runtime"
+
+// Scheduler runs the parts of operations that are split between goroutines,
+// so the concurrency of encoders can be limited by an application worker pool.
+type Scheduler interface {
+	// Submit runs fn, usually in another goroutine.
+	// Submit may block until a worker is available.
+	// If an error is returned, fn is run by the goroutine that called Submit.
+	Submit(fn func()) error
+}
+
+// workerPool is a fixed set of goroutines running tasks.
+// The goroutines exit when the pool is garbage collected.
+type workerPool struct {
+	tasks chan workerTask
+}
+
+// workerTask is a function submitted to a workerPool,
+// or part of an operation, so it can be sent without allocating a closure.
+type workerTask struct {
+	fn                     func()
+	r                      *reedSolomon
+	exec                   func(start, stop int)
+	start, stop, byteCount int
+}
+
+func (t workerTask) run() {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	t.r.execNUMA(t.exec, t.start, t.stop, t.byteCount)
+}
+
+// newWorkerPool starts n goroutines running tasks sent to the returned pool.
+func newWorkerPool(n int) *workerPool {
+	p := &workerPool{tasks: make(chan workerTask)}
+	// The workers only reference the channel, so the pool can be collected.
+	for i := 0; i < n; i++ {
+		go func(tasks <-chan workerTask) {
+			for task := range tasks {
+				task.run()
+			}
+		}(p.tasks)
+	}
+	runtime.SetFinalizer(p, func(p *workerPool) {
+		close(p.tasks)
+	})
+	return p
+}
+
+// Submit runs fn on a worker when one is free.
+func (p *workerPool) Submit(fn func()) error {
+	p.tasks <- workerTask{fn: fn}
+	return nil
+}
+
+// goExec runs execNUMA with the scheduler, or in a new goroutine if there is none.
+func (r *reedSolomon) goExec(exec func(start, stop int), start, stop, byteCount int) {
+	switch s := r.o.scheduler.(type) {
+	case nil:
+		go r.execNUMA(exec, start, stop, byteCount)
+		return
+	case *workerPool:
+		s.tasks <- workerTask{r: r, exec: exec, start: start, stop: stop, byteCount: byteCount}
+		return
+	}
+	job := func() {
+		r.execNUMA(exec, start, stop, byteCount)
+	}
+	if r.o.scheduler.Submit(job) != nil {
+		job()
+	}
+}
@@ -0,0 +1,90 @@
+package reedsolomon
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithPersistentWorkers(t *testing.T) {
+	before := runtime.NumGoroutine()
+	enc, err := New(10, 4, testOptions(WithPersistentWorkers(4), WithMaxGoroutines(4), WithMinSplitSize(16<<10))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runtime.NumGoroutine(); got < before+4 {
+		t.Errorf("got %d goroutines, want at least %d", got, before+4)
+	}
+	shards := enc.(Extensions).AllocAligned(256 << 10)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	for i := 0; i < 10; i++ {
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ok, err := enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+	shards[0], shards[13] = nil, nil
+	if err := enc.Reconstruct(shards); err != nil {
+		t.Fatal(err)
+	}
+	ok, err = enc.Verify(shards)
+	if !ok || err != nil {
+		t.Fatal("not ok:", ok, "err:", err)
+	}
+
+	// The workers exit when the encoder is collected.
+	enc = nil
+	for i := 0; i < 50 && runtime.NumGoroutine() > before; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > before {
+		t.Errorf("got %d goroutines after collection, want at most %d", got, before)
+	}
+}
+
+// testScheduler runs submitted functions in new goroutines, or rejects them.
+type testScheduler struct {
+	reject bool
+	calls  atomic.Int32
+}
+
+func (s *testScheduler) Submit(fn func()) error {
+	s.calls.Add(1)
+	if s.reject {
+		return errors.New("rejected")
+	}
+	go fn()
+	return nil
+}
+
+func TestWithScheduler(t *testing.T) {
+	for _, reject := range []bool{false, true} {
+		s := &testScheduler{reject: reject}
+		enc, err := New(10, 4, testOptions(WithScheduler(s), WithPersistentWorkers(4), WithMaxGoroutines(4), WithMinSplitSize(16<<10))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards := enc.(Extensions).AllocAligned(256 << 10)
+		for _, shard := range shards[:10] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := enc.Verify(shards)
+		if !ok || err != nil {
+			t.Fatal("reject", reject, "not ok:", ok, "err:", err)
+		}
+		if s.calls.Load() == 0 {
+			t.Error("reject", reject, "scheduler not used")
+		}
+	}
+}
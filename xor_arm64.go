@@ -1,4 +1,4 @@
-//go:build !noasm && !appengine && !gccgo
+//go:build !noasm && !purego && !appengine && !gccgo
 
 package reedsolomon
 
@@ -7,6 +7,9 @@ func xorSliceNEON(in, out []byte)
 
 // simple slice xor
 func sliceXor(in, out []byte, o *options) {
+	if checkEnabled {
+		checkSlice(in, out)
+	}
 	done := (len(in) >> 5) << 5
 	if raceEnabled {
 		raceWriteSlice(out[:done])
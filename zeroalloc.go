@@ -0,0 +1,66 @@
+package reedsolomon
+
+import (
+	"runtime"
+	"sync"
+)
+
+// freeList holds values reused between operations.
+// Values are kept in free if set, so they survive garbage collection,
+// and otherwise in pool.
+type freeList[T any] struct {
+	pool sync.Pool
+	free chan *T
+}
+
+// get returns a value from the list, or a new value if it is empty.
+func (f *freeList[T]) get() *T {
+	if f.free != nil {
+		select {
+		case v := <-f.free:
+			return v
+		default:
+		}
+	} else if v, ok := f.pool.Get().(*T); ok {
+		return v
+	}
+	return new(T)
+}
+
+// put returns v to the list.
+func (f *freeList[T]) put(v *T) {
+	if f.free == nil {
+		f.pool.Put(v)
+		return
+	}
+	select {
+	case f.free <- v:
+	default:
+	}
+}
+
+// share makes f use the values kept by from, if any.
+// Values taken from the pool of from are not shared.
+func (f *freeList[T]) share(from *freeList[T]) {
+	f.free = from.free
+}
+
+// keep makes f keep up to n values until they are used, and adds n new values.
+func (f *freeList[T]) keep(n int) {
+	f.free = make(chan *T, n)
+	for i := 0; i < n; i++ {
+		f.free <- new(T)
+	}
+}
+
+// keptStates returns the number of operation states to keep,
+// or 0 if states are reused through a pool.
+func (o *options) keptStates() int {
+	if o.scratchStates > 0 {
+		return o.scratchStates
+	}
+	if o.zeroAlloc {
+		return runtime.GOMAXPROCS(0)
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package reedsolomon
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestWithZeroAlloc(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts []Option
+		size int
+	}{
+		{name: "rs", size: 1 << 10},
+		{name: "rs-split", opts: []Option{WithMaxGoroutines(4), WithMinSplitSize(16 << 10)}, size: 256 << 10},
+		{name: "gf8", opts: []Option{WithLeopardGF(true)}, size: 64 << 10},
+		{name: "gf16", opts: []Option{WithLeopardGF16(true)}, size: 64 << 10},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := New(10, 4, testOptions(append(test.opts, WithZeroAlloc(true))...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shards := enc.(Extensions).AllocAligned(test.size)
+			for _, shard := range shards[:10] {
+				fillRandom(shard)
+			}
+			reconstruct := func() {
+				shards[1] = shards[1][:0]
+				shards[11] = shards[11][:0]
+				if err := enc.Reconstruct(shards); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// The first operations allocate the scratch state.
+			if err := enc.Encode(shards); err != nil {
+				t.Fatal(err)
+			}
+			reconstruct()
+
+			if n := testing.AllocsPerRun(10, func() {
+				if err := enc.Encode(shards); err != nil {
+					t.Fatal(err)
+				}
+			}); n != 0 {
+				t.Errorf("Encode: got %v allocations, want 0", n)
+			}
+			if n := testing.AllocsPerRun(10, reconstruct); n != 0 {
+				t.Errorf("Reconstruct: got %v allocations, want 0", n)
+			}
+			if err := enc.EncodeIdx(shards[0], 0, shards[10:]); err != ErrNotSupported {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := testing.AllocsPerRun(10, func() {
+					if err := enc.EncodeIdx(shards[0], 0, shards[10:]); err != nil {
+						t.Fatal(err)
+					}
+				}); n != 0 {
+					t.Errorf("EncodeIdx: got %v allocations, want 0", n)
+				}
+			}
+
+			// Overrides share the kept states.
+			ov, err := Override(enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := testing.AllocsPerRun(10, func() {
+				if err := ov.Encode(shards); err != nil {
+					t.Fatal(err)
+				}
+			}); n != 0 {
+				t.Errorf("Override Encode: got %v allocations, want 0", n)
+			}
+			if ok, err := enc.Verify(shards); !ok || err != nil {
+				t.Fatal("not ok:", ok, "err:", err)
+			}
+		})
+	}
+}
+
+func BenchmarkZeroAlloc(b *testing.B) {
+	enc, err := New(10, 4, WithZeroAlloc(true))
+	if err != nil {
+		b.Fatal(err)
+	}
+	shards := enc.(Extensions).AllocAligned(4 << 10)
+	for _, shard := range shards[:10] {
+		fillRandom(shard)
+	}
+	b.SetBytes(10 * 4 << 10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := enc.Encode(shards); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWithScratchStates(t *testing.T) {
+	for _, opt := range []Option{WithLeopardGF(true), WithLeopardGF16(true), WithLeopardGF32(true)} {
+		enc, err := New(10, 4, testOptions(opt, WithScratchStates(2))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Kept states survive garbage collections.
+		shards := enc.(Extensions).AllocAligned(64 << 10)
+		for _, shard := range shards[:10] {
+			fillRandom(shard)
+		}
+		if err := enc.Encode(shards); err != nil {
+			t.Fatal(err)
+		}
+		if n := testing.AllocsPerRun(2, func() {
+			runtime.GC()
+			runtime.GC()
+			if err := enc.Encode(shards); err != nil {
+				t.Fatal(err)
+			}
+		}); n != 0 {
+			t.Errorf("got %v allocations, want 0", n)
+		}
+
+		// More concurrent operations than kept states.
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				shards := enc.(Extensions).AllocAligned(64 << 10)
+				for _, shard := range shards[:10] {
+					fillRandom(shard)
+				}
+				for i := 0; i < 5; i++ {
+					if err := enc.Encode(shards); err != nil {
+						t.Error(err)
+						return
+					}
+					shards[i], shards[10+i%4] = nil, nil
+					if err := enc.Reconstruct(shards); err != nil {
+						t.Error(err)
+						return
+					}
+					if ok, err := enc.Verify(shards); !ok || err != nil {
+						t.Error("not ok:", ok, "err:", err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}